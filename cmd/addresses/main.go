@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/adapters/secondary"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/blockchain"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"ethereum-raw-data-crawler/internal/infrastructure/throttle"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "addresses failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	checkCode := flag.Bool("check-code", cfg.Addresses.CheckCode, "classify the addresses that only received transactions with eth_getCode")
+	flag.Parse()
+
+	log, err := logger.NewLogger(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer log.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, err := database.NewMongoDB(&cfg.MongoDB)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	defer db.Close(context.Background())
+
+	if err := db.CreateIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	storage, err := secondary.NewStorage(db, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+	if err := storage.Migrate(ctx); err != nil {
+		return fmt.Errorf("failed to migrate storage: %w", err)
+	}
+
+	// The Ethereum node is only needed to read code
+	var blockchainService service.BlockchainService
+	if *checkCode {
+		blockchainService = blockchain.NewEthereumService(&cfg.Ethereum, throttle.NewController(cfg, log), log)
+		if err := blockchainService.Connect(ctx); err != nil {
+			return fmt.Errorf("failed to connect to Ethereum node: %w", err)
+		}
+		defer blockchainService.Disconnect()
+	}
+
+	addressService := appservice.NewAddressService(storage.AddressRepository(), blockchainService, cfg, log)
+
+	result, err := addressService.Rebuild(ctx, appservice.AddressRebuildRequest{CheckCode: *checkCode})
+	if result != nil {
+		fmt.Printf("Addresses rebuilt: %d, classified by code: %d, contracts: %d, failed: %d, duration: %s\n",
+			result.Addresses, result.Classified, result.Contracts, result.Failed, result.Duration)
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/adapters/secondary"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+const usage = `Usage:
+  checkpoint show
+  checkpoint rewind --block N`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", usage)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	log, err := logger.NewLogger(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer log.Sync()
+
+	ctx := context.Background()
+
+	db, err := database.NewMongoDB(&cfg.MongoDB)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	defer db.Close(ctx)
+
+	checkpoints := appservice.NewCheckpointService(secondary.NewCheckpointRepository(db), cfg, log)
+
+	switch args[0] {
+	case "show":
+		checkpoint, err := checkpoints.GetCheckpoint(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get checkpoint: %w", err)
+		}
+		if checkpoint == nil {
+			fmt.Printf("No checkpoint for network %s\n", cfg.Ethereum.Network)
+			return nil
+		}
+		printCheckpoint(checkpoint)
+		return nil
+	case "rewind":
+		return rewind(ctx, checkpoints, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+// rewind moves the committed watermark back so crawling resumes at --block
+func rewind(ctx context.Context, checkpoints *appservice.CheckpointService, args []string) error {
+	flags := flag.NewFlagSet("rewind", flag.ExitOnError)
+	block := flags.Uint64("block", 0, "block number to resume crawling from")
+	flags.Parse(args)
+
+	if *block == 0 {
+		return fmt.Errorf("--block is required\n%s", usage)
+	}
+
+	checkpoint, err := checkpoints.Rewind(ctx, *block)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Rewound checkpoint, crawling resumes at block %d\n", *block)
+	printCheckpoint(checkpoint)
+	return nil
+}
+
+// printCheckpoint prints both watermarks of a checkpoint
+func printCheckpoint(checkpoint *entity.Checkpoint) {
+	fmt.Printf("Network:            %s\n", checkpoint.Network)
+	fmt.Printf("Committed block:    %d\n", checkpoint.CommittedBlock)
+	fmt.Printf("Highest seen block: %d\n", checkpoint.HighestSeenBlock)
+	fmt.Printf("Updated at:         %s\n", checkpoint.UpdatedAt.Format(time.RFC3339))
+	if checkpoint.RewoundAt != nil {
+		fmt.Printf("Rewound at:         %s\n", checkpoint.RewoundAt.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/adapters/secondary"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"ethereum-raw-data-crawler/internal/infrastructure/export"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "export failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	from := flag.Uint64("from", 0, "first block number to export, defaults to START_BLOCK_NUMBER")
+	to := flag.Uint64("to", 0, "last block number to export (inclusive), 0 for the last processed block")
+	fromTime := flag.String("from-time", "", "export blocks mined from this time (RFC 3339, or YYYY-MM-DD for midnight UTC) instead of a block range")
+	toTime := flag.String("to-time", "", "export blocks mined until this time (RFC 3339, or YYYY-MM-DD for midnight UTC), empty for now")
+	format := flag.String("format", export.FormatParquet, "file format: parquet, csv or jsonl")
+	out := flag.String("out", "export", "export directory")
+	incremental := flag.Bool("incremental", false, "start after the last block in the export manifest and stop at the first unprocessed block")
+	batchSize := flag.Uint64("batch-size", 1000, "blocks read per chunk")
+	flag.Parse()
+
+	fromSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "from" {
+			fromSet = true
+		}
+	})
+	if !fromSet {
+		// Blocks below the start block aren't crawled
+		*from = cfg.Ethereum.StartBlock
+	}
+
+	req := appservice.ExportRequest{
+		Network:     cfg.Ethereum.Network,
+		FromBlock:   *from,
+		ToBlock:     *to,
+		Incremental: *incremental,
+		BatchSize:   *batchSize,
+	}
+	if req.FromTime, err = parseExportTime(*fromTime); err != nil {
+		return fmt.Errorf("invalid --from-time: %w", err)
+	}
+	if req.ToTime, err = parseExportTime(*toTime); err != nil {
+		return fmt.Errorf("invalid --to-time: %w", err)
+	}
+	if (!req.FromTime.IsZero() || !req.ToTime.IsZero()) && (fromSet || *to != 0) {
+		flag.Usage()
+		return fmt.Errorf("--from-time and --to-time can't be combined with --from and --to")
+	}
+
+	sink, err := export.NewFileSink(*out, *format)
+	if err != nil {
+		return err
+	}
+
+	log, err := logger.NewLogger(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer log.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, err := database.NewMongoDB(&cfg.MongoDB)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	defer db.Close(context.Background())
+
+	storage, err := secondary.NewStorage(db, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	exportService := appservice.NewExportService(
+		storage.BlockRepository(),
+		storage.TransactionRepository(),
+		sink,
+		log,
+	)
+
+	result, err := exportService.Export(ctx, req)
+	if result != nil {
+		fmt.Printf("Blocks %d-%d exported: %d, missing: %d, transactions exported: %d, files written: %d, duration: %s\n",
+			result.FromBlock, result.ToBlock, result.BlocksExported, result.BlocksMissing,
+			result.TransactionsExported, result.FilesWritten, result.Duration)
+	}
+	return err
+}
+
+// parseExportTime parses an RFC 3339 time or a UTC date, empty is the zero time
+func parseExportTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/adapters/secondary"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// Migrations selected with --migration
+const (
+	migrationAll             = "all"
+	migrationWei             = "wei"
+	migrationBlockTimestamps = "block-timestamps"
+	migrationBlockHeights    = "block-heights"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	migration := flag.String("migration", migrationAll, "migration to run: wei, block-timestamps, block-heights or all")
+	batchSize := flag.Int("batch-size", 1000, "documents updated per bulk write")
+	dryRun := flag.Bool("dry-run", false, "count the transactions to migrate without updating them")
+	flag.Parse()
+
+	if *batchSize <= 0 {
+		flag.Usage()
+		return fmt.Errorf("--batch-size must be positive")
+	}
+	switch *migration {
+	case migrationAll, migrationWei, migrationBlockTimestamps, migrationBlockHeights:
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown migration %q", *migration)
+	}
+
+	// PostgreSQL stores wei amounts as NUMERIC from the start and backfills
+	// block timestamps in its SQL migrations
+	if cfg.Storage.Backend == secondary.StorageBackendPostgres {
+		fmt.Println("Transactions are stored on PostgreSQL, nothing to migrate")
+		return nil
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, err := database.NewMongoDB(&cfg.MongoDB)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	defer db.Close(context.Background())
+
+	// The value, block_timestamp and block_height indexes serve the queries the migrations enable
+	if err := db.CreateIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	action := "migrated"
+	if *dryRun {
+		action = "to migrate"
+	}
+
+	if *migration == migrationAll || *migration == migrationWei {
+		result, err := secondary.MigrateWeiValues(ctx, db, *batchSize, *dryRun)
+		if result != nil {
+			fmt.Printf("Wei amounts - transactions scanned: %d, %s: %d, failed: %d\n", result.Scanned, action, result.Migrated, result.Failed)
+			if len(result.FailedHashes) > 0 {
+				fmt.Printf("Failed transactions: %s\n", strings.Join(result.FailedHashes, ", "))
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to migrate wei amounts: %w", err)
+		}
+	}
+
+	if *migration == migrationAll || *migration == migrationBlockTimestamps {
+		result, err := secondary.MigrateBlockTimestamps(ctx, db, *batchSize, *dryRun)
+		if result != nil {
+			fmt.Printf("Block timestamps - blocks scanned: %d, transactions %s: %d\n", result.BlocksScanned, action, result.Transactions)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to migrate block timestamps: %w", err)
+		}
+	}
+
+	if *migration == migrationAll || *migration == migrationBlockHeights {
+		count, err := secondary.MigrateBlockHeights(ctx, db, *dryRun)
+		if err != nil {
+			return fmt.Errorf("failed to migrate block heights: %w", err)
+		}
+		fmt.Printf("Block heights - transactions %s: %d\n", action, count)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/adapters/secondary"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/blockchain"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "rederive failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	from := flag.Uint64("from", 0, "first block number to rebuild")
+	to := flag.Uint64("to", 0, "last block number to rebuild (inclusive)")
+	backend := flag.String("backend", cfg.Archive.Backend, "archive backend to read: gridfs or filesystem")
+	path := flag.String("path", cfg.Archive.Path, "archive directory of the filesystem backend")
+	flag.Parse()
+
+	if *to == 0 {
+		flag.Usage()
+		return fmt.Errorf("--to is required")
+	}
+
+	log, err := logger.NewLogger(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer log.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, err := database.NewMongoDB(&cfg.MongoDB)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	defer db.Close(context.Background())
+
+	storage, err := secondary.NewStorage(db, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+	if err := storage.Migrate(ctx); err != nil {
+		return fmt.Errorf("failed to migrate storage: %w", err)
+	}
+
+	archiveConfig := *cfg
+	archiveConfig.Archive.Backend = *backend
+	archiveConfig.Archive.Path = *path
+
+	archive, err := secondary.NewBlockArchiveRepository(db, &archiveConfig)
+	if err != nil {
+		return fmt.Errorf("failed to open block archive: %w", err)
+	}
+
+	// No Ethereum node is needed, everything comes from the archive
+	rederiveService := appservice.NewRederiveService(
+		storage.BlockRepository(),
+		storage.TransactionRepository(),
+		storage.BlockCommitRepository(),
+		blockchain.NewArchiveReader(archive, &cfg.Ethereum, log),
+		log,
+	)
+
+	// Addresses are recorded from the archived transactions, recipients are
+	// classified by cmd/addresses --check-code since there is no node
+	if cfg.Addresses.Enabled {
+		addressConfig := *cfg
+		addressConfig.Addresses.CheckCode = false
+		rederiveService.SetAddressService(
+			appservice.NewAddressService(storage.AddressRepository(), nil, &addressConfig, log))
+	}
+
+	// Token transfers are decoded from the archived receipts
+	if cfg.TokenTransfers.Enabled {
+		tokenTransferService := appservice.NewTokenTransferService(storage.TokenTransferRepository(), nil, cfg, log)
+		// New tokens are only added, the scheduler leader reads their metadata
+		if cfg.Tokens.Enabled {
+			tokenTransferService.SetTokenMetadataService(
+				appservice.NewTokenMetadataService(storage.TokenRepository(), nil, cfg, log))
+		}
+		rederiveService.SetTokenTransferService(tokenTransferService)
+	}
+
+	result, err := rederiveService.Rederive(ctx, appservice.RederiveRequest{
+		FromBlock: *from,
+		ToBlock:   *to,
+	})
+	if result != nil {
+		fmt.Printf("Blocks rederived: %d, missing from archive: %d, transactions written: %d, token transfers written: %d, duration: %s\n",
+			result.BlocksRederived, result.BlocksMissing, result.TransactionsWritten, result.TransfersWritten, result.Duration)
+	}
+	return err
+}
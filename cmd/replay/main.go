@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/adapters/secondary"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"ethereum-raw-data-crawler/internal/infrastructure/messaging"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "replay failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Replays go to a separate stream by default so live consumers are not disturbed
+	from := flag.Uint64("from", 0, "first block number to replay")
+	to := flag.Uint64("to", 0, "last block number to replay (inclusive)")
+	ratePerSecond := flag.Float64("rate", 100, "maximum transactions published per second, 0 for unlimited")
+	stream := flag.String("stream", cfg.NATS.StreamName+"_REPLAY", "JetStream stream to publish to")
+	subjectPrefix := flag.String("subject-prefix", cfg.NATS.SubjectPrefix+".replay", "subject prefix to publish to (events go to <prefix>.events)")
+	flag.Parse()
+
+	if *to == 0 {
+		flag.Usage()
+		return fmt.Errorf("--to is required")
+	}
+
+	log, err := logger.NewLogger(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer log.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, err := database.NewMongoDB(&cfg.MongoDB)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	defer db.Close(context.Background())
+
+	storage, err := secondary.NewStorage(db, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	natsConfig := cfg.NATS
+	natsConfig.Enabled = true
+	natsConfig.StreamName = *stream
+	natsConfig.SubjectPrefix = *subjectPrefix
+
+	messagingService := messaging.NewNATSClient(&natsConfig, log)
+	if err := messagingService.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	defer messagingService.Disconnect()
+
+	replayService := appservice.NewReplayService(
+		storage.BlockRepository(),
+		storage.TransactionRepository(),
+		messagingService,
+		log,
+	)
+
+	log.Info("Replaying blocks",
+		zap.String("stream", natsConfig.StreamName),
+		zap.String("subject_prefix", natsConfig.SubjectPrefix))
+
+	result, err := replayService.Replay(ctx, appservice.ReplayRequest{
+		FromBlock:     *from,
+		ToBlock:       *to,
+		RatePerSecond: *ratePerSecond,
+	})
+	if result != nil {
+		fmt.Printf("Blocks replayed: %d, missing: %d, transactions published: %d, skipped: %d, duration: %s\n",
+			result.BlocksReplayed, result.BlocksMissing, result.TransactionsPublished,
+			result.TransactionsSkipped, result.Duration)
+	}
+	return err
+}
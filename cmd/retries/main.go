@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/adapters/secondary"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage:
+  retries list [--status dead_letter|pending] [--limit N]
+  retries requeue --block N
+  retries requeue --all`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", usage)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	log, err := logger.NewLogger(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer log.Sync()
+
+	ctx := context.Background()
+
+	db, err := database.NewMongoDB(&cfg.MongoDB)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	defer db.Close(ctx)
+
+	// The retry worker isn't started here, so no crawler is needed
+	retryService := appservice.NewRetryService(secondary.NewBlockRetryRepository(db), nil, cfg, log)
+
+	switch args[0] {
+	case "list":
+		return list(ctx, retryService, args[1:])
+	case "requeue":
+		return requeue(ctx, retryService, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+// list prints queued blocks with the given status
+func list(ctx context.Context, retryService *appservice.RetryService, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	status := flags.String("status", string(entity.BlockRetryStatusDeadLetter), "retry status to list: dead_letter or pending")
+	limit := flags.Int("limit", 100, "maximum number of blocks to list")
+	flags.Parse(args)
+
+	retries, err := retryService.ListBlockRetries(ctx, entity.BlockRetryStatus(*status), *limit)
+	if err != nil {
+		return fmt.Errorf("failed to list block retries: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BLOCK\tSTATUS\tATTEMPTS\tNEXT ATTEMPT\tUPDATED\tLAST ERROR")
+	for _, retry := range retries {
+		nextAttempt := "-"
+		if retry.Status == entity.BlockRetryStatusPending {
+			nextAttempt = retry.NextAttemptAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\n",
+			retry.BlockNumber, retry.Status, retry.Attempts, nextAttempt,
+			retry.UpdatedAt.Format(time.RFC3339), retry.LastError)
+	}
+	return w.Flush()
+}
+
+// requeue moves a dead-lettered block, or all of them, back to pending
+func requeue(ctx context.Context, retryService *appservice.RetryService, args []string) error {
+	flags := flag.NewFlagSet("requeue", flag.ExitOnError)
+	block := flags.Uint64("block", 0, "block number to requeue")
+	all := flags.Bool("all", false, "requeue every dead-lettered block")
+	flags.Parse(args)
+
+	if *all {
+		count, err := retryService.RequeueDeadLetters(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Requeued %d blocks\n", count)
+		return nil
+	}
+
+	if *block == 0 {
+		return fmt.Errorf("--block or --all is required\n%s", usage)
+	}
+
+	if err := retryService.Requeue(ctx, *block); err != nil {
+		return err
+	}
+	fmt.Printf("Requeued block %d\n", *block)
+	return nil
+}
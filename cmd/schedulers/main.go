@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"ethereum-raw-data-crawler/internal/adapters/primary"
 	"ethereum-raw-data-crawler/internal/adapters/secondary"
 	appservice "ethereum-raw-data-crawler/internal/application/service"
 	"ethereum-raw-data-crawler/internal/domain/repository"
@@ -11,6 +12,7 @@ import (
 	"ethereum-raw-data-crawler/internal/infrastructure/database"
 	"ethereum-raw-data-crawler/internal/infrastructure/logger"
 	"ethereum-raw-data-crawler/internal/infrastructure/messaging"
+	"ethereum-raw-data-crawler/internal/infrastructure/throttle"
 	"os"
 	"os/signal"
 	"syscall"
@@ -43,12 +45,14 @@ func main() {
 		// Infrastructure
 		fx.Provide(logger.NewLogger),
 		fx.Provide(database.NewMongoDB),
+		fx.Provide(throttle.NewController),
 
 		// Messaging service
 		fx.Provide(
 			fx.Annotate(
 				messaging.NewNATSMessagingService,
 				fx.As(new(service.MessagingService)),
+				fx.As(new(service.RequestReplyService)),
 			),
 		),
 
@@ -69,28 +73,60 @@ func main() {
 		),
 
 		// Repositories
+		// Blocks, transactions, addresses, token transfers, tokens and metrics are stored on the STORAGE_BACKEND backend
+		fx.Provide(secondary.NewStorage),
+		fx.Provide((*secondary.Storage).BlockRepository),
+		fx.Provide((*secondary.Storage).TransactionRepository),
+		fx.Provide((*secondary.Storage).BlockCommitRepository),
+		fx.Provide((*secondary.Storage).AddressRepository),
+		fx.Provide((*secondary.Storage).TokenTransferRepository),
+		fx.Provide((*secondary.Storage).TokenRepository),
+		fx.Provide((*secondary.Storage).MetricsRepository),
 		fx.Provide(
 			fx.Annotate(
-				secondary.NewBlockRepository,
-				fx.As(new(repository.BlockRepository)),
+				secondary.NewBlockRetryRepository,
+				fx.As(new(repository.BlockRetryRepository)),
 			),
 		),
 		fx.Provide(
 			fx.Annotate(
-				secondary.NewTransactionRepository,
-				fx.As(new(repository.TransactionRepository)),
+				secondary.NewLeaseRepository,
+				fx.As(new(repository.LeaseRepository)),
 			),
 		),
 		fx.Provide(
 			fx.Annotate(
-				secondary.NewMetricsRepository,
-				fx.As(new(repository.MetricsRepository)),
+				secondary.NewWorkRangeRepository,
+				fx.As(new(repository.WorkRangeRepository)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				secondary.NewCheckpointRepository,
+				fx.As(new(repository.CheckpointRepository)),
 			),
 		),
 
 		// Application services
 		fx.Provide(appservice.NewCrawlerService),
+		fx.Provide(appservice.NewBlockWriter),
+		fx.Provide(appservice.NewAddressService),
+		fx.Provide(appservice.NewTokenTransferService),
+		fx.Provide(appservice.NewTokenMetadataService),
+		fx.Provide(appservice.NewCheckpointService),
+		fx.Provide(appservice.NewRetryService),
+		fx.Provide(appservice.NewLeaderElectionService),
 		fx.Provide(appservice.NewSchedulerService),
+		fx.Provide(appservice.NewWorkCoordinatorService),
+		fx.Provide(appservice.NewPriorityCrawlService),
+		fx.Provide(appservice.NewReceiptRepairService),
+		fx.Provide(appservice.NewVerifyService),
+		fx.Provide(appservice.NewBlockTimeService),
+		fx.Provide(appservice.NewAddressActivityService),
+
+		// Admin interface and on-demand crawl requests
+		fx.Provide(primary.NewAdminServer),
+		fx.Provide(primary.NewCrawlRequestHandler),
 
 		// Lifecycle hooks
 		fx.Invoke(registerSchedulerHooks),
@@ -105,9 +141,23 @@ func registerSchedulerHooks(
 	cfg *config.Config,
 	logger *logger.Logger,
 	db *database.MongoDB,
+	storage *secondary.Storage,
 	messagingService service.MessagingService,
 	crawlerService *appservice.CrawlerService,
+	blockWriter *appservice.BlockWriter,
+	addressService *appservice.AddressService,
+	tokenTransferService *appservice.TokenTransferService,
+	tokenMetadata *appservice.TokenMetadataService,
 	schedulerService *appservice.SchedulerService,
+	retryService *appservice.RetryService,
+	leaderElection *appservice.LeaderElectionService,
+	workCoordinator *appservice.WorkCoordinatorService,
+	checkpoints *appservice.CheckpointService,
+	priorityCrawls *appservice.PriorityCrawlService,
+	receiptRepair *appservice.ReceiptRepairService,
+	verifier *appservice.VerifyService,
+	adminServer *primary.AdminServer,
+	crawlRequestHandler *primary.CrawlRequestHandler,
 ) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
@@ -122,6 +172,13 @@ func registerSchedulerHooks(
 				return err
 			}
 
+			// Apply PostgreSQL migrations when it is the storage backend
+			logger.Info("Using storage backend", zap.String("backend", storage.Backend()))
+			if err := storage.Migrate(ctx); err != nil {
+				logger.Error("Failed to migrate storage", zap.Error(err))
+				return err
+			}
+
 			// Connect to messaging service (only if enabled)
 			if cfg.NATS.Enabled {
 				logger.Info("NATS is enabled, connecting to messaging service")
@@ -136,14 +193,145 @@ func registerSchedulerHooks(
 			// Configure crawler for external scheduler mode
 			crawlerService.SetExternalSchedulerMode(true)
 
+			// Resume from and advance the committed checkpoint
+			crawlerService.SetCheckpointService(checkpoints)
+
+			// Writes guarded by the lease stop once it is lost; the checkpoint and
+			// work ranges carry the fencing token so a stale leader can't overwrite
+			// a newer one's
+			checkpoints.SetLeaderElection(leaderElection)
+			workCoordinator.SetLeaderElection(leaderElection)
+			retryService.SetLeaderElection(leaderElection)
+
+			// Keep the raw block and receipts of every processed block
+			if cfg.Archive.Enabled {
+				archive, err := secondary.NewBlockArchiveRepository(db, cfg)
+				if err != nil {
+					logger.Error("Failed to open block archive", zap.Error(err))
+					return err
+				}
+				crawlerService.SetBlockArchive(archive)
+				// Verification rebuilds the tries of stored blocks from the archive
+				verifier.SetBlockArchive(blockchain.NewArchiveReader(archive, &cfg.Ethereum, logger))
+			}
+
+			// Commit blocks in bulk across blocks, meant for backfills
+			if cfg.Crawler.BufferedWrites {
+				if err := blockWriter.Start(ctx); err != nil {
+					logger.Error("Failed to start block writer", zap.Error(err))
+					return err
+				}
+				crawlerService.SetBlockWriter(blockWriter)
+			}
+
+			// Record first-seen and last-active blocks and contracts of addresses
+			if cfg.Addresses.Enabled {
+				crawlerService.SetAddressService(addressService)
+			}
+
+			// Store and publish the token transfers decoded from receipt logs
+			if cfg.TokenTransfers.Enabled {
+				crawlerService.SetTokenTransferService(tokenTransferService)
+				// Tokens seen for the first time are added to the metadata cache, the
+				// leader reads their metadata
+				if cfg.Tokens.Enabled {
+					tokenTransferService.SetTokenMetadataService(tokenMetadata)
+				}
+				receiptRepair.SetTokenTransferService(tokenTransferService)
+				verifier.SetTokenTransferService(tokenTransferService)
+			}
+
 			// Start crawler service (without internal worker)
 			if err := crawlerService.Start(ctx); err != nil {
 				logger.Error("Failed to start crawler service", zap.Error(err))
 				return err
 			}
 
-			// Start scheduler service (this will handle block scheduling)
-			if err := schedulerService.Start(ctx); err != nil {
+			crawlerService.RegisterHealthComponent("leader_election", leaderElection.HealthCheck)
+			crawlerService.RegisterHealthComponent("checkpoint", checkpoints.HealthCheck)
+
+			// Blocks published as work ranges are crawled by cmd/worker processes
+			if cfg.Work.Enabled {
+				schedulerService.SetWorkCoordinator(workCoordinator)
+				schedulerService.RegisterStats("work", workCoordinator.GetStats)
+			}
+			if cfg.TokenTransfers.Enabled && cfg.Tokens.Enabled {
+				schedulerService.RegisterStats("token_metadata", func(ctx context.Context) map[string]interface{} {
+					return tokenMetadata.GetStats()
+				})
+			}
+
+			// On-demand crawls are served by every instance, leader or not
+			if err := priorityCrawls.Start(ctx); err != nil {
+				logger.Error("Failed to start priority crawl service", zap.Error(err))
+				return err
+			}
+			if cfg.NATS.Enabled {
+				if err := crawlRequestHandler.Start(ctx); err != nil {
+					logger.Error("Failed to start crawl request handler", zap.Error(err))
+					return err
+				}
+			}
+
+			// Only the leader runs the scheduler (this will handle block scheduling),
+			// followers stand by until the lease becomes free
+			err := leaderElection.Start(ctx, appservice.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) error {
+					// Resume from blocks processed by the previous leader
+					if err := crawlerService.ReloadStartingBlock(ctx); err != nil {
+						return err
+					}
+					if err := schedulerService.Start(ctx); err != nil {
+						return err
+					}
+					// Publish block ranges for cmd/worker processes
+					if cfg.Work.Enabled {
+						if err := workCoordinator.Start(ctx); err != nil {
+							if stopErr := schedulerService.Stop(); stopErr != nil {
+								logger.Error("Error stopping scheduler service", zap.Error(stopErr))
+							}
+							return err
+						}
+					}
+					// Re-fetch receipts that were missing when blocks were crawled
+					if cfg.ReceiptRepair.Enabled {
+						if err := receiptRepair.Start(ctx); err != nil {
+							logger.Error("Failed to start receipt repair service", zap.Error(err))
+						}
+					}
+					// Read the metadata of new tokens and refresh the cached one
+					if cfg.TokenTransfers.Enabled && cfg.Tokens.Enabled {
+						if err := tokenMetadata.Start(ctx); err != nil {
+							logger.Error("Failed to start token metadata service", zap.Error(err))
+						}
+					}
+					// Check newly confirmed blocks against the chain
+					if cfg.Verify.Enabled {
+						if err := verifier.Start(ctx); err != nil {
+							logger.Error("Failed to start verify service", zap.Error(err))
+						}
+					}
+					return nil
+				},
+				OnStoppedLeading: func() {
+					if err := verifier.Stop(); err != nil {
+						logger.Error("Error stopping verify service", zap.Error(err))
+					}
+					if err := receiptRepair.Stop(); err != nil {
+						logger.Error("Error stopping receipt repair service", zap.Error(err))
+					}
+					if err := tokenMetadata.Stop(); err != nil {
+						logger.Error("Error stopping token metadata service", zap.Error(err))
+					}
+					if err := workCoordinator.Stop(); err != nil {
+						logger.Error("Error stopping work coordinator", zap.Error(err))
+					}
+					if err := schedulerService.Stop(); err != nil {
+						logger.Error("Error stopping scheduler service", zap.Error(err))
+					}
+				},
+			})
+			if err != nil {
 				logger.Error("Failed to start scheduler service", zap.Error(err))
 				return err
 			}
@@ -156,36 +344,97 @@ func registerSchedulerHooks(
 
 				logger.Info("Received shutdown signal")
 
-				// Stop scheduler service first
+				// Stop scheduler service first and hand over leadership
+				if err := leaderElection.Stop(context.Background()); err != nil {
+					logger.Error("Error stopping leader election", zap.Error(err))
+				}
 				if err := schedulerService.Stop(); err != nil {
 					logger.Error("Error stopping scheduler service", zap.Error(err))
 				}
+				if err := verifier.Stop(); err != nil {
+					logger.Error("Error stopping verify service", zap.Error(err))
+				}
+				if err := receiptRepair.Stop(); err != nil {
+					logger.Error("Error stopping receipt repair service", zap.Error(err))
+				}
+				if err := tokenMetadata.Stop(); err != nil {
+					logger.Error("Error stopping token metadata service", zap.Error(err))
+				}
+				if err := crawlRequestHandler.Stop(); err != nil {
+					logger.Error("Error stopping crawl request handler", zap.Error(err))
+				}
+				if err := priorityCrawls.Stop(); err != nil {
+					logger.Error("Error stopping priority crawl service", zap.Error(err))
+				}
 
 				// Then stop crawler service
 				if err := crawlerService.Stop(ctx); err != nil {
 					logger.Error("Error stopping crawler service", zap.Error(err))
 				}
+
+				// Flush blocks still buffered by the stopped crawls
+				if err := blockWriter.Stop(); err != nil {
+					logger.Error("Error stopping block writer", zap.Error(err))
+				}
 			}()
 
+			// Runtime control (pause/resume, mode, polling interval, on-demand crawls)
+			if cfg.Admin.Enabled {
+				if err := adminServer.Start(ctx); err != nil {
+					logger.Error("Failed to start admin server", zap.Error(err))
+					return err
+				}
+			}
+
 			logger.Info("Ethereum Block Scheduler started successfully",
 				zap.String("mode", cfg.Scheduler.Mode),
 				zap.Bool("realtime_enabled", cfg.Scheduler.EnableRealtime),
-				zap.Bool("polling_enabled", cfg.Scheduler.EnablePolling))
+				zap.Bool("polling_enabled", cfg.Scheduler.EnablePolling),
+				zap.Bool("work_distribution_enabled", cfg.Work.Enabled))
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
 			logger.Info("Stopping Ethereum Block Scheduler")
 
-			// Stop scheduler service first
+			if err := adminServer.Stop(ctx); err != nil {
+				logger.Error("Error stopping admin server", zap.Error(err))
+			}
+
+			// Stop scheduler service first and hand over leadership
+			if err := leaderElection.Stop(ctx); err != nil {
+				logger.Error("Error stopping leader election", zap.Error(err))
+			}
 			if err := schedulerService.Stop(); err != nil {
 				logger.Error("Error stopping scheduler service", zap.Error(err))
 			}
+			if err := verifier.Stop(); err != nil {
+				logger.Error("Error stopping verify service", zap.Error(err))
+			}
+			if err := receiptRepair.Stop(); err != nil {
+				logger.Error("Error stopping receipt repair service", zap.Error(err))
+			}
+			if err := tokenMetadata.Stop(); err != nil {
+				logger.Error("Error stopping token metadata service", zap.Error(err))
+			}
+
+			// Fail queued on-demand crawls before the crawler goes away
+			if err := crawlRequestHandler.Stop(); err != nil {
+				logger.Error("Error stopping crawl request handler", zap.Error(err))
+			}
+			if err := priorityCrawls.Stop(); err != nil {
+				logger.Error("Error stopping priority crawl service", zap.Error(err))
+			}
 
 			// Stop crawler service
 			if err := crawlerService.Stop(ctx); err != nil {
 				logger.Error("Error stopping crawler service", zap.Error(err))
 			}
 
+			// Flush blocks still buffered by the stopped crawls
+			if err := blockWriter.Stop(); err != nil {
+				logger.Error("Error stopping block writer", zap.Error(err))
+			}
+
 			// Disconnect from messaging service (only if it was connected)
 			if cfg.NATS.Enabled {
 				if err := messagingService.Disconnect(); err != nil {
@@ -197,6 +446,7 @@ func registerSchedulerHooks(
 			if err := db.Close(ctx); err != nil {
 				logger.Error("Error closing database connection", zap.Error(err))
 			}
+			storage.Close()
 
 			// Sync logger
 			if err := logger.Sync(); err != nil {
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/adapters/secondary"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/blockchain"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"ethereum-raw-data-crawler/internal/infrastructure/throttle"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "verify failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	from := flag.Uint64("from", 0, "first block number to verify")
+	to := flag.Uint64("to", 0, "last block number to verify (inclusive)")
+	requeue := flag.Bool("requeue", false, "delete mismatched blocks and queue them for re-crawl by the scheduler")
+	flag.Parse()
+
+	if *to == 0 {
+		flag.Usage()
+		return fmt.Errorf("--to is required")
+	}
+
+	log, err := logger.NewLogger(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer log.Sync()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, err := database.NewMongoDB(&cfg.MongoDB)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	defer db.Close(context.Background())
+
+	storage, err := secondary.NewStorage(db, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	blockchainService := blockchain.NewEthereumService(&cfg.Ethereum, throttle.NewController(cfg, log), log)
+	if err := blockchainService.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to Ethereum node: %w", err)
+	}
+	defer blockchainService.Disconnect()
+
+	// The retry worker isn't started here, re-crawls are done by the scheduler
+	retryService := appservice.NewRetryService(secondary.NewBlockRetryRepository(db), nil, cfg, log)
+
+	verifyService := appservice.NewVerifyService(
+		storage.BlockRepository(),
+		storage.TransactionRepository(),
+		blockchainService,
+		retryService,
+		cfg,
+		log,
+	)
+	// Without the archive only the stored fields are compared to the chain
+	if cfg.Archive.Enabled {
+		archive, err := secondary.NewBlockArchiveRepository(db, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to open block archive: %w", err)
+		}
+		verifyService.SetBlockArchive(blockchain.NewArchiveReader(archive, &cfg.Ethereum, log))
+	}
+	// Requeued blocks lose their token transfers too
+	if cfg.TokenTransfers.Enabled {
+		verifyService.SetTokenTransferService(
+			appservice.NewTokenTransferService(storage.TokenTransferRepository(), nil, cfg, log))
+	}
+
+	result, err := verifyService.Verify(ctx, appservice.VerifyRequest{
+		FromBlock: *from,
+		ToBlock:   *to,
+		Requeue:   *requeue,
+	})
+	if result != nil {
+		printResult(result)
+	}
+	if err != nil {
+		return err
+	}
+	if len(result.Mismatches) > 0 {
+		return fmt.Errorf("%d blocks don't match the chain", len(result.Mismatches))
+	}
+	return nil
+}
+
+// printResult prints the mismatched blocks and a summary
+func printResult(result *appservice.VerifyResult) {
+	if len(result.Mismatches) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "BLOCK\tHASH\tREQUEUED\tISSUE")
+		for _, mismatch := range result.Mismatches {
+			hash := mismatch.BlockHash
+			if hash == "" {
+				hash = "-"
+			}
+			for i, issue := range mismatch.Issues {
+				if i == 0 {
+					fmt.Fprintf(w, "%d\t%s\t%t\t%s\n", mismatch.BlockNumber, hash, mismatch.Requeued, issue)
+				} else {
+					fmt.Fprintf(w, "\t\t\t%s\n", issue)
+				}
+			}
+		}
+		w.Flush()
+	}
+
+	fmt.Printf("Blocks verified: %d, mismatched: %d, missing: %d, requeued: %d, duration: %s\n",
+		result.BlocksVerified, len(result.Mismatches), result.BlocksMissing,
+		result.BlocksRequeued, result.Duration)
+	fmt.Printf("Tries rebuilt from the raw archive: %d blocks; the transactions of the others are only compared field by field to the chain\n",
+		result.BlocksArchiveVerified)
+}
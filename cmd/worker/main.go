@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/adapters/secondary"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/blockchain"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"ethereum-raw-data-crawler/internal/infrastructure/messaging"
+	"ethereum-raw-data-crawler/internal/infrastructure/throttle"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// provideMongoDBConfig extracts MongoDB configuration from main config
+func provideMongoDBConfig(cfg *config.Config) *config.MongoDBConfig {
+	return &cfg.MongoDB
+}
+
+// provideEthereumConfig extracts Ethereum configuration from main config
+func provideEthereumConfig(cfg *config.Config) *config.EthereumConfig {
+	return &cfg.Ethereum
+}
+
+func main() {
+	app := fx.New(
+		fx.StartTimeout(5*time.Minute),
+		fx.StopTimeout(time.Minute),
+		// Configuration
+		fx.Provide(config.LoadConfig),
+		fx.Provide(provideMongoDBConfig),
+		fx.Provide(provideEthereumConfig),
+
+		// Infrastructure
+		fx.Provide(logger.NewLogger),
+		fx.Provide(database.NewMongoDB),
+		fx.Provide(throttle.NewController),
+
+		// Messaging service
+		fx.Provide(
+			fx.Annotate(
+				messaging.NewNATSMessagingService,
+				fx.As(new(service.MessagingService)),
+			),
+		),
+
+		// Blockchain service
+		fx.Provide(
+			fx.Annotate(
+				blockchain.NewEthereumService,
+				fx.As(new(service.BlockchainService)),
+			),
+		),
+
+		// Repositories
+		// Blocks, transactions, addresses, token transfers, tokens and metrics are stored on the STORAGE_BACKEND backend
+		fx.Provide(secondary.NewStorage),
+		fx.Provide((*secondary.Storage).BlockRepository),
+		fx.Provide((*secondary.Storage).TransactionRepository),
+		fx.Provide((*secondary.Storage).BlockCommitRepository),
+		fx.Provide((*secondary.Storage).AddressRepository),
+		fx.Provide((*secondary.Storage).TokenTransferRepository),
+		fx.Provide((*secondary.Storage).TokenRepository),
+		fx.Provide((*secondary.Storage).MetricsRepository),
+		fx.Provide(
+			fx.Annotate(
+				secondary.NewWorkRangeRepository,
+				fx.As(new(repository.WorkRangeRepository)),
+			),
+		),
+
+		// Application services
+		fx.Provide(appservice.NewCrawlerService),
+		fx.Provide(appservice.NewBlockWriter),
+		fx.Provide(appservice.NewAddressService),
+		fx.Provide(appservice.NewTokenTransferService),
+		fx.Provide(appservice.NewTokenMetadataService),
+		fx.Provide(appservice.NewWorkerService),
+
+		// Lifecycle hooks
+		fx.Invoke(registerWorkerHooks),
+	)
+
+	app.Run()
+}
+
+// registerWorkerHooks registers worker application lifecycle hooks
+func registerWorkerHooks(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	logger *logger.Logger,
+	db *database.MongoDB,
+	storage *secondary.Storage,
+	messagingService service.MessagingService,
+	crawlerService *appservice.CrawlerService,
+	blockWriter *appservice.BlockWriter,
+	addressService *appservice.AddressService,
+	tokenTransferService *appservice.TokenTransferService,
+	tokenMetadata *appservice.TokenMetadataService,
+	workerService *appservice.WorkerService,
+) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			logger.Info("Starting Ethereum Crawler Worker",
+				zap.String("version", "1.0.0"),
+				zap.String("network", cfg.Ethereum.Network))
+
+			// Create database indexes
+			if err := db.CreateIndexes(ctx); err != nil {
+				logger.Error("Failed to create database indexes", zap.Error(err))
+				return err
+			}
+
+			// Apply PostgreSQL migrations when it is the storage backend
+			logger.Info("Using storage backend", zap.String("backend", storage.Backend()))
+			if err := storage.Migrate(ctx); err != nil {
+				logger.Error("Failed to migrate storage", zap.Error(err))
+				return err
+			}
+
+			// Connect to messaging service (only if enabled)
+			if cfg.NATS.Enabled {
+				logger.Info("NATS is enabled, connecting to messaging service")
+				if err := messagingService.Connect(ctx); err != nil {
+					logger.Error("Failed to connect to messaging service", zap.Error(err))
+					return err
+				}
+			} else {
+				logger.Info("NATS is disabled, skipping messaging service connection")
+			}
+
+			// Blocks come from claimed work ranges, not the crawler's own worker.
+			// The checkpoint is left to the scheduler, workers see ranges out of order.
+			crawlerService.SetExternalSchedulerMode(true)
+
+			// Keep the raw block and receipts of every processed block
+			if cfg.Archive.Enabled {
+				archive, err := secondary.NewBlockArchiveRepository(db, cfg)
+				if err != nil {
+					logger.Error("Failed to open block archive", zap.Error(err))
+					return err
+				}
+				crawlerService.SetBlockArchive(archive)
+			}
+
+			// Commit blocks in bulk across blocks, meant for backfills
+			if cfg.Crawler.BufferedWrites {
+				if err := blockWriter.Start(ctx); err != nil {
+					logger.Error("Failed to start block writer", zap.Error(err))
+					return err
+				}
+				crawlerService.SetBlockWriter(blockWriter)
+			}
+
+			// Record first-seen and last-active blocks and contracts of addresses
+			if cfg.Addresses.Enabled {
+				crawlerService.SetAddressService(addressService)
+			}
+
+			// Store and publish the token transfers decoded from receipt logs
+			if cfg.TokenTransfers.Enabled {
+				crawlerService.SetTokenTransferService(tokenTransferService)
+				// Tokens seen for the first time are added to the metadata cache, their
+				// metadata is read by the scheduler leader
+				if cfg.Tokens.Enabled {
+					tokenTransferService.SetTokenMetadataService(tokenMetadata)
+				}
+			}
+
+			if err := crawlerService.Start(ctx); err != nil {
+				logger.Error("Failed to start crawler service", zap.Error(err))
+				return err
+			}
+
+			if err := workerService.Start(ctx); err != nil {
+				logger.Error("Failed to start worker service", zap.Error(err))
+				return err
+			}
+			crawlerService.RegisterHealthComponent("worker", workerService.HealthCheck)
+
+			logger.Info("Ethereum Crawler Worker started successfully")
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Stopping Ethereum Crawler Worker")
+
+			// Stop claiming first, the range in progress is released
+			if err := workerService.Stop(); err != nil {
+				logger.Error("Error stopping worker service", zap.Error(err))
+			}
+
+			if err := crawlerService.Stop(ctx); err != nil {
+				logger.Error("Error stopping crawler service", zap.Error(err))
+			}
+
+			// Flush blocks still buffered by the stopped crawls
+			if err := blockWriter.Stop(); err != nil {
+				logger.Error("Error stopping block writer", zap.Error(err))
+			}
+
+			// Disconnect from messaging service (only if it was connected)
+			if cfg.NATS.Enabled {
+				if err := messagingService.Disconnect(); err != nil {
+					logger.Error("Error disconnecting from messaging service", zap.Error(err))
+				}
+			}
+
+			// Close database connection
+			if err := db.Close(ctx); err != nil {
+				logger.Error("Error closing database connection", zap.Error(err))
+			}
+			storage.Close()
+
+			// Sync logger
+			if err := logger.Sync(); err != nil {
+				// Ignore errors on sync as this is expected on some systems
+			}
+
+			logger.Info("Ethereum Crawler Worker stopped")
+			return nil
+		},
+	})
+}
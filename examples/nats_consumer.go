@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"ethereum-raw-data-crawler/pkg/consumer"
+	"ethereum-raw-data-crawler/pkg/events"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -14,21 +16,6 @@ import (
 	"github.com/nats-io/nats.go"
 )
 
-// TransactionEvent represents a transaction event received from NATS
-type TransactionEvent struct {
-	Hash        string    `json:"hash"`
-	From        string    `json:"from"`
-	To          string    `json:"to"`
-	Value       string    `json:"value"`
-	Data        string    `json:"data"`
-	BlockNumber string    `json:"block_number"`
-	BlockHash   string    `json:"block_hash"`
-	Timestamp   time.Time `json:"timestamp"`
-	GasUsed     string    `json:"gas_used"`
-	GasPrice    string    `json:"gas_price"`
-	Network     string    `json:"network"`
-}
-
 func main() {
 	// Configuration
 	natsURL := getEnv("NATS_URL", "nats://localhost:4222")
@@ -60,53 +47,32 @@ func main() {
 	}
 	defer nc.Close()
 
-	// Create JetStream context
-	js, err := nc.JetStream()
-	if err != nil {
-		log.Fatalf("Failed to create JetStream context: %v", err)
-	}
-
-	// Check if stream exists
-	subject := fmt.Sprintf("%s.events", subjectPrefix)
-	streamInfo, err := js.StreamInfo(streamName)
+	// Create consumer. START_BLOCK only applies when the durable consumer is first created.
+	startBlock, _ := strconv.ParseUint(getEnv("START_BLOCK", "0"), 10, 64)
+	c, err := consumer.New(nc, consumer.Config{
+		Stream:     streamName,
+		Subject:    fmt.Sprintf("%s.events", subjectPrefix),
+		Durable:    consumerName,
+		StartBlock: startBlock,
+		MaxDeliver: 3,
+		AckWait:    30 * time.Second,
+		ErrorHandler: func(msg *nats.Msg, err error) {
+			log.Printf("Consumer error: %v", err)
+		},
+	})
 	if err != nil {
-		log.Fatalf("Stream %s does not exist: %v", streamName, err)
+		log.Fatalf("Failed to create consumer: %v", err)
 	}
 
-	fmt.Printf("Stream Info:\n")
-	fmt.Printf("  Name: %s\n", streamInfo.Config.Name)
-	fmt.Printf("  Subjects: %v\n", streamInfo.Config.Subjects)
-	fmt.Printf("  Messages: %d\n", streamInfo.State.Msgs)
-	fmt.Printf("  Bytes: %d\n", streamInfo.State.Bytes)
-	fmt.Printf("  Last Message Time: %v\n", streamInfo.State.LastTime)
-	fmt.Println()
-
-	// Create or get consumer
-	consumerConfig := &nats.ConsumerConfig{
-		Durable:       consumerName,
-		AckPolicy:     nats.AckExplicitPolicy,
-		FilterSubject: subject,
-		MaxDeliver:    3,
-		AckWait:       30 * time.Second,
-		ReplayPolicy:  nats.ReplayInstantPolicy, // Start from now
+	if info, err := c.Info(); err == nil {
+		fmt.Printf("Consumer Info:\n")
+		fmt.Printf("  Name: %s\n", info.Name)
+		fmt.Printf("  Filter Subject: %s\n", info.Config.FilterSubject)
+		fmt.Printf("  Pending Messages: %d\n", info.NumPending)
+		fmt.Printf("  Delivered Messages: %d\n", info.Delivered.Consumer)
+		fmt.Println()
 	}
 
-	consumer, err := js.AddConsumer(streamName, consumerConfig)
-	if err != nil {
-		// Consumer might already exist, try to get it
-		consumer, err = js.ConsumerInfo(streamName, consumerName)
-		if err != nil {
-			log.Fatalf("Failed to create or get consumer: %v", err)
-		}
-	}
-
-	fmt.Printf("Consumer Info:\n")
-	fmt.Printf("  Name: %s\n", consumer.Name)
-	fmt.Printf("  Filter Subject: %s\n", consumer.Config.FilterSubject)
-	fmt.Printf("  Pending Messages: %d\n", consumer.NumPending)
-	fmt.Printf("  Delivered Messages: %d\n", consumer.Delivered.Consumer)
-	fmt.Println()
-
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -120,65 +86,37 @@ func main() {
 		cancel()
 	}()
 
-	// Subscribe to messages
-	fmt.Printf("Starting to consume transaction events from subject: %s\n", subject)
-	fmt.Println("Press Ctrl+C to stop...")
+	fmt.Println("Starting to consume transaction events, press Ctrl+C to stop...")
 	fmt.Println(strings.Repeat("-", 80))
 
 	messageCount := 0
-	subscription, err := js.PullSubscribe(subject, consumerName)
+	err = c.Run(ctx, func(ctx context.Context, event *consumer.Event) error {
+		messageCount++
+		txEvent := event.Transaction
+
+		fmt.Printf("[%d] Transaction Event Received:\n", messageCount)
+		fmt.Printf("  Hash: %s\n", txEvent.Hash)
+		fmt.Printf("  From: %s\n", txEvent.From)
+		fmt.Printf("  To: %s\n", txEvent.To)
+		fmt.Printf("  Value: %s wei\n", txEvent.Value)
+		fmt.Printf("  Block: %d\n", event.BlockNumber)
+		fmt.Printf("  Network: %s\n", event.Network)
+		fmt.Printf("  Gas Used: %d\n", txEvent.GasUsed)
+		fmt.Printf("  Gas Price: %s\n", txEvent.GasPrice)
+		fmt.Printf("  Timestamp: %s\n", txEvent.Timestamp.AsTime().Format(time.RFC3339))
+		fmt.Printf("  Content-Type: %s\n", event.Msg.Header.Get(events.HeaderContentType))
+		fmt.Printf("  Delivery: %d\n", event.NumDelivered)
+		fmt.Println(strings.Repeat("-", 80))
+
+		// Returning an error redelivers the event with backoff
+		return nil
+	})
 	if err != nil {
-		log.Fatalf("Failed to create pull subscription: %v", err)
+		log.Printf("Consumer stopped: %v", err)
 	}
 
-	// Start consuming messages
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Println("Context cancelled, stopping consumer")
-			return
-		default:
-			// Fetch messages
-			msgs, err := subscription.Fetch(10, nats.MaxWait(1*time.Second))
-			if err != nil {
-				if err == nats.ErrTimeout {
-					continue // No messages available, continue polling
-				}
-				log.Printf("Error fetching messages: %v", err)
-				continue
-			}
-
-			for _, msg := range msgs {
-				messageCount++
-
-				// Parse transaction event
-				var txEvent TransactionEvent
-				if err := json.Unmarshal(msg.Data, &txEvent); err != nil {
-					log.Printf("Failed to unmarshal transaction event: %v", err)
-					msg.Nak() // Negative acknowledgment
-					continue
-				}
-
-				// Process transaction event
-				fmt.Printf("[%d] Transaction Event Received:\n", messageCount)
-				fmt.Printf("  Hash: %s\n", txEvent.Hash)
-				fmt.Printf("  From: %s\n", txEvent.From)
-				fmt.Printf("  To: %s\n", txEvent.To)
-				fmt.Printf("  Value: %s wei\n", txEvent.Value)
-				fmt.Printf("  Block: %s\n", txEvent.BlockNumber)
-				fmt.Printf("  Network: %s\n", txEvent.Network)
-				fmt.Printf("  Gas Used: %s\n", txEvent.GasUsed)
-				fmt.Printf("  Gas Price: %s\n", txEvent.GasPrice)
-				fmt.Printf("  Timestamp: %s\n", txEvent.Timestamp.Format(time.RFC3339))
-				fmt.Printf("  Subject: %s\n", msg.Subject)
-				fmt.Println(strings.Repeat("-", 80))
-
-				// Acknowledge message
-				if err := msg.Ack(); err != nil {
-					log.Printf("Failed to acknowledge message: %v", err)
-				}
-			}
-		}
+	if err := c.Drain(); err != nil {
+		log.Printf("Failed to drain consumer: %v", err)
 	}
 }
 
@@ -0,0 +1,460 @@
+package primary
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SchedulerController is the part of the scheduler controlled by the admin interface
+type SchedulerController interface {
+	Pause() error
+	Resume() error
+	SetMode(mode appservice.SchedulerMode) error
+	SetPollingInterval(interval time.Duration) error
+	GetStats() map[string]interface{}
+}
+
+// CrawlRequester queues on-demand crawl jobs ahead of routine work
+type CrawlRequester interface {
+	Submit(request appservice.CrawlRequest, source string) (*appservice.CrawlJobResult, error)
+	Wait(ctx context.Context, id string) (*appservice.CrawlJobResult, error)
+	GetJob(id string) (*appservice.CrawlJobResult, error)
+	RequestTimeout() time.Duration
+}
+
+// BlockResolver resolves timestamps to blocks
+type BlockResolver interface {
+	ResolveBlock(ctx context.Context, at time.Time, search appservice.BlockSearch) (*entity.Block, error)
+}
+
+// AddressActivity pages through and counts the transactions of addresses
+type AddressActivity interface {
+	ListTransactions(ctx context.Context, req appservice.AddressActivityRequest) (*appservice.AddressActivityPage, error)
+	CountTransactions(ctx context.Context, address string) (*entity.AddressTransactionCount, error)
+}
+
+// TokenResolver reads the metadata of tokens on request
+type TokenResolver interface {
+	ResolveToken(ctx context.Context, address string) (*entity.Token, error)
+}
+
+// AdminServer exposes scheduler runtime control and on-demand crawls over HTTP
+type AdminServer struct {
+	scheduler SchedulerController
+	crawls    CrawlRequester
+	blocks    BlockResolver
+	addresses AddressActivity
+	tokens    TokenResolver // Nil when token metadata is disabled
+	config    *config.AdminConfig
+	logger    *logger.Logger
+	server    *http.Server
+	mu        sync.Mutex
+}
+
+// NewAdminServer creates a new admin server
+func NewAdminServer(
+	schedulerService *appservice.SchedulerService,
+	priorityCrawls *appservice.PriorityCrawlService,
+	blockTimes *appservice.BlockTimeService,
+	addressActivity *appservice.AddressActivityService,
+	tokenMetadata *appservice.TokenMetadataService,
+	config *config.Config,
+	logger *logger.Logger,
+) *AdminServer {
+	var tokens TokenResolver
+	if config.TokenTransfers.Enabled && config.Tokens.Enabled {
+		tokens = tokenMetadata
+	}
+	return newAdminServer(schedulerService, priorityCrawls, blockTimes, addressActivity, tokens, &config.Admin, logger)
+}
+
+func newAdminServer(
+	scheduler SchedulerController,
+	crawls CrawlRequester,
+	blocks BlockResolver,
+	addresses AddressActivity,
+	tokens TokenResolver,
+	config *config.AdminConfig,
+	logger *logger.Logger,
+) *AdminServer {
+	return &AdminServer{
+		scheduler: scheduler,
+		crawls:    crawls,
+		blocks:    blocks,
+		addresses: addresses,
+		tokens:    tokens,
+		config:    config,
+		logger:    logger.WithComponent("admin-server"),
+	}
+}
+
+// Start listens on the configured address
+func (s *AdminServer) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		return fmt.Errorf("admin server is already running")
+	}
+
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.config.Addr, err)
+	}
+
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Admin server stopped", zap.Error(err))
+		}
+	}()
+
+	if s.config.Token == "" {
+		s.logger.Warn("Admin server has no token, restrict access to the listen address")
+	}
+	s.logger.Info("Admin server started", zap.String("addr", listener.Addr().String()))
+	return nil
+}
+
+// Stop shuts the server down
+func (s *AdminServer) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server == nil {
+		return nil
+	}
+
+	err := s.server.Shutdown(ctx)
+	s.server = nil
+	s.logger.Info("Admin server stopped")
+	return err
+}
+
+// Handler returns the admin routes
+func (s *AdminServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/scheduler", s.handleStats)
+	mux.HandleFunc("POST /admin/scheduler/pause", s.handlePause)
+	mux.HandleFunc("POST /admin/scheduler/resume", s.handleResume)
+	mux.HandleFunc("POST /admin/scheduler/mode", s.handleMode)
+	mux.HandleFunc("POST /admin/scheduler/polling-interval", s.handlePollingInterval)
+	mux.HandleFunc("POST /admin/crawl", s.handleCrawl)
+	mux.HandleFunc("GET /admin/crawl/{id}", s.handleCrawlJob)
+	mux.HandleFunc("GET /admin/blocks/at", s.handleBlockAt)
+	mux.HandleFunc("GET /admin/addresses/{address}/transactions", s.handleAddressTransactions)
+	mux.HandleFunc("GET /admin/addresses/{address}/count", s.handleAddressCount)
+	mux.HandleFunc("POST /admin/tokens/{address}/refresh", s.handleTokenRefresh)
+	return s.authenticate(mux)
+}
+
+// authenticate rejects requests without the configured bearer token
+func (s *AdminServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.Token != "" {
+			expected := "Bearer " + s.config.Token
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *AdminServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"stats": s.scheduler.GetStats()})
+}
+
+func (s *AdminServer) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.apply(w, "pause", s.scheduler.Pause)
+}
+
+func (s *AdminServer) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.apply(w, "resume", s.scheduler.Resume)
+}
+
+func (s *AdminServer) handleMode(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Mode string `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	mode, err := appservice.ParseSchedulerMode(request.Mode)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.apply(w, "set_mode", func() error { return s.scheduler.SetMode(mode) })
+}
+
+func (s *AdminServer) handlePollingInterval(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Interval string `json:"interval"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	interval, err := time.ParseDuration(request.Interval)
+	if err != nil || interval <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid interval: %q", request.Interval)})
+		return
+	}
+
+	s.apply(w, "set_polling_interval", func() error { return s.scheduler.SetPollingInterval(interval) })
+}
+
+// handleCrawl queues a priority crawl job. The reply waits for the outcome
+// unless the request is async or the job outlives the request timeout, then
+// it is 202 Accepted and the job can be polled by id.
+func (s *AdminServer) handleCrawl(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		appservice.CrawlRequest
+		Async bool `json:"async"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	job, err := s.crawls.Submit(request.CrawlRequest, "admin")
+	if err != nil {
+		s.logger.Warn("Crawl request rejected", zap.Error(err))
+		writeJSON(w, crawlErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	if request.Async {
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"job": job})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.crawls.RequestTimeout())
+	defer cancel()
+
+	result, err := s.crawls.Wait(ctx, job.ID)
+	if err != nil {
+		if result, err = s.crawls.GetJob(job.ID); err == nil {
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{"job": result})
+			return
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job": result})
+}
+
+func (s *AdminServer) handleCrawlJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.crawls.GetJob(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job": job})
+}
+
+// handleBlockAt resolves a timestamp, RFC 3339 or unix seconds, to a block
+func (s *AdminServer) handleBlockAt(w http.ResponseWriter, r *http.Request) {
+	at, err := parseTimestamp(r.URL.Query().Get("timestamp"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	search, err := appservice.ParseBlockSearch(r.URL.Query().Get("closest"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	block, err := s.blocks.ResolveBlock(r.Context(), at, search)
+	if errors.Is(err, appservice.ErrNoBlockAtTime) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.logger.Warn("Failed to resolve block at time", zap.Time("timestamp", at), zap.Error(err))
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"block_number": block.Number,
+		"block_hash":   block.Hash,
+		"timestamp":    block.Timestamp.UTC(),
+	})
+}
+
+// parseTimestamp parses an RFC 3339 time or unix seconds
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("timestamp is required")
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q, expected RFC 3339 or unix seconds", value)
+	}
+	return at, nil
+}
+
+// handleAddressTransactions returns a page of the transactions of an address
+func (s *AdminServer) handleAddressTransactions(w http.ResponseWriter, r *http.Request) {
+	req, err := parseAddressActivityRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	page, err := s.addresses.ListTransactions(r.Context(), req)
+	if err != nil {
+		writeJSON(w, addressActivityErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleAddressCount returns the transaction counters of an address
+func (s *AdminServer) handleAddressCount(w http.ResponseWriter, r *http.Request) {
+	count, err := s.addresses.CountTransactions(r.Context(), r.PathValue("address"))
+	if err != nil {
+		writeJSON(w, addressActivityErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, count)
+}
+
+// handleTokenRefresh reads all the metadata of a token again
+func (s *AdminServer) handleTokenRefresh(w http.ResponseWriter, r *http.Request) {
+	if s.tokens == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "token metadata is disabled"})
+		return
+	}
+
+	address := r.PathValue("address")
+	token, err := s.tokens.ResolveToken(r.Context(), address)
+	if err != nil {
+		s.logger.Warn("Failed to refresh token", zap.String("token", address), zap.Error(err))
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, token)
+}
+
+// parseAddressActivityRequest reads the address from the path and the
+// filters and page from the query
+func parseAddressActivityRequest(r *http.Request) (appservice.AddressActivityRequest, error) {
+	query := r.URL.Query()
+	req := appservice.AddressActivityRequest{Cursor: query.Get("cursor")}
+	req.Address = r.PathValue("address")
+	req.Direction = repository.AddressDirection(query.Get("direction"))
+
+	switch status := query.Get("status"); status {
+	case "":
+	case "success", "failed":
+		success := status == "success"
+		req.Success = &success
+	default:
+		return req, fmt.Errorf("invalid status %q, expected success or failed", status)
+	}
+
+	var err error
+	if req.ContractCreation, err = parseOptionalBool(query.Get("contract_creation")); err != nil {
+		return req, fmt.Errorf("invalid contract_creation: %w", err)
+	}
+	if req.FromBlock, err = parseOptionalUint(query.Get("from_block")); err != nil {
+		return req, fmt.Errorf("invalid from_block: %w", err)
+	}
+	if req.ToBlock, err = parseOptionalUint(query.Get("to_block")); err != nil {
+		return req, fmt.Errorf("invalid to_block: %w", err)
+	}
+	if value := query.Get("limit"); value != "" {
+		if req.Limit, err = strconv.Atoi(value); err != nil || req.Limit <= 0 {
+			return req, fmt.Errorf("invalid limit %q", value)
+		}
+	}
+	return req, nil
+}
+
+// parseOptionalBool parses a boolean query parameter, nil when absent
+func parseOptionalBool(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// parseOptionalUint parses a block number query parameter, nil when absent
+func parseOptionalUint(value string) (*uint64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// addressActivityErrorStatus maps a failed address activity request to its HTTP status
+func addressActivityErrorStatus(err error) int {
+	if errors.Is(err, appservice.ErrInvalidAddressActivityRequest) {
+		return http.StatusBadRequest
+	}
+	return http.StatusServiceUnavailable
+}
+
+// crawlErrorStatus maps a rejected crawl request to its HTTP status
+func crawlErrorStatus(err error) int {
+	if errors.Is(err, appservice.ErrInvalidCrawlRequest) {
+		return http.StatusBadRequest
+	}
+	return http.StatusServiceUnavailable // Queue full or service stopped
+}
+
+// apply runs a control operation and responds with the resulting stats
+func (s *AdminServer) apply(w http.ResponseWriter, operation string, fn func() error) {
+	if err := fn(); err != nil {
+		s.logger.Warn("Admin operation failed", zap.String("operation", operation), zap.Error(err))
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("Admin operation applied", zap.String("operation", operation))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"stats": s.scheduler.GetStats()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,418 @@
+package primary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSchedulerController struct {
+	paused   bool
+	mode     appservice.SchedulerMode
+	interval time.Duration
+	modeErr  error
+}
+
+func (f *fakeSchedulerController) Pause() error  { f.paused = true; return nil }
+func (f *fakeSchedulerController) Resume() error { f.paused = false; return nil }
+
+func (f *fakeSchedulerController) SetMode(mode appservice.SchedulerMode) error {
+	if f.modeErr != nil {
+		return f.modeErr
+	}
+	f.mode = mode
+	return nil
+}
+
+func (f *fakeSchedulerController) SetPollingInterval(interval time.Duration) error {
+	f.interval = interval
+	return nil
+}
+
+func (f *fakeSchedulerController) GetStats() map[string]interface{} {
+	return map[string]interface{}{"paused": f.paused, "mode": string(f.mode)}
+}
+
+// fakeCrawlRequester completes jobs on Wait, failing the configured blocks
+type fakeCrawlRequester struct {
+	jobs      map[string]*appservice.CrawlJobResult
+	fail      map[uint64]bool
+	submitErr error
+	hang      bool // Wait never completes
+}
+
+func newFakeCrawlRequester() *fakeCrawlRequester {
+	return &fakeCrawlRequester{jobs: map[string]*appservice.CrawlJobResult{}, fail: map[uint64]bool{}}
+}
+
+func (f *fakeCrawlRequester) Submit(request appservice.CrawlRequest, source string) (*appservice.CrawlJobResult, error) {
+	if f.submitErr != nil {
+		return nil, f.submitErr
+	}
+	if request.Block == nil {
+		return nil, fmt.Errorf("%w: block is required", appservice.ErrInvalidCrawlRequest)
+	}
+	job := &appservice.CrawlJobResult{
+		ID:        fmt.Sprintf("job-%d", len(f.jobs)+1),
+		Type:      appservice.CrawlJobBlock,
+		Source:    source,
+		FromBlock: *request.Block,
+		ToBlock:   *request.Block,
+		Status:    appservice.CrawlJobQueued,
+	}
+	f.jobs[job.ID] = job
+	result := *job
+	return &result, nil
+}
+
+func (f *fakeCrawlRequester) Wait(ctx context.Context, id string) (*appservice.CrawlJobResult, error) {
+	job, ok := f.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("crawl job %s not found", id)
+	}
+	if f.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if f.fail[job.FromBlock] {
+		job.Status = appservice.CrawlJobFailed
+		job.Error = "1 of 1 blocks failed"
+		job.FailedBlocks = []uint64{job.FromBlock}
+	} else {
+		job.Status = appservice.CrawlJobCompleted
+		job.BlocksProcessed = 1
+	}
+	return f.GetJob(id)
+}
+
+func (f *fakeCrawlRequester) GetJob(id string) (*appservice.CrawlJobResult, error) {
+	job, ok := f.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("crawl job %s not found", id)
+	}
+	result := *job
+	return &result, nil
+}
+
+func (f *fakeCrawlRequester) RequestTimeout() time.Duration {
+	return 50 * time.Millisecond
+}
+
+// fakeBlockResolver resolves every timestamp to block 100 unless err is set
+type fakeBlockResolver struct {
+	at     time.Time
+	search appservice.BlockSearch
+	err    error
+}
+
+func (f *fakeBlockResolver) ResolveBlock(ctx context.Context, at time.Time, search appservice.BlockSearch) (*entity.Block, error) {
+	f.at, f.search = at, search
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &entity.Block{Number: "100", Hash: "0xb100", Timestamp: at}, nil
+}
+
+// fakeAddressActivity records the last request and returns one page
+type fakeAddressActivity struct {
+	req appservice.AddressActivityRequest
+	err error
+}
+
+func (f *fakeAddressActivity) ListTransactions(ctx context.Context, req appservice.AddressActivityRequest) (*appservice.AddressActivityPage, error) {
+	f.req = req
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &appservice.AddressActivityPage{
+		Transactions: []*entity.Transaction{{Hash: "0x1", From: req.Address}},
+		NextCursor:   "next",
+	}, nil
+}
+
+func (f *fakeAddressActivity) CountTransactions(ctx context.Context, address string) (*entity.AddressTransactionCount, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &entity.AddressTransactionCount{Address: address, Total: 3, Sent: 2, Received: 1}, nil
+}
+
+func newTestAdminServer(t *testing.T, token string) (*fakeSchedulerController, http.Handler) {
+	log, err := logger.NewLogger(&config.Config{App: config.AppConfig{LogLevel: "error"}})
+	require.NoError(t, err)
+
+	controller := &fakeSchedulerController{mode: appservice.HybridMode}
+	server := newAdminServer(controller, newFakeCrawlRequester(), &fakeBlockResolver{}, &fakeAddressActivity{}, nil, &config.AdminConfig{Token: token}, log)
+	return controller, server.Handler()
+}
+
+func doRequest(handler http.Handler, method, path, body, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec, response
+}
+
+func TestAdminServer_Token(t *testing.T) {
+	_, handler := newTestAdminServer(t, "secret")
+
+	rec, _ := doRequest(handler, http.MethodGet, "/admin/scheduler", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec, _ = doRequest(handler, http.MethodGet, "/admin/scheduler", "", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec, response := doRequest(handler, http.MethodGet, "/admin/scheduler", "", "secret")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, response, "stats")
+}
+
+func TestAdminServer_PauseResume(t *testing.T) {
+	controller, handler := newTestAdminServer(t, "")
+
+	rec, response := doRequest(handler, http.MethodPost, "/admin/scheduler/pause", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, controller.paused)
+	assert.Equal(t, true, response["stats"].(map[string]interface{})["paused"])
+
+	rec, _ = doRequest(handler, http.MethodPost, "/admin/scheduler/resume", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, controller.paused)
+
+	rec, _ = doRequest(handler, http.MethodGet, "/admin/scheduler/pause", "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestAdminServer_Mode(t *testing.T) {
+	controller, handler := newTestAdminServer(t, "")
+
+	rec, _ := doRequest(handler, http.MethodPost, "/admin/scheduler/mode", `{"mode":"polling"}`, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, appservice.PollingMode, controller.mode)
+
+	rec, response := doRequest(handler, http.MethodPost, "/admin/scheduler/mode", `{"mode":"fast"}`, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, response["error"], "unknown scheduler mode")
+
+	controller.modeErr = errors.New("realtime mode requires a block scheduler")
+	rec, response = doRequest(handler, http.MethodPost, "/admin/scheduler/mode", `{"mode":"realtime"}`, "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "realtime mode requires a block scheduler", response["error"])
+}
+
+func TestAdminServer_PollingInterval(t *testing.T) {
+	controller, handler := newTestAdminServer(t, "")
+
+	rec, _ := doRequest(handler, http.MethodPost, "/admin/scheduler/polling-interval", `{"interval":"1500ms"}`, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1500*time.Millisecond, controller.interval)
+
+	for _, body := range []string{`{"interval":"soon"}`, `{"interval":"-1s"}`, `not json`} {
+		rec, _ = doRequest(handler, http.MethodPost, "/admin/scheduler/polling-interval", body, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
+
+func newTestCrawlAdminServer(t *testing.T) (*fakeCrawlRequester, http.Handler) {
+	log, err := logger.NewLogger(&config.Config{App: config.AppConfig{LogLevel: "error"}})
+	require.NoError(t, err)
+
+	crawls := newFakeCrawlRequester()
+	server := newAdminServer(&fakeSchedulerController{}, crawls, &fakeBlockResolver{}, &fakeAddressActivity{}, nil, &config.AdminConfig{}, log)
+	return crawls, server.Handler()
+}
+
+func TestAdminServer_Crawl(t *testing.T) {
+	crawls, handler := newTestCrawlAdminServer(t)
+	crawls.fail[13] = true
+
+	rec, response := doRequest(handler, http.MethodPost, "/admin/crawl", `{"block":12}`, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	job := response["job"].(map[string]interface{})
+	assert.Equal(t, "completed", job["status"])
+	assert.Equal(t, "admin", job["source"])
+
+	// A failed job is still a completed request, the outcome is in the job
+	rec, response = doRequest(handler, http.MethodPost, "/admin/crawl", `{"block":13}`, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	job = response["job"].(map[string]interface{})
+	assert.Equal(t, "failed", job["status"])
+	assert.Equal(t, []interface{}{float64(13)}, job["failed_blocks"])
+
+	rec, response = doRequest(handler, http.MethodPost, "/admin/crawl", `{"block":14,"async":true}`, "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	id := response["job"].(map[string]interface{})["id"].(string)
+	rec, response = doRequest(handler, http.MethodGet, "/admin/crawl/"+id, "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "queued", response["job"].(map[string]interface{})["status"])
+
+	rec, _ = doRequest(handler, http.MethodGet, "/admin/crawl/missing", "", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAdminServer_CrawlRejected(t *testing.T) {
+	crawls, handler := newTestCrawlAdminServer(t)
+
+	rec, _ := doRequest(handler, http.MethodPost, "/admin/crawl", `not json`, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec, response := doRequest(handler, http.MethodPost, "/admin/crawl", `{"tx_hash":"0x12"}`, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, response["error"], "invalid crawl request")
+
+	crawls.submitErr = appservice.ErrCrawlQueueFull
+	rec, _ = doRequest(handler, http.MethodPost, "/admin/crawl", `{"block":1}`, "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestAdminServer_CrawlTimeout(t *testing.T) {
+	crawls, handler := newTestCrawlAdminServer(t)
+	crawls.hang = true
+
+	// The job outlives the request timeout, the reply tells where it is
+	rec, response := doRequest(handler, http.MethodPost, "/admin/crawl", `{"block":12}`, "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "queued", response["job"].(map[string]interface{})["status"])
+}
+
+func TestAdminServer_BlockAt(t *testing.T) {
+	log, err := logger.NewLogger(&config.Config{App: config.AppConfig{LogLevel: "error"}})
+	require.NoError(t, err)
+	blocks := &fakeBlockResolver{}
+	handler := newAdminServer(&fakeSchedulerController{}, newFakeCrawlRequester(), blocks, &fakeAddressActivity{}, nil, &config.AdminConfig{}, log).Handler()
+
+	rec, response := doRequest(handler, http.MethodGet, "/admin/blocks/at?timestamp=2024-01-02T03:04:05Z&closest=before", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "100", response["block_number"])
+	assert.Equal(t, "0xb100", response["block_hash"])
+	assert.True(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Equal(blocks.at))
+	assert.Equal(t, appservice.BlockAtOrBefore, blocks.search)
+
+	rec, _ = doRequest(handler, http.MethodGet, "/admin/blocks/at?timestamp=1700000000", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(1_700_000_000), blocks.at.Unix())
+	assert.Equal(t, appservice.BlockNearest, blocks.search)
+
+	for _, query := range []string{"", "?timestamp=yesterday", "?timestamp=1700000000&closest=closest"} {
+		rec, _ = doRequest(handler, http.MethodGet, "/admin/blocks/at"+query, "", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+
+	blocks.err = fmt.Errorf("%w: after the latest block", appservice.ErrNoBlockAtTime)
+	rec, _ = doRequest(handler, http.MethodGet, "/admin/blocks/at?timestamp=1700000000&closest=after", "", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	blocks.err = errors.New("rpc unavailable")
+	rec, _ = doRequest(handler, http.MethodGet, "/admin/blocks/at?timestamp=1700000000", "", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestAdminServer_AddressTransactions(t *testing.T) {
+	log, err := logger.NewLogger(&config.Config{App: config.AppConfig{LogLevel: "error"}})
+	require.NoError(t, err)
+	addresses := &fakeAddressActivity{}
+	handler := newAdminServer(&fakeSchedulerController{}, newFakeCrawlRequester(), &fakeBlockResolver{}, addresses, nil, &config.AdminConfig{}, log).Handler()
+
+	rec, response := doRequest(handler, http.MethodGet,
+		"/admin/addresses/0xalice/transactions?direction=out&status=failed&contract_creation=true&from_block=10&to_block=20&limit=5&cursor=abc", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "next", response["next_cursor"])
+	assert.Len(t, response["transactions"], 1)
+
+	req := addresses.req
+	assert.Equal(t, "0xalice", req.Address)
+	assert.Equal(t, repository.AddressDirectionOut, req.Direction)
+	require.NotNil(t, req.Success)
+	assert.False(t, *req.Success)
+	require.NotNil(t, req.ContractCreation)
+	assert.True(t, *req.ContractCreation)
+	assert.Equal(t, uint64(10), *req.FromBlock)
+	assert.Equal(t, uint64(20), *req.ToBlock)
+	assert.Equal(t, 5, req.Limit)
+	assert.Equal(t, "abc", req.Cursor)
+
+	rec, _ = doRequest(handler, http.MethodGet, "/admin/addresses/0xalice/transactions", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, addresses.req.Success)
+	assert.Nil(t, addresses.req.FromBlock)
+
+	for _, query := range []string{"status=ok", "contract_creation=maybe", "from_block=-1", "limit=0"} {
+		rec, _ = doRequest(handler, http.MethodGet, "/admin/addresses/0xalice/transactions?"+query, "", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+
+	addresses.err = fmt.Errorf("%w: invalid cursor", appservice.ErrInvalidAddressActivityRequest)
+	rec, _ = doRequest(handler, http.MethodGet, "/admin/addresses/0xalice/transactions?cursor=bad", "", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	addresses.err = errors.New("database unavailable")
+	rec, _ = doRequest(handler, http.MethodGet, "/admin/addresses/0xalice/transactions", "", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestAdminServer_AddressCount(t *testing.T) {
+	log, err := logger.NewLogger(&config.Config{App: config.AppConfig{LogLevel: "error"}})
+	require.NoError(t, err)
+	handler := newAdminServer(&fakeSchedulerController{}, newFakeCrawlRequester(), &fakeBlockResolver{}, &fakeAddressActivity{}, nil, &config.AdminConfig{}, log).Handler()
+
+	rec, response := doRequest(handler, http.MethodGet, "/admin/addresses/0xalice/count", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0xalice", response["address"])
+	assert.Equal(t, float64(3), response["total"])
+	assert.Equal(t, float64(2), response["sent"])
+	assert.Equal(t, float64(1), response["received"])
+}
+
+// fakeTokenResolver resolves tokens to a fixed symbol
+type fakeTokenResolver struct {
+	resolved []string
+	err      error
+}
+
+func (f *fakeTokenResolver) ResolveToken(ctx context.Context, address string) (*entity.Token, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.resolved = append(f.resolved, address)
+	return &entity.Token{Address: address, Symbol: "CN"}, nil
+}
+
+func TestAdminServer_TokenRefresh(t *testing.T) {
+	log, err := logger.NewLogger(&config.Config{App: config.AppConfig{LogLevel: "error"}})
+	require.NoError(t, err)
+	tokens := &fakeTokenResolver{}
+	handler := newAdminServer(&fakeSchedulerController{}, newFakeCrawlRequester(), &fakeBlockResolver{}, &fakeAddressActivity{}, tokens, &config.AdminConfig{}, log).Handler()
+
+	rec, response := doRequest(handler, http.MethodPost, "/admin/tokens/0xcoin/refresh", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "CN", response["symbol"])
+	assert.Equal(t, []string{"0xcoin"}, tokens.resolved)
+
+	tokens.err = errors.New("request timed out")
+	rec, _ = doRequest(handler, http.MethodPost, "/admin/tokens/0xcoin/refresh", "", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	// Without token metadata
+	handler = newAdminServer(&fakeSchedulerController{}, newFakeCrawlRequester(), &fakeBlockResolver{}, &fakeAddressActivity{}, nil, &config.AdminConfig{}, log).Handler()
+	rec, _ = doRequest(handler, http.MethodPost, "/admin/tokens/0xcoin/refresh", "", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,127 @@
+package primary
+
+import (
+	"context"
+	"encoding/json"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// crawlReply is the reply to a crawl request sent over NATS
+type crawlReply struct {
+	Job   *appservice.CrawlJobResult `json:"job,omitempty"`
+	Error string                     `json:"error,omitempty"`
+}
+
+// CrawlRequestHandler serves priority crawl requests sent to a NATS request
+// subject. The reply is sent once the job finished.
+type CrawlRequestHandler struct {
+	requests service.RequestReplyService
+	crawls   CrawlRequester
+	subject  string
+	logger   *logger.Logger
+
+	stop func() error
+	mu   sync.Mutex
+}
+
+// NewCrawlRequestHandler creates a new crawl request handler
+func NewCrawlRequestHandler(
+	requests service.RequestReplyService,
+	priorityCrawls *appservice.PriorityCrawlService,
+	config *config.Config,
+	logger *logger.Logger,
+) *CrawlRequestHandler {
+	return newCrawlRequestHandler(requests, priorityCrawls, crawlRequestSubject(config), logger)
+}
+
+func newCrawlRequestHandler(requests service.RequestReplyService, crawls CrawlRequester, subject string, logger *logger.Logger) *CrawlRequestHandler {
+	return &CrawlRequestHandler{
+		requests: requests,
+		crawls:   crawls,
+		subject:  subject,
+		logger:   logger.WithComponent("crawl-request-handler"),
+	}
+}
+
+// crawlRequestSubject returns the configured subject, by default next to the event subject
+func crawlRequestSubject(config *config.Config) string {
+	if config.PriorityCrawl.NATSSubject != "" {
+		return config.PriorityCrawl.NATSSubject
+	}
+	return fmt.Sprintf("%s.crawl.requests", config.NATS.SubjectPrefix)
+}
+
+// Start subscribes to the request subject
+func (h *CrawlRequestHandler) Start(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stop != nil {
+		return fmt.Errorf("crawl request handler is already running")
+	}
+
+	stop, err := h.requests.HandleRequests(h.subject, h.handle)
+	if err != nil {
+		return err
+	}
+	h.stop = stop
+
+	h.logger.Info("Crawl request handler started", zap.String("subject", h.subject))
+	return nil
+}
+
+// Stop unsubscribes from the request subject
+func (h *CrawlRequestHandler) Stop() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stop == nil {
+		return nil
+	}
+
+	err := h.stop()
+	h.stop = nil
+	h.logger.Info("Crawl request handler stopped")
+	return err
+}
+
+// handle queues a request and replies with the job outcome. A job still
+// running at the request timeout is reported as is, it keeps running.
+func (h *CrawlRequestHandler) handle(ctx context.Context, data []byte) []byte {
+	var request appservice.CrawlRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return encodeCrawlReply(crawlReply{Error: "invalid request body"})
+	}
+
+	job, err := h.crawls.Submit(request, "nats")
+	if err != nil {
+		h.logger.Warn("Crawl request rejected", zap.Error(err))
+		return encodeCrawlReply(crawlReply{Error: err.Error()})
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, h.crawls.RequestTimeout())
+	defer cancel()
+
+	result, err := h.crawls.Wait(waitCtx, job.ID)
+	if err != nil {
+		if result, err = h.crawls.GetJob(job.ID); err != nil {
+			return encodeCrawlReply(crawlReply{Error: err.Error()})
+		}
+	}
+	return encodeCrawlReply(crawlReply{Job: result})
+}
+
+func encodeCrawlReply(reply crawlReply) []byte {
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return []byte(`{"error":"failed to encode reply"}`)
+	}
+	return data
+}
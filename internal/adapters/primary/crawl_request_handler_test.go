@@ -0,0 +1,80 @@
+package primary
+
+import (
+	"context"
+	"encoding/json"
+	appservice "ethereum-raw-data-crawler/internal/application/service"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRequestReplyService keeps the registered handler so tests can call it
+type fakeRequestReplyService struct {
+	subject string
+	handler service.RequestHandler
+}
+
+func (f *fakeRequestReplyService) HandleRequests(subject string, handler service.RequestHandler) (func() error, error) {
+	f.subject = subject
+	f.handler = handler
+	return func() error {
+		f.handler = nil
+		return nil
+	}, nil
+}
+
+func (f *fakeRequestReplyService) request(t *testing.T, body string) crawlReply {
+	t.Helper()
+	require.NotNil(t, f.handler, "handler is not registered")
+
+	var reply crawlReply
+	require.NoError(t, json.Unmarshal(f.handler(context.Background(), []byte(body)), &reply))
+	return reply
+}
+
+func TestCrawlRequestHandler(t *testing.T) {
+	log, err := logger.NewLogger(&config.Config{App: config.AppConfig{LogLevel: "error"}})
+	require.NoError(t, err)
+
+	requests := &fakeRequestReplyService{}
+	crawls := newFakeCrawlRequester()
+	crawls.fail[8] = true
+	handler := newCrawlRequestHandler(requests, crawls, "transactions.crawl.requests", log)
+
+	require.NoError(t, handler.Start(context.Background()))
+	assert.Equal(t, "transactions.crawl.requests", requests.subject)
+	assert.Error(t, handler.Start(context.Background()), "already running")
+
+	reply := requests.request(t, `{"block":7}`)
+	require.NotNil(t, reply.Job)
+	assert.Equal(t, appservice.CrawlJobCompleted, reply.Job.Status)
+	assert.Equal(t, "nats", reply.Job.Source)
+
+	reply = requests.request(t, `{"block":8}`)
+	require.NotNil(t, reply.Job)
+	assert.Equal(t, appservice.CrawlJobFailed, reply.Job.Status)
+	assert.Equal(t, []uint64{8}, reply.Job.FailedBlocks)
+
+	reply = requests.request(t, `{"tx_hash":"0x12"}`)
+	assert.Nil(t, reply.Job)
+	assert.Contains(t, reply.Error, "invalid crawl request")
+
+	reply = requests.request(t, `{`)
+	assert.Equal(t, "invalid request body", reply.Error)
+
+	require.NoError(t, handler.Stop())
+	assert.Nil(t, requests.handler)
+}
+
+func TestCrawlRequestSubject(t *testing.T) {
+	cfg := &config.Config{NATS: config.NATSConfig{SubjectPrefix: "mainnet"}}
+	assert.Equal(t, "mainnet.crawl.requests", crawlRequestSubject(cfg))
+
+	cfg.PriorityCrawl.NATSSubject = "support.crawl"
+	assert.Equal(t, "support.crawl", crawlRequestSubject(cfg))
+}
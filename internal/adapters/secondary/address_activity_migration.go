@@ -0,0 +1,37 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// missingBlockHeight matches transactions stored before block_height existed
+var missingBlockHeight = bson.M{
+	"block_height": bson.M{"$exists": false},
+	"block_number": bson.M{"$regex": "^[0-9]+$"},
+}
+
+// MigrateBlockHeights sets the numeric block height address history is
+// paginated by on transactions stored before it existed. Only transactions
+// without one are updated, so the migration can run while the crawler writes
+// and can be resumed. Returns the number of transactions updated, or to
+// update on a dry run.
+func MigrateBlockHeights(ctx context.Context, db *database.MongoDB, dryRun bool) (int64, error) {
+	transactions := db.GetCollection("transactions")
+	if dryRun {
+		return transactions.CountDocuments(ctx, missingBlockHeight)
+	}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"block_height": bson.M{"$toLong": "$block_number"}}}},
+	}
+	result, err := transactions.UpdateMany(ctx, missingBlockHeight, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set block heights: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,214 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// addressesCollection holds the addresses and their transaction counters
+const addressesCollection = "addresses"
+
+// addressCountDelta is the change of the counters of an address
+type addressCountDelta struct {
+	total, sent, received int64
+}
+
+// addressCountDeltas returns the counter changes of writing (sign 1) or
+// deleting (sign -1) transactions
+func addressCountDeltas(txs []*entity.Transaction, sign int64) map[string]*addressCountDelta {
+	deltas := map[string]*addressCountDelta{}
+	add := func(address string, sent, received int64) {
+		delta, ok := deltas[address]
+		if !ok {
+			delta = &addressCountDelta{}
+			deltas[address] = delta
+		}
+		delta.total += sign
+		delta.sent += sign * sent
+		delta.received += sign * received
+	}
+
+	for _, tx := range txs {
+		if tx.To != nil && *tx.To == tx.From {
+			add(tx.From, 1, 1)
+			continue
+		}
+		add(tx.From, 1, 0)
+		if tx.To != nil {
+			add(*tx.To, 0, 1)
+		}
+	}
+	return deltas
+}
+
+// updateAddressCounts applies the counter changes of writing (sign 1) or
+// deleting (sign -1) transactions
+func updateAddressCounts(ctx context.Context, collection *mongo.Collection, txs []*entity.Transaction, sign int64) error {
+	deltas := addressCountDeltas(txs, sign)
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	// In address order, so concurrent commits touch shared counters in the same order
+	addresses := make([]string, 0, len(deltas))
+	for address := range deltas {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	now := time.Now()
+	operations := make([]mongo.WriteModel, 0, len(addresses))
+	for _, address := range addresses {
+		delta := deltas[address]
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"address": address}).
+			SetUpdate(bson.M{
+				"$inc": bson.M{"total": delta.total, "sent": delta.sent, "received": delta.received},
+				"$set": bson.M{"updated_at": now},
+			}).
+			SetUpsert(true))
+	}
+
+	if _, err := collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to update address counts: %w", err)
+	}
+	return nil
+}
+
+// countTransactions adds the transactions not counted yet to the address
+// counters. Transactions are stored with counted false and claimed before the
+// counters change, so a retried or repeated write counts each one once; it is
+// called after the write, outside its retries, and also counts what a failed
+// write stored. Within a transaction the claim commits with the counters,
+// otherwise a failed count releases the claim for the next write.
+func countTransactions(ctx context.Context, transactions, counts *mongo.Collection, txs []*entity.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	hashes := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash)
+	}
+
+	claim := primitive.NewObjectID()
+	result, err := transactions.UpdateMany(ctx,
+		bson.M{"hash": bson.M{"$in": hashes}, "counted": false},
+		bson.M{"$set": bson.M{"counted": true, "count_claim": claim}})
+	if err != nil {
+		return fmt.Errorf("failed to claim transaction counts: %w", err)
+	}
+	if result.ModifiedCount == 0 {
+		return nil
+	}
+
+	claimed := bson.M{"hash": bson.M{"$in": hashes}, "count_claim": claim}
+	release := func(err error) error {
+		if _, releaseErr := transactions.UpdateMany(ctx, claimed, bson.M{"$set": bson.M{"counted": false}}); releaseErr != nil {
+			return fmt.Errorf("%w (release of transaction counts failed: %v)", err, releaseErr)
+		}
+		return err
+	}
+
+	cursor, err := transactions.Find(ctx, claimed, options.Find().SetProjection(bson.M{"from": 1, "to": 1}))
+	if err != nil {
+		return release(fmt.Errorf("failed to find claimed transactions: %w", err))
+	}
+	var txsToCount []*entity.Transaction
+	if err := cursor.All(ctx, &txsToCount); err != nil {
+		return release(fmt.Errorf("failed to decode claimed transactions: %w", err))
+	}
+
+	if err := updateAddressCounts(ctx, counts, txsToCount, 1); err != nil {
+		return release(err)
+	}
+	return nil
+}
+
+// countedTransaction is a transaction with its counted flag, missing on
+// transactions stored before the flag, which were counted with their write
+type countedTransaction struct {
+	entity.Transaction `bson:",inline"`
+	Counted            *bool `bson:"counted,omitempty"`
+}
+
+// deleteTransactions deletes the transactions matching a filter one by one
+// and updates the counters of the ones it deleted, so concurrent deletes of
+// the same transactions count each once. Transactions not counted yet leave
+// the counters unchanged. Returns the number deleted.
+func deleteTransactions(ctx context.Context, transactions, counts *mongo.Collection, filter bson.M) (int64, error) {
+	opts := options.FindOneAndDelete().SetProjection(bson.M{"from": 1, "to": 1, "counted": 1})
+
+	var (
+		deleted []*entity.Transaction
+		count   int64
+	)
+	for {
+		var tx countedTransaction
+		err := transactions.FindOneAndDelete(ctx, filter, opts).Decode(&tx)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			// Count what was deleted before failing
+			if countErr := updateAddressCounts(ctx, counts, deleted, -1); countErr != nil {
+				return count, fmt.Errorf("%w (%v)", err, countErr)
+			}
+			return count, err
+		}
+		count++
+		if tx.Counted == nil || *tx.Counted {
+			deleted = append(deleted, &tx.Transaction)
+		}
+	}
+
+	return count, updateAddressCounts(ctx, counts, deleted, -1)
+}
+
+// getAddressTransactionCount returns the counters of an address, zero when it has none
+func getAddressTransactionCount(ctx context.Context, counts *mongo.Collection, address string) (*entity.AddressTransactionCount, error) {
+	count := &entity.AddressTransactionCount{}
+	err := counts.FindOne(ctx, bson.M{"address": address}).Decode(count)
+	if err == mongo.ErrNoDocuments {
+		return &entity.AddressTransactionCount{Address: address}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return count, nil
+}
+
+// blockHeight returns the block number of a transaction as a number, false
+// when it isn't one. MongoDB stores block_number as a string, block_height
+// orders transactions numerically.
+func blockHeight(tx *entity.Transaction) (int64, bool) {
+	height, err := strconv.ParseInt(tx.BlockNumber, 10, 64)
+	return height, err == nil
+}
+
+// transactionDocument returns the document of a transaction with its block
+// height, not counted yet
+func transactionDocument(tx *entity.Transaction) (bson.D, error) {
+	data, err := bson.Marshal(tx)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if height, ok := blockHeight(tx); ok {
+		doc = append(doc, bson.E{Key: "block_height", Value: height})
+	}
+	// Counted by countTransactions once stored
+	doc = append(doc, bson.E{Key: "counted", Value: false})
+	return doc, nil
+}
@@ -0,0 +1,46 @@
+package secondary
+
+import (
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAddressCountDeltas(t *testing.T) {
+	alice, bob := "0xalice", "0xbob"
+	txs := []*entity.Transaction{
+		{From: alice, To: &bob},
+		{From: bob, To: &alice},
+		{From: alice, To: &alice}, // To itself, counted once
+		{From: alice},             // Contract creation
+	}
+
+	deltas := addressCountDeltas(txs, 1)
+	assert.Equal(t, &addressCountDelta{total: 4, sent: 3, received: 2}, deltas[alice])
+	assert.Equal(t, &addressCountDelta{total: 2, sent: 1, received: 1}, deltas[bob])
+	assert.Len(t, deltas, 2)
+
+	deltas = addressCountDeltas(txs[:1], -1)
+	assert.Equal(t, &addressCountDelta{total: -1, sent: -1}, deltas[alice])
+	assert.Equal(t, &addressCountDelta{total: -1, received: -1}, deltas[bob])
+}
+
+func TestTransactionDocumentBlockHeight(t *testing.T) {
+	doc, err := transactionDocument(&entity.Transaction{Hash: "0x1", BlockNumber: "19000000", Value: "1"})
+	require.NoError(t, err)
+	data, err := bson.Marshal(doc)
+	require.NoError(t, err)
+	assert.Equal(t, int64(19_000_000), bson.Raw(data).Lookup("block_height").Int64())
+	assert.Equal(t, "19000000", bson.Raw(data).Lookup("block_number").StringValue())
+	assert.False(t, bson.Raw(data).Lookup("counted").Boolean(), "counted once stored")
+
+	doc, err = transactionDocument(&entity.Transaction{Hash: "0x2", BlockNumber: "pending"})
+	require.NoError(t, err)
+	data, err = bson.Marshal(doc)
+	require.NoError(t, err)
+	_, err = bson.Raw(data).LookupErr("block_height")
+	assert.Error(t, err, "no height without a numeric block number")
+}
@@ -0,0 +1,267 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// legacyAddressCountsCollection held the transaction counters before addresses existed
+const legacyAddressCountsCollection = "address_transaction_counts"
+
+// AddressRepositoryImpl implements AddressRepository interface
+type AddressRepositoryImpl struct {
+	db           *database.MongoDB
+	collection   *mongo.Collection
+	transactions *mongo.Collection
+}
+
+// NewAddressRepository creates new address repository
+func NewAddressRepository(db *database.MongoDB) repository.AddressRepository {
+	return &AddressRepositoryImpl{
+		db:           db,
+		collection:   db.GetCollection(addressesCollection),
+		transactions: db.GetCollection("transactions"),
+	}
+}
+
+// RecordActivity records the first-seen and last-active blocks of addresses
+func (r *AddressRepositoryImpl) RecordActivity(ctx context.Context, updates []*repository.AddressUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	// In address order, so concurrent blocks touch shared addresses in the same order
+	sorted := make([]*repository.AddressUpdate, len(updates))
+	copy(sorted, updates)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Address < sorted[j].Address })
+
+	now := time.Now()
+	operations := make([]mongo.WriteModel, 0, len(sorted))
+	for _, update := range sorted {
+		block, index := int64(update.FirstBlock), int64(update.FirstTxIndex)
+		// Compared with the stored values, the update is applied in one stage
+		earlier := bson.M{"$or": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$type": "$first_seen_block"}, "missing"}},
+			bson.M{"$gt": bson.A{"$first_seen_block", block}},
+			bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$first_seen_block", block}},
+				bson.M{"$gt": bson.A{"$first_seen_tx_index", index}},
+			}},
+		}}
+
+		set := bson.M{
+			"first_seen_block":    bson.M{"$cond": bson.A{earlier, block, "$first_seen_block"}},
+			"first_seen_tx_index": bson.M{"$cond": bson.A{earlier, index, "$first_seen_tx_index"}},
+			"first_seen_tx":       bson.M{"$cond": bson.A{earlier, update.FirstTx, "$first_seen_tx"}},
+			"last_active_block":   bson.M{"$max": bson.A{"$last_active_block", int64(update.LastBlock)}},
+			"updated_at":          now,
+		}
+		if update.IsContract != nil {
+			set["is_contract"] = contractStatusExpression(*update.IsContract)
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"address": update.Address}).
+			SetUpdate(mongo.Pipeline{{{Key: "$set", Value: set}}}).
+			SetUpsert(true))
+	}
+
+	if _, err := r.collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to record address activity: %w", err)
+	}
+	return nil
+}
+
+// SetContractStatus records whether addresses are contracts
+func (r *AddressRepositoryImpl) SetContractStatus(ctx context.Context, statuses map[string]bool) error {
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	addresses := make([]string, 0, len(statuses))
+	for address := range statuses {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	now := time.Now()
+	operations := make([]mongo.WriteModel, 0, len(addresses))
+	for _, address := range addresses {
+		set := bson.M{"is_contract": contractStatusExpression(statuses[address]), "updated_at": now}
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"address": address}).
+			SetUpdate(mongo.Pipeline{{{Key: "$set", Value: set}}}))
+	}
+
+	if _, err := r.collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to set contract status: %w", err)
+	}
+	return nil
+}
+
+// contractStatusExpression keeps an address known as a contract one
+func contractStatusExpression(isContract bool) bson.M {
+	return bson.M{"$or": bson.A{bson.M{"$eq": bson.A{"$is_contract", true}}, isContract}}
+}
+
+// GetAddress gets an address
+func (r *AddressRepositoryImpl) GetAddress(ctx context.Context, address string) (*entity.Address, error) {
+	var result entity.Address
+	err := r.collection.FindOne(ctx, bson.M{"address": address}).Decode(&result)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetUnclassifiedAddresses returns the given addresses not classified yet
+func (r *AddressRepositoryImpl) GetUnclassifiedAddresses(ctx context.Context, addresses []string) ([]string, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	filter := bson.M{"address": bson.M{"$in": addresses}, "is_contract": bson.M{"$exists": true}}
+	opts := options.Find().SetProjection(bson.M{"address": 1})
+	classified, err := r.findAddresses(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(classified))
+	for _, address := range classified {
+		known[address] = true
+	}
+	var unclassified []string
+	for _, address := range addresses {
+		if !known[address] {
+			unclassified = append(unclassified, address)
+		}
+	}
+	return unclassified, nil
+}
+
+// ListUnclassifiedAddresses pages through the stored addresses not classified yet
+func (r *AddressRepositoryImpl) ListUnclassifiedAddresses(ctx context.Context, after string, limit int) ([]string, error) {
+	filter := bson.M{"address": bson.M{"$gt": after}, "is_contract": bson.M{"$exists": false}}
+	opts := options.Find().
+		SetProjection(bson.M{"address": 1}).
+		SetSort(bson.M{"address": 1}).
+		SetLimit(int64(limit))
+	return r.findAddresses(ctx, filter, opts)
+}
+
+// findAddresses returns the address of the matching documents
+func (r *AddressRepositoryImpl) findAddresses(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]string, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var addresses []string
+	for cursor.Next(ctx) {
+		var doc struct {
+			Address string `bson:"address"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, doc.Address)
+	}
+	return addresses, cursor.Err()
+}
+
+// RebuildAddresses recomputes the addresses from the stored transactions
+func (r *AddressRepositoryImpl) RebuildAddresses(ctx context.Context) (int64, error) {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{}); err != nil {
+		return 0, fmt.Errorf("failed to clear addresses: %w", err)
+	}
+	// The rebuild counts every stored transaction
+	if _, err := r.transactions.UpdateMany(ctx, bson.M{"counted": false}, bson.M{"$set": bson.M{"counted": true}}); err != nil {
+		return 0, fmt.Errorf("failed to mark transactions counted: %w", err)
+	}
+
+	// One entry per role of each transaction: the sender, a recipient other
+	// than the sender, and the contract it created. The created contract is
+	// not counted as one of its transactions.
+	role := func(address string, counted, sent, received, contract interface{}) bson.M {
+		return bson.M{
+			"address":  address,
+			"counted":  counted,
+			"sent":     sent,
+			"received": received,
+			"contract": contract,
+			// Documents compare field by field, so the minimum is the first transaction
+			"position": bson.D{
+				{Key: "block", Value: bson.M{"$toLong": "$block_number"}},
+				{Key: "index", Value: "$transaction_index"},
+				{Key: "hash", Value: "$hash"},
+			},
+		}
+	}
+	noRecipient := bson.M{"$or": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$to", nil}}, nil}},
+		bson.M{"$eq": bson.A{"$to", "$from"}},
+	}}
+	createdContract := bson.M{"$ne": bson.A{bson.M{"$ifNull": bson.A{"$contract_address", nil}}, nil}}
+
+	pipeline := mongo.Pipeline{
+		// $set rather than $project, which reads literal numbers and booleans as projection flags
+		{{Key: "$set", Value: bson.M{"roles": bson.M{"$concatArrays": bson.A{
+			bson.A{role("$from", 1, 1, bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$to", "$from"}}, 1, 0}}, false)},
+			bson.M{"$cond": bson.A{noRecipient, bson.A{}, bson.A{role("$to", 1, 0, 1, nil)}}},
+			bson.M{"$cond": bson.A{createdContract, bson.A{role("$contract_address", 0, 0, 0, true)}, bson.A{}}},
+		}}}}},
+		{{Key: "$unwind", Value: "$roles"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$roles.address",
+			"total":    bson.M{"$sum": "$roles.counted"},
+			"sent":     bson.M{"$sum": "$roles.sent"},
+			"received": bson.M{"$sum": "$roles.received"},
+			"first":    bson.M{"$min": "$roles.position"},
+			"last":     bson.M{"$max": "$roles.position.block"},
+			// Recipients don't tell, $max skips them
+			"contract": bson.M{"$max": "$roles.contract"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":                 0,
+			"address":             "$_id",
+			"total":               1,
+			"sent":                1,
+			"received":            1,
+			"first_seen_block":    "$first.block",
+			"first_seen_tx_index": "$first.index",
+			"first_seen_tx":       "$first.hash",
+			"last_active_block":   "$last",
+			"is_contract":         bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$contract", nil}}, "$$REMOVE", "$contract"}},
+			"updated_at":          "$$NOW",
+		}}},
+		{{Key: "$merge", Value: bson.M{"into": addressesCollection, "on": "address"}}},
+	}
+
+	cursor, err := r.transactions.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild addresses: %w", err)
+	}
+	cursor.Close(ctx)
+
+	// Superseded by the counters of the addresses
+	if err := r.db.GetCollection(legacyAddressCountsCollection).Drop(ctx); err != nil {
+		return 0, fmt.Errorf("failed to drop %s: %w", legacyAddressCountsCollection, err)
+	}
+
+	return r.collection.CountDocuments(ctx, bson.M{})
+}
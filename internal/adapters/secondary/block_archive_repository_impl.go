@@ -0,0 +1,199 @@
+package secondary
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RawBlocksBucket is the GridFS bucket of the block archive
+const RawBlocksBucket = "raw_blocks"
+
+// Archive backends
+const (
+	ArchiveBackendGridFS     = "gridfs"
+	ArchiveBackendFilesystem = "filesystem"
+)
+
+// NewBlockArchiveRepository creates the block archive repository of the configured backend
+func NewBlockArchiveRepository(db *database.MongoDB, config *config.Config) (repository.BlockArchiveRepository, error) {
+	switch config.Archive.Backend {
+	case ArchiveBackendGridFS, "":
+		return NewGridFSBlockArchiveRepository(db)
+	case ArchiveBackendFilesystem:
+		return NewFileBlockArchiveRepository(config.Archive.Path)
+	default:
+		return nil, fmt.Errorf("unknown archive backend %q", config.Archive.Backend)
+	}
+}
+
+// GridFSBlockArchiveRepositoryImpl implements BlockArchiveRepository on MongoDB GridFS
+type GridFSBlockArchiveRepositoryImpl struct {
+	db     *database.MongoDB
+	bucket *gridfs.Bucket
+	files  *mongo.Collection
+}
+
+// NewGridFSBlockArchiveRepository creates new GridFS block archive repository
+func NewGridFSBlockArchiveRepository(db *database.MongoDB) (repository.BlockArchiveRepository, error) {
+	bucket, err := gridfs.NewBucket(db.Database, options.GridFSBucket().SetName(RawBlocksBucket))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GridFS bucket: %w", err)
+	}
+
+	return &GridFSBlockArchiveRepositoryImpl{
+		db:     db,
+		bucket: bucket,
+		files:  bucket.GetFilesCollection(),
+	}, nil
+}
+
+// SaveRawBlock archives a block, a block already archived under its hash is kept
+func (r *GridFSBlockArchiveRepositoryImpl) SaveRawBlock(ctx context.Context, raw *entity.RawBlock) error {
+	exists, err := r.RawBlockExists(ctx, raw.Network, raw.Hash)
+	if err != nil || exists {
+		return err
+	}
+
+	data, err := encodeArchivedBlock(raw)
+	if err != nil {
+		return err
+	}
+
+	metadata := bson.M{
+		"network":     raw.Network,
+		"number":      int64(raw.Number),
+		"hash":        raw.Hash,
+		"archived_at": raw.ArchivedAt,
+	}
+	_, err = r.bucket.UploadFromStream(archivedBlockName(raw.Network, raw.Number, raw.Hash), bytes.NewReader(data),
+		options.GridFSUpload().SetMetadata(metadata))
+	return err
+}
+
+// GetRawBlockByNumber gets the most recently archived block at the height
+func (r *GridFSBlockArchiveRepositoryImpl) GetRawBlockByNumber(ctx context.Context, network string, blockNumber uint64) (*entity.RawBlock, error) {
+	return r.findRawBlock(ctx, bson.M{"metadata.network": network, "metadata.number": int64(blockNumber)})
+}
+
+// GetRawBlockByHash gets an archived block by hash
+func (r *GridFSBlockArchiveRepositoryImpl) GetRawBlockByHash(ctx context.Context, network string, blockHash string) (*entity.RawBlock, error) {
+	return r.findRawBlock(ctx, bson.M{"metadata.network": network, "metadata.hash": blockHash})
+}
+
+// RawBlockExists checks if a block is archived
+func (r *GridFSBlockArchiveRepositoryImpl) RawBlockExists(ctx context.Context, network string, blockHash string) (bool, error) {
+	count, err := r.files.CountDocuments(ctx, bson.M{"metadata.network": network, "metadata.hash": blockHash})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// findRawBlock downloads the most recent file matching the filter
+func (r *GridFSBlockArchiveRepositoryImpl) findRawBlock(ctx context.Context, filter bson.M) (*entity.RawBlock, error) {
+	var file struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "uploadDate", Value: -1}}).SetProjection(bson.M{"_id": 1})
+	if err := r.files.FindOne(ctx, filter, opts).Decode(&file); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := r.bucket.DownloadToStream(file.ID, &buf); err != nil {
+		return nil, fmt.Errorf("failed to download archived block: %w", err)
+	}
+
+	return decodeArchivedBlock(buf.Bytes())
+}
+
+// archivedBlock is the stored form of a RawBlock, gzip compressed RLP
+type archivedBlock struct {
+	Version    uint
+	Network    string
+	Number     uint64
+	Hash       string
+	Block      []byte
+	Receipts   [][]byte
+	ArchivedAt uint64 // Unix seconds
+}
+
+const archivedBlockVersion = 1
+
+// archivedBlockName names an archived block, unique per hash
+func archivedBlockName(network string, blockNumber uint64, blockHash string) string {
+	return fmt.Sprintf("%s/%d-%s.rlp.gz", network, blockNumber, blockHash)
+}
+
+// encodeArchivedBlock encodes and compresses a raw block
+func encodeArchivedBlock(raw *entity.RawBlock) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+
+	err := rlp.Encode(zw, &archivedBlock{
+		Version:    archivedBlockVersion,
+		Network:    raw.Network,
+		Number:     raw.Number,
+		Hash:       raw.Hash,
+		Block:      raw.Block,
+		Receipts:   raw.Receipts,
+		ArchivedAt: uint64(raw.ArchivedAt.Unix()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode archived block: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress archived block: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeArchivedBlock decompresses and decodes a raw block
+func decodeArchivedBlock(data []byte) (*entity.RawBlock, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archived block: %w", err)
+	}
+	defer zr.Close()
+
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archived block: %w", err)
+	}
+
+	var archived archivedBlock
+	if err := rlp.DecodeBytes(decompressed, &archived); err != nil {
+		return nil, fmt.Errorf("failed to decode archived block: %w", err)
+	}
+	if archived.Version != archivedBlockVersion {
+		return nil, fmt.Errorf("unsupported archived block version %d", archived.Version)
+	}
+
+	return &entity.RawBlock{
+		Network:    archived.Network,
+		Number:     archived.Number,
+		Hash:       archived.Hash,
+		Block:      archived.Block,
+		Receipts:   archived.Receipts,
+		ArchivedAt: time.Unix(int64(archived.ArchivedAt), 0),
+	}, nil
+}
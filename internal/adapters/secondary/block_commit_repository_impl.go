@@ -0,0 +1,377 @@
+package secondary
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// compensationTimeout bounds the cleanup after a failed standalone commit,
+// which runs even when the commit failed because its context expired
+const compensationTimeout = 30 * time.Second
+
+// uncountedBatchSize is the number of transactions the count sweep counts at once
+const uncountedBatchSize = 1000
+
+// BlockCommitRepositoryImpl implements BlockCommitRepository interface
+type BlockCommitRepositoryImpl struct {
+	db           *database.MongoDB
+	blocks       *mongo.Collection
+	transactions *mongo.Collection
+	transfers    *mongo.Collection
+	counts       *mongo.Collection
+}
+
+// NewBlockCommitRepository creates new block commit repository
+func NewBlockCommitRepository(db *database.MongoDB) repository.BlockCommitRepository {
+	return &BlockCommitRepositoryImpl{
+		db:           db,
+		blocks:       db.GetCollection("blocks"),
+		transactions: db.GetCollection("transactions"),
+		transfers:    db.GetCollection("token_transfers"),
+		counts:       db.GetCollection(addressesCollection),
+	}
+}
+
+// IsAtomic reports whether commits run in a multi-document transaction
+func (r *BlockCommitRepositoryImpl) IsAtomic(ctx context.Context) (bool, error) {
+	return r.db.SupportsTransactions(ctx)
+}
+
+// CommitBlock writes the block, its transactions and its status in one
+// transaction, or with compensation on a standalone server
+func (r *BlockCommitRepositoryImpl) CommitBlock(ctx context.Context, block *entity.Block, transactions []*entity.Transaction, status entity.BlockStatus) error {
+	return r.CommitBlocks(ctx, []*repository.BlockCommit{{Block: block, Transactions: transactions, Status: status}})
+}
+
+// CommitBlocks writes the blocks, their transactions and their statuses in
+// one transaction, or with compensation on a standalone server
+func (r *BlockCommitRepositoryImpl) CommitBlocks(ctx context.Context, commits []*repository.BlockCommit) error {
+	if len(commits) == 0 {
+		return nil
+	}
+
+	atomic, err := r.IsAtomic(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check transaction support: %w", err)
+	}
+	if !atomic {
+		return r.commitWithCompensation(ctx, commits)
+	}
+
+	session, err := r.db.Client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	// WithTransaction retries the whole callback on transient transaction errors
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if _, err := r.insertBlocks(sc, commits); err != nil {
+			return nil, err
+		}
+		if err := r.upsertTransactions(sc, commits); err != nil {
+			return nil, err
+		}
+		return nil, r.setStatuses(sc, commits)
+	})
+	return err
+}
+
+// commitWithCompensation writes the blocks, transactions and statuses one
+// after the other. When a write fails, the blocks inserted by this commit are
+// deleted again together with their transactions, so they are crawled from
+// scratch, as are the transactions of blocks another block took the height of.
+func (r *BlockCommitRepositoryImpl) commitWithCompensation(ctx context.Context, commits []*repository.BlockCommit) error {
+	inserted, err := r.insertBlocks(ctx, commits)
+	if err != nil && isOnlyDuplicateKeyError(err) {
+		// Concurrent upserts of the same height, a block is stored and
+		// setStatuses fails unless it is this one
+		err = nil
+	}
+	if err == nil {
+		err = r.upsertTransactions(ctx, commits)
+	}
+	if err == nil {
+		err = r.setStatuses(ctx, commits)
+	}
+	if err == nil {
+		return err
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), compensationTimeout)
+	defer cancel()
+
+	for _, blockHash := range inserted {
+		if _, cleanupErr := r.deletePendingBlock(cleanupCtx, blockHash); cleanupErr != nil {
+			return fmt.Errorf("%w (cleanup of block %s failed: %v)", err, blockHash, cleanupErr)
+		}
+	}
+	// Blocks stored before keep their status, rewritten transactions hold the same data
+	for _, commit := range commits {
+		if cleanupErr := r.deleteUnstoredTransactions(cleanupCtx, commit.Block.Hash); cleanupErr != nil {
+			return fmt.Errorf("%w (cleanup of block %s failed: %v)", err, commit.Block.Hash, cleanupErr)
+		}
+	}
+	return err
+}
+
+// deleteUnstoredTransactions deletes the transactions of a block unless the
+// block is stored
+func (r *BlockCommitRepositoryImpl) deleteUnstoredTransactions(ctx context.Context, blockHash string) error {
+	stored, err := r.blocks.CountDocuments(ctx, bson.M{"hash": blockHash}, options.Count().SetLimit(1))
+	if err != nil {
+		return fmt.Errorf("failed to check block: %w", err)
+	}
+	if stored > 0 {
+		return nil
+	}
+	if _, err := deleteTransactions(ctx, r.transactions, r.counts, bson.M{"block_hash": blockHash}); err != nil {
+		return fmt.Errorf("failed to delete transactions: %w", err)
+	}
+	return nil
+}
+
+// insertBlocks stores the blocks as pending unless they are stored, replacing
+// blocks of another hash at their height. Returns the hashes of the inserted
+// blocks, also when some inserts failed.
+func (r *BlockCommitRepositoryImpl) insertBlocks(ctx context.Context, commits []*repository.BlockCommit) ([]string, error) {
+	if err := r.deleteReorgedBlocks(ctx, commits); err != nil {
+		return nil, err
+	}
+
+	operations := make([]mongo.WriteModel, 0, len(commits))
+	for _, commit := range commits {
+		block := commit.Block
+		if block.ID.IsZero() {
+			block.ID = primitive.NewObjectID()
+		}
+
+		pending := *block
+		pending.Status = entity.BlockStatusPending
+		pending.ProcessedAt = nil
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"number": block.Number}).
+			SetUpdate(bson.M{"$setOnInsert": &pending}).
+			SetUpsert(true))
+	}
+
+	result, err := r.blocks.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false))
+
+	var inserted []string
+	if result != nil {
+		for index := range result.UpsertedIDs {
+			inserted = append(inserted, commits[index].Block.Hash)
+		}
+	}
+	if err != nil {
+		return inserted, fmt.Errorf("failed to save blocks: %w", err)
+	}
+	return inserted, nil
+}
+
+// deleteReorgedBlocks deletes the blocks stored at the heights of the commits
+// with another hash, left behind by a chain reorganization, together with
+// their transactions and token transfers. The block goes last, so a failed
+// delete outside a transaction is repeated by the next commit of the height.
+func (r *BlockCommitRepositoryImpl) deleteReorgedBlocks(ctx context.Context, commits []*repository.BlockCommit) error {
+	numbers := make([]string, 0, len(commits))
+	hashes := make([]string, 0, len(commits))
+	for _, commit := range commits {
+		numbers = append(numbers, commit.Block.Number)
+		hashes = append(hashes, commit.Block.Hash)
+	}
+
+	filter := bson.M{"number": bson.M{"$in": numbers}, "hash": bson.M{"$nin": hashes}}
+	cursor, err := r.blocks.Find(ctx, filter, options.Find().SetProjection(bson.M{"number": 1, "hash": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to find reorged blocks: %w", err)
+	}
+	var reorged []*entity.Block
+	if err := cursor.All(ctx, &reorged); err != nil {
+		return fmt.Errorf("failed to decode reorged blocks: %w", err)
+	}
+
+	for _, block := range reorged {
+		if _, err := deleteTransactions(ctx, r.transactions, r.counts, bson.M{"block_hash": block.Hash}); err != nil {
+			return fmt.Errorf("failed to delete transactions of reorged block %s: %w", block.Number, err)
+		}
+		if _, err := r.transfers.DeleteMany(ctx, bson.M{"block_hash": block.Hash}); err != nil {
+			return fmt.Errorf("failed to delete token transfers of reorged block %s: %w", block.Number, err)
+		}
+		if _, err := r.blocks.DeleteOne(ctx, bson.M{"hash": block.Hash}); err != nil {
+			return fmt.Errorf("failed to delete reorged block %s: %w", block.Number, err)
+		}
+	}
+	return nil
+}
+
+// upsertTransactions upserts the transactions of all blocks by hash and
+// counts the ones not counted yet in the address counters
+func (r *BlockCommitRepositoryImpl) upsertTransactions(ctx context.Context, commits []*repository.BlockCommit) error {
+	var (
+		transactions []*entity.Transaction
+		operations   []mongo.WriteModel
+	)
+	for _, commit := range commits {
+		for _, tx := range commit.Transactions {
+			transactions = append(transactions, tx)
+			operations = append(operations, newTransactionUpsertModel(tx))
+		}
+	}
+	if len(operations) == 0 {
+		return nil
+	}
+
+	// Counted also after a failed write: a cleanup deletes the stored
+	// transactions with their counts, a transaction rolls both back
+	_, err := r.transactions.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false))
+	countErr := countTransactions(ctx, r.transactions, r.counts, transactions)
+	if err != nil {
+		return fmt.Errorf("failed to save transactions: %w", err)
+	}
+	return countErr
+}
+
+// setStatuses sets the status of the blocks, and the processed time for processed blocks
+func (r *BlockCommitRepositoryImpl) setStatuses(ctx context.Context, commits []*repository.BlockCommit) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	operations := make([]mongo.WriteModel, 0, len(commits))
+	for _, commit := range commits {
+		set := bson.M{"status": commit.Status}
+		if commit.Status == entity.BlockStatusProcessed {
+			set["processed_at"] = now
+		}
+
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"hash": commit.Block.Hash}).
+			SetUpdate(bson.M{"$set": set}))
+	}
+
+	result, err := r.blocks.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("failed to update block status: %w", err)
+	}
+	// A block of another hash was stored at the height meanwhile
+	if result.MatchedCount < int64(len(operations)) {
+		return fmt.Errorf("failed to update block status: %d of %d blocks not stored", int64(len(operations))-result.MatchedCount, len(operations))
+	}
+	return nil
+}
+
+// isOnlyDuplicateKeyError reports whether every write of a failed bulk write
+// hit a duplicate key
+func isOnlyDuplicateKeyError(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) {
+		return mongo.IsDuplicateKeyError(err)
+	}
+	if bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != 11000 {
+			return false
+		}
+	}
+	return true
+}
+
+// deletePendingBlock deletes the block while it is still pending, then its
+// transactions. Returns true when the block was deleted.
+func (r *BlockCommitRepositoryImpl) deletePendingBlock(ctx context.Context, blockHash string) (bool, error) {
+	result, err := r.blocks.DeleteOne(ctx, bson.M{"hash": blockHash, "status": entity.BlockStatusPending})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete block: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return false, nil
+	}
+
+	if _, err := deleteTransactions(ctx, r.transactions, r.counts, bson.M{"block_hash": blockHash}); err != nil {
+		return true, fmt.Errorf("failed to delete transactions: %w", err)
+	}
+	return true, nil
+}
+
+// CleanupUncommittedBlocks deletes stale pending blocks and their transactions
+func (r *BlockCommitRepositoryImpl) CleanupUncommittedBlocks(ctx context.Context, network string, before time.Time) ([]uint64, error) {
+	filter := bson.M{
+		"network":    network,
+		"status":     entity.BlockStatusPending,
+		"crawled_at": bson.M{"$lt": before},
+	}
+	opts := options.Find().SetProjection(bson.M{"number": 1, "hash": 1})
+
+	cursor, err := r.blocks.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending blocks: %w", err)
+	}
+	var blocks []*entity.Block
+	if err := cursor.All(ctx, &blocks); err != nil {
+		return nil, fmt.Errorf("failed to decode pending blocks: %w", err)
+	}
+
+	var deleted []uint64
+	for _, block := range blocks {
+		ok, err := r.deletePendingBlock(ctx, block.Hash)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to clean up block %s: %w", block.Number, err)
+		}
+		if !ok {
+			continue
+		}
+
+		number, err := strconv.ParseUint(block.Number, 10, 64)
+		if err != nil {
+			return deleted, fmt.Errorf("invalid block number %q: %w", block.Number, err)
+		}
+		deleted = append(deleted, number)
+	}
+
+	return deleted, nil
+}
+
+// CountUncountedTransactions counts the transactions still marked not counted
+// whose ID was generated before the given time, in batches. A write counting
+// them concurrently is harmless, the claim counts each transaction once.
+func (r *BlockCommitRepositoryImpl) CountUncountedTransactions(ctx context.Context, network string, before time.Time) (int64, error) {
+	filter := bson.M{
+		"network": network,
+		"counted": false,
+		"_id":     bson.M{"$lt": primitive.NewObjectIDFromTimestamp(before)},
+	}
+	opts := options.Find().SetProjection(bson.M{"hash": 1}).SetLimit(uncountedBatchSize)
+
+	var counted int64
+	for {
+		cursor, err := r.transactions.Find(ctx, filter, opts)
+		if err != nil {
+			return counted, fmt.Errorf("failed to find uncounted transactions: %w", err)
+		}
+		var txs []*entity.Transaction
+		if err := cursor.All(ctx, &txs); err != nil {
+			return counted, fmt.Errorf("failed to decode uncounted transactions: %w", err)
+		}
+		if len(txs) == 0 {
+			return counted, nil
+		}
+
+		if err := countTransactions(ctx, r.transactions, r.counts, txs); err != nil {
+			return counted, err
+		}
+		counted += int64(len(txs))
+	}
+}
@@ -193,9 +193,13 @@ func (r *BlockRepositoryImpl) GetBlocksInRange(ctx context.Context, startBlock,
 
 // GetLastProcessedBlock gets last processed block
 func (r *BlockRepositoryImpl) GetLastProcessedBlock(ctx context.Context, network string) (*entity.Block, error) {
+	// Incomplete blocks are stored, their missing receipts are repaired in place
 	filter := bson.M{
 		"network": network,
-		"status":  entity.BlockStatusProcessed,
+		"status": bson.M{"$in": []entity.BlockStatus{
+			entity.BlockStatusProcessed,
+			entity.BlockStatusIncomplete,
+		}},
 	}
 
 	opts := options.FindOne().SetSort(bson.D{{Key: "number", Value: -1}})
@@ -237,6 +241,94 @@ func (r *BlockRepositoryImpl) GetBlocksByStatus(ctx context.Context, status enti
 	return blocks, cursor.Err()
 }
 
+// GetBlocksDueForRepair gets the incomplete blocks due for a receipt repair.
+// Blocks never tried have no next repair time and sort first.
+func (r *BlockRepositoryImpl) GetBlocksDueForRepair(ctx context.Context, network string, now time.Time, limit int) ([]*entity.Block, error) {
+	filter := bson.M{
+		"network":        network,
+		"status":         entity.BlockStatusIncomplete,
+		"next_repair_at": bson.M{"$not": bson.M{"$gt": now}},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "next_repair_at", Value: 1}, {Key: "timestamp", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*entity.Block
+	if err := cursor.All(ctx, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// GetBlocksByTimeRange gets the blocks mined within a time range
+func (r *BlockRepositoryImpl) GetBlocksByTimeRange(ctx context.Context, network string, startTime, endTime time.Time) ([]*entity.Block, error) {
+	filter := bson.M{
+		"network": network,
+		"timestamp": bson.M{
+			"$gte": startTime,
+			"$lte": endTime,
+		},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var blocks []*entity.Block
+	for cursor.Next(ctx) {
+		var block entity.Block
+		if err := cursor.Decode(&block); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &block)
+	}
+
+	return blocks, cursor.Err()
+}
+
+// GetBlockRangeByTime gets the first and last block mined within a time range
+func (r *BlockRepositoryImpl) GetBlockRangeByTime(ctx context.Context, network string, startTime, endTime time.Time) (*entity.Block, *entity.Block, error) {
+	filter := bson.M{
+		"network": network,
+		"timestamp": bson.M{
+			"$gte": startTime,
+			"$lte": endTime,
+		},
+	}
+
+	first, err := r.findOneBlock(ctx, filter, options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: 1}}))
+	if err != nil || first == nil {
+		return nil, nil, err
+	}
+
+	last, err := r.findOneBlock(ctx, filter, options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}}))
+	if err != nil {
+		return nil, nil, err
+	}
+	return first, last, nil
+}
+
+// findOneBlock returns the first block matching a filter, nil when there is none
+func (r *BlockRepositoryImpl) findOneBlock(ctx context.Context, filter bson.M, opts *options.FindOneOptions) (*entity.Block, error) {
+	var block entity.Block
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&block)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
 // UpdateBlockStatus updates block status
 func (r *BlockRepositoryImpl) UpdateBlockStatus(ctx context.Context, blockHash string, status entity.BlockStatus) error {
 	filter := bson.M{"hash": blockHash}
@@ -260,6 +352,20 @@ func (r *BlockRepositoryImpl) MarkBlockAsProcessed(ctx context.Context, blockHas
 	return err
 }
 
+// ScheduleBlockRepair records a failed receipt repair of a block
+func (r *BlockRepositoryImpl) ScheduleBlockRepair(ctx context.Context, blockHash string, attempts int, nextAttempt time.Time) error {
+	filter := bson.M{"hash": blockHash}
+	update := bson.M{
+		"$set": bson.M{
+			"repair_attempts": attempts,
+			"next_repair_at":  nextAttempt,
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
 // DeleteBlock deletes block
 func (r *BlockRepositoryImpl) DeleteBlock(ctx context.Context, blockHash string) error {
 	filter := bson.M{"hash": blockHash}
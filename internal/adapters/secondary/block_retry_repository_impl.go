@@ -0,0 +1,158 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BlockRetryRepositoryImpl implements BlockRetryRepository interface
+type BlockRetryRepositoryImpl struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewBlockRetryRepository creates new block retry repository
+func NewBlockRetryRepository(db *database.MongoDB) repository.BlockRetryRepository {
+	return &BlockRetryRepositoryImpl{
+		db:         db,
+		collection: db.GetCollection("block_retries"),
+	}
+}
+
+// UpsertBlockRetry creates or replaces the retry entry for a block
+func (r *BlockRetryRepositoryImpl) UpsertBlockRetry(ctx context.Context, retry *entity.BlockRetry) error {
+	filter := bson.M{"network": retry.Network, "block_number": retry.BlockNumber}
+	update := bson.M{
+		"$set": bson.M{
+			"status":          retry.Status,
+			"attempts":        retry.Attempts,
+			"last_error":      retry.LastError,
+			"next_attempt_at": retry.NextAttemptAt,
+			"updated_at":      retry.UpdatedAt,
+			"dead_letter_at":  retry.DeadLetterAt,
+		},
+		"$setOnInsert": bson.M{
+			"created_at": retry.CreatedAt,
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetBlockRetry gets the retry entry for a block
+func (r *BlockRetryRepositoryImpl) GetBlockRetry(ctx context.Context, network string, blockNumber uint64) (*entity.BlockRetry, error) {
+	filter := bson.M{"network": network, "block_number": blockNumber}
+
+	var retry entity.BlockRetry
+	err := r.collection.FindOne(ctx, filter).Decode(&retry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &retry, nil
+}
+
+// GetDueBlockRetries gets pending retries whose next attempt is due, oldest first
+func (r *BlockRetryRepositoryImpl) GetDueBlockRetries(ctx context.Context, network string, dueBefore time.Time, limit int) ([]*entity.BlockRetry, error) {
+	filter := bson.M{
+		"network":         network,
+		"status":          entity.BlockRetryStatusPending,
+		"next_attempt_at": bson.M{"$lte": dueBefore},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	return r.find(ctx, filter, opts)
+}
+
+// GetBlockRetriesByStatus gets retries by status ordered by block number
+func (r *BlockRetryRepositoryImpl) GetBlockRetriesByStatus(ctx context.Context, network string, status entity.BlockRetryStatus, limit int) ([]*entity.BlockRetry, error) {
+	filter := bson.M{"network": network, "status": status}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "block_number", Value: 1}}).
+		SetLimit(int64(limit))
+
+	return r.find(ctx, filter, opts)
+}
+
+// RequeueBlockRetry moves a retry back to pending with a fresh attempt count
+func (r *BlockRetryRepositoryImpl) RequeueBlockRetry(ctx context.Context, network string, blockNumber uint64, nextAttemptAt time.Time) (bool, error) {
+	filter := bson.M{"network": network, "block_number": blockNumber}
+
+	result, err := r.collection.UpdateOne(ctx, filter, requeueUpdate(nextAttemptAt))
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// RequeueDeadLetterBlockRetries moves all dead-lettered retries back to pending
+func (r *BlockRetryRepositoryImpl) RequeueDeadLetterBlockRetries(ctx context.Context, network string, nextAttemptAt time.Time) (int64, error) {
+	filter := bson.M{"network": network, "status": entity.BlockRetryStatusDeadLetter}
+
+	result, err := r.collection.UpdateMany(ctx, filter, requeueUpdate(nextAttemptAt))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// DeleteBlockRetry deletes the retry entry for a block
+func (r *BlockRetryRepositoryImpl) DeleteBlockRetry(ctx context.Context, network string, blockNumber uint64) error {
+	filter := bson.M{"network": network, "block_number": blockNumber}
+	_, err := r.collection.DeleteOne(ctx, filter)
+	return err
+}
+
+// GetBlockRetryCountByStatus gets retry count by status
+func (r *BlockRetryRepositoryImpl) GetBlockRetryCountByStatus(ctx context.Context, network string, status entity.BlockRetryStatus) (int64, error) {
+	filter := bson.M{"network": network, "status": status}
+	return r.collection.CountDocuments(ctx, filter)
+}
+
+// find runs a query and decodes all retries
+func (r *BlockRetryRepositoryImpl) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*entity.BlockRetry, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var retries []*entity.BlockRetry
+	for cursor.Next(ctx) {
+		var retry entity.BlockRetry
+		if err := cursor.Decode(&retry); err != nil {
+			return nil, err
+		}
+		retries = append(retries, &retry)
+	}
+
+	return retries, cursor.Err()
+}
+
+// requeueUpdate resets a retry to pending
+func requeueUpdate(nextAttemptAt time.Time) bson.M {
+	return bson.M{
+		"$set": bson.M{
+			"status":          entity.BlockRetryStatusPending,
+			"attempts":        0,
+			"next_attempt_at": nextAttemptAt,
+			"updated_at":      time.Now(),
+		},
+		"$unset": bson.M{"dead_letter_at": ""},
+	}
+}
@@ -0,0 +1,94 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BlockTimestampMigrationResult summarizes a block timestamp migration run
+type BlockTimestampMigrationResult struct {
+	BlocksScanned int64
+	// Transactions updated, or missing a block timestamp on a dry run
+	Transactions int64
+}
+
+// missingBlockTimestamp matches transactions stored before block_timestamp
+// existed or whose block was unknown when they were stored
+var missingBlockTimestamp = bson.A{
+	bson.M{"block_timestamp": bson.M{"$exists": false}},
+	bson.M{"block_timestamp": time.Time{}},
+}
+
+// MigrateBlockTimestamps copies the timestamp of stored blocks to their
+// transactions. Only transactions without a block timestamp are updated, so
+// the migration can run while the crawler writes and can be resumed.
+func MigrateBlockTimestamps(ctx context.Context, db *database.MongoDB, batchSize int, dryRun bool) (*BlockTimestampMigrationResult, error) {
+	transactions := db.GetCollection("transactions")
+	result := &BlockTimestampMigrationResult{}
+
+	if dryRun {
+		count, err := transactions.CountDocuments(ctx, bson.M{"$or": missingBlockTimestamp})
+		if err != nil {
+			return nil, err
+		}
+		result.Transactions = count
+		return result, nil
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"hash": 1, "timestamp": 1}).
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetBatchSize(int32(batchSize))
+
+	cursor, err := db.GetCollection("blocks").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var updates []mongo.WriteModel
+	flush := func() error {
+		if len(updates) == 0 {
+			return nil
+		}
+		written, err := transactions.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return fmt.Errorf("failed to write block timestamps: %w", err)
+		}
+		result.Transactions += written.ModifiedCount
+		updates = updates[:0]
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var block struct {
+			Hash      string    `bson:"hash"`
+			Timestamp time.Time `bson:"timestamp"`
+		}
+		if err := cursor.Decode(&block); err != nil {
+			return result, err
+		}
+		result.BlocksScanned++
+
+		updates = append(updates, mongo.NewUpdateManyModel().
+			SetFilter(bson.M{"block_hash": block.Hash, "$or": missingBlockTimestamp}).
+			SetUpdate(bson.M{"$set": bson.M{"block_timestamp": block.Timestamp}}))
+
+		if len(updates) >= batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return result, err
+	}
+
+	return result, flush()
+}
@@ -0,0 +1,85 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CheckpointRepositoryImpl implements CheckpointRepository interface
+type CheckpointRepositoryImpl struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewCheckpointRepository creates new checkpoint repository
+func NewCheckpointRepository(db *database.MongoDB) repository.CheckpointRepository {
+	return &CheckpointRepositoryImpl{
+		db:         db,
+		collection: db.GetCollection("checkpoints"),
+	}
+}
+
+// GetCheckpoint gets the checkpoint of a network
+func (r *CheckpointRepositoryImpl) GetCheckpoint(ctx context.Context, network string) (*entity.Checkpoint, error) {
+	var checkpoint entity.Checkpoint
+	err := r.collection.FindOne(ctx, bson.M{"_id": network}).Decode(&checkpoint)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// SaveCheckpoint writes the checkpoint if its version is unchanged and no
+// newer leader wrote it
+func (r *CheckpointRepositoryImpl) SaveCheckpoint(ctx context.Context, checkpoint *entity.Checkpoint) (bool, error) {
+	checkpoint.UpdatedAt = time.Now()
+
+	filter := bson.M{
+		"_id":     checkpoint.Network,
+		"version": checkpoint.Version,
+		"$or": []bson.M{
+			{"fencing_token": bson.M{"$lte": checkpoint.FencingToken}},
+			{"fencing_token": bson.M{"$exists": false}},
+		},
+	}
+	set := bson.M{
+		"committed_block":    checkpoint.CommittedBlock,
+		"highest_seen_block": checkpoint.HighestSeenBlock,
+		"version":            checkpoint.Version + 1,
+		"fencing_token":      checkpoint.FencingToken,
+		"updated_at":         checkpoint.UpdatedAt,
+	}
+	if checkpoint.RewoundAt != nil {
+		set["rewound_at"] = checkpoint.RewoundAt
+	}
+	update := bson.M{"$set": set}
+
+	// Only a new checkpoint may be inserted, otherwise a version mismatch would
+	// upsert a duplicate _id
+	opts := options.Update().SetUpsert(checkpoint.Version == 0)
+
+	result, err := r.collection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if result.MatchedCount == 0 && result.UpsertedCount == 0 {
+		return false, nil
+	}
+
+	checkpoint.Version++
+	return true, nil
+}
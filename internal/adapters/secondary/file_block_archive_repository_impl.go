@@ -0,0 +1,150 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// blocksPerArchiveDir keeps archive directories to a manageable size
+const blocksPerArchiveDir = 10000
+
+// FileBlockArchiveRepositoryImpl implements BlockArchiveRepository on the local filesystem.
+// Blocks are stored at <root>/<network>/blocks/<number/10000>/<number>-<hash>.rlp.gz,
+// with <root>/<network>/hashes/<hash> holding the number of each hash.
+type FileBlockArchiveRepositoryImpl struct {
+	root string
+}
+
+// NewFileBlockArchiveRepository creates new filesystem block archive repository
+func NewFileBlockArchiveRepository(root string) (repository.BlockArchiveRepository, error) {
+	if root == "" {
+		return nil, fmt.Errorf("archive path is required for the filesystem backend")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	return &FileBlockArchiveRepositoryImpl{root: root}, nil
+}
+
+// SaveRawBlock archives a block, a block already archived under its hash is kept
+func (r *FileBlockArchiveRepositoryImpl) SaveRawBlock(ctx context.Context, raw *entity.RawBlock) error {
+	exists, err := r.RawBlockExists(ctx, raw.Network, raw.Hash)
+	if err != nil || exists {
+		return err
+	}
+
+	data, err := encodeArchivedBlock(raw)
+	if err != nil {
+		return err
+	}
+
+	// The block is written before its hash entry, so a listed hash is always readable
+	if err := writeFileAtomic(r.blockPath(raw.Network, raw.Number, raw.Hash), data); err != nil {
+		return fmt.Errorf("failed to write archived block: %w", err)
+	}
+	if err := writeFileAtomic(r.hashPath(raw.Network, raw.Hash), []byte(strconv.FormatUint(raw.Number, 10))); err != nil {
+		return fmt.Errorf("failed to write archived block hash: %w", err)
+	}
+	return nil
+}
+
+// GetRawBlockByNumber gets the most recently archived block at the height
+func (r *FileBlockArchiveRepositoryImpl) GetRawBlockByNumber(ctx context.Context, network string, blockNumber uint64) (*entity.RawBlock, error) {
+	pattern := filepath.Join(r.blockDir(network, blockNumber), fmt.Sprintf("%d-*.rlp.gz", blockNumber))
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *entity.RawBlock
+	for _, path := range paths {
+		raw, err := readArchivedBlock(path)
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil || raw.ArchivedAt.After(latest.ArchivedAt) {
+			latest = raw
+		}
+	}
+	return latest, nil
+}
+
+// GetRawBlockByHash gets an archived block by hash
+func (r *FileBlockArchiveRepositoryImpl) GetRawBlockByHash(ctx context.Context, network string, blockHash string) (*entity.RawBlock, error) {
+	data, err := os.ReadFile(r.hashPath(network, blockHash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	blockNumber, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archived block hash entry %s: %w", blockHash, err)
+	}
+
+	return readArchivedBlock(r.blockPath(network, blockNumber, blockHash))
+}
+
+// RawBlockExists checks if a block is archived
+func (r *FileBlockArchiveRepositoryImpl) RawBlockExists(ctx context.Context, network string, blockHash string) (bool, error) {
+	_, err := os.Stat(r.hashPath(network, blockHash))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (r *FileBlockArchiveRepositoryImpl) blockDir(network string, blockNumber uint64) string {
+	return filepath.Join(r.root, network, "blocks", strconv.FormatUint(blockNumber/blocksPerArchiveDir, 10))
+}
+
+func (r *FileBlockArchiveRepositoryImpl) blockPath(network string, blockNumber uint64, blockHash string) string {
+	return filepath.Join(r.blockDir(network, blockNumber), fmt.Sprintf("%d-%s.rlp.gz", blockNumber, strings.ToLower(blockHash)))
+}
+
+func (r *FileBlockArchiveRepositoryImpl) hashPath(network string, blockHash string) string {
+	return filepath.Join(r.root, network, "hashes", strings.ToLower(blockHash))
+}
+
+// readArchivedBlock reads and decodes an archived block file
+func readArchivedBlock(path string) (*entity.RawBlock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archived block: %w", err)
+	}
+	return decodeArchivedBlock(data)
+}
+
+// writeFileAtomic writes a file through a temporary file so readers never see it half written
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,73 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBlockArchiveRepository(t *testing.T) {
+	root := t.TempDir()
+	repo, err := NewFileBlockArchiveRepository(root)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	original := &entity.RawBlock{
+		Network:    "ethereum",
+		Number:     19_000_001,
+		Hash:       "0xaaaa",
+		Block:      []byte{0xf9, 0x01, 0x02},
+		Receipts:   [][]byte{{0x01}, {0x02, 0x03}},
+		ArchivedAt: time.Unix(1_700_000_000, 0),
+	}
+	reorged := &entity.RawBlock{
+		Network:    "ethereum",
+		Number:     19_000_001,
+		Hash:       "0xbbbb",
+		Block:      []byte{0xf9, 0x04},
+		Receipts:   [][]byte{},
+		ArchivedAt: time.Unix(1_700_000_060, 0),
+	}
+
+	exists, err := repo.RawBlockExists(ctx, "ethereum", "0xaaaa")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, repo.SaveRawBlock(ctx, original))
+	require.NoError(t, repo.SaveRawBlock(ctx, reorged))
+	require.NoError(t, repo.SaveRawBlock(ctx, original), "saving again keeps the archived block")
+
+	assert.FileExists(t, filepath.Join(root, "ethereum", "blocks", "1900", "19000001-0xaaaa.rlp.gz"))
+
+	raw, err := repo.GetRawBlockByHash(ctx, "ethereum", "0xaaaa")
+	require.NoError(t, err)
+	assert.Equal(t, original, raw)
+
+	// The most recently archived block at the height wins
+	raw, err = repo.GetRawBlockByNumber(ctx, "ethereum", 19_000_001)
+	require.NoError(t, err)
+	assert.Equal(t, "0xbbbb", raw.Hash)
+
+	raw, err = repo.GetRawBlockByNumber(ctx, "ethereum", 19_000_002)
+	require.NoError(t, err)
+	assert.Nil(t, raw)
+	raw, err = repo.GetRawBlockByHash(ctx, "sepolia", "0xaaaa")
+	require.NoError(t, err)
+	assert.Nil(t, raw)
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Join(root, "ethereum", "blocks", "1900"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestFileBlockArchiveRepository_EmptyRoot(t *testing.T) {
+	_, err := NewFileBlockArchiveRepository("")
+	assert.Error(t, err)
+}
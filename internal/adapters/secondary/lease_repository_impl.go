@@ -0,0 +1,116 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LeaseRepositoryImpl implements LeaseRepository interface
+type LeaseRepositoryImpl struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewLeaseRepository creates new lease repository
+func NewLeaseRepository(db *database.MongoDB) repository.LeaseRepository {
+	return &LeaseRepositoryImpl{
+		db:         db,
+		collection: db.GetCollection("leases"),
+	}
+}
+
+// AcquireLease takes the lease if it is free, expired or already ours. Times
+// are taken from the server clock ($$NOW) so replicas with skewed clocks agree
+// on when the lease expires.
+func (r *LeaseRepositoryImpl) AcquireLease(ctx context.Context, name, holderID string, ttl time.Duration) (*entity.Lease, error) {
+	filter := bson.M{
+		"_id": name,
+		"$or": []bson.M{
+			{"$expr": bson.M{"$lte": bson.A{"$expires_at", "$$NOW"}}},
+			{"holder_id": holderID},
+		},
+	}
+	// Pipeline update for $$NOW, the token is missing on insert
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"holder_id":   holderID,
+			"acquired_at": "$$NOW",
+			"renewed_at":  "$$NOW",
+			"expires_at":  leaseExpiry(ttl),
+			"token":       bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$token", int64(0)}}, int64(1)}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	var lease entity.Lease
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&lease)
+	if err != nil {
+		// The upsert conflicts with the existing document when another holder owns the lease
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &lease, nil
+}
+
+// RenewLease extends a lease still held with the given token
+func (r *LeaseRepositoryImpl) RenewLease(ctx context.Context, name, holderID string, token int64, ttl time.Duration) (*entity.Lease, error) {
+	filter := bson.M{"_id": name, "holder_id": holderID, "token": token}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"renewed_at": "$$NOW",
+			"expires_at": leaseExpiry(ttl),
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var lease entity.Lease
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&lease)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &lease, nil
+}
+
+// ReleaseLease expires the lease, keeping the token so it keeps increasing
+func (r *LeaseRepositoryImpl) ReleaseLease(ctx context.Context, name, holderID string, token int64) error {
+	filter := bson.M{"_id": name, "holder_id": holderID, "token": token}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"expires_at": "$$NOW"}}}}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// GetLease gets the current lease state
+func (r *LeaseRepositoryImpl) GetLease(ctx context.Context, name string) (*entity.Lease, error) {
+	var lease entity.Lease
+	err := r.collection.FindOne(ctx, bson.M{"_id": name}).Decode(&lease)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &lease, nil
+}
+
+// leaseExpiry is the server time the lease expires at when set now
+func leaseExpiry(ttl time.Duration) bson.M {
+	return bson.M{"$add": bson.A{"$$NOW", ttl.Milliseconds()}}
+}
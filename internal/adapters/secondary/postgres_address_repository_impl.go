@@ -0,0 +1,190 @@
+package secondary
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PostgresAddressRepositoryImpl implements AddressRepository interface on PostgreSQL
+type PostgresAddressRepositoryImpl struct {
+	db *database.PostgresDB
+}
+
+// NewPostgresAddressRepository creates new PostgreSQL address repository
+func NewPostgresAddressRepository(db *database.PostgresDB) repository.AddressRepository {
+	return &PostgresAddressRepositoryImpl{db: db}
+}
+
+// RecordActivity records the first-seen and last-active blocks of addresses
+func (r *PostgresAddressRepositoryImpl) RecordActivity(ctx context.Context, updates []*repository.AddressUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	n := len(updates)
+	addresses, firstTxs := make([]string, 0, n), make([]string, 0, n)
+	firstBlocks, firstIndexes, lastBlocks := make([]int64, 0, n), make([]int64, 0, n), make([]int64, 0, n)
+	contracts := make([]*bool, 0, n)
+	for _, update := range updates {
+		addresses = append(addresses, update.Address)
+		firstBlocks = append(firstBlocks, int64(update.FirstBlock))
+		firstIndexes = append(firstIndexes, int64(update.FirstTxIndex))
+		firstTxs = append(firstTxs, update.FirstTx)
+		lastBlocks = append(lastBlocks, int64(update.LastBlock))
+		contracts = append(contracts, update.IsContract)
+	}
+
+	// In address order, so concurrent blocks lock shared addresses in the same order
+	_, err := r.db.Pool.Exec(ctx, `INSERT INTO addresses AS a
+		(address, first_seen_block, first_seen_tx_index, first_seen_tx, last_active_block, is_contract, updated_at)
+		SELECT address, first_block, first_index, first_tx, last_block, is_contract, now()
+		FROM unnest($1::text[], $2::bigint[], $3::integer[], $4::text[], $5::bigint[], $6::boolean[])
+			AS u(address, first_block, first_index, first_tx, last_block, is_contract)
+		ORDER BY address
+		ON CONFLICT (address) DO UPDATE SET
+			first_seen_block = CASE WHEN `+earlierSQL+` THEN EXCLUDED.first_seen_block ELSE a.first_seen_block END,
+			first_seen_tx_index = CASE WHEN `+earlierSQL+` THEN EXCLUDED.first_seen_tx_index ELSE a.first_seen_tx_index END,
+			first_seen_tx = CASE WHEN `+earlierSQL+` THEN EXCLUDED.first_seen_tx ELSE a.first_seen_tx END,
+			last_active_block = GREATEST(a.last_active_block, EXCLUDED.last_active_block),
+			is_contract = `+contractStatusSQL+`,
+			updated_at = EXCLUDED.updated_at`,
+		addresses, firstBlocks, firstIndexes, firstTxs, lastBlocks, contracts)
+	if err != nil {
+		return fmt.Errorf("failed to record address activity: %w", err)
+	}
+	return nil
+}
+
+// earlierSQL tells whether the upserted first-seen transaction precedes the stored one
+const earlierSQL = `(a.first_seen_block IS NULL OR
+	(EXCLUDED.first_seen_block, EXCLUDED.first_seen_tx_index) < (a.first_seen_block, a.first_seen_tx_index))`
+
+// contractStatusSQL keeps an address known as a contract one, and the stored
+// status when the upserted one is unknown
+const contractStatusSQL = `COALESCE(a.is_contract OR EXCLUDED.is_contract, a.is_contract, EXCLUDED.is_contract)`
+
+// SetContractStatus records whether addresses are contracts
+func (r *PostgresAddressRepositoryImpl) SetContractStatus(ctx context.Context, statuses map[string]bool) error {
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	addresses := make([]string, 0, len(statuses))
+	for address := range statuses {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	contracts := make([]bool, len(addresses))
+	for i, address := range addresses {
+		contracts[i] = statuses[address]
+	}
+
+	_, err := r.db.Pool.Exec(ctx, `UPDATE addresses AS a
+		SET is_contract = a.is_contract IS TRUE OR u.is_contract, updated_at = now()
+		FROM unnest($1::text[], $2::boolean[]) AS u(address, is_contract)
+		WHERE a.address = u.address`,
+		addresses, contracts)
+	if err != nil {
+		return fmt.Errorf("failed to set contract status: %w", err)
+	}
+	return nil
+}
+
+// GetAddress gets an address
+func (r *PostgresAddressRepositoryImpl) GetAddress(ctx context.Context, address string) (*entity.Address, error) {
+	var (
+		result                            entity.Address
+		firstBlock, firstIndex, lastBlock int64
+	)
+	err := r.db.Pool.QueryRow(ctx, `SELECT address, COALESCE(first_seen_block, 0), COALESCE(first_seen_tx_index, 0),
+		COALESCE(first_seen_tx, ''), COALESCE(last_active_block, 0), total, sent, received, is_contract, updated_at
+		FROM addresses WHERE address = $1`, address).
+		Scan(&result.Address, &firstBlock, &firstIndex, &result.FirstSeenTx, &lastBlock,
+			&result.TransactionCount, &result.SentCount, &result.ReceivedCount, &result.IsContract, &result.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.FirstSeenBlock = uint64(firstBlock)
+	result.FirstSeenTxIndex = uint(firstIndex)
+	result.LastActiveBlock = uint64(lastBlock)
+	return &result, nil
+}
+
+// GetUnclassifiedAddresses returns the given addresses not classified yet
+func (r *PostgresAddressRepositoryImpl) GetUnclassifiedAddresses(ctx context.Context, addresses []string) ([]string, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	// In the order given, the ones without a classified row
+	return r.getAddresses(ctx, `SELECT u.address
+		FROM unnest($1::text[]) WITH ORDINALITY AS u(address, position)
+		WHERE NOT EXISTS (SELECT 1 FROM addresses a WHERE a.address = u.address AND a.is_contract IS NOT NULL)
+		ORDER BY u.position`, addresses)
+}
+
+// ListUnclassifiedAddresses pages through the stored addresses not classified yet
+func (r *PostgresAddressRepositoryImpl) ListUnclassifiedAddresses(ctx context.Context, after string, limit int) ([]string, error) {
+	return r.getAddresses(ctx, `SELECT address FROM addresses
+		WHERE is_contract IS NULL AND address > $1 ORDER BY address LIMIT $2`, after, pgLimit(limit))
+}
+
+// getAddresses runs a query selecting addresses
+func (r *PostgresAddressRepositoryImpl) getAddresses(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// RebuildAddresses recomputes the addresses from the stored transactions
+func (r *PostgresAddressRepositoryImpl) RebuildAddresses(ctx context.Context) (int64, error) {
+	var count int64
+	err := pgx.BeginFunc(ctx, r.db.Pool, func(dbTx pgx.Tx) error {
+		if _, err := dbTx.Exec(ctx, "DELETE FROM addresses"); err != nil {
+			return fmt.Errorf("failed to clear addresses: %w", err)
+		}
+
+		// One row per role of each transaction: the sender, a recipient other
+		// than the sender, and the contract it created, which is not counted
+		// as one of its transactions
+		tag, err := dbTx.Exec(ctx, `INSERT INTO addresses
+			(address, total, sent, received, first_seen_block, first_seen_tx_index, first_seen_tx, last_active_block, is_contract)
+			SELECT DISTINCT ON (address) address,
+				sum(counted) OVER w, sum(sent) OVER w, sum(received) OVER w,
+				block_number, transaction_index, hash,
+				max(block_number) OVER w, bool_or(contract) OVER w
+			FROM (
+				SELECT from_address AS address, 1 AS counted, 1 AS sent,
+					CASE WHEN to_address = from_address THEN 1 ELSE 0 END AS received,
+					false AS contract, block_number, transaction_index, hash
+				FROM transactions
+				UNION ALL
+				SELECT to_address, 1, 0, 1, NULL, block_number, transaction_index, hash
+				FROM transactions WHERE to_address <> from_address
+				UNION ALL
+				SELECT contract_address, 0, 0, 0, true, block_number, transaction_index, hash
+				FROM transactions WHERE contract_address IS NOT NULL
+			) roles
+			WINDOW w AS (PARTITION BY address)
+			ORDER BY address, block_number, transaction_index`)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild addresses: %w", err)
+		}
+		count = tag.RowsAffected()
+		return nil
+	})
+	return count, err
+}
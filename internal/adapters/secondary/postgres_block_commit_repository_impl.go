@@ -0,0 +1,186 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PostgresBlockCommitRepositoryImpl implements BlockCommitRepository interface on PostgreSQL
+type PostgresBlockCommitRepositoryImpl struct {
+	db *database.PostgresDB
+}
+
+// NewPostgresBlockCommitRepository creates new PostgreSQL block commit repository
+func NewPostgresBlockCommitRepository(db *database.PostgresDB) repository.BlockCommitRepository {
+	return &PostgresBlockCommitRepositoryImpl{db: db}
+}
+
+// IsAtomic reports whether commits run in a multi-document transaction,
+// which they always do on PostgreSQL
+func (r *PostgresBlockCommitRepositoryImpl) IsAtomic(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+// CommitBlock writes the block, its transactions and its status in one transaction
+func (r *PostgresBlockCommitRepositoryImpl) CommitBlock(ctx context.Context, block *entity.Block, transactions []*entity.Transaction, status entity.BlockStatus) error {
+	return r.CommitBlocks(ctx, []*repository.BlockCommit{{Block: block, Transactions: transactions, Status: status}})
+}
+
+// CommitBlocks writes the blocks, their transactions and their statuses in one
+// transaction. A block stored at the same height with another hash is
+// replaced, its transactions and token transfers deleted, as in MongoDB.
+func (r *PostgresBlockCommitRepositoryImpl) CommitBlocks(ctx context.Context, commits []*repository.BlockCommit) error {
+	if len(commits) == 0 {
+		return nil
+	}
+
+	// The blocks are written with their final status, the statement keeps the
+	// processed time of a stored block unless it becomes processed again
+	blockInsert := insertSQL("blocks", blockColumns, `ON CONFLICT (number) DO UPDATE
+		SET status = EXCLUDED.status,
+			processed_at = CASE WHEN EXCLUDED.status = 'processed' THEN EXCLUDED.processed_at ELSE blocks.processed_at END
+		WHERE blocks.hash = EXCLUDED.hash`)
+
+	now := time.Now()
+	batch := &pgx.Batch{}
+	var (
+		transactions []*entity.Transaction
+		numbers      = make([]int64, 0, len(commits))
+		hashes       = make([]string, 0, len(commits))
+	)
+	for _, commit := range commits {
+		block := *commit.Block
+		block.Status = commit.Status
+		block.ProcessedAt = nil
+		if commit.Status == entity.BlockStatusProcessed {
+			block.ProcessedAt = &now
+		}
+
+		values, err := blockValues(&block)
+		if err != nil {
+			return err
+		}
+		batch.Queue(blockInsert, values...)
+		transactions = append(transactions, commit.Transactions...)
+		number, err := strconv.ParseInt(block.Number, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid block number %q: %w", block.Number, err)
+		}
+		numbers = append(numbers, number)
+		hashes = append(hashes, block.Hash)
+	}
+
+	return pgx.BeginFunc(ctx, r.db.Pool, func(dbTx pgx.Tx) error {
+		if err := deleteReorgedBlocksPostgres(ctx, dbTx, numbers, hashes); err != nil {
+			return err
+		}
+
+		results := dbTx.SendBatch(ctx, batch)
+		for _, commit := range commits {
+			tag, err := results.Exec()
+			if err != nil {
+				results.Close()
+				return fmt.Errorf("failed to save blocks: %w", err)
+			}
+			// A block of another hash was stored at the height meanwhile
+			if tag.RowsAffected() == 0 {
+				results.Close()
+				return fmt.Errorf("failed to save blocks: block %s not stored", commit.Block.Number)
+			}
+		}
+		if err := results.Close(); err != nil {
+			return fmt.Errorf("failed to save blocks: %w", err)
+		}
+		if len(transactions) == 0 {
+			return nil
+		}
+		if err := upsertTransactionsPostgres(ctx, dbTx, transactions); err != nil {
+			return fmt.Errorf("failed to save transactions: %w", err)
+		}
+		return nil
+	})
+}
+
+// deleteReorgedBlocksPostgres deletes the blocks stored at the heights with
+// another hash, left behind by a chain reorganization, together with their
+// transactions and token transfers
+func deleteReorgedBlocksPostgres(ctx context.Context, dbTx pgx.Tx, numbers []int64, hashes []string) error {
+	rows, err := dbTx.Query(ctx, `DELETE FROM blocks
+		WHERE number = ANY($1) AND hash <> ALL($2)
+		RETURNING hash`,
+		numbers, hashes)
+	if err != nil {
+		return fmt.Errorf("failed to delete reorged blocks: %w", err)
+	}
+	reorged, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to delete reorged blocks: %w", err)
+	}
+	if len(reorged) == 0 {
+		return nil
+	}
+
+	if _, err := dbTx.Exec(ctx, "DELETE FROM transactions WHERE block_hash = ANY($1)", reorged); err != nil {
+		return fmt.Errorf("failed to delete transactions of reorged blocks: %w", err)
+	}
+	if _, err := dbTx.Exec(ctx, "DELETE FROM token_transfers WHERE block_hash = ANY($1)", reorged); err != nil {
+		return fmt.Errorf("failed to delete token transfers of reorged blocks: %w", err)
+	}
+	return nil
+}
+
+// CleanupUncommittedBlocks deletes stale pending blocks and their
+// transactions. Commits never leave pending blocks behind on PostgreSQL, this
+// only clears blocks written as pending by other means.
+func (r *PostgresBlockCommitRepositoryImpl) CleanupUncommittedBlocks(ctx context.Context, network string, before time.Time) ([]uint64, error) {
+	var deleted []uint64
+	err := pgx.BeginFunc(ctx, r.db.Pool, func(dbTx pgx.Tx) error {
+		rows, err := dbTx.Query(ctx, `DELETE FROM blocks
+			WHERE network = $1 AND status = $2 AND crawled_at < $3
+			RETURNING number, hash`,
+			network, entity.BlockStatusPending, before)
+		if err != nil {
+			return fmt.Errorf("failed to delete pending blocks: %w", err)
+		}
+
+		var hashes []string
+		for rows.Next() {
+			var (
+				number int64
+				hash   string
+			)
+			if err := rows.Scan(&number, &hash); err != nil {
+				rows.Close()
+				return err
+			}
+			deleted = append(deleted, uint64(number))
+			hashes = append(hashes, hash)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to delete pending blocks: %w", err)
+		}
+
+		if _, err := dbTx.Exec(ctx, "DELETE FROM transactions WHERE block_hash = ANY($1)", hashes); err != nil {
+			return fmt.Errorf("failed to delete transactions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// CountUncountedTransactions does nothing: on PostgreSQL triggers count the
+// transactions in the database transaction writing them
+func (r *PostgresBlockCommitRepositoryImpl) CountUncountedTransactions(ctx context.Context, network string, before time.Time) (int64, error) {
+	return 0, nil
+}
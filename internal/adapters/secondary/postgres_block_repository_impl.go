@@ -0,0 +1,300 @@
+package secondary
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// blockColumns are the blocks columns in the order of blockValues
+var blockColumns = []string{
+	"number", "hash", "parent_hash", "nonce", "sha3_uncles", "logs_bloom",
+	"transactions_root", "state_root", "receipts_root", "miner", "difficulty",
+	"total_difficulty", "extra_data", "size", "gas_limit", "gas_used", "timestamp",
+	"transaction_hashes", "uncles", "crawled_at", "network", "processed_at", "status",
+}
+
+// blockSelect selects the blocks columns in the order of scanBlock
+const blockSelect = `SELECT number, hash, parent_hash, nonce::text, sha3_uncles, logs_bloom,
+	transactions_root, state_root, receipts_root, miner, COALESCE(difficulty::text, ''),
+	COALESCE(total_difficulty::text, ''), extra_data, size, gas_limit, gas_used, timestamp,
+	transaction_hashes, uncles, crawled_at, network, processed_at, status,
+	repair_attempts, next_repair_at
+	FROM blocks`
+
+// PostgresBlockRepositoryImpl implements BlockRepository interface on PostgreSQL
+type PostgresBlockRepositoryImpl struct {
+	db *database.PostgresDB
+}
+
+// NewPostgresBlockRepository creates new PostgreSQL block repository
+func NewPostgresBlockRepository(db *database.PostgresDB) repository.BlockRepository {
+	return &PostgresBlockRepositoryImpl{db: db}
+}
+
+// CreateBlock creates a new block
+func (r *PostgresBlockRepositoryImpl) CreateBlock(ctx context.Context, block *entity.Block) error {
+	values, err := blockValues(block)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Pool.Exec(ctx, insertSQL("blocks", blockColumns, ""), values...)
+	return err
+}
+
+// CreateBlocks creates multiple blocks with COPY
+func (r *PostgresBlockRepositoryImpl) CreateBlocks(ctx context.Context, blocks []*entity.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	rows := make([][]any, len(blocks))
+	for i, block := range blocks {
+		values, err := blockValues(block)
+		if err != nil {
+			return err
+		}
+		rows[i] = values
+	}
+
+	_, err := r.db.Pool.CopyFrom(ctx, pgx.Identifier{"blocks"}, blockColumns, pgx.CopyFromRows(rows))
+	return err
+}
+
+// GetBlockByNumber gets block by number
+func (r *PostgresBlockRepositoryImpl) GetBlockByNumber(ctx context.Context, blockNumber *big.Int) (*entity.Block, error) {
+	return r.getBlock(ctx, blockSelect+" WHERE number = $1", blockNumber.Int64())
+}
+
+// GetBlockByHash gets block by hash
+func (r *PostgresBlockRepositoryImpl) GetBlockByHash(ctx context.Context, hash string) (*entity.Block, error) {
+	return r.getBlock(ctx, blockSelect+" WHERE hash = $1", hash)
+}
+
+// GetBlocksInRange gets blocks in range
+func (r *PostgresBlockRepositoryImpl) GetBlocksInRange(ctx context.Context, startBlock, endBlock *big.Int) ([]*entity.Block, error) {
+	return r.getBlocks(ctx, blockSelect+" WHERE number BETWEEN $1 AND $2 ORDER BY number",
+		startBlock.Int64(), endBlock.Int64())
+}
+
+// GetLastProcessedBlock gets last processed block
+func (r *PostgresBlockRepositoryImpl) GetLastProcessedBlock(ctx context.Context, network string) (*entity.Block, error) {
+	// Incomplete blocks are stored, their missing receipts are repaired in place
+	return r.getBlock(ctx, blockSelect+" WHERE network = $1 AND status IN ($2, $3) ORDER BY number DESC LIMIT 1",
+		network, entity.BlockStatusProcessed, entity.BlockStatusIncomplete)
+}
+
+// GetBlocksByStatus gets blocks by status
+func (r *PostgresBlockRepositoryImpl) GetBlocksByStatus(ctx context.Context, status entity.BlockStatus, limit int) ([]*entity.Block, error) {
+	return r.getBlocks(ctx, blockSelect+" WHERE status = $1 ORDER BY number LIMIT $2", status, pgLimit(limit))
+}
+
+// GetBlocksDueForRepair gets the incomplete blocks due for a receipt repair
+func (r *PostgresBlockRepositoryImpl) GetBlocksDueForRepair(ctx context.Context, network string, now time.Time, limit int) ([]*entity.Block, error) {
+	return r.getBlocks(ctx, blockSelect+` WHERE network = $1 AND status = $2
+		AND (next_repair_at IS NULL OR next_repair_at <= $3)
+		ORDER BY next_repair_at NULLS FIRST, number LIMIT $4`,
+		network, entity.BlockStatusIncomplete, now, pgLimit(limit))
+}
+
+// GetBlocksByTimeRange gets the blocks mined within a time range
+func (r *PostgresBlockRepositoryImpl) GetBlocksByTimeRange(ctx context.Context, network string, startTime, endTime time.Time) ([]*entity.Block, error) {
+	return r.getBlocks(ctx, blockSelect+" WHERE network = $1 AND timestamp BETWEEN $2 AND $3 ORDER BY timestamp, number",
+		network, startTime, endTime)
+}
+
+// GetBlockRangeByTime gets the first and last block mined within a time range
+func (r *PostgresBlockRepositoryImpl) GetBlockRangeByTime(ctx context.Context, network string, startTime, endTime time.Time) (*entity.Block, *entity.Block, error) {
+	const where = " WHERE network = $1 AND timestamp BETWEEN $2 AND $3"
+
+	first, err := r.getBlock(ctx, blockSelect+where+" ORDER BY timestamp, number LIMIT 1", network, startTime, endTime)
+	if err != nil || first == nil {
+		return nil, nil, err
+	}
+
+	last, err := r.getBlock(ctx, blockSelect+where+" ORDER BY timestamp DESC, number DESC LIMIT 1", network, startTime, endTime)
+	if err != nil {
+		return nil, nil, err
+	}
+	return first, last, nil
+}
+
+// UpdateBlockStatus updates block status
+func (r *PostgresBlockRepositoryImpl) UpdateBlockStatus(ctx context.Context, blockHash string, status entity.BlockStatus) error {
+	_, err := r.db.Pool.Exec(ctx, "UPDATE blocks SET status = $2 WHERE hash = $1", blockHash, status)
+	return err
+}
+
+// MarkBlockAsProcessed marks block as processed
+func (r *PostgresBlockRepositoryImpl) MarkBlockAsProcessed(ctx context.Context, blockHash string) error {
+	_, err := r.db.Pool.Exec(ctx, "UPDATE blocks SET status = $2, processed_at = now() WHERE hash = $1",
+		blockHash, entity.BlockStatusProcessed)
+	return err
+}
+
+// ScheduleBlockRepair records a failed receipt repair of a block
+func (r *PostgresBlockRepositoryImpl) ScheduleBlockRepair(ctx context.Context, blockHash string, attempts int, nextAttempt time.Time) error {
+	_, err := r.db.Pool.Exec(ctx, "UPDATE blocks SET repair_attempts = $2, next_repair_at = $3 WHERE hash = $1",
+		blockHash, attempts, nextAttempt)
+	return err
+}
+
+// DeleteBlock deletes block
+func (r *PostgresBlockRepositoryImpl) DeleteBlock(ctx context.Context, blockHash string) error {
+	_, err := r.db.Pool.Exec(ctx, "DELETE FROM blocks WHERE hash = $1", blockHash)
+	return err
+}
+
+// BlockExists checks if block exists
+func (r *PostgresBlockRepositoryImpl) BlockExists(ctx context.Context, blockHash string) (bool, error) {
+	var exists bool
+	err := r.db.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM blocks WHERE hash = $1)", blockHash).Scan(&exists)
+	return exists, err
+}
+
+// GetBlockCount gets total block count
+func (r *PostgresBlockRepositoryImpl) GetBlockCount(ctx context.Context, network string) (int64, error) {
+	return pgCount(ctx, r.db, "SELECT count(*) FROM blocks WHERE network = $1", network)
+}
+
+// GetBlockCountByStatus gets block count by status
+func (r *PostgresBlockRepositoryImpl) GetBlockCountByStatus(ctx context.Context, status entity.BlockStatus, network string) (int64, error) {
+	return pgCount(ctx, r.db, "SELECT count(*) FROM blocks WHERE network = $1 AND status = $2", network, status)
+}
+
+// getBlock returns the first block of a query, nil when there is none
+func (r *PostgresBlockRepositoryImpl) getBlock(ctx context.Context, query string, args ...any) (*entity.Block, error) {
+	block, err := scanBlock(r.db.Pool.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return block, err
+}
+
+// getBlocks returns the blocks of a query
+func (r *PostgresBlockRepositoryImpl) getBlocks(ctx context.Context, query string, args ...any) ([]*entity.Block, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []*entity.Block
+	for rows.Next() {
+		block, err := scanBlock(rows)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, rows.Err()
+}
+
+// scanBlock scans a row selected by blockSelect
+func scanBlock(row pgx.Row) (*entity.Block, error) {
+	var (
+		block  entity.Block
+		number int64
+		nonce  string
+	)
+	err := row.Scan(&number, &block.Hash, &block.ParentHash, &nonce, &block.Sha3Uncles, &block.LogsBloom,
+		&block.TransactionsRoot, &block.StateRoot, &block.ReceiptsRoot, &block.Miner, &block.Difficulty,
+		&block.TotalDifficulty, &block.ExtraData, &block.Size, &block.GasLimit, &block.GasUsed, &block.Timestamp,
+		&block.TransactionHashes, &block.Uncles, &block.CrawledAt, &block.Network, &block.ProcessedAt, &block.Status,
+		&block.RepairAttempts, &block.NextRepairAt)
+	if err != nil {
+		return nil, err
+	}
+
+	block.Number = strconv.FormatInt(number, 10)
+	if block.Nonce, err = strconv.ParseUint(nonce, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid nonce of block %s: %w", block.Number, err)
+	}
+	return &block, nil
+}
+
+// blockValues returns the values of a block in the order of blockColumns
+func blockValues(block *entity.Block) ([]any, error) {
+	number, err := strconv.ParseInt(block.Number, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block number %q: %w", block.Number, err)
+	}
+	difficulty, err := pgNumeric(block.Difficulty)
+	if err != nil {
+		return nil, fmt.Errorf("invalid difficulty of block %s: %w", block.Number, err)
+	}
+	totalDifficulty, err := pgNumeric(block.TotalDifficulty)
+	if err != nil {
+		return nil, fmt.Errorf("invalid total difficulty of block %s: %w", block.Number, err)
+	}
+
+	return []any{
+		number, block.Hash, block.ParentHash, pgtype.Numeric{Int: new(big.Int).SetUint64(block.Nonce), Valid: true},
+		block.Sha3Uncles, block.LogsBloom, block.TransactionsRoot, block.StateRoot, block.ReceiptsRoot,
+		block.Miner, difficulty, totalDifficulty, block.ExtraData, int64(block.Size), int64(block.GasLimit),
+		int64(block.GasUsed), block.Timestamp, pgTextArray(block.TransactionHashes), pgTextArray(block.Uncles),
+		block.CrawledAt, block.Network, block.ProcessedAt, block.Status,
+	}, nil
+}
+
+// pgNumeric converts a decimal string to NUMERIC, an empty string is NULL
+func pgNumeric(value string) (pgtype.Numeric, error) {
+	if value == "" {
+		return pgtype.Numeric{}, nil
+	}
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return pgtype.Numeric{}, fmt.Errorf("invalid decimal %q", value)
+	}
+	return pgtype.Numeric{Int: n, Valid: true}, nil
+}
+
+// pgTextArray keeps nil slices out of NOT NULL array columns
+func pgTextArray(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// pgLimit returns the LIMIT argument of a limit, where 0 means no limit as in MongoDB
+func pgLimit(limit int) any {
+	if limit <= 0 {
+		return nil
+	}
+	return limit
+}
+
+// pgCount runs a count query
+func pgCount(ctx context.Context, db *database.PostgresDB, query string, args ...any) (int64, error) {
+	var count int64
+	err := db.Pool.QueryRow(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+// insertSQL builds an INSERT of one row into the columns, followed by suffix
+func insertSQL(table string, columns []string, suffix string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	if suffix != "" {
+		query += " " + suffix
+	}
+	return query
+}
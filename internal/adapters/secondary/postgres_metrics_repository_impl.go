@@ -0,0 +1,241 @@
+package secondary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// crawlerMetricsColumns are the crawler_metrics columns in the order of crawlerMetricsValues
+var crawlerMetricsColumns = []string{
+	"timestamp", "last_processed_block", "current_block", "blocks_processed", "blocks_per_second",
+	"incomplete_blocks", "transactions_processed", "transactions_per_second", "error_count",
+	"last_error_message", "last_error_time", "average_processing_time", "memory_usage",
+	"goroutine_count", "network_latency", "rpc_calls_count", "rpc_error_rate",
+	"rpc_rate_limited_count", "concurrent_workers", "request_delay", "db_connection_count",
+	"db_writes_per_second", "network",
+}
+
+// crawlerMetricsSelect selects the crawler_metrics columns in the order of scanCrawlerMetrics
+const crawlerMetricsSelect = `SELECT timestamp, last_processed_block, current_block, blocks_processed,
+	blocks_per_second, incomplete_blocks, transactions_processed, transactions_per_second, error_count,
+	last_error_message, last_error_time, average_processing_time, memory_usage, goroutine_count,
+	network_latency, rpc_calls_count, rpc_error_rate, rpc_rate_limited_count, concurrent_workers,
+	request_delay, db_connection_count, db_writes_per_second, network
+	FROM crawler_metrics`
+
+// systemHealthSelect selects the system_health columns in the order of scanSystemHealth
+const systemHealthSelect = `SELECT timestamp, status, components_health, message, network FROM system_health`
+
+// PostgresMetricsRepositoryImpl implements MetricsRepository interface on PostgreSQL
+type PostgresMetricsRepositoryImpl struct {
+	db *database.PostgresDB
+}
+
+// NewPostgresMetricsRepository creates new PostgreSQL metrics repository
+func NewPostgresMetricsRepository(db *database.PostgresDB) repository.MetricsRepository {
+	return &PostgresMetricsRepositoryImpl{db: db}
+}
+
+// SaveCrawlerMetrics saves crawler metrics
+func (r *PostgresMetricsRepositoryImpl) SaveCrawlerMetrics(ctx context.Context, metrics *entity.CrawlerMetrics) error {
+	_, err := r.db.Pool.Exec(ctx, insertSQL("crawler_metrics", crawlerMetricsColumns, ""), crawlerMetricsValues(metrics)...)
+	return err
+}
+
+// GetLatestCrawlerMetrics gets latest crawler metrics
+func (r *PostgresMetricsRepositoryImpl) GetLatestCrawlerMetrics(ctx context.Context, network string) (*entity.CrawlerMetrics, error) {
+	metrics, err := scanCrawlerMetrics(r.db.Pool.QueryRow(ctx,
+		crawlerMetricsSelect+" WHERE network = $1 ORDER BY timestamp DESC LIMIT 1", network))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return metrics, err
+}
+
+// GetCrawlerMetricsByTimeRange gets crawler metrics by time range
+func (r *PostgresMetricsRepositoryImpl) GetCrawlerMetricsByTimeRange(ctx context.Context, network string, startTime, endTime time.Time) ([]*entity.CrawlerMetrics, error) {
+	return r.getCrawlerMetrics(ctx, crawlerMetricsSelect+" WHERE network = $1 AND timestamp BETWEEN $2 AND $3 ORDER BY timestamp",
+		network, startTime, endTime)
+}
+
+// GetCrawlerMetricsHistory gets crawler metrics history
+func (r *PostgresMetricsRepositoryImpl) GetCrawlerMetricsHistory(ctx context.Context, network string, limit int) ([]*entity.CrawlerMetrics, error) {
+	return r.getCrawlerMetrics(ctx, crawlerMetricsSelect+" WHERE network = $1 ORDER BY timestamp DESC LIMIT $2",
+		network, pgLimit(limit))
+}
+
+// SaveSystemHealth saves system health
+func (r *PostgresMetricsRepositoryImpl) SaveSystemHealth(ctx context.Context, health *entity.SystemHealth) error {
+	components, err := json.Marshal(health.ComponentsHealth)
+	if err != nil {
+		return fmt.Errorf("failed to encode components health: %w", err)
+	}
+
+	_, err = r.db.Pool.Exec(ctx, `INSERT INTO system_health (timestamp, status, components_health, message, network)
+		VALUES ($1, $2, $3, $4, $5)`,
+		health.Timestamp, health.Status, components, health.Message, health.Network)
+	return err
+}
+
+// GetLatestSystemHealth gets latest system health
+func (r *PostgresMetricsRepositoryImpl) GetLatestSystemHealth(ctx context.Context, network string) (*entity.SystemHealth, error) {
+	health, err := scanSystemHealth(r.db.Pool.QueryRow(ctx,
+		systemHealthSelect+" WHERE network = $1 ORDER BY timestamp DESC LIMIT 1", network))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return health, err
+}
+
+// GetSystemHealthHistory gets system health history
+func (r *PostgresMetricsRepositoryImpl) GetSystemHealthHistory(ctx context.Context, network string, limit int) ([]*entity.SystemHealth, error) {
+	rows, err := r.db.Pool.Query(ctx, systemHealthSelect+" WHERE network = $1 ORDER BY timestamp DESC LIMIT $2",
+		network, pgLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var healthRecords []*entity.SystemHealth
+	for rows.Next() {
+		health, err := scanSystemHealth(rows)
+		if err != nil {
+			return nil, err
+		}
+		healthRecords = append(healthRecords, health)
+	}
+
+	return healthRecords, rows.Err()
+}
+
+// GetAverageProcessingTime gets average processing time
+func (r *PostgresMetricsRepositoryImpl) GetAverageProcessingTime(ctx context.Context, network string, timeRange time.Duration) (time.Duration, error) {
+	var average int64
+	err := r.db.Pool.QueryRow(ctx, `SELECT COALESCE(avg(average_processing_time), 0)::bigint
+		FROM crawler_metrics WHERE network = $1 AND timestamp >= $2`,
+		network, time.Now().Add(-timeRange)).Scan(&average)
+	return time.Duration(average), err
+}
+
+// GetErrorRate gets error rate
+func (r *PostgresMetricsRepositoryImpl) GetErrorRate(ctx context.Context, network string, timeRange time.Duration) (float64, error) {
+	var errorRate float64
+	err := r.db.Pool.QueryRow(ctx, `SELECT COALESCE(sum(error_count)::double precision / NULLIF(sum(blocks_processed), 0)::double precision, 0)
+		FROM crawler_metrics WHERE network = $1 AND timestamp >= $2`,
+		network, time.Now().Add(-timeRange)).Scan(&errorRate)
+	return errorRate, err
+}
+
+// GetThroughputStats gets throughput statistics
+func (r *PostgresMetricsRepositoryImpl) GetThroughputStats(ctx context.Context, network string, timeRange time.Duration) (map[string]float64, error) {
+	var (
+		count                                                  int64
+		avgBlocks, avgTransactions, maxBlocks, maxTransactions *float64
+	)
+	err := r.db.Pool.QueryRow(ctx, `SELECT count(*), avg(blocks_per_second), avg(transactions_per_second),
+		max(blocks_per_second), max(transactions_per_second)
+		FROM crawler_metrics WHERE network = $1 AND timestamp >= $2`,
+		network, time.Now().Add(-timeRange)).Scan(&count, &avgBlocks, &avgTransactions, &maxBlocks, &maxTransactions)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return make(map[string]float64), nil
+	}
+
+	return map[string]float64{
+		"avg_blocks_per_second":       *avgBlocks,
+		"avg_transactions_per_second": *avgTransactions,
+		"max_blocks_per_second":       *maxBlocks,
+		"max_transactions_per_second": *maxTransactions,
+	}, nil
+}
+
+// CleanupOldMetrics cleans up old metrics
+func (r *PostgresMetricsRepositoryImpl) CleanupOldMetrics(ctx context.Context, olderThan time.Time) error {
+	// Delete old crawler metrics
+	if _, err := r.db.Pool.Exec(ctx, "DELETE FROM crawler_metrics WHERE timestamp < $1", olderThan); err != nil {
+		return err
+	}
+
+	// Delete old health records
+	_, err := r.db.Pool.Exec(ctx, "DELETE FROM system_health WHERE timestamp < $1", olderThan)
+	return err
+}
+
+// getCrawlerMetrics returns the crawler metrics of a query
+func (r *PostgresMetricsRepositoryImpl) getCrawlerMetrics(ctx context.Context, query string, args ...any) ([]*entity.CrawlerMetrics, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metrics []*entity.CrawlerMetrics
+	for rows.Next() {
+		m, err := scanCrawlerMetrics(rows)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics, rows.Err()
+}
+
+// scanCrawlerMetrics scans a row selected by crawlerMetricsSelect
+func scanCrawlerMetrics(row pgx.Row) (*entity.CrawlerMetrics, error) {
+	var (
+		m                                            entity.CrawlerMetrics
+		averageProcessingTime, latency, requestDelay int64
+	)
+	err := row.Scan(&m.Timestamp, &m.LastProcessedBlock, &m.CurrentBlock, &m.BlocksProcessed,
+		&m.BlocksPerSecond, &m.IncompleteBlocks, &m.TransactionsProcessed, &m.TransactionsPerSecond, &m.ErrorCount,
+		&m.LastErrorMessage, &m.LastErrorTime, &averageProcessingTime, &m.MemoryUsage, &m.GoroutineCount,
+		&latency, &m.RPCCallsCount, &m.RPCErrorRate, &m.RPCRateLimitedCount, &m.ConcurrentWorkers,
+		&requestDelay, &m.DBConnectionCount, &m.DBWritesPerSecond, &m.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	m.AverageProcessingTime = time.Duration(averageProcessingTime)
+	m.NetworkLatency = time.Duration(latency)
+	m.RequestDelay = time.Duration(requestDelay)
+	return &m, nil
+}
+
+// crawlerMetricsValues returns the values of crawler metrics in the order of crawlerMetricsColumns
+func crawlerMetricsValues(m *entity.CrawlerMetrics) []any {
+	return []any{
+		m.Timestamp, int64(m.LastProcessedBlock), int64(m.CurrentBlock), int64(m.BlocksProcessed), m.BlocksPerSecond,
+		m.IncompleteBlocks, int64(m.TransactionsProcessed), m.TransactionsPerSecond, int64(m.ErrorCount),
+		m.LastErrorMessage, m.LastErrorTime, int64(m.AverageProcessingTime), int64(m.MemoryUsage),
+		m.GoroutineCount, int64(m.NetworkLatency), int64(m.RPCCallsCount), m.RPCErrorRate,
+		int64(m.RPCRateLimitedCount), m.ConcurrentWorkers, int64(m.RequestDelay), m.DBConnectionCount,
+		m.DBWritesPerSecond, m.Network,
+	}
+}
+
+// scanSystemHealth scans a row selected by systemHealthSelect
+func scanSystemHealth(row pgx.Row) (*entity.SystemHealth, error) {
+	var (
+		health     entity.SystemHealth
+		components []byte
+	)
+	if err := row.Scan(&health.Timestamp, &health.Status, &components, &health.Message, &health.Network); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(components, &health.ComponentsHealth); err != nil {
+		return nil, fmt.Errorf("failed to decode components health: %w", err)
+	}
+	return &health, nil
+}
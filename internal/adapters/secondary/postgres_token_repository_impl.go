@@ -0,0 +1,176 @@
+package secondary
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// tokenSelect selects the tokens columns in the order of scanToken
+const tokenSelect = `SELECT address, standard, first_seen_block, name, symbol, decimals, COALESCE(total_supply::text, ''),
+	interfaces, resolved_at, failures, last_error, refresh_at, created_at, updated_at
+	FROM tokens`
+
+// PostgresTokenRepositoryImpl implements TokenRepository interface on PostgreSQL
+type PostgresTokenRepositoryImpl struct {
+	db *database.PostgresDB
+}
+
+// NewPostgresTokenRepository creates new PostgreSQL token repository
+func NewPostgresTokenRepository(db *database.PostgresDB) repository.TokenRepository {
+	return &PostgresTokenRepositoryImpl{db: db}
+}
+
+// AddTokens inserts the tokens not stored yet
+func (r *PostgresTokenRepositoryImpl) AddTokens(ctx context.Context, tokens []*entity.Token) ([]string, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	n := len(tokens)
+	addresses, standards := make([]string, 0, n), make([]string, 0, n)
+	firstBlocks, refreshAts := make([]int64, 0, n), make([]time.Time, 0, n)
+	for _, token := range tokens {
+		addresses = append(addresses, token.Address)
+		standards = append(standards, string(token.Standard))
+		firstBlocks = append(firstBlocks, int64(token.FirstSeenBlock))
+		refreshAts = append(refreshAts, token.RefreshAt)
+	}
+
+	// In address order, so concurrent blocks lock shared tokens in the same order
+	rows, err := r.db.Pool.Query(ctx, `INSERT INTO tokens (address, standard, first_seen_block, refresh_at, created_at, updated_at)
+		SELECT address, standard, first_block, refresh_at, now(), now()
+		FROM unnest($1::text[], $2::text[], $3::bigint[], $4::timestamptz[]) AS u(address, standard, first_block, refresh_at)
+		ORDER BY address
+		ON CONFLICT (address) DO NOTHING
+		RETURNING address`,
+		addresses, standards, firstBlocks, refreshAts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add tokens: %w", err)
+	}
+
+	added, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to add tokens: %w", err)
+	}
+	sort.Strings(added)
+	return added, nil
+}
+
+// SaveToken stores the metadata and refresh state of a token
+func (r *PostgresTokenRepositoryImpl) SaveToken(ctx context.Context, token *entity.Token) error {
+	totalSupply, err := pgNumeric(token.TotalSupply)
+	if err != nil {
+		return fmt.Errorf("invalid total supply of token %s: %w", token.Address, err)
+	}
+	var decimals *int16
+	if token.Decimals != nil {
+		value := int16(*token.Decimals)
+		decimals = &value
+	}
+	var resolvedAt *time.Time
+	if token.IsResolved() {
+		resolvedAt = &token.ResolvedAt
+	}
+	interfaces := make([]string, 0, len(token.Interfaces))
+	for _, iface := range token.Interfaces {
+		interfaces = append(interfaces, string(iface))
+	}
+	createdAt := token.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err = r.db.Pool.Exec(ctx, `INSERT INTO tokens AS t
+		(address, standard, first_seen_block, name, symbol, decimals, total_supply, interfaces,
+			resolved_at, failures, last_error, refresh_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, now())
+		ON CONFLICT (address) DO UPDATE SET
+			standard = EXCLUDED.standard,
+			name = EXCLUDED.name,
+			symbol = EXCLUDED.symbol,
+			decimals = EXCLUDED.decimals,
+			total_supply = EXCLUDED.total_supply,
+			interfaces = EXCLUDED.interfaces,
+			resolved_at = COALESCE(EXCLUDED.resolved_at, t.resolved_at),
+			failures = EXCLUDED.failures,
+			last_error = EXCLUDED.last_error,
+			refresh_at = EXCLUDED.refresh_at,
+			updated_at = EXCLUDED.updated_at`,
+		token.Address, string(token.Standard), int64(token.FirstSeenBlock), token.Name, token.Symbol, decimals,
+		totalSupply, interfaces, resolvedAt, token.Failures, token.LastError, token.RefreshAt, createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	return nil
+}
+
+// GetToken gets a token
+func (r *PostgresTokenRepositoryImpl) GetToken(ctx context.Context, address string) (*entity.Token, error) {
+	token, err := scanToken(r.db.Pool.QueryRow(ctx, tokenSelect+" WHERE address = $1", address))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// GetTokensToRefresh gets the tokens due for a lookup
+func (r *PostgresTokenRepositoryImpl) GetTokensToRefresh(ctx context.Context, now time.Time, limit int) ([]*entity.Token, error) {
+	rows, err := r.db.Pool.Query(ctx, tokenSelect+" WHERE refresh_at <= $1 ORDER BY refresh_at LIMIT $2",
+		now, pgLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*entity.Token
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// scanToken scans a row selected with tokenSelect
+func scanToken(row pgx.Row) (*entity.Token, error) {
+	var (
+		token      entity.Token
+		standard   string
+		firstBlock int64
+		decimals   *int16
+		interfaces []string
+		resolvedAt *time.Time
+	)
+	err := row.Scan(&token.Address, &standard, &firstBlock, &token.Name, &token.Symbol, &decimals, &token.TotalSupply,
+		&interfaces, &resolvedAt, &token.Failures, &token.LastError, &token.RefreshAt, &token.CreatedAt, &token.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Standard = entity.TokenStandard(standard)
+	token.FirstSeenBlock = uint64(firstBlock)
+	if decimals != nil {
+		value := uint8(*decimals)
+		token.Decimals = &value
+	}
+	for _, iface := range interfaces {
+		token.Interfaces = append(token.Interfaces, entity.TokenInterface(iface))
+	}
+	if resolvedAt != nil {
+		token.ResolvedAt = *resolvedAt
+	}
+	return &token, nil
+}
@@ -0,0 +1,154 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// tokenTransferColumns are the token_transfers columns in the order of tokenTransferValues
+var tokenTransferColumns = []string{
+	"transaction_hash", "log_index", "batch_index", "token", "standard", "from_address", "to_address",
+	"operator", "amount", "token_id", "transaction_index", "block_number", "block_hash", "block_timestamp",
+	"network", "crawled_at",
+}
+
+// tokenTransferSelect selects the token_transfers columns in the order of scanTokenTransfer
+const tokenTransferSelect = `SELECT transaction_hash, log_index, batch_index, token, standard, from_address, to_address,
+	COALESCE(operator, ''), amount::text, COALESCE(token_id::text, ''), transaction_index, block_number, block_hash,
+	block_timestamp, network, crawled_at
+	FROM token_transfers`
+
+// tokenTransferNewest orders token transfers newest first
+const tokenTransferNewest = " ORDER BY block_number DESC, log_index DESC, batch_index DESC LIMIT $2 OFFSET $3"
+
+// PostgresTokenTransferRepositoryImpl implements TokenTransferRepository interface on PostgreSQL
+type PostgresTokenTransferRepositoryImpl struct {
+	db *database.PostgresDB
+}
+
+// NewPostgresTokenTransferRepository creates new PostgreSQL token transfer repository
+func NewPostgresTokenTransferRepository(db *database.PostgresDB) repository.TokenTransferRepository {
+	return &PostgresTokenTransferRepositoryImpl{db: db}
+}
+
+// SaveTokenTransfers upserts transfers by transaction, log index and batch index
+func (r *PostgresTokenTransferRepositoryImpl) SaveTokenTransfers(ctx context.Context, transfers []*entity.TokenTransfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	updates := make([]string, 0, len(tokenTransferColumns)-3)
+	for _, column := range tokenTransferColumns[3:] {
+		updates = append(updates, column+" = EXCLUDED."+column)
+	}
+	query := insertSQL("token_transfers", tokenTransferColumns,
+		"ON CONFLICT (transaction_hash, log_index, batch_index) DO UPDATE SET "+strings.Join(updates, ", "))
+
+	batch := &pgx.Batch{}
+	for _, transfer := range transfers {
+		values, err := tokenTransferValues(transfer)
+		if err != nil {
+			return err
+		}
+		batch.Queue(query, values...)
+	}
+
+	if err := r.db.Pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save token transfers: %w", err)
+	}
+	return nil
+}
+
+// GetTokenTransfersByTransaction gets the transfers of a transaction in log order
+func (r *PostgresTokenTransferRepositoryImpl) GetTokenTransfersByTransaction(ctx context.Context, txHash string) ([]*entity.TokenTransfer, error) {
+	return r.getTokenTransfers(ctx, tokenTransferSelect+" WHERE transaction_hash = $1 ORDER BY log_index, batch_index", txHash)
+}
+
+// GetTokenTransfersByToken gets the transfers of a token, newest first
+func (r *PostgresTokenTransferRepositoryImpl) GetTokenTransfersByToken(ctx context.Context, token string, limit int, offset int) ([]*entity.TokenTransfer, error) {
+	return r.getTokenTransfers(ctx, tokenTransferSelect+" WHERE token = $1"+tokenTransferNewest,
+		token, pgLimit(limit), offset)
+}
+
+// GetTokenTransfersByHolder gets the transfers from or to an address, newest first
+func (r *PostgresTokenTransferRepositoryImpl) GetTokenTransfersByHolder(ctx context.Context, holder string, limit int, offset int) ([]*entity.TokenTransfer, error) {
+	return r.getTokenTransfers(ctx, tokenTransferSelect+" WHERE from_address = $1 OR to_address = $1"+tokenTransferNewest,
+		holder, pgLimit(limit), offset)
+}
+
+// DeleteTokenTransfersByBlockHash deletes the transfers of a block
+func (r *PostgresTokenTransferRepositoryImpl) DeleteTokenTransfersByBlockHash(ctx context.Context, blockHash string) error {
+	_, err := r.db.Pool.Exec(ctx, "DELETE FROM token_transfers WHERE block_hash = $1", blockHash)
+	return err
+}
+
+// getTokenTransfers returns the token transfers of a query
+func (r *PostgresTokenTransferRepositoryImpl) getTokenTransfers(ctx context.Context, query string, args ...any) ([]*entity.TokenTransfer, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []*entity.TokenTransfer
+	for rows.Next() {
+		transfer, err := scanTokenTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, rows.Err()
+}
+
+// scanTokenTransfer scans a row selected by tokenTransferSelect
+func scanTokenTransfer(row pgx.Row) (*entity.TokenTransfer, error) {
+	var (
+		transfer                      entity.TokenTransfer
+		logIndex, batchIndex, txIndex int32
+		blockNumber                   int64
+	)
+	err := row.Scan(&transfer.TransactionHash, &logIndex, &batchIndex, &transfer.Token, &transfer.Standard,
+		&transfer.From, &transfer.To, &transfer.Operator, &transfer.Amount, &transfer.TokenID, &txIndex,
+		&blockNumber, &transfer.BlockHash, &transfer.BlockTimestamp, &transfer.Network, &transfer.CrawledAt)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.LogIndex = uint(logIndex)
+	transfer.BatchIndex = uint(batchIndex)
+	transfer.TransactionIndex = uint(txIndex)
+	transfer.BlockNumber = uint64(blockNumber)
+	return &transfer, nil
+}
+
+// tokenTransferValues returns the values of a transfer in the order of tokenTransferColumns
+func tokenTransferValues(transfer *entity.TokenTransfer) ([]any, error) {
+	amount, err := pgNumeric(transfer.Amount)
+	if err != nil || !amount.Valid {
+		return nil, fmt.Errorf("invalid amount %q of token transfer %s:%d", transfer.Amount, transfer.TransactionHash, transfer.LogIndex)
+	}
+	tokenID, err := pgNumeric(transfer.TokenID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token ID of token transfer %s:%d: %w", transfer.TransactionHash, transfer.LogIndex, err)
+	}
+
+	var operator *string
+	if transfer.Operator != "" {
+		operator = &transfer.Operator
+	}
+
+	return []any{
+		transfer.TransactionHash, int32(transfer.LogIndex), int32(transfer.BatchIndex), transfer.Token,
+		transfer.Standard, transfer.From, transfer.To, operator, amount, tokenID,
+		int32(transfer.TransactionIndex), int64(transfer.BlockNumber), transfer.BlockHash, transfer.BlockTimestamp,
+		transfer.Network, transfer.CrawledAt,
+	}, nil
+}
@@ -0,0 +1,393 @@
+package secondary
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// transactionColumns are the transactions columns in the order of transactionValues
+var transactionColumns = []string{
+	"hash", "block_hash", "block_number", "transaction_index", "from_address", "to_address",
+	"value", "gas", "gas_price", "gas_used", "cumulative_gas_used", "data", "nonce", "status",
+	"max_fee_per_gas", "max_priority_fee_per_gas", "contract_address", "receipt_missing",
+	"crawled_at", "network", "processed_at", "tx_status", "block_timestamp",
+}
+
+// transactionSelect selects the transactions columns in the order of scanTransaction
+const transactionSelect = `SELECT hash, block_hash, block_number, transaction_index, from_address, to_address,
+	value::text, gas, COALESCE(gas_price::text, ''), gas_used, cumulative_gas_used, data, nonce, status,
+	COALESCE(max_fee_per_gas::text, ''), COALESCE(max_priority_fee_per_gas::text, ''), contract_address,
+	receipt_missing, crawled_at, network, processed_at, tx_status, block_timestamp
+	FROM transactions`
+
+// PostgresTransactionRepositoryImpl implements TransactionRepository interface on PostgreSQL
+type PostgresTransactionRepositoryImpl struct {
+	db *database.PostgresDB
+}
+
+// NewPostgresTransactionRepository creates new PostgreSQL transaction repository
+func NewPostgresTransactionRepository(db *database.PostgresDB) repository.TransactionRepository {
+	return &PostgresTransactionRepositoryImpl{db: db}
+}
+
+// CreateTransaction creates a new transaction
+func (r *PostgresTransactionRepositoryImpl) CreateTransaction(ctx context.Context, tx *entity.Transaction) error {
+	values, err := transactionValues(tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Pool.Exec(ctx, insertSQL("transactions", transactionColumns, ""), values...)
+	return err
+}
+
+// CreateTransactions creates multiple transactions with COPY
+func (r *PostgresTransactionRepositoryImpl) CreateTransactions(ctx context.Context, txs []*entity.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+
+	rows, err := transactionRows(txs)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Pool.CopyFrom(ctx, pgx.Identifier{"transactions"}, transactionColumns, pgx.CopyFromRows(rows))
+	return err
+}
+
+// UpsertTransactions upserts multiple transactions by hash
+func (r *PostgresTransactionRepositoryImpl) UpsertTransactions(ctx context.Context, txs []*entity.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+
+	return pgx.BeginFunc(ctx, r.db.Pool, func(dbTx pgx.Tx) error {
+		return upsertTransactionsPostgres(ctx, dbTx, txs)
+	})
+}
+
+// upsertTransactionsPostgres copies the transactions into a staging table and
+// upserts them from there by hash, in the caller's database transaction
+func upsertTransactionsPostgres(ctx context.Context, dbTx pgx.Tx, txs []*entity.Transaction) error {
+	rows, err := transactionRows(txs)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbTx.Exec(ctx, "CREATE TEMP TABLE transactions_staging (LIKE transactions INCLUDING DEFAULTS) ON COMMIT DROP")
+	if err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+	if _, err := dbTx.CopyFrom(ctx, pgx.Identifier{"transactions_staging"}, transactionColumns, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("failed to copy transactions: %w", err)
+	}
+
+	updates := make([]string, 0, len(transactionColumns)-1)
+	for _, column := range transactionColumns[1:] {
+		updates = append(updates, column+" = EXCLUDED."+column)
+	}
+	columns := strings.Join(transactionColumns, ", ")
+
+	// A statement can't upsert a row twice, the staging table keeps one copy per hash
+	query := fmt.Sprintf(`INSERT INTO transactions (%s)
+		SELECT DISTINCT ON (hash) %s FROM transactions_staging ORDER BY hash
+		ON CONFLICT (hash) DO UPDATE SET %s`, columns, columns, strings.Join(updates, ", "))
+	if _, err := dbTx.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to upsert transactions: %w", err)
+	}
+
+	_, err = dbTx.Exec(ctx, "DROP TABLE transactions_staging")
+	return err
+}
+
+// GetTransactionByHash gets transaction by hash
+func (r *PostgresTransactionRepositoryImpl) GetTransactionByHash(ctx context.Context, hash string) (*entity.Transaction, error) {
+	tx, err := scanTransaction(r.db.Pool.QueryRow(ctx, transactionSelect+" WHERE hash = $1", hash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return tx, err
+}
+
+// GetTransactionsByBlockHash gets transactions by block hash
+func (r *PostgresTransactionRepositoryImpl) GetTransactionsByBlockHash(ctx context.Context, blockHash string) ([]*entity.Transaction, error) {
+	return r.getTransactions(ctx, transactionSelect+" WHERE block_hash = $1 ORDER BY transaction_index", blockHash)
+}
+
+// GetTransactionsByBlockNumber gets transactions by block number
+func (r *PostgresTransactionRepositoryImpl) GetTransactionsByBlockNumber(ctx context.Context, blockNumber *big.Int) ([]*entity.Transaction, error) {
+	return r.getTransactions(ctx, transactionSelect+" WHERE block_number = $1 ORDER BY transaction_index", blockNumber.Int64())
+}
+
+// GetTransactionsByAddress gets transactions by address
+func (r *PostgresTransactionRepositoryImpl) GetTransactionsByAddress(ctx context.Context, address string, limit int, offset int) ([]*entity.Transaction, error) {
+	return r.getTransactions(ctx, transactionSelect+` WHERE from_address = $1 OR to_address = $1
+		ORDER BY block_number DESC, transaction_index DESC LIMIT $2 OFFSET $3`,
+		address, pgLimit(limit), offset)
+}
+
+// GetAddressTransactions gets the transactions of an address, newest first,
+// paginated by block number and transaction index
+func (r *PostgresTransactionRepositoryImpl) GetAddressTransactions(ctx context.Context, filter repository.AddressTransactionFilter, after *repository.TransactionPosition, limit int) ([]*entity.Transaction, error) {
+	args := []any{filter.Address, pgLimit(limit)}
+	var conditions strings.Builder
+	condition := func(format string, values ...any) {
+		placeholders := make([]any, len(values))
+		for i, value := range values {
+			args = append(args, value)
+			placeholders[i] = "$" + strconv.Itoa(len(args))
+		}
+		conditions.WriteString(" AND " + fmt.Sprintf(format, placeholders...))
+	}
+
+	if filter.Success != nil {
+		status := 0
+		if *filter.Success {
+			status = 1
+		}
+		condition("status = %s AND NOT receipt_missing", status)
+	}
+	if filter.ContractCreation != nil {
+		if *filter.ContractCreation {
+			conditions.WriteString(" AND to_address IS NULL")
+		} else {
+			conditions.WriteString(" AND to_address IS NOT NULL")
+		}
+	}
+	if filter.FromBlock != nil {
+		condition("block_number >= %s", int64(*filter.FromBlock))
+	}
+	if filter.ToBlock != nil {
+		condition("block_number <= %s", int64(*filter.ToBlock))
+	}
+	if after != nil {
+		condition("(block_number, transaction_index) < (%s, %s)", int64(after.BlockNumber), int32(after.TransactionIndex))
+	}
+
+	const order = " ORDER BY block_number DESC, transaction_index DESC LIMIT $2"
+	switch filter.Direction {
+	case repository.AddressDirectionIn:
+		return r.getTransactions(ctx, transactionSelect+" WHERE to_address = $1"+conditions.String()+order, args...)
+	case repository.AddressDirectionOut:
+		return r.getTransactions(ctx, transactionSelect+" WHERE from_address = $1"+conditions.String()+order, args...)
+	}
+
+	// One branch per role, so each is served by its address index in position order
+	query := fmt.Sprintf(`%[1]s WHERE hash IN (
+		(SELECT hash FROM transactions WHERE from_address = $1%[2]s%[3]s)
+		UNION ALL
+		(SELECT hash FROM transactions WHERE to_address = $1 AND from_address <> $1%[2]s%[3]s))%[3]s`,
+		transactionSelect, conditions.String(), order)
+	return r.getTransactions(ctx, query, args...)
+}
+
+// GetTransactionsByStatus gets transactions by status
+func (r *PostgresTransactionRepositoryImpl) GetTransactionsByStatus(ctx context.Context, status entity.TransactionStatus, limit int) ([]*entity.Transaction, error) {
+	return r.getTransactions(ctx, transactionSelect+" WHERE tx_status = $1 ORDER BY block_number, transaction_index LIMIT $2",
+		status, pgLimit(limit))
+}
+
+// GetTransactionsByTimeRange gets transactions by the timestamp of their block
+func (r *PostgresTransactionRepositoryImpl) GetTransactionsByTimeRange(ctx context.Context, startTime, endTime time.Time) ([]*entity.Transaction, error) {
+	return r.getTransactions(ctx, transactionSelect+" WHERE block_timestamp BETWEEN $1 AND $2 ORDER BY block_timestamp, block_number, transaction_index",
+		startTime, endTime)
+}
+
+// UpdateTransactionStatus updates transaction status
+func (r *PostgresTransactionRepositoryImpl) UpdateTransactionStatus(ctx context.Context, hash string, status entity.TransactionStatus) error {
+	_, err := r.db.Pool.Exec(ctx, "UPDATE transactions SET tx_status = $2 WHERE hash = $1", hash, status)
+	return err
+}
+
+// UpdateTransactionReceipt patches the receipt fields of a stored transaction
+// and clears its receipt_missing flag
+func (r *PostgresTransactionRepositoryImpl) UpdateTransactionReceipt(ctx context.Context, tx *entity.Transaction) error {
+	result, err := r.db.Pool.Exec(ctx, `UPDATE transactions
+		SET status = $2, gas_used = $3, cumulative_gas_used = $4, contract_address = $5, tx_status = $6, receipt_missing = false
+		WHERE hash = $1`,
+		tx.Hash, int16(tx.Status), int64(tx.GasUsed), int64(tx.CumulativeGasUsed), tx.ContractAddress, tx.TxStatus)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("transaction %s not found", tx.Hash)
+	}
+	return nil
+}
+
+// MarkTransactionAsProcessed marks transaction as processed
+func (r *PostgresTransactionRepositoryImpl) MarkTransactionAsProcessed(ctx context.Context, hash string) error {
+	_, err := r.db.Pool.Exec(ctx, "UPDATE transactions SET tx_status = $2, processed_at = now() WHERE hash = $1",
+		hash, entity.TransactionStatusProcessed)
+	return err
+}
+
+// DeleteTransaction deletes transaction
+func (r *PostgresTransactionRepositoryImpl) DeleteTransaction(ctx context.Context, hash string) error {
+	_, err := r.db.Pool.Exec(ctx, "DELETE FROM transactions WHERE hash = $1", hash)
+	return err
+}
+
+// DeleteTransactionsByBlockHash deletes transactions by block hash
+func (r *PostgresTransactionRepositoryImpl) DeleteTransactionsByBlockHash(ctx context.Context, blockHash string) error {
+	_, err := r.db.Pool.Exec(ctx, "DELETE FROM transactions WHERE block_hash = $1", blockHash)
+	return err
+}
+
+// TransactionExists checks if transaction exists
+func (r *PostgresTransactionRepositoryImpl) TransactionExists(ctx context.Context, hash string) (bool, error) {
+	var exists bool
+	err := r.db.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM transactions WHERE hash = $1)", hash).Scan(&exists)
+	return exists, err
+}
+
+// GetTransactionCount gets total transaction count
+func (r *PostgresTransactionRepositoryImpl) GetTransactionCount(ctx context.Context, network string) (int64, error) {
+	return pgCount(ctx, r.db, "SELECT count(*) FROM transactions WHERE network = $1", network)
+}
+
+// GetTransactionCountByStatus gets transaction count by status
+func (r *PostgresTransactionRepositoryImpl) GetTransactionCountByStatus(ctx context.Context, status entity.TransactionStatus, network string) (int64, error) {
+	return pgCount(ctx, r.db, "SELECT count(*) FROM transactions WHERE network = $1 AND tx_status = $2", network, status)
+}
+
+// GetTransactionCountByAddress gets transaction count by address from its counters
+func (r *PostgresTransactionRepositoryImpl) GetTransactionCountByAddress(ctx context.Context, address string) (int64, error) {
+	count, err := r.GetAddressTransactionCount(ctx, address)
+	if err != nil {
+		return 0, err
+	}
+	return count.Total, nil
+}
+
+// GetAddressTransactionCount gets the transaction counters of an address,
+// maintained by triggers on the transactions table
+func (r *PostgresTransactionRepositoryImpl) GetAddressTransactionCount(ctx context.Context, address string) (*entity.AddressTransactionCount, error) {
+	count := &entity.AddressTransactionCount{Address: address}
+	err := r.db.Pool.QueryRow(ctx, "SELECT total, sent, received, updated_at FROM addresses WHERE address = $1", address).
+		Scan(&count.Total, &count.Sent, &count.Received, &count.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return count, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return count, nil
+}
+
+// GetTransactionVolumeByTimeRange gets transaction volume by the timestamp of their block
+func (r *PostgresTransactionRepositoryImpl) GetTransactionVolumeByTimeRange(ctx context.Context, startTime, endTime time.Time) (*big.Int, error) {
+	var total string
+	err := r.db.Pool.QueryRow(ctx, "SELECT COALESCE(sum(value), 0)::text FROM transactions WHERE block_timestamp BETWEEN $1 AND $2",
+		startTime, endTime).Scan(&total)
+	if err != nil {
+		return big.NewInt(0), err
+	}
+
+	volume, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		return big.NewInt(0), fmt.Errorf("invalid transaction volume %q", total)
+	}
+	return volume, nil
+}
+
+// GetTopTransactionsByValue gets top transactions by value
+func (r *PostgresTransactionRepositoryImpl) GetTopTransactionsByValue(ctx context.Context, limit int) ([]*entity.Transaction, error) {
+	return r.getTransactions(ctx, transactionSelect+" ORDER BY value DESC LIMIT $1", pgLimit(limit))
+}
+
+// getTransactions returns the transactions of a query
+func (r *PostgresTransactionRepositoryImpl) getTransactions(ctx context.Context, query string, args ...any) ([]*entity.Transaction, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*entity.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, rows.Err()
+}
+
+// scanTransaction scans a row selected by transactionSelect
+func scanTransaction(row pgx.Row) (*entity.Transaction, error) {
+	var (
+		tx          entity.Transaction
+		blockNumber int64
+	)
+	err := row.Scan(&tx.Hash, &tx.BlockHash, &blockNumber, &tx.TransactionIndex, &tx.From, &tx.To,
+		&tx.Value, &tx.Gas, &tx.GasPrice, &tx.GasUsed, &tx.CumulativeGasUsed, &tx.Data, &tx.Nonce, &tx.Status,
+		&tx.MaxFeePerGas, &tx.MaxPriorityFeePerGas, &tx.ContractAddress,
+		&tx.ReceiptMissing, &tx.CrawledAt, &tx.Network, &tx.ProcessedAt, &tx.TxStatus, &tx.BlockTimestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	tx.BlockNumber = strconv.FormatInt(blockNumber, 10)
+	return &tx, nil
+}
+
+// transactionRows returns the COPY rows of transactions
+func transactionRows(txs []*entity.Transaction) ([][]any, error) {
+	rows := make([][]any, len(txs))
+	for i, tx := range txs {
+		values, err := transactionValues(tx)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = values
+	}
+	return rows, nil
+}
+
+// transactionValues returns the values of a transaction in the order of transactionColumns
+func transactionValues(tx *entity.Transaction) ([]any, error) {
+	blockNumber, err := strconv.ParseInt(tx.BlockNumber, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block number %q of transaction %s: %w", tx.BlockNumber, tx.Hash, err)
+	}
+	value, err := pgNumeric(string(tx.Value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of transaction %s: %w", tx.Hash, err)
+	}
+	if !value.Valid {
+		value, _ = pgNumeric("0")
+	}
+	gasPrice, err := pgNumeric(string(tx.GasPrice))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gas price of transaction %s: %w", tx.Hash, err)
+	}
+	maxFeePerGas, err := pgNumeric(string(tx.MaxFeePerGas))
+	if err != nil {
+		return nil, fmt.Errorf("invalid max fee per gas of transaction %s: %w", tx.Hash, err)
+	}
+	maxPriorityFeePerGas, err := pgNumeric(string(tx.MaxPriorityFeePerGas))
+	if err != nil {
+		return nil, fmt.Errorf("invalid max priority fee per gas of transaction %s: %w", tx.Hash, err)
+	}
+
+	return []any{
+		tx.Hash, tx.BlockHash, blockNumber, int32(tx.TransactionIndex), tx.From, tx.To,
+		value, int64(tx.Gas), gasPrice, int64(tx.GasUsed), int64(tx.CumulativeGasUsed), tx.Data,
+		int64(tx.Nonce), int16(tx.Status), maxFeePerGas, maxPriorityFeePerGas, tx.ContractAddress,
+		tx.ReceiptMissing, tx.CrawledAt, tx.Network, tx.ProcessedAt, tx.TxStatus, tx.BlockTimestamp,
+	}, nil
+}
@@ -0,0 +1,1020 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The contract tests run against a real database and are skipped unless
+// MONGODB_TEST_URI or POSTGRES_TEST_DSN is set. Every run uses its own
+// database or schema and drops it afterwards.
+
+// contractRepositories are the repositories of one storage backend
+type contractRepositories struct {
+	blocks         repository.BlockRepository
+	transactions   repository.TransactionRepository
+	blockCommits   repository.BlockCommitRepository
+	addresses      repository.AddressRepository
+	tokenTransfers repository.TokenTransferRepository
+	tokens         repository.TokenRepository
+	metrics        repository.MetricsRepository
+}
+
+func TestMongoRepositoryContract(t *testing.T) {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI is not set")
+	}
+	ctx := context.Background()
+
+	db, err := database.NewMongoDB(&config.MongoDBConfig{
+		URI:            uri,
+		Database:       fmt.Sprintf("contract_test_%d", time.Now().UnixNano()),
+		ConnectTimeout: 10 * time.Second,
+		MaxPoolSize:    10,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Database.Drop(ctx)
+		db.Close(ctx)
+	})
+	require.NoError(t, db.CreateIndexes(ctx))
+
+	runRepositoryContract(t, contractRepositories{
+		blocks:         NewBlockRepository(db),
+		transactions:   NewTransactionRepository(db),
+		blockCommits:   NewBlockCommitRepository(db),
+		addresses:      NewAddressRepository(db),
+		tokenTransfers: NewTokenTransferRepository(db),
+		tokens:         NewTokenRepository(db),
+		metrics:        NewMetricsRepository(db),
+	})
+}
+
+func TestPostgresRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+	ctx := context.Background()
+
+	admin, err := database.NewPostgresDB(&config.PostgresConfig{DSN: dsn})
+	require.NoError(t, err)
+	t.Cleanup(admin.Close)
+
+	schema := fmt.Sprintf("contract_test_%d", time.Now().UnixNano())
+	_, err = admin.Pool.Exec(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		admin.Pool.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	db, err := database.NewPostgresDB(&config.PostgresConfig{DSN: withSearchPath(dsn, schema)})
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+
+	require.NoError(t, db.Migrate(ctx))
+	// Migrating twice is a no-op
+	require.NoError(t, db.Migrate(ctx))
+
+	runRepositoryContract(t, contractRepositories{
+		blocks:         NewPostgresBlockRepository(db),
+		transactions:   NewPostgresTransactionRepository(db),
+		blockCommits:   NewPostgresBlockCommitRepository(db),
+		addresses:      NewPostgresAddressRepository(db),
+		tokenTransfers: NewPostgresTokenTransferRepository(db),
+		tokens:         NewPostgresTokenRepository(db),
+		metrics:        NewPostgresMetricsRepository(db),
+	})
+}
+
+// withSearchPath points a URL or keyword/value DSN at a schema
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
+
+func runRepositoryContract(t *testing.T, repos contractRepositories) {
+	t.Run("blocks", func(t *testing.T) { testBlockRepositoryContract(t, repos.blocks) })
+	t.Run("transactions", func(t *testing.T) { testTransactionRepositoryContract(t, repos.transactions) })
+	t.Run("block_commits", func(t *testing.T) { testBlockCommitRepositoryContract(t, repos) })
+	t.Run("metrics", func(t *testing.T) { testMetricsRepositoryContract(t, repos.metrics) })
+	t.Run("addresses", func(t *testing.T) { testAddressRepositoryContract(t, repos) })
+	t.Run("token transfers", func(t *testing.T) { testTokenTransferRepositoryContract(t, repos.tokenTransfers) })
+	t.Run("tokens", func(t *testing.T) { testTokenRepositoryContract(t, repos.tokens) })
+}
+
+func testBlockRepositoryContract(t *testing.T, repo repository.BlockRepository) {
+	ctx := context.Background()
+	const network = "contract-blocks"
+
+	first := contractBlock(network, 1000, entity.BlockStatusProcessed)
+	require.NoError(t, repo.CreateBlock(ctx, first))
+	require.NoError(t, repo.CreateBlocks(ctx, []*entity.Block{
+		contractBlock(network, 1001, entity.BlockStatusPending),
+		contractBlock(network, 1002, entity.BlockStatusPending),
+	}))
+	assert.Error(t, repo.CreateBlock(ctx, contractBlock(network, 1000, entity.BlockStatusProcessed)), "duplicate height")
+
+	stored, err := repo.GetBlockByNumber(ctx, big.NewInt(1000))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assertBlock(t, first, stored)
+
+	stored, err = repo.GetBlockByHash(ctx, contractBlockHash(1001))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, "1001", stored.Number)
+	assert.Equal(t, entity.BlockStatusPending, stored.Status)
+
+	missing, err := repo.GetBlockByHash(ctx, "0xmissing")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+	missing, err = repo.GetBlockByNumber(ctx, big.NewInt(999))
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	blocks, err := repo.GetBlocksInRange(ctx, big.NewInt(1001), big.NewInt(1002))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1001", "1002"}, blockNumbers(blocks))
+
+	// Blocks are 12 seconds apart, the range covers the mining times of 1001 and 1002
+	from, to, err := repo.GetBlockRangeByTime(ctx, network, first.Timestamp.Add(time.Second), first.Timestamp.Add(time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, from)
+	require.NotNil(t, to)
+	assert.Equal(t, "1001", from.Number)
+	assert.Equal(t, "1002", to.Number)
+	from, to, err = repo.GetBlockRangeByTime(ctx, network, first.Timestamp.Add(-time.Hour), first.Timestamp.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, from)
+	assert.Nil(t, to)
+
+	blocks, err = repo.GetBlocksByTimeRange(ctx, network, first.Timestamp, first.Timestamp.Add(20*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1000", "1001"}, blockNumbers(blocks))
+	blocks, err = repo.GetBlocksByTimeRange(ctx, "other-network", first.Timestamp, first.Timestamp.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, blocks)
+
+	last, err := repo.GetLastProcessedBlock(ctx, network)
+	require.NoError(t, err)
+	require.NotNil(t, last)
+	assert.Equal(t, "1000", last.Number)
+
+	// Incomplete blocks count as processed for the starting block
+	require.NoError(t, repo.UpdateBlockStatus(ctx, contractBlockHash(1002), entity.BlockStatusIncomplete))
+	last, err = repo.GetLastProcessedBlock(ctx, network)
+	require.NoError(t, err)
+	assert.Equal(t, "1002", last.Number)
+
+	// Incomplete blocks are due for a repair until a failed repair defers them
+	due, err := repo.GetBlocksDueForRepair(ctx, network, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1002"}, blockNumbers(due))
+	next := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	require.NoError(t, repo.ScheduleBlockRepair(ctx, contractBlockHash(1002), 1, next))
+	due, err = repo.GetBlocksDueForRepair(ctx, network, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	due, err = repo.GetBlocksDueForRepair(ctx, network, next, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, 1, due[0].RepairAttempts)
+	require.NotNil(t, due[0].NextRepairAt)
+	assert.True(t, next.Equal(*due[0].NextRepairAt))
+
+	none, err := repo.GetLastProcessedBlock(ctx, "contract-empty")
+	require.NoError(t, err)
+	assert.Nil(t, none)
+
+	pending, err := repo.GetBlocksByStatus(ctx, entity.BlockStatusPending, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1001"}, blockNumbers(filterBlocks(pending, network)))
+
+	require.NoError(t, repo.MarkBlockAsProcessed(ctx, contractBlockHash(1001)))
+	stored, err = repo.GetBlockByHash(ctx, contractBlockHash(1001))
+	require.NoError(t, err)
+	assert.Equal(t, entity.BlockStatusProcessed, stored.Status)
+	assert.NotNil(t, stored.ProcessedAt)
+
+	count, err := repo.GetBlockCount(ctx, network)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	count, err = repo.GetBlockCountByStatus(ctx, entity.BlockStatusProcessed, network)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	exists, err := repo.BlockExists(ctx, contractBlockHash(1002))
+	require.NoError(t, err)
+	assert.True(t, exists)
+	require.NoError(t, repo.DeleteBlock(ctx, contractBlockHash(1002)))
+	exists, err = repo.BlockExists(ctx, contractBlockHash(1002))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func testTransactionRepositoryContract(t *testing.T, repo repository.TransactionRepository) {
+	ctx := context.Background()
+	const network = "contract-transactions"
+
+	creation := contractTransaction(network, 2000, 0, "0xalice", nil)
+	creation.Value = "123456789012345678901234567890" // Above uint64
+	creation.ContractAddress = contractString("0xcontract")
+	creation.ReceiptMissing = true
+	require.NoError(t, repo.CreateTransaction(ctx, creation))
+	small := contractTransaction(network, 2001, 0, "0xalice", contractString("0xbob"))
+	small.Value = "9" // Above "10000..." as a string
+	require.NoError(t, repo.CreateTransactions(ctx, []*entity.Transaction{
+		contractTransaction(network, 2000, 1, "0xbob", contractString("0xalice")),
+		small,
+	}))
+	assert.Error(t, repo.CreateTransaction(ctx, contractTransaction(network, 2000, 0, "0xalice", nil)), "duplicate hash")
+
+	stored, err := repo.GetTransactionByHash(ctx, creation.Hash)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assertTransaction(t, creation, stored)
+
+	missing, err := repo.GetTransactionByHash(ctx, "0xmissing")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	txs, err := repo.GetTransactionsByBlockHash(ctx, contractBlockHash(2000))
+	require.NoError(t, err)
+	assert.Equal(t, []string{contractTransactionHash(2000, 0), contractTransactionHash(2000, 1)}, transactionHashes(txs))
+
+	txs, err = repo.GetTransactionsByBlockNumber(ctx, big.NewInt(2001))
+	require.NoError(t, err)
+	assert.Equal(t, []string{contractTransactionHash(2001, 0)}, transactionHashes(txs))
+
+	// Newest first, as sender or recipient
+	txs, err = repo.GetTransactionsByAddress(ctx, "0xbob", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{contractTransactionHash(2001, 0), contractTransactionHash(2000, 1)}, transactionHashes(txs))
+	txs, err = repo.GetTransactionsByAddress(ctx, "0xbob", 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{contractTransactionHash(2000, 1)}, transactionHashes(txs))
+
+	count, err := repo.GetTransactionCountByAddress(ctx, "0xalice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assertAddressCount(t, repo, "0xalice", 3, 2, 1)
+
+	// Address history, newest first, continuing after a position
+	alice := repository.AddressTransactionFilter{Address: "0xalice"}
+	txs, err = repo.GetAddressTransactions(ctx, alice, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{contractTransactionHash(2001, 0), contractTransactionHash(2000, 1), contractTransactionHash(2000, 0)},
+		transactionHashes(txs))
+	txs, err = repo.GetAddressTransactions(ctx, alice, &repository.TransactionPosition{BlockNumber: 2001, TransactionIndex: 0}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{contractTransactionHash(2000, 1)}, transactionHashes(txs))
+
+	filtered := func(change func(*repository.AddressTransactionFilter)) []string {
+		filter := alice
+		change(&filter)
+		txs, err := repo.GetAddressTransactions(ctx, filter, nil, 10)
+		require.NoError(t, err)
+		return transactionHashes(txs)
+	}
+	yes, from := true, uint64(2001)
+	assert.Equal(t, []string{contractTransactionHash(2000, 1)},
+		filtered(func(f *repository.AddressTransactionFilter) { f.Direction = repository.AddressDirectionIn }))
+	assert.Equal(t, []string{contractTransactionHash(2001, 0), contractTransactionHash(2000, 0)},
+		filtered(func(f *repository.AddressTransactionFilter) { f.Direction = repository.AddressDirectionOut }))
+	assert.Equal(t, []string{contractTransactionHash(2000, 0)},
+		filtered(func(f *repository.AddressTransactionFilter) { f.ContractCreation = &yes }))
+	assert.Equal(t, []string{contractTransactionHash(2001, 0), contractTransactionHash(2000, 1)},
+		filtered(func(f *repository.AddressTransactionFilter) { f.Success = &yes }), "a missing receipt is not a success")
+	assert.Equal(t, []string{contractTransactionHash(2001, 0)},
+		filtered(func(f *repository.AddressTransactionFilter) { f.FromBlock = &from }))
+
+	// Selected by the timestamp of their block, 12 seconds apart
+	block2000 := contractBlockTimestamp(2000)
+	txs, err = repo.GetTransactionsByTimeRange(ctx, block2000, block2000.Add(11*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, []string{contractTransactionHash(2000, 0), contractTransactionHash(2000, 1)}, transactionHashes(txs))
+
+	// Wei amounts sort and sum numerically
+	txs, err = repo.GetTopTransactionsByValue(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{creation.Hash, contractTransactionHash(2000, 1), small.Hash}, transactionHashes(txs))
+	assert.Equal(t, creation.Value, txs[0].Value)
+
+	volume, err := repo.GetTransactionVolumeByTimeRange(ctx, block2000, contractBlockTimestamp(2001))
+	require.NoError(t, err)
+	assert.Equal(t, "123456789013345678901234567899", volume.String())
+
+	// Upserts update stored transactions by hash and insert new ones
+	updated := contractTransaction(network, 2000, 1, "0xbob", contractString("0xalice"))
+	updated.Status = 0
+	updated.TxStatus = entity.TransactionStatusFailed
+	require.NoError(t, repo.UpsertTransactions(ctx, []*entity.Transaction{
+		updated,
+		contractTransaction(network, 2001, 1, "0xcarol", contractString("0xdave")),
+	}))
+	stored, err = repo.GetTransactionByHash(ctx, updated.Hash)
+	require.NoError(t, err)
+	assertTransaction(t, updated, stored)
+
+	count, err = repo.GetTransactionCount(ctx, network)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+	// Counters count inserted transactions only
+	assertAddressCount(t, repo, "0xalice", 3, 2, 1)
+	assertAddressCount(t, repo, "0xdave", 1, 0, 1)
+	// Repeated writes count nothing again
+	require.NoError(t, repo.UpsertTransactions(ctx, []*entity.Transaction{
+		contractTransaction(network, 2001, 1, "0xcarol", contractString("0xdave")),
+	}))
+	assertAddressCount(t, repo, "0xdave", 1, 0, 1)
+	count, err = repo.GetTransactionCountByStatus(ctx, entity.TransactionStatusFailed, network)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Receipt repair clears the missing flag
+	repaired := *creation
+	repaired.Status = 1
+	repaired.GasUsed = 53_000
+	repaired.CumulativeGasUsed = 53_000
+	repaired.TxStatus = entity.TransactionStatusProcessed
+	require.NoError(t, repo.UpdateTransactionReceipt(ctx, &repaired))
+	stored, err = repo.GetTransactionByHash(ctx, creation.Hash)
+	require.NoError(t, err)
+	assert.False(t, stored.ReceiptMissing)
+	assert.Equal(t, uint64(53_000), stored.GasUsed)
+	assert.Equal(t, uint64(1), stored.Status)
+	assert.Equal(t, creation.Value, stored.Value)
+	unknown := contractTransaction(network, 2999, 0, "0xalice", nil)
+	assert.Error(t, repo.UpdateTransactionReceipt(ctx, unknown))
+
+	require.NoError(t, repo.UpdateTransactionStatus(ctx, contractTransactionHash(2001, 0), entity.TransactionStatusFailed))
+	require.NoError(t, repo.MarkTransactionAsProcessed(ctx, contractTransactionHash(2001, 1)))
+	stored, err = repo.GetTransactionByHash(ctx, contractTransactionHash(2001, 1))
+	require.NoError(t, err)
+	assert.Equal(t, entity.TransactionStatusProcessed, stored.TxStatus)
+	assert.NotNil(t, stored.ProcessedAt)
+
+	txs, err = repo.GetTransactionsByStatus(ctx, entity.TransactionStatusFailed, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{contractTransactionHash(2000, 1), contractTransactionHash(2001, 0)},
+		transactionHashes(filterTransactions(txs, network)))
+
+	require.NoError(t, repo.DeleteTransaction(ctx, contractTransactionHash(2001, 1)))
+	exists, err := repo.TransactionExists(ctx, contractTransactionHash(2001, 1))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assertAddressCount(t, repo, "0xdave", 0, 0, 0)
+
+	require.NoError(t, repo.DeleteTransactionsByBlockHash(ctx, contractBlockHash(2000)))
+	count, err = repo.GetTransactionCount(ctx, network)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assertAddressCount(t, repo, "0xalice", 1, 1, 0)
+}
+
+func testBlockCommitRepositoryContract(t *testing.T, repos contractRepositories) {
+	ctx := context.Background()
+	const network = "contract-commits"
+	commits := repos.blockCommits
+
+	_, err := commits.IsAtomic(ctx)
+	require.NoError(t, err)
+
+	block := contractBlock(network, 3000, entity.BlockStatusPending)
+	txs := []*entity.Transaction{
+		contractTransaction(network, 3000, 0, "0xalice", contractString("0xbob")),
+		contractTransaction(network, 3000, 1, "0xbob", contractString("0xalice")),
+	}
+	require.NoError(t, commits.CommitBlock(ctx, block, txs, entity.BlockStatusIncomplete))
+
+	stored, err := repos.blocks.GetBlockByNumber(ctx, big.NewInt(3000))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, entity.BlockStatusIncomplete, stored.Status)
+	assert.Nil(t, stored.ProcessedAt)
+	storedTxs, err := repos.transactions.GetTransactionsByBlockHash(ctx, block.Hash)
+	require.NoError(t, err)
+	assert.Len(t, storedTxs, 2)
+
+	// Committing again is idempotent and moves the block on
+	require.NoError(t, commits.CommitBlock(ctx, block, txs, entity.BlockStatusProcessed))
+	stored, err = repos.blocks.GetBlockByNumber(ctx, big.NewInt(3000))
+	require.NoError(t, err)
+	assert.Equal(t, entity.BlockStatusProcessed, stored.Status)
+	assert.NotNil(t, stored.ProcessedAt)
+	count, err := repos.transactions.GetTransactionCount(ctx, network)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	require.NoError(t, commits.CommitBlocks(ctx, []*repository.BlockCommit{
+		{Block: contractBlock(network, 3001, entity.BlockStatusPending), Status: entity.BlockStatusProcessed},
+		{
+			Block:        contractBlock(network, 3002, entity.BlockStatusPending),
+			Transactions: []*entity.Transaction{contractTransaction(network, 3002, 0, "0xcarol", nil)},
+			Status:       entity.BlockStatusProcessed,
+		},
+	}))
+	last, err := repos.blocks.GetLastProcessedBlock(ctx, network)
+	require.NoError(t, err)
+	require.NotNil(t, last)
+	assert.Equal(t, "3002", last.Number)
+
+	// A block of another hash replaces the stored block with its transactions and token transfers
+	orphaned := contractTransaction(network, 3001, 0, "0xreorger", nil)
+	require.NoError(t, repos.transactions.CreateTransaction(ctx, orphaned))
+	require.NoError(t, repos.tokenTransfers.SaveTokenTransfers(ctx, []*entity.TokenTransfer{{
+		Token:           "0xreorgtoken",
+		Standard:        entity.TokenStandardERC20,
+		From:            "0xreorger",
+		To:              "0xreorgee",
+		Amount:          "1",
+		TransactionHash: orphaned.Hash,
+		BlockNumber:     3001,
+		BlockHash:       contractBlockHash(3001),
+		BlockTimestamp:  contractBlockTimestamp(3001),
+		Network:         network,
+		CrawledAt:       time.Now().UTC().Truncate(time.Millisecond),
+	}}))
+	reorged := contractBlock(network, 3001, entity.BlockStatusPending)
+	reorged.Hash = "0xreorged3001"
+	replacement := contractTransaction(network, 3001, 0, "0xreorger", nil)
+	replacement.Hash, replacement.BlockHash = "0xreorgedtx3001", reorged.Hash
+	require.NoError(t, commits.CommitBlock(ctx, reorged, []*entity.Transaction{replacement}, entity.BlockStatusIncomplete))
+	stored, err = repos.blocks.GetBlockByNumber(ctx, big.NewInt(3001))
+	require.NoError(t, err)
+	assert.Equal(t, reorged.Hash, stored.Hash)
+	assert.Equal(t, entity.BlockStatusIncomplete, stored.Status)
+	exists, err := repos.transactions.TransactionExists(ctx, orphaned.Hash)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = repos.transactions.TransactionExists(ctx, replacement.Hash)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	transfers, err := repos.tokenTransfers.GetTokenTransfersByTransaction(ctx, orphaned.Hash)
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+	assertAddressCount(t, repos.transactions, "0xreorger", 1, 1, 0)
+
+	// Stale pending blocks are cleaned up with their transactions
+	stale := contractBlock(network, 3005, entity.BlockStatusPending)
+	stale.CrawledAt = time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	require.NoError(t, repos.blocks.CreateBlock(ctx, stale))
+	require.NoError(t, repos.transactions.CreateTransaction(ctx, contractTransaction(network, 3005, 0, "0xalice", nil)))
+
+	deleted, err := commits.CleanupUncommittedBlocks(ctx, network, time.Now().Add(-10*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3005}, deleted)
+	exists, err = repos.blocks.BlockExists(ctx, stale.Hash)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = repos.transactions.TransactionExists(ctx, contractTransactionHash(3005, 0))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	deleted, err = commits.CleanupUncommittedBlocks(ctx, network, time.Now().Add(-10*time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	// Every transaction written above was counted with its write
+	counted, err := commits.CountUncountedTransactions(ctx, network, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Zero(t, counted)
+	assertAddressCount(t, repos.transactions, "0xreorger", 1, 1, 0)
+}
+
+func testAddressRepositoryContract(t *testing.T, repos contractRepositories) {
+	ctx := context.Background()
+	const network = "contract-addresses"
+	repo := repos.addresses
+	erin, frank, gina, factory := "0xerin", "0xfrank", "0xgina", "0xfactory"
+
+	creation := contractTransaction(network, 4000, 1, erin, nil)
+	creation.ContractAddress = contractString(factory)
+	require.NoError(t, repos.transactions.CreateTransactions(ctx, []*entity.Transaction{
+		contractTransaction(network, 4000, 0, erin, contractString(frank)),
+		creation,
+		contractTransaction(network, 4001, 0, frank, contractString(erin)),
+		contractTransaction(network, 4001, 1, frank, contractString(gina)),
+	}))
+
+	no, yes := false, true
+	update := func(address string, block uint64, index uint, last uint64, isContract *bool) *repository.AddressUpdate {
+		return &repository.AddressUpdate{
+			Address:      address,
+			FirstBlock:   block,
+			FirstTxIndex: index,
+			FirstTx:      contractTransactionHash(block, index),
+			LastBlock:    last,
+			IsContract:   isContract,
+		}
+	}
+	require.NoError(t, repo.RecordActivity(ctx, []*repository.AddressUpdate{
+		update(erin, 4000, 0, 4000, &no),
+		update(frank, 4000, 0, 4000, nil),
+		update(factory, 4000, 1, 4000, &yes),
+	}))
+	require.NoError(t, repo.RecordActivity(ctx, []*repository.AddressUpdate{
+		update(erin, 4001, 0, 4001, nil),
+		update(frank, 4001, 0, 4001, &no),
+		update(gina, 4001, 1, 4001, nil),
+	}))
+
+	// First seen stays at the earliest transaction, counters come from the transactions
+	address, err := repo.GetAddress(ctx, erin)
+	require.NoError(t, err)
+	require.NotNil(t, address)
+	assert.Equal(t, uint64(4000), address.FirstSeenBlock)
+	assert.Equal(t, uint(0), address.FirstSeenTxIndex)
+	assert.Equal(t, contractTransactionHash(4000, 0), address.FirstSeenTx)
+	assert.Equal(t, uint64(4001), address.LastActiveBlock)
+	assert.Equal(t, []int64{3, 2, 1}, []int64{address.TransactionCount, address.SentCount, address.ReceivedCount})
+	require.NotNil(t, address.IsContract)
+	assert.False(t, *address.IsContract)
+
+	// An earlier transaction moves first seen back, a later one only moves last active
+	require.NoError(t, repo.RecordActivity(ctx, []*repository.AddressUpdate{update(gina, 4001, 0, 4001, nil)}))
+	require.NoError(t, repo.RecordActivity(ctx, []*repository.AddressUpdate{update(gina, 4002, 0, 4002, nil)}))
+	address, err = repo.GetAddress(ctx, gina)
+	require.NoError(t, err)
+	assert.Equal(t, contractTransactionHash(4001, 0), address.FirstSeenTx)
+	assert.Equal(t, uint64(4002), address.LastActiveBlock)
+	assert.Nil(t, address.IsContract)
+
+	missing, err := repo.GetAddress(ctx, "0xnobody")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	unclassified, err := repo.GetUnclassifiedAddresses(ctx, []string{erin, gina, "0xnobody", factory})
+	require.NoError(t, err)
+	assert.Equal(t, []string{gina, "0xnobody"}, unclassified)
+	unclassified, err = repo.ListUnclassifiedAddresses(ctx, "0xfrank", 1000)
+	require.NoError(t, err)
+	assert.Contains(t, unclassified, gina)
+	assert.NotContains(t, unclassified, erin)
+
+	// A contract stays one
+	require.NoError(t, repo.SetContractStatus(ctx, map[string]bool{gina: false, factory: false}))
+	address, err = repo.GetAddress(ctx, gina)
+	require.NoError(t, err)
+	require.NotNil(t, address.IsContract)
+	assert.False(t, *address.IsContract)
+	address, err = repo.GetAddress(ctx, factory)
+	require.NoError(t, err)
+	assert.True(t, *address.IsContract)
+
+	// Rebuilt from the transactions alone, recipients are unclassified again
+	count, err := repo.RebuildAddresses(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, count, int64(4))
+	address, err = repo.GetAddress(ctx, gina)
+	require.NoError(t, err)
+	assert.Equal(t, contractTransactionHash(4001, 1), address.FirstSeenTx)
+	assert.Equal(t, uint64(4001), address.LastActiveBlock)
+	assert.Equal(t, []int64{1, 0, 1}, []int64{address.TransactionCount, address.SentCount, address.ReceivedCount})
+	assert.Nil(t, address.IsContract)
+	address, err = repo.GetAddress(ctx, factory)
+	require.NoError(t, err)
+	assert.Equal(t, contractTransactionHash(4000, 1), address.FirstSeenTx)
+	assert.Zero(t, address.TransactionCount)
+	assert.True(t, *address.IsContract)
+	address, err = repo.GetAddress(ctx, erin)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 2, 1}, []int64{address.TransactionCount, address.SentCount, address.ReceivedCount})
+	assert.False(t, *address.IsContract)
+	assertAddressCount(t, repos.transactions, frank, 3, 2, 1)
+}
+
+func testTokenTransferRepositoryContract(t *testing.T, repo repository.TokenTransferRepository) {
+	ctx := context.Background()
+	huge := new(big.Int).Lsh(big.NewInt(1), 255).String()
+	transfer := func(block uint64, logIndex, batchIndex uint, token, from, to, amount string) *entity.TokenTransfer {
+		return &entity.TokenTransfer{
+			Token:           token,
+			Standard:        entity.TokenStandardERC20,
+			From:            from,
+			To:              to,
+			Amount:          amount,
+			TransactionHash: contractTransactionHash(block, 0),
+			LogIndex:        logIndex,
+			BatchIndex:      batchIndex,
+			BlockNumber:     block,
+			BlockHash:       fmt.Sprintf("0xtransfers%d", block),
+			BlockTimestamp:  time.Unix(int64(block), 0).UTC(),
+			Network:         "contract-transfers",
+			CrawledAt:       time.Now().UTC().Truncate(time.Millisecond),
+		}
+	}
+
+	nft := transfer(5001, 1, 1, "0xnft", "0xholder", "0xother", "2")
+	nft.Standard, nft.Operator, nft.TokenID = entity.TokenStandardERC1155, "0xoperator", huge
+	require.NoError(t, repo.SaveTokenTransfers(ctx, []*entity.TokenTransfer{
+		transfer(5000, 0, 0, "0xcoin", "0xholder", "0xother", "100"),
+		transfer(5001, 0, 0, "0xcoin", "0xother", "0xholder", huge),
+		nft,
+		transfer(5002, 3, 0, "0xcoin", "0xother", "0xthird", "7"),
+	}))
+
+	// Saving again replaces
+	replaced := transfer(5000, 0, 0, "0xcoin", "0xholder", "0xother", "150")
+	require.NoError(t, repo.SaveTokenTransfers(ctx, []*entity.TokenTransfer{replaced}))
+
+	byToken, err := repo.GetTokenTransfersByToken(ctx, "0xcoin", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, byToken, 3)
+	assert.Equal(t, []uint64{5002, 5001, 5000}, []uint64{byToken[0].BlockNumber, byToken[1].BlockNumber, byToken[2].BlockNumber})
+	assert.Equal(t, huge, byToken[1].Amount)
+	assert.Equal(t, "150", byToken[2].Amount)
+	assert.Empty(t, byToken[2].TokenID)
+	assert.Equal(t, replaced.BlockTimestamp, byToken[2].BlockTimestamp.UTC())
+
+	page, err := repo.GetTokenTransfersByToken(ctx, "0xcoin", 1, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, uint64(5001), page[0].BlockNumber)
+
+	byHolder, err := repo.GetTokenTransfersByHolder(ctx, "0xholder", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, byHolder, 3)
+	assert.Equal(t, "0xnft", byHolder[0].Token, "highest log index of the block first")
+	assert.Equal(t, entity.TokenStandardERC1155, byHolder[0].Standard)
+	assert.Equal(t, "0xoperator", byHolder[0].Operator)
+	assert.Equal(t, huge, byHolder[0].TokenID)
+	assert.Equal(t, uint(1), byHolder[0].BatchIndex)
+
+	byTransaction, err := repo.GetTokenTransfersByTransaction(ctx, contractTransactionHash(5001, 0))
+	require.NoError(t, err)
+	require.Len(t, byTransaction, 2)
+	assert.Equal(t, "0xcoin", byTransaction[0].Token)
+
+	require.NoError(t, repo.DeleteTokenTransfersByBlockHash(ctx, "0xtransfers5001"))
+	byTransaction, err = repo.GetTokenTransfersByTransaction(ctx, contractTransactionHash(5001, 0))
+	require.NoError(t, err)
+	assert.Empty(t, byTransaction)
+}
+
+func testTokenRepositoryContract(t *testing.T, repo repository.TokenRepository) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newToken := func(address string, block uint64, refreshAt time.Time) *entity.Token {
+		return &entity.Token{
+			Address:        address,
+			Standard:       entity.TokenStandardERC20,
+			FirstSeenBlock: block,
+			RefreshAt:      refreshAt,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+	}
+
+	added, err := repo.AddTokens(ctx, []*entity.Token{
+		newToken("0xtokenb", 6000, now.Add(-time.Minute)),
+		newToken("0xtokena", 6000, now.Add(-2*time.Minute)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0xtokena", "0xtokenb"}, added)
+
+	// Stored tokens are left untouched
+	added, err = repo.AddTokens(ctx, []*entity.Token{
+		newToken("0xtokena", 6001, now),
+		newToken("0xtokenc", 6001, now.Add(time.Hour)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0xtokenc"}, added)
+
+	token, err := repo.GetToken(ctx, "0xtokena")
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.Equal(t, uint64(6000), token.FirstSeenBlock)
+	assert.False(t, token.IsResolved())
+	assert.Nil(t, token.Decimals)
+
+	due, err := repo.GetTokensToRefresh(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, []string{"0xtokena", "0xtokenb"}, []string{due[0].Address, due[1].Address})
+
+	decimals := uint8(18)
+	huge := new(big.Int).Lsh(big.NewInt(1), 255).String()
+	resolved := newToken("0xtokena", 9999, now.Add(24*time.Hour))
+	resolved.Standard = entity.TokenStandardERC721
+	resolved.Name, resolved.Symbol, resolved.Decimals, resolved.TotalSupply = "Token A", "TKA", &decimals, huge
+	resolved.Interfaces = []entity.TokenInterface{entity.TokenInterfaceERC165, entity.TokenInterfaceERC721}
+	resolved.ResolvedAt = now
+	require.NoError(t, repo.SaveToken(ctx, resolved))
+
+	token, err = repo.GetToken(ctx, "0xtokena")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6000), token.FirstSeenBlock, "the first-seen block is kept")
+	assert.Equal(t, entity.TokenStandardERC721, token.Standard)
+	assert.Equal(t, []string{"Token A", "TKA", huge}, []string{token.Name, token.Symbol, token.TotalSupply})
+	require.NotNil(t, token.Decimals)
+	assert.Equal(t, uint8(18), *token.Decimals)
+	assert.True(t, token.SupportsInterface(entity.TokenInterfaceERC721))
+	assert.Equal(t, now, token.ResolvedAt.UTC())
+
+	// A failed lookup keeps the resolve time
+	failed := *token
+	failed.Failures, failed.LastError = 1, "request timed out"
+	failed.ResolvedAt = time.Time{}
+	failed.RefreshAt = now.Add(-time.Second)
+	require.NoError(t, repo.SaveToken(ctx, &failed))
+
+	token, err = repo.GetToken(ctx, "0xtokena")
+	require.NoError(t, err)
+	assert.Equal(t, 1, token.Failures)
+	assert.Equal(t, "request timed out", token.LastError)
+	assert.Equal(t, now, token.ResolvedAt.UTC())
+
+	due, err = repo.GetTokensToRefresh(ctx, now, 1)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, "0xtokenb", due[0].Address, "longest due first")
+
+	missing, err := repo.GetToken(ctx, "0xmissing")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func testMetricsRepositoryContract(t *testing.T, repo repository.MetricsRepository) {
+	ctx := context.Background()
+	const network = "contract-metrics"
+	now := time.Now().Truncate(time.Millisecond)
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, repo.SaveCrawlerMetrics(ctx, &entity.CrawlerMetrics{
+			Timestamp:             now.Add(time.Duration(i-4) * time.Minute),
+			LastProcessedBlock:    uint64(100 * i),
+			CurrentBlock:          uint64(100*i + 5),
+			BlocksProcessed:       uint64(10 * i),
+			BlocksPerSecond:       float64(i),
+			TransactionsPerSecond: float64(10 * i),
+			ErrorCount:            uint64(i),
+			LastErrorMessage:      fmt.Sprintf("error %d", i),
+			AverageProcessingTime: time.Duration(i) * 100 * time.Millisecond,
+			NetworkLatency:        50 * time.Millisecond,
+			ConcurrentWorkers:     4,
+			RequestDelay:          200 * time.Millisecond,
+			Network:               network,
+		}))
+	}
+
+	latest, err := repo.GetLatestCrawlerMetrics(ctx, network)
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, uint64(300), latest.LastProcessedBlock)
+	assert.Equal(t, uint64(305), latest.CurrentBlock)
+	assert.Equal(t, "error 3", latest.LastErrorMessage)
+	assert.Equal(t, 300*time.Millisecond, latest.AverageProcessingTime)
+	assert.Equal(t, 50*time.Millisecond, latest.NetworkLatency)
+	assert.Equal(t, 200*time.Millisecond, latest.RequestDelay)
+	assert.True(t, now.Add(-time.Minute).Equal(latest.Timestamp))
+
+	none, err := repo.GetLatestCrawlerMetrics(ctx, "contract-empty")
+	require.NoError(t, err)
+	assert.Nil(t, none)
+
+	history, err := repo.GetCrawlerMetricsHistory(ctx, network, 2)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, uint64(300), history[0].LastProcessedBlock)
+	assert.Equal(t, uint64(200), history[1].LastProcessedBlock)
+
+	inRange, err := repo.GetCrawlerMetricsByTimeRange(ctx, network, now.Add(-3*time.Minute), now.Add(-2*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, inRange, 2)
+	assert.Equal(t, uint64(100), inRange[0].LastProcessedBlock)
+
+	average, err := repo.GetAverageProcessingTime(ctx, network, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 200*time.Millisecond, average)
+
+	errorRate, err := repo.GetErrorRate(ctx, network, time.Hour)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.1, errorRate, 1e-9)
+
+	throughput, err := repo.GetThroughputStats(ctx, network, time.Hour)
+	require.NoError(t, err)
+	assert.InDelta(t, 2.0, throughput["avg_blocks_per_second"], 1e-9)
+	assert.InDelta(t, 30.0, throughput["max_transactions_per_second"], 1e-9)
+	throughput, err = repo.GetThroughputStats(ctx, "contract-empty", time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, throughput)
+
+	require.NoError(t, repo.SaveSystemHealth(ctx, &entity.SystemHealth{
+		Timestamp: now.Add(-2 * time.Minute),
+		Status:    entity.HealthStatusDegraded,
+		Message:   "slow rpc",
+		Network:   network,
+	}))
+	require.NoError(t, repo.SaveSystemHealth(ctx, &entity.SystemHealth{
+		Timestamp: now,
+		Status:    entity.HealthStatusHealthy,
+		ComponentsHealth: map[string]entity.ComponentHealth{
+			"database": {Status: entity.HealthStatusHealthy, LastChecked: now, Message: "ok", ResponseTime: 3 * time.Millisecond},
+		},
+		Message: "all good",
+		Network: network,
+	}))
+
+	health, err := repo.GetLatestSystemHealth(ctx, network)
+	require.NoError(t, err)
+	require.NotNil(t, health)
+	assert.Equal(t, entity.HealthStatusHealthy, health.Status)
+	require.Contains(t, health.ComponentsHealth, "database")
+	assert.Equal(t, 3*time.Millisecond, health.ComponentsHealth["database"].ResponseTime)
+	assert.True(t, now.Equal(health.ComponentsHealth["database"].LastChecked))
+
+	healthHistory, err := repo.GetSystemHealthHistory(ctx, network, 10)
+	require.NoError(t, err)
+	require.Len(t, healthHistory, 2)
+	assert.Equal(t, "slow rpc", healthHistory[1].Message)
+
+	require.NoError(t, repo.CleanupOldMetrics(ctx, now.Add(-90*time.Second)))
+	history, err = repo.GetCrawlerMetricsHistory(ctx, network, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+	healthHistory, err = repo.GetSystemHealthHistory(ctx, network, 10)
+	require.NoError(t, err)
+	assert.Len(t, healthHistory, 1)
+}
+
+func contractBlockHash(number uint64) string {
+	return fmt.Sprintf("0xblock%d", number)
+}
+
+func contractTransactionHash(blockNumber uint64, index uint) string {
+	return fmt.Sprintf("0xtx%d_%d", blockNumber, index)
+}
+
+// contractBlockTimestamp returns the mining time of a block, 12 seconds apart
+func contractBlockTimestamp(number uint64) time.Time {
+	return time.Unix(1_700_000_000+int64(number)*12, 0)
+}
+
+func contractString(s string) *string {
+	return &s
+}
+
+func contractBlock(network string, number uint64, status entity.BlockStatus) *entity.Block {
+	timestamp := contractBlockTimestamp(number)
+	return &entity.Block{
+		Number:            fmt.Sprintf("%d", number),
+		Hash:              contractBlockHash(number),
+		ParentHash:        contractBlockHash(number - 1),
+		Nonce:             0x42,
+		Sha3Uncles:        "0x1dcc",
+		LogsBloom:         "0x00",
+		TransactionsRoot:  "0x56e8",
+		StateRoot:         "0xd7f8",
+		ReceiptsRoot:      "0x56e8",
+		Miner:             "0xminer",
+		Difficulty:        "17179869184",
+		TotalDifficulty:   "58750003716598352816469",
+		ExtraData:         "0x",
+		Size:              540,
+		GasLimit:          30_000_000,
+		GasUsed:           21_000,
+		Timestamp:         timestamp,
+		TransactionHashes: []string{contractTransactionHash(number, 0)},
+		Uncles:            []string{"0xuncle"},
+		CrawledAt:         time.Now().Truncate(time.Millisecond),
+		Network:           network,
+		Status:            status,
+	}
+}
+
+func contractTransaction(network string, blockNumber uint64, index uint, from string, to *string) *entity.Transaction {
+	return &entity.Transaction{
+		Hash:                 contractTransactionHash(blockNumber, index),
+		BlockHash:            contractBlockHash(blockNumber),
+		BlockNumber:          fmt.Sprintf("%d", blockNumber),
+		BlockTimestamp:       contractBlockTimestamp(blockNumber),
+		TransactionIndex:     index,
+		From:                 from,
+		To:                   to,
+		Value:                "1000000000000000000",
+		Gas:                  21_000,
+		GasPrice:             "20000000000",
+		GasUsed:              21_000,
+		CumulativeGasUsed:    21_000 * uint64(index+1),
+		Data:                 "0x",
+		Nonce:                uint64(index),
+		Status:               1,
+		MaxFeePerGas:         "30000000000",
+		MaxPriorityFeePerGas: "1500000000",
+		CrawledAt:            time.Now().Truncate(time.Millisecond),
+		Network:              network,
+		TxStatus:             entity.TransactionStatusPending,
+	}
+}
+
+func assertBlock(t *testing.T, want, got *entity.Block) {
+	t.Helper()
+	assert.Equal(t, want.Number, got.Number)
+	assert.Equal(t, want.Hash, got.Hash)
+	assert.Equal(t, want.ParentHash, got.ParentHash)
+	assert.Equal(t, want.Nonce, got.Nonce)
+	assert.Equal(t, want.Miner, got.Miner)
+	assert.Equal(t, want.Difficulty, got.Difficulty)
+	assert.Equal(t, want.TotalDifficulty, got.TotalDifficulty)
+	assert.Equal(t, want.Size, got.Size)
+	assert.Equal(t, want.GasLimit, got.GasLimit)
+	assert.Equal(t, want.GasUsed, got.GasUsed)
+	assert.True(t, want.Timestamp.Equal(got.Timestamp), "timestamp %s, want %s", got.Timestamp, want.Timestamp)
+	assert.Equal(t, want.TransactionHashes, got.TransactionHashes)
+	assert.Equal(t, want.Uncles, got.Uncles)
+	assert.True(t, want.CrawledAt.Equal(got.CrawledAt), "crawled_at %s, want %s", got.CrawledAt, want.CrawledAt)
+	assert.Equal(t, want.Network, got.Network)
+	assert.Equal(t, want.Status, got.Status)
+}
+
+func assertTransaction(t *testing.T, want, got *entity.Transaction) {
+	t.Helper()
+	assert.Equal(t, want.Hash, got.Hash)
+	assert.Equal(t, want.BlockHash, got.BlockHash)
+	assert.Equal(t, want.BlockNumber, got.BlockNumber)
+	assert.True(t, want.BlockTimestamp.Equal(got.BlockTimestamp), "block timestamp %s, want %s", got.BlockTimestamp, want.BlockTimestamp)
+	assert.Equal(t, want.TransactionIndex, got.TransactionIndex)
+	assert.Equal(t, want.From, got.From)
+	assert.Equal(t, want.To, got.To)
+	assert.Equal(t, want.Value, got.Value)
+	assert.Equal(t, want.Gas, got.Gas)
+	assert.Equal(t, want.GasPrice, got.GasPrice)
+	assert.Equal(t, want.GasUsed, got.GasUsed)
+	assert.Equal(t, want.CumulativeGasUsed, got.CumulativeGasUsed)
+	assert.Equal(t, want.Data, got.Data)
+	assert.Equal(t, want.Nonce, got.Nonce)
+	assert.Equal(t, want.Status, got.Status)
+	assert.Equal(t, want.MaxFeePerGas, got.MaxFeePerGas)
+	assert.Equal(t, want.MaxPriorityFeePerGas, got.MaxPriorityFeePerGas)
+	assert.Equal(t, want.ContractAddress, got.ContractAddress)
+	assert.Equal(t, want.ReceiptMissing, got.ReceiptMissing)
+	assert.True(t, want.CrawledAt.Equal(got.CrawledAt), "crawled_at %s, want %s", got.CrawledAt, want.CrawledAt)
+	assert.Equal(t, want.Network, got.Network)
+	assert.Equal(t, want.TxStatus, got.TxStatus)
+}
+
+func assertAddressCount(t *testing.T, repo repository.TransactionRepository, address string, total, sent, received int64) {
+	t.Helper()
+	count, err := repo.GetAddressTransactionCount(context.Background(), address)
+	require.NoError(t, err)
+	assert.Equal(t, address, count.Address)
+	assert.Equal(t, []int64{total, sent, received}, []int64{count.Total, count.Sent, count.Received}, "counts of %s", address)
+}
+
+func blockNumbers(blocks []*entity.Block) []string {
+	numbers := make([]string, len(blocks))
+	for i, block := range blocks {
+		numbers[i] = block.Number
+	}
+	return numbers
+}
+
+func transactionHashes(txs []*entity.Transaction) []string {
+	hashes := make([]string, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash
+	}
+	return hashes
+}
+
+// filterBlocks keeps the blocks of a network, for queries that span networks
+func filterBlocks(blocks []*entity.Block, network string) []*entity.Block {
+	var filtered []*entity.Block
+	for _, block := range blocks {
+		if block.Network == network {
+			filtered = append(filtered, block)
+		}
+	}
+	return filtered
+}
+
+// filterTransactions keeps the transactions of a network, for queries that span networks
+func filterTransactions(txs []*entity.Transaction, network string) []*entity.Transaction {
+	var filtered []*entity.Transaction
+	for _, tx := range txs {
+		if tx.Network == network {
+			filtered = append(filtered, tx)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,122 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+)
+
+// Storage backends
+const (
+	StorageBackendMongoDB  = "mongodb"
+	StorageBackendPostgres = "postgres"
+)
+
+// Storage holds the block, transaction, block commit, address, token transfer,
+// token and metrics repositories of the configured storage backend. The other repositories always use MongoDB.
+type Storage struct {
+	backend  string
+	postgres *database.PostgresDB
+
+	blocks         repository.BlockRepository
+	transactions   repository.TransactionRepository
+	blockCommits   repository.BlockCommitRepository
+	addresses      repository.AddressRepository
+	tokenTransfers repository.TokenTransferRepository
+	tokens         repository.TokenRepository
+	metrics        repository.MetricsRepository
+}
+
+// NewStorage opens the storage backend selected by STORAGE_BACKEND
+func NewStorage(db *database.MongoDB, config *config.Config) (*Storage, error) {
+	switch config.Storage.Backend {
+	case StorageBackendMongoDB, "":
+		return &Storage{
+			backend:        StorageBackendMongoDB,
+			blocks:         NewBlockRepository(db),
+			transactions:   NewTransactionRepository(db),
+			blockCommits:   NewBlockCommitRepository(db),
+			addresses:      NewAddressRepository(db),
+			tokenTransfers: NewTokenTransferRepository(db),
+			tokens:         NewTokenRepository(db),
+			metrics:        NewMetricsRepository(db),
+		}, nil
+	case StorageBackendPostgres:
+		postgres, err := database.NewPostgresDB(&config.Postgres)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+		}
+		return &Storage{
+			backend:        StorageBackendPostgres,
+			postgres:       postgres,
+			blocks:         NewPostgresBlockRepository(postgres),
+			transactions:   NewPostgresTransactionRepository(postgres),
+			blockCommits:   NewPostgresBlockCommitRepository(postgres),
+			addresses:      NewPostgresAddressRepository(postgres),
+			tokenTransfers: NewPostgresTokenTransferRepository(postgres),
+			tokens:         NewPostgresTokenRepository(postgres),
+			metrics:        NewPostgresMetricsRepository(postgres),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.Storage.Backend)
+	}
+}
+
+// Backend returns the name of the storage backend
+func (s *Storage) Backend() string {
+	return s.backend
+}
+
+// Migrate applies pending PostgreSQL migrations. MongoDB indexes are created
+// by database.MongoDB.CreateIndexes.
+func (s *Storage) Migrate(ctx context.Context) error {
+	if s.postgres == nil {
+		return nil
+	}
+	return s.postgres.Migrate(ctx)
+}
+
+// Close closes the PostgreSQL connection pool, the MongoDB connection is
+// closed by its owner
+func (s *Storage) Close() {
+	if s.postgres != nil {
+		s.postgres.Close()
+	}
+}
+
+// BlockRepository returns the block repository
+func (s *Storage) BlockRepository() repository.BlockRepository {
+	return s.blocks
+}
+
+// TransactionRepository returns the transaction repository
+func (s *Storage) TransactionRepository() repository.TransactionRepository {
+	return s.transactions
+}
+
+// BlockCommitRepository returns the block commit repository
+func (s *Storage) BlockCommitRepository() repository.BlockCommitRepository {
+	return s.blockCommits
+}
+
+// AddressRepository returns the address repository
+func (s *Storage) AddressRepository() repository.AddressRepository {
+	return s.addresses
+}
+
+// TokenTransferRepository returns the token transfer repository
+func (s *Storage) TokenTransferRepository() repository.TokenTransferRepository {
+	return s.tokenTransfers
+}
+
+// TokenRepository returns the token repository
+func (s *Storage) TokenRepository() repository.TokenRepository {
+	return s.tokens
+}
+
+// MetricsRepository returns the metrics repository
+func (s *Storage) MetricsRepository() repository.MetricsRepository {
+	return s.metrics
+}
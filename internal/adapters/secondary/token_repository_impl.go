@@ -0,0 +1,144 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TokenRepositoryImpl implements TokenRepository interface
+type TokenRepositoryImpl struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewTokenRepository creates new token repository
+func NewTokenRepository(db *database.MongoDB) repository.TokenRepository {
+	return &TokenRepositoryImpl{
+		db:         db,
+		collection: db.GetCollection("tokens"),
+	}
+}
+
+// AddTokens inserts the tokens not stored yet
+func (r *TokenRepositoryImpl) AddTokens(ctx context.Context, tokens []*entity.Token) ([]string, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	// In address order, so concurrent blocks touch shared tokens in the same order
+	sorted := make([]*entity.Token, len(tokens))
+	copy(sorted, tokens)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Address < sorted[j].Address })
+
+	operations := make([]mongo.WriteModel, 0, len(sorted))
+	for _, token := range sorted {
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"address": token.Address}).
+			SetUpdate(bson.M{"$setOnInsert": token}).
+			SetUpsert(true))
+	}
+
+	result, err := r.collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false))
+	// Concurrent inserts of the same token lose the race on the unique index
+	if err != nil && !isOnlyDuplicateKeyError(err) {
+		return nil, fmt.Errorf("failed to add tokens: %w", err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+
+	indexes := make([]int, 0, len(result.UpsertedIDs))
+	for index := range result.UpsertedIDs {
+		indexes = append(indexes, int(index))
+	}
+	sort.Ints(indexes)
+
+	added := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		added = append(added, sorted[index].Address)
+	}
+	return added, nil
+}
+
+// SaveToken stores the metadata and refresh state of a token
+func (r *TokenRepositoryImpl) SaveToken(ctx context.Context, token *entity.Token) error {
+	now := time.Now()
+	set := bson.M{
+		"standard":     token.Standard,
+		"name":         token.Name,
+		"symbol":       token.Symbol,
+		"decimals":     token.Decimals,
+		"total_supply": token.TotalSupply,
+		"interfaces":   token.Interfaces,
+		"failures":     token.Failures,
+		"last_error":   token.LastError,
+		"refresh_at":   token.RefreshAt,
+		"updated_at":   now,
+	}
+	if token.IsResolved() {
+		set["resolved_at"] = token.ResolvedAt
+	}
+	createdAt := token.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"address": token.Address},
+		bson.M{
+			"$set":         set,
+			"$setOnInsert": bson.M{"first_seen_block": token.FirstSeenBlock, "created_at": createdAt},
+		},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	return nil
+}
+
+// GetToken gets a token
+func (r *TokenRepositoryImpl) GetToken(ctx context.Context, address string) (*entity.Token, error) {
+	var token entity.Token
+	err := r.collection.FindOne(ctx, bson.M{"address": address}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetTokensToRefresh gets the tokens due for a lookup
+func (r *TokenRepositoryImpl) GetTokensToRefresh(ctx context.Context, now time.Time, limit int) ([]*entity.Token, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "refresh_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"refresh_at": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []*entity.Token
+	for cursor.Next(ctx) {
+		var token entity.Token
+		if err := cursor.Decode(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &token)
+	}
+
+	return tokens, cursor.Err()
+}
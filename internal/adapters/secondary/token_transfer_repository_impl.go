@@ -0,0 +1,115 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TokenTransferRepositoryImpl implements TokenTransferRepository interface
+type TokenTransferRepositoryImpl struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewTokenTransferRepository creates new token transfer repository
+func NewTokenTransferRepository(db *database.MongoDB) repository.TokenTransferRepository {
+	return &TokenTransferRepositoryImpl{
+		db:         db,
+		collection: db.GetCollection("token_transfers"),
+	}
+}
+
+// SaveTokenTransfers upserts transfers by transaction, log index and batch index
+func (r *TokenTransferRepositoryImpl) SaveTokenTransfers(ctx context.Context, transfers []*entity.TokenTransfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	operations := make([]mongo.WriteModel, 0, len(transfers))
+	for _, transfer := range transfers {
+		// A replaced document keeps its _id
+		doc := *transfer
+		doc.ID = primitive.NilObjectID
+		operations = append(operations, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{
+				"transaction_hash": transfer.TransactionHash,
+				"log_index":        transfer.LogIndex,
+				"batch_index":      transfer.BatchIndex,
+			}).
+			SetReplacement(&doc).
+			SetUpsert(true))
+	}
+
+	if _, err := r.collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to save token transfers: %w", err)
+	}
+	return nil
+}
+
+// GetTokenTransfersByTransaction gets the transfers of a transaction in log order
+func (r *TokenTransferRepositoryImpl) GetTokenTransfersByTransaction(ctx context.Context, txHash string) ([]*entity.TokenTransfer, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "log_index", Value: 1}, {Key: "batch_index", Value: 1}})
+	return r.findTokenTransfers(ctx, bson.M{"transaction_hash": txHash}, opts)
+}
+
+// GetTokenTransfersByToken gets the transfers of a token, newest first
+func (r *TokenTransferRepositoryImpl) GetTokenTransfersByToken(ctx context.Context, token string, limit int, offset int) ([]*entity.TokenTransfer, error) {
+	return r.findTokenTransfers(ctx, bson.M{"token": token}, newestTokenTransfers(limit, offset))
+}
+
+// GetTokenTransfersByHolder gets the transfers from or to an address, newest first
+func (r *TokenTransferRepositoryImpl) GetTokenTransfersByHolder(ctx context.Context, holder string, limit int, offset int) ([]*entity.TokenTransfer, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"from": holder},
+			{"to": holder},
+		},
+	}
+	return r.findTokenTransfers(ctx, filter, newestTokenTransfers(limit, offset))
+}
+
+// newestTokenTransfers sorts transfers newest first, in the order of the holder and token indexes
+func newestTokenTransfers(limit int, offset int) *options.FindOptions {
+	return options.Find().
+		SetSort(bson.D{
+			{Key: "block_number", Value: -1},
+			{Key: "log_index", Value: -1},
+			{Key: "batch_index", Value: -1},
+		}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+}
+
+// findTokenTransfers returns the matching transfers
+func (r *TokenTransferRepositoryImpl) findTokenTransfers(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*entity.TokenTransfer, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var transfers []*entity.TokenTransfer
+	for cursor.Next(ctx) {
+		var transfer entity.TokenTransfer
+		if err := cursor.Decode(&transfer); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, &transfer)
+	}
+
+	return transfers, cursor.Err()
+}
+
+// DeleteTokenTransfersByBlockHash deletes the transfers of a block
+func (r *TokenTransferRepositoryImpl) DeleteTokenTransfersByBlockHash(ctx context.Context, blockHash string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"block_hash": blockHash})
+	return err
+}
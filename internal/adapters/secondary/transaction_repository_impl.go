@@ -5,11 +5,13 @@ import (
 	"ethereum-raw-data-crawler/internal/domain/entity"
 	"ethereum-raw-data-crawler/internal/domain/repository"
 	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
 	"math/big"
 	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -19,6 +21,7 @@ import (
 type TransactionRepositoryImpl struct {
 	db         *database.MongoDB
 	collection *mongo.Collection
+	counts     *mongo.Collection
 }
 
 // retryOperation executes an operation with retry logic for MongoDB connection issues
@@ -77,14 +80,21 @@ func NewTransactionRepository(db *database.MongoDB) repository.TransactionReposi
 	return &TransactionRepositoryImpl{
 		db:         db,
 		collection: db.GetCollection("transactions"),
+		counts:     db.GetCollection(addressesCollection),
 	}
 }
 
 // CreateTransaction creates a new transaction
 func (r *TransactionRepositoryImpl) CreateTransaction(ctx context.Context, tx *entity.Transaction) error {
 	tx.ID = primitive.NewObjectID()
-	_, err := r.collection.InsertOne(ctx, tx)
-	return err
+	doc, err := transactionDocument(tx)
+	if err != nil {
+		return err
+	}
+	if _, err := r.collection.InsertOne(ctx, doc); err != nil {
+		return err
+	}
+	return countTransactions(ctx, r.collection, r.counts, []*entity.Transaction{tx})
 }
 
 // CreateTransactions creates multiple transactions with retry logic
@@ -93,16 +103,21 @@ func (r *TransactionRepositoryImpl) CreateTransactions(ctx context.Context, txs
 		return nil
 	}
 
-	return r.retryOperation(ctx, func() error {
+	err := r.retryOperation(ctx, func() error {
 		documents := make([]interface{}, len(txs))
 		for i, tx := range txs {
 			tx.ID = primitive.NewObjectID()
-			documents[i] = tx
+			doc, err := transactionDocument(tx)
+			if err != nil {
+				return err
+			}
+			documents[i] = doc
 		}
 
 		_, err := r.collection.InsertMany(ctx, documents)
 		return err
 	})
+	return r.countTransactions(ctx, txs, err)
 }
 
 // UpsertTransactions upserts multiple transactions using bulk operations with retry logic
@@ -111,64 +126,89 @@ func (r *TransactionRepositoryImpl) UpsertTransactions(ctx context.Context, txs
 		return nil
 	}
 
-	return r.retryOperation(ctx, func() error {
+	err := r.retryOperation(ctx, func() error {
 		// Create bulk write operations
 		var operations []mongo.WriteModel
 
 		for _, tx := range txs {
-			// Set ID if not already set
-			if tx.ID.IsZero() {
-				tx.ID = primitive.NewObjectID()
-			}
-
-			// Create filter based on transaction hash (unique identifier)
-			filter := bson.M{"hash": tx.Hash}
-
-			// Create update document excluding _id field to avoid immutable field error
-			update := bson.M{
-				"$set": bson.M{
-					"hash":                     tx.Hash,
-					"block_hash":               tx.BlockHash,
-					"block_number":             tx.BlockNumber,
-					"transaction_index":        tx.TransactionIndex,
-					"from":                     tx.From,
-					"to":                       tx.To,
-					"value":                    tx.Value,
-					"gas":                      tx.Gas,
-					"gas_price":                tx.GasPrice,
-					"gas_used":                 tx.GasUsed,
-					"cumulative_gas_used":      tx.CumulativeGasUsed,
-					"data":                     tx.Data,
-					"nonce":                    tx.Nonce,
-					"status":                   tx.Status,
-					"max_fee_per_gas":          tx.MaxFeePerGas,
-					"max_priority_fee_per_gas": tx.MaxPriorityFeePerGas,
-					"contract_address":         tx.ContractAddress,
-					"crawled_at":               tx.CrawledAt,
-					"network":                  tx.Network,
-					"processed_at":             tx.ProcessedAt,
-					"tx_status":                tx.TxStatus,
-				},
-				"$setOnInsert": bson.M{
-					"_id": tx.ID,
-				},
-			}
-
-			// Create upsert operation
-			upsertOp := mongo.NewUpdateOneModel()
-			upsertOp.SetFilter(filter)
-			upsertOp.SetUpdate(update)
-			upsertOp.SetUpsert(true)
-
-			operations = append(operations, upsertOp)
+			operations = append(operations, newTransactionUpsertModel(tx))
 		}
 
 		// Execute bulk write
 		opts := options.BulkWrite().SetOrdered(false) // Allow parallel execution
 		_, err := r.collection.BulkWrite(ctx, operations, opts)
-
 		return err
 	})
+	return r.countTransactions(ctx, txs, err)
+}
+
+// countTransactions counts the written transactions once the write and its
+// retries are done, also after a failed write, which may have stored some
+func (r *TransactionRepositoryImpl) countTransactions(ctx context.Context, txs []*entity.Transaction, writeErr error) error {
+	err := countTransactions(ctx, r.collection, r.counts, txs)
+	if writeErr != nil {
+		if err != nil {
+			return fmt.Errorf("%w (%v)", writeErr, err)
+		}
+		return writeErr
+	}
+	return err
+}
+
+// newTransactionUpsertModel builds the bulk upsert of a transaction, keyed by hash
+func newTransactionUpsertModel(tx *entity.Transaction) mongo.WriteModel {
+	// Set ID if not already set
+	if tx.ID.IsZero() {
+		tx.ID = primitive.NewObjectID()
+	}
+
+	// Create filter based on transaction hash (unique identifier)
+	filter := bson.M{"hash": tx.Hash}
+
+	// Create update document excluding _id field to avoid immutable field error
+	set := bson.M{
+		"hash":                     tx.Hash,
+		"block_hash":               tx.BlockHash,
+		"block_number":             tx.BlockNumber,
+		"block_timestamp":          tx.BlockTimestamp,
+		"transaction_index":        tx.TransactionIndex,
+		"from":                     tx.From,
+		"to":                       tx.To,
+		"value":                    tx.Value,
+		"gas":                      tx.Gas,
+		"gas_price":                tx.GasPrice,
+		"gas_used":                 tx.GasUsed,
+		"cumulative_gas_used":      tx.CumulativeGasUsed,
+		"data":                     tx.Data,
+		"nonce":                    tx.Nonce,
+		"status":                   tx.Status,
+		"max_fee_per_gas":          tx.MaxFeePerGas,
+		"max_priority_fee_per_gas": tx.MaxPriorityFeePerGas,
+		"contract_address":         tx.ContractAddress,
+		"receipt_missing":          tx.ReceiptMissing,
+		"crawled_at":               tx.CrawledAt,
+		"network":                  tx.Network,
+		"processed_at":             tx.ProcessedAt,
+		"tx_status":                tx.TxStatus,
+	}
+	if height, ok := blockHeight(tx); ok {
+		set["block_height"] = height
+	}
+	update := bson.M{
+		"$set": set,
+		"$setOnInsert": bson.M{
+			"_id":     tx.ID,
+			"counted": false,
+		},
+	}
+
+	// Create upsert operation
+	upsertOp := mongo.NewUpdateOneModel()
+	upsertOp.SetFilter(filter)
+	upsertOp.SetUpdate(update)
+	upsertOp.SetUpsert(true)
+
+	return upsertOp
 }
 
 // GetTransactionByHash gets transaction by hash
@@ -265,6 +305,81 @@ func (r *TransactionRepositoryImpl) GetTransactionsByAddress(ctx context.Context
 	return transactions, cursor.Err()
 }
 
+// GetAddressTransactions gets the transactions of an address, newest first,
+// paginated by block height and transaction index
+func (r *TransactionRepositoryImpl) GetAddressTransactions(ctx context.Context, filter repository.AddressTransactionFilter, after *repository.TransactionPosition, limit int) ([]*entity.Transaction, error) {
+	conditions := addressTransactionConditions(filter, after)
+
+	// One branch per role, so each is served by its address index in position order
+	var query bson.M
+	switch filter.Direction {
+	case repository.AddressDirectionIn:
+		query = bson.M{"$and": append(conditions, bson.M{"to": filter.Address})}
+	case repository.AddressDirectionOut:
+		query = bson.M{"$and": append(conditions, bson.M{"from": filter.Address})}
+	default:
+		query = bson.M{"$or": bson.A{
+			bson.M{"$and": append(bson.A{bson.M{"from": filter.Address}}, conditions...)},
+			bson.M{"$and": append(bson.A{bson.M{"to": filter.Address}}, conditions...)},
+		}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "block_height", Value: -1}, {Key: "transaction_index", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var transactions []*entity.Transaction
+	for cursor.Next(ctx) {
+		var tx entity.Transaction
+		if err := cursor.Decode(&tx); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, &tx)
+	}
+
+	return transactions, cursor.Err()
+}
+
+// addressTransactionConditions returns the conditions of an address
+// transaction filter other than the address
+func addressTransactionConditions(filter repository.AddressTransactionFilter, after *repository.TransactionPosition) bson.A {
+	conditions := bson.A{}
+	if filter.Success != nil {
+		status := 0
+		if *filter.Success {
+			status = 1
+		}
+		conditions = append(conditions, bson.M{"status": status, "receipt_missing": bson.M{"$ne": true}})
+	}
+	if filter.ContractCreation != nil {
+		if *filter.ContractCreation {
+			conditions = append(conditions, bson.M{"to": nil})
+		} else {
+			conditions = append(conditions, bson.M{"to": bson.M{"$ne": nil}})
+		}
+	}
+	if filter.FromBlock != nil {
+		conditions = append(conditions, bson.M{"block_height": bson.M{"$gte": int64(*filter.FromBlock)}})
+	}
+	if filter.ToBlock != nil {
+		conditions = append(conditions, bson.M{"block_height": bson.M{"$lte": int64(*filter.ToBlock)}})
+	}
+	if after != nil {
+		height := int64(after.BlockNumber)
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"block_height": bson.M{"$lt": height}},
+			bson.M{"block_height": height, "transaction_index": bson.M{"$lt": after.TransactionIndex}},
+		}})
+	}
+	return conditions
+}
+
 // GetTransactionsByStatus gets transactions by status
 func (r *TransactionRepositoryImpl) GetTransactionsByStatus(ctx context.Context, status entity.TransactionStatus, limit int) ([]*entity.Transaction, error) {
 	filter := bson.M{"tx_status": status}
@@ -290,16 +405,20 @@ func (r *TransactionRepositoryImpl) GetTransactionsByStatus(ctx context.Context,
 	return transactions, cursor.Err()
 }
 
-// GetTransactionsByTimeRange gets transactions by time range
-func (r *TransactionRepositoryImpl) GetTransactionsByTimeRange(ctx context.Context, startTime, endTime *big.Int) ([]*entity.Transaction, error) {
+// GetTransactionsByTimeRange gets transactions by the timestamp of their block
+func (r *TransactionRepositoryImpl) GetTransactionsByTimeRange(ctx context.Context, startTime, endTime time.Time) ([]*entity.Transaction, error) {
 	filter := bson.M{
-		"block_number": bson.M{
-			"$gte": startTime.String(),
-			"$lte": endTime.String(),
+		"block_timestamp": bson.M{
+			"$gte": startTime,
+			"$lte": endTime,
 		},
 	}
 
-	opts := options.Find().SetSort(bson.D{{Key: "block_number", Value: 1}})
+	opts := options.Find().SetSort(bson.D{
+		{Key: "block_timestamp", Value: 1},
+		{Key: "block_number", Value: 1},
+		{Key: "transaction_index", Value: 1},
+	})
 	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
@@ -327,6 +446,31 @@ func (r *TransactionRepositoryImpl) UpdateTransactionStatus(ctx context.Context,
 	return err
 }
 
+// UpdateTransactionReceipt patches the receipt fields of a stored transaction
+// and clears its receipt_missing flag
+func (r *TransactionRepositoryImpl) UpdateTransactionReceipt(ctx context.Context, tx *entity.Transaction) error {
+	filter := bson.M{"hash": tx.Hash}
+	update := bson.M{
+		"$set": bson.M{
+			"status":              tx.Status,
+			"gas_used":            tx.GasUsed,
+			"cumulative_gas_used": tx.CumulativeGasUsed,
+			"contract_address":    tx.ContractAddress,
+			"tx_status":           tx.TxStatus,
+		},
+		"$unset": bson.M{"receipt_missing": ""},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("transaction %s not found", tx.Hash)
+	}
+	return nil
+}
+
 // MarkTransactionAsProcessed marks transaction as processed
 func (r *TransactionRepositoryImpl) MarkTransactionAsProcessed(ctx context.Context, hash string) error {
 	filter := bson.M{"hash": hash}
@@ -344,14 +488,14 @@ func (r *TransactionRepositoryImpl) MarkTransactionAsProcessed(ctx context.Conte
 // DeleteTransaction deletes transaction
 func (r *TransactionRepositoryImpl) DeleteTransaction(ctx context.Context, hash string) error {
 	filter := bson.M{"hash": hash}
-	_, err := r.collection.DeleteOne(ctx, filter)
+	_, err := deleteTransactions(ctx, r.collection, r.counts, filter)
 	return err
 }
 
 // DeleteTransactionsByBlockHash deletes transactions by block hash
 func (r *TransactionRepositoryImpl) DeleteTransactionsByBlockHash(ctx context.Context, blockHash string) error {
 	filter := bson.M{"block_hash": blockHash}
-	_, err := r.collection.DeleteMany(ctx, filter)
+	_, err := deleteTransactions(ctx, r.collection, r.counts, filter)
 	return err
 }
 
@@ -377,25 +521,28 @@ func (r *TransactionRepositoryImpl) GetTransactionCountByStatus(ctx context.Cont
 	return r.collection.CountDocuments(ctx, filter)
 }
 
-// GetTransactionCountByAddress gets transaction count by address
+// GetTransactionCountByAddress gets transaction count by address from its counters
 func (r *TransactionRepositoryImpl) GetTransactionCountByAddress(ctx context.Context, address string) (int64, error) {
-	filter := bson.M{
-		"$or": []bson.M{
-			{"from": address},
-			{"to": address},
-		},
+	count, err := r.GetAddressTransactionCount(ctx, address)
+	if err != nil {
+		return 0, err
 	}
-	return r.collection.CountDocuments(ctx, filter)
+	return count.Total, nil
 }
 
-// GetTransactionVolumeByTimeRange gets transaction volume by time range
-func (r *TransactionRepositoryImpl) GetTransactionVolumeByTimeRange(ctx context.Context, startTime, endTime *big.Int) (*big.Int, error) {
+// GetAddressTransactionCount gets the transaction counters of an address
+func (r *TransactionRepositoryImpl) GetAddressTransactionCount(ctx context.Context, address string) (*entity.AddressTransactionCount, error) {
+	return getAddressTransactionCount(ctx, r.counts, address)
+}
+
+// GetTransactionVolumeByTimeRange gets transaction volume by the timestamp of their block
+func (r *TransactionRepositoryImpl) GetTransactionVolumeByTimeRange(ctx context.Context, startTime, endTime time.Time) (*big.Int, error) {
 	pipeline := []bson.M{
 		{
 			"$match": bson.M{
-				"block_number": bson.M{
-					"$gte": startTime.String(),
-					"$lte": endTime.String(),
+				"block_timestamp": bson.M{
+					"$gte": startTime,
+					"$lte": endTime,
 				},
 			},
 		},
@@ -414,22 +561,17 @@ func (r *TransactionRepositoryImpl) GetTransactionVolumeByTimeRange(ctx context.
 	defer cursor.Close(ctx)
 
 	var result struct {
-		Total string `bson:"total"`
+		Total bson.RawValue `bson:"total"`
 	}
 
 	if cursor.Next(ctx) {
 		if err := cursor.Decode(&result); err != nil {
 			return big.NewInt(0), err
 		}
-
-		totalValue, ok := new(big.Int).SetString(result.Total, 10)
-		if !ok {
-			return big.NewInt(0), nil
-		}
-		return totalValue, nil
+		return bigIntFromSum(result.Total)
 	}
 
-	return big.NewInt(0), nil
+	return big.NewInt(0), cursor.Err()
 }
 
 // GetTopTransactionsByValue gets top transactions by value
@@ -438,7 +580,10 @@ func (r *TransactionRepositoryImpl) GetTopTransactionsByValue(ctx context.Contex
 		SetSort(bson.D{{Key: "value", Value: -1}}).
 		SetLimit(int64(limit))
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	// Strings sort above numbers, values not migrated to Decimal128 yet are left out
+	filter := bson.M{"value": bson.M{"$type": "decimal"}}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -455,3 +600,23 @@ func (r *TransactionRepositoryImpl) GetTopTransactionsByValue(ctx context.Contex
 
 	return transactions, cursor.Err()
 }
+
+// bigIntFromSum converts the result of $sum over wei amounts. The sum is
+// Decimal128, or the integer 0 when no amount was summed.
+func bigIntFromSum(total bson.RawValue) (*big.Int, error) {
+	switch total.Type {
+	case bsontype.Decimal128:
+		amount, err := entity.WeiFromDecimal128(total.Decimal128())
+		if err != nil {
+			return big.NewInt(0), err
+		}
+		sum, _ := amount.BigInt()
+		return sum, nil
+	case bsontype.Int32:
+		return big.NewInt(int64(total.Int32())), nil
+	case bsontype.Int64:
+		return big.NewInt(total.Int64()), nil
+	default:
+		return big.NewInt(0), fmt.Errorf("unexpected transaction volume type %s", total.Type)
+	}
+}
@@ -0,0 +1,132 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// transactionWeiFields are the transaction fields holding wei amounts
+var transactionWeiFields = []string{"value", "gas_price", "max_fee_per_gas", "max_priority_fee_per_gas"}
+
+// WeiMigrationResult summarizes a wei migration run
+type WeiMigrationResult struct {
+	Scanned  int64
+	Migrated int64
+	// Transactions with an amount that isn't a decimal integer or doesn't fit
+	// in Decimal128, left as they are
+	Failed       int64
+	FailedHashes []string
+}
+
+// maxReportedFailures bounds WeiMigrationResult.FailedHashes
+const maxReportedFailures = 100
+
+// MigrateWeiValues converts the wei amounts of transactions stored as decimal
+// strings to Decimal128. Only transactions still holding a string are read, so
+// the migration can run while the crawler writes and can be resumed.
+func MigrateWeiValues(ctx context.Context, db *database.MongoDB, batchSize int, dryRun bool) (*WeiMigrationResult, error) {
+	collection := db.GetCollection("transactions")
+
+	var legacy bson.A
+	projection := bson.M{"hash": 1}
+	for _, field := range transactionWeiFields {
+		legacy = append(legacy, bson.M{field: bson.M{"$type": "string"}})
+		projection[field] = 1
+	}
+
+	opts := options.Find().
+		SetProjection(projection).
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetBatchSize(int32(batchSize))
+
+	cursor, err := collection.Find(ctx, bson.M{"$or": legacy}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	result := &WeiMigrationResult{}
+	var updates []mongo.WriteModel
+	flush := func() error {
+		if len(updates) == 0 || dryRun {
+			updates = updates[:0]
+			return nil
+		}
+		if _, err := collection.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to write migrated transactions: %w", err)
+		}
+		updates = updates[:0]
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID   primitive.ObjectID `bson:"_id"`
+			Hash string             `bson:"hash"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return result, err
+		}
+		result.Scanned++
+
+		set, err := weiFieldsToDecimal128(cursor.Current)
+		if err != nil {
+			result.Failed++
+			if len(result.FailedHashes) < maxReportedFailures {
+				result.FailedHashes = append(result.FailedHashes, doc.Hash)
+			}
+			continue
+		}
+
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetUpdate(bson.M{"$set": set}))
+		result.Migrated++
+
+		if len(updates) >= batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return result, err
+	}
+
+	return result, flush()
+}
+
+// weiFieldsToDecimal128 returns the string wei amounts of a transaction
+// document converted to Decimal128
+func weiFieldsToDecimal128(doc bson.Raw) (bson.M, error) {
+	set := bson.M{}
+	for _, field := range transactionWeiFields {
+		value, err := doc.LookupErr(field)
+		if err != nil {
+			continue // Not set, e.g. max_fee_per_gas of legacy transactions
+		}
+		s, ok := value.StringValueOK()
+		if !ok {
+			continue // Already migrated
+		}
+
+		amount := entity.Wei(s)
+		if amount == "" {
+			set[field] = nil
+			continue
+		}
+		decimal, err := amount.Decimal128()
+		if err != nil {
+			return nil, err
+		}
+		set[field] = decimal
+	}
+	return set, nil
+}
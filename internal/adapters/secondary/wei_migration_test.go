@@ -0,0 +1,84 @@
+package secondary
+
+import (
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWeiDecimal128RoundTrip(t *testing.T) {
+	tx := &entity.Transaction{
+		Hash:     "0x1",
+		Value:    "123456789012345678901234567890",
+		GasPrice: "20000000000",
+	}
+
+	data, err := bson.Marshal(tx)
+	require.NoError(t, err)
+	doc := bson.Raw(data)
+	assert.Equal(t, bsontype.Decimal128, doc.Lookup("value").Type)
+	assert.Equal(t, bsontype.Decimal128, doc.Lookup("gas_price").Type)
+	_, err = doc.LookupErr("max_fee_per_gas")
+	assert.Error(t, err, "empty amounts are omitted")
+
+	var decoded entity.Transaction
+	require.NoError(t, bson.Unmarshal(data, &decoded))
+	assert.Equal(t, tx.Value, decoded.Value)
+	assert.Equal(t, tx.GasPrice, decoded.GasPrice)
+	assert.Empty(t, decoded.MaxFeePerGas)
+}
+
+func TestWeiDecodesLegacyStrings(t *testing.T) {
+	data, err := bson.Marshal(bson.M{"hash": "0x1", "value": "1000000000000000000", "gas_price": nil})
+	require.NoError(t, err)
+
+	var decoded entity.Transaction
+	require.NoError(t, bson.Unmarshal(data, &decoded))
+	assert.Equal(t, entity.Wei("1000000000000000000"), decoded.Value)
+	assert.Empty(t, decoded.GasPrice)
+}
+
+func TestWeiFromDecimal128(t *testing.T) {
+	scaled, err := primitive.ParseDecimal128("1.5E+3")
+	require.NoError(t, err)
+	amount, err := entity.WeiFromDecimal128(scaled)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Wei("1500"), amount)
+
+	fraction, err := primitive.ParseDecimal128("1.5")
+	require.NoError(t, err)
+	_, err = entity.WeiFromDecimal128(fraction)
+	assert.Error(t, err)
+
+	// 35 significant digits don't fit
+	_, err = entity.Wei("12345678901234567890123456789012345").Decimal128()
+	assert.Error(t, err)
+	_, err = entity.Wei("0x10").Decimal128()
+	assert.Error(t, err)
+}
+
+func TestWeiFieldsToDecimal128(t *testing.T) {
+	data, err := bson.Marshal(bson.M{
+		"hash":            "0x1",
+		"value":           "42",
+		"gas_price":       primitive.NewDecimal128(0, 7),
+		"max_fee_per_gas": "",
+	})
+	require.NoError(t, err)
+
+	set, err := weiFieldsToDecimal128(data)
+	require.NoError(t, err)
+	require.Len(t, set, 2, "gas_price is already migrated")
+	assert.Equal(t, "42", set["value"].(primitive.Decimal128).String())
+	assert.Nil(t, set["max_fee_per_gas"])
+
+	invalid, err := bson.Marshal(bson.M{"hash": "0x2", "value": "not a number"})
+	require.NoError(t, err)
+	_, err = weiFieldsToDecimal128(invalid)
+	assert.Error(t, err)
+}
@@ -0,0 +1,207 @@
+package secondary
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WorkRangeRepositoryImpl implements WorkRangeRepository interface
+type WorkRangeRepositoryImpl struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewWorkRangeRepository creates new work range repository
+func NewWorkRangeRepository(db *database.MongoDB) repository.WorkRangeRepository {
+	return &WorkRangeRepositoryImpl{
+		db:         db,
+		collection: db.GetCollection("work_ranges"),
+	}
+}
+
+// CreateWorkRanges creates multiple work ranges unless ranges were published
+// with a higher fencing token. A range a stale leader inserts after the check
+// at a start block the newer leader published fails on the unique index.
+func (r *WorkRangeRepositoryImpl) CreateWorkRanges(ctx context.Context, ranges []*entity.WorkRange, fencingToken int64) error {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	if fencingToken > 0 {
+		newer, err := r.collection.CountDocuments(ctx,
+			bson.M{"network": ranges[0].Network, "fencing_token": bson.M{"$gt": fencingToken}},
+			options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if newer > 0 {
+			return repository.ErrStaleFencingToken
+		}
+	}
+
+	documents := make([]interface{}, len(ranges))
+	for i, workRange := range ranges {
+		workRange.ID = primitive.NewObjectID()
+		workRange.FencingToken = fencingToken
+		documents[i] = workRange
+	}
+
+	_, err := r.collection.InsertMany(ctx, documents)
+	return err
+}
+
+// GetLastWorkRange gets the range with the highest end block
+func (r *WorkRangeRepositoryImpl) GetLastWorkRange(ctx context.Context, network string) (*entity.WorkRange, error) {
+	filter := bson.M{"network": network}
+	opts := options.FindOne().SetSort(bson.D{{Key: "end_block", Value: -1}})
+
+	var workRange entity.WorkRange
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&workRange)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &workRange, nil
+}
+
+// ClaimWorkRange claims the lowest available range, after failing the expired
+// claims that used up their attempts
+func (r *WorkRangeRepositoryImpl) ClaimWorkRange(ctx context.Context, network, workerID string, claimTTL time.Duration, maxAttempts int) (*entity.WorkRange, error) {
+	now := time.Now()
+
+	// The worker died or hung on every attempt
+	exhausted := bson.M{
+		"network":          network,
+		"status":           entity.WorkRangeStatusClaimed,
+		"claim_expires_at": bson.M{"$lte": now},
+		"attempts":         bson.M{"$gte": maxAttempts},
+	}
+	if _, err := r.collection.UpdateMany(ctx, exhausted, bson.M{
+		"$set":   bson.M{"status": entity.WorkRangeStatusFailed, "last_error": "claim expired", "failed_at": now},
+		"$unset": bson.M{"claim_expires_at": ""},
+	}); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"network": network,
+		"$or": []bson.M{
+			{"status": entity.WorkRangeStatusPending},
+			{"status": entity.WorkRangeStatusClaimed, "claim_expires_at": bson.M{"$lte": now}, "attempts": bson.M{"$lt": maxAttempts}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":           entity.WorkRangeStatusClaimed,
+			"worker_id":        workerID,
+			"claimed_at":       now,
+			"claim_expires_at": now.Add(claimTTL),
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "start_block", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var workRange entity.WorkRange
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&workRange)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &workRange, nil
+}
+
+// ExtendWorkRangeClaim extends a claim still held by the worker
+func (r *WorkRangeRepositoryImpl) ExtendWorkRangeClaim(ctx context.Context, id primitive.ObjectID, workerID string, claimTTL time.Duration) (bool, error) {
+	filter := claimFilter(id, workerID)
+	update := bson.M{"$set": bson.M{"claim_expires_at": time.Now().Add(claimTTL)}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// CompleteWorkRange marks a range as completed
+func (r *WorkRangeRepositoryImpl) CompleteWorkRange(ctx context.Context, id primitive.ObjectID, workerID string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":       entity.WorkRangeStatusCompleted,
+			"completed_at": time.Now(),
+		},
+		"$unset": bson.M{"claim_expires_at": "", "last_error": ""},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, claimFilter(id, workerID), update)
+	return err
+}
+
+// ReleaseWorkRange returns a range to the queue or marks it failed
+func (r *WorkRangeRepositoryImpl) ReleaseWorkRange(ctx context.Context, id primitive.ObjectID, workerID string, lastError string, maxAttempts int) error {
+	// Pipeline update so the status can depend on the stored attempt count
+	exhausted := bson.M{"$gte": bson.A{"$attempts", maxAttempts}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"status":     bson.M{"$cond": bson.A{exhausted, entity.WorkRangeStatusFailed, entity.WorkRangeStatusPending}},
+			"failed_at":  bson.M{"$cond": bson.A{exhausted, time.Now(), "$$REMOVE"}},
+			"last_error": lastError,
+		}}},
+		{{Key: "$unset", Value: bson.A{"claim_expires_at"}}},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, claimFilter(id, workerID), update)
+	return err
+}
+
+// RequeueFailedWorkRanges returns the ranges that failed before failedBefore to the queue
+func (r *WorkRangeRepositoryImpl) RequeueFailedWorkRanges(ctx context.Context, network string, failedBefore time.Time) (int64, error) {
+	// Ranges failed before failed_at was stored are requeued as well
+	filter := bson.M{
+		"network":   network,
+		"status":    entity.WorkRangeStatusFailed,
+		"failed_at": bson.M{"$not": bson.M{"$gt": failedBefore}},
+	}
+	update := bson.M{
+		"$set":   bson.M{"status": entity.WorkRangeStatusPending, "attempts": 0},
+		"$unset": bson.M{"failed_at": ""},
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// GetWorkRangeCountByStatus gets work range count by status
+func (r *WorkRangeRepositoryImpl) GetWorkRangeCountByStatus(ctx context.Context, network string, status entity.WorkRangeStatus) (int64, error) {
+	filter := bson.M{"network": network, "status": status}
+	return r.collection.CountDocuments(ctx, filter)
+}
+
+// claimFilter matches a range still claimed by the worker
+func claimFilter(id primitive.ObjectID, workerID string) bson.M {
+	return bson.M{
+		"_id":       id,
+		"worker_id": workerID,
+		"status":    entity.WorkRangeStatusClaimed,
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultAddressActivityLimit is the page size when none is requested
+	DefaultAddressActivityLimit = 50
+	// MaxAddressActivityLimit bounds the page size
+	MaxAddressActivityLimit = 1000
+)
+
+// ErrInvalidAddressActivityRequest is returned for malformed address activity requests
+var ErrInvalidAddressActivityRequest = errors.New("invalid address activity request")
+
+// AddressActivityRequest selects a page of the transactions of an address
+type AddressActivityRequest struct {
+	repository.AddressTransactionFilter
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor string
+	// Limit is the page size, 0 for DefaultAddressActivityLimit
+	Limit int
+}
+
+// AddressActivityPage is a page of the transactions of an address, newest first
+type AddressActivityPage struct {
+	Transactions []*entity.Transaction `json:"transactions"`
+	// NextCursor continues after the last transaction, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// AddressActivityService pages through the transactions of an address
+type AddressActivityService struct {
+	txRepo repository.TransactionRepository
+	logger *logger.Logger
+}
+
+// NewAddressActivityService creates a new address activity service
+func NewAddressActivityService(txRepo repository.TransactionRepository, logger *logger.Logger) *AddressActivityService {
+	return &AddressActivityService{
+		txRepo: txRepo,
+		logger: logger.WithComponent("address-activity-service"),
+	}
+}
+
+// ListTransactions returns a page of the transactions of an address. Pages
+// are keyed by the position of their last transaction rather than an offset,
+// so a page costs the same however deep it is, and transactions stored
+// while paging don't shift the following pages.
+func (s *AddressActivityService) ListTransactions(ctx context.Context, req AddressActivityRequest) (*AddressActivityPage, error) {
+	if err := validateAddressActivityRequest(&req); err != nil {
+		return nil, err
+	}
+
+	var after *repository.TransactionPosition
+	if req.Cursor != "" {
+		position, err := decodeActivityCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = position
+	}
+
+	// One more than the page tells whether there is a next page
+	txs, err := s.txRepo.GetAddressTransactions(ctx, req.AddressTransactionFilter, after, req.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions of %s: %w", req.Address, err)
+	}
+
+	page := &AddressActivityPage{Transactions: txs}
+	if len(txs) > req.Limit {
+		page.Transactions = txs[:req.Limit]
+		cursor, err := encodeActivityCursor(page.Transactions[req.Limit-1])
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = cursor
+	}
+	if page.Transactions == nil {
+		page.Transactions = []*entity.Transaction{}
+	}
+	return page, nil
+}
+
+// CountTransactions returns the transaction counters of an address
+func (s *AddressActivityService) CountTransactions(ctx context.Context, address string) (*entity.AddressTransactionCount, error) {
+	if address == "" {
+		return nil, fmt.Errorf("%w: address is required", ErrInvalidAddressActivityRequest)
+	}
+	count, err := s.txRepo.GetAddressTransactionCount(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count transactions of %s: %w", address, err)
+	}
+	return count, nil
+}
+
+// validateAddressActivityRequest checks a request and applies the default limit
+func validateAddressActivityRequest(req *AddressActivityRequest) error {
+	if req.Address == "" {
+		return fmt.Errorf("%w: address is required", ErrInvalidAddressActivityRequest)
+	}
+	switch req.Direction {
+	case repository.AddressDirectionAll, repository.AddressDirectionIn, repository.AddressDirectionOut:
+	default:
+		return fmt.Errorf("%w: unknown direction %q, expected in or out", ErrInvalidAddressActivityRequest, req.Direction)
+	}
+	if req.FromBlock != nil && req.ToBlock != nil && *req.FromBlock > *req.ToBlock {
+		return fmt.Errorf("%w: from_block %d is after to_block %d", ErrInvalidAddressActivityRequest, *req.FromBlock, *req.ToBlock)
+	}
+	if req.Limit < 0 || req.Limit > MaxAddressActivityLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidAddressActivityRequest, MaxAddressActivityLimit)
+	}
+	if req.Limit == 0 {
+		req.Limit = DefaultAddressActivityLimit
+	}
+	return nil
+}
+
+// encodeActivityCursor returns the cursor continuing after a transaction
+func encodeActivityCursor(tx *entity.Transaction) (string, error) {
+	blockNumber, err := strconv.ParseUint(tx.BlockNumber, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid block number %q of transaction %s: %w", tx.BlockNumber, tx.Hash, err)
+	}
+	position := fmt.Sprintf("%d:%d", blockNumber, tx.TransactionIndex)
+	return base64.RawURLEncoding.EncodeToString([]byte(position)), nil
+}
+
+// decodeActivityCursor returns the position a cursor continues after
+func decodeActivityCursor(cursor string) (*repository.TransactionPosition, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", ErrInvalidAddressActivityRequest)
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	blockPart, indexPart, ok := strings.Cut(string(data), ":")
+	if !ok {
+		return nil, invalid
+	}
+	blockNumber, err := strconv.ParseUint(blockPart, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	index, err := strconv.ParseUint(indexPart, 10, 32)
+	if err != nil {
+		return nil, invalid
+	}
+	return &repository.TransactionPosition{BlockNumber: blockNumber, TransactionIndex: uint(index)}, nil
+}
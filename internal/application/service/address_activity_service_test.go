@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAddressTransactions serves address history newest first from a list
+// sorted oldest first, honouring the direction and position only
+type fakeAddressTransactions struct {
+	repository.TransactionRepository
+	transactions []*entity.Transaction
+	lastLimit    int
+}
+
+func (r *fakeAddressTransactions) GetAddressTransactions(ctx context.Context, filter repository.AddressTransactionFilter, after *repository.TransactionPosition, limit int) ([]*entity.Transaction, error) {
+	r.lastLimit = limit
+	var result []*entity.Transaction
+	for i := len(r.transactions) - 1; i >= 0 && len(result) < limit; i-- {
+		tx := r.transactions[i]
+		sent := tx.From == filter.Address
+		received := tx.To != nil && *tx.To == filter.Address
+		switch {
+		case filter.Direction == repository.AddressDirectionIn && !received,
+			filter.Direction == repository.AddressDirectionOut && !sent,
+			!sent && !received:
+			continue
+		}
+		if after != nil {
+			number, _ := strconv.ParseUint(tx.BlockNumber, 10, 64)
+			if number > after.BlockNumber || (number == after.BlockNumber && tx.TransactionIndex >= after.TransactionIndex) {
+				continue
+			}
+		}
+		result = append(result, tx)
+	}
+	return result, nil
+}
+
+func (r *fakeAddressTransactions) GetAddressTransactionCount(ctx context.Context, address string) (*entity.AddressTransactionCount, error) {
+	return &entity.AddressTransactionCount{Address: address, Total: int64(len(r.transactions))}, nil
+}
+
+// newActivityTestService stores 5 transactions of 0xalice, in blocks 10 and
+// 11, every other one received
+func newActivityTestService(t *testing.T) (*AddressActivityService, *fakeAddressTransactions) {
+	alice, bob := "0xalice", "0xbob"
+	repo := &fakeAddressTransactions{}
+	for i := 0; i < 5; i++ {
+		tx := &entity.Transaction{
+			Hash:             fmt.Sprintf("0x%d", i),
+			BlockNumber:      strconv.Itoa(10 + i/3),
+			TransactionIndex: uint(i % 3),
+			From:             alice,
+			To:               &bob,
+		}
+		if i%2 == 1 {
+			tx.From, tx.To = bob, &alice
+		}
+		repo.transactions = append(repo.transactions, tx)
+	}
+	return NewAddressActivityService(repo, newTestLogger(t)), repo
+}
+
+func activityHashes(page *AddressActivityPage) []string {
+	hashes := make([]string, len(page.Transactions))
+	for i, tx := range page.Transactions {
+		hashes[i] = tx.Hash
+	}
+	return hashes
+}
+
+func TestAddressActivityPagination(t *testing.T) {
+	svc, repo := newActivityTestService(t)
+	ctx := context.Background()
+
+	req := AddressActivityRequest{Limit: 2}
+	req.Address = "0xalice"
+
+	var pages [][]string
+	for {
+		page, err := svc.ListTransactions(ctx, req)
+		require.NoError(t, err)
+		pages = append(pages, activityHashes(page))
+		if page.NextCursor == "" {
+			break
+		}
+		req.Cursor = page.NextCursor
+	}
+	assert.Equal(t, [][]string{{"0x4", "0x3"}, {"0x2", "0x1"}, {"0x0"}}, pages)
+	assert.Equal(t, 3, repo.lastLimit, "one more than the page size")
+
+	// A page ending exactly at the last transaction has no cursor
+	req = AddressActivityRequest{Limit: 5}
+	req.Address = "0xalice"
+	page, err := svc.ListTransactions(ctx, req)
+	require.NoError(t, err)
+	assert.Len(t, page.Transactions, 5)
+	assert.Empty(t, page.NextCursor)
+
+	req.Direction = repository.AddressDirectionIn
+	page, err = svc.ListTransactions(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0x3", "0x1"}, activityHashes(page))
+
+	req.Address = "0xnobody"
+	page, err = svc.ListTransactions(ctx, req)
+	require.NoError(t, err)
+	assert.NotNil(t, page.Transactions, "an empty page lists no transactions")
+	assert.Empty(t, page.Transactions)
+}
+
+func TestAddressActivityDefaultLimit(t *testing.T) {
+	svc, repo := newActivityTestService(t)
+
+	req := AddressActivityRequest{}
+	req.Address = "0xalice"
+	_, err := svc.ListTransactions(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAddressActivityLimit+1, repo.lastLimit)
+}
+
+func TestAddressActivityInvalidRequests(t *testing.T) {
+	svc, _ := newActivityTestService(t)
+	ctx := context.Background()
+	from, to := uint64(20), uint64(10)
+
+	requests := map[string]AddressActivityRequest{
+		"no address": {},
+		"direction":  {AddressTransactionFilter: repository.AddressTransactionFilter{Address: "0xalice", Direction: "sideways"}},
+		"range":      {AddressTransactionFilter: repository.AddressTransactionFilter{Address: "0xalice", FromBlock: &from, ToBlock: &to}},
+		"limit":      {AddressTransactionFilter: repository.AddressTransactionFilter{Address: "0xalice"}, Limit: MaxAddressActivityLimit + 1},
+		"cursor":     {AddressTransactionFilter: repository.AddressTransactionFilter{Address: "0xalice"}, Cursor: "not-a-cursor"},
+	}
+	for name, req := range requests {
+		_, err := svc.ListTransactions(ctx, req)
+		assert.ErrorIs(t, err, ErrInvalidAddressActivityRequest, name)
+	}
+
+	_, err := svc.CountTransactions(ctx, "")
+	assert.ErrorIs(t, err, ErrInvalidAddressActivityRequest)
+}
+
+func TestActivityCursorRoundTrip(t *testing.T) {
+	cursor, err := encodeActivityCursor(&entity.Transaction{BlockNumber: "19000000", TransactionIndex: 42})
+	require.NoError(t, err)
+
+	position, err := decodeActivityCursor(cursor)
+	require.NoError(t, err)
+	assert.Equal(t, &repository.TransactionPosition{BlockNumber: 19_000_000, TransactionIndex: 42}, position)
+}
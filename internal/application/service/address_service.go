@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// addressClassifyBatchSize is the number of addresses classified per page on a rebuild
+const addressClassifyBatchSize = 500
+
+// AddressRebuildRequest describes an address rebuild
+type AddressRebuildRequest struct {
+	// CheckCode classifies the addresses the transactions don't tell about
+	// with eth_getCode
+	CheckCode bool
+}
+
+// AddressRebuildResult summarizes an address rebuild
+type AddressRebuildResult struct {
+	Addresses  int64
+	Classified int64 // By their code
+	Contracts  int64 // Of the ones classified by their code
+	Failed     int64 // Code not fetched, left unclassified
+	Duration   time.Duration
+}
+
+// AddressService records the addresses of processed blocks: when they were
+// first seen and last active, and whether they are contracts
+type AddressService struct {
+	addressRepo       repository.AddressRepository
+	blockchainService service.BlockchainService
+	checkCode         bool
+	logger            *logger.Logger
+}
+
+// NewAddressService creates a new address service
+func NewAddressService(
+	addressRepo repository.AddressRepository,
+	blockchainService service.BlockchainService,
+	config *config.Config,
+	logger *logger.Logger,
+) *AddressService {
+	return &AddressService{
+		addressRepo:       addressRepo,
+		blockchainService: blockchainService,
+		checkCode:         config.Addresses.CheckCode,
+		logger:            logger.WithComponent("address-service"),
+	}
+}
+
+// RecordBlock records the addresses of the transactions of a block. Senders
+// are no contracts and created contracts are; recipients not classified yet
+// are classified by their code at the latest block. Recording a block again
+// changes nothing.
+func (s *AddressService) RecordBlock(ctx context.Context, transactions []*entity.Transaction) error {
+	updates, err := addressUpdates(transactions)
+	if err != nil {
+		return err
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if s.checkCode {
+		if err := s.classifyRecipients(ctx, updates); err != nil {
+			return err
+		}
+	}
+
+	if err := s.addressRepo.RecordActivity(ctx, updates); err != nil {
+		return fmt.Errorf("failed to record addresses: %w", err)
+	}
+	return nil
+}
+
+// classifyRecipients sets the contract status of the recipients the
+// transactions don't classify and that aren't classified yet
+func (s *AddressService) classifyRecipients(ctx context.Context, updates []*repository.AddressUpdate) error {
+	unknown := make(map[string]*repository.AddressUpdate)
+	var addresses []string
+	for _, update := range updates {
+		if update.IsContract == nil {
+			unknown[update.Address] = update
+			addresses = append(addresses, update.Address)
+		}
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	unclassified, err := s.addressRepo.GetUnclassifiedAddresses(ctx, addresses)
+	if err != nil {
+		return fmt.Errorf("failed to get unclassified addresses: %w", err)
+	}
+
+	for _, address := range unclassified {
+		isContract, err := s.hasCode(ctx, address)
+		if err != nil {
+			// Left unclassified, it is checked again the next time it is seen
+			s.logger.Warn("Failed to get code of address", zap.String("address", address), zap.Error(err))
+			continue
+		}
+		unknown[address].IsContract = &isContract
+	}
+	return nil
+}
+
+// hasCode tells whether an address has code at the latest block
+func (s *AddressService) hasCode(ctx context.Context, address string) (bool, error) {
+	code, err := s.blockchainService.GetCode(ctx, address, nil)
+	if err != nil {
+		return false, err
+	}
+	return len(code) > 0, nil
+}
+
+// Rebuild recomputes every address from the stored transactions and then
+// classifies the remaining addresses by their code. Run it while the crawler
+// is stopped.
+func (s *AddressService) Rebuild(ctx context.Context, req AddressRebuildRequest) (*AddressRebuildResult, error) {
+	result := &AddressRebuildResult{}
+	startTime := time.Now()
+
+	s.logger.Info("Rebuilding addresses from stored transactions")
+	count, err := s.addressRepo.RebuildAddresses(ctx)
+	if err != nil {
+		result.Duration = time.Since(startTime)
+		return result, err
+	}
+	result.Addresses = count
+
+	if req.CheckCode {
+		s.logger.Info("Classifying addresses by their code", zap.Int64("addresses", count))
+		err = s.classifyStored(ctx, result)
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, err
+}
+
+// classifyStored classifies the stored addresses not classified yet, page by page
+func (s *AddressService) classifyStored(ctx context.Context, result *AddressRebuildResult) error {
+	after := ""
+	for {
+		addresses, err := s.addressRepo.ListUnclassifiedAddresses(ctx, after, addressClassifyBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list unclassified addresses: %w", err)
+		}
+		if len(addresses) == 0 {
+			return nil
+		}
+
+		statuses := make(map[string]bool, len(addresses))
+		for _, address := range addresses {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			isContract, err := s.hasCode(ctx, address)
+			if err != nil {
+				s.logger.Warn("Failed to get code of address", zap.String("address", address), zap.Error(err))
+				result.Failed++
+				continue
+			}
+			statuses[address] = isContract
+			result.Classified++
+			if isContract {
+				result.Contracts++
+			}
+		}
+
+		if err := s.addressRepo.SetContractStatus(ctx, statuses); err != nil {
+			return fmt.Errorf("failed to set contract status: %w", err)
+		}
+		after = addresses[len(addresses)-1]
+	}
+}
+
+// addressUpdates returns the activity of each address in the transactions of
+// a block, in address order
+func addressUpdates(transactions []*entity.Transaction) ([]*repository.AddressUpdate, error) {
+	byAddress := make(map[string]*repository.AddressUpdate)
+	see := func(address string, block uint64, tx *entity.Transaction, isContract *bool) {
+		update, ok := byAddress[address]
+		if !ok {
+			update = &repository.AddressUpdate{
+				Address:      address,
+				FirstBlock:   block,
+				FirstTxIndex: tx.TransactionIndex,
+				FirstTx:      tx.Hash,
+			}
+			byAddress[address] = update
+		}
+		if block < update.FirstBlock || (block == update.FirstBlock && tx.TransactionIndex < update.FirstTxIndex) {
+			update.FirstBlock, update.FirstTxIndex, update.FirstTx = block, tx.TransactionIndex, tx.Hash
+		}
+		if block > update.LastBlock {
+			update.LastBlock = block
+		}
+		// A contract stays one
+		if isContract != nil && (update.IsContract == nil || !*update.IsContract) {
+			update.IsContract = isContract
+		}
+	}
+
+	notContract, contract := false, true
+	for _, tx := range transactions {
+		block, err := strconv.ParseUint(tx.BlockNumber, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block number %q of transaction %s: %w", tx.BlockNumber, tx.Hash, err)
+		}
+		see(tx.From, block, tx, &notContract)
+		if tx.To != nil && *tx.To != "" {
+			see(*tx.To, block, tx, nil)
+		}
+		if tx.ContractAddress != nil && *tx.ContractAddress != "" {
+			see(*tx.ContractAddress, block, tx, &contract)
+		}
+	}
+
+	updates := make([]*repository.AddressUpdate, 0, len(byAddress))
+	for _, update := range byAddress {
+		updates = append(updates, update)
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Address < updates[j].Address })
+	return updates, nil
+}
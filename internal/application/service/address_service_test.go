@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"fmt"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAddressRepository keeps addresses in a map with the repository's merge rules
+type fakeAddressRepository struct {
+	addresses map[string]*entity.Address
+	err       error
+}
+
+func newFakeAddressRepository() *fakeAddressRepository {
+	return &fakeAddressRepository{addresses: map[string]*entity.Address{}}
+}
+
+func (r *fakeAddressRepository) RecordActivity(ctx context.Context, updates []*repository.AddressUpdate) error {
+	if r.err != nil {
+		return r.err
+	}
+	for _, update := range updates {
+		address, ok := r.addresses[update.Address]
+		if !ok {
+			address = &entity.Address{Address: update.Address, FirstSeenBlock: update.FirstBlock, FirstSeenTxIndex: update.FirstTxIndex, FirstSeenTx: update.FirstTx}
+			r.addresses[update.Address] = address
+		}
+		if update.FirstBlock < address.FirstSeenBlock ||
+			(update.FirstBlock == address.FirstSeenBlock && update.FirstTxIndex < address.FirstSeenTxIndex) {
+			address.FirstSeenBlock, address.FirstSeenTxIndex, address.FirstSeenTx = update.FirstBlock, update.FirstTxIndex, update.FirstTx
+		}
+		if update.LastBlock > address.LastActiveBlock {
+			address.LastActiveBlock = update.LastBlock
+		}
+		if update.IsContract != nil {
+			r.setContract(address, *update.IsContract)
+		}
+	}
+	return nil
+}
+
+func (r *fakeAddressRepository) setContract(address *entity.Address, isContract bool) {
+	isContract = isContract || (address.IsContract != nil && *address.IsContract)
+	address.IsContract = &isContract
+}
+
+func (r *fakeAddressRepository) SetContractStatus(ctx context.Context, statuses map[string]bool) error {
+	for address, isContract := range statuses {
+		if stored, ok := r.addresses[address]; ok {
+			r.setContract(stored, isContract)
+		}
+	}
+	return nil
+}
+
+func (r *fakeAddressRepository) GetAddress(ctx context.Context, address string) (*entity.Address, error) {
+	return r.addresses[address], nil
+}
+
+func (r *fakeAddressRepository) GetUnclassifiedAddresses(ctx context.Context, addresses []string) ([]string, error) {
+	var unclassified []string
+	for _, address := range addresses {
+		if stored, ok := r.addresses[address]; !ok || stored.IsContract == nil {
+			unclassified = append(unclassified, address)
+		}
+	}
+	return unclassified, nil
+}
+
+func (r *fakeAddressRepository) ListUnclassifiedAddresses(ctx context.Context, after string, limit int) ([]string, error) {
+	var addresses []string
+	for address, stored := range r.addresses {
+		if stored.IsContract == nil && address > after {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	if len(addresses) > limit {
+		addresses = addresses[:limit]
+	}
+	return addresses, nil
+}
+
+func (r *fakeAddressRepository) RebuildAddresses(ctx context.Context) (int64, error) {
+	return int64(len(r.addresses)), nil
+}
+
+// fakeCodeBlockchain serves code for the contracts, failing for broken addresses
+type fakeCodeBlockchain struct {
+	service.BlockchainService
+	contracts map[string]bool
+	broken    map[string]bool
+	calls     []string
+}
+
+func (b *fakeCodeBlockchain) GetCode(ctx context.Context, address string, blockNumber *big.Int) ([]byte, error) {
+	b.calls = append(b.calls, address)
+	if b.broken[address] {
+		return nil, errors.New("rpc unavailable")
+	}
+	if b.contracts[address] {
+		return []byte{0x60, 0x80}, nil
+	}
+	return nil, nil
+}
+
+func newAddressTestService(t *testing.T, checkCode bool) (*AddressService, *fakeAddressRepository, *fakeCodeBlockchain) {
+	repo := newFakeAddressRepository()
+	chain := &fakeCodeBlockchain{contracts: map[string]bool{"0xtoken": true}, broken: map[string]bool{}}
+	cfg := &config.Config{Addresses: config.AddressesConfig{Enabled: true, CheckCode: checkCode}}
+	return NewAddressService(repo, chain, cfg, newTestLogger(t)), repo, chain
+}
+
+func addressTransaction(block string, index uint, hash, from string, to, contract *string) *entity.Transaction {
+	return &entity.Transaction{Hash: hash, BlockNumber: block, TransactionIndex: index, From: from, To: to, ContractAddress: contract}
+}
+
+func TestAddressUpdates(t *testing.T) {
+	alice, bob, deployed := "0xalice", "0xbob", "0xdeployed"
+	updates, err := addressUpdates([]*entity.Transaction{
+		addressTransaction("7", 2, "0x72", bob, &alice, nil),
+		addressTransaction("7", 0, "0x70", alice, &bob, nil),
+		addressTransaction("7", 1, "0x71", alice, nil, &deployed),
+	})
+	require.NoError(t, err)
+	require.Len(t, updates, 3)
+
+	no, yes := false, true
+	assert.Equal(t, &repository.AddressUpdate{Address: alice, FirstBlock: 7, FirstTxIndex: 0, FirstTx: "0x70", LastBlock: 7, IsContract: &no}, updates[0])
+	assert.Equal(t, &repository.AddressUpdate{Address: bob, FirstBlock: 7, FirstTxIndex: 0, FirstTx: "0x70", LastBlock: 7, IsContract: &no}, updates[1])
+	assert.Equal(t, &repository.AddressUpdate{Address: deployed, FirstBlock: 7, FirstTxIndex: 1, FirstTx: "0x71", LastBlock: 7, IsContract: &yes}, updates[2])
+
+	_, err = addressUpdates([]*entity.Transaction{addressTransaction("latest", 0, "0x1", alice, nil, nil)})
+	assert.Error(t, err)
+}
+
+func TestAddressServiceRecordBlock(t *testing.T) {
+	svc, repo, chain := newAddressTestService(t, true)
+	ctx := context.Background()
+	alice, token, carol := "0xalice", "0xtoken", "0xcarol"
+
+	require.NoError(t, svc.RecordBlock(ctx, []*entity.Transaction{
+		addressTransaction("10", 0, "0xa", alice, &token, nil),
+		addressTransaction("10", 1, "0xb", alice, &carol, nil),
+	}))
+	assert.ElementsMatch(t, []string{token, carol}, chain.calls, "only recipients are checked")
+	assert.True(t, *repo.addresses[token].IsContract)
+	assert.False(t, *repo.addresses[carol].IsContract)
+	assert.False(t, *repo.addresses[alice].IsContract)
+
+	// Classified addresses aren't checked again, activity moves forward only
+	chain.calls = nil
+	require.NoError(t, svc.RecordBlock(ctx, []*entity.Transaction{
+		addressTransaction("12", 3, "0xc", carol, &token, nil),
+	}))
+	assert.Empty(t, chain.calls)
+	assert.Equal(t, uint64(12), repo.addresses[token].LastActiveBlock)
+	assert.Equal(t, uint64(10), repo.addresses[token].FirstSeenBlock)
+	assert.Equal(t, "0xa", repo.addresses[token].FirstSeenTx)
+
+	// Recording the same block again changes nothing
+	before := *repo.addresses[token]
+	require.NoError(t, svc.RecordBlock(ctx, []*entity.Transaction{
+		addressTransaction("12", 3, "0xc", carol, &token, nil),
+	}))
+	assert.Equal(t, before.FirstSeenTx, repo.addresses[token].FirstSeenTx)
+	assert.Equal(t, before.LastActiveBlock, repo.addresses[token].LastActiveBlock)
+}
+
+func TestAddressServiceRecordBlockCodeFailure(t *testing.T) {
+	svc, repo, chain := newAddressTestService(t, true)
+	ctx := context.Background()
+	dave := "0xdave"
+	chain.broken[dave] = true
+
+	require.NoError(t, svc.RecordBlock(ctx, []*entity.Transaction{
+		addressTransaction("10", 0, "0xa", "0xalice", &dave, nil),
+	}))
+	require.Contains(t, repo.addresses, dave, "recorded unclassified")
+	assert.Nil(t, repo.addresses[dave].IsContract)
+
+	repo.err = errors.New("database unavailable")
+	assert.ErrorContains(t, svc.RecordBlock(ctx, []*entity.Transaction{
+		addressTransaction("11", 0, "0xb", "0xalice", &dave, nil),
+	}), "database unavailable")
+}
+
+func TestAddressServiceWithoutCodeCheck(t *testing.T) {
+	svc, repo, chain := newAddressTestService(t, false)
+	token := "0xtoken"
+
+	require.NoError(t, svc.RecordBlock(context.Background(), []*entity.Transaction{
+		addressTransaction("10", 0, "0xa", "0xalice", &token, nil),
+	}))
+	assert.Empty(t, chain.calls)
+	assert.Nil(t, repo.addresses[token].IsContract)
+}
+
+func TestAddressServiceRebuild(t *testing.T) {
+	svc, repo, chain := newAddressTestService(t, true)
+	no := false
+	repo.addresses["0xalice"] = &entity.Address{Address: "0xalice", IsContract: &no}
+	repo.addresses["0xbroken"] = &entity.Address{Address: "0xbroken"}
+	repo.addresses["0xtoken"] = &entity.Address{Address: "0xtoken"}
+	for i := 0; i < addressClassifyBatchSize+1; i++ {
+		address := fmt.Sprintf("0xeoa%04d", i)
+		repo.addresses[address] = &entity.Address{Address: address}
+	}
+	chain.broken["0xbroken"] = true
+
+	result, err := svc.Rebuild(context.Background(), AddressRebuildRequest{CheckCode: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(addressClassifyBatchSize+4), result.Addresses)
+	assert.Equal(t, int64(addressClassifyBatchSize+2), result.Classified)
+	assert.Equal(t, int64(1), result.Contracts)
+	assert.Equal(t, int64(1), result.Failed)
+	assert.True(t, *repo.addresses["0xtoken"].IsContract)
+	assert.Nil(t, repo.addresses["0xbroken"].IsContract)
+	assert.NotContains(t, chain.calls, "0xalice", "classified addresses aren't checked")
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// BlockSearch selects the block resolved for a timestamp
+type BlockSearch string
+
+const (
+	// BlockAtOrBefore resolves the last block mined at or before the timestamp
+	BlockAtOrBefore BlockSearch = "before"
+	// BlockAtOrAfter resolves the first block mined at or after the timestamp
+	BlockAtOrAfter BlockSearch = "after"
+	// BlockNearest resolves the block mined closest to the timestamp, the
+	// earlier one on a tie
+	BlockNearest BlockSearch = "nearest"
+)
+
+// ErrNoBlockAtTime is returned when no block matches the timestamp, e.g. a
+// time after the latest block searching forward
+var ErrNoBlockAtTime = errors.New("no block at time")
+
+// ParseBlockSearch parses a block search, empty meaning nearest
+func ParseBlockSearch(s string) (BlockSearch, error) {
+	switch search := BlockSearch(s); search {
+	case "":
+		return BlockNearest, nil
+	case BlockAtOrBefore, BlockAtOrAfter, BlockNearest:
+		return search, nil
+	default:
+		return "", fmt.Errorf("invalid block search %q, expected before, after or nearest", s)
+	}
+}
+
+// BlockTimeService resolves timestamps to block numbers
+type BlockTimeService struct {
+	blockRepo         repository.BlockRepository
+	blockchainService service.BlockchainService
+	network           string
+	logger            *logger.Logger
+}
+
+// NewBlockTimeService creates a new block time service
+func NewBlockTimeService(
+	blockRepo repository.BlockRepository,
+	blockchainService service.BlockchainService,
+	config *config.Config,
+	logger *logger.Logger,
+) *BlockTimeService {
+	return &BlockTimeService{
+		blockRepo:         blockRepo,
+		blockchainService: blockchainService,
+		network:           config.Ethereum.Network,
+		logger:            logger.WithComponent("block-time-service"),
+	}
+}
+
+// ResolveBlock returns the block mined at the timestamp per the search. Block
+// timestamps increase with the height, so it binary searches the chain, reading
+// stored blocks and fetching the ones not stored over RPC.
+func (s *BlockTimeService) ResolveBlock(ctx context.Context, at time.Time, search BlockSearch) (*entity.Block, error) {
+	latest, err := s.upperBound(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	// First block mined at or after the timestamp, within [0, latest]
+	var after *entity.Block
+	low, high := uint64(0), latest.number
+	for low <= high {
+		mid := low + (high-low)/2
+		block, err := s.blockAt(ctx, mid)
+		if err != nil {
+			return nil, err
+		}
+		if block.Timestamp.Before(at) {
+			low = mid + 1
+			continue
+		}
+		after = block
+		if mid == 0 {
+			break
+		}
+		high = mid - 1
+	}
+
+	var before *entity.Block
+	switch {
+	case after == nil:
+		before = latest.block
+	case after.Timestamp.Equal(at):
+		return after, nil
+	case low > 0:
+		if before, err = s.blockAt(ctx, low-1); err != nil {
+			return nil, err
+		}
+	}
+
+	switch search {
+	case BlockAtOrBefore:
+		after = nil
+	case BlockAtOrAfter:
+		before = nil
+	case BlockNearest:
+		if before != nil && after != nil {
+			if after.Timestamp.Sub(at) < at.Sub(before.Timestamp) {
+				return after, nil
+			}
+			return before, nil
+		}
+	default:
+		return nil, fmt.Errorf("invalid block search %q", search)
+	}
+
+	if before != nil {
+		return before, nil
+	}
+	if after != nil {
+		return after, nil
+	}
+	return nil, fmt.Errorf("%w %s searching %s", ErrNoBlockAtTime, at.UTC().Format(time.RFC3339), search)
+}
+
+// searchBound is the highest block searched
+type searchBound struct {
+	number uint64
+	block  *entity.Block
+}
+
+// upperBound returns the last processed block, or the latest block of the
+// chain when the timestamp is past it or nothing is stored yet
+func (s *BlockTimeService) upperBound(ctx context.Context, at time.Time) (*searchBound, error) {
+	stored, err := s.blockRepo.GetLastProcessedBlock(ctx, s.network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last processed block: %w", err)
+	}
+	if stored != nil && !stored.Timestamp.Before(at) {
+		number, err := strconv.ParseUint(stored.Number, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block number %q: %w", stored.Number, err)
+		}
+		return &searchBound{number: number, block: stored}, nil
+	}
+
+	latest, err := s.blockchainService.GetLatestBlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest block number: %w", err)
+	}
+	block, err := s.blockAt(ctx, latest.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	return &searchBound{number: latest.Uint64(), block: block}, nil
+}
+
+// blockAt returns a block from storage, falling back to RPC
+func (s *BlockTimeService) blockAt(ctx context.Context, number uint64) (*entity.Block, error) {
+	blockNumber := new(big.Int).SetUint64(number)
+
+	block, err := s.blockRepo.GetBlockByNumber(ctx, blockNumber)
+	if err != nil {
+		s.logger.Warn("Failed to read stored block, fetching it",
+			zap.Uint64("block_number", number),
+			zap.Error(err))
+	}
+	if block != nil {
+		return block, nil
+	}
+
+	block, err = s.blockchainService.GetBlockByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %d: %w", number, err)
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	return block, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (r *fakeBlockRepository) GetLastProcessedBlock(ctx context.Context, network string) (*entity.Block, error) {
+	var (
+		last       *entity.Block
+		lastNumber uint64
+	)
+	for number, block := range r.blocks {
+		if last == nil || number > lastNumber {
+			last, lastNumber = block, number
+		}
+	}
+	return last, nil
+}
+
+// fakeChainBlockchain serves blocks 0 to latest, counting fetches
+type fakeChainBlockchain struct {
+	service.BlockchainService
+	latest  uint64
+	fetches int
+}
+
+func (b *fakeChainBlockchain) GetLatestBlockNumber(ctx context.Context) (*big.Int, error) {
+	return new(big.Int).SetUint64(b.latest), nil
+}
+
+func (b *fakeChainBlockchain) GetBlockByNumber(ctx context.Context, blockNumber *big.Int) (*entity.Block, error) {
+	b.fetches++
+	if blockNumber.Uint64() > b.latest {
+		return nil, fmt.Errorf("block %d not found", blockNumber)
+	}
+	return chainBlock(blockNumber.Uint64()), nil
+}
+
+// chainBlock returns a block mined 12 seconds after its parent
+func chainBlock(number uint64) *entity.Block {
+	return &entity.Block{
+		Number:    fmt.Sprintf("%d", number),
+		Timestamp: chainBlockTime(number),
+		Status:    entity.BlockStatusProcessed,
+	}
+}
+
+func chainBlockTime(number uint64) time.Time {
+	return time.Unix(1_600_000_000+int64(number)*12, 0)
+}
+
+// newBlockTimeTestService stores blocks 0-59 of a chain of 100 blocks
+func newBlockTimeTestService(t *testing.T) (*BlockTimeService, *fakeChainBlockchain) {
+	blocks := map[uint64]*entity.Block{}
+	for number := uint64(0); number < 60; number++ {
+		blocks[number] = chainBlock(number)
+	}
+	chain := &fakeChainBlockchain{latest: 99}
+	cfg := &config.Config{Ethereum: config.EthereumConfig{Network: "mainnet"}}
+	return NewBlockTimeService(&fakeBlockRepository{blocks: blocks}, chain, cfg, newTestLogger(t)), chain
+}
+
+func TestBlockTimeServiceResolvesStoredBlocks(t *testing.T) {
+	svc, chain := newBlockTimeTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		search BlockSearch
+		want   string
+	}{
+		{"exact before", chainBlockTime(30), BlockAtOrBefore, "30"},
+		{"exact after", chainBlockTime(30), BlockAtOrAfter, "30"},
+		{"between before", chainBlockTime(30).Add(5 * time.Second), BlockAtOrBefore, "30"},
+		{"between after", chainBlockTime(30).Add(5 * time.Second), BlockAtOrAfter, "31"},
+		{"nearest earlier", chainBlockTime(30).Add(5 * time.Second), BlockNearest, "30"},
+		{"nearest later", chainBlockTime(30).Add(7 * time.Second), BlockNearest, "31"},
+		{"nearest tie", chainBlockTime(30).Add(6 * time.Second), BlockNearest, "30"},
+		{"genesis", chainBlockTime(0), BlockAtOrBefore, "0"},
+		{"before genesis", chainBlockTime(0).Add(-time.Hour), BlockAtOrAfter, "0"},
+		{"last stored", chainBlockTime(59).Add(-time.Second), BlockAtOrAfter, "59"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, err := svc.ResolveBlock(ctx, tt.at, tt.search)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, block.Number)
+		})
+	}
+	assert.Zero(t, chain.fetches, "stored blocks are not fetched")
+}
+
+func TestBlockTimeServiceFallsBackToRPC(t *testing.T) {
+	svc, chain := newBlockTimeTestService(t)
+	ctx := context.Background()
+
+	block, err := svc.ResolveBlock(ctx, chainBlockTime(80).Add(time.Second), BlockAtOrAfter)
+	require.NoError(t, err)
+	assert.Equal(t, "81", block.Number)
+	assert.NotZero(t, chain.fetches)
+
+	block, err = svc.ResolveBlock(ctx, chainBlockTime(99).Add(time.Hour), BlockAtOrBefore)
+	require.NoError(t, err)
+	assert.Equal(t, "99", block.Number)
+	block, err = svc.ResolveBlock(ctx, chainBlockTime(99).Add(time.Hour), BlockNearest)
+	require.NoError(t, err)
+	assert.Equal(t, "99", block.Number)
+}
+
+func TestBlockTimeServiceNoBlock(t *testing.T) {
+	svc, _ := newBlockTimeTestService(t)
+	ctx := context.Background()
+
+	_, err := svc.ResolveBlock(ctx, chainBlockTime(0).Add(-time.Second), BlockAtOrBefore)
+	assert.ErrorIs(t, err, ErrNoBlockAtTime)
+	_, err = svc.ResolveBlock(ctx, chainBlockTime(99).Add(time.Second), BlockAtOrAfter)
+	assert.ErrorIs(t, err, ErrNoBlockAtTime)
+}
+
+func TestParseBlockSearch(t *testing.T) {
+	search, err := ParseBlockSearch("")
+	require.NoError(t, err)
+	assert.Equal(t, BlockNearest, search)
+	search, err = ParseBlockSearch("before")
+	require.NoError(t, err)
+	assert.Equal(t, BlockAtOrBefore, search)
+	_, err = ParseBlockSearch("closest")
+	assert.Error(t, err)
+}
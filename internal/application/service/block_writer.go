@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// flushTimeout bounds a single flush of the block writer
+const flushTimeout = 2 * time.Minute
+
+// BlockWriter buffers block commits from concurrent crawls and writes them
+// across blocks in bulk, flushing every BatchSize blocks or FlushInterval.
+// Write returns once the flush holding the block is written.
+type BlockWriter struct {
+	commitRepo repository.BlockCommitRepository
+	logger     *logger.Logger
+
+	batchSize     int
+	flushInterval time.Duration
+	bufferSize    int
+
+	// Buffered commits, writers wait when it is full
+	pending chan *pendingBlockCommit
+
+	// Flush statistics
+	statsMu             sync.Mutex
+	flushes             int64
+	failedFlushes       int64
+	blocksWritten       int64
+	transactionsWritten int64
+
+	isRunning bool
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.RWMutex
+}
+
+// pendingBlockCommit is a buffered commit and the channel its result goes to
+type pendingBlockCommit struct {
+	commit *repository.BlockCommit
+	done   chan error
+}
+
+// NewBlockWriter creates a new block writer
+func NewBlockWriter(
+	commitRepo repository.BlockCommitRepository,
+	config *config.Config,
+	logger *logger.Logger,
+) *BlockWriter {
+	w := &BlockWriter{
+		commitRepo:    commitRepo,
+		logger:        logger.WithComponent("block-writer"),
+		batchSize:     10,
+		flushInterval: 5 * time.Second,
+		bufferSize:    100,
+	}
+
+	if config.WebSocket.BatchSize > 0 {
+		w.batchSize = config.WebSocket.BatchSize
+	}
+	if config.WebSocket.FlushInterval > 0 {
+		w.flushInterval = config.WebSocket.FlushInterval
+	}
+	if config.WebSocket.BufferSize > 0 {
+		w.bufferSize = config.WebSocket.BufferSize
+	}
+
+	return w
+}
+
+// Start starts the flush worker
+func (w *BlockWriter) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.isRunning {
+		return fmt.Errorf("block writer is already running")
+	}
+
+	w.pending = make(chan *pendingBlockCommit, w.bufferSize)
+	w.stopChan = make(chan struct{})
+	w.isRunning = true
+
+	// The worker outlives the start context and is stopped through stopChan
+	w.wg.Add(1)
+	go w.flushWorker(context.WithoutCancel(ctx), w.pending, w.stopChan)
+
+	w.logger.Info("Block writer started",
+		zap.Int("batch_size", w.batchSize),
+		zap.Duration("flush_interval", w.flushInterval),
+		zap.Int("buffer_size", w.bufferSize))
+
+	return nil
+}
+
+// Stop flushes the buffered blocks and stops the flush worker
+func (w *BlockWriter) Stop() error {
+	// Waits for writers that are handing over a block
+	w.mu.Lock()
+	if !w.isRunning {
+		w.mu.Unlock()
+		return nil
+	}
+	close(w.stopChan)
+	w.isRunning = false
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	w.logger.Info("Block writer stopped")
+	return nil
+}
+
+// Write buffers a block commit and waits until the flush holding it is
+// written. It blocks while the buffer is full. Blocks are committed directly
+// while the writer isn't running.
+func (w *BlockWriter) Write(ctx context.Context, commit *repository.BlockCommit) error {
+	pending := &pendingBlockCommit{commit: commit, done: make(chan error, 1)}
+
+	w.mu.RLock()
+	if !w.isRunning {
+		w.mu.RUnlock()
+		return w.commitRepo.CommitBlocks(ctx, []*repository.BlockCommit{commit})
+	}
+	select {
+	case w.pending <- pending:
+	case <-ctx.Done():
+		w.mu.RUnlock()
+		return ctx.Err()
+	}
+	w.mu.RUnlock()
+
+	select {
+	case err := <-pending.done:
+		return err
+	case <-ctx.Done():
+		// The block is still written with its flush, commits are idempotent
+		return ctx.Err()
+	}
+}
+
+// GetStats returns block writer statistics
+func (w *BlockWriter) GetStats() map[string]interface{} {
+	w.mu.RLock()
+	running := w.isRunning
+	buffered := len(w.pending)
+	w.mu.RUnlock()
+
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	return map[string]interface{}{
+		"running":              running,
+		"buffered_blocks":      buffered,
+		"flushes":              w.flushes,
+		"failed_flushes":       w.failedFlushes,
+		"blocks_written":       w.blocksWritten,
+		"transactions_written": w.transactionsWritten,
+	}
+}
+
+// flushWorker collects buffered commits and flushes them by size or interval
+func (w *BlockWriter) flushWorker(ctx context.Context, pending chan *pendingBlockCommit, stopChan chan struct{}) {
+	defer w.wg.Done()
+
+	// Add panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Error("Panic recovered in flushWorker",
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*pendingBlockCommit, 0, w.batchSize)
+	for {
+		select {
+		case <-stopChan:
+			// No writer hands over blocks anymore, flush what is left
+			for drained := false; !drained; {
+				select {
+				case p := <-pending:
+					batch = append(batch, p)
+				default:
+					drained = true
+				}
+			}
+			w.flush(ctx, batch)
+			return
+		case p := <-pending:
+			batch = append(batch, p)
+			if len(batch) >= w.batchSize {
+				w.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush writes a batch of blocks and acknowledges every block with its result
+func (w *BlockWriter) flush(ctx context.Context, batch []*pendingBlockCommit) {
+	if len(batch) == 0 {
+		return
+	}
+
+	flushCtx, cancel := context.WithTimeout(ctx, flushTimeout)
+	defer cancel()
+
+	commits := make([]*repository.BlockCommit, len(batch))
+	transactionCount := 0
+	for i, p := range batch {
+		commits[i] = p.commit
+		transactionCount += len(p.commit.Transactions)
+	}
+
+	start := time.Now()
+	err := w.commitRepo.CommitBlocks(flushCtx, commits)
+	if err == nil {
+		for _, p := range batch {
+			p.done <- nil
+		}
+		w.recordFlush(len(batch), transactionCount, false)
+		w.logger.Debug("Flushed blocks",
+			zap.Int("blocks", len(batch)),
+			zap.Int("transactions", transactionCount),
+			zap.Duration("duration", time.Since(start)))
+		return
+	}
+
+	w.recordFlush(0, 0, true)
+	if len(batch) == 1 {
+		batch[0].done <- err
+		return
+	}
+
+	// Commit the blocks one by one so a failing block only fails itself
+	w.logger.Warn("Failed to flush blocks, committing them one by one",
+		zap.Int("blocks", len(batch)),
+		zap.Error(err))
+	for _, p := range batch {
+		err := w.commitRepo.CommitBlocks(flushCtx, []*repository.BlockCommit{p.commit})
+		if err == nil {
+			w.recordFlush(1, len(p.commit.Transactions), false)
+		}
+		p.done <- err
+	}
+}
+
+// recordFlush updates the flush statistics
+func (w *BlockWriter) recordFlush(blocks, transactions int, failed bool) {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	if failed {
+		w.failedFlushes++
+		return
+	}
+	w.flushes++
+	w.blocksWritten += int64(blocks)
+	w.transactionsWritten += int64(transactions)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBlockWriter(t *testing.T, commits repository.BlockCommitRepository, batchSize int, flushInterval time.Duration, bufferSize int) *BlockWriter {
+	cfg := &config.Config{WebSocket: config.WebSocketConfig{
+		BatchSize:     batchSize,
+		FlushInterval: flushInterval,
+		BufferSize:    bufferSize,
+	}}
+	writer := NewBlockWriter(commits, cfg, newTestLogger(t))
+	require.NoError(t, writer.Start(context.Background()))
+	t.Cleanup(func() { writer.Stop() })
+	return writer
+}
+
+func testBlockCommit(number int) *repository.BlockCommit {
+	hash := fmt.Sprintf("0xb%d", number)
+	return &repository.BlockCommit{
+		Block:        &entity.Block{Number: fmt.Sprint(number), Hash: hash},
+		Transactions: []*entity.Transaction{{Hash: fmt.Sprintf("0xt%d", number), BlockHash: hash}},
+		Status:       entity.BlockStatusProcessed,
+	}
+}
+
+// writeBlocks writes the blocks concurrently and returns their results by block
+func writeBlocks(writer *BlockWriter, from, to int) map[int]error {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = map[int]error{}
+	)
+	for n := from; n <= to; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			err := writer.Write(context.Background(), testBlockCommit(n))
+			mu.Lock()
+			results[n] = err
+			mu.Unlock()
+		}(n)
+	}
+	wg.Wait()
+	return results
+}
+
+func TestBlockWriter_FlushesBySize(t *testing.T) {
+	commits := &fakeBlockCommitRepository{}
+	writer := newTestBlockWriter(t, commits, 4, time.Hour, 16)
+
+	results := writeBlocks(writer, 1, 8)
+
+	for n, err := range results {
+		assert.NoError(t, err, "block %d", n)
+	}
+	assert.Equal(t, []int{4, 4}, commits.calls)
+	assert.Len(t, commits.committedHashes(), 8)
+
+	stats := writer.GetStats()
+	assert.Equal(t, int64(2), stats["flushes"])
+	assert.Equal(t, int64(8), stats["blocks_written"])
+	assert.Equal(t, int64(8), stats["transactions_written"])
+}
+
+func TestBlockWriter_FlushesByInterval(t *testing.T) {
+	commits := &fakeBlockCommitRepository{}
+	writer := newTestBlockWriter(t, commits, 100, 20*time.Millisecond, 100)
+
+	start := time.Now()
+	results := writeBlocks(writer, 1, 3)
+
+	for n, err := range results {
+		assert.NoError(t, err, "block %d", n)
+	}
+	assert.Equal(t, 3, sumInts(commits.calls))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestBlockWriter_Backpressure(t *testing.T) {
+	commits := &blockingCommitRepository{
+		fakeBlockCommitRepository: &fakeBlockCommitRepository{},
+		release:                   make(chan struct{}),
+		started:                   make(chan struct{}, 10),
+	}
+	writer := newTestBlockWriter(t, commits, 1, time.Hour, 2)
+
+	// The first block is being flushed, two more fill the buffer
+	done := make(chan int, 10)
+	for n := 1; n <= 4; n++ {
+		go func(n int) {
+			_ = writer.Write(context.Background(), testBlockCommit(n))
+			done <- n
+		}(n)
+	}
+	<-commits.started
+	require.Eventually(t, func() bool { return writer.GetStats()["buffered_blocks"] == 2 }, time.Second, time.Millisecond)
+
+	// Further writers wait for room in the buffer
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, writer.Write(ctx, testBlockCommit(5)), context.DeadlineExceeded)
+	assert.Empty(t, done, "no block is acknowledged before its flush")
+
+	close(commits.release)
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	assert.Len(t, commits.committedHashes(), 4)
+}
+
+func TestBlockWriter_FailedFlushCommitsBlocksOneByOne(t *testing.T) {
+	commits := &fakeBlockCommitRepository{failHash: "0xb2"}
+	writer := newTestBlockWriter(t, commits, 3, time.Hour, 10)
+
+	results := writeBlocks(writer, 1, 3)
+
+	assert.NoError(t, results[1])
+	assert.ErrorContains(t, results[2], "write conflict")
+	assert.NoError(t, results[3])
+	assert.ElementsMatch(t, []string{"0xb1", "0xb3"}, commits.committedHashes())
+	assert.Equal(t, []int{3, 1, 1, 1}, commits.calls)
+}
+
+func TestBlockWriter_StopFlushesBufferedBlocks(t *testing.T) {
+	commits := &fakeBlockCommitRepository{}
+	writer := newTestBlockWriter(t, commits, 100, time.Hour, 100)
+
+	done := make(chan error, 2)
+	for n := 1; n <= 2; n++ {
+		go func(n int) { done <- writer.Write(context.Background(), testBlockCommit(n)) }(n)
+	}
+	time.Sleep(20 * time.Millisecond) // Let both writers hand over their block
+	assert.Empty(t, done, "blocks wait for the next flush")
+
+	require.NoError(t, writer.Stop())
+	assert.NoError(t, <-done)
+	assert.NoError(t, <-done)
+	assert.Len(t, commits.committedHashes(), 2)
+
+	// Stopped writers commit directly
+	require.NoError(t, writer.Write(context.Background(), testBlockCommit(3)))
+	assert.Len(t, commits.committedHashes(), 3)
+}
+
+// blockingCommitRepository holds every flush until release is closed
+type blockingCommitRepository struct {
+	*fakeBlockCommitRepository
+	release chan struct{}
+	started chan struct{}
+}
+
+func (r *blockingCommitRepository) CommitBlocks(ctx context.Context, commits []*repository.BlockCommit) error {
+	r.started <- struct{}{}
+	<-r.release
+	return r.fakeBlockCommitRepository.CommitBlocks(ctx, commits)
+}
+
+func sumInts(values []int) int {
+	total := 0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrCheckpointChanged is returned when the stored checkpoint was changed by
+// someone else, usually an operator rewind. The tracker has been reloaded.
+var ErrCheckpointChanged = errors.New("checkpoint changed externally")
+
+const (
+	// maxBlocksAhead caps the committed blocks held above the watermark. Blocks
+	// committed beyond it are dropped and recorded again by the gap filler.
+	maxBlocksAhead = 100_000
+	// checkpointStallTimeout is how long the watermark may stay put while blocks
+	// above it are committed before the checkpoint reports a stall
+	checkpointStallTimeout = 10 * time.Minute
+)
+
+// CheckpointService maintains the committed watermark of a network. Blocks
+// may be committed in any order; the watermark only advances over a
+// contiguous run of committed blocks.
+type CheckpointService struct {
+	checkpointRepo repository.CheckpointRepository
+	logger         *logger.Logger
+	network        string
+
+	// Fences the checkpoint writes, nil without leader election
+	leaderElection *LeaderElectionService
+
+	mu          sync.Mutex
+	loaded      bool
+	checkpoint  *entity.Checkpoint  // Last persisted checkpoint, nil before the first commit
+	nextBlock   uint64              // First block not yet committed
+	highestSeen uint64              // Highest block committed in any order
+	ahead       map[uint64]struct{} // Committed blocks above nextBlock
+	dropped     int64               // Commits not held because ahead was full
+	advancedAt  time.Time           // When nextBlock last moved
+}
+
+// NewCheckpointService creates a new checkpoint service
+func NewCheckpointService(
+	checkpointRepo repository.CheckpointRepository,
+	config *config.Config,
+	logger *logger.Logger,
+) *CheckpointService {
+	return &CheckpointService{
+		checkpointRepo: checkpointRepo,
+		logger:         logger.WithComponent("checkpoint-service"),
+		network:        config.Ethereum.Network,
+		ahead:          make(map[uint64]struct{}),
+	}
+}
+
+// SetLeaderElection makes the checkpoint only written while this instance
+// leads, tagged with its fencing token so a newer leader's checkpoint can't be
+// overwritten by a stale one
+func (s *CheckpointService) SetLeaderElection(leaderElection *LeaderElectionService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leaderElection = leaderElection
+}
+
+// Load reads the stored checkpoint and returns the block to resume from.
+// defaultNext is used when the network has no checkpoint yet.
+func (s *CheckpointService) Load(ctx context.Context, defaultNext uint64) (uint64, error) {
+	checkpoint, err := s.checkpointRepo.GetCheckpoint(ctx, s.network)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reset(checkpoint, defaultNext)
+	s.loaded = true
+
+	if checkpoint == nil {
+		s.logger.Info("No checkpoint yet, starting from default block",
+			zap.Uint64("next_block", defaultNext))
+	} else {
+		s.logger.Info("Loaded checkpoint",
+			zap.Uint64("committed_block", checkpoint.CommittedBlock),
+			zap.Uint64("highest_seen_block", checkpoint.HighestSeenBlock))
+	}
+
+	return s.nextBlock, nil
+}
+
+// NextBlock returns the first block that is not committed yet
+func (s *CheckpointService) NextBlock() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextBlock
+}
+
+// MarkCommitted records that a block and its transactions are stored and
+// persists the watermark when it advances
+func (s *CheckpointService) MarkCommitted(ctx context.Context, blockNumber uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		return nil
+	}
+
+	if blockNumber > s.highestSeen {
+		s.highestSeen = blockNumber
+	}
+	if blockNumber < s.nextBlock {
+		// Reprocessed block below the watermark
+		return nil
+	}
+
+	if len(s.ahead) >= maxBlocksAhead && blockNumber != s.nextBlock {
+		// The stored block is committed again once the gap below it is filled
+		s.dropped++
+		return nil
+	}
+
+	s.ahead[blockNumber] = struct{}{}
+	advanced := false
+	for {
+		if _, ok := s.ahead[s.nextBlock]; !ok {
+			break
+		}
+		delete(s.ahead, s.nextBlock)
+		s.nextBlock++
+		advanced = true
+	}
+
+	if !advanced {
+		return nil
+	}
+	s.advancedAt = time.Now()
+
+	return s.persist(ctx)
+}
+
+// MissingBlocks returns up to limit blocks from the watermark up to, but not
+// including, upTo that are not committed yet, lowest first
+func (s *CheckpointService) MissingBlocks(upTo uint64, limit int) []uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		return nil
+	}
+
+	var missing []uint64
+	for block := s.nextBlock; block < upTo && len(missing) < limit; block++ {
+		if _, ok := s.ahead[block]; !ok {
+			missing = append(missing, block)
+		}
+	}
+	return missing
+}
+
+// Rewind moves the committed watermark back so crawling resumes at block.
+// A running crawler picks the change up on its next checkpoint write.
+func (s *CheckpointService) Rewind(ctx context.Context, block uint64) (*entity.Checkpoint, error) {
+	if block == 0 {
+		return nil, fmt.Errorf("block must be greater than 0")
+	}
+
+	checkpoint, err := s.checkpointRepo.GetCheckpoint(ctx, s.network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+	if checkpoint == nil {
+		return nil, fmt.Errorf("no checkpoint for network %s", s.network)
+	}
+	if block > checkpoint.CommittedBlock+1 {
+		return nil, fmt.Errorf("block %d is ahead of the committed block %d, a rewind can only move the checkpoint back",
+			block, checkpoint.CommittedBlock)
+	}
+
+	now := time.Now()
+	checkpoint.CommittedBlock = block - 1
+	checkpoint.RewoundAt = &now
+
+	saved, err := s.checkpointRepo.SaveCheckpoint(ctx, checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	if !saved {
+		return nil, fmt.Errorf("checkpoint changed concurrently, try again")
+	}
+
+	s.mu.Lock()
+	if s.loaded {
+		s.reset(checkpoint, block)
+	}
+	s.mu.Unlock()
+
+	s.logger.Warn("Checkpoint rewound", zap.Uint64("next_block", block))
+	return checkpoint, nil
+}
+
+// GetCheckpoint returns the stored checkpoint
+func (s *CheckpointService) GetCheckpoint(ctx context.Context) (*entity.Checkpoint, error) {
+	return s.checkpointRepo.GetCheckpoint(ctx, s.network)
+}
+
+// GetStats returns checkpoint statistics
+func (s *CheckpointService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := map[string]interface{}{
+		"checkpoint_next_block":         s.nextBlock,
+		"checkpoint_highest_seen_block": s.highestSeen,
+		"checkpoint_blocks_ahead":       len(s.ahead),
+		"checkpoint_blocks_dropped":     s.dropped,
+		"checkpoint_stalled":            s.stalled(),
+	}
+	if !s.advancedAt.IsZero() {
+		stats["checkpoint_advanced_at"] = s.advancedAt
+	}
+	if s.checkpoint != nil {
+		stats["checkpoint_committed_block"] = s.checkpoint.CommittedBlock
+	}
+
+	return stats
+}
+
+// HealthCheck reports the checkpoint as degraded while blocks above the
+// watermark are committed but the watermark doesn't advance
+func (s *CheckpointService) HealthCheck(ctx context.Context) entity.ComponentHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := entity.ComponentHealth{
+		Status:      entity.HealthStatusHealthy,
+		Message:     fmt.Sprintf("Next block %d", s.nextBlock),
+		LastChecked: time.Now(),
+	}
+	if s.stalled() {
+		health.Status = entity.HealthStatusDegraded
+		health.Message = fmt.Sprintf("Checkpoint stalled at block %d since %s, %d blocks committed above it",
+			s.nextBlock, s.advancedAt.Format(time.RFC3339), len(s.ahead))
+	}
+
+	return health
+}
+
+// stalled reports whether blocks are held above a watermark that hasn't
+// advanced for checkpointStallTimeout. Must be called with mu held.
+func (s *CheckpointService) stalled() bool {
+	return len(s.ahead) > 0 && time.Since(s.advancedAt) > checkpointStallTimeout
+}
+
+// persist saves the watermark, reloading the stored checkpoint on conflict.
+// Must be called with mu held.
+func (s *CheckpointService) persist(ctx context.Context) error {
+	checkpoint := &entity.Checkpoint{Network: s.network}
+	if s.checkpoint != nil {
+		copied := *s.checkpoint
+		checkpoint = &copied
+	}
+	checkpoint.CommittedBlock = s.nextBlock - 1
+	if s.highestSeen > checkpoint.HighestSeenBlock {
+		checkpoint.HighestSeenBlock = s.highestSeen
+	}
+
+	// Without leader election the stored token is kept
+	if s.leaderElection != nil {
+		if !s.leaderElection.IsLeader() {
+			return fmt.Errorf("not the leader, checkpoint not saved")
+		}
+		if token := s.leaderElection.FencingToken(); token > 0 {
+			checkpoint.FencingToken = token
+		}
+	}
+
+	saved, err := s.checkpointRepo.SaveCheckpoint(ctx, checkpoint)
+	if err != nil {
+		// The in-memory watermark is kept and written with the next commit
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+
+	if !saved {
+		stored, err := s.checkpointRepo.GetCheckpoint(ctx, s.network)
+		if err != nil {
+			return fmt.Errorf("failed to reload checkpoint: %w", err)
+		}
+		if stored != nil && stored.FencingToken > checkpoint.FencingToken {
+			return fmt.Errorf("checkpoint written by a newer leader: %w", repository.ErrStaleFencingToken)
+		}
+		s.reset(stored, s.nextBlock)
+		s.logger.Warn("Checkpoint changed externally, resuming from stored checkpoint",
+			zap.Uint64("next_block", s.nextBlock))
+		return ErrCheckpointChanged
+	}
+
+	s.checkpoint = checkpoint
+	return nil
+}
+
+// reset points the tracker at a checkpoint, or at defaultNext when there is
+// none. Must be called with mu held.
+func (s *CheckpointService) reset(checkpoint *entity.Checkpoint, defaultNext uint64) {
+	s.checkpoint = checkpoint
+	s.ahead = make(map[uint64]struct{})
+	s.advancedAt = time.Now()
+	s.nextBlock = defaultNext
+	s.highestSeen = 0
+	if checkpoint != nil {
+		s.nextBlock = checkpoint.CommittedBlock + 1
+		s.highestSeen = checkpoint.HighestSeenBlock
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryCheckpointRepository mimics the versioned Mongo writes in memory
+type memoryCheckpointRepository struct {
+	checkpoint *entity.Checkpoint
+	saves      int
+}
+
+func (r *memoryCheckpointRepository) GetCheckpoint(ctx context.Context, network string) (*entity.Checkpoint, error) {
+	if r.checkpoint == nil {
+		return nil, nil
+	}
+	copied := *r.checkpoint
+	return &copied, nil
+}
+
+func (r *memoryCheckpointRepository) SaveCheckpoint(ctx context.Context, checkpoint *entity.Checkpoint) (bool, error) {
+	stored := int64(0)
+	if r.checkpoint != nil {
+		stored = r.checkpoint.Version
+		if r.checkpoint.FencingToken > checkpoint.FencingToken {
+			return false, nil
+		}
+	}
+	if stored != checkpoint.Version {
+		return false, nil
+	}
+	checkpoint.Version++
+	copied := *checkpoint
+	r.checkpoint = &copied
+	r.saves++
+	return true, nil
+}
+
+func newTestCheckpointService(t *testing.T, repo *memoryCheckpointRepository) *CheckpointService {
+	cfg := &config.Config{Ethereum: config.EthereumConfig{Network: "ethereum"}}
+	return NewCheckpointService(repo, cfg, newTestLogger(t))
+}
+
+func TestCheckpointService_AdvancesOverContiguousBlocks(t *testing.T) {
+	repo := &memoryCheckpointRepository{}
+	checkpoints := newTestCheckpointService(t, repo)
+	ctx := context.Background()
+
+	next, err := checkpoints.Load(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), next)
+
+	// Out of order: nothing is committed while block 100 is missing
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 102))
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 101))
+	assert.Nil(t, repo.checkpoint)
+	assert.Equal(t, uint64(100), checkpoints.NextBlock())
+
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 100))
+	require.NotNil(t, repo.checkpoint)
+	assert.Equal(t, uint64(102), repo.checkpoint.CommittedBlock)
+	assert.Equal(t, uint64(102), repo.checkpoint.HighestSeenBlock)
+
+	// Block 103 fails, later blocks only move the highest seen block
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 104))
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 105))
+	assert.Equal(t, uint64(102), repo.checkpoint.CommittedBlock)
+	assert.Equal(t, uint64(105), checkpoints.GetStats()["checkpoint_highest_seen_block"])
+
+	// Resume reads the committed watermark, not the highest seen block
+	restarted := newTestCheckpointService(t, repo)
+	next, err = restarted.Load(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(103), next)
+
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 103))
+	assert.Equal(t, uint64(105), repo.checkpoint.CommittedBlock)
+	assert.Equal(t, uint64(105), repo.checkpoint.HighestSeenBlock)
+}
+
+func TestCheckpointService_Rewind(t *testing.T) {
+	repo := &memoryCheckpointRepository{}
+	running := newTestCheckpointService(t, repo)
+	ctx := context.Background()
+
+	_, err := running.Load(ctx, 100)
+	require.NoError(t, err)
+	for block := uint64(100); block < 110; block++ {
+		require.NoError(t, running.MarkCommitted(ctx, block))
+	}
+	assert.Equal(t, uint64(109), repo.checkpoint.CommittedBlock)
+
+	operator := newTestCheckpointService(t, repo)
+	_, err = operator.Rewind(ctx, 200)
+	assert.Error(t, err, "rewind can't move the checkpoint forward")
+
+	checkpoint, err := operator.Rewind(ctx, 105)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(104), checkpoint.CommittedBlock)
+	assert.NotNil(t, checkpoint.RewoundAt)
+
+	// The running crawler notices on its next write and follows the rewind
+	err = running.MarkCommitted(ctx, 110)
+	assert.ErrorIs(t, err, ErrCheckpointChanged)
+	assert.Equal(t, uint64(105), running.NextBlock())
+	assert.Equal(t, uint64(104), repo.checkpoint.CommittedBlock)
+
+	require.NoError(t, running.MarkCommitted(ctx, 105))
+	assert.Equal(t, uint64(105), repo.checkpoint.CommittedBlock)
+}
+
+func TestCheckpointService_MissingBlocksAndStall(t *testing.T) {
+	repo := &memoryCheckpointRepository{}
+	checkpoints := newTestCheckpointService(t, repo)
+	ctx := context.Background()
+
+	_, err := checkpoints.Load(ctx, 100)
+	require.NoError(t, err)
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 101))
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 103))
+
+	assert.Equal(t, []uint64{100, 102, 104}, checkpoints.MissingBlocks(105, 10))
+	assert.Equal(t, []uint64{100, 102}, checkpoints.MissingBlocks(105, 2))
+	assert.Empty(t, checkpoints.MissingBlocks(100, 10))
+
+	assert.Equal(t, entity.HealthStatusHealthy, checkpoints.HealthCheck(ctx).Status)
+	assert.Equal(t, false, checkpoints.GetStats()["checkpoint_stalled"])
+
+	// Blocks are committed above a watermark that hasn't moved for too long
+	checkpoints.mu.Lock()
+	checkpoints.advancedAt = time.Now().Add(-checkpointStallTimeout - time.Minute)
+	checkpoints.mu.Unlock()
+	assert.Equal(t, entity.HealthStatusDegraded, checkpoints.HealthCheck(ctx).Status)
+	assert.Equal(t, true, checkpoints.GetStats()["checkpoint_stalled"])
+
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 100))
+	assert.Equal(t, uint64(102), checkpoints.NextBlock())
+	assert.Equal(t, entity.HealthStatusHealthy, checkpoints.HealthCheck(ctx).Status)
+}
+
+func TestCheckpointService_CapsBlocksAhead(t *testing.T) {
+	repo := &memoryCheckpointRepository{}
+	checkpoints := newTestCheckpointService(t, repo)
+	ctx := context.Background()
+
+	_, err := checkpoints.Load(ctx, 1)
+	require.NoError(t, err)
+	for block := uint64(2); block < maxBlocksAhead+10; block++ {
+		require.NoError(t, checkpoints.MarkCommitted(ctx, block))
+	}
+
+	stats := checkpoints.GetStats()
+	assert.Equal(t, maxBlocksAhead, stats["checkpoint_blocks_ahead"])
+	assert.Equal(t, int64(8), stats["checkpoint_blocks_dropped"])
+	assert.Equal(t, []uint64{1, maxBlocksAhead + 2}, checkpoints.MissingBlocks(maxBlocksAhead+3, 10))
+
+	// The block the watermark waits for is always taken
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 1))
+	assert.Equal(t, uint64(maxBlocksAhead+2), checkpoints.NextBlock())
+	assert.Equal(t, uint64(maxBlocksAhead+1), repo.checkpoint.CommittedBlock)
+}
+
+func TestCheckpointService_FencedByNewerLeader(t *testing.T) {
+	repo := &memoryCheckpointRepository{}
+	ctx := context.Background()
+
+	stale := newTestCheckpointService(t, repo)
+	staleLeader := newTestLeader(t, 1)
+	stale.SetLeaderElection(staleLeader)
+	_, err := stale.Load(ctx, 100)
+	require.NoError(t, err)
+
+	// A new leader took over while the old one was paused past its lease
+	current := newTestCheckpointService(t, repo)
+	current.SetLeaderElection(newTestLeader(t, 2))
+	_, err = current.Load(ctx, 100)
+	require.NoError(t, err)
+	require.NoError(t, current.MarkCommitted(ctx, 100))
+	assert.Equal(t, int64(2), repo.checkpoint.FencingToken)
+
+	// The stale leader can't write, not even after reloading the checkpoint
+	err = stale.MarkCommitted(ctx, 100)
+	assert.ErrorIs(t, err, repository.ErrStaleFencingToken)
+	err = stale.MarkCommitted(ctx, 101)
+	assert.ErrorIs(t, err, repository.ErrStaleFencingToken)
+	assert.Equal(t, uint64(100), repo.checkpoint.CommittedBlock)
+
+	// A leader that knows it lost the lease doesn't try
+	staleLeader.mu.Lock()
+	staleLeader.isLeader = false
+	staleLeader.mu.Unlock()
+	saves := repo.saves
+	assert.Error(t, stale.MarkCommitted(ctx, 102))
+	assert.Equal(t, saves, repo.saves)
+
+	// An operator rewind keeps the token of the current leader
+	checkpoint, err := newTestCheckpointService(t, repo).Rewind(ctx, 90)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), checkpoint.FencingToken)
+}
@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"ethereum-raw-data-crawler/internal/domain/entity"
 	"ethereum-raw-data-crawler/internal/domain/repository"
 	"ethereum-raw-data-crawler/internal/domain/service"
 	"ethereum-raw-data-crawler/internal/infrastructure/config"
 	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"ethereum-raw-data-crawler/internal/infrastructure/throttle"
 	"fmt"
 	"math/big"
 	"runtime"
@@ -17,25 +19,49 @@ import (
 	"go.uber.org/zap"
 )
 
+// uncommittedBlockAge is how long a block may stay pending before it is
+// considered abandoned, twice the block processing timeout
+const uncommittedBlockAge = 10 * time.Minute
+
 // CrawlerService handles the main crawling logic
 type CrawlerService struct {
 	blockchainService service.BlockchainService
 	messagingService  service.MessagingService
 	blockRepo         repository.BlockRepository
 	txRepo            repository.TransactionRepository
+	blockCommitRepo   repository.BlockCommitRepository
 	metricsRepo       repository.MetricsRepository
 	config            *config.Config
 	logger            *logger.Logger
+	throttle          *throttle.Controller // Adaptive worker slots
 
 	// State management
 	isRunning            bool
 	currentBlock         *big.Int
-	workerPool           chan struct{}
+	latestBlock          *big.Int // Chain head seen by the last poll
 	stopChan             chan struct{}
 	wg                   sync.WaitGroup
 	mu                   sync.RWMutex
 	useExternalScheduler bool // Flag to disable internal crawler worker
 
+	// Additional health components reported by performHealthCheck
+	healthComponents map[string]HealthCheckFunc
+
+	// Committed watermark, nil when this instance doesn't own the checkpoint
+	checkpoints *CheckpointService
+
+	// Raw block archive, nil when archiving is disabled
+	archive repository.BlockArchiveRepository
+
+	// Buffered writer committing blocks in bulk, nil to commit every block on its own
+	writer *BlockWriter
+
+	// Records the addresses of every processed block, nil when disabled
+	addresses *AddressService
+
+	// Stores the token transfers of every processed block, nil when disabled
+	transfers *TokenTransferService
+
 	// Metrics
 	metrics *CrawlerMetrics
 
@@ -44,6 +70,9 @@ type CrawlerService struct {
 	lastHealthCheckTime            time.Time
 }
 
+// HealthCheckFunc reports the health of an additional system component
+type HealthCheckFunc func(ctx context.Context) entity.ComponentHealth
+
 // CrawlerMetrics holds runtime metrics
 type CrawlerMetrics struct {
 	BlocksProcessed       uint64
@@ -61,7 +90,9 @@ func NewCrawlerService(
 	messagingService service.MessagingService,
 	blockRepo repository.BlockRepository,
 	txRepo repository.TransactionRepository,
+	blockCommitRepo repository.BlockCommitRepository,
 	metricsRepo repository.MetricsRepository,
+	throttle *throttle.Controller,
 	config *config.Config,
 	logger *logger.Logger,
 ) *CrawlerService {
@@ -70,11 +101,13 @@ func NewCrawlerService(
 		messagingService:  messagingService,
 		blockRepo:         blockRepo,
 		txRepo:            txRepo,
+		blockCommitRepo:   blockCommitRepo,
 		metricsRepo:       metricsRepo,
 		config:            config,
 		logger:            logger.WithComponent("crawler-service"),
-		workerPool:        make(chan struct{}, config.Crawler.ConcurrentWorkers),
+		throttle:          throttle,
 		stopChan:          make(chan struct{}),
+		healthComponents:  make(map[string]HealthCheckFunc),
 		metrics: &CrawlerMetrics{
 			StartTime: time.Now(),
 		},
@@ -98,6 +131,10 @@ func (s *CrawlerService) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to connect to blockchain: %w", err)
 	}
 
+	// Remove blocks left half-written by a previous run
+	s.cleanupUncommittedBlocks(ctx)
+	s.countUncountedTransactions(ctx)
+
 	// Initialize starting block
 	if err := s.initializeStartingBlock(ctx); err != nil {
 		return fmt.Errorf("failed to initialize starting block: %w", err)
@@ -106,16 +143,18 @@ func (s *CrawlerService) Start(ctx context.Context) error {
 	// Start worker routines
 	if s.useExternalScheduler {
 		// Only start metrics and health check workers when using external scheduler
-		s.wg.Add(2)
+		s.wg.Add(3)
 		go s.metricsWorker(ctx)
 		go s.healthCheckWorker(ctx)
+		go s.countSweepWorker(ctx)
 		s.logger.Info("Crawler started in external scheduler mode")
 	} else {
 		// Start all workers including internal crawler worker
-		s.wg.Add(3)
+		s.wg.Add(4)
 		go s.crawlerWorker(ctx)
 		go s.metricsWorker(ctx)
 		go s.healthCheckWorker(ctx)
+		go s.countSweepWorker(ctx)
 		s.logger.Info("Crawler started in internal polling mode")
 	}
 
@@ -222,15 +261,131 @@ func (s *CrawlerService) initializeStartingBlock(ctx context.Context) error {
 			zap.String("start_block", s.currentBlock.String()))
 	}
 
+	// The committed checkpoint takes precedence, the highest processed block
+	// may lie above blocks that failed. It is only used to seed a new checkpoint.
+	if s.checkpoints != nil {
+		nextBlock, err := s.checkpoints.Load(ctx, s.currentBlock.Uint64())
+		if err != nil {
+			return err
+		}
+		s.currentBlock = new(big.Int).SetUint64(nextBlock)
+		s.logger.Info("Resuming from committed checkpoint",
+			zap.String("current_block", s.currentBlock.String()))
+	}
+
 	return nil
 }
 
+// ReloadStartingBlock resumes from the last processed block in the database,
+// picking up progress made by another instance
+func (s *CrawlerService) ReloadStartingBlock(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.initializeStartingBlock(ctx)
+}
+
+// SetCheckpointService makes this instance maintain the committed checkpoint
+// and resume from it
+func (s *CrawlerService) SetCheckpointService(checkpoints *CheckpointService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints = checkpoints
+}
+
+// checkpointService returns the checkpoint service, nil when not set
+func (s *CrawlerService) checkpointService() *CheckpointService {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checkpoints
+}
+
+// SetBlockArchive makes this instance archive the raw block and receipts of
+// every block it processes
+func (s *CrawlerService) SetBlockArchive(archive repository.BlockArchiveRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archive = archive
+}
+
+// blockArchive returns the raw block archive, nil when not set
+func (s *CrawlerService) blockArchive() repository.BlockArchiveRepository {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.archive
+}
+
+// SetBlockWriter makes the crawler commit blocks through a buffered writer
+func (s *CrawlerService) SetBlockWriter(writer *BlockWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writer = writer
+}
+
+// blockWriter returns the buffered block writer, nil when blocks are committed one by one
+func (s *CrawlerService) blockWriter() *BlockWriter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.writer
+}
+
+// SetAddressService makes the crawler record the addresses of every block it processes
+func (s *CrawlerService) SetAddressService(addresses *AddressService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addresses = addresses
+}
+
+// addressService returns the address service, nil when not set
+func (s *CrawlerService) addressService() *AddressService {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.addresses
+}
+
+// SetTokenTransferService makes the crawler store and publish the token
+// transfers of every block it processes
+func (s *CrawlerService) SetTokenTransferService(transfers *TokenTransferService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfers = transfers
+}
+
+// tokenTransferService returns the token transfer service, nil when not set
+func (s *CrawlerService) tokenTransferService() *TokenTransferService {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.transfers
+}
+
+// slotRelease returns a function releasing the current worker slot once, so
+// processBlock can free it early and the caller still releases it on return
+func (s *CrawlerService) slotRelease() func() {
+	var once sync.Once
+	return func() {
+		once.Do(s.throttle.Release)
+	}
+}
+
+// RegisterHealthComponent adds a component to the periodic health check
+func (s *CrawlerService) RegisterHealthComponent(name string, check HealthCheckFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthComponents[name] = check
+}
+
 // crawlerWorker is the main crawler worker routine
 func (s *CrawlerService) crawlerWorker(ctx context.Context) {
 	defer s.wg.Done()
 
-	ticker := time.NewTicker(3 * time.Second) // Process every 3 seconds (reduced from 1s)
-	defer ticker.Stop()
+	pollInterval := s.config.Crawler.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 3 * time.Second
+	}
+
+	// Process the next batch right away while behind the chain head, pacing is
+	// left to the throttle. Poll once caught up or after an error.
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 
 	for {
 		select {
@@ -238,15 +393,26 @@ func (s *CrawlerService) crawlerWorker(ctx context.Context) {
 			return
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-timer.C:
+			wait := pollInterval
 			if err := s.processNextBlocks(ctx); err != nil {
 				s.updateErrorMetrics(err)
 				s.logger.Error("Error processing blocks", zap.Error(err))
+			} else if s.isBehind() {
+				wait = 0
 			}
+			timer.Reset(wait)
 		}
 	}
 }
 
+// isBehind reports whether blocks up to the last seen chain head remain
+func (s *CrawlerService) isBehind() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.latestBlock != nil && s.currentBlock != nil && s.currentBlock.Cmp(s.latestBlock) <= 0
+}
+
 // processNextBlocks processes the next batch of blocks
 func (s *CrawlerService) processNextBlocks(ctx context.Context) error {
 	// Add panic recovery
@@ -279,6 +445,10 @@ func (s *CrawlerService) processNextBlocks(ctx context.Context) error {
 		return fmt.Errorf("received nil latest block")
 	}
 
+	s.mu.Lock()
+	s.latestBlock = latestBlock
+	s.mu.Unlock()
+
 	s.logger.Debug("Checking blocks for processing",
 		zap.String("current_block", s.currentBlock.String()),
 		zap.String("latest_block", latestBlock.String()))
@@ -308,43 +478,31 @@ func (s *CrawlerService) processNextBlocks(ctx context.Context) error {
 
 // processBlockRange processes a range of blocks
 func (s *CrawlerService) processBlockRange(ctx context.Context, startBlock, endBlock *big.Int) error {
-	var wg sync.WaitGroup
-	errChan := make(chan error, s.config.Crawler.ConcurrentWorkers)
+	rangeStart := new(big.Int).Set(startBlock)
 
-	// Add delay between batches to prevent overwhelming API
-	batchDelay := 5 * time.Second // Increased from 2s to 5s
-	blockCount := 0
+	var wg sync.WaitGroup
+	blockCount := new(big.Int).Sub(endBlock, startBlock).Int64() + 1
+	errChan := make(chan error, blockCount)
 
 	for i := new(big.Int).Set(startBlock); i.Cmp(endBlock) <= 0; i.Add(i, big.NewInt(1)) {
-		// Add delay every batch to prevent rate limiting (but not on the first batch)
-		if blockCount > 0 && blockCount%s.config.Crawler.BatchSize == 0 {
-			s.logger.Info("Pausing between batches to avoid rate limiting",
-				zap.Duration("delay", batchDelay),
-				zap.Int("blocks_processed", blockCount))
-			time.Sleep(batchDelay)
+		// Acquire worker slot, the throttle sizes the pool and paces RPC requests
+		if err := s.throttle.Acquire(ctx); err != nil {
+			errChan <- err
+			break
 		}
-
-		// Add small delay between blocks to be gentler on API
-		if blockCount > 0 {
-			time.Sleep(500 * time.Millisecond)
-		}
-
-		// Acquire worker slot
-		s.workerPool <- struct{}{}
 		wg.Add(1)
 
 		go func(blockNum *big.Int) {
+			release := s.slotRelease()
 			defer func() {
 				wg.Done()
-				<-s.workerPool // Release worker slot
+				release() // Release worker slot
 			}()
 
-			if err := s.processBlock(ctx, new(big.Int).Set(blockNum)); err != nil {
+			if err := s.processBlock(ctx, new(big.Int).Set(blockNum), release); err != nil {
 				errChan <- err
 			}
 		}(new(big.Int).Set(i))
-
-		blockCount++
 	}
 
 	// Wait for all workers to complete
@@ -361,9 +519,11 @@ func (s *CrawlerService) processBlockRange(ctx context.Context, startBlock, endB
 		return fmt.Errorf("errors processing block range: %v", errors)
 	}
 
-	// Update current block
+	// Update current block, unless a checkpoint rewind moved it meanwhile
 	s.mu.Lock()
-	s.currentBlock.Add(endBlock, big.NewInt(1))
+	if s.currentBlock.Cmp(rangeStart) == 0 {
+		s.currentBlock.Add(endBlock, big.NewInt(1))
+	}
 	s.mu.Unlock()
 
 	return nil
@@ -390,20 +550,102 @@ func (s *CrawlerService) ProcessSpecificBlock(ctx context.Context, blockNumber *
 	}
 
 	// Validate dependencies
-	if s.workerPool == nil {
-		return fmt.Errorf("workerPool is nil")
+	if s.throttle == nil {
+		return fmt.Errorf("throttle is nil")
 	}
 
 	s.logger.Info("Processing specific block from scheduler",
 		zap.String("block_number", blockNumber.String()))
 
 	// Acquire worker slot
-	s.workerPool <- struct{}{}
-	defer func() {
-		<-s.workerPool // Release worker slot
-	}()
+	if err := s.throttle.Acquire(ctx); err != nil {
+		return err
+	}
+	release := s.slotRelease()
+	defer release() // Release worker slot
+
+	return s.processBlock(ctx, blockNumber, release)
+}
+
+// ProcessPriorityBlock processes a block requested on demand. It takes the
+// next free worker slot ahead of routine work.
+func (s *CrawlerService) ProcessPriorityBlock(ctx context.Context, blockNumber *big.Int) error {
+	if blockNumber == nil {
+		return fmt.Errorf("blockNumber is nil")
+	}
+	if !s.IsRunning() {
+		return fmt.Errorf("crawler service is not running")
+	}
+
+	s.logger.Info("Processing priority block",
+		zap.String("block_number", blockNumber.String()))
+
+	if err := s.throttle.AcquirePriority(ctx); err != nil {
+		return err
+	}
+	release := s.slotRelease()
+	defer release()
+
+	return s.processBlock(ctx, blockNumber, release)
+}
+
+// RefetchTransaction fetches a transaction and its receipt again, overwrites
+// the stored copy and republishes it. It runs ahead of routine work.
+func (s *CrawlerService) RefetchTransaction(ctx context.Context, txHash string) (*entity.Transaction, error) {
+	if !s.IsRunning() {
+		return nil, fmt.Errorf("crawler service is not running")
+	}
+
+	logger := s.logger.WithTransaction(txHash)
+	logger.Info("Refetching transaction")
+
+	if err := s.throttle.AcquirePriority(ctx); err != nil {
+		return nil, err
+	}
+	defer s.throttle.Release()
 
-	return s.processBlock(ctx, blockNumber)
+	txCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	tx, err := s.blockchainService.GetTransactionReceipt(txCtx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", txHash, err)
+	}
+
+	// Receipts don't carry the block time
+	if tx.BlockTimestamp, err = s.getBlockTimestamp(txCtx, tx.BlockHash); err != nil {
+		return nil, fmt.Errorf("failed to get block of transaction %s: %w", txHash, err)
+	}
+
+	// Always upsert, the stored copy is replaced whatever the insert mode
+	transactions := []*entity.Transaction{tx}
+	if err := s.txRepo.UpsertTransactions(txCtx, transactions); err != nil {
+		return nil, fmt.Errorf("failed to save transaction %s: %w", txHash, err)
+	}
+
+	if err := s.publishTransactions(txCtx, transactions, logger); err != nil {
+		logger.Warn("Failed to publish refetched transaction", zap.Error(err))
+	}
+
+	logger.Info("Transaction refetched", zap.String("block_number", tx.BlockNumber))
+	return tx, nil
+}
+
+// getBlockTimestamp returns the time of a block, from the database when it is stored
+func (s *CrawlerService) getBlockTimestamp(ctx context.Context, blockHash string) (time.Time, error) {
+	block, err := s.blockRepo.GetBlockByHash(ctx, blockHash)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if block == nil {
+		if block, err = s.blockchainService.GetBlockByHash(ctx, blockHash); err != nil {
+			return time.Time{}, err
+		}
+		if block == nil {
+			return time.Time{}, fmt.Errorf("block %s not found", blockHash)
+		}
+	}
+	return block.Timestamp, nil
 }
 
 // SetExternalSchedulerMode sets whether to use external scheduler
@@ -413,8 +655,9 @@ func (s *CrawlerService) SetExternalSchedulerMode(useExternal bool) {
 	s.useExternalScheduler = useExternal
 }
 
-// processBlock processes a single block
-func (s *CrawlerService) processBlock(ctx context.Context, blockNumber *big.Int) error {
+// processBlock processes a single block. release frees the caller's worker
+// slot, it is called once the block waits for a buffered write.
+func (s *CrawlerService) processBlock(ctx context.Context, blockNumber *big.Int, release func()) error {
 	logger := s.logger.WithBlock(blockNumber.Uint64())
 	logger.Info("Starting to process block", zap.Uint64("block_number", blockNumber.Uint64()))
 
@@ -429,33 +672,6 @@ func (s *CrawlerService) processBlock(ctx context.Context, blockNumber *big.Int)
 		return fmt.Errorf("failed to get block %s: %w", blockNumber.String(), err)
 	}
 
-	// Check if block already exists by number
-	existingBlock, err := s.blockRepo.GetBlockByNumber(blockCtx, blockNumber)
-	if err != nil {
-		logger.Error("Failed to check if block exists", zap.Error(err))
-		return fmt.Errorf("failed to check block existence %s: %w", blockNumber.String(), err)
-	}
-
-	if existingBlock != nil {
-		logger.Info("Block already exists, skipping block save",
-			zap.String("block_hash", block.Hash),
-			zap.String("existing_hash", existingBlock.Hash))
-	} else {
-		// Save block to database
-		if err := s.blockRepo.CreateBlock(blockCtx, block); err != nil {
-			// Check if it's a duplicate key error (race condition)
-			if strings.Contains(err.Error(), "E11000") || strings.Contains(err.Error(), "duplicate key") {
-				logger.Warn("Block already exists (race condition), continuing",
-					zap.String("block_hash", block.Hash))
-			} else {
-				logger.Error("Failed to save block", zap.Error(err))
-				return fmt.Errorf("failed to save block %s: %w", blockNumber.String(), err)
-			}
-		} else {
-			logger.Info("Block saved to database")
-		}
-	}
-
 	// Get all transactions for this block
 	logger.Info("Getting transactions for block", zap.Int("tx_hash_count", len(block.TransactionHashes)))
 	transactions, err := s.blockchainService.GetTransactionsByBlock(blockCtx, blockNumber)
@@ -465,19 +681,77 @@ func (s *CrawlerService) processBlock(ctx context.Context, blockNumber *big.Int)
 	}
 	logger.Info("Retrieved transactions", zap.Int("count", len(transactions)))
 
-	// Save transactions to database
-	if len(transactions) > 0 {
-		if err := s.saveTransactions(blockCtx, transactions, logger); err != nil {
-			return fmt.Errorf("failed to save transactions for block %s: %w", blockNumber.String(), err)
+	// Archive the raw block, a failure leaves the block to be retried
+	if archive := s.blockArchive(); archive != nil {
+		if err := s.archiveBlock(blockCtx, archive, block, logger); err != nil {
+			logger.Error("Failed to archive block", zap.Error(err))
+			return fmt.Errorf("failed to archive block %s: %w", blockNumber.String(), err)
+		}
+	}
+
+	// Processed, or incomplete until the missing receipts are repaired
+	status := entity.BlockStatusProcessed
+	if missing := countMissingReceipts(transactions); missing > 0 {
+		logger.Warn("Block has missing receipts, committing as incomplete",
+			zap.Int("missing_receipts", missing))
+		status = entity.BlockStatusIncomplete
+	}
+
+	// Commit the block, its transactions and its status together
+	if writer := s.blockWriter(); writer != nil {
+		// The block waits for the next flush, it doesn't need the worker slot meanwhile
+		release()
+		err = writer.Write(blockCtx, &repository.BlockCommit{Block: block, Transactions: transactions, Status: status})
+	} else {
+		err = s.blockCommitRepo.CommitBlock(blockCtx, block, transactions, status)
+	}
+	if err != nil {
+		logger.Error("Failed to commit block", zap.Error(err))
+		return fmt.Errorf("failed to commit block %s: %w", blockNumber.String(), err)
+	}
+	logger.Info("Block committed to database",
+		zap.Int("transaction_count", len(transactions)),
+		zap.String("status", string(status)))
+
+	// Record the addresses, a failure leaves the block to be retried. Recording
+	// is idempotent, like the commit.
+	if addresses := s.addressService(); addresses != nil {
+		if err := addresses.RecordBlock(blockCtx, transactions); err != nil {
+			logger.Error("Failed to record addresses", zap.Error(err))
+			return fmt.Errorf("failed to record addresses of block %s: %w", blockNumber.String(), err)
 		}
-		logger.Info("Transactions saved to database", zap.Int("count", len(transactions)))
 	}
 
-	// Mark block as processed
-	if err := s.blockRepo.MarkBlockAsProcessed(ctx, block.Hash); err != nil {
-		logger.Error("Failed to mark block as processed", zap.Error(err))
+	// Store the token transfers, a failure leaves the block to be retried
+	var transfers []*entity.TokenTransfer
+	transferService := s.tokenTransferService()
+	if transferService != nil {
+		if transfers, err = transferService.RecordBlock(blockCtx, transactions); err != nil {
+			logger.Error("Failed to record token transfers", zap.Error(err))
+			return fmt.Errorf("failed to record token transfers of block %s: %w", blockNumber.String(), err)
+		}
 	}
 
+	// Publish transactions to NATS JetStream ONLY AFTER the commit
+	if len(transactions) > 0 {
+		if err := s.publishTransactions(blockCtx, transactions, logger); err != nil {
+			// Messaging failure shouldn't block the crawler
+			logger.Warn("Failed to publish transactions to messaging service after commit",
+				zap.Error(err),
+				zap.Int("transaction_count", len(transactions)))
+		}
+	}
+	if len(transfers) > 0 {
+		if err := transferService.Publish(blockCtx, transfers); err != nil {
+			logger.Warn("Failed to publish token transfers after commit",
+				zap.Error(err),
+				zap.Int("transfer_count", len(transfers)))
+		}
+	}
+
+	// Advance the committed watermark
+	s.commitCheckpoint(ctx, blockNumber, logger)
+
 	// Update metrics
 	s.updateProcessingMetrics(block, transactions)
 
@@ -488,129 +762,126 @@ func (s *CrawlerService) processBlock(ctx context.Context, blockNumber *big.Int)
 	return nil
 }
 
-// saveTransactions saves transactions using configured method (upsert or insert)
-func (s *CrawlerService) saveTransactions(ctx context.Context, transactions []*entity.Transaction, logger *logger.Logger) error {
-	start := time.Now()
-	txCount := len(transactions)
-
-	var dbSaveSuccessful bool
-	var dbSaveError error
-
-	if s.config.Crawler.UseUpsert {
-		// Try upsert first
-		logger.Debug("Attempting batch upsert", zap.Int("transaction_count", txCount))
-
-		if err := s.txRepo.UpsertTransactions(ctx, transactions); err != nil {
-			duration := time.Since(start)
-
-			// Check if this is a duplicate key error which means data already exists
-			if strings.Contains(err.Error(), "E11000") || strings.Contains(err.Error(), "duplicate key") {
-				logger.Warn("Transactions already exist in database (upsert), considering as success",
-					zap.Int("transaction_count", txCount),
-					zap.Duration("duration", duration))
-				dbSaveSuccessful = true // Consider this as success since data is already there
-			} else {
-				logger.Warn("Batch upsert failed",
-					zap.Error(err),
-					zap.Int("transaction_count", txCount),
-					zap.Duration("duration", duration))
-
-				// Fallback to insert if configured
-				if s.config.Crawler.UpsertFallback {
-					logger.Info("Falling back to batch insert", zap.Int("transaction_count", txCount))
-					fallbackStart := time.Now()
-
-					if insertErr := s.txRepo.CreateTransactions(ctx, transactions); insertErr != nil {
-						fallbackDuration := time.Since(fallbackStart)
-
-						// Check if this is a duplicate key error which means data already exists
-						if strings.Contains(insertErr.Error(), "E11000") || strings.Contains(insertErr.Error(), "duplicate key") {
-							logger.Warn("Transactions already exist in database, considering as success",
-								zap.Int("transaction_count", txCount),
-								zap.Duration("fallback_duration", fallbackDuration),
-								zap.Duration("total_duration", time.Since(start)))
-							dbSaveSuccessful = true // Consider this as success since data is already there
-						} else {
-							logger.Error("Fallback batch insert also failed",
-								zap.Error(insertErr),
-								zap.Int("transaction_count", txCount),
-								zap.Duration("fallback_duration", fallbackDuration),
-								zap.Duration("total_duration", time.Since(start)))
-							dbSaveError = fmt.Errorf("both upsert and insert failed: upsert=%w, insert=%w", err, insertErr)
-							dbSaveSuccessful = false
-						}
-					} else {
-						fallbackDuration := time.Since(fallbackStart)
-						logger.Info("Fallback batch insert succeeded",
-							zap.Int("transaction_count", txCount),
-							zap.Duration("fallback_duration", fallbackDuration),
-							zap.Duration("total_duration", time.Since(start)))
-						dbSaveSuccessful = true
-					}
-				} else {
-					dbSaveError = err
-					dbSaveSuccessful = false
-				}
-			}
-		} else {
-			duration := time.Since(start)
-			logger.Debug("Batch upsert succeeded",
-				zap.Int("transaction_count", txCount),
-				zap.Duration("duration", duration))
-			dbSaveSuccessful = true
-		}
-	} else {
-		// Use traditional insert
-		logger.Debug("Attempting batch insert", zap.Int("transaction_count", txCount))
-
-		if err := s.txRepo.CreateTransactions(ctx, transactions); err != nil {
-			duration := time.Since(start)
-
-			// Check if this is a duplicate key error which means data already exists
-			if strings.Contains(err.Error(), "E11000") || strings.Contains(err.Error(), "duplicate key") {
-				logger.Warn("Transactions already exist in database, considering as success",
-					zap.Int("transaction_count", txCount),
-					zap.Duration("duration", duration))
-				dbSaveSuccessful = true // Consider this as success since data is already there
-			} else {
-				logger.Error("Batch insert failed",
-					zap.Error(err),
-					zap.Int("transaction_count", txCount),
-					zap.Duration("duration", duration))
-				dbSaveError = err
-				dbSaveSuccessful = false
-			}
-		} else {
-			duration := time.Since(start)
-			logger.Debug("Batch insert succeeded",
-				zap.Int("transaction_count", txCount),
-				zap.Duration("duration", duration))
-			dbSaveSuccessful = true
-		}
+// archiveBlock stores the canonical encoding of a block and its receipts,
+// unless the block is archived already
+func (s *CrawlerService) archiveBlock(ctx context.Context, archive repository.BlockArchiveRepository, block *entity.Block, logger *logger.Logger) error {
+	exists, err := archive.RawBlockExists(ctx, s.config.Ethereum.Network, block.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to check archive: %w", err)
+	}
+	if exists {
+		return nil
 	}
 
-	// Publish transactions to NATS JetStream ONLY AFTER successful DB save
-	if dbSaveSuccessful {
-		if err := s.publishTransactions(ctx, transactions, logger); err != nil {
-			logger.Warn("Failed to publish transactions to messaging service after successful DB save",
-				zap.Error(err),
-				zap.Int("transaction_count", txCount))
-			// Don't return error here since DB save was successful
-			// Messaging failure shouldn't block the crawler
-		} else {
-			logger.Debug("Successfully published transactions to messaging service after DB save",
-				zap.Int("transaction_count", txCount))
-		}
-	} else {
-		logger.Debug("Skipping transaction publishing due to DB save failure",
-			zap.Int("transaction_count", txCount))
+	blockNumber, ok := new(big.Int).SetString(block.Number, 10)
+	if !ok {
+		return fmt.Errorf("invalid block number %q", block.Number)
 	}
 
-	// Return the DB save result
-	return dbSaveError
+	raw, err := s.blockchainService.GetRawBlock(ctx, blockNumber)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(raw.Hash, block.Hash) {
+		// Reorged between the requests, the archive keeps both by hash
+		logger.Warn("Archived block differs from the processed block",
+			zap.String("block_hash", block.Hash),
+			zap.String("archived_hash", raw.Hash))
+	}
+
+	if err := archive.SaveRawBlock(ctx, raw); err != nil {
+		return err
+	}
+
+	logger.Debug("Block archived",
+		zap.Int("block_bytes", len(raw.Block)),
+		zap.Int("receipt_count", len(raw.Receipts)))
+	return nil
 }
 
-// publishTransactions publishes transactions to messaging service
+// cleanupUncommittedBlocks deletes pending blocks older than a block's
+// processing timeout, left behind when a previous run stopped mid-commit
+func (s *CrawlerService) cleanupUncommittedBlocks(ctx context.Context) {
+	if s.blockCommitRepo == nil {
+		return
+	}
+
+	atomic, err := s.blockCommitRepo.IsAtomic(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to check MongoDB transaction support", zap.Error(err))
+		return
+	}
+	s.logger.Info("Block commit mode", zap.Bool("atomic", atomic))
+
+	before := time.Now().Add(-uncommittedBlockAge)
+	deleted, err := s.blockCommitRepo.CleanupUncommittedBlocks(ctx, s.config.Ethereum.Network, before)
+	if err != nil {
+		s.logger.Warn("Failed to clean up uncommitted blocks", zap.Error(err))
+	}
+	if len(deleted) > 0 {
+		s.logger.Warn("Deleted uncommitted blocks, they are crawled again",
+			zap.Uint64s("block_numbers", deleted))
+	}
+}
+
+// countUncountedTransactions adds the transactions stored longer than a
+// block's processing timeout ago but never counted, left behind when a write
+// stopped before counting them, to the address counters
+func (s *CrawlerService) countUncountedTransactions(ctx context.Context) {
+	if s.blockCommitRepo == nil {
+		return
+	}
+
+	before := time.Now().Add(-uncommittedBlockAge)
+	counted, err := s.blockCommitRepo.CountUncountedTransactions(ctx, s.config.Ethereum.Network, before)
+	if err != nil {
+		s.logger.Warn("Failed to count uncounted transactions", zap.Error(err))
+	}
+	if counted > 0 {
+		s.logger.Warn("Counted transactions left uncounted by an interrupted write",
+			zap.Int64("transactions", counted))
+	}
+}
+
+// commitCheckpoint records a stored block in the checkpoint
+func (s *CrawlerService) commitCheckpoint(ctx context.Context, blockNumber *big.Int, logger *logger.Logger) {
+	checkpoints := s.checkpointService()
+	if checkpoints == nil {
+		return
+	}
+
+	err := checkpoints.MarkCommitted(ctx, blockNumber.Uint64())
+	switch {
+	case errors.Is(err, ErrCheckpointChanged):
+		// An operator rewound the checkpoint, continue polling from there
+		s.mu.Lock()
+		s.currentBlock.SetUint64(checkpoints.NextBlock())
+		s.mu.Unlock()
+		logger.Warn("Checkpoint changed externally, moved current block",
+			zap.Uint64("next_block", checkpoints.NextBlock()))
+	case err != nil:
+		logger.Warn("Failed to update checkpoint", zap.Error(err))
+	}
+}
+
+// commitStoredBlock records a block that is already stored, for instance by a
+// work worker, in the checkpoint without crawling it again. Returns false when
+// the block isn't stored or its commit didn't finish.
+func (s *CrawlerService) commitStoredBlock(ctx context.Context, blockNumber *big.Int) (bool, error) {
+	block, err := s.blockRepo.GetBlockByNumber(ctx, blockNumber)
+	if err != nil {
+		return false, fmt.Errorf("failed to get block %s: %w", blockNumber.String(), err)
+	}
+	if block == nil || (block.Status != entity.BlockStatusProcessed && block.Status != entity.BlockStatusIncomplete) {
+		return false, nil
+	}
+
+	s.commitCheckpoint(ctx, blockNumber, s.logger.WithBlock(blockNumber.Uint64()))
+	return true, nil
+}
+
+// publishTransactions publishes transactions to messaging service. Transactions
+// without a receipt are published once the receipt repair worker fetched it.
 func (s *CrawlerService) publishTransactions(ctx context.Context, transactions []*entity.Transaction, logger *logger.Logger) error {
 	if s.messagingService == nil {
 		logger.Debug("Messaging service not available, skipping transaction publishing")
@@ -622,6 +893,19 @@ func (s *CrawlerService) publishTransactions(ctx context.Context, transactions [
 		return nil
 	}
 
+	if countMissingReceipts(transactions) > 0 {
+		complete := make([]*entity.Transaction, 0, len(transactions))
+		for _, tx := range transactions {
+			if !tx.ReceiptMissing {
+				complete = append(complete, tx)
+			}
+		}
+		transactions = complete
+		if len(transactions) == 0 {
+			return nil
+		}
+	}
+
 	start := time.Now()
 	err := s.messagingService.PublishTransactions(ctx, transactions)
 	duration := time.Since(start)
@@ -641,6 +925,17 @@ func (s *CrawlerService) publishTransactions(ctx context.Context, transactions [
 	return nil
 }
 
+// countMissingReceipts counts the transactions stored without a receipt
+func countMissingReceipts(transactions []*entity.Transaction) int {
+	missing := 0
+	for _, tx := range transactions {
+		if tx.ReceiptMissing {
+			missing++
+		}
+	}
+	return missing
+}
+
 // metricsWorker periodically saves metrics to database
 func (s *CrawlerService) metricsWorker(ctx context.Context) {
 	defer s.wg.Done()
@@ -683,6 +978,26 @@ func (s *CrawlerService) healthCheckWorker(ctx context.Context) {
 	}
 }
 
+// countSweepWorker periodically counts the transactions left uncounted by
+// interrupted writes
+func (s *CrawlerService) countSweepWorker(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(uncommittedBlockAge)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.countUncountedTransactions(ctx)
+		}
+	}
+}
+
 // saveMetrics saves current metrics to database
 func (s *CrawlerService) saveMetrics(ctx context.Context) error {
 	metrics := s.GetMetrics()
@@ -705,18 +1020,32 @@ func (s *CrawlerService) saveMetrics(ctx context.Context) error {
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
 
+	throttleStats := s.throttle.GetStats()
+
+	incompleteBlocks, err := s.blockRepo.GetBlockCountByStatus(ctx, entity.BlockStatusIncomplete, s.config.Ethereum.Network)
+	if err != nil {
+		s.logger.Warn("Failed to count incomplete blocks", zap.Error(err))
+	}
+
 	metricsEntity := &entity.CrawlerMetrics{
 		Timestamp:             time.Now(),
 		LastProcessedBlock:    metrics.LastProcessedBlock,
 		CurrentBlock:          latestBlock.Uint64(),
 		BlocksProcessed:       metrics.BlocksProcessed,
 		BlocksPerSecond:       blocksPerSecond,
+		IncompleteBlocks:      incompleteBlocks,
 		TransactionsProcessed: metrics.TransactionsProcessed,
 		TransactionsPerSecond: transactionsPerSecond,
 		ErrorCount:            metrics.ErrorCount,
 		LastErrorMessage:      metrics.LastErrorMessage,
 		MemoryUsage:           memStats.Alloc,
 		GoroutineCount:        runtime.NumGoroutine(),
+		NetworkLatency:        throttleStats.AverageLatency,
+		RPCCallsCount:         throttleStats.RPCCalls,
+		RPCErrorRate:          throttleStats.ErrorRate,
+		RPCRateLimitedCount:   throttleStats.RateLimitedCalls,
+		ConcurrentWorkers:     throttleStats.Workers,
+		RequestDelay:          throttleStats.RequestDelay,
 		Network:               s.config.Ethereum.Network,
 	}
 
@@ -813,6 +1142,30 @@ func (s *CrawlerService) performHealthCheck(ctx context.Context) error {
 		ResponseTime: messagingLatency,
 	}
 
+	// Check registered components
+	s.mu.RLock()
+	healthComponents := make(map[string]HealthCheckFunc, len(s.healthComponents))
+	for name, check := range s.healthComponents {
+		healthComponents[name] = check
+	}
+	s.mu.RUnlock()
+
+	for name, check := range healthComponents {
+		componentHealth := check(ctx)
+		componentsHealth[name] = componentHealth
+
+		switch componentHealth.Status {
+		case entity.HealthStatusUnhealthy:
+			overallStatus = entity.HealthStatusUnhealthy
+			messages = append(messages, componentHealth.Message)
+		case entity.HealthStatusDegraded:
+			if overallStatus == entity.HealthStatusHealthy {
+				overallStatus = entity.HealthStatusDegraded
+			}
+			messages = append(messages, componentHealth.Message)
+		}
+	}
+
 	// Determine overall message
 	overallMessage := "All systems operational"
 	if len(messages) > 0 {
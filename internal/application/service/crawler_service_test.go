@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCommitBlockchain serves a single block and its transactions
+type fakeCommitBlockchain struct {
+	service.BlockchainService
+	block        *entity.Block
+	transactions []*entity.Transaction
+}
+
+func (b *fakeCommitBlockchain) GetBlockByNumber(ctx context.Context, blockNumber *big.Int) (*entity.Block, error) {
+	return b.block, nil
+}
+
+func (b *fakeCommitBlockchain) GetTransactionsByBlock(ctx context.Context, blockNumber *big.Int) ([]*entity.Transaction, error) {
+	return b.transactions, nil
+}
+
+type blockCommit struct {
+	block        *entity.Block
+	transactions []*entity.Transaction
+	status       entity.BlockStatus
+}
+
+// fakeBlockCommitRepository records commits and cleanups
+type fakeBlockCommitRepository struct {
+	mu        sync.Mutex
+	commits   []blockCommit
+	calls     []int  // Blocks per CommitBlocks call
+	err       error  // Fails every commit
+	failHash  string // Fails commits holding the block
+	pending   []uint64
+	cleanedAt time.Time
+	uncounted int64 // Transactions the count sweep finds
+	sweptAt   time.Time
+}
+
+func (r *fakeBlockCommitRepository) CommitBlock(ctx context.Context, block *entity.Block, transactions []*entity.Transaction, status entity.BlockStatus) error {
+	return r.CommitBlocks(ctx, []*repository.BlockCommit{{Block: block, Transactions: transactions, Status: status}})
+}
+
+func (r *fakeBlockCommitRepository) CommitBlocks(ctx context.Context, commits []*repository.BlockCommit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, len(commits))
+	for _, commit := range commits {
+		if r.failHash != "" && commit.Block.Hash == r.failHash {
+			return errors.New("write conflict")
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	for _, commit := range commits {
+		r.commits = append(r.commits, blockCommit{block: commit.Block, transactions: commit.Transactions, status: commit.Status})
+	}
+	return nil
+}
+
+func (r *fakeBlockCommitRepository) committedHashes() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hashes := make([]string, 0, len(r.commits))
+	for _, commit := range r.commits {
+		hashes = append(hashes, commit.block.Hash)
+	}
+	return hashes
+}
+
+func (r *fakeBlockCommitRepository) IsAtomic(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+func (r *fakeBlockCommitRepository) CleanupUncommittedBlocks(ctx context.Context, network string, before time.Time) ([]uint64, error) {
+	r.cleanedAt = before
+	deleted := r.pending
+	r.pending = nil
+	return deleted, nil
+}
+
+func (r *fakeBlockCommitRepository) CountUncountedTransactions(ctx context.Context, network string, before time.Time) (int64, error) {
+	r.sweptAt = before
+	counted := r.uncounted
+	r.uncounted = 0
+	return counted, nil
+}
+
+func newTestCommitCrawler(t *testing.T, transactions []*entity.Transaction) (*CrawlerService, *fakeBlockCommitRepository, *fakeMessagingService) {
+	blockchain := &fakeCommitBlockchain{
+		block:        &entity.Block{Number: "100", Hash: "0xb100"},
+		transactions: transactions,
+	}
+	commits := &fakeBlockCommitRepository{}
+	messaging := &fakeMessagingService{}
+
+	cfg := &config.Config{Ethereum: config.EthereumConfig{Network: "ethereum"}}
+	crawler := NewCrawlerService(blockchain, messaging, nil, nil, commits, nil, nil, cfg, newTestLogger(t))
+	return crawler, commits, messaging
+}
+
+func TestCrawlerService_ProcessBlockCommits(t *testing.T) {
+	transactions := []*entity.Transaction{
+		{Hash: "0x1", BlockHash: "0xb100"},
+		{Hash: "0x2", BlockHash: "0xb100", ReceiptMissing: true},
+	}
+	crawler, commits, messaging := newTestCommitCrawler(t, transactions)
+
+	require.NoError(t, crawler.processBlock(context.Background(), big.NewInt(100), func() {}))
+
+	require.Len(t, commits.commits, 1)
+	assert.Equal(t, "0xb100", commits.commits[0].block.Hash)
+	assert.Len(t, commits.commits[0].transactions, 2)
+	assert.Equal(t, entity.BlockStatusIncomplete, commits.commits[0].status)
+
+	// Only transactions with a receipt are published, after the commit
+	assert.Equal(t, []string{"0x1"}, messaging.published)
+}
+
+func TestCrawlerService_ProcessBlockCommitFailure(t *testing.T) {
+	crawler, commits, messaging := newTestCommitCrawler(t, []*entity.Transaction{{Hash: "0x1", BlockHash: "0xb100"}})
+	commits.err = errors.New("write conflict")
+
+	err := crawler.processBlock(context.Background(), big.NewInt(100), func() {})
+	assert.ErrorContains(t, err, "write conflict")
+	assert.Empty(t, messaging.published)
+	assert.Equal(t, uint64(0), crawler.GetMetrics().BlocksProcessed)
+}
+
+func TestCrawlerService_ProcessBlockBufferedWrite(t *testing.T) {
+	crawler, commits, messaging := newTestCommitCrawler(t, []*entity.Transaction{{Hash: "0x1", BlockHash: "0xb100"}})
+	writer := NewBlockWriter(commits, &config.Config{WebSocket: config.WebSocketConfig{BatchSize: 1}}, newTestLogger(t))
+	require.NoError(t, writer.Start(context.Background()))
+	defer writer.Stop()
+	crawler.SetBlockWriter(writer)
+
+	released := false
+	require.NoError(t, crawler.processBlock(context.Background(), big.NewInt(100), func() { released = true }))
+
+	assert.True(t, released, "the worker slot is freed while the block waits for its flush")
+	assert.Equal(t, []string{"0xb100"}, commits.committedHashes())
+	assert.Equal(t, []string{"0x1"}, messaging.published)
+}
+
+func TestCrawlerService_ProcessBlockRecordsAddresses(t *testing.T) {
+	bob := "0xbob"
+	crawler, _, messaging := newTestCommitCrawler(t, []*entity.Transaction{
+		{Hash: "0x1", BlockHash: "0xb100", BlockNumber: "100", From: "0xalice", To: &bob},
+	})
+	addresses := newFakeAddressRepository()
+	cfg := &config.Config{Addresses: config.AddressesConfig{Enabled: true}}
+	crawler.SetAddressService(NewAddressService(addresses, nil, cfg, newTestLogger(t)))
+
+	require.NoError(t, crawler.processBlock(context.Background(), big.NewInt(100), func() {}))
+	assert.Contains(t, addresses.addresses, "0xalice")
+	assert.Equal(t, uint64(100), addresses.addresses[bob].LastActiveBlock)
+	assert.Equal(t, []string{"0x1"}, messaging.published)
+
+	// The block is retried, and published then
+	messaging.published = nil
+	addresses.err = errors.New("database unavailable")
+	err := crawler.processBlock(context.Background(), big.NewInt(100), func() {})
+	assert.ErrorContains(t, err, "database unavailable")
+	assert.Empty(t, messaging.published)
+}
+
+func TestCrawlerService_ProcessBlockRecordsTokenTransfers(t *testing.T) {
+	transfer := &entity.TokenTransfer{TransactionHash: "0x1", Standard: entity.TokenStandardERC20, Amount: "5"}
+	crawler, _, messaging := newTestCommitCrawler(t, []*entity.Transaction{
+		{Hash: "0x1", BlockHash: "0xb100", TokenTransfers: []*entity.TokenTransfer{transfer}},
+	})
+	transfers := newFakeTokenTransferRepository()
+	cfg := &config.Config{TokenTransfers: config.TokenTransfersConfig{Enabled: true, Publish: true}}
+	crawler.SetTokenTransferService(NewTokenTransferService(transfers, messaging, cfg, newTestLogger(t)))
+
+	require.NoError(t, crawler.processBlock(context.Background(), big.NewInt(100), func() {}))
+	assert.Len(t, transfers.transfers, 1)
+	assert.Equal(t, []string{"0x1"}, messaging.published)
+	assert.Equal(t, []*entity.TokenTransfer{transfer}, messaging.transfers)
+
+	// The block is retried, and published then
+	messaging.published, messaging.transfers = nil, nil
+	transfers.err = errors.New("database unavailable")
+	err := crawler.processBlock(context.Background(), big.NewInt(100), func() {})
+	assert.ErrorContains(t, err, "database unavailable")
+	assert.Empty(t, messaging.published)
+	assert.Empty(t, messaging.transfers)
+}
+
+func TestCrawlerService_CleanupUncommittedBlocks(t *testing.T) {
+	crawler, commits, _ := newTestCommitCrawler(t, nil)
+	commits.pending = []uint64{98, 99}
+
+	crawler.cleanupUncommittedBlocks(context.Background())
+
+	assert.Empty(t, commits.pending)
+	assert.WithinDuration(t, time.Now().Add(-uncommittedBlockAge), commits.cleanedAt, time.Minute)
+}
+
+func TestCrawlerService_CountUncountedTransactions(t *testing.T) {
+	crawler, commits, _ := newTestCommitCrawler(t, nil)
+	commits.uncounted = 3
+
+	crawler.countUncountedTransactions(context.Background())
+
+	assert.Zero(t, commits.uncounted)
+	assert.WithinDuration(t, time.Now().Add(-uncommittedBlockAge), commits.sweptAt, time.Minute)
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultExportBatchSize is the number of blocks read per chunk
+const defaultExportBatchSize = 1000
+
+// ExportRequest describes the blocks to export. A time range, when set,
+// selects the blocks mined within it instead of the block range.
+type ExportRequest struct {
+	Network   string
+	FromBlock uint64
+	ToBlock   uint64 // 0 exports up to the last processed block
+	FromTime  time.Time
+	ToTime    time.Time // Zero exports up to now when FromTime is set
+	// Incremental starts after the last block of the export manifest and
+	// stops at the first block that isn't processed yet or misses receipts
+	Incremental bool
+	BatchSize   uint64
+}
+
+// ExportResult summarizes an export run
+type ExportResult struct {
+	FromBlock            uint64
+	ToBlock              uint64
+	BlocksExported       int64
+	BlocksMissing        int64
+	TransactionsExported int64
+	TransactionsSkipped  int64
+	FilesWritten         int64
+	Duration             time.Duration
+}
+
+// ExportService exports stored blocks and transactions to partitioned files
+type ExportService struct {
+	blockRepo repository.BlockRepository
+	txRepo    repository.TransactionRepository
+	sink      service.ExportSink
+	logger    *logger.Logger
+}
+
+// NewExportService creates a new export service
+func NewExportService(
+	blockRepo repository.BlockRepository,
+	txRepo repository.TransactionRepository,
+	sink service.ExportSink,
+	logger *logger.Logger,
+) *ExportService {
+	return &ExportService{
+		blockRepo: blockRepo,
+		txRepo:    txRepo,
+		sink:      sink,
+		logger:    logger.WithComponent("export-service"),
+	}
+}
+
+// Export writes the blocks of the range and their transactions, one set of
+// files per UTC day, and records every exported chunk in the manifest so an
+// interrupted or repeated incremental export resumes after the last chunk.
+func (s *ExportService) Export(ctx context.Context, req ExportRequest) (*ExportResult, error) {
+	manifest, err := s.loadManifest(ctx, req.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	fromBlock, toBlock, found, err := s.resolveRange(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if last, ok := manifest.LastExportedBlock(); req.Incremental && ok && last >= fromBlock {
+		if last >= toBlock {
+			found = false
+		} else {
+			fromBlock = last + 1
+		}
+	}
+	if next, ok := manifest.NextExportedBlock(fromBlock); req.Incremental && ok && next <= toBlock {
+		// Blocks after a gap in the manifest are exported already
+		toBlock = next - 1
+	}
+
+	result := &ExportResult{FromBlock: fromBlock, ToBlock: toBlock}
+	if !found {
+		s.logger.Info("Nothing to export", zap.String("network", req.Network))
+		return result, nil
+	}
+	if fromBlock > toBlock {
+		return nil, fmt.Errorf("invalid block range: %d > %d", fromBlock, toBlock)
+	}
+
+	batchSize := req.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultExportBatchSize
+	}
+
+	startTime := time.Now()
+
+	s.logger.Info("Starting export",
+		zap.String("network", req.Network),
+		zap.String("format", s.sink.Format()),
+		zap.Uint64("from_block", fromBlock),
+		zap.Uint64("to_block", toBlock),
+		zap.Bool("incremental", req.Incremental))
+
+	for chunkStart := fromBlock; ; chunkStart += batchSize {
+		if err := ctx.Err(); err != nil {
+			result.Duration = time.Since(startTime)
+			return result, err
+		}
+
+		chunkEnd := toBlock
+		if toBlock-chunkStart >= batchSize {
+			chunkEnd = chunkStart + batchSize - 1
+		}
+
+		complete, err := s.exportChunk(ctx, req, manifest, chunkStart, chunkEnd, result)
+		if err != nil {
+			result.Duration = time.Since(startTime)
+			return result, fmt.Errorf("failed to export blocks %d-%d: %w", chunkStart, chunkEnd, err)
+		}
+		if !complete || chunkEnd == toBlock {
+			break // Also avoids overflow when toBlock is the maximum uint64
+		}
+	}
+
+	result.Duration = time.Since(startTime)
+
+	s.logger.Info("Export completed",
+		zap.Int64("blocks_exported", result.BlocksExported),
+		zap.Int64("blocks_missing", result.BlocksMissing),
+		zap.Int64("transactions_exported", result.TransactionsExported),
+		zap.Int64("transactions_skipped", result.TransactionsSkipped),
+		zap.Int64("files_written", result.FilesWritten),
+		zap.Duration("duration", result.Duration))
+
+	return result, nil
+}
+
+// loadManifest loads the manifest of the network, or starts a new one
+func (s *ExportService) loadManifest(ctx context.Context, network string) (*entity.ExportManifest, error) {
+	manifest, err := s.sink.LoadManifest(ctx, network)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return &entity.ExportManifest{
+			Version: entity.ExportManifestVersion,
+			Network: network,
+			Format:  s.sink.Format(),
+		}, nil
+	}
+
+	// Mixing formats or schema versions in one export breaks its readers
+	if manifest.Format != s.sink.Format() {
+		return nil, fmt.Errorf("export was written as %s, not %s", manifest.Format, s.sink.Format())
+	}
+	if manifest.Version != entity.ExportManifestVersion {
+		return nil, fmt.Errorf("export was written with schema version %d, current version is %d",
+			manifest.Version, entity.ExportManifestVersion)
+	}
+	return manifest, nil
+}
+
+// resolveRange returns the block range of the request, false when no stored block matches
+func (s *ExportService) resolveRange(ctx context.Context, req ExportRequest) (uint64, uint64, bool, error) {
+	if !req.FromTime.IsZero() || !req.ToTime.IsZero() {
+		toTime := req.ToTime
+		if toTime.IsZero() {
+			toTime = time.Now()
+		}
+		if req.FromTime.After(toTime) {
+			return 0, 0, false, fmt.Errorf("invalid time range: %s > %s", req.FromTime, toTime)
+		}
+
+		first, last, err := s.blockRepo.GetBlockRangeByTime(ctx, req.Network, req.FromTime, toTime)
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("failed to get blocks of time range: %w", err)
+		}
+		if first == nil {
+			return 0, 0, false, nil
+		}
+
+		fromBlock, err := parseExportBlockNumber(first)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		toBlock, err := parseExportBlockNumber(last)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		return fromBlock, toBlock, true, nil
+	}
+
+	if req.ToBlock != 0 {
+		return req.FromBlock, req.ToBlock, true, nil
+	}
+
+	last, err := s.blockRepo.GetLastProcessedBlock(ctx, req.Network)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to get last processed block: %w", err)
+	}
+	if last == nil {
+		return 0, 0, false, nil
+	}
+	toBlock, err := parseExportBlockNumber(last)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return req.FromBlock, toBlock, req.FromBlock <= toBlock, nil
+}
+
+// exportChunk exports the blocks of one chunk and records them in the
+// manifest. It returns false when an incremental export stopped at a block
+// that isn't processed yet or misses receipts.
+func (s *ExportService) exportChunk(ctx context.Context, req ExportRequest, manifest *entity.ExportManifest, fromBlock, toBlock uint64, result *ExportResult) (bool, error) {
+	stored, err := s.blockRepo.GetBlocksInRange(ctx, new(big.Int).SetUint64(fromBlock), new(big.Int).SetUint64(toBlock))
+	if err != nil {
+		return false, fmt.Errorf("failed to get blocks: %w", err)
+	}
+
+	blocksByNumber := make(map[uint64]*entity.Block, len(stored))
+	for _, block := range stored {
+		number, err := parseExportBlockNumber(block)
+		if err != nil {
+			return false, err
+		}
+		blocksByNumber[number] = block
+	}
+
+	var (
+		partitions   [][]*service.ExportBlock
+		complete     = true
+		lastBlock    = toBlock
+		transactions int64
+	)
+	for blockNumber := fromBlock; ; blockNumber++ {
+		block := blocksByNumber[blockNumber]
+		if req.Incremental && !isCompleteBlock(block) {
+			// Later runs pick up from here once the block is crawled or repaired
+			s.logger.Info("Stopping at block that isn't processed yet", zap.Uint64("block_number", blockNumber))
+			complete = false
+			if blockNumber == fromBlock {
+				return false, nil
+			}
+			lastBlock = blockNumber - 1
+			break
+		}
+		if !isExportableBlock(block) {
+			s.logger.Warn("Block not processed in database, skipping", zap.Uint64("block_number", blockNumber))
+			result.BlocksMissing++
+		} else {
+			exported, err := s.exportBlock(ctx, block, blockNumber, result)
+			if err != nil {
+				return false, err
+			}
+			transactions += int64(len(exported.Transactions))
+
+			// Blocks are in order, so each UTC day is one run of blocks
+			if n := len(partitions); n == 0 || !sameExportDate(partitions[n-1][0].Block, block) {
+				partitions = append(partitions, nil)
+			}
+			partitions[len(partitions)-1] = append(partitions[len(partitions)-1], exported)
+		}
+
+		if blockNumber == toBlock {
+			break
+		}
+	}
+
+	var (
+		files  []string
+		blocks int64
+	)
+	for _, partition := range partitions {
+		written, err := s.sink.WritePartition(ctx, service.ExportPartition{
+			Network: req.Network,
+			Date:    exportDate(partition[0].Block),
+		}, partition)
+		if err != nil {
+			return false, err
+		}
+		files = append(files, written...)
+		blocks += int64(len(partition))
+	}
+
+	if blocks > 0 {
+		now := time.Now()
+		manifest.Ranges = append(manifest.Ranges, entity.ExportRange{
+			FromBlock:    fromBlock,
+			ToBlock:      lastBlock,
+			Blocks:       blocks,
+			Transactions: transactions,
+			Files:        files,
+			ExportedAt:   now,
+		})
+		manifest.UpdatedAt = now
+		if err := s.sink.SaveManifest(ctx, manifest); err != nil {
+			return false, fmt.Errorf("failed to save export manifest: %w", err)
+		}
+	}
+
+	result.BlocksExported += blocks
+	result.TransactionsExported += transactions
+	result.FilesWritten += int64(len(files))
+
+	s.logger.Debug("Exported blocks",
+		zap.Uint64("from_block", fromBlock),
+		zap.Uint64("to_block", lastBlock),
+		zap.Int64("blocks", blocks),
+		zap.Int64("transactions", transactions))
+
+	return complete, nil
+}
+
+// exportBlock returns a block with its transactions, ordered by index
+func (s *ExportService) exportBlock(ctx context.Context, block *entity.Block, blockNumber uint64, result *ExportResult) (*service.ExportBlock, error) {
+	transactions, err := s.txRepo.GetTransactionsByBlockNumber(ctx, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions of block %d: %w", blockNumber, err)
+	}
+
+	exported := &service.ExportBlock{Block: block}
+	for _, tx := range transactions {
+		// Transactions left behind by a reorged block don't belong to the stored block
+		if !isTransactionOfBlock(tx, block) {
+			result.TransactionsSkipped++
+			continue
+		}
+		exported.Transactions = append(exported.Transactions, tx)
+	}
+	sort.Slice(exported.Transactions, func(i, j int) bool {
+		return exported.Transactions[i].TransactionIndex < exported.Transactions[j].TransactionIndex
+	})
+
+	return exported, nil
+}
+
+// isExportableBlock checks that a block is stored with all its transactions
+func isExportableBlock(block *entity.Block) bool {
+	return block != nil &&
+		(block.Status == entity.BlockStatusProcessed || block.Status == entity.BlockStatusIncomplete)
+}
+
+// isCompleteBlock checks that a block is stored with all its transactions and receipts
+func isCompleteBlock(block *entity.Block) bool {
+	return block != nil && block.Status == entity.BlockStatusProcessed
+}
+
+// exportDate returns the UTC day partition of a block
+func exportDate(block *entity.Block) time.Time {
+	return block.Timestamp.UTC().Truncate(24 * time.Hour)
+}
+
+func sameExportDate(a, b *entity.Block) bool {
+	return exportDate(a).Equal(exportDate(b))
+}
+
+func parseExportBlockNumber(block *entity.Block) (uint64, error) {
+	number, err := strconv.ParseUint(block.Number, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block number %q: %w", block.Number, err)
+	}
+	return number, nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"fmt"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (r *fakeBlockRepository) GetBlocksInRange(ctx context.Context, startBlock, endBlock *big.Int) ([]*entity.Block, error) {
+	var blocks []*entity.Block
+	for number := startBlock.Uint64(); number <= endBlock.Uint64(); number++ {
+		if block, ok := r.blocks[number]; ok {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, nil
+}
+
+func (r *fakeBlockRepository) GetBlockRangeByTime(ctx context.Context, network string, startTime, endTime time.Time) (*entity.Block, *entity.Block, error) {
+	var first, last *entity.Block
+	for number := uint64(0); number < 100; number++ {
+		block, ok := r.blocks[number]
+		if !ok || block.Timestamp.Before(startTime) || block.Timestamp.After(endTime) {
+			continue
+		}
+		if first == nil {
+			first = block
+		}
+		last = block
+	}
+	return first, last, nil
+}
+
+type memoryExportSink struct {
+	format     string
+	partitions []string
+	manifest   *entity.ExportManifest
+}
+
+func (s *memoryExportSink) Format() string {
+	return s.format
+}
+
+func (s *memoryExportSink) WritePartition(ctx context.Context, partition service.ExportPartition, blocks []*service.ExportBlock) ([]string, error) {
+	var hashes []string
+	for _, exported := range blocks {
+		for _, tx := range exported.Transactions {
+			hashes = append(hashes, tx.Hash)
+		}
+	}
+	file := fmt.Sprintf("%s/%s/%s-%s%v", partition.Network, partition.Date.Format("2006-01-02"),
+		blocks[0].Block.Number, blocks[len(blocks)-1].Block.Number, hashes)
+	s.partitions = append(s.partitions, file)
+	return []string{file}, nil
+}
+
+func (s *memoryExportSink) LoadManifest(ctx context.Context, network string) (*entity.ExportManifest, error) {
+	return s.manifest, nil
+}
+
+func (s *memoryExportSink) SaveManifest(ctx context.Context, manifest *entity.ExportManifest) error {
+	saved := *manifest
+	saved.Ranges = append([]entity.ExportRange(nil), manifest.Ranges...)
+	s.manifest = &saved
+	return nil
+}
+
+// exportTestBlocks returns processed blocks 10-14 mined an hour apart from
+// 2024-01-01 22:00 UTC, so blocks 12-14 fall on the next day
+func exportTestBlocks() map[uint64]*entity.Block {
+	blocks := make(map[uint64]*entity.Block)
+	for number := uint64(10); number <= 14; number++ {
+		blocks[number] = &entity.Block{
+			Number:    strconv.FormatUint(number, 10),
+			Hash:      fmt.Sprintf("0xb%d", number),
+			Timestamp: time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC).Add(time.Duration(number-10) * time.Hour),
+			Network:   "ethereum",
+			Status:    entity.BlockStatusProcessed,
+		}
+	}
+	return blocks
+}
+
+func TestExportService_Export(t *testing.T) {
+	blockRepo := &fakeBlockRepository{blocks: exportTestBlocks()}
+	delete(blockRepo.blocks, 13)
+	txRepo := &fakeTransactionRepository{transactions: map[uint64][]*entity.Transaction{
+		10: {{Hash: "0x2", BlockHash: "0xb10", TransactionIndex: 1}, {Hash: "0x1", BlockHash: "0xb10"}},
+		12: {{Hash: "0x3", BlockHash: "0xb12"}, {Hash: "0x4", BlockHash: "0xorphan"}},
+	}}
+	sink := &memoryExportSink{format: "parquet"}
+
+	exportService := NewExportService(blockRepo, txRepo, sink, newTestLogger(t))
+
+	result, err := exportService.Export(context.Background(), ExportRequest{
+		Network:   "ethereum",
+		FromBlock: 10,
+		ToBlock:   14,
+		BatchSize: 3,
+	})
+	require.NoError(t, err)
+
+	// Partitions follow the UTC day within each chunk, transactions are ordered by index
+	assert.Equal(t, []string{
+		"ethereum/2024-01-01/10-11[0x1 0x2]",
+		"ethereum/2024-01-02/12-12[0x3]",
+		"ethereum/2024-01-02/14-14[]",
+	}, sink.partitions)
+	assert.Equal(t, int64(4), result.BlocksExported)
+	assert.Equal(t, int64(1), result.BlocksMissing)
+	assert.Equal(t, int64(3), result.TransactionsExported)
+	assert.Equal(t, int64(1), result.TransactionsSkipped)
+	assert.Equal(t, int64(3), result.FilesWritten)
+
+	require.NotNil(t, sink.manifest)
+	assert.Equal(t, "parquet", sink.manifest.Format)
+	assert.Equal(t, entity.ExportManifestVersion, sink.manifest.Version)
+	require.Len(t, sink.manifest.Ranges, 2)
+	assert.Equal(t, uint64(10), sink.manifest.Ranges[0].FromBlock)
+	assert.Equal(t, uint64(12), sink.manifest.Ranges[0].ToBlock)
+	assert.Equal(t, int64(3), sink.manifest.Ranges[0].Blocks)
+	assert.Equal(t, uint64(13), sink.manifest.Ranges[1].FromBlock)
+	assert.Equal(t, uint64(14), sink.manifest.Ranges[1].ToBlock)
+}
+
+func TestExportService_Incremental(t *testing.T) {
+	blockRepo := &fakeBlockRepository{blocks: exportTestBlocks()}
+	blockRepo.blocks[13].Status = entity.BlockStatusPending
+	sink := &memoryExportSink{format: "csv"}
+
+	exportService := NewExportService(blockRepo, &fakeTransactionRepository{}, sink, newTestLogger(t))
+	req := ExportRequest{Network: "ethereum", FromBlock: 10, ToBlock: 14, Incremental: true}
+
+	// The first run stops before the pending block
+	result, err := exportService.Export(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), result.BlocksExported)
+	require.Len(t, sink.manifest.Ranges, 1)
+	assert.Equal(t, uint64(12), sink.manifest.Ranges[0].ToBlock)
+
+	// Nothing new until the block is processed
+	result, err = exportService.Export(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.BlocksExported)
+
+	blockRepo.blocks[13].Status = entity.BlockStatusProcessed
+	result, err = exportService.Export(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(13), result.FromBlock)
+	assert.Equal(t, int64(2), result.BlocksExported)
+	require.Len(t, sink.manifest.Ranges, 2)
+
+	last, ok := sink.manifest.LastExportedBlock()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(14), last)
+
+	// Everything is exported
+	result, err = exportService.Export(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.BlocksExported)
+	assert.Len(t, sink.partitions, 3)
+}
+
+func TestExportService_IncrementalFillsGaps(t *testing.T) {
+	blockRepo := &fakeBlockRepository{blocks: exportTestBlocks()}
+	blockRepo.blocks[12].Status = entity.BlockStatusIncomplete
+	sink := &memoryExportSink{format: "csv", manifest: &entity.ExportManifest{
+		Version: entity.ExportManifestVersion,
+		Network: "ethereum",
+		Format:  "csv",
+		Ranges: []entity.ExportRange{
+			{FromBlock: 14, ToBlock: 14, Blocks: 1},
+			{FromBlock: 10, ToBlock: 10, Blocks: 1},
+		},
+	}}
+
+	// The run resumes at the gap and stops at the block missing receipts
+	last, ok := sink.manifest.LastExportedBlock()
+	require.True(t, ok)
+	assert.Equal(t, uint64(10), last)
+
+	exportService := NewExportService(blockRepo, &fakeTransactionRepository{}, sink, newTestLogger(t))
+	req := ExportRequest{Network: "ethereum", FromBlock: 10, ToBlock: 14, Incremental: true}
+	result, err := exportService.Export(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), result.FromBlock)
+	assert.Equal(t, int64(1), result.BlocksExported)
+
+	// Once repaired the gap is filled without exporting block 14 again
+	blockRepo.blocks[12].Status = entity.BlockStatusProcessed
+	result, err = exportService.Export(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(12), result.FromBlock)
+	assert.Equal(t, uint64(13), result.ToBlock)
+	assert.Equal(t, int64(2), result.BlocksExported)
+	assert.Equal(t, []string{
+		"ethereum/2024-01-01/11-11[]",
+		"ethereum/2024-01-02/12-13[]",
+	}, sink.partitions)
+
+	last, ok = sink.manifest.LastExportedBlock()
+	require.True(t, ok)
+	assert.Equal(t, uint64(14), last)
+	result, err = exportService.Export(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.BlocksExported)
+}
+
+func TestExportService_TimeRange(t *testing.T) {
+	blockRepo := &fakeBlockRepository{blocks: exportTestBlocks()}
+	sink := &memoryExportSink{format: "jsonl"}
+
+	exportService := NewExportService(blockRepo, &fakeTransactionRepository{}, sink, newTestLogger(t))
+
+	result, err := exportService.Export(context.Background(), ExportRequest{
+		Network:  "ethereum",
+		FromTime: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		ToTime:   time.Date(2024, 1, 2, 1, 30, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(12), result.FromBlock)
+	assert.Equal(t, uint64(13), result.ToBlock)
+	assert.Equal(t, []string{"ethereum/2024-01-02/12-13[]"}, sink.partitions)
+}
+
+func TestExportService_FormatMismatch(t *testing.T) {
+	sink := &memoryExportSink{format: "csv", manifest: &entity.ExportManifest{
+		Version: entity.ExportManifestVersion,
+		Network: "ethereum",
+		Format:  "parquet",
+	}}
+
+	exportService := NewExportService(&fakeBlockRepository{}, &fakeTransactionRepository{}, sink, newTestLogger(t))
+
+	_, err := exportService.Export(context.Background(), ExportRequest{Network: "ethereum", ToBlock: 10})
+	assert.Error(t, err)
+}
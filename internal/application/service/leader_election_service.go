@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LeaderCallbacks are invoked when this instance gains or loses leadership,
+// one at a time. OnStartedLeading runs in its own goroutine so the lease is
+// renewed while the leader starts; OnStoppedLeading waits for it to return.
+type LeaderCallbacks struct {
+	OnStartedLeading func(ctx context.Context) error
+	OnStoppedLeading func()
+}
+
+// LeaderElectionService elects a single leader among scheduler replicas using a
+// lease with TTL. The leader renews the lease every RenewInterval; followers
+// take over within LeaseTTL + RenewInterval after the leader stops renewing.
+type LeaderElectionService struct {
+	leaseRepo repository.LeaseRepository
+	logger    *logger.Logger
+
+	enabled       bool
+	leaseName     string
+	instanceID    string
+	leaseTTL      time.Duration
+	renewInterval time.Duration
+
+	callbacks LeaderCallbacks
+	// Serializes the callbacks, leading is set once OnStartedLeading was called
+	callbackMu sync.Mutex
+	leading    bool
+	isRunning  bool
+	stopChan   chan struct{}
+	wg         sync.WaitGroup
+
+	// Leadership state
+	mu            sync.RWMutex
+	isLeader      bool
+	token         int64
+	validUntil    time.Time
+	currentLeader string
+	lastError     error
+	lastChecked   time.Time
+}
+
+// NewLeaderElectionService creates a new leader election service
+func NewLeaderElectionService(
+	leaseRepo repository.LeaseRepository,
+	config *config.Config,
+	logger *logger.Logger,
+) *LeaderElectionService {
+	cfg := config.LeaderElection
+
+	s := &LeaderElectionService{
+		leaseRepo:     leaseRepo,
+		logger:        logger.WithComponent("leader-election"),
+		enabled:       cfg.Enabled,
+		leaseName:     fmt.Sprintf("%s-%s", cfg.LeaseName, config.Ethereum.Network),
+		instanceID:    cfg.InstanceID,
+		leaseTTL:      15 * time.Second,
+		renewInterval: 5 * time.Second,
+	}
+
+	if cfg.LeaseName == "" {
+		s.leaseName = fmt.Sprintf("scheduler-%s", config.Ethereum.Network)
+	}
+	if s.instanceID == "" {
+		s.instanceID = defaultInstanceID()
+	}
+	if cfg.LeaseTTL > 0 {
+		s.leaseTTL = cfg.LeaseTTL
+	}
+	if cfg.RenewInterval > 0 {
+		s.renewInterval = cfg.RenewInterval
+	}
+	// The leader must get several renewal attempts before its lease expires
+	if s.renewInterval*2 > s.leaseTTL {
+		s.renewInterval = s.leaseTTL / 3
+	}
+
+	return s
+}
+
+// Start starts the election loop. When leader election is disabled this
+// instance becomes leader immediately.
+func (s *LeaderElectionService) Start(ctx context.Context, callbacks LeaderCallbacks) error {
+	s.mu.Lock()
+	if s.isRunning {
+		s.mu.Unlock()
+		return fmt.Errorf("leader election is already running")
+	}
+	s.isRunning = true
+	s.callbacks = callbacks
+	s.stopChan = make(chan struct{})
+	s.mu.Unlock()
+
+	// The loop outlives the start context and is stopped through stopChan
+	loopCtx := context.WithoutCancel(ctx)
+
+	if !s.enabled {
+		s.logger.Info("Leader election disabled, running as leader")
+		s.mu.Lock()
+		s.isLeader = true
+		s.currentLeader = s.instanceID
+		s.mu.Unlock()
+
+		s.callbackMu.Lock()
+		defer s.callbackMu.Unlock()
+		s.leading = true
+		if callbacks.OnStartedLeading != nil {
+			return callbacks.OnStartedLeading(loopCtx)
+		}
+		return nil
+	}
+
+	s.logger.Info("Starting leader election",
+		zap.String("lease", s.leaseName),
+		zap.String("instance_id", s.instanceID),
+		zap.Duration("lease_ttl", s.leaseTTL),
+		zap.Duration("renew_interval", s.renewInterval))
+
+	s.wg.Add(1)
+	go s.electionLoop(loopCtx, s.stopChan)
+
+	return nil
+}
+
+// Stop stops the election loop, steps down and releases the lease so a
+// follower can take over without waiting for the TTL
+func (s *LeaderElectionService) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.isRunning {
+		s.mu.Unlock()
+		return nil
+	}
+	s.isRunning = false
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	s.mu.RLock()
+	wasLeader := s.isLeader
+	token := s.token
+	s.mu.RUnlock()
+
+	if !wasLeader {
+		return nil
+	}
+
+	s.stepDown("shutting down")
+
+	if s.enabled {
+		if err := s.leaseRepo.ReleaseLease(ctx, s.leaseName, s.instanceID, token); err != nil {
+			return fmt.Errorf("failed to release lease: %w", err)
+		}
+		s.logger.Info("Released leadership lease", zap.Int64("fencing_token", token))
+	}
+
+	return nil
+}
+
+// IsLeader reports whether this instance currently holds a valid lease
+func (s *LeaderElectionService) IsLeader() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isLeader && (!s.enabled || time.Now().Before(s.validUntil))
+}
+
+// FencingToken returns the token of the lease held by this instance, 0 when not leader
+func (s *LeaderElectionService) FencingToken() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.isLeader {
+		return 0
+	}
+	return s.token
+}
+
+// GetStats returns leader election statistics
+func (s *LeaderElectionService) GetStats() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := map[string]interface{}{
+		"leader_election_enabled": s.enabled,
+		"instance_id":             s.instanceID,
+		"is_leader":               s.isLeader,
+		"leader_id":               s.currentLeader,
+		"fencing_token":           s.token,
+	}
+	if s.enabled {
+		stats["lease_name"] = s.leaseName
+		stats["lease_valid_until"] = s.validUntil
+	}
+
+	return stats
+}
+
+// HealthCheck reports leadership as a system health component
+func (s *LeaderElectionService) HealthCheck(ctx context.Context) entity.ComponentHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	health := entity.ComponentHealth{
+		Status:      entity.HealthStatusHealthy,
+		LastChecked: s.lastChecked,
+	}
+
+	switch {
+	case !s.enabled:
+		health.Message = "Leader election disabled, running as leader"
+		health.LastChecked = time.Now()
+	case s.lastError != nil:
+		health.Status = entity.HealthStatusDegraded
+		health.Message = fmt.Sprintf("Lease check failed: %v", s.lastError)
+	case s.isLeader:
+		health.Message = fmt.Sprintf("Leader (fencing token %d)", s.token)
+	case s.currentLeader != "":
+		health.Message = fmt.Sprintf("Standby, leader is %s", s.currentLeader)
+	default:
+		health.Message = "Standby, no leader"
+	}
+
+	return health
+}
+
+// electionLoop acquires or renews the lease every renew interval
+func (s *LeaderElectionService) electionLoop(ctx context.Context, stopChan chan struct{}) {
+	defer s.wg.Done()
+
+	// Add panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic recovered in electionLoop",
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+
+	ticker := time.NewTicker(s.renewInterval)
+	defer ticker.Stop()
+
+	s.tick(ctx)
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+// tick performs one acquire or renew round
+func (s *LeaderElectionService) tick(ctx context.Context) {
+	s.mu.RLock()
+	isLeader := s.isLeader
+	token := s.token
+	validUntil := s.validUntil
+	s.mu.RUnlock()
+
+	// Measure validity from before the request so the local view expires
+	// no later than the lease does in the database
+	attemptStart := time.Now()
+	opCtx, cancel := context.WithTimeout(ctx, s.renewInterval)
+	defer cancel()
+
+	if isLeader {
+		lease, err := s.leaseRepo.RenewLease(opCtx, s.leaseName, s.instanceID, token, s.leaseTTL)
+		s.recordCheck(err)
+		switch {
+		case err != nil:
+			s.logger.Warn("Failed to renew leadership lease", zap.Error(err))
+			if time.Now().After(validUntil) {
+				s.stepDown("lease expired before it could be renewed")
+			}
+		case lease == nil:
+			s.stepDown("lease taken over by another instance")
+		default:
+			s.mu.Lock()
+			s.validUntil = leaseValidUntil(attemptStart, lease)
+			s.mu.Unlock()
+		}
+		return
+	}
+
+	lease, err := s.leaseRepo.AcquireLease(opCtx, s.leaseName, s.instanceID, s.leaseTTL)
+	s.recordCheck(err)
+	if err != nil {
+		s.logger.Warn("Failed to acquire leadership lease", zap.Error(err))
+		return
+	}
+
+	if lease == nil {
+		// Someone else leads, remember who for stats
+		if current, err := s.leaseRepo.GetLease(opCtx, s.leaseName); err == nil && current != nil {
+			s.mu.Lock()
+			s.currentLeader = current.HolderID
+			s.token = current.Token
+			s.mu.Unlock()
+		}
+		return
+	}
+
+	s.mu.Lock()
+	s.isLeader = true
+	s.token = lease.Token
+	s.validUntil = leaseValidUntil(attemptStart, lease)
+	s.currentLeader = s.instanceID
+	s.mu.Unlock()
+
+	s.logger.Info("Acquired leadership",
+		zap.String("lease", s.leaseName),
+		zap.Int64("fencing_token", lease.Token))
+
+	// Starting may take longer than the lease TTL, the loop keeps renewing meanwhile
+	s.wg.Add(1)
+	go s.startLeading(ctx, lease.Token)
+}
+
+// startLeading calls OnStartedLeading for the lease with the given token, and
+// gives the lease up when it fails
+func (s *LeaderElectionService) startLeading(ctx context.Context, token int64) {
+	defer s.wg.Done()
+
+	// Add panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic recovered in startLeading",
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+
+	s.callbackMu.Lock()
+	s.mu.RLock()
+	current := s.isLeader && s.token == token
+	callbacks := s.callbacks
+	s.mu.RUnlock()
+	if !current {
+		// Lost before the previous callback returned
+		s.callbackMu.Unlock()
+		return
+	}
+
+	s.leading = true
+	var err error
+	if callbacks.OnStartedLeading != nil {
+		err = callbacks.OnStartedLeading(ctx)
+	}
+	s.callbackMu.Unlock()
+	if err == nil {
+		return
+	}
+
+	s.logger.Error("Failed to start leading, releasing lease", zap.Error(err))
+	s.stepDown("failed to start leading")
+	if err := s.leaseRepo.ReleaseLease(ctx, s.leaseName, s.instanceID, token); err != nil {
+		s.logger.Error("Failed to release lease", zap.Error(err))
+	}
+}
+
+// leaseValidUntil returns the local time the lease is valid until. Its
+// lifetime comes from the server timestamps of the lease document and is
+// counted from before the request, so clock skew doesn't extend it.
+func leaseValidUntil(attemptStart time.Time, lease *entity.Lease) time.Time {
+	return attemptStart.Add(lease.ExpiresAt.Sub(lease.RenewedAt))
+}
+
+// stepDown gives up leadership locally and notifies the callbacks
+func (s *LeaderElectionService) stepDown(reason string) {
+	s.mu.Lock()
+	if !s.isLeader {
+		s.mu.Unlock()
+		return
+	}
+	s.isLeader = false
+	s.validUntil = time.Time{}
+	if s.currentLeader == s.instanceID {
+		s.currentLeader = ""
+	}
+	callbacks := s.callbacks
+	s.mu.Unlock()
+
+	s.logger.Warn("Lost leadership", zap.String("reason", reason))
+
+	// Waits for a start still in progress, nothing to stop when it never ran
+	s.callbackMu.Lock()
+	defer s.callbackMu.Unlock()
+	if !s.leading {
+		return
+	}
+	s.leading = false
+	if callbacks.OnStoppedLeading != nil {
+		callbacks.OnStoppedLeading()
+	}
+}
+
+// recordCheck stores the outcome of the last lease operation for health reporting
+func (s *LeaderElectionService) recordCheck(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = err
+	s.lastChecked = time.Now()
+}
+
+// defaultInstanceID identifies this process among replicas
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryLeaseRepository mimics the Mongo lease semantics in memory
+type memoryLeaseRepository struct {
+	mu    sync.Mutex
+	lease *entity.Lease
+}
+
+func (r *memoryLeaseRepository) AcquireLease(ctx context.Context, name, holderID string, ttl time.Duration) (*entity.Lease, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.lease != nil && r.lease.HolderID != holderID && now.Before(r.lease.ExpiresAt) {
+		return nil, nil
+	}
+	token := int64(1)
+	if r.lease != nil {
+		token = r.lease.Token + 1
+	}
+	r.lease = &entity.Lease{Name: name, HolderID: holderID, Token: token, AcquiredAt: now, RenewedAt: now, ExpiresAt: now.Add(ttl)}
+	copied := *r.lease
+	return &copied, nil
+}
+
+func (r *memoryLeaseRepository) RenewLease(ctx context.Context, name, holderID string, token int64, ttl time.Duration) (*entity.Lease, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lease == nil || r.lease.HolderID != holderID || r.lease.Token != token {
+		return nil, nil
+	}
+	r.lease.RenewedAt = time.Now()
+	r.lease.ExpiresAt = r.lease.RenewedAt.Add(ttl)
+	copied := *r.lease
+	return &copied, nil
+}
+
+func (r *memoryLeaseRepository) ReleaseLease(ctx context.Context, name, holderID string, token int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lease != nil && r.lease.HolderID == holderID && r.lease.Token == token {
+		r.lease.ExpiresAt = time.Now()
+	}
+	return nil
+}
+
+func (r *memoryLeaseRepository) GetLease(ctx context.Context, name string) (*entity.Lease, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lease == nil {
+		return nil, nil
+	}
+	copied := *r.lease
+	return &copied, nil
+}
+
+func newTestLeaderElection(t *testing.T, repo *memoryLeaseRepository, instanceID string) *LeaderElectionService {
+	cfg := &config.Config{
+		Ethereum: config.EthereumConfig{Network: "ethereum"},
+		LeaderElection: config.LeaderElectionConfig{
+			Enabled:       true,
+			LeaseName:     "scheduler",
+			LeaseTTL:      300 * time.Millisecond,
+			RenewInterval: 50 * time.Millisecond,
+			InstanceID:    instanceID,
+		},
+	}
+	return NewLeaderElectionService(repo, cfg, newTestLogger(t))
+}
+
+// newTestLeader returns an election that holds the lease with the given token
+func newTestLeader(t *testing.T, token int64) *LeaderElectionService {
+	election := newTestLeaderElection(t, &memoryLeaseRepository{}, "leader")
+	election.isLeader = true
+	election.token = token
+	election.validUntil = time.Now().Add(time.Hour)
+	return election
+}
+
+func TestLeaderElectionService_Failover(t *testing.T) {
+	repo := &memoryLeaseRepository{}
+	first := newTestLeaderElection(t, repo, "first")
+	second := newTestLeaderElection(t, repo, "second")
+
+	var mu sync.Mutex
+	var events []string
+	callbacks := func(name string) LeaderCallbacks {
+		return LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) error {
+				mu.Lock()
+				events = append(events, name+" started")
+				mu.Unlock()
+				return nil
+			},
+			OnStoppedLeading: func() {
+				mu.Lock()
+				events = append(events, name+" stopped")
+				mu.Unlock()
+			},
+		}
+	}
+
+	ctx := context.Background()
+	require.NoError(t, first.Start(ctx, callbacks("first")))
+	require.Eventually(t, first.IsLeader, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, second.Start(ctx, callbacks("second")))
+	require.Eventually(t, func() bool {
+		return second.GetStats()["leader_id"] == "first"
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, second.IsLeader())
+	assert.Equal(t, int64(1), first.FencingToken())
+
+	// Releasing on stop lets the follower take over before the TTL
+	require.NoError(t, first.Stop(ctx))
+	require.Eventually(t, second.IsLeader, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(2), second.FencingToken())
+	require.NoError(t, second.Stop(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"first started", "first stopped", "second started", "second stopped"}, events)
+}
+
+func TestLeaderElectionService_StepsDownWhenLeaseLost(t *testing.T) {
+	repo := &memoryLeaseRepository{}
+	election := newTestLeaderElection(t, repo, "first")
+
+	stopped := make(chan struct{})
+	require.NoError(t, election.Start(context.Background(), LeaderCallbacks{
+		OnStoppedLeading: func() { close(stopped) },
+	}))
+	require.Eventually(t, election.IsLeader, time.Second, 10*time.Millisecond)
+
+	// Another instance takes the lease, e.g. after this one was paused past the TTL
+	repo.mu.Lock()
+	repo.lease.HolderID = "other"
+	repo.lease.Token++
+	repo.mu.Unlock()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("leader did not step down")
+	}
+	assert.False(t, election.IsLeader())
+	require.NoError(t, election.Stop(context.Background()))
+}
+
+func TestLeaderElectionService_RenewsWhileStarting(t *testing.T) {
+	repo := &memoryLeaseRepository{}
+	election := newTestLeaderElection(t, repo, "first")
+	other := newTestLeaderElection(t, repo, "second")
+
+	// Starting takes longer than the lease TTL, e.g. slow websocket dials
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var events []string
+	require.NoError(t, election.Start(context.Background(), LeaderCallbacks{
+		OnStartedLeading: func(ctx context.Context) error {
+			close(started)
+			<-release
+			events = append(events, "started")
+			return nil
+		},
+		OnStoppedLeading: func() { events = append(events, "stopped") },
+	}))
+	<-started
+
+	time.Sleep(2 * election.leaseTTL)
+	assert.True(t, election.IsLeader(), "the lease is renewed during startup")
+	lease, err := repo.GetLease(context.Background(), "")
+	require.NoError(t, err)
+	assert.True(t, time.Now().Before(lease.ExpiresAt))
+
+	require.NoError(t, other.Start(context.Background(), LeaderCallbacks{}))
+	time.Sleep(2 * other.renewInterval)
+	assert.False(t, other.IsLeader())
+	require.NoError(t, other.Stop(context.Background()))
+
+	// Stopping waits for the start to finish before stopping the leader
+	close(release)
+	require.NoError(t, election.Stop(context.Background()))
+	assert.Equal(t, []string{"started", "stopped"}, events)
+}
+
+func TestLeaderElectionService_Disabled(t *testing.T) {
+	election := NewLeaderElectionService(&memoryLeaseRepository{}, &config.Config{}, newTestLogger(t))
+
+	started := false
+	require.NoError(t, election.Start(context.Background(), LeaderCallbacks{
+		OnStartedLeading: func(ctx context.Context) error {
+			started = true
+			return nil
+		},
+	}))
+
+	assert.True(t, started)
+	assert.True(t, election.IsLeader())
+	assert.Equal(t, entity.HealthStatusHealthy, election.HealthCheck(context.Background()).Status)
+}
@@ -0,0 +1,508 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidCrawlRequest is returned for malformed crawl requests
+	ErrInvalidCrawlRequest = errors.New("invalid crawl request")
+
+	// ErrCrawlQueueFull is returned when the priority queue has no room left
+	ErrCrawlQueueFull = errors.New("priority crawl queue is full")
+
+	// ErrPriorityCrawlStopped is returned when requests arrive while the service is stopped
+	ErrPriorityCrawlStopped = errors.New("priority crawl service is not running")
+
+	txHashPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+)
+
+// maxRetainedCrawlJobs bounds the finished jobs kept for status lookups
+const maxRetainedCrawlJobs = 1000
+
+// priorityCrawler processes on-demand work ahead of routine work, implemented by CrawlerService
+type priorityCrawler interface {
+	ProcessPriorityBlock(ctx context.Context, blockNumber *big.Int) error
+	RefetchTransaction(ctx context.Context, txHash string) (*entity.Transaction, error)
+}
+
+// CrawlJobType is the kind of work a crawl job asks for
+type CrawlJobType string
+
+const (
+	CrawlJobBlock       CrawlJobType = "block"
+	CrawlJobRange       CrawlJobType = "range"
+	CrawlJobTransaction CrawlJobType = "transaction"
+)
+
+// CrawlJobStatus is the state of a crawl job
+type CrawlJobStatus string
+
+const (
+	CrawlJobQueued    CrawlJobStatus = "queued"
+	CrawlJobRunning   CrawlJobStatus = "running"
+	CrawlJobCompleted CrawlJobStatus = "completed"
+	CrawlJobFailed    CrawlJobStatus = "failed"
+)
+
+// CrawlRequest asks for a block, a block range or a transaction to be crawled
+// now. Exactly one of Block, FromBlock/ToBlock or TxHash is set.
+type CrawlRequest struct {
+	Block     *uint64 `json:"block,omitempty"`
+	FromBlock *uint64 `json:"from_block,omitempty"`
+	ToBlock   *uint64 `json:"to_block,omitempty"`
+	TxHash    string  `json:"tx_hash,omitempty"`
+}
+
+// CrawlJobResult describes a crawl job and, once it finished, its outcome
+type CrawlJobResult struct {
+	ID              string         `json:"id"`
+	Type            CrawlJobType   `json:"type"`
+	Source          string         `json:"source"` // admin, nats
+	FromBlock       uint64         `json:"from_block,omitempty"`
+	ToBlock         uint64         `json:"to_block,omitempty"`
+	TxHash          string         `json:"tx_hash,omitempty"`
+	Status          CrawlJobStatus `json:"status"`
+	BlocksProcessed int            `json:"blocks_processed"`
+	FailedBlocks    []uint64       `json:"failed_blocks,omitempty"`
+	TxBlockNumber   string         `json:"tx_block_number,omitempty"` // Block of a refetched transaction
+	Error           string         `json:"error,omitempty"`
+	SubmittedAt     time.Time      `json:"submitted_at"`
+	StartedAt       *time.Time     `json:"started_at,omitempty"`
+	CompletedAt     *time.Time     `json:"completed_at,omitempty"`
+	Duration        time.Duration  `json:"duration,omitempty"`
+}
+
+// IsDone reports whether the job finished
+func (r *CrawlJobResult) IsDone() bool {
+	return r.Status == CrawlJobCompleted || r.Status == CrawlJobFailed
+}
+
+// crawlJob is a queued job and the channel closed when it finishes
+type crawlJob struct {
+	result CrawlJobResult
+	done   chan struct{}
+}
+
+// PriorityCrawlService runs on-demand crawl jobs (a block, a block range or a
+// transaction refetch) from support engineers. Jobs are queued in memory,
+// take worker slots ahead of the scheduler's routine work and report their
+// outcome to whoever waits for them.
+type PriorityCrawlService struct {
+	crawler priorityCrawler
+	logger  *logger.Logger
+
+	workers        int
+	concurrency    int // Blocks of a range job processed at the same time
+	maxRangeSize   int
+	requestTimeout time.Duration
+
+	queue    chan *crawlJob
+	jobs     map[string]*crawlJob
+	finished []string // Finished job IDs, oldest first
+
+	isRunning bool
+	stopChan  chan struct{}
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+
+	// Statistics
+	jobsSubmitted int64
+	jobsCompleted int64
+	jobsFailed    int64
+	jobsRejected  int64
+}
+
+// NewPriorityCrawlService creates a new priority crawl service
+func NewPriorityCrawlService(
+	crawlerService *CrawlerService,
+	config *config.Config,
+	logger *logger.Logger,
+) *PriorityCrawlService {
+	return newPriorityCrawlService(crawlerService, config, logger)
+}
+
+func newPriorityCrawlService(crawler priorityCrawler, config *config.Config, logger *logger.Logger) *PriorityCrawlService {
+	s := &PriorityCrawlService{
+		crawler:        crawler,
+		logger:         logger.WithComponent("priority-crawl-service"),
+		workers:        2,
+		concurrency:    config.Crawler.ConcurrentWorkers,
+		maxRangeSize:   1000,
+		requestTimeout: 5 * time.Minute,
+		jobs:           make(map[string]*crawlJob),
+	}
+
+	queueSize := 100
+	if config.PriorityCrawl.Workers > 0 {
+		s.workers = config.PriorityCrawl.Workers
+	}
+	if config.PriorityCrawl.QueueSize > 0 {
+		queueSize = config.PriorityCrawl.QueueSize
+	}
+	if config.PriorityCrawl.MaxRangeSize > 0 {
+		s.maxRangeSize = config.PriorityCrawl.MaxRangeSize
+	}
+	if config.PriorityCrawl.RequestTimeout > 0 {
+		s.requestTimeout = config.PriorityCrawl.RequestTimeout
+	}
+	if s.concurrency <= 0 {
+		s.concurrency = 1
+	}
+	s.queue = make(chan *crawlJob, queueSize)
+
+	return s
+}
+
+// Start starts the job workers
+func (s *PriorityCrawlService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRunning {
+		return fmt.Errorf("priority crawl service is already running")
+	}
+
+	// Workers outlive the start context, Stop cancels jobs in progress
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.cancel = cancel
+	s.stopChan = make(chan struct{})
+	s.isRunning = true
+
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.jobWorker(runCtx, s.stopChan)
+	}
+
+	s.logger.Info("Priority crawl service started",
+		zap.Int("workers", s.workers),
+		zap.Int("queue_size", cap(s.queue)),
+		zap.Int("max_range_size", s.maxRangeSize))
+
+	return nil
+}
+
+// Stop cancels jobs in progress and fails the queued ones
+func (s *PriorityCrawlService) Stop() error {
+	s.mu.Lock()
+	if !s.isRunning {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.stopChan)
+	s.cancel()
+	s.isRunning = false
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	// Nobody will pick the queued jobs up, tell their requesters
+	for {
+		select {
+		case job := <-s.queue:
+			s.finish(job, ErrPriorityCrawlStopped)
+		default:
+			s.logger.Info("Priority crawl service stopped")
+			return nil
+		}
+	}
+}
+
+// RequestTimeout is how long a requester should wait for a job
+func (s *PriorityCrawlService) RequestTimeout() time.Duration {
+	return s.requestTimeout
+}
+
+// Submit validates a request and queues it ahead of routine work
+func (s *PriorityCrawlService) Submit(request CrawlRequest, source string) (*CrawlJobResult, error) {
+	job, err := s.newJob(request, source)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isRunning {
+		s.jobsRejected++
+		return nil, ErrPriorityCrawlStopped
+	}
+
+	select {
+	case s.queue <- job:
+	default:
+		s.jobsRejected++
+		return nil, ErrCrawlQueueFull
+	}
+
+	s.jobs[job.result.ID] = job
+	s.jobsSubmitted++
+
+	s.logger.Info("Crawl job queued",
+		zap.String("job_id", job.result.ID),
+		zap.String("type", string(job.result.Type)),
+		zap.String("source", source),
+		zap.Uint64("from_block", job.result.FromBlock),
+		zap.Uint64("to_block", job.result.ToBlock),
+		zap.String("tx_hash", job.result.TxHash))
+
+	result := job.result
+	return &result, nil
+}
+
+// Wait waits for a job to finish and returns its outcome
+func (s *PriorityCrawlService) Wait(ctx context.Context, id string) (*CrawlJobResult, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("crawl job %s not found", id)
+	}
+
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return s.GetJob(id)
+}
+
+// GetJob returns the current state of a queued, running or recently finished job
+func (s *PriorityCrawlService) GetJob(id string) (*CrawlJobResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("crawl job %s not found", id)
+	}
+
+	result := job.result
+	result.FailedBlocks = append([]uint64(nil), job.result.FailedBlocks...)
+	return &result, nil
+}
+
+// GetStats returns queue statistics
+func (s *PriorityCrawlService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]interface{}{
+		"is_running":     s.isRunning,
+		"workers":        s.workers,
+		"queued":         len(s.queue),
+		"queue_size":     cap(s.queue),
+		"jobs_submitted": s.jobsSubmitted,
+		"jobs_completed": s.jobsCompleted,
+		"jobs_failed":    s.jobsFailed,
+		"jobs_rejected":  s.jobsRejected,
+	}
+}
+
+// newJob validates a request and builds its job
+func (s *PriorityCrawlService) newJob(request CrawlRequest, source string) (*crawlJob, error) {
+	result := CrawlJobResult{
+		ID:          primitive.NewObjectID().Hex(),
+		Source:      source,
+		Status:      CrawlJobQueued,
+		SubmittedAt: time.Now(),
+	}
+
+	isRange := request.FromBlock != nil || request.ToBlock != nil
+	set := 0
+	for _, ok := range []bool{request.Block != nil, isRange, request.TxHash != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("%w: exactly one of block, from_block/to_block or tx_hash is required", ErrInvalidCrawlRequest)
+	}
+
+	switch {
+	case request.Block != nil:
+		result.Type = CrawlJobBlock
+		result.FromBlock = *request.Block
+		result.ToBlock = *request.Block
+	case isRange:
+		if request.FromBlock == nil || request.ToBlock == nil {
+			return nil, fmt.Errorf("%w: a range needs both from_block and to_block", ErrInvalidCrawlRequest)
+		}
+		if *request.FromBlock > *request.ToBlock {
+			return nil, fmt.Errorf("%w: from_block %d is after to_block %d", ErrInvalidCrawlRequest, *request.FromBlock, *request.ToBlock)
+		}
+		if size := *request.ToBlock - *request.FromBlock + 1; size > uint64(s.maxRangeSize) {
+			return nil, fmt.Errorf("%w: range of %d blocks exceeds the limit of %d", ErrInvalidCrawlRequest, size, s.maxRangeSize)
+		}
+		result.Type = CrawlJobRange
+		result.FromBlock = *request.FromBlock
+		result.ToBlock = *request.ToBlock
+	default:
+		if !txHashPattern.MatchString(request.TxHash) {
+			return nil, fmt.Errorf("%w: tx_hash %q is not a transaction hash", ErrInvalidCrawlRequest, request.TxHash)
+		}
+		result.Type = CrawlJobTransaction
+		result.TxHash = request.TxHash
+	}
+
+	return &crawlJob{result: result, done: make(chan struct{})}, nil
+}
+
+// jobWorker runs queued jobs one at a time
+func (s *PriorityCrawlService) jobWorker(ctx context.Context, stopChan chan struct{}) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case job := <-s.queue:
+			s.runJob(ctx, job)
+		}
+	}
+}
+
+// runJob processes a job and records its outcome
+func (s *PriorityCrawlService) runJob(ctx context.Context, job *crawlJob) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic recovered in crawl job",
+				zap.String("job_id", job.result.ID),
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+			err = fmt.Errorf("panic: %v", r)
+		}
+		s.finish(job, err)
+	}()
+
+	// A job taken while the service stops is failed like the queued ones
+	if ctx.Err() != nil {
+		err = ErrPriorityCrawlStopped
+		return
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	job.result.Status = CrawlJobRunning
+	job.result.StartedAt = &now
+	jobType, fromBlock, toBlock, txHash := job.result.Type, job.result.FromBlock, job.result.ToBlock, job.result.TxHash
+	s.mu.Unlock()
+
+	switch jobType {
+	case CrawlJobTransaction:
+		var tx *entity.Transaction
+		tx, err = s.crawler.RefetchTransaction(ctx, txHash)
+		if err == nil {
+			s.mu.Lock()
+			job.result.TxBlockNumber = tx.BlockNumber
+			s.mu.Unlock()
+		}
+	default:
+		err = s.processBlocks(ctx, job, fromBlock, toBlock)
+	}
+}
+
+// processBlocks processes the blocks of a block or range job
+func (s *PriorityCrawlService) processBlocks(ctx context.Context, job *crawlJob, fromBlock, toBlock uint64) error {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, s.concurrency)
+
+	for n := fromBlock; n <= toBlock; n++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(blockNumber uint64) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			err := s.crawler.ProcessPriorityBlock(ctx, new(big.Int).SetUint64(blockNumber))
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if err != nil {
+				job.result.FailedBlocks = append(job.result.FailedBlocks, blockNumber)
+				s.logger.Warn("Priority block failed",
+					zap.String("job_id", job.result.ID),
+					zap.Uint64("block_number", blockNumber),
+					zap.Error(err))
+				return
+			}
+			job.result.BlocksProcessed++
+		}(n)
+
+		if n == toBlock {
+			break // n++ would wrap around at the top of the range
+		}
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	failed := len(job.result.FailedBlocks)
+	s.mu.Unlock()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d blocks failed", failed, toBlock-fromBlock+1)
+	}
+	return nil
+}
+
+// finish records a job outcome, wakes its waiters and forgets the oldest finished jobs
+func (s *PriorityCrawlService) finish(job *crawlJob, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	job.result.CompletedAt = &now
+	if job.result.StartedAt != nil {
+		job.result.Duration = now.Sub(*job.result.StartedAt)
+	}
+	slices.Sort(job.result.FailedBlocks)
+
+	if err != nil {
+		job.result.Status = CrawlJobFailed
+		job.result.Error = err.Error()
+		s.jobsFailed++
+		s.logger.Warn("Crawl job failed",
+			zap.String("job_id", job.result.ID),
+			zap.String("type", string(job.result.Type)),
+			zap.Int("blocks_processed", job.result.BlocksProcessed),
+			zap.Error(err))
+	} else {
+		job.result.Status = CrawlJobCompleted
+		s.jobsCompleted++
+		s.logger.Info("Crawl job completed",
+			zap.String("job_id", job.result.ID),
+			zap.String("type", string(job.result.Type)),
+			zap.Int("blocks_processed", job.result.BlocksProcessed),
+			zap.Duration("duration", job.result.Duration))
+	}
+	close(job.done)
+
+	s.finished = append(s.finished, job.result.ID)
+	for len(s.finished) > maxRetainedCrawlJobs {
+		delete(s.jobs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
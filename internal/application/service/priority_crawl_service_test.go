@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePriorityCrawler records priority work and fails the configured blocks
+type fakePriorityCrawler struct {
+	mu        sync.Mutex
+	processed map[uint64]int
+	fail      map[uint64]bool
+	refetched []string
+	block     chan struct{}
+}
+
+func (c *fakePriorityCrawler) ProcessPriorityBlock(ctx context.Context, blockNumber *big.Int) error {
+	if c.block != nil {
+		select {
+		case <-c.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail[blockNumber.Uint64()] {
+		return errors.New("rpc timeout")
+	}
+	c.processed[blockNumber.Uint64()]++
+	return nil
+}
+
+func (c *fakePriorityCrawler) RefetchTransaction(ctx context.Context, txHash string) (*entity.Transaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refetched = append(c.refetched, txHash)
+	return &entity.Transaction{Hash: txHash, BlockNumber: "42"}, nil
+}
+
+func newTestPriorityCrawlService(t *testing.T, crawler *fakePriorityCrawler, queueSize int) *PriorityCrawlService {
+	cfg := &config.Config{
+		Crawler: config.CrawlerConfig{ConcurrentWorkers: 3},
+		PriorityCrawl: config.PriorityCrawlConfig{
+			Workers:      1,
+			QueueSize:    queueSize,
+			MaxRangeSize: 10,
+		},
+	}
+	s := newPriorityCrawlService(crawler, cfg, newTestLogger(t))
+	require.NoError(t, s.Start(context.Background()))
+	t.Cleanup(func() { s.Stop() })
+	return s
+}
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
+
+func TestPriorityCrawlService_Jobs(t *testing.T) {
+	crawler := &fakePriorityCrawler{processed: map[uint64]int{}, fail: map[uint64]bool{104: true}}
+	s := newTestPriorityCrawlService(t, crawler, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	queued, err := s.Submit(CrawlRequest{Block: uint64Ptr(7)}, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, CrawlJobBlock, queued.Type)
+	result, err := s.Wait(ctx, queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, CrawlJobCompleted, result.Status)
+	assert.Equal(t, 1, result.BlocksProcessed)
+	assert.NotNil(t, result.CompletedAt)
+
+	queued, err = s.Submit(CrawlRequest{FromBlock: uint64Ptr(100), ToBlock: uint64Ptr(105)}, "nats")
+	require.NoError(t, err)
+	result, err = s.Wait(ctx, queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, CrawlJobFailed, result.Status)
+	assert.Equal(t, 5, result.BlocksProcessed)
+	assert.Equal(t, []uint64{104}, result.FailedBlocks)
+	assert.Contains(t, result.Error, "1 of 6 blocks failed")
+	for n := uint64(100); n <= 105; n++ {
+		if n != 104 {
+			assert.Equal(t, 1, crawler.processed[n], "block %d", n)
+		}
+	}
+
+	hash := "0x" + "ab12cd34ef56ab12cd34ef56ab12cd34ef56ab12cd34ef56ab12cd34ef56ab12"
+	queued, err = s.Submit(CrawlRequest{TxHash: hash}, "admin")
+	require.NoError(t, err)
+	result, err = s.Wait(ctx, queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, CrawlJobCompleted, result.Status)
+	assert.Equal(t, "42", result.TxBlockNumber)
+	assert.Equal(t, []string{hash}, crawler.refetched)
+
+	stats := s.GetStats()
+	assert.Equal(t, int64(3), stats["jobs_submitted"])
+	assert.Equal(t, int64(2), stats["jobs_completed"])
+	assert.Equal(t, int64(1), stats["jobs_failed"])
+}
+
+func TestPriorityCrawlService_InvalidRequests(t *testing.T) {
+	s := newTestPriorityCrawlService(t, &fakePriorityCrawler{processed: map[uint64]int{}}, 10)
+
+	requests := map[string]CrawlRequest{
+		"empty":          {},
+		"block and hash": {Block: uint64Ptr(1), TxHash: "0x" + "00"},
+		"open range":     {FromBlock: uint64Ptr(1)},
+		"reversed range": {FromBlock: uint64Ptr(5), ToBlock: uint64Ptr(4)},
+		"range too big":  {FromBlock: uint64Ptr(1), ToBlock: uint64Ptr(11)},
+		"bad hash":       {TxHash: "0x1234"},
+	}
+	for name, request := range requests {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.Submit(request, "admin")
+			assert.ErrorIs(t, err, ErrInvalidCrawlRequest)
+		})
+	}
+}
+
+func TestPriorityCrawlService_QueueFullAndStop(t *testing.T) {
+	crawler := &fakePriorityCrawler{processed: map[uint64]int{}, block: make(chan struct{})}
+	s := newTestPriorityCrawlService(t, crawler, 1)
+
+	// The worker holds the first job, the second fills the queue
+	running, err := s.Submit(CrawlRequest{Block: uint64Ptr(1)}, "admin")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		job, err := s.GetJob(running.ID)
+		return err == nil && job.Status == CrawlJobRunning
+	}, time.Second, 5*time.Millisecond)
+
+	queued, err := s.Submit(CrawlRequest{Block: uint64Ptr(2)}, "admin")
+	require.NoError(t, err)
+
+	_, err = s.Submit(CrawlRequest{Block: uint64Ptr(3)}, "admin")
+	assert.ErrorIs(t, err, ErrCrawlQueueFull)
+
+	// Stopping cancels the running job and fails the queued one
+	require.NoError(t, s.Stop())
+	for _, id := range []string{running.ID, queued.ID} {
+		job, err := s.GetJob(id)
+		require.NoError(t, err)
+		assert.Equal(t, CrawlJobFailed, job.Status)
+	}
+	job, _ := s.GetJob(queued.ID)
+	assert.Equal(t, ErrPriorityCrawlStopped.Error(), job.Error)
+
+	_, err = s.Submit(CrawlRequest{Block: uint64Ptr(4)}, "admin")
+	assert.ErrorIs(t, err, ErrPriorityCrawlStopped)
+}
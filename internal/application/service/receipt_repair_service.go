@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errReorgedReceipt is returned for a receipt from another block than the
+// stored one, which was reorged out
+var errReorgedReceipt = errors.New("receipt is from another block")
+
+// ReceiptRepairService fetches the receipts that were missing when a block was
+// crawled, patches the stored transactions and marks the block processed once
+// every receipt is present. Repaired transactions are published then, they
+// were held back while their outcome was unknown. A block reorged out is
+// deleted and queued for re-crawl.
+type ReceiptRepairService struct {
+	blockRepo         repository.BlockRepository
+	txRepo            repository.TransactionRepository
+	blockchainService service.BlockchainService
+	messagingService  service.MessagingService
+	retryService      *RetryService
+	logger            *logger.Logger
+	network           string
+
+	interval  time.Duration
+	batchSize int
+	maxDelay  time.Duration // Longest wait before a block failing repair is tried again
+
+	// Stores the token transfers of repaired transactions, nil when disabled
+	transfers *TokenTransferService
+
+	isRunning bool
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+
+	// Statistics
+	incompleteBlocks int64 // As of the last check
+	blocksRepaired   int64
+	receiptsRepaired int64
+	receiptsFailed   int64
+	blocksRequeued   int64
+	lastCheck        time.Time
+}
+
+// NewReceiptRepairService creates a new receipt repair service. retryService
+// may be nil, reorged blocks are then left incomplete.
+func NewReceiptRepairService(
+	blockRepo repository.BlockRepository,
+	txRepo repository.TransactionRepository,
+	blockchainService service.BlockchainService,
+	messagingService service.MessagingService,
+	retryService *RetryService,
+	config *config.Config,
+	logger *logger.Logger,
+) *ReceiptRepairService {
+	s := &ReceiptRepairService{
+		blockRepo:         blockRepo,
+		txRepo:            txRepo,
+		blockchainService: blockchainService,
+		messagingService:  messagingService,
+		retryService:      retryService,
+		logger:            logger.WithComponent("receipt-repair-service"),
+		network:           config.Ethereum.Network,
+		interval:          time.Minute,
+		batchSize:         20,
+		maxDelay:          time.Hour,
+	}
+
+	if config.ReceiptRepair.Interval > 0 {
+		s.interval = config.ReceiptRepair.Interval
+	}
+	if config.ReceiptRepair.BatchSize > 0 {
+		s.batchSize = config.ReceiptRepair.BatchSize
+	}
+	if config.ReceiptRepair.MaxDelay > 0 {
+		s.maxDelay = config.ReceiptRepair.MaxDelay
+	}
+
+	return s
+}
+
+// SetTokenTransferService makes the repair store and publish the token
+// transfers of the repaired transactions
+func (s *ReceiptRepairService) SetTokenTransferService(transfers *TokenTransferService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfers = transfers
+}
+
+// tokenTransferService returns the token transfer service, nil when not set
+func (s *ReceiptRepairService) tokenTransferService() *TokenTransferService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transfers
+}
+
+// Start starts the repair worker
+func (s *ReceiptRepairService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRunning {
+		return fmt.Errorf("receipt repair service is already running")
+	}
+
+	s.stopChan = make(chan struct{})
+	s.isRunning = true
+
+	// The worker outlives the start context and is stopped through stopChan
+	s.wg.Add(1)
+	go s.repairWorker(context.WithoutCancel(ctx), s.stopChan)
+
+	s.logger.Info("Receipt repair service started",
+		zap.Duration("interval", s.interval),
+		zap.Int("batch_size", s.batchSize),
+		zap.Duration("max_delay", s.maxDelay))
+
+	return nil
+}
+
+// Stop stops the repair worker and waits for the current batch to finish
+func (s *ReceiptRepairService) Stop() error {
+	s.mu.Lock()
+	if !s.isRunning {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.stopChan)
+	s.isRunning = false
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.logger.Info("Receipt repair service stopped")
+	return nil
+}
+
+// GetStats returns repair statistics
+func (s *ReceiptRepairService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]interface{}{
+		"is_running":        s.isRunning,
+		"incomplete_blocks": s.incompleteBlocks,
+		"blocks_repaired":   s.blocksRepaired,
+		"receipts_repaired": s.receiptsRepaired,
+		"receipts_failed":   s.receiptsFailed,
+		"blocks_requeued":   s.blocksRequeued,
+		"last_check":        s.lastCheck,
+	}
+}
+
+// RepairIncompleteBlocks repairs one batch of the incomplete blocks due for a
+// repair and returns how many of them are complete now. A block still
+// incomplete is tried again after a delay doubling with every attempt, so
+// blocks that keep failing don't fill every batch.
+func (s *ReceiptRepairService) RepairIncompleteBlocks(ctx context.Context, stopChan chan struct{}) (int, error) {
+	blocks, err := s.blockRepo.GetBlocksDueForRepair(ctx, s.network, time.Now(), s.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get incomplete blocks: %w", err)
+	}
+
+	repaired := 0
+	for _, block := range blocks {
+		select {
+		case <-stopChan:
+			return repaired, nil
+		default:
+		}
+
+		complete, err := s.repairBlock(ctx, block)
+		if err != nil {
+			s.logger.Warn("Failed to repair block",
+				zap.String("block_number", block.Number),
+				zap.Error(err))
+		}
+		if complete {
+			repaired++
+			continue
+		}
+		s.scheduleRepair(ctx, block)
+	}
+
+	incomplete, err := s.blockRepo.GetBlockCountByStatus(ctx, entity.BlockStatusIncomplete, s.network)
+	if err != nil {
+		return repaired, fmt.Errorf("failed to count incomplete blocks: %w", err)
+	}
+
+	s.mu.Lock()
+	s.incompleteBlocks = incomplete
+	s.blocksRepaired += int64(repaired)
+	s.lastCheck = time.Now()
+	s.mu.Unlock()
+
+	if len(blocks) > 0 || incomplete > 0 {
+		s.logger.Info("Receipt repair check finished",
+			zap.Int("checked_blocks", len(blocks)),
+			zap.Int("repaired_blocks", repaired),
+			zap.Int64("incomplete_blocks", incomplete))
+	}
+
+	return repaired, nil
+}
+
+// scheduleRepair defers the next repair of a block still incomplete, a
+// requeued block is gone and left alone by the update
+func (s *ReceiptRepairService) scheduleRepair(ctx context.Context, block *entity.Block) {
+	attempts := block.RepairAttempts + 1
+	delay := retryBackoff(s.interval, s.maxDelay, attempts)
+	if err := s.blockRepo.ScheduleBlockRepair(ctx, block.Hash, attempts, time.Now().Add(delay)); err != nil {
+		s.logger.Warn("Failed to schedule the next repair of block",
+			zap.String("block_number", block.Number),
+			zap.Error(err))
+		return
+	}
+
+	s.logger.Debug("Block repair deferred",
+		zap.String("block_number", block.Number),
+		zap.Int("attempts", attempts),
+		zap.Duration("delay", delay))
+}
+
+// repairBlock fetches the missing receipts of a block and reports whether it is complete now
+func (s *ReceiptRepairService) repairBlock(ctx context.Context, block *entity.Block) (bool, error) {
+	logger := s.logger.With(zap.String("block_number", block.Number), zap.String("block_hash", block.Hash))
+
+	transactions, err := s.txRepo.GetTransactionsByBlockHash(ctx, block.Hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	transferService := s.tokenTransferService()
+	var repaired []*entity.Transaction
+	var transfers []*entity.TokenTransfer
+	remaining := 0
+	for _, tx := range transactions {
+		if !tx.ReceiptMissing {
+			continue
+		}
+
+		// Token transfers are stored first, the transaction stays missing its
+		// receipt until they are
+		fetched, err := s.fetchReceipt(ctx, tx)
+		if errors.Is(err, errReorgedReceipt) && s.retryService != nil {
+			// The whole block is stale, nothing of it is published
+			if err := s.requeueBlock(ctx, block, err); err != nil {
+				return false, fmt.Errorf("failed to queue reorged block: %w", err)
+			}
+			logger.Warn("Block was reorged out, queued for re-crawl", zap.String("tx_hash", tx.Hash), zap.Error(err))
+			return false, nil
+		}
+		var txTransfers []*entity.TokenTransfer
+		if err == nil && transferService != nil {
+			txTransfers, err = transferService.RecordBlock(ctx, []*entity.Transaction{fetched})
+		}
+		if err == nil {
+			err = s.txRepo.UpdateTransactionReceipt(ctx, fetched)
+		}
+		if err != nil {
+			remaining++
+			logger.Warn("Failed to repair receipt", zap.String("tx_hash", tx.Hash), zap.Error(err))
+			continue
+		}
+		repaired = append(repaired, fetched)
+		transfers = append(transfers, txTransfers...)
+	}
+
+	s.mu.Lock()
+	s.receiptsRepaired += int64(len(repaired))
+	s.receiptsFailed += int64(remaining)
+	s.mu.Unlock()
+
+	s.publish(ctx, repaired, logger)
+	if transferService != nil {
+		if err := transferService.Publish(ctx, transfers); err != nil {
+			logger.Warn("Failed to publish token transfers of repaired transactions",
+				zap.Int("transfer_count", len(transfers)),
+				zap.Error(err))
+		}
+	}
+
+	if remaining > 0 {
+		logger.Info("Block still has missing receipts",
+			zap.Int("repaired", len(repaired)),
+			zap.Int("remaining", remaining))
+		return false, nil
+	}
+
+	if err := s.blockRepo.MarkBlockAsProcessed(ctx, block.Hash); err != nil {
+		return false, fmt.Errorf("failed to mark block as processed: %w", err)
+	}
+
+	logger.Info("Block receipts repaired", zap.Int("repaired", len(repaired)))
+	return true, nil
+}
+
+// fetchReceipt fetches the receipt of a stored transaction and returns the
+// transaction with its receipt fields filled in
+func (s *ReceiptRepairService) fetchReceipt(ctx context.Context, tx *entity.Transaction) (*entity.Transaction, error) {
+	fetched, err := s.blockchainService.GetTransactionReceipt(ctx, tx.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// A receipt from another block means the stored block was reorged out
+	if !strings.EqualFold(fetched.BlockHash, tx.BlockHash) {
+		return nil, fmt.Errorf("%w: block %s, not %s", errReorgedReceipt, fetched.BlockHash, tx.BlockHash)
+	}
+
+	repaired := *tx
+	repaired.Status = fetched.Status
+	repaired.GasUsed = fetched.GasUsed
+	repaired.CumulativeGasUsed = fetched.CumulativeGasUsed
+	repaired.ContractAddress = fetched.ContractAddress
+	repaired.TokenTransfers = fetched.TokenTransfers
+	repaired.TxStatus = fetched.TxStatus
+	repaired.ReceiptMissing = false
+	return &repaired, nil
+}
+
+// requeueBlock queues a reorged block in the retry queue, then deletes it with
+// its transactions and token transfers so the crawler stores the canonical
+// block of its height
+func (s *ReceiptRepairService) requeueBlock(ctx context.Context, block *entity.Block, reason error) error {
+	number, err := strconv.ParseUint(block.Number, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid block number %q: %w", block.Number, err)
+	}
+
+	// Queued first, a failed delete is then repaired by the re-crawl
+	if err := s.retryService.Enqueue(ctx, number, "receipt repair: "+reason.Error()); err != nil {
+		return err
+	}
+	if transfers := s.tokenTransferService(); transfers != nil {
+		if err := transfers.DeleteBlock(ctx, block.Hash); err != nil {
+			return err
+		}
+	}
+	if err := s.txRepo.DeleteTransactionsByBlockHash(ctx, block.Hash); err != nil {
+		return fmt.Errorf("failed to delete transactions: %w", err)
+	}
+	if err := s.blockRepo.DeleteBlock(ctx, block.Hash); err != nil {
+		return fmt.Errorf("failed to delete block: %w", err)
+	}
+
+	s.mu.Lock()
+	s.blocksRequeued++
+	s.mu.Unlock()
+	return nil
+}
+
+// publish publishes repaired transactions, a failure doesn't undo the repair
+func (s *ReceiptRepairService) publish(ctx context.Context, transactions []*entity.Transaction, logger *logger.Logger) {
+	if len(transactions) == 0 || s.messagingService == nil || !s.messagingService.IsConnected() {
+		return
+	}
+
+	if err := s.messagingService.PublishTransactions(ctx, transactions); err != nil {
+		logger.Warn("Failed to publish repaired transactions",
+			zap.Int("transaction_count", len(transactions)),
+			zap.Error(err))
+	}
+}
+
+// repairWorker periodically repairs incomplete blocks
+func (s *ReceiptRepairService) repairWorker(ctx context.Context, stopChan chan struct{}) {
+	defer s.wg.Done()
+
+	// Add panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic recovered in repairWorker",
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.RepairIncompleteBlocks(ctx, stopChan); err != nil {
+				s.logger.Error("Failed to repair incomplete blocks", zap.Error(err))
+			}
+		}
+	}
+}
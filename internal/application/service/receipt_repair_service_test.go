@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryReceiptStore keeps blocks and transactions for the receipt repair tests
+type memoryReceiptStore struct {
+	repository.BlockRepository
+	blocks  []*entity.Block
+	elapsed time.Duration // Added to the time of the repair checks
+}
+
+// GetBlocksDueForRepair returns the due blocks in store order, the repair
+// order is the adapters' concern
+func (r *memoryReceiptStore) GetBlocksDueForRepair(ctx context.Context, network string, now time.Time, limit int) ([]*entity.Block, error) {
+	now = now.Add(r.elapsed)
+	var blocks []*entity.Block
+	for _, block := range r.blocks {
+		due := block.NextRepairAt == nil || !block.NextRepairAt.After(now)
+		if block.Status == entity.BlockStatusIncomplete && due && len(blocks) < limit {
+			copied := *block
+			blocks = append(blocks, &copied)
+		}
+	}
+	return blocks, nil
+}
+
+func (r *memoryReceiptStore) ScheduleBlockRepair(ctx context.Context, blockHash string, attempts int, nextAttempt time.Time) error {
+	for _, block := range r.blocks {
+		if block.Hash == blockHash {
+			block.RepairAttempts = attempts
+			block.NextRepairAt = &nextAttempt
+		}
+	}
+	return nil
+}
+
+func (r *memoryReceiptStore) GetBlockCountByStatus(ctx context.Context, status entity.BlockStatus, network string) (int64, error) {
+	var count int64
+	for _, block := range r.blocks {
+		if block.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryReceiptStore) DeleteBlock(ctx context.Context, blockHash string) error {
+	for i, block := range r.blocks {
+		if block.Hash == blockHash {
+			r.blocks = append(r.blocks[:i], r.blocks[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *memoryReceiptStore) MarkBlockAsProcessed(ctx context.Context, blockHash string) error {
+	for _, block := range r.blocks {
+		if block.Hash == blockHash {
+			block.Status = entity.BlockStatusProcessed
+		}
+	}
+	return nil
+}
+
+type memoryReceiptTransactions struct {
+	repository.TransactionRepository
+	transactions []*entity.Transaction
+}
+
+func (r *memoryReceiptTransactions) GetTransactionsByBlockHash(ctx context.Context, blockHash string) ([]*entity.Transaction, error) {
+	var transactions []*entity.Transaction
+	for _, tx := range r.transactions {
+		if tx.BlockHash == blockHash {
+			copied := *tx
+			transactions = append(transactions, &copied)
+		}
+	}
+	return transactions, nil
+}
+
+func (r *memoryReceiptTransactions) DeleteTransactionsByBlockHash(ctx context.Context, blockHash string) error {
+	var kept []*entity.Transaction
+	for _, tx := range r.transactions {
+		if tx.BlockHash != blockHash {
+			kept = append(kept, tx)
+		}
+	}
+	r.transactions = kept
+	return nil
+}
+
+func (r *memoryReceiptTransactions) UpdateTransactionReceipt(ctx context.Context, tx *entity.Transaction) error {
+	for i, stored := range r.transactions {
+		if stored.Hash == tx.Hash {
+			copied := *tx
+			r.transactions[i] = &copied
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+// fakeReceiptBlockchain serves receipts for the known transactions
+type fakeReceiptBlockchain struct {
+	service.BlockchainService
+	receipts map[string]*entity.Transaction
+}
+
+func (b *fakeReceiptBlockchain) GetTransactionReceipt(ctx context.Context, txHash string) (*entity.Transaction, error) {
+	receipt, ok := b.receipts[txHash]
+	if !ok {
+		return nil, errors.New("request timed out")
+	}
+	return receipt, nil
+}
+
+func TestReceiptRepairService_RepairIncompleteBlocks(t *testing.T) {
+	blocks := &memoryReceiptStore{blocks: []*entity.Block{
+		{Number: "10", Hash: "0xb10", Status: entity.BlockStatusIncomplete},
+		{Number: "11", Hash: "0xb11", Status: entity.BlockStatusIncomplete},
+		{Number: "12", Hash: "0xb12", Status: entity.BlockStatusProcessed},
+	}}
+	transactions := &memoryReceiptTransactions{transactions: []*entity.Transaction{
+		{Hash: "0x1", BlockHash: "0xb10", Status: 1, GasUsed: 21000, TxStatus: entity.TransactionStatusProcessed},
+		{Hash: "0x2", BlockHash: "0xb10", ReceiptMissing: true, TxStatus: entity.TransactionStatusPending},
+		{Hash: "0x3", BlockHash: "0xb11", ReceiptMissing: true, TxStatus: entity.TransactionStatusPending},
+		{Hash: "0x4", BlockHash: "0xb11", ReceiptMissing: true, TxStatus: entity.TransactionStatusPending},
+	}}
+	blockchain := &fakeReceiptBlockchain{receipts: map[string]*entity.Transaction{
+		"0x2": {Hash: "0x2", BlockHash: "0xB10", Status: 0, GasUsed: 50000, CumulativeGasUsed: 71000, TxStatus: entity.TransactionStatusFailed},
+		"0x3": {Hash: "0x3", BlockHash: "0xb11", Status: 1, GasUsed: 30000, TxStatus: entity.TransactionStatusProcessed},
+		// 0x4 times out
+	}}
+	messaging := &fakeMessagingService{}
+
+	cfg := &config.Config{Ethereum: config.EthereumConfig{Network: "ethereum"}}
+	s := NewReceiptRepairService(blocks, transactions, blockchain, messaging, nil, cfg, newTestLogger(t))
+
+	repaired, err := s.RepairIncompleteBlocks(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+
+	// The failed transaction is no longer recorded as successful
+	tx := transactions.transactions[1]
+	assert.False(t, tx.ReceiptMissing)
+	assert.Equal(t, uint64(0), tx.Status)
+	assert.Equal(t, uint64(50000), tx.GasUsed)
+	assert.Equal(t, entity.TransactionStatusFailed, tx.TxStatus)
+
+	assert.Equal(t, entity.BlockStatusProcessed, blocks.blocks[0].Status)
+	assert.Equal(t, entity.BlockStatusIncomplete, blocks.blocks[1].Status, "0x4 is still missing")
+	assert.False(t, transactions.transactions[2].ReceiptMissing)
+	assert.True(t, transactions.transactions[3].ReceiptMissing)
+	assert.Equal(t, []string{"0x2", "0x3"}, messaging.published)
+
+	stats := s.GetStats()
+	assert.Equal(t, int64(1), stats["incomplete_blocks"])
+	assert.Equal(t, int64(1), stats["blocks_repaired"])
+	assert.Equal(t, int64(2), stats["receipts_repaired"])
+	assert.Equal(t, int64(1), stats["receipts_failed"])
+
+	// The block is due again after the check interval, and completed then
+	assert.Equal(t, 1, blocks.blocks[1].RepairAttempts)
+	blockchain.receipts["0x4"] = &entity.Transaction{Hash: "0x4", BlockHash: "0xb11", Status: 1, TxStatus: entity.TransactionStatusProcessed}
+	blocks.elapsed = time.Minute
+	repaired, err = s.RepairIncompleteBlocks(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+	assert.Equal(t, entity.BlockStatusProcessed, blocks.blocks[1].Status)
+	assert.Equal(t, int64(0), s.GetStats()["incomplete_blocks"])
+}
+
+func TestReceiptRepairService_ReorgedReceipt(t *testing.T) {
+	blocks := &memoryReceiptStore{blocks: []*entity.Block{
+		{Number: "10", Hash: "0xb10", Status: entity.BlockStatusIncomplete},
+	}}
+	transactions := &memoryReceiptTransactions{transactions: []*entity.Transaction{
+		{Hash: "0x1", BlockHash: "0xb10", ReceiptMissing: true},
+	}}
+	blockchain := &fakeReceiptBlockchain{receipts: map[string]*entity.Transaction{
+		"0x1": {Hash: "0x1", BlockHash: "0xother", Status: 1},
+	}}
+
+	cfg := &config.Config{Ethereum: config.EthereumConfig{Network: "ethereum"}}
+	s := NewReceiptRepairService(blocks, transactions, blockchain, nil, nil, cfg, newTestLogger(t))
+
+	repaired, err := s.RepairIncompleteBlocks(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 0, repaired)
+	assert.True(t, transactions.transactions[0].ReceiptMissing, "a receipt from another block is not applied")
+	assert.Equal(t, entity.BlockStatusIncomplete, blocks.blocks[0].Status)
+
+	// With a retry queue the block is deleted and queued for re-crawl
+	blocks.elapsed = time.Minute
+	retryRepo := &fakeBlockRetryRepository{retries: map[uint64]*entity.BlockRetry{}}
+	s = NewReceiptRepairService(blocks, transactions, blockchain, nil, NewRetryService(retryRepo, nil, cfg, newTestLogger(t)), cfg, newTestLogger(t))
+	transfers := newFakeTokenTransferRepository()
+	transfers.transfers["0x1"] = &entity.TokenTransfer{TransactionHash: "0x1", BlockHash: "0xb10"}
+	s.SetTokenTransferService(NewTokenTransferService(transfers, nil, cfg, newTestLogger(t)))
+
+	repaired, err = s.RepairIncompleteBlocks(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 0, repaired)
+	assert.Empty(t, blocks.blocks)
+	assert.Empty(t, transactions.transactions)
+	assert.Empty(t, transfers.transfers)
+	require.Contains(t, retryRepo.retries, uint64(10))
+	assert.Contains(t, retryRepo.retries[10].LastError, "receipt is from another block")
+	assert.Equal(t, int64(1), s.GetStats()["blocks_requeued"])
+	assert.Equal(t, int64(0), s.GetStats()["incomplete_blocks"])
+}
+
+func TestReceiptRepairService_RecordsTokenTransfers(t *testing.T) {
+	blocks := &memoryReceiptStore{blocks: []*entity.Block{
+		{Number: "10", Hash: "0xb10", Status: entity.BlockStatusIncomplete},
+	}}
+	transactions := &memoryReceiptTransactions{transactions: []*entity.Transaction{
+		{Hash: "0x1", BlockHash: "0xb10", ReceiptMissing: true},
+	}}
+	transfer := &entity.TokenTransfer{TransactionHash: "0x1", Standard: entity.TokenStandardERC721, Amount: "1", TokenID: "42"}
+	blockchain := &fakeReceiptBlockchain{receipts: map[string]*entity.Transaction{
+		"0x1": {Hash: "0x1", BlockHash: "0xb10", Status: 1, TokenTransfers: []*entity.TokenTransfer{transfer}},
+	}}
+	messaging := &fakeMessagingService{}
+
+	cfg := &config.Config{
+		Ethereum:       config.EthereumConfig{Network: "ethereum"},
+		TokenTransfers: config.TokenTransfersConfig{Enabled: true, Publish: true},
+	}
+	s := NewReceiptRepairService(blocks, transactions, blockchain, messaging, nil, cfg, newTestLogger(t))
+	transfers := newFakeTokenTransferRepository()
+	transfers.err = errors.New("database unavailable")
+	s.SetTokenTransferService(NewTokenTransferService(transfers, messaging, cfg, newTestLogger(t)))
+
+	// The receipt is not applied until its transfers are stored
+	repaired, err := s.RepairIncompleteBlocks(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 0, repaired)
+	assert.True(t, transactions.transactions[0].ReceiptMissing)
+
+	transfers.err = nil
+	blocks.elapsed = time.Minute
+	repaired, err = s.RepairIncompleteBlocks(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+	assert.False(t, transactions.transactions[0].ReceiptMissing)
+	assert.Len(t, transfers.transfers, 1)
+	assert.Equal(t, []*entity.TokenTransfer{transfer}, messaging.transfers)
+}
+
+func TestReceiptRepairService_DefersFailingBlocks(t *testing.T) {
+	blocks := &memoryReceiptStore{blocks: []*entity.Block{
+		{Number: "10", Hash: "0xb10", Status: entity.BlockStatusIncomplete},
+		{Number: "11", Hash: "0xb11", Status: entity.BlockStatusIncomplete},
+	}}
+	transactions := &memoryReceiptTransactions{transactions: []*entity.Transaction{
+		{Hash: "0x1", BlockHash: "0xb10", ReceiptMissing: true},
+		{Hash: "0x2", BlockHash: "0xb11", ReceiptMissing: true},
+	}}
+	blockchain := &fakeReceiptBlockchain{receipts: map[string]*entity.Transaction{
+		// 0x1 always times out
+		"0x2": {Hash: "0x2", BlockHash: "0xb11", Status: 1},
+	}}
+
+	cfg := &config.Config{
+		Ethereum:      config.EthereumConfig{Network: "ethereum"},
+		ReceiptRepair: config.ReceiptRepairConfig{BatchSize: 1, MaxDelay: 3 * time.Minute},
+	}
+	s := NewReceiptRepairService(blocks, transactions, blockchain, nil, nil, cfg, newTestLogger(t))
+
+	repaired, err := s.RepairIncompleteBlocks(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 0, repaired)
+	assert.Equal(t, 1, blocks.blocks[0].RepairAttempts)
+
+	// The failing block waits, the next check repairs the other one
+	repaired, err = s.RepairIncompleteBlocks(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+	assert.Equal(t, entity.BlockStatusProcessed, blocks.blocks[1].Status)
+
+	// Every failure doubles the wait, up to the maximum delay
+	for attempts := 2; attempts <= 4; attempts++ {
+		blocks.elapsed += time.Hour
+		_, err = s.RepairIncompleteBlocks(context.Background(), make(chan struct{}))
+		require.NoError(t, err)
+		assert.Equal(t, attempts, blocks.blocks[0].RepairAttempts)
+	}
+	assert.WithinDuration(t, time.Now().Add(3*time.Minute), *blocks.blocks[0].NextRepairAt, 10*time.Second)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RederiveRequest describes a block range to rebuild from the archive
+type RederiveRequest struct {
+	FromBlock uint64
+	ToBlock   uint64
+}
+
+// RederiveResult summarizes a rederive run
+type RederiveResult struct {
+	BlocksRederived     int64
+	BlocksMissing       int64 // Not in the archive, left as stored
+	TransactionsWritten int64
+	TransfersWritten    int64 // Token transfers, when they are rebuilt
+	Duration            time.Duration
+}
+
+// RederiveService rebuilds the block and transaction collections from the raw
+// block archive, without RPC calls
+type RederiveService struct {
+	blockRepo       repository.BlockRepository
+	txRepo          repository.TransactionRepository
+	blockCommitRepo repository.BlockCommitRepository
+	archive         service.ArchivedBlockReader
+	logger          *logger.Logger
+
+	// Rebuild the token transfers and addresses of the blocks too, nil when disabled
+	transfers *TokenTransferService
+	addresses *AddressService
+	mu        sync.Mutex
+}
+
+// NewRederiveService creates a new rederive service
+func NewRederiveService(
+	blockRepo repository.BlockRepository,
+	txRepo repository.TransactionRepository,
+	blockCommitRepo repository.BlockCommitRepository,
+	archive service.ArchivedBlockReader,
+	logger *logger.Logger,
+) *RederiveService {
+	return &RederiveService{
+		blockRepo:       blockRepo,
+		txRepo:          txRepo,
+		blockCommitRepo: blockCommitRepo,
+		archive:         archive,
+		logger:          logger.WithComponent("rederive-service"),
+	}
+}
+
+// SetTokenTransferService makes rederived blocks replace their token transfers
+// with the ones decoded from the archived receipts
+func (s *RederiveService) SetTokenTransferService(transfers *TokenTransferService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfers = transfers
+}
+
+// SetAddressService makes rederived blocks record their addresses, like the
+// crawler does
+func (s *RederiveService) SetAddressService(addresses *AddressService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addresses = addresses
+}
+
+// tokenTransferService returns the token transfer service, nil when not set
+func (s *RederiveService) tokenTransferService() *TokenTransferService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transfers
+}
+
+// addressService returns the address service, nil when not set
+func (s *RederiveService) addressService() *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addresses
+}
+
+// Rederive replaces every stored block in the range, and its transactions,
+// with the entities derived from the archived block
+func (s *RederiveService) Rederive(ctx context.Context, req RederiveRequest) (*RederiveResult, error) {
+	if req.FromBlock > req.ToBlock {
+		return nil, fmt.Errorf("invalid block range: %d > %d", req.FromBlock, req.ToBlock)
+	}
+
+	result := &RederiveResult{}
+	startTime := time.Now()
+
+	s.logger.Info("Starting rederive",
+		zap.Uint64("from_block", req.FromBlock),
+		zap.Uint64("to_block", req.ToBlock))
+
+	for blockNumber := req.FromBlock; blockNumber <= req.ToBlock; blockNumber++ {
+		if err := ctx.Err(); err != nil {
+			result.Duration = time.Since(startTime)
+			return result, err
+		}
+
+		if err := s.rederiveBlock(ctx, blockNumber, result); err != nil {
+			result.Duration = time.Since(startTime)
+			return result, fmt.Errorf("failed to rederive block %d: %w", blockNumber, err)
+		}
+
+		if blockNumber == req.ToBlock {
+			break // Avoid overflow when ToBlock is the maximum uint64
+		}
+	}
+
+	result.Duration = time.Since(startTime)
+
+	s.logger.Info("Rederive completed",
+		zap.Int64("blocks_rederived", result.BlocksRederived),
+		zap.Int64("blocks_missing", result.BlocksMissing),
+		zap.Int64("transactions_written", result.TransactionsWritten),
+		zap.Int64("transfers_written", result.TransfersWritten),
+		zap.Duration("duration", result.Duration))
+
+	return result, nil
+}
+
+// rederiveBlock replaces a single stored block with the archived one
+func (s *RederiveService) rederiveBlock(ctx context.Context, blockNumber uint64, result *RederiveResult) error {
+	block, transactions, err := s.archive.GetArchivedBlock(ctx, blockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to read archived block: %w", err)
+	}
+	if block == nil {
+		s.logger.Warn("Block not found in archive, skipping", zap.Uint64("block_number", blockNumber))
+		result.BlocksMissing++
+		return nil
+	}
+
+	// Remove what is stored at the height, it may be a different (reorged)
+	// block. The block goes last, a failure leaves it stored as it was.
+	transferService := s.tokenTransferService()
+	existing, err := s.blockRepo.GetBlockByNumber(ctx, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return fmt.Errorf("failed to get stored block: %w", err)
+	}
+	if existing != nil {
+		if transferService != nil {
+			if err := transferService.DeleteBlock(ctx, existing.Hash); err != nil {
+				return err
+			}
+		}
+		if err := s.txRepo.DeleteTransactionsByBlockHash(ctx, existing.Hash); err != nil {
+			return fmt.Errorf("failed to delete stored transactions: %w", err)
+		}
+		if err := s.blockRepo.DeleteBlock(ctx, existing.Hash); err != nil {
+			return fmt.Errorf("failed to delete stored block: %w", err)
+		}
+	}
+
+	// Commit the block, its transactions and its status together, a failure
+	// leaves the height empty to be rederived or crawled again
+	status := entity.BlockStatusProcessed
+	if countMissingReceipts(transactions) > 0 {
+		status = entity.BlockStatusIncomplete
+	}
+	if err := s.blockCommitRepo.CommitBlock(ctx, block, transactions, status); err != nil {
+		return fmt.Errorf("failed to commit block: %w", err)
+	}
+
+	// Recording is idempotent, a failed run is repeated
+	if addresses := s.addressService(); addresses != nil {
+		if err := addresses.RecordBlock(ctx, transactions); err != nil {
+			return fmt.Errorf("failed to record addresses: %w", err)
+		}
+	}
+	if transferService != nil {
+		transfers, err := transferService.RecordBlock(ctx, transactions)
+		if err != nil {
+			return err
+		}
+		result.TransfersWritten += int64(len(transfers))
+	}
+
+	result.BlocksRederived++
+	result.TransactionsWritten += int64(len(transactions))
+
+	s.logger.Debug("Rederived block",
+		zap.Uint64("block_number", blockNumber),
+		zap.Int("transactions", len(transactions)))
+
+	return nil
+}
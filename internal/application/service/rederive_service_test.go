@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRederiveCommits commits blocks into the verify test stores
+type memoryRederiveCommits struct {
+	fakeBlockCommitRepository
+	blocks       *memoryVerifyStore
+	transactions *memoryVerifyTransactions
+}
+
+func (r *memoryRederiveCommits) CommitBlock(ctx context.Context, block *entity.Block, transactions []*entity.Transaction, status entity.BlockStatus) error {
+	if err := r.fakeBlockCommitRepository.CommitBlock(ctx, block, transactions, status); err != nil {
+		return err
+	}
+	committed := *block
+	committed.Status = status
+	r.blocks.blocks[mustParseUint(block.Number)] = &committed
+	r.transactions.transactions[block.Hash] = append(r.transactions.transactions[block.Hash], transactions...)
+	return nil
+}
+
+// fakeArchivedBlocks serves archived blocks by number
+type fakeArchivedBlocks struct {
+	blocks       map[uint64]*entity.Block
+	transactions map[uint64][]*entity.Transaction
+}
+
+func (a *fakeArchivedBlocks) GetArchivedBlock(ctx context.Context, blockNumber uint64) (*entity.Block, []*entity.Transaction, error) {
+	block, ok := a.blocks[blockNumber]
+	if !ok {
+		return nil, nil, nil
+	}
+	return block, a.transactions[blockNumber], nil
+}
+
+func TestRederiveService_Rederive(t *testing.T) {
+	blocks := &memoryVerifyStore{blocks: map[uint64]*entity.Block{}}
+	transactions := &memoryVerifyTransactions{transactions: map[string][]*entity.Transaction{}}
+	commits := &memoryRederiveCommits{blocks: blocks, transactions: transactions}
+	archive := &fakeArchivedBlocks{blocks: map[uint64]*entity.Block{}, transactions: map[uint64][]*entity.Transaction{}}
+
+	for n := uint64(1); n <= 3; n++ {
+		block, txs, _ := testChainBlock(n, 2)
+		archivedBlock := *block
+		archivedBlock.Status = entity.BlockStatusPending
+		archive.blocks[n] = &archivedBlock
+		archive.transactions[n] = txs
+	}
+
+	// The archived receipts of block 3 hold a token transfer
+	archive.transactions[3][0].TokenTransfers = []*entity.TokenTransfer{{
+		TransactionHash: archive.transactions[3][0].Hash,
+		BlockHash:       archive.blocks[3].Hash,
+		Standard:        entity.TokenStandardERC20,
+		Amount:          "5",
+	}}
+
+	// Block 2 is stored from a reorged chain, block 4 was never archived
+	stale, staleTxs, _ := testChainBlock(2, 1)
+	stale.Hash = "0xstale"
+	staleTxs[0].BlockHash = stale.Hash
+	transfers := newFakeTokenTransferRepository()
+	transfers.transfers["stale"] = &entity.TokenTransfer{TransactionHash: staleTxs[0].Hash, BlockHash: stale.Hash}
+	blocks.blocks[2] = stale
+	transactions.transactions[stale.Hash] = staleTxs
+	kept, keptTxs, _ := testChainBlock(4, 1)
+	blocks.blocks[4] = kept
+	transactions.transactions[kept.Hash] = keptTxs
+
+	s := NewRederiveService(blocks, transactions, commits, archive, newTestLogger(t))
+	cfg := &config.Config{TokenTransfers: config.TokenTransfersConfig{Enabled: true}}
+	s.SetTokenTransferService(NewTokenTransferService(transfers, nil, cfg, newTestLogger(t)))
+	addresses := newFakeAddressRepository()
+	s.SetAddressService(NewAddressService(addresses, nil, cfg, newTestLogger(t)))
+	result, err := s.Rederive(context.Background(), RederiveRequest{FromBlock: 1, ToBlock: 4})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(3), result.BlocksRederived)
+	assert.Equal(t, int64(1), result.BlocksMissing)
+	assert.Equal(t, int64(6), result.TransactionsWritten)
+	assert.Equal(t, int64(1), result.TransfersWritten)
+
+	// The transfers of the stale block are replaced by the archived ones
+	require.Len(t, transfers.transfers, 1)
+	for _, transfer := range transfers.transfers {
+		assert.Equal(t, archive.blocks[3].Hash, transfer.BlockHash)
+	}
+
+	for n := uint64(1); n <= 3; n++ {
+		require.Contains(t, blocks.blocks, n)
+		assert.Equal(t, archive.blocks[n].Hash, blocks.blocks[n].Hash)
+		assert.Equal(t, entity.BlockStatusProcessed, blocks.blocks[n].Status)
+		assert.Len(t, transactions.transactions[blocks.blocks[n].Hash], 2)
+	}
+	assert.NotContains(t, transactions.transactions, "0xstale")
+	assert.Same(t, kept, blocks.blocks[4])
+	assert.Len(t, transactions.transactions[kept.Hash], 1)
+
+	// Every block is committed at once, with its addresses recorded
+	assert.Equal(t, []int{1, 1, 1}, commits.calls)
+	for _, tx := range archive.transactions[3] {
+		require.Contains(t, addresses.addresses, tx.From)
+		assert.Equal(t, uint64(3), addresses.addresses[tx.From].LastActiveBlock)
+	}
+
+	// A failed commit leaves the height empty instead of half written
+	commits.failHash = archive.blocks[1].Hash
+	_, err = s.Rederive(context.Background(), RederiveRequest{FromBlock: 1, ToBlock: 1})
+	assert.Error(t, err)
+	assert.NotContains(t, blocks.blocks, uint64(1))
+
+	_, err = s.Rederive(context.Background(), RederiveRequest{FromBlock: 5, ToBlock: 4})
+	assert.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"math/big"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// ReplayRequest describes a block range to republish
+type ReplayRequest struct {
+	FromBlock uint64
+	ToBlock   uint64
+	// RatePerSecond limits published transactions per second, 0 means unlimited
+	RatePerSecond float64
+}
+
+// ReplayResult summarizes a replay run
+type ReplayResult struct {
+	BlocksReplayed        int64
+	BlocksMissing         int64
+	TransactionsPublished int64
+	TransactionsSkipped   int64
+	Duration              time.Duration
+}
+
+// ReplayService republishes stored blocks to the messaging system
+type ReplayService struct {
+	blockRepo        repository.BlockRepository
+	txRepo           repository.TransactionRepository
+	messagingService service.MessagingService
+	logger           *logger.Logger
+}
+
+// NewReplayService creates a new replay service
+func NewReplayService(
+	blockRepo repository.BlockRepository,
+	txRepo repository.TransactionRepository,
+	messagingService service.MessagingService,
+	logger *logger.Logger,
+) *ReplayService {
+	return &ReplayService{
+		blockRepo:        blockRepo,
+		txRepo:           txRepo,
+		messagingService: messagingService,
+		logger:           logger.WithComponent("replay-service"),
+	}
+}
+
+// Replay republishes the transactions of every stored block in the range.
+// Events keep their original message IDs (the transaction hash), so a replay
+// into the live stream within the duplicate window is deduplicated by JetStream.
+func (s *ReplayService) Replay(ctx context.Context, req ReplayRequest) (*ReplayResult, error) {
+	if req.FromBlock > req.ToBlock {
+		return nil, fmt.Errorf("invalid block range: %d > %d", req.FromBlock, req.ToBlock)
+	}
+	if !s.messagingService.IsConnected() {
+		return nil, fmt.Errorf("messaging service is not connected")
+	}
+
+	limit := rate.Inf
+	if req.RatePerSecond > 0 {
+		limit = rate.Limit(req.RatePerSecond)
+	}
+	limiter := rate.NewLimiter(limit, 1)
+
+	result := &ReplayResult{}
+	startTime := time.Now()
+
+	s.logger.Info("Starting replay",
+		zap.Uint64("from_block", req.FromBlock),
+		zap.Uint64("to_block", req.ToBlock),
+		zap.Float64("rate_per_second", req.RatePerSecond))
+
+	for blockNumber := req.FromBlock; blockNumber <= req.ToBlock; blockNumber++ {
+		if err := ctx.Err(); err != nil {
+			result.Duration = time.Since(startTime)
+			return result, err
+		}
+
+		if err := s.replayBlock(ctx, blockNumber, limiter, result); err != nil {
+			result.Duration = time.Since(startTime)
+			return result, fmt.Errorf("failed to replay block %d: %w", blockNumber, err)
+		}
+
+		if blockNumber == req.ToBlock {
+			break // Avoid overflow when ToBlock is the maximum uint64
+		}
+	}
+
+	result.Duration = time.Since(startTime)
+
+	s.logger.Info("Replay completed",
+		zap.Int64("blocks_replayed", result.BlocksReplayed),
+		zap.Int64("blocks_missing", result.BlocksMissing),
+		zap.Int64("transactions_published", result.TransactionsPublished),
+		zap.Int64("transactions_skipped", result.TransactionsSkipped),
+		zap.Duration("duration", result.Duration))
+
+	return result, nil
+}
+
+// replayBlock republishes the transactions of a single block
+func (s *ReplayService) replayBlock(ctx context.Context, blockNumber uint64, limiter *rate.Limiter, result *ReplayResult) error {
+	number := new(big.Int).SetUint64(blockNumber)
+
+	block, err := s.blockRepo.GetBlockByNumber(ctx, number)
+	if err != nil {
+		return fmt.Errorf("failed to get block: %w", err)
+	}
+	if block == nil {
+		s.logger.Warn("Block not found in database, skipping", zap.Uint64("block_number", blockNumber))
+		result.BlocksMissing++
+		return nil
+	}
+
+	transactions, err := s.txRepo.GetTransactionsByBlockNumber(ctx, number)
+	if err != nil {
+		return fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	for _, tx := range transactions {
+		// Transactions left behind by a reorged block don't belong to the stored block
+		if !isTransactionOfBlock(tx, block) {
+			result.TransactionsSkipped++
+			continue
+		}
+
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		if err := s.messagingService.PublishTransaction(ctx, tx); err != nil {
+			return fmt.Errorf("failed to publish transaction %s: %w", tx.Hash, err)
+		}
+		result.TransactionsPublished++
+	}
+
+	result.BlocksReplayed++
+
+	s.logger.Debug("Replayed block",
+		zap.Uint64("block_number", blockNumber),
+		zap.Int("transactions", len(transactions)))
+
+	return nil
+}
+
+// isTransactionOfBlock checks that a transaction was stored for the given block
+func isTransactionOfBlock(tx *entity.Transaction, block *entity.Block) bool {
+	return tx.BlockHash == "" || tx.BlockHash == block.Hash
+}
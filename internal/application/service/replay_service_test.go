@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBlockRepository struct {
+	repository.BlockRepository
+	blocks map[uint64]*entity.Block
+}
+
+func (r *fakeBlockRepository) GetBlockByNumber(ctx context.Context, blockNumber *big.Int) (*entity.Block, error) {
+	return r.blocks[blockNumber.Uint64()], nil
+}
+
+type fakeTransactionRepository struct {
+	repository.TransactionRepository
+	transactions map[uint64][]*entity.Transaction
+}
+
+func (r *fakeTransactionRepository) GetTransactionsByBlockNumber(ctx context.Context, blockNumber *big.Int) ([]*entity.Transaction, error) {
+	return r.transactions[blockNumber.Uint64()], nil
+}
+
+type fakeMessagingService struct {
+	service.MessagingService
+	published []string
+	transfers []*entity.TokenTransfer
+}
+
+func (m *fakeMessagingService) IsConnected() bool {
+	return true
+}
+
+func (m *fakeMessagingService) PublishTransaction(ctx context.Context, tx *entity.Transaction) error {
+	m.published = append(m.published, tx.Hash)
+	return nil
+}
+
+func (m *fakeMessagingService) PublishTransactions(ctx context.Context, transactions []*entity.Transaction) error {
+	for _, tx := range transactions {
+		m.published = append(m.published, tx.Hash)
+	}
+	return nil
+}
+
+func (m *fakeMessagingService) PublishTokenTransfers(ctx context.Context, transfers []*entity.TokenTransfer) error {
+	m.transfers = append(m.transfers, transfers...)
+	return nil
+}
+
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+	log, err := logger.NewLogger(&config.Config{App: config.AppConfig{LogLevel: "error"}})
+	require.NoError(t, err)
+	return log
+}
+
+func TestReplayService_Replay(t *testing.T) {
+	blockRepo := &fakeBlockRepository{blocks: map[uint64]*entity.Block{
+		10: {Hash: "0xb10"},
+		12: {Hash: "0xb12"},
+	}}
+	txRepo := &fakeTransactionRepository{transactions: map[uint64][]*entity.Transaction{
+		10: {{Hash: "0x1", BlockHash: "0xb10"}, {Hash: "0x2", BlockHash: "0xb10"}},
+		12: {{Hash: "0x3", BlockHash: "0xb12"}, {Hash: "0x4", BlockHash: "0xorphan"}},
+	}}
+	messagingService := &fakeMessagingService{}
+
+	replayService := NewReplayService(blockRepo, txRepo, messagingService, newTestLogger(t))
+
+	result, err := replayService.Replay(context.Background(), ReplayRequest{FromBlock: 10, ToBlock: 12})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"0x1", "0x2", "0x3"}, messagingService.published)
+	assert.Equal(t, int64(2), result.BlocksReplayed)
+	assert.Equal(t, int64(1), result.BlocksMissing)
+	assert.Equal(t, int64(3), result.TransactionsPublished)
+	assert.Equal(t, int64(1), result.TransactionsSkipped)
+}
+
+func TestReplayService_InvalidRange(t *testing.T) {
+	replayService := NewReplayService(&fakeBlockRepository{}, &fakeTransactionRepository{}, &fakeMessagingService{}, newTestLogger(t))
+
+	_, err := replayService.Replay(context.Background(), ReplayRequest{FromBlock: 5, ToBlock: 4})
+	assert.Error(t, err)
+}
@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RetryService manages the persistent queue of blocks that failed processing
+type RetryService struct {
+	retryRepo      repository.BlockRetryRepository
+	crawlerService *CrawlerService
+	logger         *logger.Logger
+	network        string
+
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	interval   time.Duration
+	batchSize  int
+
+	// Retries only run while this instance leads, nil without leader election
+	leaderElection *LeaderElectionService
+
+	isRunning bool
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+}
+
+// NewRetryService creates a new retry service. crawlerService may be nil when
+// the service is only used to inspect and requeue the queue.
+func NewRetryService(
+	retryRepo repository.BlockRetryRepository,
+	crawlerService *CrawlerService,
+	config *config.Config,
+	logger *logger.Logger,
+) *RetryService {
+	s := &RetryService{
+		retryRepo:      retryRepo,
+		crawlerService: crawlerService,
+		logger:         logger.WithComponent("retry-service"),
+		network:        config.Ethereum.Network,
+		maxRetries:     3,
+		baseDelay:      30 * time.Second,
+		maxDelay:       30 * time.Minute,
+		interval:       10 * time.Second,
+		batchSize:      10,
+	}
+
+	if config.Scheduler.MaxRetries > 0 {
+		s.maxRetries = config.Scheduler.MaxRetries
+	}
+	if config.Scheduler.RetryBaseDelay > 0 {
+		s.baseDelay = config.Scheduler.RetryBaseDelay
+	}
+	if config.Scheduler.RetryMaxDelay > 0 {
+		s.maxDelay = config.Scheduler.RetryMaxDelay
+	}
+	if config.Scheduler.RetryInterval > 0 {
+		s.interval = config.Scheduler.RetryInterval
+	}
+	if config.Scheduler.RetryBatchSize > 0 {
+		s.batchSize = config.Scheduler.RetryBatchSize
+	}
+
+	return s
+}
+
+// SetLeaderElection makes the retry worker skip its checks, and stop a batch,
+// once this instance no longer holds the lease
+func (s *RetryService) SetLeaderElection(leaderElection *LeaderElectionService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leaderElection = leaderElection
+}
+
+// isLeader reports whether this instance may process retries
+func (s *RetryService) isLeader() bool {
+	s.mu.Lock()
+	leaderElection := s.leaderElection
+	s.mu.Unlock()
+	return leaderElection == nil || leaderElection.IsLeader()
+}
+
+// Start starts the retry worker
+func (s *RetryService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRunning {
+		return fmt.Errorf("retry service is already running")
+	}
+	if s.crawlerService == nil {
+		return fmt.Errorf("crawlerService is nil")
+	}
+
+	s.stopChan = make(chan struct{})
+	s.isRunning = true
+
+	// The worker outlives the start context and is stopped through stopChan
+	s.wg.Add(1)
+	go s.retryWorker(context.WithoutCancel(ctx), s.stopChan)
+
+	s.logger.Info("Retry service started",
+		zap.Int("max_retries", s.maxRetries),
+		zap.Duration("base_delay", s.baseDelay),
+		zap.Duration("max_delay", s.maxDelay),
+		zap.Duration("interval", s.interval))
+
+	return nil
+}
+
+// Stop stops the retry worker and waits for the current batch to finish
+func (s *RetryService) Stop() error {
+	s.mu.Lock()
+	if !s.isRunning {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.stopChan)
+	s.isRunning = false
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.logger.Info("Retry service stopped")
+	return nil
+}
+
+// RecordFailure records a failed processing attempt for a block, scheduling the
+// next attempt with exponential backoff or dead-lettering it after MaxRetries
+func (s *RetryService) RecordFailure(ctx context.Context, blockNumber uint64, processErr error) (*entity.BlockRetry, error) {
+	retry, err := s.retryRepo.GetBlockRetry(ctx, s.network, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block retry: %w", err)
+	}
+
+	now := time.Now()
+	if retry == nil {
+		retry = &entity.BlockRetry{
+			Network:     s.network,
+			BlockNumber: blockNumber,
+			CreatedAt:   now,
+		}
+	}
+
+	retry.Attempts++
+	retry.LastError = processErr.Error()
+	retry.UpdatedAt = now
+
+	if retry.Attempts >= s.maxRetries {
+		retry.Status = entity.BlockRetryStatusDeadLetter
+		retry.DeadLetterAt = &now
+	} else {
+		retry.Status = entity.BlockRetryStatusPending
+		retry.NextAttemptAt = now.Add(retryBackoff(s.baseDelay, s.maxDelay, retry.Attempts))
+	}
+
+	if err := s.retryRepo.UpsertBlockRetry(ctx, retry); err != nil {
+		return nil, fmt.Errorf("failed to save block retry: %w", err)
+	}
+
+	if retry.Status == entity.BlockRetryStatusDeadLetter {
+		s.logger.Warn("Block processing failed too many times, moved to dead-letter",
+			zap.Uint64("block_number", blockNumber),
+			zap.Int("attempts", retry.Attempts),
+			zap.Error(processErr))
+
+		// MongoDB conflict errors usually mean the block was already stored by another path
+		if isDuplicateKeyError(processErr) {
+			s.logger.Info("MongoDB conflict error detected, block might already be processed",
+				zap.Uint64("block_number", blockNumber))
+		}
+	} else {
+		s.logger.Warn("Block processing failed, scheduled for retry",
+			zap.Uint64("block_number", blockNumber),
+			zap.Int("attempts", retry.Attempts),
+			zap.Int("max_retries", s.maxRetries),
+			zap.Time("next_attempt_at", retry.NextAttemptAt),
+			zap.Error(processErr))
+	}
+
+	return retry, nil
+}
+
+// Enqueue queues a block to be processed again on the next check, keeping the
+// attempts of a block that is already queued
+func (s *RetryService) Enqueue(ctx context.Context, blockNumber uint64, reason string) error {
+	retry, err := s.retryRepo.GetBlockRetry(ctx, s.network, blockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get block retry: %w", err)
+	}
+
+	now := time.Now()
+	if retry == nil {
+		retry = &entity.BlockRetry{
+			Network:     s.network,
+			BlockNumber: blockNumber,
+			CreatedAt:   now,
+		}
+	}
+
+	retry.Status = entity.BlockRetryStatusPending
+	retry.LastError = reason
+	retry.NextAttemptAt = now
+	retry.UpdatedAt = now
+	retry.DeadLetterAt = nil
+
+	if err := s.retryRepo.UpsertBlockRetry(ctx, retry); err != nil {
+		return fmt.Errorf("failed to save block retry: %w", err)
+	}
+
+	s.logger.Info("Block queued for processing",
+		zap.Uint64("block_number", blockNumber),
+		zap.String("reason", reason))
+	return nil
+}
+
+// RecordSuccess removes a block from the retry queue after it was processed
+func (s *RetryService) RecordSuccess(ctx context.Context, blockNumber uint64) error {
+	return s.retryRepo.DeleteBlockRetry(ctx, s.network, blockNumber)
+}
+
+// IsQueued reports whether a block is in the retry queue, pending or dead-lettered
+func (s *RetryService) IsQueued(ctx context.Context, blockNumber uint64) (bool, error) {
+	retry, err := s.retryRepo.GetBlockRetry(ctx, s.network, blockNumber)
+	if err != nil {
+		return false, fmt.Errorf("failed to get block retry: %w", err)
+	}
+	return retry != nil, nil
+}
+
+// ListBlockRetries lists queued blocks with the given status
+func (s *RetryService) ListBlockRetries(ctx context.Context, status entity.BlockRetryStatus, limit int) ([]*entity.BlockRetry, error) {
+	return s.retryRepo.GetBlockRetriesByStatus(ctx, s.network, status, limit)
+}
+
+// Requeue moves a block back to pending so it is retried on the next check
+func (s *RetryService) Requeue(ctx context.Context, blockNumber uint64) error {
+	found, err := s.retryRepo.RequeueBlockRetry(ctx, s.network, blockNumber, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to requeue block %d: %w", blockNumber, err)
+	}
+	if !found {
+		return fmt.Errorf("block %d is not in the retry queue", blockNumber)
+	}
+
+	s.logger.Info("Block requeued for retry", zap.Uint64("block_number", blockNumber))
+	return nil
+}
+
+// RequeueDeadLetters moves every dead-lettered block back to pending
+func (s *RetryService) RequeueDeadLetters(ctx context.Context) (int64, error) {
+	count, err := s.retryRepo.RequeueDeadLetterBlockRetries(ctx, s.network, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue dead-lettered blocks: %w", err)
+	}
+
+	s.logger.Info("Dead-lettered blocks requeued", zap.Int64("count", count))
+	return count, nil
+}
+
+// GetStats returns retry queue statistics
+func (s *RetryService) GetStats(ctx context.Context) map[string]interface{} {
+	stats := map[string]interface{}{}
+
+	if pending, err := s.retryRepo.GetBlockRetryCountByStatus(ctx, s.network, entity.BlockRetryStatusPending); err == nil {
+		stats["retry_pending"] = pending
+	}
+	if dead, err := s.retryRepo.GetBlockRetryCountByStatus(ctx, s.network, entity.BlockRetryStatusDeadLetter); err == nil {
+		stats["retry_dead_letter"] = dead
+	}
+
+	return stats
+}
+
+// retryWorker periodically retries due blocks
+func (s *RetryService) retryWorker(ctx context.Context, stopChan chan struct{}) {
+	defer s.wg.Done()
+
+	// Add panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic recovered in retryWorker",
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.processDueRetries(ctx, stopChan); err != nil {
+				s.logger.Error("Failed to process block retries", zap.Error(err))
+			}
+		}
+	}
+}
+
+// processDueRetries retries one batch of due blocks
+func (s *RetryService) processDueRetries(ctx context.Context, stopChan chan struct{}) error {
+	if !s.isLeader() {
+		s.logger.Debug("Skipping block retries, not the leader")
+		return nil
+	}
+
+	retries, err := s.retryRepo.GetDueBlockRetries(ctx, s.network, time.Now(), s.batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, retry := range retries {
+		select {
+		case <-stopChan:
+			return nil
+		default:
+		}
+		// The lease may be lost while the batch runs
+		if !s.isLeader() {
+			s.logger.Warn("Lost leadership, stopping block retries")
+			return nil
+		}
+
+		s.logger.Info("Retrying block",
+			zap.Uint64("block_number", retry.BlockNumber),
+			zap.Int("attempt", retry.Attempts+1))
+
+		blockNumber := new(big.Int).SetUint64(retry.BlockNumber)
+		if err := s.crawlerService.ProcessSpecificBlock(ctx, blockNumber); err != nil {
+			if _, recordErr := s.RecordFailure(ctx, retry.BlockNumber, err); recordErr != nil {
+				s.logger.Error("Failed to record block retry failure", zap.Error(recordErr))
+			}
+			continue
+		}
+
+		if err := s.RecordSuccess(ctx, retry.BlockNumber); err != nil {
+			s.logger.Error("Failed to remove block from retry queue", zap.Error(err))
+			continue
+		}
+
+		s.logger.Info("Block retry succeeded", zap.Uint64("block_number", retry.BlockNumber))
+	}
+
+	return nil
+}
+
+// retryBackoff returns baseDelay doubled for every previous attempt, capped at maxDelay
+func retryBackoff(baseDelay, maxDelay time.Duration, attempts int) time.Duration {
+	delay := baseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// isDuplicateKeyError checks for MongoDB upsert/duplicate errors
+func isDuplicateKeyError(err error) bool {
+	errorMsg := err.Error()
+	return strings.Contains(errorMsg, "_id") && strings.Contains(errorMsg, "immutable") ||
+		strings.Contains(errorMsg, "duplicate key error") ||
+		strings.Contains(errorMsg, "E11000")
+}
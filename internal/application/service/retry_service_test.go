@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBlockRetryRepository struct {
+	repository.BlockRetryRepository
+	retries map[uint64]*entity.BlockRetry
+}
+
+func (r *fakeBlockRetryRepository) GetBlockRetry(ctx context.Context, network string, blockNumber uint64) (*entity.BlockRetry, error) {
+	if retry, ok := r.retries[blockNumber]; ok {
+		copied := *retry
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeBlockRetryRepository) UpsertBlockRetry(ctx context.Context, retry *entity.BlockRetry) error {
+	copied := *retry
+	r.retries[retry.BlockNumber] = &copied
+	return nil
+}
+
+func (r *fakeBlockRetryRepository) DeleteBlockRetry(ctx context.Context, network string, blockNumber uint64) error {
+	delete(r.retries, blockNumber)
+	return nil
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, retryBackoff(30*time.Second, 10*time.Minute, 1))
+	assert.Equal(t, time.Minute, retryBackoff(30*time.Second, 10*time.Minute, 2))
+	assert.Equal(t, 4*time.Minute, retryBackoff(30*time.Second, 10*time.Minute, 4))
+	assert.Equal(t, 10*time.Minute, retryBackoff(30*time.Second, 10*time.Minute, 10))
+	assert.Equal(t, 10*time.Minute, retryBackoff(30*time.Second, 10*time.Minute, 1000))
+}
+
+func TestRetryService_RecordFailure(t *testing.T) {
+	repo := &fakeBlockRetryRepository{retries: map[uint64]*entity.BlockRetry{}}
+	cfg := &config.Config{
+		Ethereum:  config.EthereumConfig{Network: "ethereum"},
+		Scheduler: config.SchedulerConfig{MaxRetries: 3, RetryBaseDelay: time.Second, RetryMaxDelay: time.Minute},
+	}
+	retryService := NewRetryService(repo, nil, cfg, newTestLogger(t))
+	ctx := context.Background()
+
+	retry, err := retryService.RecordFailure(ctx, 100, errors.New("rpc timeout"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.BlockRetryStatusPending, retry.Status)
+	assert.Equal(t, 1, retry.Attempts)
+	assert.Equal(t, "rpc timeout", retry.LastError)
+	assert.WithinDuration(t, time.Now().Add(time.Second), retry.NextAttemptAt, 500*time.Millisecond)
+
+	retry, err = retryService.RecordFailure(ctx, 100, errors.New("rpc timeout"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, retry.Attempts)
+	assert.WithinDuration(t, time.Now().Add(2*time.Second), retry.NextAttemptAt, 500*time.Millisecond)
+
+	retry, err = retryService.RecordFailure(ctx, 100, errors.New("receipt missing"))
+	require.NoError(t, err)
+	assert.Equal(t, entity.BlockRetryStatusDeadLetter, retry.Status)
+	assert.NotNil(t, retry.DeadLetterAt)
+	assert.Equal(t, "receipt missing", repo.retries[100].LastError)
+
+	require.NoError(t, retryService.RecordSuccess(ctx, 100))
+	assert.Empty(t, repo.retries)
+}
+
+func TestRetryService_SkipsRetriesWhenNotLeader(t *testing.T) {
+	// The repository has no due retries query, a retry check would panic
+	repo := &fakeBlockRetryRepository{retries: map[uint64]*entity.BlockRetry{}}
+	cfg := &config.Config{Ethereum: config.EthereumConfig{Network: "ethereum"}}
+	retryService := NewRetryService(repo, nil, cfg, newTestLogger(t))
+
+	leader := newTestLeader(t, 1)
+	leader.isLeader = false
+	retryService.SetLeaderElection(leader)
+
+	require.NoError(t, retryService.processDueRetries(context.Background(), make(chan struct{})))
+}
@@ -2,12 +2,12 @@ package service
 
 import (
 	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
 	"ethereum-raw-data-crawler/internal/domain/service"
 	"ethereum-raw-data-crawler/internal/infrastructure/config"
 	"ethereum-raw-data-crawler/internal/infrastructure/logger"
 	"fmt"
 	"math/big"
-	"strings"
 	"sync"
 	"time"
 
@@ -26,34 +26,62 @@ const (
 	HybridMode SchedulerMode = "hybrid"
 )
 
+const (
+	// gapFillScanLimit is the number of missing blocks examined per gap fill pass
+	gapFillScanLimit = 1000
+	// gapFillBatchSize is the number of missing blocks crawled per gap fill pass
+	gapFillBatchSize = 10
+)
+
+// StatsFunc reports the statistics of an additional service
+type StatsFunc func(ctx context.Context) map[string]interface{}
+
 // SchedulerService manages block crawling scheduling
 type SchedulerService struct {
 	blockScheduler service.BlockSchedulerService
 	crawlerService *CrawlerService
+	retryService   *RetryService
+	leaderElection *LeaderElectionService
 	config         *config.Config
 	logger         *logger.Logger
 	mode           SchedulerMode
 	isRunning      bool
-	stopChan       chan struct{}
+	stopChan       chan struct{} // Closed when the goroutines of the current mode stop
 	mu             sync.RWMutex
 
 	// Fallback polling
 	pollingTicker   *time.Ticker
 	pollingStopChan chan struct{} // Channel to stop polling worker
+	pollingInterval time.Duration
 	lastBlockTime   time.Time
 	fallbackTimeout time.Duration
 
-	// Error tracking for blocks
-	failedBlocks  map[string]int       // block_number -> failure_count
-	skippedBlocks map[string]time.Time // block_number -> skip_time
-	maxRetries    int
-	skipDuration  time.Duration
+	// Runtime control
+	runCtx   context.Context // Context passed to Start
+	paused   bool
+	pausedAt *time.Time
+
+	// Head tracking
+	lastHead       *entity.BlockHeader
+	skippedHeads   int64
+	reorgsDetected int64
+	lastReorgBlock uint64
+
+	// Checkpoint gap filling, blocks up to the work end block are left to the
+	// workers when work distribution is enabled
+	workCoordinator *WorkCoordinatorService
+	gapBlocksFilled int64
+
+	// Statistics of additional services reported by GetStats
+	statsSources map[string]StatsFunc
 }
 
 // NewSchedulerService creates a new scheduler service
 func NewSchedulerService(
 	blockScheduler service.BlockSchedulerService,
 	crawlerService *CrawlerService,
+	retryService *RetryService,
+	leaderElection *LeaderElectionService,
 	config *config.Config,
 	logger *logger.Logger,
 ) *SchedulerService {
@@ -61,6 +89,12 @@ func NewSchedulerService(
 	if crawlerService == nil {
 		panic("crawlerService cannot be nil")
 	}
+	if retryService == nil {
+		panic("retryService cannot be nil")
+	}
+	if leaderElection == nil {
+		panic("leaderElection cannot be nil")
+	}
 	if config == nil {
 		panic("config cannot be nil")
 	}
@@ -81,34 +115,31 @@ func NewSchedulerService(
 		mode = HybridMode // Default fallback
 	}
 
-	// Get max retries and skip duration from config with defaults
-	maxRetries := 3
-	skipDuration := time.Minute
-
-	if config.Scheduler.MaxRetries > 0 {
-		maxRetries = config.Scheduler.MaxRetries
-	}
-	if config.Scheduler.SkipDuration > 0 {
-		skipDuration = config.Scheduler.SkipDuration
-	}
-
 	return &SchedulerService{
 		blockScheduler:  blockScheduler, // Can be nil for polling-only mode
 		crawlerService:  crawlerService,
+		retryService:    retryService,
+		leaderElection:  leaderElection,
 		config:          config,
 		logger:          logger.WithComponent("scheduler-service"),
 		mode:            mode,
 		stopChan:        make(chan struct{}),
 		pollingStopChan: nil, // Will be created when polling starts
+		pollingInterval: config.Scheduler.PollingInterval,
 		fallbackTimeout: config.Scheduler.FallbackTimeout,
-		failedBlocks:    make(map[string]int),
-		skippedBlocks:   make(map[string]time.Time),
-		maxRetries:      maxRetries,
-		skipDuration:    skipDuration,
+		statsSources:    make(map[string]StatsFunc),
 	}
 }
 
-// Start starts the scheduler service
+// RegisterStats adds the statistics of a service to GetStats under name
+func (s *SchedulerService) RegisterStats(name string, source StatsFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statsSources[name] = source
+}
+
+// Start starts the scheduler service. A paused scheduler starts without
+// ingesting blocks until Resume.
 func (s *SchedulerService) Start(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -117,19 +148,26 @@ func (s *SchedulerService) Start(ctx context.Context) error {
 		return fmt.Errorf("scheduler service is already running")
 	}
 
-	s.logger.Info("Starting scheduler service", zap.String("mode", string(s.mode)))
+	s.logger.Info("Starting scheduler service",
+		zap.String("mode", string(s.mode)),
+		zap.Bool("paused", s.paused))
 
-	// Start based on mode
-	switch s.mode {
-	case RealtimeMode:
-		return s.startRealtimeMode(ctx)
-	case PollingMode:
-		return s.startPollingMode(ctx)
-	case HybridMode:
-		return s.startHybridMode(ctx)
-	default:
-		return fmt.Errorf("unknown scheduler mode: %s", s.mode)
+	// Runtime control operations start goroutines with the start context, not
+	// the context of the admin request
+	s.runCtx = ctx
+
+	if s.paused {
+		s.isRunning = true
+		s.logger.Warn("Scheduler service is paused, blocks are ingested after resume")
+		return nil
+	}
+
+	if err := s.startIngestion(ctx); err != nil {
+		return err
 	}
+
+	s.isRunning = true
+	return nil
 }
 
 // Stop stops the scheduler service
@@ -143,27 +181,10 @@ func (s *SchedulerService) Stop() error {
 
 	s.logger.Info("Stopping scheduler service")
 
-	close(s.stopChan)
-	s.isRunning = false
-
-	// Stop block scheduler
-	if s.blockScheduler != nil {
-		if err := s.blockScheduler.Stop(); err != nil {
-			s.logger.Error("Failed to stop block scheduler", zap.Error(err))
-		}
-	}
-
-	// Stop polling ticker
-	if s.pollingTicker != nil {
-		s.pollingTicker.Stop()
-		s.pollingTicker = nil
-	}
-
-	// Stop polling worker
-	if s.pollingStopChan != nil {
-		close(s.pollingStopChan)
-		s.pollingStopChan = nil
+	if !s.paused {
+		s.stopIngestion()
 	}
+	s.isRunning = false
 
 	return nil
 }
@@ -175,17 +196,90 @@ func (s *SchedulerService) IsRunning() bool {
 	return s.isRunning
 }
 
-// SetMode sets the scheduler mode
-func (s *SchedulerService) SetMode(mode SchedulerMode) error {
+// Pause stops ingesting blocks without giving up leadership. The pause
+// outlives Stop and Start, a paused scheduler stays paused until Resume.
+func (s *SchedulerService) Pause() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return nil
+	}
+
+	if s.isRunning {
+		s.stopIngestion()
+	}
+
+	now := time.Now()
+	s.paused = true
+	s.pausedAt = &now
+
+	s.logger.Warn("Scheduler paused", zap.Bool("running", s.isRunning))
+	return nil
+}
+
+// Resume restarts ingesting blocks after Pause
+func (s *SchedulerService) Resume() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if !s.paused {
+		return nil
+	}
+
 	if s.isRunning {
-		return fmt.Errorf("cannot change mode while scheduler is running")
+		if err := s.startIngestion(s.runCtx); err != nil {
+			return fmt.Errorf("failed to resume scheduler: %w", err)
+		}
+	}
+
+	pausedFor := time.Since(*s.pausedAt)
+	s.paused = false
+	s.pausedAt = nil
+
+	s.logger.Info("Scheduler resumed",
+		zap.String("mode", string(s.mode)),
+		zap.Duration("paused_for", pausedFor))
+	return nil
+}
+
+// SetMode switches the scheduler mode. A running scheduler tears down the
+// goroutines of the old mode and starts the new one; if that fails it goes
+// back to the old mode.
+func (s *SchedulerService) SetMode(mode SchedulerMode) error {
+	if _, err := ParseSchedulerMode(string(mode)); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if mode == s.mode {
+		return nil
+	}
+	if mode != PollingMode && s.blockScheduler == nil {
+		return fmt.Errorf("%s mode requires a block scheduler", mode)
 	}
 
+	oldMode := s.mode
 	s.mode = mode
-	s.logger.Info("Scheduler mode changed", zap.String("mode", string(mode)))
+
+	if s.isRunning && !s.paused {
+		s.stopMode()
+		if err := s.startMode(s.runCtx); err != nil {
+			s.mode = oldMode
+			if restoreErr := s.startMode(s.runCtx); restoreErr != nil {
+				s.logger.Error("Failed to restore scheduler mode",
+					zap.String("mode", string(oldMode)),
+					zap.Error(restoreErr))
+			}
+			return fmt.Errorf("failed to switch to %s mode: %w", mode, err)
+		}
+	}
+
+	s.logger.Info("Scheduler mode changed",
+		zap.String("from", string(oldMode)),
+		zap.String("mode", string(mode)))
 	return nil
 }
 
@@ -196,17 +290,127 @@ func (s *SchedulerService) GetMode() SchedulerMode {
 	return s.mode
 }
 
+// SetPollingInterval changes the polling interval. An active polling worker
+// picks it up on its next tick.
+func (s *SchedulerService) SetPollingInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid polling interval: %v", interval)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldInterval := s.pollingInterval
+	s.pollingInterval = interval
+	if s.pollingTicker != nil {
+		s.pollingTicker.Reset(interval)
+	}
+
+	s.logger.Info("Polling interval changed",
+		zap.Duration("from", oldInterval),
+		zap.Duration("polling_interval", interval))
+	return nil
+}
+
+// ParseSchedulerMode validates a scheduler mode name
+func ParseSchedulerMode(mode string) (SchedulerMode, error) {
+	switch SchedulerMode(mode) {
+	case PollingMode, RealtimeMode, HybridMode:
+		return SchedulerMode(mode), nil
+	default:
+		return "", fmt.Errorf("unknown scheduler mode: %s", mode)
+	}
+}
+
+// startIngestion starts the goroutines of the current mode and the retry
+// worker. Caller holds the lock.
+func (s *SchedulerService) startIngestion(ctx context.Context) error {
+	if err := s.startMode(ctx); err != nil {
+		return err
+	}
+
+	// Retry blocks that failed in this or a previous run
+	if err := s.retryService.Start(ctx); err != nil {
+		s.logger.Error("Failed to start retry service", zap.Error(err))
+	}
+
+	return nil
+}
+
+// stopIngestion stops everything startIngestion started. Caller holds the lock.
+func (s *SchedulerService) stopIngestion() {
+	s.stopMode()
+
+	// Stop retry worker
+	if err := s.retryService.Stop(); err != nil {
+		s.logger.Error("Failed to stop retry service", zap.Error(err))
+	}
+}
+
+// startMode starts the goroutines of the current mode. Caller holds the lock.
+func (s *SchedulerService) startMode(ctx context.Context) error {
+	// stopMode closes stopChan, recreate it so the mode can be started again
+	// after a pause, a mode switch or losing and regaining leadership
+	s.stopChan = make(chan struct{})
+
+	// Start based on mode
+	switch s.mode {
+	case RealtimeMode:
+		return s.startRealtimeMode(ctx)
+	case PollingMode:
+		return s.startPollingMode(ctx)
+	case HybridMode:
+		return s.startHybridMode(ctx)
+	default:
+		return fmt.Errorf("unknown scheduler mode: %s", s.mode)
+	}
+}
+
+// stopMode stops the goroutines of the current mode. Caller holds the lock.
+func (s *SchedulerService) stopMode() {
+	close(s.stopChan)
+
+	// Stop block scheduler
+	if s.blockScheduler != nil {
+		if err := s.blockScheduler.Stop(); err != nil {
+			s.logger.Error("Failed to stop block scheduler", zap.Error(err))
+		}
+	}
+
+	// Stop polling ticker
+	if s.pollingTicker != nil {
+		s.pollingTicker.Stop()
+		s.pollingTicker = nil
+	}
+
+	// Stop polling worker
+	if s.pollingStopChan != nil {
+		close(s.pollingStopChan)
+		s.pollingStopChan = nil
+	}
+}
+
 // startRealtimeMode starts the scheduler in realtime mode
 func (s *SchedulerService) startRealtimeMode(ctx context.Context) error {
+	if s.blockScheduler == nil {
+		return fmt.Errorf("block scheduler is nil")
+	}
+
 	if err := s.blockScheduler.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start block scheduler: %w", err)
 	}
 
 	if err := s.blockScheduler.SubscribeNewBlocks(ctx, s.handleNewBlock); err != nil {
+		if stopErr := s.blockScheduler.Stop(); stopErr != nil {
+			s.logger.Error("Failed to stop block scheduler", zap.Error(stopErr))
+		}
 		return fmt.Errorf("failed to subscribe to new blocks: %w", err)
 	}
 
-	s.isRunning = true
+	// Heads only cover new blocks, crawl the ones between the checkpoint and the
+	// first head
+	go s.gapFillWorker(ctx, s.pollingInterval, s.stopChan)
+
 	s.logger.Info("Scheduler started in realtime mode")
 	return nil
 }
@@ -214,35 +418,24 @@ func (s *SchedulerService) startRealtimeMode(ctx context.Context) error {
 // startPollingMode starts the scheduler in polling mode
 func (s *SchedulerService) startPollingMode(ctx context.Context) error {
 	// Validate dependencies
-	if s.config == nil {
-		return fmt.Errorf("config is nil")
-	}
 	if s.crawlerService == nil {
 		return fmt.Errorf("crawlerService is nil")
 	}
-	if s.config.Scheduler.PollingInterval <= 0 {
-		return fmt.Errorf("invalid polling interval: %v", s.config.Scheduler.PollingInterval)
+	if s.pollingInterval <= 0 {
+		return fmt.Errorf("invalid polling interval: %v", s.pollingInterval)
 	}
 
 	// Use configured polling interval
-	s.pollingTicker = time.NewTicker(s.config.Scheduler.PollingInterval)
+	s.pollingTicker = time.NewTicker(s.pollingInterval)
 	s.pollingStopChan = make(chan struct{})
 
-	// Validate before starting worker
-	if s.pollingTicker == nil || s.pollingStopChan == nil {
-		return fmt.Errorf("failed to create polling resources")
-	}
-
 	go s.pollingWorker(ctx, s.pollingTicker, s.pollingStopChan)
 
-	s.isRunning = true
 	s.logger.Info("Scheduler started in polling mode",
-		zap.Duration("polling_interval", s.config.Scheduler.PollingInterval))
+		zap.Duration("polling_interval", s.pollingInterval))
 
-	// Update last block time to current time for polling mode
-	s.mu.Lock()
+	// Update last block time to current time for polling mode (caller holds the lock)
 	s.lastBlockTime = time.Now()
-	s.mu.Unlock()
 
 	return nil
 }
@@ -256,14 +449,15 @@ func (s *SchedulerService) startHybridMode(ctx context.Context) error {
 	}
 
 	// Start fallback polling monitor
-	go s.fallbackMonitor(ctx)
+	go s.fallbackMonitor(ctx, s.stopChan)
 
 	s.logger.Info("Scheduler started in hybrid mode")
 	return nil
 }
 
-// handleNewBlock handles new block notifications from WebSocket
-func (s *SchedulerService) handleNewBlock(blockNumber *big.Int) {
+// handleNewBlock handles new head notifications from WebSocket. Heads arrive
+// one at a time in chain order, so skipped blocks are crawled before the head.
+func (s *SchedulerService) handleNewBlock(event *service.NewHeadEvent) {
 	// Add panic recovery
 	defer func() {
 		if r := recover(); r != nil {
@@ -274,32 +468,43 @@ func (s *SchedulerService) handleNewBlock(blockNumber *big.Int) {
 	}()
 
 	// Validate input
-	if blockNumber == nil {
-		s.logger.Error("handleNewBlock called with nil blockNumber")
+	if event == nil || event.Header == nil || event.Header.Number == nil {
+		s.logger.Error("handleNewBlock called with nil head")
+		return
+	}
+
+	header := event.Header
+	blockNumStr := header.Number.String()
+
+	// A replica that lost its lease may still receive notifications until it stops
+	if !s.leaderElection.IsLeader() {
+		s.logger.Warn("Ignoring block notification, not the leader",
+			zap.String("block_number", blockNumStr))
+		return
+	}
+
+	// Heads queued before a pause may still be delivered
+	s.mu.RLock()
+	paused := s.paused
+	s.mu.RUnlock()
+	if paused {
+		s.logger.Info("Ignoring block notification, scheduler is paused",
+			zap.String("block_number", blockNumStr))
 		return
 	}
 
-	blockNumStr := blockNumber.String()
 	s.logger.Info("Received new block notification",
-		zap.String("block_number", blockNumStr))
+		zap.String("block_number", blockNumStr),
+		zap.String("block_hash", header.Hash))
 
 	// Update last block time
 	s.mu.Lock()
 	s.lastBlockTime = time.Now()
-
-	// Check if this block is currently being skipped due to previous failures
-	if skipTime, isSkipped := s.skippedBlocks[blockNumStr]; isSkipped {
-		if time.Since(skipTime) < s.skipDuration {
-			s.logger.Warn("Skipping block due to recent failures",
-				zap.String("block_number", blockNumStr),
-				zap.Duration("remaining_skip_time", s.skipDuration-time.Since(skipTime)))
-			s.mu.Unlock()
-			return
-		} else {
-			// Skip duration has passed, remove from skipped blocks
-			delete(s.skippedBlocks, blockNumStr)
-			delete(s.failedBlocks, blockNumStr) // Reset failure count
-		}
+	s.lastHead = header
+	s.skippedHeads += int64(len(event.SkippedBlocks))
+	if event.IsReorg() {
+		s.reorgsDetected++
+		s.lastReorgBlock = header.Number.Uint64()
 	}
 	s.mu.Unlock()
 
@@ -309,50 +514,160 @@ func (s *SchedulerService) handleNewBlock(blockNumber *big.Int) {
 		return
 	}
 
+	if event.IsReorg() {
+		s.logger.Warn("Reorg detected, block at this height was replaced",
+			zap.String("block_number", blockNumStr),
+			zap.String("old_hash", event.ReplacedHash),
+			zap.String("new_hash", header.Hash))
+	}
+
 	ctx := context.Background()
+	if len(event.SkippedBlocks) > 0 {
+		s.logger.Warn("Crawling blocks skipped by the WebSocket",
+			zap.String("from_block", event.SkippedBlocks[0].String()),
+			zap.String("to_block", event.SkippedBlocks[len(event.SkippedBlocks)-1].String()))
+		for _, blockNumber := range event.SkippedBlocks {
+			s.processScheduledBlock(ctx, blockNumber)
+		}
+	}
+
+	s.processScheduledBlock(ctx, header.Number)
+}
+
+// processScheduledBlock crawls a block announced by the block scheduler and
+// keeps the retry queue up to date
+func (s *SchedulerService) processScheduledBlock(ctx context.Context, blockNumber *big.Int) {
+	blockNumStr := blockNumber.String()
+
 	if err := s.crawlerService.ProcessSpecificBlock(ctx, blockNumber); err != nil {
-		s.handleBlockProcessingError(blockNumStr, err)
-	} else {
-		// Success: remove from failed blocks if it was there
-		s.mu.Lock()
-		delete(s.failedBlocks, blockNumStr)
-		s.mu.Unlock()
+		// Queue the block for retry, the WebSocket won't announce it again
+		if _, recordErr := s.retryService.RecordFailure(ctx, blockNumber.Uint64(), err); recordErr != nil {
+			s.logger.Error("Failed to queue block for retry",
+				zap.String("block_number", blockNumStr),
+				zap.NamedError("process_error", err),
+				zap.Error(recordErr))
+		}
+		return
+	}
+
+	// Success: remove from the retry queue in case it was there
+	if err := s.retryService.RecordSuccess(ctx, blockNumber.Uint64()); err != nil {
+		s.logger.Warn("Failed to clear block retry",
+			zap.String("block_number", blockNumStr),
+			zap.Error(err))
 	}
 }
 
-// handleBlockProcessingError handles errors during block processing with retry logic
-func (s *SchedulerService) handleBlockProcessingError(blockNumStr string, err error) {
+// SetWorkCoordinator leaves the blocks published as work ranges to the
+// workers, the gap filler only commits them to the checkpoint once stored
+func (s *SchedulerService) SetWorkCoordinator(coordinator *WorkCoordinatorService) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.workCoordinator = coordinator
+}
+
+// gapFillWorker fills the gap between the committed checkpoint and the last
+// head every interval
+func (s *SchedulerService) gapFillWorker(ctx context.Context, interval time.Duration, stopChan chan struct{}) {
+	// Add panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic recovered in gapFillWorker",
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
 
-	// Increment failure count
-	failureCount := s.failedBlocks[blockNumStr]
-	failureCount++
-	s.failedBlocks[blockNumStr] = failureCount
+	if interval <= 0 {
+		s.logger.Error("Invalid gap fill interval", zap.Duration("interval", interval))
+		return
+	}
 
-	s.logger.Error("Failed to process new block",
-		zap.String("block_number", blockNumStr),
-		zap.Int("failure_count", failureCount),
-		zap.Int("max_retries", s.maxRetries),
-		zap.Error(err))
-
-	// Check if we've exceeded max retries
-	if failureCount >= s.maxRetries {
-		// Add to skipped blocks to prevent infinite retries
-		s.skippedBlocks[blockNumStr] = time.Now()
-		s.logger.Warn("Block processing failed too many times, skipping temporarily",
-			zap.String("block_number", blockNumStr),
-			zap.Int("failure_count", failureCount),
-			zap.Duration("skip_duration", s.skipDuration))
-
-		// Check if this is a MongoDB upsert/duplicate error - these might resolve themselves
-		errorMsg := err.Error()
-		if strings.Contains(errorMsg, "_id") && strings.Contains(errorMsg, "immutable") ||
-			strings.Contains(errorMsg, "duplicate key error") ||
-			strings.Contains(errorMsg, "E11000") {
-			s.logger.Info("MongoDB conflict error detected, block might already be processed",
-				zap.String("block_number", blockNumStr))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.fillCheckpointGap(ctx, stopChan)
+		}
+	}
+}
+
+// fillCheckpointGap commits the blocks missing below the last head. Blocks
+// already stored are only committed, blocks in the retry queue are left to
+// the retry worker and blocks owned by the work coordinator to the workers.
+// The rest are crawled, up to gapFillBatchSize per pass.
+func (s *SchedulerService) fillCheckpointGap(ctx context.Context, stopChan chan struct{}) {
+	checkpoints := s.crawlerService.checkpointService()
+	if checkpoints == nil || !s.leaderElection.IsLeader() {
+		return
+	}
+
+	s.mu.RLock()
+	head := s.lastHead
+	paused := s.paused
+	coordinator := s.workCoordinator
+	s.mu.RUnlock()
+	if paused || head == nil {
+		return
+	}
+
+	// The head itself is crawled when it is announced
+	missing := checkpoints.MissingBlocks(head.Number.Uint64(), gapFillScanLimit)
+	if len(missing) == 0 {
+		return
+	}
+
+	crawled := 0
+	for _, blockNumber := range missing {
+		select {
+		case <-stopChan:
+			return
+		default:
+		}
+		if crawled >= gapFillBatchSize {
+			break
+		}
+
+		number := new(big.Int).SetUint64(blockNumber)
+		stored, err := s.crawlerService.commitStoredBlock(ctx, number)
+		if err != nil {
+			s.logger.Warn("Failed to check missing block",
+				zap.Uint64("block_number", blockNumber),
+				zap.Error(err))
+			continue
+		}
+		if stored || (coordinator != nil && coordinator.Owns(blockNumber)) {
+			continue
+		}
+
+		queued, err := s.retryService.IsQueued(ctx, blockNumber)
+		if err != nil {
+			s.logger.Warn("Failed to check block retry",
+				zap.Uint64("block_number", blockNumber),
+				zap.Error(err))
+			continue
+		}
+		if queued {
+			continue
 		}
+
+		s.logger.Info("Crawling block missing below the head",
+			zap.Uint64("block_number", blockNumber),
+			zap.Uint64("next_block", checkpoints.NextBlock()))
+		s.processScheduledBlock(ctx, number)
+		crawled++
+	}
+
+	if crawled > 0 {
+		s.mu.Lock()
+		s.gapBlocksFilled += int64(crawled)
+		s.mu.Unlock()
 	}
 }
 
@@ -392,9 +707,6 @@ func (s *SchedulerService) pollingWorker(ctx context.Context, ticker *time.Ticke
 
 	for {
 		select {
-		case <-s.stopChan:
-			s.logger.Info("Polling worker received global stop signal")
-			return
 		case <-stopChan:
 			s.logger.Info("Polling worker received specific stop signal")
 			return
@@ -404,6 +716,12 @@ func (s *SchedulerService) pollingWorker(ctx context.Context, ticker *time.Ticke
 		case <-ticker.C:
 			s.logger.Debug("Polling worker tick - checking for new blocks")
 
+			// A replica that lost its lease keeps ticking until it is stopped
+			if !s.leaderElection.IsLeader() {
+				s.logger.Warn("Skipping polling, not the leader")
+				continue
+			}
+
 			// Add nil check before calling crawlerService
 			if s.crawlerService != nil {
 				if err := s.crawlerService.processNextBlocks(ctx); err != nil {
@@ -424,7 +742,7 @@ func (s *SchedulerService) pollingWorker(ctx context.Context, ticker *time.Ticke
 }
 
 // fallbackMonitor monitors for WebSocket failures and activates polling fallback
-func (s *SchedulerService) fallbackMonitor(ctx context.Context) {
+func (s *SchedulerService) fallbackMonitor(ctx context.Context, stopChan chan struct{}) {
 	// Add panic recovery
 	defer func() {
 		if r := recover(); r != nil {
@@ -441,7 +759,7 @@ func (s *SchedulerService) fallbackMonitor(ctx context.Context) {
 
 	for {
 		select {
-		case <-s.stopChan:
+		case <-stopChan:
 			s.logger.Info("Fallback monitor received stop signal")
 			return
 		case <-ctx.Done():
@@ -449,29 +767,40 @@ func (s *SchedulerService) fallbackMonitor(ctx context.Context) {
 			return
 		case <-ticker.C:
 			s.mu.RLock()
-			timeSinceLastBlock := time.Since(s.lastBlockTime)
 			pollingActive := s.pollingTicker != nil
 			s.mu.RUnlock()
 
-			// Check if WebSocket is working with nil check
+			// Check if WebSocket is working with nil check. Any source announcing
+			// a head, even one another source announced first, counts.
 			wsWorking := s.blockScheduler != nil && s.blockScheduler.IsRunning()
+			timeSinceLastBlock := s.fallbackTimeout + 1
+			if s.blockScheduler != nil {
+				timeSinceLastBlock = time.Since(s.blockScheduler.LastHeadTime())
+			}
 
 			s.logger.Debug("Fallback monitor check",
 				zap.Duration("time_since_last_block", timeSinceLastBlock),
 				zap.Bool("websocket_running", wsWorking),
 				zap.Bool("polling_active", pollingActive))
 
-			// If no blocks received for fallbackTimeout, start polling
+			// If no source announced a head for fallbackTimeout, start polling
 			if timeSinceLastBlock > s.fallbackTimeout {
 				if !pollingActive {
-					s.logger.Warn("No blocks received via WebSocket, starting fallback polling",
+					s.logger.Warn("All WebSocket sources silent, starting fallback polling",
 						zap.Duration("time_since_last_block", timeSinceLastBlock),
 						zap.Bool("websocket_running", wsWorking))
 
 					// Create polling with proper validation
 					s.mu.Lock()
-					if s.config != nil && s.config.Scheduler.PollingInterval > 0 {
-						s.pollingTicker = time.NewTicker(s.config.Scheduler.PollingInterval)
+					select {
+					case <-stopChan:
+						// The mode was stopped while waiting for the lock
+						s.mu.Unlock()
+						return
+					default:
+					}
+					if s.pollingInterval > 0 {
+						s.pollingTicker = time.NewTicker(s.pollingInterval)
 						ticker := s.pollingTicker // Store reference before unlocking
 
 						stopChan := make(chan struct{})
@@ -518,21 +847,72 @@ func (s *SchedulerService) fallbackMonitor(ctx context.Context) {
 	}
 }
 
-// GetStats returns scheduler statistics
+// GetStats returns scheduler statistics. The scheduler fields are copied
+// under the lock, the retry queue and other services are queried after
+// releasing it.
 func (s *SchedulerService) GetStats() map[string]interface{} {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	stats := map[string]interface{}{
 		"mode":                    string(s.mode),
 		"is_running":              s.isRunning,
 		"block_scheduler_running": false,
 		"polling_active":          s.pollingTicker != nil,
 		"last_block_time":         s.lastBlockTime,
+		"paused":                  s.paused,
+		"polling_interval":        s.pollingInterval.String(),
+		"skipped_heads":           s.skippedHeads,
+		"reorgs_detected":         s.reorgsDetected,
+		"gap_blocks_filled":       s.gapBlocksFilled,
 	}
 
+	if s.pausedAt != nil {
+		stats["paused_at"] = *s.pausedAt
+	}
+	if s.lastHead != nil {
+		stats["last_head_block"] = s.lastHead.Number.Uint64()
+		stats["last_head_hash"] = s.lastHead.Hash
+	}
+	if s.reorgsDetected > 0 {
+		stats["last_reorg_block"] = s.lastReorgBlock
+	}
+	statsSources := make(map[string]StatsFunc, len(s.statsSources))
+	for name, source := range s.statsSources {
+		statsSources[name] = source
+	}
+	s.mu.RUnlock()
+
 	if s.blockScheduler != nil {
 		stats["block_scheduler_running"] = s.blockScheduler.IsRunning()
+
+		sources := s.blockScheduler.GetSourceStats()
+		connected := 0
+		for _, source := range sources {
+			if source.Connected {
+				connected++
+			}
+		}
+		stats["head_sources"] = sources
+		stats["head_sources_connected"] = connected
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for key, value := range s.retryService.GetStats(ctx) {
+		stats[key] = value
+	}
+	for key, value := range s.leaderElection.GetStats() {
+		stats[key] = value
+	}
+	if checkpoints := s.crawlerService.checkpointService(); checkpoints != nil {
+		for key, value := range checkpoints.GetStats() {
+			stats[key] = value
+		}
+	}
+	if writer := s.crawlerService.blockWriter(); writer != nil {
+		stats["block_writer"] = writer.GetStats()
+	}
+	for name, source := range statsSources {
+		stats[name] = source(ctx)
 	}
 
 	return stats
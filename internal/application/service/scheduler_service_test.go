@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBlockScheduler counts starts and stops of the realtime goroutines
+type fakeBlockScheduler struct {
+	mu       sync.Mutex
+	running  bool
+	starts   int
+	stops    int
+	startErr error
+}
+
+func (f *fakeBlockScheduler) Start(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.startErr != nil {
+		return f.startErr
+	}
+	f.running = true
+	f.starts++
+	return nil
+}
+
+func (f *fakeBlockScheduler) Stop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.running {
+		f.running = false
+		f.stops++
+	}
+	return nil
+}
+
+func (f *fakeBlockScheduler) IsRunning() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running
+}
+
+func (f *fakeBlockScheduler) SubscribeNewBlocks(ctx context.Context, callback func(*service.NewHeadEvent)) error {
+	return nil
+}
+
+func (f *fakeBlockScheduler) Unsubscribe() error { return nil }
+
+func (f *fakeBlockScheduler) LastHeadTime() time.Time { return time.Now() }
+
+func (f *fakeBlockScheduler) GetSourceStats() []service.HeadSourceStats { return nil }
+
+// idleBlockRetryRepository has no blocks to retry
+type idleBlockRetryRepository struct {
+	repository.BlockRetryRepository
+}
+
+func (r *idleBlockRetryRepository) GetDueBlockRetries(ctx context.Context, network string, dueBefore time.Time, limit int) ([]*entity.BlockRetry, error) {
+	return nil, nil
+}
+
+func (r *idleBlockRetryRepository) GetBlockRetryCountByStatus(ctx context.Context, network string, status entity.BlockRetryStatus) (int64, error) {
+	return 0, nil
+}
+
+func newTestSchedulerService(t *testing.T, mode string, blockScheduler service.BlockSchedulerService) *SchedulerService {
+	cfg := &config.Config{
+		Ethereum: config.EthereumConfig{Network: "ethereum"},
+		Scheduler: config.SchedulerConfig{
+			Mode:            mode,
+			PollingInterval: time.Hour, // The polling worker never ticks
+			FallbackTimeout: time.Hour,
+			RetryInterval:   time.Hour,
+		},
+	}
+	log := newTestLogger(t)
+
+	crawler := NewCrawlerService(nil, nil, nil, nil, nil, nil, nil, cfg, log)
+	retries := NewRetryService(&idleBlockRetryRepository{}, crawler, cfg, log)
+	leaderElection := NewLeaderElectionService(&memoryLeaseRepository{}, cfg, log)
+
+	return NewSchedulerService(blockScheduler, crawler, retries, leaderElection, cfg, log)
+}
+
+func isRetryRunning(retries *RetryService) bool {
+	retries.mu.Lock()
+	defer retries.mu.Unlock()
+	return retries.isRunning
+}
+
+func TestSchedulerService_PauseResume(t *testing.T) {
+	blockScheduler := &fakeBlockScheduler{}
+	scheduler := newTestSchedulerService(t, "hybrid", blockScheduler)
+	ctx := context.Background()
+
+	require.NoError(t, scheduler.Start(ctx))
+	defer scheduler.Stop()
+	assert.True(t, blockScheduler.IsRunning())
+
+	require.NoError(t, scheduler.Pause())
+	require.NoError(t, scheduler.Pause())
+	assert.False(t, blockScheduler.IsRunning())
+	assert.True(t, scheduler.IsRunning(), "a paused scheduler keeps running")
+	assert.False(t, isRetryRunning(scheduler.retryService))
+
+	stats := scheduler.GetStats()
+	assert.Equal(t, true, stats["paused"])
+	assert.Contains(t, stats, "paused_at")
+
+	// The pause outlives losing and regaining leadership
+	require.NoError(t, scheduler.Stop())
+	require.NoError(t, scheduler.Start(ctx))
+	assert.False(t, blockScheduler.IsRunning())
+	assert.Equal(t, 1, blockScheduler.starts)
+
+	require.NoError(t, scheduler.Resume())
+	assert.True(t, blockScheduler.IsRunning())
+	assert.True(t, isRetryRunning(scheduler.retryService))
+	assert.Equal(t, 2, blockScheduler.starts)
+
+	stats = scheduler.GetStats()
+	assert.Equal(t, false, stats["paused"])
+	assert.NotContains(t, stats, "paused_at")
+}
+
+func TestSchedulerService_SwitchMode(t *testing.T) {
+	blockScheduler := &fakeBlockScheduler{}
+	scheduler := newTestSchedulerService(t, "polling", blockScheduler)
+	ctx := context.Background()
+
+	require.NoError(t, scheduler.Start(ctx))
+	defer scheduler.Stop()
+	assert.Equal(t, true, scheduler.GetStats()["polling_active"])
+	assert.False(t, blockScheduler.IsRunning())
+
+	require.NoError(t, scheduler.SetMode(RealtimeMode))
+	assert.Equal(t, RealtimeMode, scheduler.GetMode())
+	assert.Equal(t, false, scheduler.GetStats()["polling_active"])
+	assert.True(t, blockScheduler.IsRunning())
+
+	require.NoError(t, scheduler.SetMode(HybridMode))
+	assert.Equal(t, "hybrid", scheduler.GetStats()["mode"])
+	assert.Equal(t, 2, blockScheduler.starts)
+	assert.Equal(t, 1, blockScheduler.stops)
+
+	assert.Error(t, scheduler.SetMode("bogus"))
+
+	// A failed switch goes back to the previous mode
+	require.NoError(t, scheduler.SetMode(PollingMode))
+	blockScheduler.startErr = errors.New("dial failed")
+	assert.Error(t, scheduler.SetMode(RealtimeMode))
+	assert.Equal(t, PollingMode, scheduler.GetMode())
+	assert.Equal(t, true, scheduler.GetStats()["polling_active"])
+
+	// A paused scheduler applies the mode on resume
+	blockScheduler.startErr = nil
+	require.NoError(t, scheduler.Pause())
+	require.NoError(t, scheduler.SetMode(RealtimeMode))
+	assert.False(t, blockScheduler.IsRunning())
+	require.NoError(t, scheduler.Resume())
+	assert.True(t, blockScheduler.IsRunning())
+}
+
+func TestSchedulerService_SetPollingInterval(t *testing.T) {
+	scheduler := newTestSchedulerService(t, "polling", nil)
+
+	require.NoError(t, scheduler.Start(context.Background()))
+	defer scheduler.Stop()
+
+	assert.Error(t, scheduler.SetPollingInterval(0))
+	require.NoError(t, scheduler.SetPollingInterval(5*time.Second))
+	assert.Equal(t, "5s", scheduler.GetStats()["polling_interval"])
+
+	scheduler.RegisterStats("work", func(ctx context.Context) map[string]interface{} {
+		return map[string]interface{}{"work_ranges_failed": int64(1)}
+	})
+	assert.Equal(t, map[string]interface{}{"work_ranges_failed": int64(1)}, scheduler.GetStats()["work"])
+
+	assert.Error(t, scheduler.SetMode(RealtimeMode), "realtime needs a block scheduler")
+}
+
+func TestSchedulerService_FillsCheckpointGap(t *testing.T) {
+	cfg := &config.Config{
+		Ethereum: config.EthereumConfig{Network: "ethereum"},
+		Scheduler: config.SchedulerConfig{
+			Mode:            "realtime",
+			PollingInterval: time.Hour,
+			RetryInterval:   time.Hour,
+			MaxRetries:      3,
+			RetryBaseDelay:  time.Second,
+			RetryMaxDelay:   time.Minute,
+		},
+	}
+	log := newTestLogger(t)
+	ctx := context.Background()
+
+	blocks := &fakeBlockRepository{blocks: map[uint64]*entity.Block{
+		100: {Number: "100", Status: entity.BlockStatusProcessed},
+		102: {Number: "102", Status: entity.BlockStatusPending}, // Its commit didn't finish
+	}}
+	retries := &fakeBlockRetryRepository{retries: map[uint64]*entity.BlockRetry{
+		101: {BlockNumber: 101, Status: entity.BlockRetryStatusPending},
+	}}
+
+	checkpointRepo := &memoryCheckpointRepository{}
+	checkpoints := NewCheckpointService(checkpointRepo, cfg, log)
+	_, err := checkpoints.Load(ctx, 100)
+	require.NoError(t, err)
+	require.NoError(t, checkpoints.MarkCommitted(ctx, 105))
+
+	// The crawler isn't running, every block it is asked to crawl fails
+	crawler := NewCrawlerService(nil, nil, blocks, nil, nil, nil, nil, cfg, log)
+	crawler.SetCheckpointService(checkpoints)
+	leaderElection := NewLeaderElectionService(&memoryLeaseRepository{}, cfg, log)
+	require.NoError(t, leaderElection.Start(ctx, LeaderCallbacks{}))
+	scheduler := NewSchedulerService(&fakeBlockScheduler{}, crawler, NewRetryService(retries, crawler, cfg, log), leaderElection, cfg, log)
+	scheduler.lastHead = &entity.BlockHeader{Number: big.NewInt(106)}
+
+	// Blocks 100 to 103 are published as work ranges
+	coordinator := newTestWorkCoordinatorWithEnd(t, &memoryWorkRangeRepository{}, &fakeHeadBlockchainService{}, config.WorkConfig{EndBlock: 103})
+	scheduler.SetWorkCoordinator(coordinator)
+
+	// Stored block 100 is committed, queued block 101 is left to the retry
+	// worker, 102 and 103 to the workers, 104 is crawled
+	scheduler.fillCheckpointGap(ctx, make(chan struct{}))
+	assert.Equal(t, uint64(101), checkpoints.NextBlock())
+	assert.Equal(t, uint64(100), checkpointRepo.checkpoint.CommittedBlock)
+	assert.Equal(t, 0, retries.retries[101].Attempts, "the queued block isn't crawled")
+	assert.NotContains(t, retries.retries, uint64(102))
+	assert.NotContains(t, retries.retries, uint64(103))
+	assert.Contains(t, retries.retries, uint64(104))
+	assert.NotContains(t, retries.retries, uint64(106), "the head is crawled when announced")
+	assert.Equal(t, int64(1), scheduler.gapBlocksFilled)
+
+	// Without work distribution the scheduler crawls them itself
+	scheduler.SetWorkCoordinator(nil)
+	scheduler.fillCheckpointGap(ctx, make(chan struct{}))
+	assert.Contains(t, retries.retries, uint64(102))
+	assert.Contains(t, retries.retries, uint64(103))
+	assert.Equal(t, int64(3), scheduler.gapBlocksFilled)
+
+	// Followers leave the gap alone
+	require.NoError(t, leaderElection.Stop(ctx))
+	delete(retries.retries, 104)
+	scheduler.fillCheckpointGap(ctx, make(chan struct{}))
+	assert.NotContains(t, retries.retries, uint64(104))
+}
+
+func TestSchedulerService_AdvancesPastFailedWorkRange(t *testing.T) {
+	cfg := &config.Config{
+		Ethereum: config.EthereumConfig{Network: "ethereum"},
+		Scheduler: config.SchedulerConfig{
+			Mode:            "realtime",
+			PollingInterval: time.Hour,
+			RetryInterval:   time.Hour,
+			MaxRetries:      3,
+			RetryBaseDelay:  time.Second,
+			RetryMaxDelay:   time.Minute,
+		},
+	}
+	log := newTestLogger(t)
+	ctx := context.Background()
+
+	blocks := &fakeBlockRepository{blocks: map[uint64]*entity.Block{}}
+	retries := &fakeBlockRetryRepository{retries: map[uint64]*entity.BlockRetry{}}
+
+	checkpointRepo := &memoryCheckpointRepository{}
+	checkpoints := NewCheckpointService(checkpointRepo, cfg, log)
+	_, err := checkpoints.Load(ctx, 100)
+	require.NoError(t, err)
+
+	crawler := NewCrawlerService(nil, nil, blocks, nil, nil, nil, nil, cfg, log)
+	crawler.SetCheckpointService(checkpoints)
+	leaderElection := NewLeaderElectionService(&memoryLeaseRepository{}, cfg, log)
+	require.NoError(t, leaderElection.Start(ctx, LeaderCallbacks{}))
+	defer leaderElection.Stop(ctx)
+	scheduler := NewSchedulerService(&fakeBlockScheduler{}, crawler, NewRetryService(retries, crawler, cfg, log), leaderElection, cfg, log)
+	scheduler.lastHead = &entity.BlockHeader{Number: big.NewInt(105)}
+
+	// Blocks 100 to 103 are published as one range, which fails on every attempt
+	repo := &memoryWorkRangeRepository{}
+	coordinator := newTestWorkCoordinatorWithEnd(t, repo, &fakeHeadBlockchainService{latest: 1000},
+		config.WorkConfig{EndBlock: 103, MaxAttempts: 2, FailedRetryDelay: time.Hour})
+	require.NoError(t, coordinator.reloadNextBlock(ctx))
+	require.NoError(t, coordinator.publishRanges(ctx))
+	require.Len(t, repo.ranges, 1)
+	scheduler.SetWorkCoordinator(coordinator)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		claimed, err := repo.ClaimWorkRange(ctx, "ethereum", "worker-1", time.Minute, 2)
+		require.NoError(t, err)
+		require.NoError(t, repo.ReleaseWorkRange(ctx, claimed.ID, "worker-1", "rpc error", 2))
+	}
+	require.Equal(t, entity.WorkRangeStatusFailed, repo.ranges[0].Status)
+
+	// The gap filler leaves the failed range to the workers
+	scheduler.fillCheckpointGap(ctx, make(chan struct{}))
+	assert.Equal(t, uint64(100), checkpoints.NextBlock())
+	assert.NotContains(t, retries.retries, uint64(100))
+
+	// It is requeued only after the delay
+	require.NoError(t, coordinator.requeueFailedRanges(ctx))
+	assert.Equal(t, entity.WorkRangeStatusFailed, repo.ranges[0].Status)
+	coordinator.failedRetryDelay = 0
+	require.NoError(t, coordinator.requeueFailedRanges(ctx))
+	assert.Equal(t, entity.WorkRangeStatusPending, repo.ranges[0].Status)
+	assert.Equal(t, 0, repo.ranges[0].Attempts)
+	assert.Equal(t, int64(1), coordinator.GetStats(ctx)["work_ranges_requeued"])
+
+	// A worker completes it and the checkpoint passes the range
+	completeWorkRanges(t, repo)
+	for number := uint64(100); number <= 103; number++ {
+		blocks.blocks[number] = &entity.Block{Number: fmt.Sprint(number), Status: entity.BlockStatusProcessed}
+	}
+	scheduler.fillCheckpointGap(ctx, make(chan struct{}))
+	assert.Equal(t, uint64(104), checkpoints.NextBlock())
+	assert.Equal(t, uint64(103), checkpointRepo.checkpoint.CommittedBlock)
+}
+
+// blockingBlockRetryRepository holds the retry queue counts until released
+type blockingBlockRetryRepository struct {
+	idleBlockRetryRepository
+	counting chan struct{}
+	release  chan struct{}
+}
+
+func (r *blockingBlockRetryRepository) GetBlockRetryCountByStatus(ctx context.Context, network string, status entity.BlockRetryStatus) (int64, error) {
+	select {
+	case r.counting <- struct{}{}:
+	default:
+	}
+	<-r.release
+	return 0, nil
+}
+
+func TestSchedulerService_GetStatsDoesNotHoldLockDuringQueries(t *testing.T) {
+	cfg := &config.Config{
+		Ethereum:  config.EthereumConfig{Network: "ethereum"},
+		Scheduler: config.SchedulerConfig{Mode: "polling", PollingInterval: time.Hour},
+	}
+	log := newTestLogger(t)
+	retries := &blockingBlockRetryRepository{counting: make(chan struct{}, 1), release: make(chan struct{})}
+	crawler := NewCrawlerService(nil, nil, nil, nil, nil, nil, nil, cfg, log)
+	scheduler := NewSchedulerService(nil, crawler, NewRetryService(retries, crawler, cfg, log),
+		NewLeaderElectionService(&memoryLeaseRepository{}, cfg, log), cfg, log)
+
+	statsDone := make(chan map[string]interface{})
+	go func() { statsDone <- scheduler.GetStats() }()
+	<-retries.counting
+
+	// Runtime control isn't blocked by the slow retry queue query
+	changed := make(chan error)
+	go func() { changed <- scheduler.SetPollingInterval(time.Minute) }()
+	select {
+	case err := <-changed:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("SetPollingInterval waited for GetStats")
+	}
+
+	close(retries.release)
+	stats := <-statsDone
+	assert.Equal(t, "1h0m0s", stats["polling_interval"])
+}
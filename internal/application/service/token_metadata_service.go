@@ -0,0 +1,563 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Function selectors of the token metadata calls
+var (
+	nameSelector              = []byte{0x06, 0xfd, 0xde, 0x03} // name()
+	symbolSelector            = []byte{0x95, 0xd8, 0x9b, 0x41} // symbol()
+	decimalsSelector          = []byte{0x31, 0x3c, 0xe5, 0x67} // decimals()
+	totalSupplySelector       = []byte{0x18, 0x16, 0x0d, 0xdd} // totalSupply()
+	supportsInterfaceSelector = []byte{0x01, 0xff, 0xc9, 0xa7} // supportsInterface(bytes4)
+)
+
+// ERC-165 interface IDs
+var (
+	erc165InterfaceID  = [4]byte{0x01, 0xff, 0xc9, 0xa7}
+	invalidInterfaceID = [4]byte{0xff, 0xff, 0xff, 0xff}
+
+	// Interfaces checked on tokens that implement ERC-165
+	tokenInterfaceIDs = []struct {
+		iface entity.TokenInterface
+		id    [4]byte
+	}{
+		{entity.TokenInterfaceERC721, [4]byte{0x80, 0xac, 0x58, 0xcd}},
+		{entity.TokenInterfaceERC721Metadata, [4]byte{0x5b, 0x5e, 0x13, 0x9f}},
+		{entity.TokenInterfaceERC1155, [4]byte{0xd9, 0xb6, 0x7a, 0x26}},
+		{entity.TokenInterfaceERC1155MetadataURI, [4]byte{0x0e, 0x89, 0x34, 0x1c}},
+	}
+)
+
+// maxTokenStringLength caps the bytes kept of a name or symbol
+const maxTokenStringLength = 256
+
+// TokenMetadataService reads the name, symbol, decimals, total supply and
+// ERC-165 interfaces of tokens with eth_call and caches them. Tokens are added
+// when their first transfer is seen and resolved by a worker, which also
+// retries failed lookups and refreshes the total supply of resolved tokens.
+// The rest is only read again on request.
+type TokenMetadataService struct {
+	tokenRepo         repository.TokenRepository
+	blockchainService service.BlockchainService
+	logger            *logger.Logger
+
+	interval        time.Duration
+	batchSize       int
+	refreshInterval time.Duration
+	retryInterval   time.Duration
+
+	// Wakes the worker when new tokens are seen
+	wake chan struct{}
+
+	isRunning bool
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+
+	// Statistics
+	tokensDiscovered  int64
+	tokensResolved    int64
+	suppliesRefreshed int64
+	lookupsFailed     int64
+	lastCheck         time.Time
+}
+
+// NewTokenMetadataService creates a new token metadata service
+func NewTokenMetadataService(
+	tokenRepo repository.TokenRepository,
+	blockchainService service.BlockchainService,
+	config *config.Config,
+	logger *logger.Logger,
+) *TokenMetadataService {
+	s := &TokenMetadataService{
+		tokenRepo:         tokenRepo,
+		blockchainService: blockchainService,
+		logger:            logger.WithComponent("token-metadata-service"),
+		interval:          30 * time.Second,
+		batchSize:         50,
+		refreshInterval:   24 * time.Hour,
+		retryInterval:     5 * time.Minute,
+		wake:              make(chan struct{}, 1),
+	}
+
+	if config.Tokens.Interval > 0 {
+		s.interval = config.Tokens.Interval
+	}
+	if config.Tokens.BatchSize > 0 {
+		s.batchSize = config.Tokens.BatchSize
+	}
+	if config.Tokens.RefreshInterval > 0 {
+		s.refreshInterval = config.Tokens.RefreshInterval
+	}
+	if config.Tokens.RetryInterval > 0 {
+		s.retryInterval = config.Tokens.RetryInterval
+	}
+
+	return s
+}
+
+// Start starts the lookup worker
+func (s *TokenMetadataService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRunning {
+		return fmt.Errorf("token metadata service is already running")
+	}
+
+	s.stopChan = make(chan struct{})
+	s.isRunning = true
+
+	// The worker outlives the start context and is stopped through stopChan
+	s.wg.Add(1)
+	go s.refreshWorker(context.WithoutCancel(ctx), s.stopChan)
+
+	s.logger.Info("Token metadata service started",
+		zap.Duration("interval", s.interval),
+		zap.Int("batch_size", s.batchSize),
+		zap.Duration("refresh_interval", s.refreshInterval),
+		zap.Duration("retry_interval", s.retryInterval))
+
+	return nil
+}
+
+// Stop stops the lookup worker and waits for the current batch to finish
+func (s *TokenMetadataService) Stop() error {
+	s.mu.Lock()
+	if !s.isRunning {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.stopChan)
+	s.isRunning = false
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.logger.Info("Token metadata service stopped")
+	return nil
+}
+
+// GetStats returns lookup statistics
+func (s *TokenMetadataService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]interface{}{
+		"is_running":         s.isRunning,
+		"tokens_discovered":  s.tokensDiscovered,
+		"tokens_resolved":    s.tokensResolved,
+		"supplies_refreshed": s.suppliesRefreshed,
+		"lookups_failed":     s.lookupsFailed,
+		"last_check":         s.lastCheck,
+	}
+}
+
+// Discover adds the tokens of transfers that are not stored yet, due for a
+// lookup right away
+func (s *TokenMetadataService) Discover(ctx context.Context, transfers []*entity.TokenTransfer) error {
+	now := time.Now()
+	seen := make(map[string]bool)
+	var tokens []*entity.Token
+	for _, transfer := range transfers {
+		if seen[transfer.Token] {
+			continue
+		}
+		seen[transfer.Token] = true
+		tokens = append(tokens, &entity.Token{
+			Address:        transfer.Token,
+			Standard:       transfer.Standard,
+			FirstSeenBlock: transfer.BlockNumber,
+			RefreshAt:      now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	added, err := s.tokenRepo.AddTokens(ctx, tokens)
+	if err != nil {
+		return fmt.Errorf("failed to add tokens: %w", err)
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.tokensDiscovered += int64(len(added))
+	s.mu.Unlock()
+	s.logger.Debug("New tokens seen", zap.Strings("tokens", added))
+
+	// Resolved without waiting for the next check
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// GetToken returns the cached metadata of a token, resolving it first when
+// it was never resolved
+func (s *TokenMetadataService) GetToken(ctx context.Context, address string) (*entity.Token, error) {
+	token, err := s.tokenRepo.GetToken(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	if token != nil && token.IsResolved() {
+		return token, nil
+	}
+	if token == nil {
+		token = &entity.Token{Address: address}
+	}
+
+	return s.Refresh(ctx, token)
+}
+
+// RefreshTokens looks up one batch of tokens due for a lookup, longest due
+// first, and returns how many of them were resolved
+func (s *TokenMetadataService) RefreshTokens(ctx context.Context, stopChan chan struct{}) (int, error) {
+	tokens, err := s.tokenRepo.GetTokensToRefresh(ctx, time.Now(), s.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get tokens to refresh: %w", err)
+	}
+
+	resolved := 0
+	for _, token := range tokens {
+		select {
+		case <-stopChan:
+			return resolved, nil
+		default:
+		}
+
+		if _, err := s.Refresh(ctx, token); err != nil {
+			s.logger.Warn("Failed to refresh token",
+				zap.String("token", token.Address),
+				zap.Error(err))
+			continue
+		}
+		resolved++
+	}
+
+	s.mu.Lock()
+	s.lastCheck = time.Now()
+	s.mu.Unlock()
+
+	if len(tokens) > 0 {
+		s.logger.Info("Token metadata check finished",
+			zap.Int("checked_tokens", len(tokens)),
+			zap.Int("resolved_tokens", resolved))
+	}
+
+	return resolved, nil
+}
+
+// Refresh looks a token up and stores the result: the total supply of a
+// resolved token, everything of a token never resolved or whose last lookup
+// failed. The metadata is refreshed again after the refresh interval; a
+// failed lookup keeps the metadata read before and is retried after the
+// retry interval, doubled per failure.
+func (s *TokenMetadataService) Refresh(ctx context.Context, token *entity.Token) (*entity.Token, error) {
+	if token.IsResolved() && token.Failures == 0 {
+		return s.lookup(ctx, token, true)
+	}
+	return s.lookup(ctx, token, false)
+}
+
+// ResolveToken reads all the metadata of a token again and stores it, also
+// when it was resolved before
+func (s *TokenMetadataService) ResolveToken(ctx context.Context, address string) (*entity.Token, error) {
+	token, err := s.tokenRepo.GetToken(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	if token == nil {
+		token = &entity.Token{Address: address}
+	}
+
+	return s.lookup(ctx, token, false)
+}
+
+// lookup reads the total supply of a token, or all of its metadata, and
+// stores the result
+func (s *TokenMetadataService) lookup(ctx context.Context, token *entity.Token, supplyOnly bool) (*entity.Token, error) {
+	refreshed := *token
+	now := time.Now()
+
+	var (
+		metadata  *entity.Token
+		lookupErr error
+	)
+	if supplyOnly {
+		metadata, lookupErr = s.resolveTotalSupply(ctx, token)
+	} else {
+		metadata, lookupErr = s.Resolve(ctx, token.Address)
+	}
+	if lookupErr != nil {
+		refreshed.Failures++
+		refreshed.LastError = lookupErr.Error()
+		refreshed.RefreshAt = now.Add(s.retryDelay(refreshed.Failures))
+	} else {
+		refreshed.Name = metadata.Name
+		refreshed.Symbol = metadata.Symbol
+		refreshed.Decimals = metadata.Decimals
+		refreshed.TotalSupply = metadata.TotalSupply
+		refreshed.Interfaces = metadata.Interfaces
+		if metadata.Standard != "" {
+			refreshed.Standard = metadata.Standard
+		}
+		refreshed.ResolvedAt = now
+		refreshed.Failures = 0
+		refreshed.LastError = ""
+		refreshed.RefreshAt = now.Add(s.refreshInterval)
+	}
+	refreshed.UpdatedAt = now
+
+	if err := s.tokenRepo.SaveToken(ctx, &refreshed); err != nil {
+		return nil, fmt.Errorf("failed to save token: %w", err)
+	}
+
+	s.mu.Lock()
+	switch {
+	case lookupErr != nil:
+		s.lookupsFailed++
+	case supplyOnly:
+		s.suppliesRefreshed++
+	default:
+		s.tokensResolved++
+	}
+	s.mu.Unlock()
+
+	if lookupErr != nil {
+		return nil, fmt.Errorf("failed to resolve token %s: %w", token.Address, lookupErr)
+	}
+	return &refreshed, nil
+}
+
+// retryDelay returns the delay before a lookup that failed the given number
+// of times in a row is retried
+func (s *TokenMetadataService) retryDelay(failures int) time.Duration {
+	delay := s.retryInterval
+	for i := 1; i < failures && delay < s.refreshInterval; i++ {
+		delay *= 2
+	}
+	return min(delay, s.refreshInterval)
+}
+
+// Resolve reads the metadata of a token contract at the latest block. Calls
+// the contract doesn't implement leave their field empty; an RPC failure
+// fails the lookup.
+func (s *TokenMetadataService) Resolve(ctx context.Context, address string) (*entity.Token, error) {
+	token := &entity.Token{Address: address}
+
+	interfaces, err := s.supportedInterfaces(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	token.Interfaces = interfaces
+	switch {
+	case token.SupportsInterface(entity.TokenInterfaceERC1155):
+		token.Standard = entity.TokenStandardERC1155
+	case token.SupportsInterface(entity.TokenInterfaceERC721):
+		token.Standard = entity.TokenStandardERC721
+	}
+
+	ret, err := s.call(ctx, address, nameSelector)
+	if err != nil {
+		return nil, err
+	}
+	token.Name = decodeABIString(ret)
+
+	ret, err = s.call(ctx, address, symbolSelector)
+	if err != nil {
+		return nil, err
+	}
+	token.Symbol = decodeABIString(ret)
+
+	ret, err = s.call(ctx, address, decimalsSelector)
+	if err != nil {
+		return nil, err
+	}
+	if decimals, ok := decodeABIUint(ret); ok && decimals.IsUint64() && decimals.Uint64() <= 255 {
+		value := uint8(decimals.Uint64())
+		token.Decimals = &value
+	}
+
+	ret, err = s.call(ctx, address, totalSupplySelector)
+	if err != nil {
+		return nil, err
+	}
+	if totalSupply, ok := decodeABIUint(ret); ok {
+		token.TotalSupply = totalSupply.String()
+	}
+
+	return token, nil
+}
+
+// resolveTotalSupply reads the total supply of a resolved token, the rest of
+// its metadata is returned as stored
+func (s *TokenMetadataService) resolveTotalSupply(ctx context.Context, token *entity.Token) (*entity.Token, error) {
+	metadata := *token
+
+	ret, err := s.call(ctx, token.Address, totalSupplySelector)
+	if err != nil {
+		return nil, err
+	}
+	metadata.TotalSupply = ""
+	if totalSupply, ok := decodeABIUint(ret); ok {
+		metadata.TotalSupply = totalSupply.String()
+	}
+
+	return &metadata, nil
+}
+
+// supportedInterfaces returns the token interfaces a contract reports with
+// ERC-165, nil when it doesn't implement ERC-165
+func (s *TokenMetadataService) supportedInterfaces(ctx context.Context, address string) ([]entity.TokenInterface, error) {
+	supports := func(id [4]byte) (bool, error) {
+		ret, err := s.call(ctx, address, append(bytes.Clone(supportsInterfaceSelector), abiBytes4(id)...))
+		if err != nil {
+			return false, err
+		}
+		value, ok := decodeABIUint(ret)
+		return ok && value.Cmp(big.NewInt(1)) == 0, nil
+	}
+
+	// Detection as in the ERC-165 specification, a contract answering true
+	// to the invalid ID answers true to anything
+	if ok, err := supports(erc165InterfaceID); err != nil || !ok {
+		return nil, err
+	}
+	if ok, err := supports(invalidInterfaceID); err != nil || ok {
+		return nil, err
+	}
+
+	interfaces := []entity.TokenInterface{entity.TokenInterfaceERC165}
+	for _, candidate := range tokenInterfaceIDs {
+		ok, err := supports(candidate.id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			interfaces = append(interfaces, candidate.iface)
+		}
+	}
+	return interfaces, nil
+}
+
+// call calls a token function, returning nil when the contract doesn't implement it
+func (s *TokenMetadataService) call(ctx context.Context, address string, data []byte) ([]byte, error) {
+	ret, err := s.blockchainService.CallContract(ctx, address, data, nil)
+	if errors.Is(err, service.ErrExecutionReverted) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("eth_call 0x%x failed: %w", data[:4], err)
+	}
+	return ret, nil
+}
+
+// abiBytes4 encodes a bytes4 argument, left-aligned in its word
+func abiBytes4(value [4]byte) []byte {
+	word := make([]byte, 32)
+	copy(word, value[:])
+	return word
+}
+
+// decodeABIUint decodes a uint return value
+func decodeABIUint(ret []byte) (*big.Int, bool) {
+	if len(ret) < 32 {
+		return nil, false
+	}
+	return new(big.Int).SetBytes(ret[:32]), true
+}
+
+// decodeABIString decodes a string return value, or the bytes32 legacy
+// tokens return instead. Returns "" when it is neither.
+func decodeABIString(ret []byte) string {
+	if len(ret) == 32 {
+		return tokenString(ret)
+	}
+	if len(ret) < 64 {
+		return ""
+	}
+
+	// Offset of the length word, then the length of the string after it
+	offset, ok := decodeABIUint(ret)
+	if !ok || !offset.IsInt64() || offset.Int64() > int64(len(ret)-32) {
+		return ""
+	}
+	start := int(offset.Int64()) + 32
+	length, _ := decodeABIUint(ret[start-32:])
+	if !length.IsInt64() || length.Int64() > int64(len(ret)-start) {
+		return ""
+	}
+	return tokenString(ret[start : start+int(length.Int64())])
+}
+
+// tokenString cleans up a name or symbol: null bytes, invalid UTF-8 and
+// surrounding spaces are dropped and the length is capped
+func tokenString(value []byte) string {
+	value = bytes.ReplaceAll(value, []byte{0}, nil)
+	if len(value) > maxTokenStringLength {
+		value = value[:maxTokenStringLength]
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(string(value), ""))
+}
+
+// refreshWorker looks up tokens due on every interval and as new tokens are seen
+func (s *TokenMetadataService) refreshWorker(ctx context.Context, stopChan chan struct{}) {
+	defer s.wg.Done()
+
+	// Add panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic recovered in refreshWorker",
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+
+		resolved, err := s.RefreshTokens(ctx, stopChan)
+		if err != nil {
+			s.logger.Error("Failed to refresh tokens", zap.Error(err))
+			continue
+		}
+		// A full batch resolved, more tokens may be due
+		if resolved == s.batchSize {
+			select {
+			case s.wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTokenRepository keeps tokens in a map
+type fakeTokenRepository struct {
+	repository.TokenRepository
+	tokens map[string]*entity.Token
+}
+
+func newFakeTokenRepository() *fakeTokenRepository {
+	return &fakeTokenRepository{tokens: map[string]*entity.Token{}}
+}
+
+func (r *fakeTokenRepository) AddTokens(ctx context.Context, tokens []*entity.Token) ([]string, error) {
+	var added []string
+	for _, token := range tokens {
+		if _, ok := r.tokens[token.Address]; !ok {
+			copied := *token
+			r.tokens[token.Address] = &copied
+			added = append(added, token.Address)
+		}
+	}
+	sort.Strings(added)
+	return added, nil
+}
+
+func (r *fakeTokenRepository) SaveToken(ctx context.Context, token *entity.Token) error {
+	copied := *token
+	if stored, ok := r.tokens[token.Address]; ok {
+		copied.FirstSeenBlock = stored.FirstSeenBlock
+		if !copied.IsResolved() {
+			copied.ResolvedAt = stored.ResolvedAt
+		}
+	}
+	r.tokens[token.Address] = &copied
+	return nil
+}
+
+func (r *fakeTokenRepository) GetToken(ctx context.Context, address string) (*entity.Token, error) {
+	token, ok := r.tokens[address]
+	if !ok {
+		return nil, nil
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (r *fakeTokenRepository) GetTokensToRefresh(ctx context.Context, now time.Time, limit int) ([]*entity.Token, error) {
+	var tokens []*entity.Token
+	for _, token := range r.tokens {
+		if !token.RefreshAt.After(now) {
+			copied := *token
+			tokens = append(tokens, &copied)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].RefreshAt.Before(tokens[j].RefreshAt) })
+	if len(tokens) > limit {
+		tokens = tokens[:limit]
+	}
+	return tokens, nil
+}
+
+// fakeCallBlockchain answers eth_calls by contract and call data; calls it
+// doesn't know revert
+type fakeCallBlockchain struct {
+	service.BlockchainService
+	returns map[string]map[string][]byte // Address, then hex call data
+	err     error                        // Fails every call
+	calls   int
+}
+
+func (b *fakeCallBlockchain) CallContract(ctx context.Context, address string, data []byte, blockNumber *big.Int) ([]byte, error) {
+	b.calls++
+	if b.err != nil {
+		return nil, b.err
+	}
+	ret, ok := b.returns[address][hex.EncodeToString(data)]
+	if !ok {
+		return nil, service.ErrExecutionReverted
+	}
+	return ret, nil
+}
+
+// abiWord encodes a uint return value
+func abiWord(value *big.Int) []byte {
+	word := make([]byte, 32)
+	return value.FillBytes(word)
+}
+
+// abiString encodes a string return value
+func abiString(value string) []byte {
+	data := make([]byte, (len(value)+31)/32*32)
+	copy(data, value)
+	ret := append(abiWord(big.NewInt(32)), abiWord(big.NewInt(int64(len(value))))...)
+	return append(ret, data...)
+}
+
+// bytes32 encodes a bytes32 return value
+func bytes32(value string) []byte {
+	word := make([]byte, 32)
+	copy(word, value)
+	return word
+}
+
+// supportsInterfaceCall is the hex call data of supportsInterface
+func supportsInterfaceCall(id string) string {
+	return "01ffc9a7" + id + "00000000000000000000000000000000000000000000000000000000"
+}
+
+func newTokenMetadataTestService(t *testing.T) (*TokenMetadataService, *fakeTokenRepository, *fakeCallBlockchain) {
+	tokens := newFakeTokenRepository()
+	blockchain := &fakeCallBlockchain{returns: map[string]map[string][]byte{}}
+	cfg := &config.Config{Tokens: config.TokensConfig{
+		Enabled:         true,
+		BatchSize:       10,
+		RefreshInterval: time.Hour,
+		RetryInterval:   time.Minute,
+	}}
+	return NewTokenMetadataService(tokens, blockchain, cfg, newTestLogger(t)), tokens, blockchain
+}
+
+func TestTokenMetadataServiceResolve(t *testing.T) {
+	s, _, blockchain := newTokenMetadataTestService(t)
+	supply, _ := new(big.Int).SetString("115792089237316195423570985008687907853269984665640564039457584007913129639935", 10)
+	yes, no := abiWord(big.NewInt(1)), abiWord(big.NewInt(0))
+
+	// An ERC-20 without ERC-165 and a bytes32 symbol, like MKR
+	blockchain.returns["0xcoin"] = map[string][]byte{
+		"06fdde03": abiString("Maker Coin"),
+		"95d89b41": bytes32("MKR"),
+		"313ce567": abiWord(big.NewInt(18)),
+		"18160ddd": abiWord(supply),
+	}
+	// An ERC-721 with metadata and without decimals
+	blockchain.returns["0xnft"] = map[string][]byte{
+		supportsInterfaceCall("01ffc9a7"): yes,
+		supportsInterfaceCall("ffffffff"): no,
+		supportsInterfaceCall("80ac58cd"): yes,
+		supportsInterfaceCall("5b5e139f"): yes,
+		supportsInterfaceCall("d9b67a26"): no,
+		"06fdde03":                        abiString("Kitties"),
+		"95d89b41":                        abiString("CK"),
+		"18160ddd":                        abiWord(big.NewInt(2000)),
+	}
+	// Answers true to anything, decimals out of range
+	blockchain.returns["0xliar"] = map[string][]byte{
+		supportsInterfaceCall("01ffc9a7"): yes,
+		supportsInterfaceCall("ffffffff"): yes,
+		"313ce567":                        abiWord(big.NewInt(256)),
+	}
+
+	coin, err := s.Resolve(context.Background(), "0xcoin")
+	require.NoError(t, err)
+	assert.Equal(t, "Maker Coin", coin.Name)
+	assert.Equal(t, "MKR", coin.Symbol)
+	require.NotNil(t, coin.Decimals)
+	assert.Equal(t, uint8(18), *coin.Decimals)
+	assert.Equal(t, supply.String(), coin.TotalSupply)
+	assert.Empty(t, coin.Interfaces)
+	assert.Empty(t, coin.Standard)
+
+	nft, err := s.Resolve(context.Background(), "0xnft")
+	require.NoError(t, err)
+	assert.Equal(t, entity.TokenStandardERC721, nft.Standard)
+	assert.Equal(t, []entity.TokenInterface{
+		entity.TokenInterfaceERC165, entity.TokenInterfaceERC721, entity.TokenInterfaceERC721Metadata,
+	}, nft.Interfaces)
+	assert.Equal(t, "Kitties", nft.Name)
+	assert.Nil(t, nft.Decimals)
+	assert.Equal(t, "2000", nft.TotalSupply)
+
+	liar, err := s.Resolve(context.Background(), "0xliar")
+	require.NoError(t, err)
+	assert.Empty(t, liar.Interfaces)
+	assert.Nil(t, liar.Decimals)
+
+	blockchain.err = errors.New("request timed out")
+	_, err = s.Resolve(context.Background(), "0xcoin")
+	assert.ErrorContains(t, err, "request timed out")
+}
+
+func TestDecodeABIString(t *testing.T) {
+	malformed := abiString("name")
+	malformed[31] = 0xff // Offset past the end
+
+	tests := []struct {
+		name string
+		ret  []byte
+		want string
+	}{
+		{"string", abiString("Wrapped Ether"), "Wrapped Ether"},
+		{"long string", abiString("A token name longer than one ABI word"), "A token name longer than one ABI word"},
+		{"bytes32", bytes32("DAI"), "DAI"},
+		{"invalid UTF-8", bytes32("OK\xff"), "OK"},
+		{"empty", nil, ""},
+		{"short", []byte{0x01}, ""},
+		{"bad offset", malformed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, decodeABIString(tt.ret))
+		})
+	}
+}
+
+func TestTokenMetadataServiceDiscoverAndRefresh(t *testing.T) {
+	s, tokens, blockchain := newTokenMetadataTestService(t)
+	blockchain.returns["0xcoin"] = map[string][]byte{
+		"06fdde03": abiString("Coin"),
+		"95d89b41": abiString("CN"),
+		"313ce567": abiWord(big.NewInt(6)),
+	}
+
+	transfers := []*entity.TokenTransfer{
+		{Token: "0xcoin", Standard: entity.TokenStandardERC20, BlockNumber: 100},
+		{Token: "0xcoin", Standard: entity.TokenStandardERC20, BlockNumber: 100},
+		{Token: "0xnft", Standard: entity.TokenStandardERC1155, BlockNumber: 100},
+	}
+	require.NoError(t, s.Discover(context.Background(), transfers))
+	require.Len(t, tokens.tokens, 2)
+	assert.Equal(t, uint64(100), tokens.tokens["0xcoin"].FirstSeenBlock)
+	assert.False(t, tokens.tokens["0xcoin"].IsResolved())
+
+	// Seen again, nothing changes
+	require.NoError(t, s.Discover(context.Background(), transfers))
+	assert.Equal(t, int64(2), s.GetStats()["tokens_discovered"])
+
+	// Both resolve, the one without metadata keeps the standard of its transfers
+	resolved, err := s.RefreshTokens(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 2, resolved)
+	coin := tokens.tokens["0xcoin"]
+	assert.True(t, coin.IsResolved())
+	assert.Equal(t, "Coin", coin.Name)
+	assert.Equal(t, entity.TokenStandardERC20, coin.Standard)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), coin.RefreshAt, time.Minute)
+	assert.Equal(t, entity.TokenStandardERC1155, tokens.tokens["0xnft"].Standard)
+
+	// Nothing is due until the refresh interval passes
+	resolved, err = s.RefreshTokens(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 0, resolved)
+
+	// A failed lookup keeps the metadata and is retried later, and later
+	// still after another failure
+	blockchain.err = errors.New("request timed out")
+	coin.RefreshAt = time.Now().Add(-time.Second)
+	_, err = s.Refresh(context.Background(), coin)
+	assert.ErrorContains(t, err, "request timed out")
+	coin = tokens.tokens["0xcoin"]
+	assert.Equal(t, 1, coin.Failures)
+	assert.Equal(t, "Coin", coin.Name)
+	assert.True(t, coin.IsResolved())
+	assert.WithinDuration(t, time.Now().Add(time.Minute), coin.RefreshAt, 10*time.Second)
+
+	_, err = s.Refresh(context.Background(), coin)
+	require.Error(t, err)
+	coin = tokens.tokens["0xcoin"]
+	assert.Equal(t, 2, coin.Failures)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), coin.RefreshAt, 10*time.Second)
+
+	blockchain.err = nil
+	_, err = s.Refresh(context.Background(), coin)
+	require.NoError(t, err)
+	assert.Equal(t, 0, tokens.tokens["0xcoin"].Failures)
+	assert.Empty(t, tokens.tokens["0xcoin"].LastError)
+}
+
+func TestTokenMetadataServiceRefreshesTotalSupply(t *testing.T) {
+	s, tokens, blockchain := newTokenMetadataTestService(t)
+	blockchain.returns["0xcoin"] = map[string][]byte{
+		"06fdde03": abiString("Coin"),
+		"95d89b41": abiString("CN"),
+		"18160ddd": abiWord(big.NewInt(1000)),
+	}
+	_, err := s.GetToken(context.Background(), "0xcoin")
+	require.NoError(t, err)
+
+	// A resolved token only reads its total supply again
+	blockchain.returns["0xcoin"]["06fdde03"] = abiString("Renamed")
+	blockchain.returns["0xcoin"]["18160ddd"] = abiWord(big.NewInt(2000))
+	tokens.tokens["0xcoin"].RefreshAt = time.Now().Add(-time.Second)
+	calls := blockchain.calls
+	resolved, err := s.RefreshTokens(context.Background(), make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, resolved)
+	assert.Equal(t, calls+1, blockchain.calls)
+	coin := tokens.tokens["0xcoin"]
+	assert.Equal(t, "2000", coin.TotalSupply)
+	assert.Equal(t, "Coin", coin.Name)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), coin.RefreshAt, time.Minute)
+	assert.Equal(t, int64(1), s.GetStats()["supplies_refreshed"])
+
+	// After a failed lookup everything is read again
+	blockchain.err = errors.New("request timed out")
+	_, err = s.Refresh(context.Background(), coin)
+	require.Error(t, err)
+	blockchain.err = nil
+	_, err = s.Refresh(context.Background(), tokens.tokens["0xcoin"])
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", tokens.tokens["0xcoin"].Name)
+
+	// And on request
+	blockchain.returns["0xcoin"]["95d89b41"] = abiString("RN")
+	coin, err = s.ResolveToken(context.Background(), "0xcoin")
+	require.NoError(t, err)
+	assert.Equal(t, "RN", coin.Symbol)
+	assert.Equal(t, "RN", tokens.tokens["0xcoin"].Symbol)
+}
+
+func TestTokenMetadataServiceRetryDelay(t *testing.T) {
+	s, _, _ := newTokenMetadataTestService(t)
+
+	assert.Equal(t, time.Minute, s.retryDelay(1))
+	assert.Equal(t, 4*time.Minute, s.retryDelay(3))
+	assert.Equal(t, time.Hour, s.retryDelay(10), "capped at the refresh interval")
+	assert.Equal(t, time.Hour, s.retryDelay(1000))
+}
+
+func TestTokenMetadataServiceGetTokenCached(t *testing.T) {
+	s, _, blockchain := newTokenMetadataTestService(t)
+	blockchain.returns["0xcoin"] = map[string][]byte{"95d89b41": abiString("CN")}
+
+	token, err := s.GetToken(context.Background(), "0xcoin")
+	require.NoError(t, err)
+	assert.Equal(t, "CN", token.Symbol)
+	calls := blockchain.calls
+
+	token, err = s.GetToken(context.Background(), "0xcoin")
+	require.NoError(t, err)
+	assert.Equal(t, "CN", token.Symbol)
+	assert.Equal(t, calls, blockchain.calls, "served from the cache")
+
+	blockchain.err = errors.New("request timed out")
+	_, err = s.GetToken(context.Background(), "0xother")
+	assert.ErrorContains(t, err, "request timed out")
+}
+
+func TestTokenTransferServiceDiscoversTokens(t *testing.T) {
+	transfers, _, _ := newTokenTransferTestService(t, false)
+	metadata, tokens, _ := newTokenMetadataTestService(t)
+	transfers.SetTokenMetadataService(metadata)
+
+	_, err := transfers.RecordBlock(context.Background(), []*entity.Transaction{
+		{Hash: "0x1", TokenTransfers: []*entity.TokenTransfer{{TransactionHash: "0x1", Token: "0xcoin", BlockNumber: 7}}},
+	})
+	require.NoError(t, err)
+	require.Contains(t, tokens.tokens, "0xcoin")
+	assert.Equal(t, uint64(7), tokens.tokens["0xcoin"].FirstSeenBlock)
+}
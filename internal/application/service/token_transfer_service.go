@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// TokenTransferService stores the token transfers decoded from the receipts
+// of processed blocks and publishes them
+type TokenTransferService struct {
+	transferRepo     repository.TokenTransferRepository
+	messagingService service.MessagingService
+	publish          bool
+	logger           *logger.Logger
+
+	// Adds the tokens seen for the first time, nil when disabled
+	metadata *TokenMetadataService
+	mu       sync.RWMutex
+}
+
+// NewTokenTransferService creates a new token transfer service
+func NewTokenTransferService(
+	transferRepo repository.TokenTransferRepository,
+	messagingService service.MessagingService,
+	config *config.Config,
+	logger *logger.Logger,
+) *TokenTransferService {
+	return &TokenTransferService{
+		transferRepo:     transferRepo,
+		messagingService: messagingService,
+		publish:          config.TokenTransfers.Publish,
+		logger:           logger.WithComponent("token-transfer-service"),
+	}
+}
+
+// SetTokenMetadataService makes recorded transfers add their tokens to the
+// token metadata cache
+func (s *TokenTransferService) SetTokenMetadataService(metadata *TokenMetadataService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata = metadata
+}
+
+// tokenMetadataService returns the token metadata service, nil when not set
+func (s *TokenTransferService) tokenMetadataService() *TokenMetadataService {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.metadata
+}
+
+// RecordBlock stores the token transfers of the transactions of a block and
+// returns them. Recording a block again replaces its transfers.
+func (s *TokenTransferService) RecordBlock(ctx context.Context, transactions []*entity.Transaction) ([]*entity.TokenTransfer, error) {
+	transfers := tokenTransfers(transactions)
+	if len(transfers) == 0 {
+		return nil, nil
+	}
+
+	if err := s.transferRepo.SaveTokenTransfers(ctx, transfers); err != nil {
+		return nil, fmt.Errorf("failed to save token transfers: %w", err)
+	}
+
+	// Metadata is resolved later, a token missed here is added the next time
+	// it is seen or when it is looked up
+	if metadata := s.tokenMetadataService(); metadata != nil {
+		if err := metadata.Discover(ctx, transfers); err != nil {
+			s.logger.Warn("Failed to add new tokens", zap.Error(err))
+		}
+	}
+	return transfers, nil
+}
+
+// DeleteBlock deletes the token transfers of a block
+func (s *TokenTransferService) DeleteBlock(ctx context.Context, blockHash string) error {
+	if err := s.transferRepo.DeleteTokenTransfersByBlockHash(ctx, blockHash); err != nil {
+		return fmt.Errorf("failed to delete token transfers: %w", err)
+	}
+	return nil
+}
+
+// Publish publishes token transfer events, unless publishing is disabled or
+// the messaging service is not connected
+func (s *TokenTransferService) Publish(ctx context.Context, transfers []*entity.TokenTransfer) error {
+	if len(transfers) == 0 || !s.publish || s.messagingService == nil || !s.messagingService.IsConnected() {
+		return nil
+	}
+
+	if err := s.messagingService.PublishTokenTransfers(ctx, transfers); err != nil {
+		return err
+	}
+
+	s.logger.Debug("Published token transfers", zap.Int("transfer_count", len(transfers)))
+	return nil
+}
+
+// tokenTransfers returns the token transfers of transactions, with the block
+// time and metadata of their transaction
+func tokenTransfers(transactions []*entity.Transaction) []*entity.TokenTransfer {
+	var transfers []*entity.TokenTransfer
+	for _, tx := range transactions {
+		for _, transfer := range tx.TokenTransfers {
+			transfer.BlockTimestamp = tx.BlockTimestamp
+			transfer.Network = tx.Network
+			transfer.CrawledAt = tx.CrawledAt
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTokenTransferRepository keeps transfers by transaction, log index and batch index
+type fakeTokenTransferRepository struct {
+	repository.TokenTransferRepository
+	transfers map[string]*entity.TokenTransfer
+	err       error
+}
+
+func newFakeTokenTransferRepository() *fakeTokenTransferRepository {
+	return &fakeTokenTransferRepository{transfers: map[string]*entity.TokenTransfer{}}
+}
+
+func (r *fakeTokenTransferRepository) SaveTokenTransfers(ctx context.Context, transfers []*entity.TokenTransfer) error {
+	if r.err != nil {
+		return r.err
+	}
+	for _, transfer := range transfers {
+		key := fmt.Sprintf("%s:%d:%d", transfer.TransactionHash, transfer.LogIndex, transfer.BatchIndex)
+		r.transfers[key] = transfer
+	}
+	return nil
+}
+
+func (r *fakeTokenTransferRepository) DeleteTokenTransfersByBlockHash(ctx context.Context, blockHash string) error {
+	for key, transfer := range r.transfers {
+		if transfer.BlockHash == blockHash {
+			delete(r.transfers, key)
+		}
+	}
+	return nil
+}
+
+func newTokenTransferTestService(t *testing.T, publish bool) (*TokenTransferService, *fakeTokenTransferRepository, *fakeMessagingService) {
+	transfers := newFakeTokenTransferRepository()
+	messaging := &fakeMessagingService{}
+	cfg := &config.Config{TokenTransfers: config.TokenTransfersConfig{Enabled: true, Publish: publish}}
+	return NewTokenTransferService(transfers, messaging, cfg, newTestLogger(t)), transfers, messaging
+}
+
+func TestTokenTransferServiceRecordBlock(t *testing.T) {
+	s, repo, messaging := newTokenTransferTestService(t, true)
+	blockTime := time.Unix(1_700_000_000, 0).UTC()
+	transactions := []*entity.Transaction{
+		{Hash: "0x1", Network: "ethereum", BlockTimestamp: blockTime, TokenTransfers: []*entity.TokenTransfer{
+			{TransactionHash: "0x1", LogIndex: 0, Standard: entity.TokenStandardERC20, Amount: "5"},
+			{TransactionHash: "0x1", LogIndex: 1, Standard: entity.TokenStandardERC1155, Amount: "1", TokenID: "7"},
+		}},
+		{Hash: "0x2"},
+	}
+
+	transfers, err := s.RecordBlock(context.Background(), transactions)
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	assert.Len(t, repo.transfers, 2)
+	assert.Equal(t, blockTime, transfers[0].BlockTimestamp)
+	assert.Equal(t, "ethereum", transfers[1].Network)
+
+	// Recording the block again replaces its transfers
+	_, err = s.RecordBlock(context.Background(), transactions)
+	require.NoError(t, err)
+	assert.Len(t, repo.transfers, 2)
+
+	require.NoError(t, s.Publish(context.Background(), transfers))
+	assert.Equal(t, transfers, messaging.transfers)
+}
+
+func TestTokenTransferServiceRecordBlockFailure(t *testing.T) {
+	s, repo, _ := newTokenTransferTestService(t, true)
+	repo.err = errors.New("database unavailable")
+
+	_, err := s.RecordBlock(context.Background(), []*entity.Transaction{
+		{Hash: "0x1", TokenTransfers: []*entity.TokenTransfer{{TransactionHash: "0x1"}}},
+	})
+	assert.ErrorContains(t, err, "database unavailable")
+
+	// Blocks without transfers don't touch the repository
+	transfers, err := s.RecordBlock(context.Background(), []*entity.Transaction{{Hash: "0x2"}})
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+}
+
+func TestTokenTransferServicePublishDisabled(t *testing.T) {
+	s, _, messaging := newTokenTransferTestService(t, false)
+
+	require.NoError(t, s.Publish(context.Background(), []*entity.TokenTransfer{{TransactionHash: "0x1"}}))
+	assert.Empty(t, messaging.transfers)
+}
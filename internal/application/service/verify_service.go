@@ -0,0 +1,574 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// VerifyRequest describes a block range to verify
+type VerifyRequest struct {
+	FromBlock uint64
+	ToBlock   uint64
+	// Requeue deletes mismatched blocks and queues them for re-crawl
+	Requeue bool
+}
+
+// BlockMismatch describes a stored block that doesn't match the chain
+type BlockMismatch struct {
+	BlockNumber uint64   `json:"block_number"`
+	BlockHash   string   `json:"block_hash,omitempty"` // Empty when the block isn't stored
+	Issues      []string `json:"issues"`
+	Requeued    bool     `json:"requeued"`
+}
+
+// VerifyResult summarizes a verification run
+type VerifyResult struct {
+	BlocksVerified int64
+	BlocksMissing  int64
+	BlocksRequeued int64
+	// Blocks whose archived transactions and receipts rebuilt the chain's
+	// roots, the others are only compared field by field
+	BlocksArchiveVerified int64
+	Mismatches            []*BlockMismatch
+	Duration              time.Duration
+}
+
+// VerifyService checks stored blocks against the chain. The transactions and
+// receipts tries are rebuilt from the block body and receipts served by the
+// node and compared to the stored roots, and every stored transaction is
+// compared to the chain's field by field. Stored transactions don't keep
+// signatures or logs, so the tries can't be rebuilt from them; with the raw
+// block archive they are rebuilt from the archived block, which the stored
+// transactions are then compared to.
+type VerifyService struct {
+	blockRepo         repository.BlockRepository
+	txRepo            repository.TransactionRepository
+	blockchainService service.BlockchainService
+	retryService      *RetryService
+	logger            *logger.Logger
+	network           string
+
+	// Deletes the token transfers of requeued blocks, nil when disabled
+	transfers *TokenTransferService
+	// Rebuilds the tries of the stored blocks, nil without the archive
+	archive service.ArchivedBlockProver
+
+	interval      time.Duration
+	batchSize     uint64
+	confirmations uint64
+	requeue       bool
+
+	isRunning bool
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+
+	// Periodic job position, set on the first run
+	nextBlock    uint64
+	nextBlockSet bool
+
+	// Statistics
+	blocksVerified        int64
+	blocksArchiveVerified int64
+	mismatches            int64
+	blocksRequeued        int64
+	lastRun               time.Time
+}
+
+// NewVerifyService creates a new verify service. retryService may be nil when
+// mismatched blocks are only reported.
+func NewVerifyService(
+	blockRepo repository.BlockRepository,
+	txRepo repository.TransactionRepository,
+	blockchainService service.BlockchainService,
+	retryService *RetryService,
+	config *config.Config,
+	logger *logger.Logger,
+) *VerifyService {
+	s := &VerifyService{
+		blockRepo:         blockRepo,
+		txRepo:            txRepo,
+		blockchainService: blockchainService,
+		retryService:      retryService,
+		logger:            logger.WithComponent("verify-service"),
+		network:           config.Ethereum.Network,
+		interval:          10 * time.Minute,
+		batchSize:         100,
+		confirmations:     config.Verify.Confirmations,
+		requeue:           config.Verify.Requeue,
+	}
+
+	if config.Verify.Interval > 0 {
+		s.interval = config.Verify.Interval
+	}
+	if config.Verify.BatchSize > 0 {
+		s.batchSize = uint64(config.Verify.BatchSize)
+	}
+
+	return s
+}
+
+// SetTokenTransferService makes requeued blocks lose their token transfers
+// together with their transactions
+func (s *VerifyService) SetTokenTransferService(transfers *TokenTransferService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfers = transfers
+}
+
+// SetBlockArchive makes verification rebuild the tries of archived blocks
+// from the archive
+func (s *VerifyService) SetBlockArchive(archive service.ArchivedBlockProver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archive = archive
+}
+
+// tokenTransferService returns the token transfer service, nil when not set
+func (s *VerifyService) tokenTransferService() *TokenTransferService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transfers
+}
+
+// blockArchive returns the block archive, nil when not set
+func (s *VerifyService) blockArchive() service.ArchivedBlockProver {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.archive
+}
+
+// Start starts the periodic verification of newly stored blocks
+func (s *VerifyService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRunning {
+		return fmt.Errorf("verify service is already running")
+	}
+
+	s.stopChan = make(chan struct{})
+	s.isRunning = true
+
+	// The worker outlives the start context and is stopped through stopChan
+	s.wg.Add(1)
+	go s.verifyWorker(context.WithoutCancel(ctx), s.stopChan)
+
+	s.logger.Info("Verify service started",
+		zap.Duration("interval", s.interval),
+		zap.Uint64("batch_size", s.batchSize),
+		zap.Uint64("confirmations", s.confirmations),
+		zap.Bool("requeue", s.requeue))
+
+	return nil
+}
+
+// Stop stops the periodic verification and waits for the current run to finish
+func (s *VerifyService) Stop() error {
+	s.mu.Lock()
+	if !s.isRunning {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.stopChan)
+	s.isRunning = false
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.logger.Info("Verify service stopped")
+	return nil
+}
+
+// GetStats returns verification statistics
+func (s *VerifyService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]interface{}{
+		"is_running":              s.isRunning,
+		"blocks_verified":         s.blocksVerified,
+		"blocks_archive_verified": s.blocksArchiveVerified,
+		"mismatches":              s.mismatches,
+		"blocks_requeued":         s.blocksRequeued,
+		"next_block":              s.nextBlock,
+		"last_run":                s.lastRun,
+	}
+}
+
+// Verify verifies every block in the range and reports the mismatches
+func (s *VerifyService) Verify(ctx context.Context, req VerifyRequest) (*VerifyResult, error) {
+	return s.verifyRange(ctx, req, nil)
+}
+
+// verifyRange verifies a block range, stopping early when stopChan is closed
+func (s *VerifyService) verifyRange(ctx context.Context, req VerifyRequest, stopChan chan struct{}) (*VerifyResult, error) {
+	if req.FromBlock > req.ToBlock {
+		return nil, fmt.Errorf("invalid block range: %d > %d", req.FromBlock, req.ToBlock)
+	}
+	if req.Requeue && s.retryService == nil {
+		return nil, fmt.Errorf("re-crawl is not available without the retry queue")
+	}
+
+	result := &VerifyResult{}
+	startTime := time.Now()
+
+	s.logger.Info("Starting verification",
+		zap.Uint64("from_block", req.FromBlock),
+		zap.Uint64("to_block", req.ToBlock),
+		zap.Bool("requeue", req.Requeue))
+
+	for blockNumber := req.FromBlock; blockNumber <= req.ToBlock; blockNumber++ {
+		select {
+		case <-stopChan:
+			result.Duration = time.Since(startTime)
+			return result, nil
+		default:
+		}
+		if err := ctx.Err(); err != nil {
+			result.Duration = time.Since(startTime)
+			return result, err
+		}
+
+		mismatch, archived, err := s.verifyBlock(ctx, blockNumber)
+		if err != nil {
+			result.Duration = time.Since(startTime)
+			return result, fmt.Errorf("failed to verify block %d: %w", blockNumber, err)
+		}
+		result.BlocksVerified++
+		if archived {
+			result.BlocksArchiveVerified++
+		}
+
+		if mismatch != nil {
+			if mismatch.BlockHash == "" {
+				result.BlocksMissing++
+			}
+			s.logger.Warn("Stored block doesn't match the chain",
+				zap.Uint64("block_number", blockNumber),
+				zap.String("block_hash", mismatch.BlockHash),
+				zap.Strings("issues", mismatch.Issues))
+
+			if req.Requeue {
+				if err := s.requeueBlock(ctx, mismatch); err != nil {
+					s.logger.Error("Failed to queue block for re-crawl",
+						zap.Uint64("block_number", blockNumber),
+						zap.Error(err))
+				} else {
+					mismatch.Requeued = true
+					result.BlocksRequeued++
+				}
+			}
+			result.Mismatches = append(result.Mismatches, mismatch)
+		}
+
+		if blockNumber == req.ToBlock {
+			break // Avoid overflow when ToBlock is the maximum uint64
+		}
+	}
+
+	result.Duration = time.Since(startTime)
+
+	s.mu.Lock()
+	s.blocksVerified += result.BlocksVerified
+	s.blocksArchiveVerified += result.BlocksArchiveVerified
+	s.mismatches += int64(len(result.Mismatches))
+	s.blocksRequeued += result.BlocksRequeued
+	s.mu.Unlock()
+
+	s.logger.Info("Verification completed",
+		zap.Int64("blocks_verified", result.BlocksVerified),
+		zap.Int64("blocks_archive_verified", result.BlocksArchiveVerified),
+		zap.Int("mismatches", len(result.Mismatches)),
+		zap.Int64("blocks_missing", result.BlocksMissing),
+		zap.Int64("blocks_requeued", result.BlocksRequeued),
+		zap.Duration("duration", result.Duration))
+
+	return result, nil
+}
+
+// verifyBlock checks a stored block and its transactions, returning nil when
+// they match the chain, and whether the tries were rebuilt from the archive
+func (s *VerifyService) verifyBlock(ctx context.Context, blockNumber uint64) (*BlockMismatch, bool, error) {
+	number := new(big.Int).SetUint64(blockNumber)
+
+	block, err := s.blockRepo.GetBlockByNumber(ctx, number)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get block: %w", err)
+	}
+	if block == nil {
+		return &BlockMismatch{BlockNumber: blockNumber, Issues: []string{"block is not stored"}}, false, nil
+	}
+
+	transactions, err := s.txRepo.GetTransactionsByBlockHash(ctx, block.Hash)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	proof, err := s.blockchainService.GetBlockProof(ctx, number)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get block from chain: %w", err)
+	}
+
+	issues := checkStoredBlock(block, transactions)
+	issues = append(issues, checkBlockProof(block, transactions, proof)...)
+
+	var archived *service.BlockProof
+	if archive := s.blockArchive(); archive != nil {
+		if archived, err = archive.GetArchivedBlockProof(ctx, block.Hash); err != nil {
+			return nil, false, fmt.Errorf("failed to read archived block: %w", err)
+		}
+		if archived != nil {
+			issues = append(issues, checkArchivedProof(transactions, archived, proof)...)
+		}
+	}
+
+	if len(issues) == 0 {
+		return nil, archived != nil, nil
+	}
+	return &BlockMismatch{BlockNumber: blockNumber, BlockHash: block.Hash, Issues: issues}, archived != nil, nil
+}
+
+// requeueBlock deletes a mismatched block so the crawler stores it again, and
+// queues it in the retry queue
+func (s *VerifyService) requeueBlock(ctx context.Context, mismatch *BlockMismatch) error {
+	if mismatch.BlockHash != "" {
+		if transfers := s.tokenTransferService(); transfers != nil {
+			if err := transfers.DeleteBlock(ctx, mismatch.BlockHash); err != nil {
+				return err
+			}
+		}
+		if err := s.txRepo.DeleteTransactionsByBlockHash(ctx, mismatch.BlockHash); err != nil {
+			return fmt.Errorf("failed to delete transactions: %w", err)
+		}
+		if err := s.blockRepo.DeleteBlock(ctx, mismatch.BlockHash); err != nil {
+			return fmt.Errorf("failed to delete block: %w", err)
+		}
+	}
+
+	return s.retryService.Enqueue(ctx, mismatch.BlockNumber, "verification failed: "+mismatch.Issues[0])
+}
+
+// checkStoredBlock checks that the stored transactions are the ones the stored block lists
+func checkStoredBlock(block *entity.Block, transactions []*entity.Transaction) []string {
+	var issues []string
+
+	if len(transactions) != len(block.TransactionHashes) {
+		issues = append(issues, fmt.Sprintf("%d transactions stored, block lists %d",
+			len(transactions), len(block.TransactionHashes)))
+	}
+
+	seen := make(map[uint]bool, len(transactions))
+	var gasUsed uint64
+	receiptsComplete := true
+	for _, tx := range transactions {
+		switch {
+		case seen[tx.TransactionIndex]:
+			issues = append(issues, fmt.Sprintf("transaction index %d stored twice", tx.TransactionIndex))
+		case int(tx.TransactionIndex) >= len(block.TransactionHashes):
+			issues = append(issues, fmt.Sprintf("transaction %s has index %d outside the block", tx.Hash, tx.TransactionIndex))
+		case !strings.EqualFold(block.TransactionHashes[tx.TransactionIndex], tx.Hash):
+			issues = append(issues, fmt.Sprintf("transaction %d is %s, block lists %s",
+				tx.TransactionIndex, tx.Hash, block.TransactionHashes[tx.TransactionIndex]))
+		}
+		seen[tx.TransactionIndex] = true
+
+		gasUsed += tx.GasUsed
+		if tx.ReceiptMissing {
+			receiptsComplete = false
+		}
+	}
+
+	// Receipt gas only adds up once every receipt is there
+	if receiptsComplete && len(transactions) == len(block.TransactionHashes) && gasUsed != block.GasUsed {
+		issues = append(issues, fmt.Sprintf("transactions used %d gas, block used %d", gasUsed, block.GasUsed))
+	}
+
+	return issues
+}
+
+// checkBlockProof compares a stored block and its transactions to the chain
+func checkBlockProof(block *entity.Block, transactions []*entity.Transaction, proof *service.BlockProof) []string {
+	if !strings.EqualFold(block.Hash, proof.Hash) {
+		return []string{fmt.Sprintf("block hash is %s, chain has %s", block.Hash, proof.Hash)}
+	}
+
+	var issues []string
+	if !strings.EqualFold(block.TransactionsRoot, proof.TransactionsRoot) {
+		issues = append(issues, fmt.Sprintf("transactions root is %s, rebuilt from chain %s",
+			block.TransactionsRoot, proof.TransactionsRoot))
+	}
+	if !strings.EqualFold(block.ReceiptsRoot, proof.ReceiptsRoot) {
+		issues = append(issues, fmt.Sprintf("receipts root is %s, rebuilt from chain %s",
+			block.ReceiptsRoot, proof.ReceiptsRoot))
+	}
+	if len(block.TransactionHashes) != len(proof.Transactions) {
+		issues = append(issues, fmt.Sprintf("block lists %d transactions, chain has %d",
+			len(block.TransactionHashes), len(proof.Transactions)))
+	}
+
+	for _, tx := range transactions {
+		if int(tx.TransactionIndex) >= len(proof.Transactions) {
+			continue // Reported by the stored block check
+		}
+		if fields := transactionDiff(tx, proof.Transactions[tx.TransactionIndex]); len(fields) > 0 {
+			issues = append(issues, fmt.Sprintf("transaction %d (%s) differs from chain: %s",
+				tx.TransactionIndex, tx.Hash, strings.Join(fields, ", ")))
+		}
+	}
+
+	return issues
+}
+
+// checkArchivedProof compares the tries rebuilt from the archived block to the
+// chain's, and the stored transactions to the archived ones
+func checkArchivedProof(transactions []*entity.Transaction, archived, proof *service.BlockProof) []string {
+	var issues []string
+	if !strings.EqualFold(archived.TransactionsRoot, proof.TransactionsRoot) {
+		issues = append(issues, fmt.Sprintf("transactions root rebuilt from archive is %s, chain has %s",
+			archived.TransactionsRoot, proof.TransactionsRoot))
+	}
+	if !strings.EqualFold(archived.ReceiptsRoot, proof.ReceiptsRoot) {
+		issues = append(issues, fmt.Sprintf("receipts root rebuilt from archive is %s, chain has %s",
+			archived.ReceiptsRoot, proof.ReceiptsRoot))
+	}
+
+	for _, tx := range transactions {
+		if int(tx.TransactionIndex) >= len(archived.Transactions) {
+			continue // Reported by the stored block check
+		}
+		if fields := transactionDiff(tx, archived.Transactions[tx.TransactionIndex]); len(fields) > 0 {
+			issues = append(issues, fmt.Sprintf("transaction %d (%s) differs from archive: %s",
+				tx.TransactionIndex, tx.Hash, strings.Join(fields, ", ")))
+		}
+	}
+
+	return issues
+}
+
+// transactionDiff returns the fields of a stored transaction that differ from
+// the chain's. Receipt fields are skipped while the receipt is missing.
+func transactionDiff(stored, chain *entity.Transaction) []string {
+	var fields []string
+	diff := func(name string, equal bool) {
+		if !equal {
+			fields = append(fields, name)
+		}
+	}
+
+	diff("hash", strings.EqualFold(stored.Hash, chain.Hash))
+	diff("from", strings.EqualFold(stored.From, chain.From))
+	diff("to", equalAddress(stored.To, chain.To))
+	diff("value", stored.Value == chain.Value)
+	diff("gas", stored.Gas == chain.Gas)
+	diff("gas_price", stored.GasPrice == chain.GasPrice)
+	diff("nonce", stored.Nonce == chain.Nonce)
+	diff("data", strings.EqualFold(stored.Data, chain.Data))
+
+	if !stored.ReceiptMissing {
+		diff("status", stored.Status == chain.Status)
+		diff("gas_used", stored.GasUsed == chain.GasUsed)
+		diff("cumulative_gas_used", stored.CumulativeGasUsed == chain.CumulativeGasUsed)
+		diff("contract_address", equalAddress(stored.ContractAddress, chain.ContractAddress))
+	}
+
+	return fields
+}
+
+// equalAddress compares optional addresses
+func equalAddress(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return strings.EqualFold(*a, *b)
+}
+
+// verifyNextBlocks verifies the next batch of blocks that have enough confirmations
+func (s *VerifyService) verifyNextBlocks(ctx context.Context, stopChan chan struct{}) error {
+	last, err := s.blockRepo.GetLastProcessedBlock(ctx, s.network)
+	if err != nil {
+		return fmt.Errorf("failed to get last stored block: %w", err)
+	}
+	if last == nil {
+		return nil
+	}
+
+	lastNumber, err := strconv.ParseUint(last.Number, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid last stored block number %q: %w", last.Number, err)
+	}
+	if lastNumber < s.confirmations {
+		return nil
+	}
+	safeBlock := lastNumber - s.confirmations
+
+	s.mu.Lock()
+	if !s.nextBlockSet {
+		// Start with the latest confirmed batch, older blocks are left to cmd/verify
+		s.nextBlock = 0
+		if safeBlock+1 > s.batchSize {
+			s.nextBlock = safeBlock + 1 - s.batchSize
+		}
+		s.nextBlockSet = true
+	}
+	fromBlock := s.nextBlock
+	s.mu.Unlock()
+
+	if fromBlock > safeBlock {
+		return nil
+	}
+	toBlock := min(fromBlock+s.batchSize-1, safeBlock)
+
+	result, err := s.verifyRange(ctx, VerifyRequest{FromBlock: fromBlock, ToBlock: toBlock, Requeue: s.requeue}, stopChan)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.nextBlock = fromBlock + uint64(result.BlocksVerified)
+	s.lastRun = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// verifyWorker periodically verifies newly confirmed blocks
+func (s *VerifyService) verifyWorker(ctx context.Context, stopChan chan struct{}) {
+	defer s.wg.Done()
+
+	// Add panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic recovered in verifyWorker",
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.verifyNextBlocks(ctx, stopChan); err != nil {
+				s.logger.Error("Failed to verify blocks", zap.Error(err))
+			}
+		}
+	}
+}
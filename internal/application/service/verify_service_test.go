@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"fmt"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryVerifyStore keeps stored blocks and transactions for the verify tests
+type memoryVerifyStore struct {
+	repository.BlockRepository
+	blocks map[uint64]*entity.Block
+}
+
+func (r *memoryVerifyStore) GetBlockByNumber(ctx context.Context, blockNumber *big.Int) (*entity.Block, error) {
+	return r.blocks[blockNumber.Uint64()], nil
+}
+
+func (r *memoryVerifyStore) GetLastProcessedBlock(ctx context.Context, network string) (*entity.Block, error) {
+	var last *entity.Block
+	for number, block := range r.blocks {
+		if last == nil || number > mustParseUint(last.Number) {
+			last = block
+		}
+	}
+	return last, nil
+}
+
+func (r *memoryVerifyStore) DeleteBlock(ctx context.Context, blockHash string) error {
+	for number, block := range r.blocks {
+		if block.Hash == blockHash {
+			delete(r.blocks, number)
+		}
+	}
+	return nil
+}
+
+type memoryVerifyTransactions struct {
+	repository.TransactionRepository
+	transactions map[string][]*entity.Transaction
+}
+
+func (r *memoryVerifyTransactions) GetTransactionsByBlockHash(ctx context.Context, blockHash string) ([]*entity.Transaction, error) {
+	return r.transactions[blockHash], nil
+}
+
+func (r *memoryVerifyTransactions) DeleteTransactionsByBlockHash(ctx context.Context, blockHash string) error {
+	delete(r.transactions, blockHash)
+	return nil
+}
+
+// fakeProofBlockchain serves the chain's view of blocks
+type fakeProofBlockchain struct {
+	service.BlockchainService
+	proofs    map[uint64]*service.BlockProof
+	requested []uint64
+}
+
+func (b *fakeProofBlockchain) GetBlockProof(ctx context.Context, blockNumber *big.Int) (*service.BlockProof, error) {
+	b.requested = append(b.requested, blockNumber.Uint64())
+	proof, ok := b.proofs[blockNumber.Uint64()]
+	if !ok {
+		return nil, fmt.Errorf("block %d not found", blockNumber.Uint64())
+	}
+	return proof, nil
+}
+
+func mustParseUint(s string) uint64 {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// testChainBlock builds a stored block, its transactions and the matching proof
+func testChainBlock(number uint64, txCount int) (*entity.Block, []*entity.Transaction, *service.BlockProof) {
+	blockHash := fmt.Sprintf("0xb%d", number)
+	block := &entity.Block{
+		Number:           strconv.FormatUint(number, 10),
+		Hash:             blockHash,
+		TransactionsRoot: fmt.Sprintf("0xtroot%d", number),
+		ReceiptsRoot:     fmt.Sprintf("0xrroot%d", number),
+		Status:           entity.BlockStatusProcessed,
+	}
+	proof := &service.BlockProof{
+		Hash:             blockHash,
+		TransactionsRoot: block.TransactionsRoot,
+		ReceiptsRoot:     block.ReceiptsRoot,
+	}
+
+	var transactions []*entity.Transaction
+	var cumulative uint64
+	for i := 0; i < txCount; i++ {
+		to := "0xto"
+		cumulative += 21000
+		tx := &entity.Transaction{
+			Hash:              fmt.Sprintf("0xt%d_%d", number, i),
+			BlockHash:         blockHash,
+			BlockNumber:       block.Number,
+			TransactionIndex:  uint(i),
+			From:              "0xfrom",
+			To:                &to,
+			Value:             "1000",
+			Gas:               21000,
+			GasPrice:          "10",
+			GasUsed:           21000,
+			CumulativeGasUsed: cumulative,
+			Nonce:             uint64(i),
+			Status:            1,
+		}
+		chainTx := *tx
+		block.TransactionHashes = append(block.TransactionHashes, tx.Hash)
+		transactions = append(transactions, tx)
+		proof.Transactions = append(proof.Transactions, &chainTx)
+	}
+	block.GasUsed = cumulative
+
+	return block, transactions, proof
+}
+
+func newTestVerifyService(t *testing.T, blockCount uint64, cfg *config.Config) (*VerifyService, *memoryVerifyStore, *memoryVerifyTransactions, *fakeProofBlockchain, *fakeBlockRetryRepository) {
+	blocks := &memoryVerifyStore{blocks: map[uint64]*entity.Block{}}
+	transactions := &memoryVerifyTransactions{transactions: map[string][]*entity.Transaction{}}
+	blockchain := &fakeProofBlockchain{proofs: map[uint64]*service.BlockProof{}}
+	for n := uint64(1); n <= blockCount; n++ {
+		block, txs, proof := testChainBlock(n, 3)
+		blocks.blocks[n] = block
+		transactions.transactions[block.Hash] = txs
+		blockchain.proofs[n] = proof
+	}
+
+	cfg.Ethereum.Network = "ethereum"
+	retryRepo := &fakeBlockRetryRepository{retries: map[uint64]*entity.BlockRetry{}}
+	retryService := NewRetryService(retryRepo, nil, cfg, newTestLogger(t))
+	s := NewVerifyService(blocks, transactions, blockchain, retryService, cfg, newTestLogger(t))
+	return s, blocks, transactions, blockchain, retryRepo
+}
+
+func TestVerifyService_Verify(t *testing.T) {
+	s, blocks, transactions, blockchain, retryRepo := newTestVerifyService(t, 6, &config.Config{})
+
+	// 2: stored root differs from the rebuilt one
+	blocks.blocks[2].TransactionsRoot = "0xcorrupt"
+	// 3: a transaction was stored with a wrong value and a wrong outcome
+	transactions.transactions["0xb3"][1].Value = "999"
+	transactions.transactions["0xb3"][1].Status = 0
+	// 4: a transaction is missing
+	transactions.transactions["0xb4"] = transactions.transactions["0xb4"][:2]
+	// 5: not stored
+	delete(blocks.blocks, 5)
+	// 6: receipt missing, its receipt fields aren't compared yet
+	missing := transactions.transactions["0xb6"][2]
+	missing.ReceiptMissing = true
+	missing.Status, missing.GasUsed, missing.CumulativeGasUsed = 0, 0, 0
+
+	result, err := s.Verify(context.Background(), VerifyRequest{FromBlock: 1, ToBlock: 6})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), result.BlocksVerified)
+	assert.Equal(t, int64(1), result.BlocksMissing)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 6}, blockchain.requested)
+
+	mismatches := map[uint64]*BlockMismatch{}
+	for _, mismatch := range result.Mismatches {
+		mismatches[mismatch.BlockNumber] = mismatch
+		assert.False(t, mismatch.Requeued)
+	}
+	require.Len(t, mismatches, 4)
+	assert.Contains(t, mismatches[2].Issues[0], "transactions root is 0xcorrupt")
+	assert.Equal(t, []string{"transaction 1 (0xt3_1) differs from chain: value, status"}, mismatches[3].Issues)
+	assert.Contains(t, mismatches[4].Issues, "2 transactions stored, block lists 3")
+	assert.Equal(t, "", mismatches[5].BlockHash)
+	assert.Equal(t, []string{"block is not stored"}, mismatches[5].Issues)
+
+	// Nothing is deleted or queued without Requeue
+	assert.Len(t, blocks.blocks, 5)
+	assert.Empty(t, retryRepo.retries)
+}
+
+// fakeArchivedProofs serves the tries rebuilt from archived blocks by hash
+type fakeArchivedProofs map[string]*service.BlockProof
+
+func (a fakeArchivedProofs) GetArchivedBlockProof(ctx context.Context, blockHash string) (*service.BlockProof, error) {
+	return a[blockHash], nil
+}
+
+func TestVerifyService_VerifyWithArchive(t *testing.T) {
+	s, _, _, _, _ := newTestVerifyService(t, 4, &config.Config{})
+
+	archive := fakeArchivedProofs{}
+	for n := uint64(1); n <= 3; n++ {
+		_, _, proof := testChainBlock(n, 3)
+		archive[proof.Hash] = proof
+	}
+	// 2: the archived receipts don't rebuild the chain's root
+	archive["0xb2"].ReceiptsRoot = "0xbadroot"
+	// 3: the stored transaction matches the chain but not the archived one
+	archive["0xb3"].Transactions[0].Value = "5"
+	// 4: not archived, only compared field by field
+	s.SetBlockArchive(archive)
+
+	result, err := s.Verify(context.Background(), VerifyRequest{FromBlock: 1, ToBlock: 4})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.BlocksVerified)
+	assert.Equal(t, int64(3), result.BlocksArchiveVerified)
+	assert.Equal(t, int64(3), s.GetStats()["blocks_archive_verified"])
+
+	require.Len(t, result.Mismatches, 2)
+	assert.Equal(t, []string{"receipts root rebuilt from archive is 0xbadroot, chain has 0xrroot2"}, result.Mismatches[0].Issues)
+	assert.Equal(t, []string{"transaction 0 (0xt3_0) differs from archive: value"}, result.Mismatches[1].Issues)
+}
+
+func TestVerifyService_Requeue(t *testing.T) {
+	s, blocks, transactions, _, retryRepo := newTestVerifyService(t, 3, &config.Config{})
+
+	blocks.blocks[2].ReceiptsRoot = "0xcorrupt"
+	delete(blocks.blocks, 3)
+	transfers := newFakeTokenTransferRepository()
+	transfers.transfers["b1"] = &entity.TokenTransfer{BlockHash: "0xb1"}
+	transfers.transfers["b2"] = &entity.TokenTransfer{BlockHash: "0xb2"}
+	s.SetTokenTransferService(NewTokenTransferService(transfers, nil, &config.Config{}, newTestLogger(t)))
+
+	result, err := s.Verify(context.Background(), VerifyRequest{FromBlock: 1, ToBlock: 3, Requeue: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.BlocksRequeued)
+	for _, mismatch := range result.Mismatches {
+		assert.True(t, mismatch.Requeued)
+	}
+
+	// The mismatched block is deleted so the crawler stores the chain's version
+	assert.NotContains(t, blocks.blocks, uint64(2))
+	assert.NotContains(t, transactions.transactions, "0xb2")
+	assert.Contains(t, blocks.blocks, uint64(1))
+	assert.NotContains(t, transfers.transfers, "b2")
+	assert.Contains(t, transfers.transfers, "b1")
+
+	require.Contains(t, retryRepo.retries, uint64(2))
+	require.Contains(t, retryRepo.retries, uint64(3))
+	assert.Equal(t, entity.BlockRetryStatusPending, retryRepo.retries[2].Status)
+	assert.Equal(t, 0, retryRepo.retries[2].Attempts)
+	assert.Contains(t, retryRepo.retries[2].LastError, "receipts root is 0xcorrupt")
+}
+
+func TestVerifyService_VerifyNextBlocks(t *testing.T) {
+	cfg := &config.Config{Verify: config.VerifyConfig{BatchSize: 4, Confirmations: 2}}
+	s, blocks, _, blockchain, _ := newTestVerifyService(t, 10, cfg)
+	stopChan := make(chan struct{})
+
+	// Starts with the latest batch that has enough confirmations
+	require.NoError(t, s.verifyNextBlocks(context.Background(), stopChan))
+	assert.Equal(t, []uint64{5, 6, 7, 8}, blockchain.requested)
+
+	// Nothing new is confirmed yet
+	require.NoError(t, s.verifyNextBlocks(context.Background(), stopChan))
+	assert.Len(t, blockchain.requested, 4)
+
+	for n := uint64(11); n <= 13; n++ {
+		block, _, proof := testChainBlock(n, 0)
+		blocks.blocks[n] = block
+		blockchain.proofs[n] = proof
+	}
+	require.NoError(t, s.verifyNextBlocks(context.Background(), stopChan))
+	assert.Equal(t, []uint64{5, 6, 7, 8, 9, 10, 11}, blockchain.requested)
+
+	stats := s.GetStats()
+	assert.Equal(t, int64(7), stats["blocks_verified"])
+	assert.Equal(t, int64(0), stats["mismatches"])
+	assert.Equal(t, uint64(12), stats["next_block"])
+}
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WorkCoordinatorService splits the chain into block ranges and publishes them
+// to the work range collection, where crawler workers claim them. Publishing
+// stops at the end block, blocks above it are left to the scheduler. Failed
+// ranges are requeued after failedRetryDelay, the checkpoint can't pass them.
+type WorkCoordinatorService struct {
+	workRangeRepo     repository.WorkRangeRepository
+	blockchainService service.BlockchainService
+	logger            *logger.Logger
+	network           string
+	startBlock        uint64
+
+	rangeSize        uint64
+	maxPendingRanges int
+	interval         time.Duration
+	headDistance     uint64
+	failedRetryDelay time.Duration
+
+	// Fences the published ranges, nil without leader election
+	leaderElection *LeaderElectionService
+
+	isRunning bool
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+
+	// Publishing state, the end block is fixed on the first publish unless configured
+	nextBlock       uint64
+	endBlock        uint64
+	endBlockSet     bool
+	rangesPublished int64
+	rangesRequeued  int64
+}
+
+// NewWorkCoordinatorService creates a new work coordinator service
+func NewWorkCoordinatorService(
+	workRangeRepo repository.WorkRangeRepository,
+	blockchainService service.BlockchainService,
+	config *config.Config,
+	logger *logger.Logger,
+) *WorkCoordinatorService {
+	s := &WorkCoordinatorService{
+		workRangeRepo:     workRangeRepo,
+		blockchainService: blockchainService,
+		logger:            logger.WithComponent("work-coordinator"),
+		network:           config.Ethereum.Network,
+		startBlock:        config.Ethereum.StartBlock,
+		rangeSize:         100,
+		maxPendingRanges:  50,
+		interval:          10 * time.Second,
+		failedRetryDelay:  10 * time.Minute,
+		headDistance:      config.Work.HeadDistance,
+		endBlock:          config.Work.EndBlock,
+		endBlockSet:       config.Work.EndBlock > 0,
+	}
+
+	if config.Work.RangeSize > 0 {
+		s.rangeSize = uint64(config.Work.RangeSize)
+	}
+	if config.Work.MaxPendingRanges > 0 {
+		s.maxPendingRanges = config.Work.MaxPendingRanges
+	}
+	if config.Work.PublishInterval > 0 {
+		s.interval = config.Work.PublishInterval
+	}
+	if config.Work.FailedRetryDelay > 0 {
+		s.failedRetryDelay = config.Work.FailedRetryDelay
+	}
+
+	return s
+}
+
+// SetLeaderElection makes the coordinator publish only while this instance
+// leads and tag the ranges with its fencing token
+func (s *WorkCoordinatorService) SetLeaderElection(leaderElection *LeaderElectionService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leaderElection = leaderElection
+}
+
+// Start resumes after the last published range and starts publishing
+func (s *WorkCoordinatorService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRunning {
+		return fmt.Errorf("work coordinator is already running")
+	}
+
+	// Continue where the previous coordinator stopped, up to its end block
+	nextBlock, endBlock, err := s.loadNextBlock(ctx)
+	if err != nil {
+		return err
+	}
+	s.nextBlock = nextBlock
+	s.restoreEndBlock(endBlock)
+
+	s.isRunning = true
+	s.stopChan = make(chan struct{})
+
+	s.logger.Info("Starting work coordinator",
+		zap.Uint64("next_block", s.nextBlock),
+		zap.Uint64("range_size", s.rangeSize),
+		zap.Int("max_pending_ranges", s.maxPendingRanges))
+
+	s.wg.Add(1)
+	go s.publishWorker(context.WithoutCancel(ctx), s.stopChan)
+
+	return nil
+}
+
+// Stop stops publishing ranges, claimed ranges are left to the workers
+func (s *WorkCoordinatorService) Stop() error {
+	s.mu.Lock()
+	if !s.isRunning {
+		s.mu.Unlock()
+		return nil
+	}
+	s.isRunning = false
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.logger.Info("Work coordinator stopped")
+	return nil
+}
+
+// GetStats returns work distribution statistics
+func (s *WorkCoordinatorService) GetStats(ctx context.Context) map[string]interface{} {
+	s.mu.Lock()
+	stats := map[string]interface{}{
+		"work_next_block":       s.nextBlock,
+		"work_ranges_published": s.rangesPublished,
+		"work_ranges_requeued":  s.rangesRequeued,
+		"work_publishing_done":  s.endBlockSet && s.nextBlock > s.endBlock,
+	}
+	if s.endBlockSet {
+		stats["work_end_block"] = s.endBlock
+	}
+	s.mu.Unlock()
+
+	for _, status := range []entity.WorkRangeStatus{
+		entity.WorkRangeStatusPending,
+		entity.WorkRangeStatusClaimed,
+		entity.WorkRangeStatusCompleted,
+		entity.WorkRangeStatusFailed,
+	} {
+		if count, err := s.workRangeRepo.GetWorkRangeCountByStatus(ctx, s.network, status); err == nil {
+			stats["work_ranges_"+string(status)] = count
+		}
+	}
+
+	return stats
+}
+
+// publishWorker publishes new ranges and requeues failed ones every interval
+func (s *WorkCoordinatorService) publishWorker(ctx context.Context, stopChan chan struct{}) {
+	defer s.wg.Done()
+
+	// Add panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic recovered in publishWorker",
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	publishing := true
+	for {
+		if publishing {
+			if err := s.publishRanges(ctx); err != nil {
+				s.logger.Error("Failed to publish work ranges", zap.Error(err))
+			}
+			if endBlock, done := s.publishedAll(); done {
+				// Failed ranges are still requeued until the coordinator stops
+				s.logger.Info("Work ranges published up to the end block, publishing stopped",
+					zap.Uint64("end_block", endBlock))
+				publishing = false
+			}
+		}
+		if err := s.requeueFailedRanges(ctx); err != nil {
+			s.logger.Error("Failed to requeue failed work ranges", zap.Error(err))
+		}
+
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishedAll returns the end block and whether every range up to it is published
+func (s *WorkCoordinatorService) publishedAll() (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.endBlock, s.endBlockSet && s.nextBlock > s.endBlock
+}
+
+// Owns reports whether a block is left to the workers. Until the end block is
+// fixed every block from the start block on may be.
+func (s *WorkCoordinatorService) Owns(blockNumber uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return blockNumber >= s.startBlock && (!s.endBlockSet || blockNumber <= s.endBlock)
+}
+
+// publishRanges publishes full ranges up to the end block and the chain head
+// while fewer than maxPendingRanges are unfinished. The last range ends at the
+// end block.
+func (s *WorkCoordinatorService) publishRanges(ctx context.Context) error {
+	if _, done := s.publishedAll(); done {
+		return nil
+	}
+
+	s.mu.Lock()
+	leaderElection := s.leaderElection
+	s.mu.Unlock()
+	fencingToken := int64(0)
+	if leaderElection != nil {
+		// The lease may have been lost since the last renewal
+		if !leaderElection.IsLeader() {
+			return nil
+		}
+		fencingToken = leaderElection.FencingToken()
+	}
+
+	pending, err := s.workRangeRepo.GetWorkRangeCountByStatus(ctx, s.network, entity.WorkRangeStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to count pending ranges: %w", err)
+	}
+	claimed, err := s.workRangeRepo.GetWorkRangeCountByStatus(ctx, s.network, entity.WorkRangeStatusClaimed)
+	if err != nil {
+		return fmt.Errorf("failed to count claimed ranges: %w", err)
+	}
+
+	available := s.maxPendingRanges - int(pending+claimed)
+	if available <= 0 {
+		return nil
+	}
+
+	latestBlock, err := s.blockchainService.GetLatestBlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest block: %w", err)
+	}
+	latest := latestBlock.Uint64()
+
+	s.mu.Lock()
+	if !s.endBlockSet {
+		// The scheduler owns the blocks above the horizon
+		if latest > s.headDistance {
+			s.endBlock = latest - s.headDistance
+		}
+		s.endBlockSet = true
+		s.logger.Info("Work ranges end below the head",
+			zap.Uint64("end_block", s.endBlock),
+			zap.Uint64("latest_block", latest),
+			zap.Uint64("head_distance", s.headDistance))
+	}
+	nextBlock, endBlock := s.nextBlock, s.endBlock
+	s.mu.Unlock()
+
+	// Only full ranges below the head are published, but the last one
+	var ranges []*entity.WorkRange
+	now := time.Now()
+	for len(ranges) < available && nextBlock <= endBlock {
+		rangeEnd := nextBlock + s.rangeSize - 1
+		if rangeEnd > endBlock {
+			rangeEnd = endBlock
+		}
+		if rangeEnd > latest {
+			break
+		}
+		ranges = append(ranges, &entity.WorkRange{
+			Network:      s.network,
+			StartBlock:   nextBlock,
+			EndBlock:     rangeEnd,
+			Status:       entity.WorkRangeStatusPending,
+			WorkEndBlock: endBlock,
+			CreatedAt:    now,
+		})
+		nextBlock = rangeEnd + 1
+	}
+
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	if err := s.workRangeRepo.CreateWorkRanges(ctx, ranges, fencingToken); err != nil {
+		if isDuplicateKeyError(err) {
+			// Another coordinator published these ranges, continue after its last one
+			return s.reloadNextBlock(ctx)
+		}
+		return fmt.Errorf("failed to create work ranges: %w", err)
+	}
+
+	s.mu.Lock()
+	s.nextBlock = nextBlock
+	s.rangesPublished += int64(len(ranges))
+	s.mu.Unlock()
+
+	s.logger.Info("Published work ranges",
+		zap.Int("count", len(ranges)),
+		zap.Uint64("from_block", ranges[0].StartBlock),
+		zap.Uint64("to_block", ranges[len(ranges)-1].EndBlock),
+		zap.Uint64("latest_block", latest))
+
+	return nil
+}
+
+// requeueFailedRanges returns ranges that failed at least failedRetryDelay ago
+// to the queue, their blocks are left to the workers and the checkpoint would
+// never pass them otherwise
+func (s *WorkCoordinatorService) requeueFailedRanges(ctx context.Context) error {
+	s.mu.Lock()
+	leaderElection := s.leaderElection
+	s.mu.Unlock()
+	if leaderElection != nil && !leaderElection.IsLeader() {
+		return nil
+	}
+
+	requeued, err := s.workRangeRepo.RequeueFailedWorkRanges(ctx, s.network, time.Now().Add(-s.failedRetryDelay))
+	if err != nil {
+		return err
+	}
+	if requeued == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.rangesRequeued += requeued
+	s.mu.Unlock()
+
+	s.logger.Warn("Requeued failed work ranges",
+		zap.Int64("count", requeued),
+		zap.Duration("failed_retry_delay", s.failedRetryDelay))
+	return nil
+}
+
+// reloadNextBlock moves the next block past the last published range
+func (s *WorkCoordinatorService) reloadNextBlock(ctx context.Context) error {
+	nextBlock, endBlock, err := s.loadNextBlock(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.nextBlock = nextBlock
+	s.restoreEndBlock(endBlock)
+	s.mu.Unlock()
+	return nil
+}
+
+// restoreEndBlock takes over the end block of the coordinator that published
+// the last range, unless the end block is configured or already fixed. 0 when
+// unknown. Must be called with mu held.
+func (s *WorkCoordinatorService) restoreEndBlock(endBlock uint64) {
+	if s.endBlockSet || endBlock == 0 {
+		return
+	}
+	s.endBlock = endBlock
+	s.endBlockSet = true
+	s.logger.Info("Resuming work ranges up to the published end block",
+		zap.Uint64("end_block", endBlock))
+}
+
+// loadNextBlock returns the first block after the last published range and
+// the end block it was published with, 0 when unknown
+func (s *WorkCoordinatorService) loadNextBlock(ctx context.Context) (uint64, uint64, error) {
+	lastRange, err := s.workRangeRepo.GetLastWorkRange(ctx, s.network)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get last work range: %w", err)
+	}
+	if lastRange == nil {
+		return s.startBlock, 0, nil
+	}
+	return lastRange.EndBlock + 1, lastRange.WorkEndBlock, nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHeadBlockchainService struct {
+	service.BlockchainService
+	latest uint64
+}
+
+func (s *fakeHeadBlockchainService) GetLatestBlockNumber(ctx context.Context) (*big.Int, error) {
+	return new(big.Int).SetUint64(s.latest), nil
+}
+
+func newTestWorkCoordinator(t *testing.T, repo *memoryWorkRangeRepository, chain *fakeHeadBlockchainService) *WorkCoordinatorService {
+	return newTestWorkCoordinatorWithEnd(t, repo, chain, config.WorkConfig{EndBlock: 10_000})
+}
+
+func newTestWorkCoordinatorWithEnd(t *testing.T, repo *memoryWorkRangeRepository, chain *fakeHeadBlockchainService, work config.WorkConfig) *WorkCoordinatorService {
+	work.RangeSize, work.MaxPendingRanges = 10, 3
+	cfg := &config.Config{
+		Ethereum: config.EthereumConfig{Network: "ethereum", StartBlock: 100},
+		Work:     work,
+	}
+	return NewWorkCoordinatorService(repo, chain, cfg, newTestLogger(t))
+}
+
+func TestWorkCoordinatorService_PublishRanges(t *testing.T) {
+	repo := &memoryWorkRangeRepository{}
+	chain := &fakeHeadBlockchainService{latest: 125}
+	coordinator := newTestWorkCoordinator(t, repo, chain)
+	ctx := context.Background()
+
+	require.NoError(t, coordinator.reloadNextBlock(ctx))
+	require.NoError(t, coordinator.publishRanges(ctx))
+
+	// Only full ranges below the head are published
+	require.Len(t, repo.ranges, 2)
+	assert.Equal(t, uint64(100), repo.ranges[0].StartBlock)
+	assert.Equal(t, uint64(109), repo.ranges[0].EndBlock)
+	assert.Equal(t, uint64(119), repo.ranges[1].EndBlock)
+
+	// Publishing pauses once MaxPendingRanges are unfinished
+	chain.latest = 1000
+	require.NoError(t, coordinator.publishRanges(ctx))
+	require.Len(t, repo.ranges, 3)
+	require.NoError(t, coordinator.publishRanges(ctx))
+	require.Len(t, repo.ranges, 3)
+
+	claimed, err := repo.ClaimWorkRange(ctx, "ethereum", "worker-1", 0, 5)
+	require.NoError(t, err)
+	require.NoError(t, repo.CompleteWorkRange(ctx, claimed.ID, "worker-1"))
+	require.NoError(t, coordinator.publishRanges(ctx))
+	require.Len(t, repo.ranges, 4)
+	assert.Equal(t, uint64(130), repo.ranges[3].StartBlock)
+
+	stats := coordinator.GetStats(ctx)
+	assert.Equal(t, int64(4), stats["work_ranges_published"])
+	assert.Equal(t, int64(1), stats["work_ranges_"+string(entity.WorkRangeStatusCompleted)])
+}
+
+func TestWorkCoordinatorService_ResumesAfterLastRange(t *testing.T) {
+	repo := &memoryWorkRangeRepository{}
+	newTestWorkRanges(t, repo, 500)
+	coordinator := newTestWorkCoordinator(t, repo, &fakeHeadBlockchainService{latest: 520})
+	ctx := context.Background()
+
+	require.NoError(t, coordinator.reloadNextBlock(ctx))
+	require.NoError(t, coordinator.publishRanges(ctx))
+
+	require.Len(t, repo.ranges, 2)
+	assert.Equal(t, uint64(510), repo.ranges[1].StartBlock)
+}
+
+func TestWorkCoordinatorService_StopsAtEndBlock(t *testing.T) {
+	repo := &memoryWorkRangeRepository{}
+	coordinator := newTestWorkCoordinatorWithEnd(t, repo, &fakeHeadBlockchainService{latest: 1000}, config.WorkConfig{EndBlock: 124})
+	ctx := context.Background()
+
+	require.NoError(t, coordinator.reloadNextBlock(ctx))
+	require.NoError(t, coordinator.publishRanges(ctx))
+
+	// The last range ends at the end block
+	require.Len(t, repo.ranges, 3)
+	assert.Equal(t, uint64(120), repo.ranges[2].StartBlock)
+	assert.Equal(t, uint64(124), repo.ranges[2].EndBlock)
+	endBlock, done := coordinator.publishedAll()
+	assert.True(t, done)
+	assert.Equal(t, uint64(124), endBlock)
+
+	completeWorkRanges(t, repo)
+	require.NoError(t, coordinator.publishRanges(ctx))
+	assert.Len(t, repo.ranges, 3, "nothing is published past the end block")
+	assert.Equal(t, true, coordinator.GetStats(ctx)["work_publishing_done"])
+}
+
+func TestWorkCoordinatorService_EndsBelowHead(t *testing.T) {
+	repo := &memoryWorkRangeRepository{}
+	chain := &fakeHeadBlockchainService{latest: 150}
+	coordinator := newTestWorkCoordinatorWithEnd(t, repo, chain, config.WorkConfig{HeadDistance: 25})
+	ctx := context.Background()
+
+	require.NoError(t, coordinator.reloadNextBlock(ctx))
+	require.NoError(t, coordinator.publishRanges(ctx))
+
+	require.Len(t, repo.ranges, 3)
+	assert.Equal(t, uint64(125), repo.ranges[2].EndBlock)
+	assert.Equal(t, uint64(125), coordinator.GetStats(ctx)["work_end_block"])
+
+	// The horizon is fixed on the first publish, later heads belong to the scheduler
+	chain.latest = 5000
+	completeWorkRanges(t, repo)
+	require.NoError(t, coordinator.publishRanges(ctx))
+	assert.Len(t, repo.ranges, 3)
+	_, done := coordinator.publishedAll()
+	assert.True(t, done)
+}
+
+func TestWorkCoordinatorService_KeepsEndBlockAcrossRestarts(t *testing.T) {
+	repo := &memoryWorkRangeRepository{}
+	chain := &fakeHeadBlockchainService{latest: 150}
+	work := config.WorkConfig{HeadDistance: 25}
+	ctx := context.Background()
+
+	first := newTestWorkCoordinatorWithEnd(t, repo, chain, work)
+	first.maxPendingRanges = 1
+	require.NoError(t, first.reloadNextBlock(ctx))
+	require.NoError(t, first.publishRanges(ctx))
+	require.Len(t, repo.ranges, 1)
+	assert.Equal(t, uint64(125), repo.ranges[0].WorkEndBlock)
+
+	// A new leader sees a newer head but publishes up to the same end block
+	chain.latest = 5000
+	completeWorkRanges(t, repo)
+	second := newTestWorkCoordinatorWithEnd(t, repo, chain, work)
+	require.NoError(t, second.reloadNextBlock(ctx))
+	require.NoError(t, second.publishRanges(ctx))
+
+	require.Len(t, repo.ranges, 3)
+	assert.Equal(t, uint64(125), repo.ranges[2].EndBlock)
+	endBlock, done := second.publishedAll()
+	assert.True(t, done)
+	assert.Equal(t, uint64(125), endBlock)
+}
+
+// completeWorkRanges claims and completes every pending range
+func completeWorkRanges(t *testing.T, repo *memoryWorkRangeRepository) {
+	ctx := context.Background()
+	for {
+		claimed, err := repo.ClaimWorkRange(ctx, "ethereum", "worker-1", 0, 5)
+		require.NoError(t, err)
+		if claimed == nil {
+			return
+		}
+		require.NoError(t, repo.CompleteWorkRange(ctx, claimed.ID, "worker-1"))
+	}
+}
+
+func TestWorkCoordinatorService_FencedByNewerLeader(t *testing.T) {
+	repo := &memoryWorkRangeRepository{}
+	chain := &fakeHeadBlockchainService{latest: 1000}
+	ctx := context.Background()
+
+	current := newTestWorkCoordinator(t, repo, chain)
+	current.SetLeaderElection(newTestLeader(t, 2))
+	require.NoError(t, current.reloadNextBlock(ctx))
+	require.NoError(t, current.publishRanges(ctx))
+	require.Len(t, repo.ranges, 3)
+	assert.Equal(t, int64(2), repo.ranges[0].FencingToken)
+	repo.ranges = repo.ranges[:1]
+
+	// A stale leader publishes nothing, whether it knows it lost the lease or not
+	staleLeader := newTestLeader(t, 1)
+	stale := newTestWorkCoordinator(t, repo, chain)
+	stale.SetLeaderElection(staleLeader)
+	require.NoError(t, stale.reloadNextBlock(ctx))
+	assert.ErrorIs(t, stale.publishRanges(ctx), repository.ErrStaleFencingToken)
+
+	staleLeader.mu.Lock()
+	staleLeader.isLeader = false
+	staleLeader.mu.Unlock()
+	require.NoError(t, stale.publishRanges(ctx))
+	assert.Len(t, repo.ranges, 1)
+}
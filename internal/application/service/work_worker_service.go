@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errClaimLost is returned when another worker took over a range
+var errClaimLost = errors.New("work range claim lost")
+
+// blockProcessor processes a single block, implemented by CrawlerService
+type blockProcessor interface {
+	ProcessSpecificBlock(ctx context.Context, blockNumber *big.Int) error
+}
+
+// WorkerService claims block ranges published by the WorkCoordinatorService,
+// processes them and reports completion. Claims are renewed while a range is
+// processed; a range whose claim expires is reassigned to another worker.
+type WorkerService struct {
+	workRangeRepo repository.WorkRangeRepository
+	processor     blockProcessor
+	logger        *logger.Logger
+	network       string
+	workerID      string
+
+	concurrency   int
+	claimTTL      time.Duration
+	renewInterval time.Duration
+	maxAttempts   int
+	pollInterval  time.Duration
+
+	isRunning bool
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+
+	// Statistics
+	currentRange    *entity.WorkRange
+	rangesCompleted int64
+	rangesFailed    int64
+	blocksProcessed int64
+}
+
+// NewWorkerService creates a new worker service
+func NewWorkerService(
+	workRangeRepo repository.WorkRangeRepository,
+	crawlerService *CrawlerService,
+	config *config.Config,
+	logger *logger.Logger,
+) *WorkerService {
+	s := &WorkerService{
+		workRangeRepo: workRangeRepo,
+		processor:     crawlerService,
+		logger:        logger.WithComponent("worker"),
+		network:       config.Ethereum.Network,
+		workerID:      config.Work.WorkerID,
+		concurrency:   config.Crawler.ConcurrentWorkers,
+		claimTTL:      2 * time.Minute,
+		renewInterval: 30 * time.Second,
+		maxAttempts:   5,
+		pollInterval:  5 * time.Second,
+	}
+
+	if s.workerID == "" {
+		s.workerID = defaultInstanceID()
+	}
+	if s.concurrency <= 0 {
+		s.concurrency = 1
+	}
+	if config.Work.ClaimTTL > 0 {
+		s.claimTTL = config.Work.ClaimTTL
+	}
+	if config.Work.ClaimRenewInterval > 0 {
+		s.renewInterval = config.Work.ClaimRenewInterval
+	}
+	if config.Work.MaxAttempts > 0 {
+		s.maxAttempts = config.Work.MaxAttempts
+	}
+	if config.Work.PollInterval > 0 {
+		s.pollInterval = config.Work.PollInterval
+	}
+	// The claim must get several renewal attempts before it expires
+	if s.renewInterval*2 > s.claimTTL {
+		s.renewInterval = s.claimTTL / 3
+	}
+
+	return s
+}
+
+// Start starts claiming and processing ranges
+func (s *WorkerService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRunning {
+		return fmt.Errorf("worker is already running")
+	}
+
+	s.isRunning = true
+	s.stopChan = make(chan struct{})
+
+	s.logger.Info("Starting worker",
+		zap.String("worker_id", s.workerID),
+		zap.Int("concurrency", s.concurrency),
+		zap.Duration("claim_ttl", s.claimTTL))
+
+	s.wg.Add(1)
+	go s.workLoop(context.WithoutCancel(ctx), s.stopChan)
+
+	return nil
+}
+
+// Stop stops the worker. The range in progress is released so another worker
+// can pick it up without waiting for the claim to expire.
+func (s *WorkerService) Stop() error {
+	s.mu.Lock()
+	if !s.isRunning {
+		s.mu.Unlock()
+		return nil
+	}
+	s.isRunning = false
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.logger.Info("Worker stopped")
+	return nil
+}
+
+// GetStats returns worker statistics
+func (s *WorkerService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := map[string]interface{}{
+		"worker_id":        s.workerID,
+		"is_running":       s.isRunning,
+		"ranges_completed": s.rangesCompleted,
+		"ranges_failed":    s.rangesFailed,
+		"blocks_processed": s.blocksProcessed,
+	}
+	if s.currentRange != nil {
+		stats["current_range"] = fmt.Sprintf("%d-%d", s.currentRange.StartBlock, s.currentRange.EndBlock)
+	}
+
+	return stats
+}
+
+// HealthCheck reports the worker statistics in the crawler health check,
+// degraded when the worker isn't running
+func (s *WorkerService) HealthCheck(ctx context.Context) entity.ComponentHealth {
+	stats := s.GetStats()
+
+	health := entity.ComponentHealth{
+		Status:      entity.HealthStatusHealthy,
+		LastChecked: time.Now(),
+	}
+
+	state := "idle"
+	if workRange, ok := stats["current_range"]; ok {
+		state = fmt.Sprintf("range %s", workRange)
+	}
+	if running, _ := stats["is_running"].(bool); !running {
+		health.Status = entity.HealthStatusDegraded
+		state = "not running"
+	}
+	health.Message = fmt.Sprintf("Worker %s %s, %d ranges completed, %d failed, %d blocks processed",
+		s.workerID, state, stats["ranges_completed"], stats["ranges_failed"], stats["blocks_processed"])
+
+	return health
+}
+
+// workLoop claims ranges until stopped, waiting pollInterval when idle
+func (s *WorkerService) workLoop(ctx context.Context, stopChan chan struct{}) {
+	defer s.wg.Done()
+
+	// Add panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic recovered in workLoop",
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		default:
+		}
+
+		claimed, err := s.claimAndProcess(ctx, stopChan)
+		if err != nil {
+			s.logger.Error("Failed to claim work range", zap.Error(err))
+		}
+		if claimed && err == nil {
+			continue
+		}
+
+		select {
+		case <-stopChan:
+			return
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// claimAndProcess claims one range and processes it. Returns false when there
+// was nothing to claim.
+func (s *WorkerService) claimAndProcess(ctx context.Context, stopChan chan struct{}) (bool, error) {
+	workRange, err := s.workRangeRepo.ClaimWorkRange(ctx, s.network, s.workerID, s.claimTTL, s.maxAttempts)
+	if err != nil {
+		return false, err
+	}
+	if workRange == nil {
+		return false, nil
+	}
+
+	logger := s.logger.With(
+		zap.Uint64("start_block", workRange.StartBlock),
+		zap.Uint64("end_block", workRange.EndBlock),
+		zap.Int("attempt", workRange.Attempts))
+	logger.Info("Claimed work range")
+
+	s.mu.Lock()
+	s.currentRange = workRange
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.currentRange = nil
+		s.mu.Unlock()
+	}()
+
+	start := time.Now()
+	processErr := s.processRange(ctx, workRange, stopChan)
+
+	switch {
+	case processErr == nil:
+		if err := s.workRangeRepo.CompleteWorkRange(ctx, workRange.ID, s.workerID); err != nil {
+			return true, fmt.Errorf("failed to complete work range: %w", err)
+		}
+		s.mu.Lock()
+		s.rangesCompleted++
+		s.mu.Unlock()
+		logger.Info("Completed work range", zap.Duration("duration", time.Since(start)))
+
+	case errors.Is(processErr, errClaimLost):
+		logger.Warn("Abandoning work range, claim was taken over")
+
+	case isStopped(stopChan):
+		// Shutting down is not the range's fault, don't count it as a failed attempt
+		if err := s.workRangeRepo.ReleaseWorkRange(ctx, workRange.ID, s.workerID, "worker stopped", math.MaxInt32); err != nil {
+			return true, fmt.Errorf("failed to release work range: %w", err)
+		}
+		logger.Info("Released work range on shutdown")
+
+	default:
+		if err := s.workRangeRepo.ReleaseWorkRange(ctx, workRange.ID, s.workerID, processErr.Error(), s.maxAttempts); err != nil {
+			return true, fmt.Errorf("failed to release work range: %w", err)
+		}
+		s.mu.Lock()
+		s.rangesFailed++
+		s.mu.Unlock()
+		logger.Error("Failed to process work range", zap.Error(processErr))
+	}
+
+	return true, nil
+}
+
+// processRange processes every block of the range with up to concurrency
+// blocks in flight while renewing the claim in the background
+func (s *WorkerService) processRange(ctx context.Context, workRange *entity.WorkRange, stopChan chan struct{}) error {
+	rangeCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// Renew the claim, abort when it is lost or the worker stops
+	renewDone := make(chan struct{})
+	go func() {
+		defer close(renewDone)
+		s.renewClaim(rangeCtx, workRange, stopChan, cancel)
+	}()
+	defer func() {
+		cancel(nil)
+		<-renewDone
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	slots := make(chan struct{}, s.concurrency)
+
+	for blockNumber := workRange.StartBlock; blockNumber <= workRange.EndBlock; blockNumber++ {
+		select {
+		case slots <- struct{}{}:
+		case <-rangeCtx.Done():
+		}
+		if rangeCtx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(blockNumber uint64) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := s.processor.ProcessSpecificBlock(rangeCtx, new(big.Int).SetUint64(blockNumber)); err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("block %d: %w", blockNumber, err)
+				}
+				errMu.Unlock()
+				// Remaining blocks are retried with the range
+				cancel(err)
+				return
+			}
+
+			s.mu.Lock()
+			s.blocksProcessed++
+			s.mu.Unlock()
+		}(blockNumber)
+	}
+	wg.Wait()
+
+	if cause := context.Cause(rangeCtx); cause != nil && errors.Is(cause, errClaimLost) {
+		return errClaimLost
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return context.Cause(rangeCtx)
+}
+
+// renewClaim extends the claim every renew interval until ctx is done
+func (s *WorkerService) renewClaim(ctx context.Context, workRange *entity.WorkRange, stopChan chan struct{}, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(s.renewInterval)
+	defer ticker.Stop()
+
+	claimValidUntil := time.Now().Add(s.claimTTL)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stopChan:
+			cancel(fmt.Errorf("worker stopped"))
+			return
+		case <-ticker.C:
+		}
+
+		attemptStart := time.Now()
+		extended, err := s.workRangeRepo.ExtendWorkRangeClaim(ctx, workRange.ID, s.workerID, s.claimTTL)
+		switch {
+		case err != nil:
+			s.logger.Warn("Failed to renew work range claim", zap.Error(err))
+			if time.Now().After(claimValidUntil) {
+				cancel(errClaimLost)
+				return
+			}
+		case !extended:
+			cancel(errClaimLost)
+			return
+		default:
+			claimValidUntil = attemptStart.Add(s.claimTTL)
+		}
+	}
+}
+
+// isStopped reports whether stopChan is closed
+func isStopped(stopChan chan struct{}) bool {
+	select {
+	case <-stopChan:
+		return true
+	default:
+		return false
+	}
+}
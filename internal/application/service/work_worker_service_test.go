@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryWorkRangeRepository mimics the Mongo claim semantics in memory
+type memoryWorkRangeRepository struct {
+	mu     sync.Mutex
+	ranges []*entity.WorkRange
+}
+
+func (r *memoryWorkRangeRepository) CreateWorkRanges(ctx context.Context, ranges []*entity.WorkRange, fencingToken int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.ranges {
+		if fencingToken > 0 && stored.FencingToken > fencingToken {
+			return repository.ErrStaleFencingToken
+		}
+	}
+	for _, workRange := range ranges {
+		workRange.ID = primitive.NewObjectID()
+		workRange.FencingToken = fencingToken
+		copied := *workRange
+		r.ranges = append(r.ranges, &copied)
+	}
+	sort.Slice(r.ranges, func(i, j int) bool { return r.ranges[i].StartBlock < r.ranges[j].StartBlock })
+	return nil
+}
+
+func (r *memoryWorkRangeRepository) GetLastWorkRange(ctx context.Context, network string) (*entity.WorkRange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.ranges) == 0 {
+		return nil, nil
+	}
+	copied := *r.ranges[len(r.ranges)-1]
+	return &copied, nil
+}
+
+func (r *memoryWorkRangeRepository) ClaimWorkRange(ctx context.Context, network, workerID string, claimTTL time.Duration, maxAttempts int) (*entity.WorkRange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, workRange := range r.ranges {
+		expired := workRange.Status == entity.WorkRangeStatusClaimed && !now.Before(*workRange.ClaimExpiresAt)
+		if expired && workRange.Attempts >= maxAttempts {
+			workRange.Status = entity.WorkRangeStatusFailed
+			workRange.LastError = "claim expired"
+			workRange.FailedAt = &now
+			workRange.ClaimExpiresAt = nil
+			continue
+		}
+		if workRange.Status != entity.WorkRangeStatusPending && !expired {
+			continue
+		}
+		expiresAt := now.Add(claimTTL)
+		workRange.Status = entity.WorkRangeStatusClaimed
+		workRange.WorkerID = workerID
+		workRange.ClaimedAt = &now
+		workRange.ClaimExpiresAt = &expiresAt
+		workRange.Attempts++
+		copied := *workRange
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *memoryWorkRangeRepository) ExtendWorkRangeClaim(ctx context.Context, id primitive.ObjectID, workerID string, claimTTL time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workRange := r.claimed(id, workerID)
+	if workRange == nil {
+		return false, nil
+	}
+	expiresAt := time.Now().Add(claimTTL)
+	workRange.ClaimExpiresAt = &expiresAt
+	return true, nil
+}
+
+func (r *memoryWorkRangeRepository) CompleteWorkRange(ctx context.Context, id primitive.ObjectID, workerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if workRange := r.claimed(id, workerID); workRange != nil {
+		now := time.Now()
+		workRange.Status = entity.WorkRangeStatusCompleted
+		workRange.CompletedAt = &now
+	}
+	return nil
+}
+
+func (r *memoryWorkRangeRepository) ReleaseWorkRange(ctx context.Context, id primitive.ObjectID, workerID string, lastError string, maxAttempts int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if workRange := r.claimed(id, workerID); workRange != nil {
+		workRange.Status = entity.WorkRangeStatusPending
+		if workRange.Attempts >= maxAttempts {
+			now := time.Now()
+			workRange.Status = entity.WorkRangeStatusFailed
+			workRange.FailedAt = &now
+		}
+		workRange.LastError = lastError
+		workRange.ClaimExpiresAt = nil
+	}
+	return nil
+}
+
+func (r *memoryWorkRangeRepository) RequeueFailedWorkRanges(ctx context.Context, network string, failedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var requeued int64
+	for _, workRange := range r.ranges {
+		if workRange.Status != entity.WorkRangeStatusFailed || (workRange.FailedAt != nil && workRange.FailedAt.After(failedBefore)) {
+			continue
+		}
+		workRange.Status = entity.WorkRangeStatusPending
+		workRange.Attempts = 0
+		workRange.FailedAt = nil
+		requeued++
+	}
+	return requeued, nil
+}
+
+func (r *memoryWorkRangeRepository) GetWorkRangeCountByStatus(ctx context.Context, network string, status entity.WorkRangeStatus) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, workRange := range r.ranges {
+		if workRange.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryWorkRangeRepository) claimed(id primitive.ObjectID, workerID string) *entity.WorkRange {
+	for _, workRange := range r.ranges {
+		if workRange.ID == id && workRange.WorkerID == workerID && workRange.Status == entity.WorkRangeStatusClaimed {
+			return workRange
+		}
+	}
+	return nil
+}
+
+func (r *memoryWorkRangeRepository) get(startBlock uint64) entity.WorkRange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, workRange := range r.ranges {
+		if workRange.StartBlock == startBlock {
+			return *workRange
+		}
+	}
+	return entity.WorkRange{}
+}
+
+// fakeBlockProcessor records processed blocks and fails the configured ones
+type fakeBlockProcessor struct {
+	mu        sync.Mutex
+	processed map[uint64]int
+	fail      map[uint64]bool
+	block     chan struct{}
+}
+
+func (p *fakeBlockProcessor) ProcessSpecificBlock(ctx context.Context, blockNumber *big.Int) error {
+	if p.block != nil {
+		select {
+		case <-p.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail[blockNumber.Uint64()] {
+		return errors.New("rpc timeout")
+	}
+	p.processed[blockNumber.Uint64()]++
+	return nil
+}
+
+func (p *fakeBlockProcessor) count(blockNumber uint64) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.processed[blockNumber]
+}
+
+func newTestWorker(t *testing.T, repo *memoryWorkRangeRepository, processor *fakeBlockProcessor, workerID string) *WorkerService {
+	cfg := &config.Config{
+		Ethereum: config.EthereumConfig{Network: "ethereum"},
+		Crawler:  config.CrawlerConfig{ConcurrentWorkers: 3},
+		Work: config.WorkConfig{
+			ClaimTTL:           300 * time.Millisecond,
+			ClaimRenewInterval: 50 * time.Millisecond,
+			MaxAttempts:        2,
+			PollInterval:       10 * time.Millisecond,
+			WorkerID:           workerID,
+		},
+	}
+	worker := NewWorkerService(repo, nil, cfg, newTestLogger(t))
+	worker.processor = processor
+	return worker
+}
+
+func newTestWorkRanges(t *testing.T, repo *memoryWorkRangeRepository, starts ...uint64) {
+	var ranges []*entity.WorkRange
+	for _, start := range starts {
+		ranges = append(ranges, &entity.WorkRange{
+			Network:    "ethereum",
+			StartBlock: start,
+			EndBlock:   start + 9,
+			Status:     entity.WorkRangeStatusPending,
+		})
+	}
+	require.NoError(t, repo.CreateWorkRanges(context.Background(), ranges, 0))
+}
+
+func TestWorkerService_ProcessesRanges(t *testing.T) {
+	repo := &memoryWorkRangeRepository{}
+	newTestWorkRanges(t, repo, 100, 110)
+	processor := &fakeBlockProcessor{processed: map[uint64]int{}, fail: map[uint64]bool{115: true}}
+	worker := newTestWorker(t, repo, processor, "worker-1")
+
+	require.NoError(t, worker.Start(context.Background()))
+	require.Eventually(t, func() bool {
+		return repo.get(110).Status == entity.WorkRangeStatusFailed
+	}, 2*time.Second, 10*time.Millisecond)
+	require.NoError(t, worker.Stop())
+
+	completed := repo.get(100)
+	assert.Equal(t, entity.WorkRangeStatusCompleted, completed.Status)
+	assert.NotNil(t, completed.CompletedAt)
+	for block := uint64(100); block <= 109; block++ {
+		assert.Equal(t, 1, processor.count(block), "block %d", block)
+	}
+
+	// The failing range is retried until MaxAttempts, then marked failed
+	failed := repo.get(110)
+	assert.Equal(t, 2, failed.Attempts)
+	assert.Contains(t, failed.LastError, "block 115")
+
+	stats := worker.GetStats()
+	assert.Equal(t, int64(1), stats["ranges_completed"])
+	assert.Equal(t, int64(2), stats["ranges_failed"])
+	assert.Contains(t, worker.HealthCheck(context.Background()).Message, "1 ranges completed, 2 failed")
+}
+
+func TestWorkerService_ReassignsExpiredClaim(t *testing.T) {
+	repo := &memoryWorkRangeRepository{}
+	newTestWorkRanges(t, repo, 100)
+
+	// A worker that died after claiming, without renewing its claim
+	_, err := repo.ClaimWorkRange(context.Background(), "ethereum", "lost-worker", 50*time.Millisecond, 2)
+	require.NoError(t, err)
+
+	processor := &fakeBlockProcessor{processed: map[uint64]int{}}
+	worker := newTestWorker(t, repo, processor, "worker-2")
+	require.NoError(t, worker.Start(context.Background()))
+	require.Eventually(t, func() bool {
+		return repo.get(100).Status == entity.WorkRangeStatusCompleted
+	}, 2*time.Second, 10*time.Millisecond)
+	require.NoError(t, worker.Stop())
+
+	workRange := repo.get(100)
+	assert.Equal(t, "worker-2", workRange.WorkerID)
+	assert.Equal(t, 2, workRange.Attempts)
+}
+
+func TestWorkerService_FailsExpiredClaimAtMaxAttempts(t *testing.T) {
+	repo := &memoryWorkRangeRepository{}
+	newTestWorkRanges(t, repo, 100)
+
+	// Workers that died on every attempt
+	for _, workerID := range []string{"lost-worker-1", "lost-worker-2"} {
+		claim, err := repo.ClaimWorkRange(context.Background(), "ethereum", workerID, 0, 2)
+		require.NoError(t, err)
+		require.NotNil(t, claim)
+	}
+
+	claim, err := repo.ClaimWorkRange(context.Background(), "ethereum", "worker-3", time.Minute, 2)
+	require.NoError(t, err)
+	assert.Nil(t, claim, "the range used up its attempts")
+	failed := repo.get(100)
+	assert.Equal(t, entity.WorkRangeStatusFailed, failed.Status)
+	assert.Equal(t, 2, failed.Attempts)
+	assert.Equal(t, "claim expired", failed.LastError)
+}
+
+func TestWorkerService_RenewsClaimAndReleasesOnStop(t *testing.T) {
+	repo := &memoryWorkRangeRepository{}
+	newTestWorkRanges(t, repo, 100)
+
+	// Blocks never finish, so the claim must be kept alive past its TTL
+	processor := &fakeBlockProcessor{processed: map[uint64]int{}, block: make(chan struct{})}
+	worker := newTestWorker(t, repo, processor, "worker-1")
+	require.NoError(t, worker.Start(context.Background()))
+	require.Eventually(t, func() bool {
+		return repo.get(100).Status == entity.WorkRangeStatusClaimed
+	}, time.Second, 10*time.Millisecond)
+
+	time.Sleep(500 * time.Millisecond)
+	claim, err := repo.ClaimWorkRange(context.Background(), "ethereum", "worker-2", time.Minute, 2)
+	require.NoError(t, err)
+	assert.Nil(t, claim, "renewed claim must not be reassigned")
+
+	require.NoError(t, worker.Stop())
+	released := repo.get(100)
+	assert.Equal(t, entity.WorkRangeStatusPending, released.Status)
+	assert.Equal(t, "worker stopped", released.LastError)
+}
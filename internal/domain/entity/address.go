@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// Address is an account seen in the crawled transactions, as sender,
+// recipient or created contract. The transaction counters are updated with
+// every transaction written or deleted, the rest is recorded as blocks are
+// processed.
+type Address struct {
+	Address string `bson:"address" json:"address"`
+
+	// First transaction involving the address
+	FirstSeenBlock   uint64 `bson:"first_seen_block" json:"first_seen_block"`
+	FirstSeenTxIndex uint   `bson:"first_seen_tx_index" json:"first_seen_tx_index"`
+	FirstSeenTx      string `bson:"first_seen_tx" json:"first_seen_tx"`
+	// Block of the last transaction involving the address
+	LastActiveBlock uint64 `bson:"last_active_block" json:"last_active_block"`
+
+	// Sent or received, a transaction to the address itself counted once
+	TransactionCount int64 `bson:"total" json:"transaction_count"`
+	SentCount        int64 `bson:"sent" json:"sent_count"`
+	ReceivedCount    int64 `bson:"received" json:"received_count"`
+
+	// IsContract is nil until the address is classified. An address that
+	// created no contract and sent no transaction is classified by its code.
+	IsContract *bool `bson:"is_contract,omitempty" json:"is_contract"`
+
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
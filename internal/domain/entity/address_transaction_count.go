@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// AddressTransactionCount counts the stored transactions of an address. The
+// counters are updated with every transaction written or deleted, so reading
+// them doesn't scan the transactions.
+type AddressTransactionCount struct {
+	Address string `bson:"address" json:"address"`
+	// Sent or received, a transaction to the address itself counted once
+	Total     int64     `bson:"total" json:"total"`
+	Sent      int64     `bson:"sent" json:"sent"`
+	Received  int64     `bson:"received" json:"received"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
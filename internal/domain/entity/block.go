@@ -34,12 +34,17 @@ type Block struct {
 	Network     string      `bson:"network" json:"network"`
 	ProcessedAt *time.Time  `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
 	Status      BlockStatus `bson:"status" json:"status"`
+
+	// Receipt repair of an incomplete block, set after a failed attempt
+	RepairAttempts int        `bson:"repair_attempts,omitempty" json:"repair_attempts,omitempty"`
+	NextRepairAt   *time.Time `bson:"next_repair_at,omitempty" json:"next_repair_at,omitempty"`
 }
 
 type BlockStatus string
 
 const (
-	BlockStatusPending   BlockStatus = "pending"
-	BlockStatusProcessed BlockStatus = "processed"
-	BlockStatusFailed    BlockStatus = "failed"
+	BlockStatusPending    BlockStatus = "pending"
+	BlockStatusProcessed  BlockStatus = "processed"
+	BlockStatusIncomplete BlockStatus = "incomplete" // Stored, but some transaction receipts are missing
+	BlockStatusFailed     BlockStatus = "failed"
 )
@@ -0,0 +1,15 @@
+package entity
+
+import (
+	"math/big"
+	"time"
+)
+
+// BlockHeader is a block header announced by the node (newHeads) before the
+// full block is fetched
+type BlockHeader struct {
+	Number     *big.Int  `json:"number"`
+	Hash       string    `json:"hash"`
+	ParentHash string    `json:"parent_hash"`
+	Timestamp  time.Time `json:"timestamp"`
+}
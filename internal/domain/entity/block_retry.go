@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BlockRetry represents a block that failed processing and is queued for retry
+type BlockRetry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Network       string             `bson:"network" json:"network"`
+	BlockNumber   uint64             `bson:"block_number" json:"block_number"`
+	Status        BlockRetryStatus   `bson:"status" json:"status"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	LastError     string             `bson:"last_error" json:"last_error"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+
+	// Metadata
+	CreatedAt    time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `bson:"updated_at" json:"updated_at"`
+	DeadLetterAt *time.Time `bson:"dead_letter_at,omitempty" json:"dead_letter_at,omitempty"`
+}
+
+type BlockRetryStatus string
+
+const (
+	BlockRetryStatusPending    BlockRetryStatus = "pending"
+	BlockRetryStatusDeadLetter BlockRetryStatus = "dead_letter"
+)
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// Checkpoint is the per-network crawl watermark. Every block from the start
+// block through CommittedBlock has been stored, so crawling resumes at
+// CommittedBlock + 1. HighestSeenBlock may run ahead when blocks are processed
+// out of order or a block keeps failing.
+type Checkpoint struct {
+	Network          string `bson:"_id" json:"network"`
+	CommittedBlock   uint64 `bson:"committed_block" json:"committed_block"`
+	HighestSeenBlock uint64 `bson:"highest_seen_block" json:"highest_seen_block"`
+
+	// Version is incremented on every write and guards against lost updates,
+	// e.g. an operator rewind racing with a running crawler
+	Version int64 `bson:"version" json:"version"`
+
+	// FencingToken is the lease token of the leader that wrote the checkpoint,
+	// writes with a lower token are rejected. 0 without leader election.
+	FencingToken int64 `bson:"fencing_token" json:"fencing_token"`
+
+	// Metadata
+	UpdatedAt time.Time  `bson:"updated_at" json:"updated_at"`
+	RewoundAt *time.Time `bson:"rewound_at,omitempty" json:"rewound_at,omitempty"`
+}
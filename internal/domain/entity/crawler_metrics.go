@@ -16,6 +16,7 @@ type CrawlerMetrics struct {
 	CurrentBlock       uint64  `bson:"current_block" json:"current_block"`
 	BlocksProcessed    uint64  `bson:"blocks_processed" json:"blocks_processed"`
 	BlocksPerSecond    float64 `bson:"blocks_per_second" json:"blocks_per_second"`
+	IncompleteBlocks   int64   `bson:"incomplete_blocks" json:"incomplete_blocks"` // Blocks waiting for missing receipts
 
 	// Transaction metrics
 	TransactionsProcessed uint64  `bson:"transactions_processed" json:"transactions_processed"`
@@ -32,8 +33,14 @@ type CrawlerMetrics struct {
 	GoroutineCount        int           `bson:"goroutine_count" json:"goroutine_count"`
 
 	// Network metrics
-	NetworkLatency time.Duration `bson:"network_latency" json:"network_latency"`
-	RPCCallsCount  uint64        `bson:"rpc_calls_count" json:"rpc_calls_count"`
+	NetworkLatency      time.Duration `bson:"network_latency" json:"network_latency"`
+	RPCCallsCount       uint64        `bson:"rpc_calls_count" json:"rpc_calls_count"`
+	RPCErrorRate        float64       `bson:"rpc_error_rate" json:"rpc_error_rate"`
+	RPCRateLimitedCount uint64        `bson:"rpc_rate_limited_count" json:"rpc_rate_limited_count"`
+
+	// Adaptive concurrency
+	ConcurrentWorkers int           `bson:"concurrent_workers" json:"concurrent_workers"`
+	RequestDelay      time.Duration `bson:"request_delay" json:"request_delay"`
 
 	// Database metrics
 	DBConnectionCount int     `bson:"db_connection_count" json:"db_connection_count"`
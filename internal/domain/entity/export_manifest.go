@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"sort"
+	"time"
+)
+
+// ExportManifestVersion is the version of the export file schema. It changes
+// whenever a column is added, removed or changes type.
+const ExportManifestVersion = 1
+
+// ExportManifest records the block ranges exported for a network, so
+// incremental exports only write blocks after the last exported range
+type ExportManifest struct {
+	Version   int           `json:"version"`
+	Network   string        `json:"network"`
+	Format    string        `json:"format"`
+	Ranges    []ExportRange `json:"ranges"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// ExportRange is one exported block range and the files holding it
+type ExportRange struct {
+	FromBlock    uint64    `json:"from_block"`
+	ToBlock      uint64    `json:"to_block"`
+	Blocks       int64     `json:"blocks"`
+	Transactions int64     `json:"transactions"`
+	Files        []string  `json:"files"` // Relative to the export root
+	ExportedAt   time.Time `json:"exported_at"`
+}
+
+// LastExportedBlock returns the last block of the exported blocks starting at
+// the lowest exported one without a gap, false when nothing was exported yet.
+// Ranges after a gap are ignored so an incremental export fills the gap first.
+func (m *ExportManifest) LastExportedBlock() (uint64, bool) {
+	ranges := m.sortedRanges()
+	if len(ranges) == 0 {
+		return 0, false
+	}
+
+	last := ranges[0].ToBlock
+	for _, r := range ranges[1:] {
+		if r.FromBlock > last+1 {
+			break
+		}
+		if r.ToBlock > last {
+			last = r.ToBlock
+		}
+	}
+	return last, true
+}
+
+// NextExportedBlock returns the first exported block after the given block,
+// false when no later block was exported
+func (m *ExportManifest) NextExportedBlock(after uint64) (uint64, bool) {
+	for _, r := range m.sortedRanges() {
+		if r.FromBlock > after {
+			return r.FromBlock, true
+		}
+	}
+	return 0, false
+}
+
+// sortedRanges returns the ranges ordered by their first block
+func (m *ExportManifest) sortedRanges() []ExportRange {
+	ranges := append([]ExportRange(nil), m.Ranges...)
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].FromBlock < ranges[j].FromBlock })
+	return ranges
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// Lease represents a named leadership lease held by one instance at a time
+type Lease struct {
+	Name     string `bson:"_id" json:"name"`
+	HolderID string `bson:"holder_id" json:"holder_id"`
+	// Token is a fencing token incremented every time the lease changes hands
+	Token      int64     `bson:"token" json:"token"`
+	AcquiredAt time.Time `bson:"acquired_at" json:"acquired_at"`
+	RenewedAt  time.Time `bson:"renewed_at" json:"renewed_at"`
+	ExpiresAt  time.Time `bson:"expires_at" json:"expires_at"`
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// RawBlock is the canonical encoding of a block and its receipts. Unlike Block
+// and Transaction it keeps everything (signatures, access lists, logs), so the
+// block can be reproduced exactly.
+type RawBlock struct {
+	Network  string
+	Number   uint64
+	Hash     string
+	Block    []byte   // RLP of the block: header, transactions, uncles and withdrawals
+	Receipts [][]byte // Consensus encoding of each receipt, in transaction order
+
+	ArchivedAt time.Time
+}
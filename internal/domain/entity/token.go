@@ -0,0 +1,57 @@
+package entity
+
+import "time"
+
+// TokenInterface is an interface a token contract reports through ERC-165
+type TokenInterface string
+
+const (
+	TokenInterfaceERC165             TokenInterface = "erc165"
+	TokenInterfaceERC721             TokenInterface = "erc721"
+	TokenInterfaceERC721Metadata     TokenInterface = "erc721_metadata"
+	TokenInterfaceERC1155            TokenInterface = "erc1155"
+	TokenInterfaceERC1155MetadataURI TokenInterface = "erc1155_metadata_uri"
+)
+
+// Token is the metadata of a token contract, read with eth_call and cached.
+// A token is stored when its first transfer is seen and resolved later;
+// fields the contract doesn't implement stay empty.
+type Token struct {
+	Address string `bson:"address" json:"address"`
+	// From the ERC-165 interfaces, otherwise from the first transfer seen
+	Standard       TokenStandard `bson:"standard" json:"standard"`
+	FirstSeenBlock uint64        `bson:"first_seen_block" json:"first_seen_block"`
+
+	Name     string `bson:"name,omitempty" json:"name,omitempty"`
+	Symbol   string `bson:"symbol,omitempty" json:"symbol,omitempty"`
+	Decimals *uint8 `bson:"decimals,omitempty" json:"decimals,omitempty"`
+	// Decimal string, up to 256 bits
+	TotalSupply string           `bson:"total_supply,omitempty" json:"total_supply,omitempty"`
+	Interfaces  []TokenInterface `bson:"interfaces,omitempty" json:"interfaces,omitempty"`
+
+	// ResolvedAt is zero until the metadata is read the first time
+	ResolvedAt time.Time `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	// Lookups failed in a row, with the last error
+	Failures  int    `bson:"failures" json:"failures"`
+	LastError string `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	// RefreshAt is when the metadata is read next
+	RefreshAt time.Time `bson:"refresh_at" json:"refresh_at"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// IsResolved reports whether the metadata was read at least once
+func (t *Token) IsResolved() bool {
+	return !t.ResolvedAt.IsZero()
+}
+
+// SupportsInterface reports whether the token reported the interface
+func (t *Token) SupportsInterface(iface TokenInterface) bool {
+	for _, supported := range t.Interfaces {
+		if supported == iface {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TokenStandard is the token standard of a transfer
+type TokenStandard string
+
+const (
+	TokenStandardERC20   TokenStandard = "erc20"
+	TokenStandardERC721  TokenStandard = "erc721"
+	TokenStandardERC1155 TokenStandard = "erc1155"
+)
+
+// TokenTransfer is a movement of tokens decoded from a Transfer,
+// TransferSingle or TransferBatch log. A TransferBatch log gives one transfer
+// per token, told apart by BatchIndex.
+type TokenTransfer struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Token    string             `bson:"token" json:"token"` // Contract that emitted the log
+	Standard TokenStandard      `bson:"standard" json:"standard"`
+	From     string             `bson:"from" json:"from"`                             // Zero address for mints
+	To       string             `bson:"to" json:"to"`                                 // Zero address for burns
+	Operator string             `bson:"operator,omitempty" json:"operator,omitempty"` // ERC-1155 only
+
+	// Decimal strings, amounts and token IDs take up to 256 bits, more than
+	// Decimal128 holds
+	Amount  string `bson:"amount" json:"amount"`                         // 1 for ERC-721
+	TokenID string `bson:"token_id,omitempty" json:"token_id,omitempty"` // Empty for ERC-20
+
+	TransactionHash  string    `bson:"transaction_hash" json:"transaction_hash"`
+	TransactionIndex uint      `bson:"transaction_index" json:"transaction_index"`
+	LogIndex         uint      `bson:"log_index" json:"log_index"`
+	BatchIndex       uint      `bson:"batch_index" json:"batch_index"`
+	BlockNumber      uint64    `bson:"block_number" json:"block_number"`
+	BlockHash        string    `bson:"block_hash" json:"block_hash"`
+	BlockTimestamp   time.Time `bson:"block_timestamp" json:"block_timestamp"`
+
+	// Metadata
+	Network   string    `bson:"network" json:"network"`
+	CrawledAt time.Time `bson:"crawled_at" json:"crawled_at"`
+}
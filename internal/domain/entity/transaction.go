@@ -12,25 +12,33 @@ type Transaction struct {
 	Hash              string             `bson:"hash" json:"hash"`
 	BlockHash         string             `bson:"block_hash" json:"block_hash"`
 	BlockNumber       string             `bson:"block_number" json:"block_number"`
+	BlockTimestamp    time.Time          `bson:"block_timestamp" json:"block_timestamp"` // Zero when the block is unknown
 	TransactionIndex  uint               `bson:"transaction_index" json:"transaction_index"`
 	From              string             `bson:"from" json:"from"`
 	To                *string            `bson:"to" json:"to"` // Can be nil for contract creation
-	Value             string             `bson:"value" json:"value"`
+	Value             Wei                `bson:"value" json:"value"`
 	Gas               uint64             `bson:"gas" json:"gas"`
-	GasPrice          string             `bson:"gas_price" json:"gas_price"`
+	GasPrice          Wei                `bson:"gas_price" json:"gas_price"`
 	GasUsed           uint64             `bson:"gas_used" json:"gas_used"`
 	CumulativeGasUsed uint64             `bson:"cumulative_gas_used" json:"cumulative_gas_used"`
 	Data              string             `bson:"data" json:"data"`
 	Nonce             uint64             `bson:"nonce" json:"nonce"`
-	Status            uint64             `bson:"status" json:"status"` // 1 for success, 0 for failure
+	Status            uint64             `bson:"status" json:"status"` // 1 for success, 0 for failure, 0 while ReceiptMissing
 
 	// EIP-1559 fields
-	MaxFeePerGas         string `bson:"max_fee_per_gas,omitempty" json:"max_fee_per_gas,omitempty"`
-	MaxPriorityFeePerGas string `bson:"max_priority_fee_per_gas,omitempty" json:"max_priority_fee_per_gas,omitempty"`
+	MaxFeePerGas         Wei `bson:"max_fee_per_gas,omitempty" json:"max_fee_per_gas,omitempty"`
+	MaxPriorityFeePerGas Wei `bson:"max_priority_fee_per_gas,omitempty" json:"max_priority_fee_per_gas,omitempty"`
 
 	// Contract creation
 	ContractAddress *string `bson:"contract_address,omitempty" json:"contract_address,omitempty"`
 
+	// The receipt could not be fetched, receipt fields (Status, GasUsed,
+	// CumulativeGasUsed, ContractAddress) are unknown until it is repaired
+	ReceiptMissing bool `bson:"receipt_missing,omitempty" json:"receipt_missing,omitempty"`
+
+	// Token transfers decoded from the receipt logs, stored on their own
+	TokenTransfers []*TokenTransfer `bson:"-" json:"-"`
+
 	// Metadata
 	CrawledAt   time.Time         `bson:"crawled_at" json:"crawled_at"`
 	Network     string            `bson:"network" json:"network"`
@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"fmt"
+	"math/big"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Wei is an amount of wei as a decimal string. MongoDB stores it as
+// Decimal128 so amounts sort and sum numerically; Decimal128 holds 34 digits,
+// well above any amount of ether in existence (about 27 digits in wei).
+type Wei string
+
+// BigInt returns the amount, false when it isn't a decimal integer
+func (w Wei) BigInt() (*big.Int, bool) {
+	return new(big.Int).SetString(string(w), 10)
+}
+
+// Decimal128 converts the amount to Decimal128, failing when it doesn't fit exactly
+func (w Wei) Decimal128() (primitive.Decimal128, error) {
+	amount, ok := w.BigInt()
+	if !ok {
+		return primitive.Decimal128{}, fmt.Errorf("invalid wei amount %q", string(w))
+	}
+	d, ok := primitive.ParseDecimal128FromBigInt(amount, 0)
+	if !ok {
+		return primitive.Decimal128{}, fmt.Errorf("wei amount %s doesn't fit in Decimal128", string(w))
+	}
+	return d, nil
+}
+
+// WeiFromDecimal128 converts a Decimal128 integer to an amount of wei
+func WeiFromDecimal128(d primitive.Decimal128) (Wei, error) {
+	amount, exp, err := d.BigInt()
+	if err != nil {
+		return "", fmt.Errorf("invalid wei amount %s: %w", d, err)
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil)
+	if exp >= 0 {
+		return Wei(amount.Mul(amount, scale).String()), nil
+	}
+
+	// Written as a fraction by hand or by $toDecimal, e.g. 1.0E+3
+	quotient, remainder := new(big.Int).QuoRem(amount, scale, new(big.Int))
+	if remainder.Sign() != 0 {
+		return "", fmt.Errorf("wei amount %s is not an integer", d)
+	}
+	return Wei(quotient.String()), nil
+}
+
+// MarshalBSONValue stores the amount as Decimal128, an empty amount as null
+func (w Wei) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if w == "" {
+		return bsontype.Null, nil, nil
+	}
+	d, err := w.Decimal128()
+	if err != nil {
+		return 0, nil, err
+	}
+	return bson.MarshalValue(d)
+}
+
+// UnmarshalBSONValue reads Decimal128 amounts and the strings stored before
+// the Decimal128 migration
+func (w *Wei) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.Null, bsontype.Undefined:
+		*w = ""
+	case bsontype.String:
+		*w = Wei(value.StringValue())
+	case bsontype.Decimal128:
+		amount, err := WeiFromDecimal128(value.Decimal128())
+		if err != nil {
+			return err
+		}
+		*w = amount
+	default:
+		return fmt.Errorf("cannot decode %s as wei", t)
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
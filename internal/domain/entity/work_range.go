@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkRange represents a block range distributed to crawler workers
+type WorkRange struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Network    string             `bson:"network" json:"network"`
+	StartBlock uint64             `bson:"start_block" json:"start_block"`
+	EndBlock   uint64             `bson:"end_block" json:"end_block"` // Inclusive
+	Status     WorkRangeStatus    `bson:"status" json:"status"`
+
+	// Lease token of the leader that published the range, 0 without leader election
+	FencingToken int64 `bson:"fencing_token" json:"fencing_token"`
+	// Block the coordinator publishes ranges up to, restored when a coordinator
+	// resumes so the horizon doesn't move with a newer head
+	WorkEndBlock uint64 `bson:"work_end_block,omitempty" json:"work_end_block,omitempty"`
+
+	// Claim state, a claim is lost when it isn't extended before ClaimExpiresAt
+	WorkerID       string     `bson:"worker_id,omitempty" json:"worker_id,omitempty"`
+	ClaimedAt      *time.Time `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"`
+	ClaimExpiresAt *time.Time `bson:"claim_expires_at,omitempty" json:"claim_expires_at,omitempty"`
+	Attempts       int        `bson:"attempts" json:"attempts"`
+	LastError      string     `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	FailedAt       *time.Time `bson:"failed_at,omitempty" json:"failed_at,omitempty"` // Requeued after the failed retry delay
+
+	// Metadata
+	CreatedAt   time.Time  `bson:"created_at" json:"created_at"`
+	CompletedAt *time.Time `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+type WorkRangeStatus string
+
+const (
+	WorkRangeStatusPending   WorkRangeStatus = "pending"
+	WorkRangeStatusClaimed   WorkRangeStatus = "claimed"
+	WorkRangeStatusCompleted WorkRangeStatus = "completed"
+	WorkRangeStatusFailed    WorkRangeStatus = "failed"
+)
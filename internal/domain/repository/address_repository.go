@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+)
+
+// AddressUpdate is the activity of an address in a block
+type AddressUpdate struct {
+	Address string
+	// First transaction of the block involving the address
+	FirstBlock   uint64
+	FirstTxIndex uint
+	FirstTx      string
+	// LastBlock is the block of its last transaction
+	LastBlock uint64
+	// IsContract is nil when the transactions don't tell
+	IsContract *bool
+}
+
+// AddressRepository interface for address operations. The transaction
+// counters of addresses are kept by the TransactionRepository.
+type AddressRepository interface {
+	// RecordActivity moves the first-seen transaction of each address earlier
+	// and its last-active block later, adding the addresses not stored yet.
+	// Recording the same activity again changes nothing.
+	RecordActivity(ctx context.Context, updates []*AddressUpdate) error
+	// SetContractStatus records whether addresses are contracts. An address
+	// known as a contract stays one.
+	SetContractStatus(ctx context.Context, statuses map[string]bool) error
+
+	// GetAddress returns nil when the address is not stored
+	GetAddress(ctx context.Context, address string) (*entity.Address, error)
+	// GetUnclassifiedAddresses returns the addresses among the given ones
+	// that are not stored or not known to be contracts or not
+	GetUnclassifiedAddresses(ctx context.Context, addresses []string) ([]string, error)
+	// ListUnclassifiedAddresses returns stored addresses not classified yet,
+	// in address order after the given address ("" for the first)
+	ListUnclassifiedAddresses(ctx context.Context, after string, limit int) ([]string, error)
+
+	// RebuildAddresses recomputes every address and its counters from the
+	// stored transactions, classifying senders and created contracts.
+	// Transactions written while it runs may be missed or counted twice, so
+	// run it while the crawler is stopped. Returns the number of addresses.
+	RebuildAddresses(ctx context.Context) (int64, error)
+}
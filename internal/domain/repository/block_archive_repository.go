@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+)
+
+// BlockArchiveRepository interface for raw block archive operations
+type BlockArchiveRepository interface {
+	// Create operations
+	SaveRawBlock(ctx context.Context, raw *entity.RawBlock) error
+
+	// Read operations
+	// GetRawBlockByNumber returns the most recently archived block at the height
+	GetRawBlockByNumber(ctx context.Context, network string, blockNumber uint64) (*entity.RawBlock, error)
+	GetRawBlockByHash(ctx context.Context, network string, blockHash string) (*entity.RawBlock, error)
+
+	// Utility operations
+	RawBlockExists(ctx context.Context, network string, blockHash string) (bool, error)
+}
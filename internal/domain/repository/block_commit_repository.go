@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"time"
+)
+
+// BlockCommit is a block with its transactions and the status to commit it with
+type BlockCommit struct {
+	Block        *entity.Block
+	Transactions []*entity.Transaction
+	Status       entity.BlockStatus
+}
+
+// BlockCommitRepository interface for storing a block together with its transactions
+type BlockCommitRepository interface {
+	// CommitBlock stores the block unless it is stored, upserts the transactions
+	// and sets the block status. A block of another hash stored at its height,
+	// left behind by a reorganization, is deleted with its transactions and
+	// token transfers. The writes are applied all or nothing: in a single
+	// transaction when the deployment supports it, otherwise the block and
+	// transactions written by the failed commit are removed again.
+	CommitBlock(ctx context.Context, block *entity.Block, transactions []*entity.Transaction, status entity.BlockStatus) error
+
+	// CommitBlocks commits several blocks like CommitBlock, with one bulk write
+	// per collection. The blocks are committed or fail together.
+	CommitBlocks(ctx context.Context, commits []*BlockCommit) error
+
+	// IsAtomic reports whether commits run in a multi-document transaction
+	IsAtomic(ctx context.Context) (bool, error)
+
+	// CleanupUncommittedBlocks deletes pending blocks crawled before the given
+	// time, and their transactions, left behind by commits that never finished.
+	// Returns the numbers of the deleted blocks.
+	CleanupUncommittedBlocks(ctx context.Context, network string, before time.Time) ([]uint64, error)
+
+	// CountUncountedTransactions adds the transactions stored before the given
+	// time and still missing from the address counters, left behind by writes
+	// that stopped between storing and counting them. Returns the number counted.
+	CountUncountedTransactions(ctx context.Context, network string, before time.Time) (int64, error)
+}
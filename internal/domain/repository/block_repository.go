@@ -4,6 +4,7 @@ import (
 	"context"
 	"ethereum-raw-data-crawler/internal/domain/entity"
 	"math/big"
+	"time"
 )
 
 // BlockRepository interface for block data operations
@@ -18,10 +19,23 @@ type BlockRepository interface {
 	GetBlocksInRange(ctx context.Context, startBlock, endBlock *big.Int) ([]*entity.Block, error)
 	GetLastProcessedBlock(ctx context.Context, network string) (*entity.Block, error)
 	GetBlocksByStatus(ctx context.Context, status entity.BlockStatus, limit int) ([]*entity.Block, error)
+	// GetBlockRangeByTime returns the first and last block of a network mined
+	// within the time range, both nil when there is none
+	GetBlockRangeByTime(ctx context.Context, network string, startTime, endTime time.Time) (first, last *entity.Block, err error)
+	// GetBlocksByTimeRange returns the blocks of a network mined within the
+	// time range, both ends inclusive
+	GetBlocksByTimeRange(ctx context.Context, network string, startTime, endTime time.Time) ([]*entity.Block, error)
+	// GetBlocksDueForRepair returns the incomplete blocks of a network whose
+	// next receipt repair is due at the given time, the ones never tried first
+	// and then the longest overdue
+	GetBlocksDueForRepair(ctx context.Context, network string, now time.Time, limit int) ([]*entity.Block, error)
 
 	// Update operations
 	UpdateBlockStatus(ctx context.Context, blockHash string, status entity.BlockStatus) error
 	MarkBlockAsProcessed(ctx context.Context, blockHash string) error
+	// ScheduleBlockRepair records the failed receipt repairs of a block and
+	// when the next one is due
+	ScheduleBlockRepair(ctx context.Context, blockHash string, attempts int, nextAttempt time.Time) error
 
 	// Delete operations
 	DeleteBlock(ctx context.Context, blockHash string) error
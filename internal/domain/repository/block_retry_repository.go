@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"time"
+)
+
+// BlockRetryRepository interface for block retry queue operations
+type BlockRetryRepository interface {
+	// Create operations
+	UpsertBlockRetry(ctx context.Context, retry *entity.BlockRetry) error
+
+	// Read operations
+	GetBlockRetry(ctx context.Context, network string, blockNumber uint64) (*entity.BlockRetry, error)
+	GetDueBlockRetries(ctx context.Context, network string, dueBefore time.Time, limit int) ([]*entity.BlockRetry, error)
+	GetBlockRetriesByStatus(ctx context.Context, network string, status entity.BlockRetryStatus, limit int) ([]*entity.BlockRetry, error)
+
+	// Update operations
+	RequeueBlockRetry(ctx context.Context, network string, blockNumber uint64, nextAttemptAt time.Time) (bool, error)
+	RequeueDeadLetterBlockRetries(ctx context.Context, network string, nextAttemptAt time.Time) (int64, error)
+
+	// Delete operations
+	DeleteBlockRetry(ctx context.Context, network string, blockNumber uint64) error
+
+	// Utility operations
+	GetBlockRetryCountByStatus(ctx context.Context, network string, status entity.BlockRetryStatus) (int64, error)
+}
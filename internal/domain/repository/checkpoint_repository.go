@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+)
+
+// CheckpointRepository interface for crawl watermark operations
+type CheckpointRepository interface {
+	// GetCheckpoint returns nil when the network has no checkpoint yet
+	GetCheckpoint(ctx context.Context, network string) (*entity.Checkpoint, error)
+	// SaveCheckpoint writes the checkpoint if the stored version still equals
+	// checkpoint.Version (0 for a new checkpoint) and the stored fencing token
+	// isn't above checkpoint.FencingToken, and bumps the version.
+	// Returns false when another writer changed it first.
+	SaveCheckpoint(ctx context.Context, checkpoint *entity.Checkpoint) (bool, error)
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"time"
+)
+
+// ErrStaleFencingToken is returned by writes guarded by the leader lease when
+// a leader with a higher fencing token has written since
+var ErrStaleFencingToken = errors.New("stale fencing token, a newer leader has written")
+
+// LeaseRepository interface for leadership lease operations
+type LeaseRepository interface {
+	// AcquireLease takes the lease if it is free or expired and increments its
+	// fencing token. Returns nil when another holder owns an unexpired lease.
+	AcquireLease(ctx context.Context, name, holderID string, ttl time.Duration) (*entity.Lease, error)
+	// RenewLease extends a lease still held with the given token.
+	// Returns nil when the lease was lost.
+	RenewLease(ctx context.Context, name, holderID string, token int64, ttl time.Duration) (*entity.Lease, error)
+	// ReleaseLease expires a lease held with the given token so another holder can take it
+	ReleaseLease(ctx context.Context, name, holderID string, token int64) error
+	// GetLease gets the current lease state
+	GetLease(ctx context.Context, name string) (*entity.Lease, error)
+}
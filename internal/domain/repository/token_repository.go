@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"time"
+)
+
+// TokenRepository interface for the token metadata cache
+type TokenRepository interface {
+	// AddTokens stores the tokens not stored yet and returns their addresses.
+	// Stored tokens are left untouched.
+	AddTokens(ctx context.Context, tokens []*entity.Token) ([]string, error)
+	// SaveToken stores the metadata and refresh state of a token, keeping the
+	// first-seen block and creation time of a stored one
+	SaveToken(ctx context.Context, token *entity.Token) error
+
+	// GetToken returns nil when the token is not stored
+	GetToken(ctx context.Context, address string) (*entity.Token, error)
+	// GetTokensToRefresh returns the tokens due for a lookup at the given
+	// time, longest due first
+	GetTokensToRefresh(ctx context.Context, now time.Time, limit int) ([]*entity.Token, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+)
+
+// TokenTransferRepository interface for token transfer operations
+type TokenTransferRepository interface {
+	// SaveTokenTransfers stores transfers by transaction, log index and batch
+	// index. Saving a stored transfer again replaces it.
+	SaveTokenTransfers(ctx context.Context, transfers []*entity.TokenTransfer) error
+
+	// Read operations, newest first when paginated
+	GetTokenTransfersByTransaction(ctx context.Context, txHash string) ([]*entity.TokenTransfer, error)
+	GetTokenTransfersByToken(ctx context.Context, token string, limit int, offset int) ([]*entity.TokenTransfer, error)
+	// GetTokenTransfersByHolder returns the transfers from or to an address
+	GetTokenTransfersByHolder(ctx context.Context, holder string, limit int, offset int) ([]*entity.TokenTransfer, error)
+
+	// Delete operations
+	DeleteTokenTransfersByBlockHash(ctx context.Context, blockHash string) error
+}
@@ -4,8 +4,42 @@ import (
 	"context"
 	"ethereum-raw-data-crawler/internal/domain/entity"
 	"math/big"
+	"time"
 )
 
+// AddressDirection selects the transactions of an address by its role
+type AddressDirection string
+
+const (
+	// AddressDirectionAll selects the transactions sent or received
+	AddressDirectionAll AddressDirection = ""
+	// AddressDirectionIn selects the transactions received
+	AddressDirectionIn AddressDirection = "in"
+	// AddressDirectionOut selects the transactions sent
+	AddressDirectionOut AddressDirection = "out"
+)
+
+// AddressTransactionFilter selects the transactions of an address
+type AddressTransactionFilter struct {
+	Address   string
+	Direction AddressDirection
+	// Success selects succeeded (true) or failed (false) transactions, nil for
+	// both. Transactions with a missing receipt are neither.
+	Success *bool
+	// ContractCreation selects contract creations (true) or the other
+	// transactions (false), nil for both
+	ContractCreation *bool
+	// FromBlock and ToBlock bound the blocks, both inclusive, nil when unbounded
+	FromBlock *uint64
+	ToBlock   *uint64
+}
+
+// TransactionPosition is the position of a transaction in the chain
+type TransactionPosition struct {
+	BlockNumber      uint64
+	TransactionIndex uint
+}
+
 // TransactionRepository interface for transaction data operations
 type TransactionRepository interface {
 	// Create operations
@@ -18,11 +52,17 @@ type TransactionRepository interface {
 	GetTransactionsByBlockHash(ctx context.Context, blockHash string) ([]*entity.Transaction, error)
 	GetTransactionsByBlockNumber(ctx context.Context, blockNumber *big.Int) ([]*entity.Transaction, error)
 	GetTransactionsByAddress(ctx context.Context, address string, limit int, offset int) ([]*entity.Transaction, error)
+	// GetAddressTransactions returns the transactions of an address matching the
+	// filter, newest first, starting after the position (nil for the newest)
+	GetAddressTransactions(ctx context.Context, filter AddressTransactionFilter, after *TransactionPosition, limit int) ([]*entity.Transaction, error)
 	GetTransactionsByStatus(ctx context.Context, status entity.TransactionStatus, limit int) ([]*entity.Transaction, error)
-	GetTransactionsByTimeRange(ctx context.Context, startTime, endTime *big.Int) ([]*entity.Transaction, error)
+	// GetTransactionsByTimeRange returns the transactions of blocks mined
+	// within the time range, both ends inclusive
+	GetTransactionsByTimeRange(ctx context.Context, startTime, endTime time.Time) ([]*entity.Transaction, error)
 
 	// Update operations
 	UpdateTransactionStatus(ctx context.Context, hash string, status entity.TransactionStatus) error
+	UpdateTransactionReceipt(ctx context.Context, tx *entity.Transaction) error
 	MarkTransactionAsProcessed(ctx context.Context, hash string) error
 
 	// Delete operations
@@ -34,8 +74,11 @@ type TransactionRepository interface {
 	GetTransactionCount(ctx context.Context, network string) (int64, error)
 	GetTransactionCountByStatus(ctx context.Context, status entity.TransactionStatus, network string) (int64, error)
 	GetTransactionCountByAddress(ctx context.Context, address string) (int64, error)
+	// GetAddressTransactionCount returns the transaction counters of an
+	// address, zero when it has no transactions
+	GetAddressTransactionCount(ctx context.Context, address string) (*entity.AddressTransactionCount, error)
 
 	// Analytics operations
-	GetTransactionVolumeByTimeRange(ctx context.Context, startTime, endTime *big.Int) (*big.Int, error)
+	GetTransactionVolumeByTimeRange(ctx context.Context, startTime, endTime time.Time) (*big.Int, error)
 	GetTopTransactionsByValue(ctx context.Context, limit int) ([]*entity.Transaction, error)
 }
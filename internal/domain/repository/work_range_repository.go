@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkRangeRepository interface for distributed block range operations
+type WorkRangeRepository interface {
+	// Create operations
+	// CreateWorkRanges stores the ranges with the fencing token of the publishing
+	// leader. Returns ErrStaleFencingToken when ranges were published with a
+	// higher token; a token of 0 (no leader election) isn't checked.
+	CreateWorkRanges(ctx context.Context, ranges []*entity.WorkRange, fencingToken int64) error
+
+	// Read operations
+	GetLastWorkRange(ctx context.Context, network string) (*entity.WorkRange, error)
+
+	// Claim operations
+	// ClaimWorkRange claims the lowest pending range, or a claimed range whose claim
+	// expired before maxAttempts. Expired claims at maxAttempts are marked failed.
+	// Returns nil when there is no work.
+	ClaimWorkRange(ctx context.Context, network, workerID string, claimTTL time.Duration, maxAttempts int) (*entity.WorkRange, error)
+	// ExtendWorkRangeClaim extends a claim still held by the worker
+	ExtendWorkRangeClaim(ctx context.Context, id primitive.ObjectID, workerID string, claimTTL time.Duration) (bool, error)
+	// CompleteWorkRange marks a range claimed by the worker as completed
+	CompleteWorkRange(ctx context.Context, id primitive.ObjectID, workerID string) error
+	// ReleaseWorkRange returns a range claimed by the worker to the queue, or marks
+	// it failed when it reached maxAttempts
+	ReleaseWorkRange(ctx context.Context, id primitive.ObjectID, workerID string, lastError string, maxAttempts int) error
+	// RequeueFailedWorkRanges returns the ranges that failed before failedBefore to
+	// the queue with their attempts reset, and returns how many were requeued
+	RequeueFailedWorkRanges(ctx context.Context, network string, failedBefore time.Time) (int64, error)
+
+	// Utility operations
+	GetWorkRangeCountByStatus(ctx context.Context, network string, status entity.WorkRangeStatus) (int64, error)
+}
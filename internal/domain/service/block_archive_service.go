@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+)
+
+// ArchivedBlockReader reads blocks from the raw block archive without RPC calls
+type ArchivedBlockReader interface {
+	// GetArchivedBlock derives the block and transaction entities of the most
+	// recently archived block at the height. It returns nil when the block
+	// isn't archived.
+	GetArchivedBlock(ctx context.Context, blockNumber uint64) (*entity.Block, []*entity.Transaction, error)
+}
+
+// ArchivedBlockProver rebuilds the tries of archived blocks
+type ArchivedBlockProver interface {
+	// GetArchivedBlockProof rebuilds the transactions and receipts tries from
+	// the archived block with the hash and its receipts. It returns nil when
+	// the block isn't archived.
+	GetArchivedBlockProof(ctx context.Context, blockHash string) (*BlockProof, error)
+}
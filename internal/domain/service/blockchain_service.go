@@ -2,10 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
 	"ethereum-raw-data-crawler/internal/domain/entity"
 	"math/big"
+	"time"
 )
 
+// ErrExecutionReverted is returned by CallContract when the call reverts or
+// fails in the EVM, such as a contract without the called function
+var ErrExecutionReverted = errors.New("execution reverted")
+
 // BlockchainService interface for blockchain interactions
 type BlockchainService interface {
 	// Connection management
@@ -23,9 +29,21 @@ type BlockchainService interface {
 	GetTransactionReceipt(ctx context.Context, txHash string) (*entity.Transaction, error)
 	GetTransactionsByBlock(ctx context.Context, blockNumber *big.Int) ([]*entity.Transaction, error)
 
+	// Account operations
+	GetCode(ctx context.Context, address string, blockNumber *big.Int) ([]byte, error)
+
+	// Contract calls, at the latest block when blockNumber is nil
+	CallContract(ctx context.Context, address string, data []byte, blockNumber *big.Int) ([]byte, error)
+
 	// Batch operations
 	GetBlocksInRange(ctx context.Context, startBlock, endBlock *big.Int) ([]*entity.Block, error)
 
+	// Verification
+	GetBlockProof(ctx context.Context, blockNumber *big.Int) (*BlockProof, error)
+
+	// Archive
+	GetRawBlock(ctx context.Context, blockNumber *big.Int) (*entity.RawBlock, error)
+
 	// Network information
 	GetNetworkID(ctx context.Context) (*big.Int, error)
 	GetGasPrice(ctx context.Context) (*big.Int, error)
@@ -35,6 +53,47 @@ type BlockchainService interface {
 	HealthCheck(ctx context.Context) error
 }
 
+// BlockProof is a block as the node serves it, with the transactions and
+// receipts tries rebuilt from its body and receipts
+type BlockProof struct {
+	Hash             string
+	TransactionsRoot string
+	ReceiptsRoot     string
+
+	// Transactions in block order, with their receipt fields
+	Transactions []*entity.Transaction
+}
+
+// NewHeadEvent is a new head notification. Events are delivered one at a time
+// in the order the node announced them.
+type NewHeadEvent struct {
+	Header *entity.BlockHeader
+
+	// SkippedBlocks are the heights between the previous head and this one
+	// that were never announced, in ascending order
+	SkippedBlocks []*big.Int
+
+	// ReplacedHash is the hash previously announced at the same height. A
+	// non-empty value signals a reorg.
+	ReplacedHash string
+}
+
+// IsReorg reports whether the head replaces a different block at the same height
+func (e *NewHeadEvent) IsReorg() bool {
+	return e.ReplacedHash != ""
+}
+
+// HeadSourceStats describes one endpoint of a BlockSchedulerService
+type HeadSourceStats struct {
+	Name           string        `json:"name"`
+	Connected      bool          `json:"connected"`
+	HeadsReceived  int64         `json:"heads_received"`
+	FirstSeen      int64         `json:"first_seen"`      // Heads announced before any other source
+	AverageLatency time.Duration `json:"average_latency"` // Block timestamp to arrival
+	AverageLag     time.Duration `json:"average_lag"`     // Delay behind the first source
+	LastHeadTime   time.Time     `json:"last_head_time"`
+}
+
 // BlockSchedulerService defines the interface for real-time block scheduling
 type BlockSchedulerService interface {
 	// Start the scheduler to listen for new blocks
@@ -47,8 +106,14 @@ type BlockSchedulerService interface {
 	IsRunning() bool
 
 	// Subscribe to new block events
-	SubscribeNewBlocks(ctx context.Context, callback func(*big.Int)) error
+	SubscribeNewBlocks(ctx context.Context, callback func(*NewHeadEvent)) error
 
 	// Unsubscribe from new block events
 	Unsubscribe() error
+
+	// Time a head was last received from any source
+	LastHeadTime() time.Time
+
+	// Statistics of each head source
+	GetSourceStats() []HeadSourceStats
 }
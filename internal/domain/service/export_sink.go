@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"time"
+)
+
+// ExportPartition identifies the files of one network and UTC day
+type ExportPartition struct {
+	Network string
+	Date    time.Time
+}
+
+// ExportBlock is a stored block with the transactions it contains
+type ExportBlock struct {
+	Block        *entity.Block
+	Transactions []*entity.Transaction
+}
+
+// ExportSink writes exported blocks and transactions into partitioned files
+// and keeps the export manifest next to them
+type ExportSink interface {
+	// Format returns the file format, e.g. parquet, csv or jsonl
+	Format() string
+
+	// WritePartition writes consecutive blocks of one partition and returns
+	// the written files, relative to the export root. Writing the same blocks
+	// again replaces their files.
+	WritePartition(ctx context.Context, partition ExportPartition, blocks []*ExportBlock) ([]string, error)
+
+	// LoadManifest returns the manifest of a network, nil when there is none
+	LoadManifest(ctx context.Context, network string) (*entity.ExportManifest, error)
+	SaveManifest(ctx context.Context, manifest *entity.ExportManifest) error
+}
@@ -22,6 +22,19 @@ type MessagingService interface {
 	// PublishTransactions publishes multiple transaction events
 	PublishTransactions(ctx context.Context, transactions []*entity.Transaction) error
 	
+	// PublishTokenTransfers publishes token transfer events
+	PublishTokenTransfers(ctx context.Context, transfers []*entity.TokenTransfer) error
+	
 	// GetStreamInfo returns information about the message stream (if applicable)
 	GetStreamInfo() (interface{}, error)
 }
+
+// RequestHandler handles a request message and returns the reply payload
+type RequestHandler func(ctx context.Context, data []byte) []byte
+
+// RequestReplyService serves requests sent to a subject of the messaging system
+type RequestReplyService interface {
+	// HandleRequests replies to requests on subject until stop is called.
+	// Instances handling the same subject share the requests.
+	HandleRequests(subject string, handler RequestHandler) (stop func() error, err error)
+}
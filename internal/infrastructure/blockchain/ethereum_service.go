@@ -8,34 +8,37 @@ import (
 	"ethereum-raw-data-crawler/internal/domain/service"
 	"ethereum-raw-data-crawler/internal/infrastructure/config"
 	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"ethereum-raw-data-crawler/internal/infrastructure/throttle"
 	"fmt"
 	"math/big"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
 	"go.uber.org/zap"
 )
 
 // EthereumService implements BlockchainService for Ethereum
 type EthereumService struct {
-	client          *ethclient.Client
-	config          *config.EthereumConfig
-	logger          *logger.Logger
-	isConnected     bool
-	lastRequestTime time.Time
-	minRequestDelay time.Duration
+	client      *ethclient.Client
+	config      *config.EthereumConfig
+	logger      *logger.Logger
+	isConnected bool
+	throttle    *throttle.Controller // Paces requests and observes their outcome
 }
 
 // NewEthereumService creates new Ethereum service
-func NewEthereumService(cfg *config.EthereumConfig, logger *logger.Logger) service.BlockchainService {
+func NewEthereumService(cfg *config.EthereumConfig, throttle *throttle.Controller, logger *logger.Logger) service.BlockchainService {
 	return &EthereumService{
-		config:          cfg,
-		logger:          logger.WithComponent("ethereum-service"),
-		minRequestDelay: cfg.RateLimit, // Use configurable rate limit
+		config:   cfg,
+		logger:   logger.WithComponent("ethereum-service"),
+		throttle: throttle,
 	}
 }
 
@@ -129,7 +132,11 @@ func (s *EthereumService) GetLatestBlockNumber(ctx context.Context) (*big.Int, e
 		return nil, ErrNotConnected
 	}
 
-	blockNumber, err := s.client.BlockNumber(ctx)
+	var blockNumber uint64
+	err := s.call(ctx, func() (err error) {
+		blockNumber, err = s.client.BlockNumber(ctx)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("Failed to get latest block number", zap.Error(err))
 		return nil, err
@@ -153,9 +160,10 @@ func (s *EthereumService) GetBlockByNumber(ctx context.Context, blockNumber *big
 
 	// Retry with exponential backoff for rate limiting
 	for attempt := 1; attempt <= 3; attempt++ {
-		s.rateLimit() // Apply rate limiting
-
-		block, err = s.client.BlockByNumber(ctx, blockNumber)
+		err = s.call(ctx, func() (err error) {
+			block, err = s.client.BlockByNumber(ctx, blockNumber)
+			return err
+		})
 		if err == nil {
 			break
 		}
@@ -169,7 +177,7 @@ func (s *EthereumService) GetBlockByNumber(ctx context.Context, blockNumber *big
 		}
 
 		// Handle rate limiting
-		if err = s.handleRateLimitError(err, attempt); err != nil && attempt < 3 {
+		if err = s.handleRateLimitError(ctx, err, attempt); err != nil && attempt < 3 {
 			continue
 		}
 
@@ -191,7 +199,11 @@ func (s *EthereumService) GetBlockByHash(ctx context.Context, blockHash string)
 	}
 
 	hash := common.HexToHash(blockHash)
-	block, err := s.client.BlockByHash(ctx, hash)
+	var block *types.Block
+	err := s.call(ctx, func() (err error) {
+		block, err = s.client.BlockByHash(ctx, hash)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("Failed to get block by hash",
 			zap.String("block_hash", blockHash),
@@ -209,7 +221,14 @@ func (s *EthereumService) GetTransactionByHash(ctx context.Context, txHash strin
 	}
 
 	hash := common.HexToHash(txHash)
-	tx, isPending, err := s.client.TransactionByHash(ctx, hash)
+	var (
+		tx        *types.Transaction
+		isPending bool
+	)
+	err := s.call(ctx, func() (err error) {
+		tx, isPending, err = s.client.TransactionByHash(ctx, hash)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("Failed to get transaction by hash",
 			zap.String("tx_hash", txHash),
@@ -232,7 +251,11 @@ func (s *EthereumService) GetTransactionReceipt(ctx context.Context, txHash stri
 	}
 
 	hash := common.HexToHash(txHash)
-	receipt, err := s.client.TransactionReceipt(ctx, hash)
+	var receipt *types.Receipt
+	err := s.call(ctx, func() (err error) {
+		receipt, err = s.client.TransactionReceipt(ctx, hash)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("Failed to get transaction receipt",
 			zap.String("tx_hash", txHash),
@@ -241,7 +264,11 @@ func (s *EthereumService) GetTransactionReceipt(ctx context.Context, txHash stri
 	}
 
 	// Get the transaction details as well
-	tx, _, err := s.client.TransactionByHash(ctx, hash)
+	var tx *types.Transaction
+	err = s.call(ctx, func() (err error) {
+		tx, _, err = s.client.TransactionByHash(ctx, hash)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("Failed to get transaction details for receipt",
 			zap.String("tx_hash", txHash),
@@ -260,7 +287,11 @@ func (s *EthereumService) GetTransactionsByBlock(ctx context.Context, blockNumbe
 		}
 	}
 
-	block, err := s.client.BlockByNumber(ctx, blockNumber)
+	var block *types.Block
+	err := s.call(ctx, func() (err error) {
+		block, err = s.client.BlockByNumber(ctx, blockNumber)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -271,15 +302,13 @@ func (s *EthereumService) GetTransactionsByBlock(ctx context.Context, blockNumbe
 		zap.String("block_number", blockNumber.String()),
 		zap.Int("tx_count", len(block.Transactions())))
 
+	missingReceipts := 0
 	for i, tx := range block.Transactions() {
 		var receipt *types.Receipt
 		var err error
 
 		// Skip receipt fetching if configured to do so (for faster testing)
 		if !s.config.SkipReceipts {
-			// Apply rate limiting before each transaction receipt call
-			s.rateLimit()
-
 			// Create context with configurable timeout for individual transaction
 			txCtx, cancel := context.WithTimeout(ctx, s.config.RequestTimeout)
 
@@ -296,7 +325,13 @@ func (s *EthereumService) GetTransactionsByBlock(ctx context.Context, blockNumbe
 			}
 		}
 
-		transactions = append(transactions, s.convertTransaction(tx, receipt, block, uint(i)))
+		transaction := s.convertTransaction(tx, receipt, block, uint(i))
+		if err != nil {
+			// Don't guess the outcome, the receipt repair worker fills it in later
+			markReceiptMissing(transaction)
+			missingReceipts++
+		}
+		transactions = append(transactions, transaction)
 
 		// Log progress for blocks with transactions
 		if len(block.Transactions()) > 10 && (i+1)%10 == 0 {
@@ -309,11 +344,23 @@ func (s *EthereumService) GetTransactionsByBlock(ctx context.Context, blockNumbe
 
 	s.logger.Info("Completed processing transactions in block",
 		zap.String("block_number", blockNumber.String()),
-		zap.Int("successful", len(transactions)))
+		zap.Int("successful", len(transactions)-missingReceipts),
+		zap.Int("missing_receipts", missingReceipts))
 
 	return transactions, nil
 }
 
+// markReceiptMissing clears the receipt fields of a transaction whose receipt
+// could not be fetched
+func markReceiptMissing(tx *entity.Transaction) {
+	tx.ReceiptMissing = true
+	tx.Status = 0
+	tx.GasUsed = 0
+	tx.CumulativeGasUsed = 0
+	tx.ContractAddress = nil
+	tx.TxStatus = entity.TransactionStatusPending
+}
+
 // sanitizeData converts raw bytes to a safe UTF-8 string for MongoDB storage
 func (s *EthereumService) sanitizeData(data []byte) string {
 	if len(data) == 0 {
@@ -332,7 +379,10 @@ func (s *EthereumService) getTransactionReceiptWithRetry(ctx context.Context, tx
 	// Retry with exponential backoff for rate limiting and timeouts
 	maxRetries := 5 // Increased retries for better reliability
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		receipt, err = s.client.TransactionReceipt(ctx, txHash)
+		err = s.call(ctx, func() (err error) {
+			receipt, err = s.client.TransactionReceipt(ctx, txHash)
+			return err
+		})
 		if err == nil {
 			return receipt, nil
 		}
@@ -344,10 +394,7 @@ func (s *EthereumService) getTransactionReceiptWithRetry(ctx context.Context, tx
 
 		// Handle rate limiting and timeouts with backoff
 		if attempt < maxRetries {
-			s.handleRateLimitError(err, attempt)
-			// Additional progressive delay for retries
-			additionalDelay := time.Duration(attempt) * 500 * time.Millisecond
-			time.Sleep(additionalDelay)
+			s.handleRateLimitError(ctx, err, attempt)
 			continue
 		}
 
@@ -358,6 +405,96 @@ func (s *EthereumService) getTransactionReceiptWithRetry(ctx context.Context, tx
 	return receipt, err
 }
 
+// GetCode gets the code of an address at a block, empty for an externally
+// owned account. A nil block number reads the latest block.
+func (s *EthereumService) GetCode(ctx context.Context, address string, blockNumber *big.Int) ([]byte, error) {
+	if !s.IsConnected() {
+		if err := s.reconnect(ctx); err != nil {
+			return nil, ErrNotConnected
+		}
+	}
+
+	var code []byte
+	err := s.call(ctx, func() (err error) {
+		code, err = s.client.CodeAt(ctx, common.HexToAddress(address), blockNumber)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to get code",
+			zap.String("address", address),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return code, nil
+}
+
+// CallContract executes an eth_call against a contract. Calls failing in
+// the EVM return service.ErrExecutionReverted.
+func (s *EthereumService) CallContract(ctx context.Context, address string, data []byte, blockNumber *big.Int) ([]byte, error) {
+	if !s.IsConnected() {
+		if err := s.reconnect(ctx); err != nil {
+			return nil, ErrNotConnected
+		}
+	}
+
+	to := common.HexToAddress(address)
+	var (
+		result   []byte
+		callErr  error
+		reverted bool
+	)
+	err := s.call(ctx, func() error {
+		result, callErr = s.client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: data}, blockNumber)
+		// A revert is an answer of the node, not a failure
+		reverted = isExecutionError(callErr)
+		if reverted {
+			return nil
+		}
+		return callErr
+	})
+	if reverted {
+		return nil, fmt.Errorf("%w: %v", service.ErrExecutionReverted, callErr)
+	}
+	if err != nil {
+		s.logger.Error("Failed to call contract",
+			zap.String("address", address),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// executionErrors are the messages of calls failing in the EVM
+var executionErrors = []string{
+	"execution reverted",
+	"invalid opcode",
+	"invalid jump destination",
+	"out of gas",
+	"stack underflow",
+}
+
+// isExecutionError tells whether a call failed in the EVM rather than at the node
+func isExecutionError(err error) bool {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	// Reverts with revert data
+	if rpcErr.ErrorCode() == 3 {
+		return true
+	}
+
+	message := strings.ToLower(rpcErr.Error())
+	for _, executionError := range executionErrors {
+		if strings.Contains(message, executionError) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetBlocksInRange gets blocks in range
 func (s *EthereumService) GetBlocksInRange(ctx context.Context, startBlock, endBlock *big.Int) ([]*entity.Block, error) {
 	if !s.IsConnected() {
@@ -377,6 +514,82 @@ func (s *EthereumService) GetBlocksInRange(ctx context.Context, startBlock, endB
 	return blocks, nil
 }
 
+// GetBlockProof gets a block with all of its receipts and rebuilds the
+// transactions and receipts tries from them
+func (s *EthereumService) GetBlockProof(ctx context.Context, blockNumber *big.Int) (*service.BlockProof, error) {
+	block, receipts, err := s.getBlockWithReceipts(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.blockProof(block, receipts), nil
+}
+
+// blockProof rebuilds the transactions and receipts tries of a block
+func (s *EthereumService) blockProof(block *types.Block, receipts types.Receipts) *service.BlockProof {
+	proof := &service.BlockProof{
+		Hash:             block.Hash().Hex(),
+		TransactionsRoot: types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)).Hex(),
+		ReceiptsRoot:     types.DeriveSha(receipts, trie.NewStackTrie(nil)).Hex(),
+		Transactions:     make([]*entity.Transaction, len(block.Transactions())),
+	}
+
+	for i, tx := range block.Transactions() {
+		proof.Transactions[i] = s.convertTransaction(tx, receipts[i], block, uint(i))
+	}
+
+	return proof
+}
+
+// GetRawBlock gets the canonical encoding of a block and its receipts
+func (s *EthereumService) GetRawBlock(ctx context.Context, blockNumber *big.Int) (*entity.RawBlock, error) {
+	block, receipts, err := s.getBlockWithReceipts(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return EncodeRawBlock(s.config.Network, block, receipts)
+}
+
+// getBlockWithReceipts gets a block and all of its receipts, checking that
+// both match the block header
+func (s *EthereumService) getBlockWithReceipts(ctx context.Context, blockNumber *big.Int) (*types.Block, types.Receipts, error) {
+	if !s.IsConnected() {
+		if err := s.reconnect(ctx); err != nil {
+			return nil, nil, ErrNotConnected
+		}
+	}
+
+	var block *types.Block
+	err := s.call(ctx, func() (err error) {
+		block, err = s.client.BlockByNumber(ctx, blockNumber)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Receipts are requested by hash so they can't belong to a block reorged in meanwhile
+	var receipts []*types.Receipt
+	err = s.call(ctx, func() (err error) {
+		receipts, err = s.client.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(block.Hash(), false))
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get block receipts: %w", err)
+	}
+	if len(receipts) != len(block.Transactions()) {
+		return nil, nil, fmt.Errorf("node returned %d receipts for %d transactions", len(receipts), len(block.Transactions()))
+	}
+
+	// A node serving data that doesn't match its own header can't be used as a reference
+	if err := CheckBlockRoots(block, receipts); err != nil {
+		return nil, nil, err
+	}
+
+	return block, receipts, nil
+}
+
 // GetNetworkID gets network ID
 func (s *EthereumService) GetNetworkID(ctx context.Context) (*big.Int, error) {
 	if !s.IsConnected() {
@@ -464,13 +677,19 @@ func (s *EthereumService) convertTransaction(tx *types.Transaction, receipt *typ
 	}
 
 	var contractAddress *string
+	var tokenTransfers []*entity.TokenTransfer
 	var status uint64 = 1 // Default success
 	var gasUsed uint64
 	var cumulativeGasUsed uint64
 	var blockHash string
 	var blockNumber *big.Int
+	var blockTimestamp time.Time
 	var transactionIndex uint = txIndex
 
+	if block != nil {
+		blockTimestamp = time.Unix(int64(block.Time()), 0)
+	}
+
 	if receipt != nil {
 		if receipt.ContractAddress != (common.Address{}) {
 			addr := receipt.ContractAddress.Hex()
@@ -482,6 +701,7 @@ func (s *EthereumService) convertTransaction(tx *types.Transaction, receipt *typ
 		blockHash = receipt.BlockHash.Hex()
 		blockNumber = receipt.BlockNumber
 		transactionIndex = receipt.TransactionIndex
+		tokenTransfers = DecodeTokenTransfers(receipt)
 	} else if block != nil {
 		// Use block context when receipt is not available
 		blockHash = block.Hash().Hex()
@@ -555,20 +775,22 @@ func (s *EthereumService) convertTransaction(tx *types.Transaction, receipt *typ
 		Hash:                 tx.Hash().Hex(),
 		BlockHash:            blockHash,
 		BlockNumber:          blockNumberStr,
+		BlockTimestamp:       blockTimestamp,
 		TransactionIndex:     transactionIndex,
 		From:                 fromAddr,
 		To:                   to,
-		Value:                tx.Value().String(),
+		Value:                entity.Wei(tx.Value().String()),
 		Gas:                  tx.Gas(),
-		GasPrice:             tx.GasPrice().String(),
+		GasPrice:             entity.Wei(tx.GasPrice().String()),
 		GasUsed:              gasUsed,
 		CumulativeGasUsed:    cumulativeGasUsed,
 		Data:                 s.sanitizeData(tx.Data()),
 		Nonce:                tx.Nonce(),
 		Status:               status,
-		MaxFeePerGas:         tx.GasFeeCap().String(),
-		MaxPriorityFeePerGas: tx.GasTipCap().String(),
+		MaxFeePerGas:         entity.Wei(tx.GasFeeCap().String()),
+		MaxPriorityFeePerGas: entity.Wei(tx.GasTipCap().String()),
 		ContractAddress:      contractAddress,
+		TokenTransfers:       tokenTransfers,
 		CrawledAt:            time.Now(),
 		Network:              s.config.Network,
 		TxStatus:             txStatus,
@@ -581,44 +803,51 @@ var (
 	ErrTransactionPending = errors.New("transaction is pending")
 )
 
-// rateLimit ensures minimum delay between requests
-func (s *EthereumService) rateLimit() {
-	elapsed := time.Since(s.lastRequestTime)
-	if elapsed < s.minRequestDelay {
-		time.Sleep(s.minRequestDelay - elapsed)
+// call paces an RPC request and reports its latency and outcome to the throttle
+func (s *EthereumService) call(ctx context.Context, request func() error) error {
+	if err := s.throttle.Wait(ctx); err != nil {
+		return err
 	}
-	s.lastRequestTime = time.Now()
+
+	start := time.Now()
+	err := request()
+	s.throttle.Observe(time.Since(start), err)
+	return err
 }
 
-// handleRateLimitError checks if error is rate limit or timeout and implements exponential backoff
-func (s *EthereumService) handleRateLimitError(err error, attempt int) error {
+// handleRateLimitError backs off after a rate limit or timeout error before
+// the request is retried. The adaptive throttle already slows requests down
+// after such errors, so the backoff only applies while it is disabled, and
+// ends early when the context is done.
+func (s *EthereumService) handleRateLimitError(ctx context.Context, err error, attempt int) error {
 	if err == nil {
 		return nil
 	}
 
+	var backoffDuration time.Duration
 	errStr := err.Error()
-
-	// Check if it's a rate limit error
-	if strings.Contains(errStr, "429") || strings.Contains(errStr, "Too Many Requests") {
-		backoffDuration := time.Duration(attempt*attempt) * time.Second // Exponential backoff
-		s.logger.Warn("Rate limit hit, backing off",
-			zap.Int("attempt", attempt),
-			zap.Duration("backoff", backoffDuration),
-			zap.Error(err))
-		time.Sleep(backoffDuration)
+	switch {
+	case throttle.IsRateLimitError(err):
+		backoffDuration = time.Duration(attempt*attempt) * time.Second // Exponential backoff
+	case strings.Contains(errStr, "context deadline exceeded") || strings.Contains(errStr, "timeout"):
+		backoffDuration = time.Duration(attempt) * 2 * time.Second // Linear backoff for timeouts
+	default:
 		return err
 	}
-
-	// Check if it's a timeout error
-	if strings.Contains(errStr, "context deadline exceeded") || strings.Contains(errStr, "timeout") {
-		backoffDuration := time.Duration(attempt) * 2 * time.Second // Linear backoff for timeouts
-		s.logger.Warn("Request timeout, backing off",
-			zap.Int("attempt", attempt),
-			zap.Duration("backoff", backoffDuration),
-			zap.Error(err))
-		time.Sleep(backoffDuration)
+	if s.throttle.GetStats().Enabled {
 		return err
 	}
 
+	s.logger.Warn("Request rate limited or timed out, backing off",
+		zap.Int("attempt", attempt),
+		zap.Duration("backoff", backoffDuration),
+		zap.Error(err))
+
+	timer := time.NewTimer(backoffDuration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
 	return err
 }
@@ -0,0 +1,42 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"ethereum-raw-data-crawler/internal/infrastructure/throttle"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEthereumService(t *testing.T, adaptive bool) *EthereumService {
+	cfg := &config.Config{
+		App:     config.AppConfig{LogLevel: "error"},
+		Crawler: config.CrawlerConfig{AdaptiveConcurrency: adaptive, ConcurrentWorkers: 1},
+	}
+	log, err := logger.NewLogger(cfg)
+	require.NoError(t, err)
+	return NewEthereumService(&cfg.Ethereum, throttle.NewController(cfg, log), log).(*EthereumService)
+}
+
+func TestEthereumService_HandleRateLimitError(t *testing.T) {
+	rateLimited := errors.New("429 Too Many Requests")
+
+	// The adaptive throttle paces the retry, no backoff on top of it
+	service := newTestEthereumService(t, true)
+	start := time.Now()
+	assert.Equal(t, rateLimited, service.handleRateLimitError(context.Background(), rateLimited, 3))
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Without it the backoff ends with the context
+	service = newTestEthereumService(t, false)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	assert.Equal(t, rateLimited, service.handleRateLimitError(ctx, rateLimited, 3))
+	assert.Less(t, time.Since(start), time.Second)
+}
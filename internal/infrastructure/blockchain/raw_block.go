@@ -0,0 +1,112 @@
+package blockchain
+
+import (
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// EncodeRawBlock encodes a block and its receipts for the archive. Receipts
+// keep only their consensus fields, the rest is derived again on decode.
+func EncodeRawBlock(network string, block *types.Block, receipts types.Receipts) (*entity.RawBlock, error) {
+	data, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode block: %w", err)
+	}
+
+	raw := &entity.RawBlock{
+		Network:    network,
+		Number:     block.NumberU64(),
+		Hash:       block.Hash().Hex(),
+		Block:      data,
+		Receipts:   make([][]byte, len(receipts)),
+		ArchivedAt: time.Now(),
+	}
+	for i, receipt := range receipts {
+		if raw.Receipts[i], err = receipt.MarshalBinary(); err != nil {
+			return nil, fmt.Errorf("failed to encode receipt %d: %w", i, err)
+		}
+	}
+
+	return raw, nil
+}
+
+// DecodeRawBlock rehydrates an archived block and its receipts, deriving the
+// receipt fields that aren't part of the consensus encoding
+func DecodeRawBlock(raw *entity.RawBlock, chainConfig *params.ChainConfig) (*types.Block, types.Receipts, error) {
+	block, receipts, err := decodeRawBlock(raw, chainConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := CheckBlockRoots(block, receipts); err != nil {
+		return nil, nil, err
+	}
+
+	return block, receipts, nil
+}
+
+// decodeRawBlock rehydrates an archived block and its receipts without
+// checking them against the header
+func decodeRawBlock(raw *entity.RawBlock, chainConfig *params.ChainConfig) (*types.Block, types.Receipts, error) {
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(raw.Block, block); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode block %d: %w", raw.Number, err)
+	}
+	if !strings.EqualFold(block.Hash().Hex(), raw.Hash) {
+		return nil, nil, fmt.Errorf("archived block %d decodes to hash %s, archived as %s", raw.Number, block.Hash().Hex(), raw.Hash)
+	}
+
+	receipts := make(types.Receipts, len(raw.Receipts))
+	for i, data := range raw.Receipts {
+		receipts[i] = new(types.Receipt)
+		if err := receipts[i].UnmarshalBinary(data); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode receipt %d of block %d: %w", i, raw.Number, err)
+		}
+	}
+
+	var blobGasPrice *big.Int
+	if block.ExcessBlobGas() != nil && chainConfig.BlobScheduleConfig != nil && chainConfig.IsCancun(block.Number(), block.Time()) {
+		blobGasPrice = eip4844.CalcBlobFee(chainConfig, block.Header())
+	}
+	err := receipts.DeriveFields(chainConfig, block.Hash(), block.NumberU64(), block.Time(), block.BaseFee(), blobGasPrice, block.Transactions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive receipt fields of block %d: %w", raw.Number, err)
+	}
+
+	return block, receipts, nil
+}
+
+// CheckBlockRoots rebuilds the transactions and receipts tries and compares
+// them to the block header
+func CheckBlockRoots(block *types.Block, receipts types.Receipts) error {
+	if root := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); root != block.TxHash() {
+		return fmt.Errorf("block %d transactions root is %s, header has %s", block.NumberU64(), root.Hex(), block.TxHash().Hex())
+	}
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != block.ReceiptHash() {
+		return fmt.Errorf("block %d receipts root is %s, header has %s", block.NumberU64(), root.Hex(), block.ReceiptHash().Hex())
+	}
+	return nil
+}
+
+// ChainConfig returns the chain config of a network, mainnet for unknown networks
+func ChainConfig(network string) *params.ChainConfig {
+	switch strings.ToLower(network) {
+	case "sepolia":
+		return params.SepoliaChainConfig
+	case "holesky":
+		return params.HoleskyChainConfig
+	case "hoodi":
+		return params.HoodiChainConfig
+	default:
+		return params.MainnetChainConfig
+	}
+}
@@ -0,0 +1,205 @@
+package blockchain
+
+import (
+	"context"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/repository"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBlockWithReceipts builds a Cancun era mainnet block with a transfer, a
+// contract creation and an access list transaction, and their receipts
+func testBlockWithReceipts(t *testing.T) (*types.Block, types.Receipts) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := types.LatestSigner(params.MainnetChainConfig)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	txs := []*types.Transaction{
+		types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID: big.NewInt(1), Nonce: 0, GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(30e9),
+			Gas: 21000, To: &to, Value: big.NewInt(1e18),
+		}),
+		types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce: 1, GasPrice: big.NewInt(20e9), Gas: 100000, Data: []byte{0x60, 0x00},
+		}),
+		types.MustSignNewTx(key, signer, &types.AccessListTx{
+			ChainID: big.NewInt(1), Nonce: 2, GasPrice: big.NewInt(20e9), Gas: 50000, To: &to,
+			AccessList: types.AccessList{{Address: to, StorageKeys: []common.Hash{{0x01}}}},
+		}),
+	}
+
+	receipts := types.Receipts{
+		{Type: txs[0].Type(), Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000},
+		{Type: txs[1].Type(), Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 74000},
+		{Type: txs[2].Type(), Status: types.ReceiptStatusFailed, CumulativeGasUsed: 100000, Logs: []*types.Log{
+			{Address: to, Topics: []common.Hash{{0xdd}}, Data: []byte{0x01}},
+		}},
+	}
+	for _, receipt := range receipts {
+		receipt.Bloom = types.CreateBloom(receipt)
+	}
+
+	header := &types.Header{
+		Number:   big.NewInt(20_000_000),
+		Time:     1_720_000_000,
+		GasLimit: 30_000_000,
+		GasUsed:  100000,
+		BaseFee:  big.NewInt(10e9),
+	}
+	block := types.NewBlock(header, &types.Body{Transactions: txs}, receipts, trie.NewStackTrie(nil))
+	return block, receipts
+}
+
+func TestRawBlock_RoundTrip(t *testing.T) {
+	block, receipts := testBlockWithReceipts(t)
+
+	raw, err := EncodeRawBlock("ethereum", block, receipts)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(20_000_000), raw.Number)
+	assert.Equal(t, block.Hash().Hex(), raw.Hash)
+
+	decoded, decodedReceipts, err := DecodeRawBlock(raw, ChainConfig("ethereum"))
+	require.NoError(t, err)
+	assert.Equal(t, block.Hash(), decoded.Hash())
+	require.Len(t, decoded.Transactions(), 3)
+
+	// Signatures and access lists survive, unlike in entity.Transaction
+	for i, tx := range decoded.Transactions() {
+		assert.Equal(t, block.Transactions()[i].Hash(), tx.Hash())
+		v, r, s := tx.RawSignatureValues()
+		wantV, wantR, wantS := block.Transactions()[i].RawSignatureValues()
+		assert.Equal(t, wantV, v)
+		assert.Equal(t, wantR, r)
+		assert.Equal(t, wantS, s)
+	}
+	assert.Equal(t, block.Transactions()[2].AccessList(), decoded.Transactions()[2].AccessList())
+
+	// Receipt fields outside the consensus encoding are derived again
+	require.Len(t, decodedReceipts, 3)
+	assert.Equal(t, uint64(53000), decodedReceipts[1].GasUsed)
+	assert.Equal(t, block.Hash(), decodedReceipts[1].BlockHash)
+	assert.Equal(t, uint(1), decodedReceipts[1].TransactionIndex)
+	sender, err := types.Sender(types.LatestSigner(params.MainnetChainConfig), block.Transactions()[1])
+	require.NoError(t, err)
+	assert.Equal(t, crypto.CreateAddress(sender, 1), decodedReceipts[1].ContractAddress)
+	require.Len(t, decodedReceipts[2].Logs, 1)
+	assert.Equal(t, block.Transactions()[2].Hash(), decodedReceipts[2].Logs[0].TxHash)
+	assert.Equal(t, uint64(types.ReceiptStatusFailed), decodedReceipts[2].Status)
+}
+
+func TestRawBlock_Tampered(t *testing.T) {
+	block, receipts := testBlockWithReceipts(t)
+	raw, err := EncodeRawBlock("ethereum", block, receipts)
+	require.NoError(t, err)
+
+	// A receipt that doesn't match the header's receipts root
+	tampered := *receipts[0]
+	tampered.CumulativeGasUsed = 1
+	raw.Receipts[0], err = tampered.MarshalBinary()
+	require.NoError(t, err)
+
+	_, _, err = DecodeRawBlock(raw, ChainConfig("ethereum"))
+	assert.ErrorContains(t, err, "receipts root")
+
+	raw.Hash = "0x1234"
+	_, _, err = DecodeRawBlock(raw, ChainConfig("ethereum"))
+	assert.ErrorContains(t, err, "decodes to hash")
+}
+
+// memoryBlockArchive keeps raw blocks by hash
+type memoryBlockArchive struct {
+	repository.BlockArchiveRepository
+	blocks map[string]*entity.RawBlock
+}
+
+func (a *memoryBlockArchive) GetRawBlockByNumber(ctx context.Context, network string, blockNumber uint64) (*entity.RawBlock, error) {
+	for _, raw := range a.blocks {
+		if raw.Network == network && raw.Number == blockNumber {
+			return raw, nil
+		}
+	}
+	return nil, nil
+}
+
+func (a *memoryBlockArchive) GetRawBlockByHash(ctx context.Context, network, blockHash string) (*entity.RawBlock, error) {
+	return a.blocks[blockHash], nil
+}
+
+func TestArchiveReader_GetArchivedBlock(t *testing.T) {
+	block, receipts := testBlockWithReceipts(t)
+	raw, err := EncodeRawBlock("ethereum", block, receipts)
+	require.NoError(t, err)
+
+	log, err := logger.NewLogger(&config.Config{App: config.AppConfig{LogLevel: "error"}})
+	require.NoError(t, err)
+	reader := NewArchiveReader(&memoryBlockArchive{blocks: map[string]*entity.RawBlock{raw.Hash: raw}},
+		&config.EthereumConfig{Network: "ethereum"}, log)
+
+	storedBlock, transactions, err := reader.GetArchivedBlock(context.Background(), 20_000_000)
+	require.NoError(t, err)
+	require.NotNil(t, storedBlock)
+	assert.Equal(t, block.Hash().Hex(), storedBlock.Hash)
+	assert.Equal(t, block.TxHash().Hex(), storedBlock.TransactionsRoot)
+	assert.Equal(t, block.ReceiptHash().Hex(), storedBlock.ReceiptsRoot)
+	assert.Len(t, storedBlock.TransactionHashes, 3)
+
+	require.Len(t, transactions, 3)
+	assert.Equal(t, entity.Wei("1000000000000000000"), transactions[0].Value)
+	assert.Equal(t, uint64(21000), transactions[0].GasUsed)
+	assert.NotEmpty(t, transactions[0].From)
+	require.NotNil(t, transactions[1].ContractAddress)
+	assert.Equal(t, uint64(0), transactions[2].Status)
+	assert.Equal(t, entity.TransactionStatusFailed, transactions[2].TxStatus)
+	assert.Equal(t, storedBlock.Hash, transactions[2].BlockHash)
+
+	// Not archived
+	storedBlock, transactions, err = reader.GetArchivedBlock(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Nil(t, storedBlock)
+	assert.Nil(t, transactions)
+}
+
+func TestArchiveReader_GetArchivedBlockProof(t *testing.T) {
+	block, receipts := testBlockWithReceipts(t)
+	raw, err := EncodeRawBlock("ethereum", block, receipts)
+	require.NoError(t, err)
+
+	log, err := logger.NewLogger(&config.Config{App: config.AppConfig{LogLevel: "error"}})
+	require.NoError(t, err)
+	archive := &memoryBlockArchive{blocks: map[string]*entity.RawBlock{raw.Hash: raw}}
+	reader := NewArchiveReader(archive, &config.EthereumConfig{Network: "ethereum"}, log)
+
+	proof, err := reader.GetArchivedBlockProof(context.Background(), raw.Hash)
+	require.NoError(t, err)
+	require.NotNil(t, proof)
+	assert.Equal(t, block.TxHash().Hex(), proof.TransactionsRoot)
+	assert.Equal(t, block.ReceiptHash().Hex(), proof.ReceiptsRoot)
+	require.Len(t, proof.Transactions, 3)
+	assert.Equal(t, uint64(21000), proof.Transactions[0].GasUsed)
+
+	// A tampered receipt is rebuilt into another root instead of failing
+	tampered := *receipts[0]
+	tampered.CumulativeGasUsed = 1
+	raw.Receipts[0], err = tampered.MarshalBinary()
+	require.NoError(t, err)
+	proof, err = reader.GetArchivedBlockProof(context.Background(), raw.Hash)
+	require.NoError(t, err)
+	assert.NotEqual(t, block.ReceiptHash().Hex(), proof.ReceiptsRoot)
+
+	// Not archived
+	proof, err = reader.GetArchivedBlockProof(context.Background(), "0x1234")
+	require.NoError(t, err)
+	assert.Nil(t, proof)
+}
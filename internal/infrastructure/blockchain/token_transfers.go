@@ -0,0 +1,168 @@
+package blockchain
+
+import (
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Event signatures of the token transfer logs
+var (
+	// ERC-20 indexes from and to, ERC-721 the token ID as well
+	transferTopic       = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	transferBatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// transferBatchArguments are the ids and values of a TransferBatch log
+var transferBatchArguments = func() abi.Arguments {
+	uint256s, err := abi.NewType("uint256[]", "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return abi.Arguments{{Name: "ids", Type: uint256s}, {Name: "values", Type: uint256s}}
+}()
+
+// DecodeTokenTransfers decodes the token transfers in the logs of a receipt.
+// Logs that don't follow the standard layouts, like a Transfer without
+// indexed fields, are skipped.
+func DecodeTokenTransfers(receipt *types.Receipt) []*entity.TokenTransfer {
+	var transfers []*entity.TokenTransfer
+	for _, log := range receipt.Logs {
+		if log.Removed || len(log.Topics) == 0 {
+			continue
+		}
+
+		switch log.Topics[0] {
+		case transferTopic:
+			transfers = appendTransfer(transfers, decodeTransfer(log))
+		case transferSingleTopic:
+			transfers = appendTransfer(transfers, decodeTransferSingle(log))
+		case transferBatchTopic:
+			transfers = append(transfers, decodeTransferBatch(log)...)
+		}
+	}
+	return transfers
+}
+
+// appendTransfer appends a transfer unless the log wasn't decoded
+func appendTransfer(transfers []*entity.TokenTransfer, transfer *entity.TokenTransfer) []*entity.TokenTransfer {
+	if transfer == nil {
+		return transfers
+	}
+	return append(transfers, transfer)
+}
+
+// decodeTransfer decodes an ERC-20 Transfer, with the amount in the data, or
+// an ERC-721 Transfer, with the token ID as the last topic
+func decodeTransfer(log *types.Log) *entity.TokenTransfer {
+	switch {
+	case len(log.Topics) == 3 && len(log.Data) == 32:
+		addresses, ok := topicAddresses(log.Topics[1], log.Topics[2])
+		if !ok {
+			return nil
+		}
+		transfer := newTokenTransfer(log, entity.TokenStandardERC20, addresses[0], addresses[1])
+		transfer.Amount = wordToDecimal(log.Data)
+		return transfer
+	case len(log.Topics) == 4 && len(log.Data) == 0:
+		addresses, ok := topicAddresses(log.Topics[1], log.Topics[2])
+		if !ok {
+			return nil
+		}
+		transfer := newTokenTransfer(log, entity.TokenStandardERC721, addresses[0], addresses[1])
+		transfer.Amount = "1"
+		transfer.TokenID = wordToDecimal(log.Topics[3].Bytes())
+		return transfer
+	default:
+		return nil
+	}
+}
+
+// decodeTransferSingle decodes an ERC-1155 TransferSingle, with the token ID
+// and amount in the data
+func decodeTransferSingle(log *types.Log) *entity.TokenTransfer {
+	if len(log.Topics) != 4 || len(log.Data) != 64 {
+		return nil
+	}
+	// Operator, from and to
+	addresses, ok := topicAddresses(log.Topics[1:]...)
+	if !ok {
+		return nil
+	}
+
+	transfer := newTokenTransfer(log, entity.TokenStandardERC1155, addresses[1], addresses[2])
+	transfer.Operator = addresses[0]
+	transfer.TokenID = wordToDecimal(log.Data[:32])
+	transfer.Amount = wordToDecimal(log.Data[32:])
+	return transfer
+}
+
+// decodeTransferBatch decodes an ERC-1155 TransferBatch into one transfer per token
+func decodeTransferBatch(log *types.Log) []*entity.TokenTransfer {
+	if len(log.Topics) != 4 {
+		return nil
+	}
+	addresses, ok := topicAddresses(log.Topics[1:]...)
+	if !ok {
+		return nil
+	}
+
+	values, err := transferBatchArguments.Unpack(log.Data)
+	if err != nil {
+		return nil
+	}
+	ids, idsOk := values[0].([]*big.Int)
+	amounts, amountsOk := values[1].([]*big.Int)
+	if !idsOk || !amountsOk || len(ids) != len(amounts) {
+		return nil
+	}
+
+	transfers := make([]*entity.TokenTransfer, len(ids))
+	for i := range ids {
+		transfer := newTokenTransfer(log, entity.TokenStandardERC1155, addresses[1], addresses[2])
+		transfer.Operator = addresses[0]
+		transfer.TokenID = ids[i].String()
+		transfer.Amount = amounts[i].String()
+		transfer.BatchIndex = uint(i)
+		transfers[i] = transfer
+	}
+	return transfers
+}
+
+// newTokenTransfer returns a transfer with the position of its log
+func newTokenTransfer(log *types.Log, standard entity.TokenStandard, from, to string) *entity.TokenTransfer {
+	return &entity.TokenTransfer{
+		Token:            log.Address.Hex(),
+		Standard:         standard,
+		From:             from,
+		To:               to,
+		TransactionHash:  log.TxHash.Hex(),
+		TransactionIndex: log.TxIndex,
+		LogIndex:         log.Index,
+		BlockNumber:      log.BlockNumber,
+		BlockHash:        log.BlockHash.Hex(),
+	}
+}
+
+// topicAddresses reads addresses from indexed topics, failing when a topic
+// has more than its last 20 bytes set
+func topicAddresses(topics ...common.Hash) ([]string, bool) {
+	addresses := make([]string, len(topics))
+	for i, topic := range topics {
+		if new(big.Int).SetBytes(topic.Bytes()).BitLen() > common.AddressLength*8 {
+			return nil, false
+		}
+		addresses[i] = common.BytesToAddress(topic.Bytes()).Hex()
+	}
+	return addresses, true
+}
+
+// wordToDecimal formats a 32-byte big-endian word as a decimal string
+func wordToDecimal(word []byte) string {
+	return new(big.Int).SetBytes(word).String()
+}
@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeTokenTransfers(t *testing.T) {
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	operator := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	from := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	to := common.HexToAddress("0x00000000000000000000000000000000000000dd")
+	txHash, blockHash := common.Hash{0x01}, common.Hash{0x02}
+
+	word := func(n int64) []byte { return common.BigToHash(big.NewInt(n)).Bytes() }
+	topic := func(address common.Address) common.Hash { return common.BytesToHash(address.Bytes()) }
+	huge := new(big.Int).Lsh(big.NewInt(1), 255)
+
+	batch, err := transferBatchArguments.Pack(
+		[]*big.Int{big.NewInt(7), big.NewInt(8)},
+		[]*big.Int{big.NewInt(70), huge},
+	)
+	require.NoError(t, err)
+
+	logs := []*types.Log{
+		// ERC-20
+		{Topics: []common.Hash{transferTopic, topic(from), topic(to)}, Data: word(1000)},
+		// ERC-721, a mint
+		{Topics: []common.Hash{transferTopic, {}, topic(to), common.BigToHash(big.NewInt(42))}},
+		{Topics: []common.Hash{transferSingleTopic, topic(operator), topic(from), topic(to)}, Data: append(word(5), word(3)...)},
+		{Topics: []common.Hash{transferBatchTopic, topic(operator), topic(from), topic(to)}, Data: batch},
+		// Not indexed, an address topic with more than 20 bytes, another event, removed
+		{Topics: []common.Hash{transferTopic}, Data: append(append(word(1), word(2)...), word(3)...)},
+		{Topics: []common.Hash{transferTopic, {0xff}, topic(to)}, Data: word(1)},
+		{Topics: []common.Hash{{0xee}, topic(from), topic(to)}, Data: word(1)},
+		{Topics: []common.Hash{transferTopic, topic(from), topic(to)}, Data: word(1), Removed: true},
+	}
+	for i, log := range logs {
+		log.Address, log.TxHash, log.TxIndex, log.Index = token, txHash, 3, uint(10+i)
+		log.BlockNumber, log.BlockHash = 20_000_000, blockHash
+	}
+
+	transfers := DecodeTokenTransfers(&types.Receipt{Logs: logs})
+	require.Len(t, transfers, 5)
+
+	erc20 := transfers[0]
+	assert.Equal(t, entity.TokenStandardERC20, erc20.Standard)
+	assert.Equal(t, token.Hex(), erc20.Token)
+	assert.Equal(t, from.Hex(), erc20.From)
+	assert.Equal(t, to.Hex(), erc20.To)
+	assert.Equal(t, "1000", erc20.Amount)
+	assert.Empty(t, erc20.TokenID)
+	assert.Equal(t, txHash.Hex(), erc20.TransactionHash)
+	assert.Equal(t, uint(3), erc20.TransactionIndex)
+	assert.Equal(t, uint(10), erc20.LogIndex)
+	assert.Equal(t, uint64(20_000_000), erc20.BlockNumber)
+	assert.Equal(t, blockHash.Hex(), erc20.BlockHash)
+
+	erc721 := transfers[1]
+	assert.Equal(t, entity.TokenStandardERC721, erc721.Standard)
+	assert.Equal(t, common.Address{}.Hex(), erc721.From)
+	assert.Equal(t, "1", erc721.Amount)
+	assert.Equal(t, "42", erc721.TokenID)
+
+	single := transfers[2]
+	assert.Equal(t, entity.TokenStandardERC1155, single.Standard)
+	assert.Equal(t, operator.Hex(), single.Operator)
+	assert.Equal(t, from.Hex(), single.From)
+	assert.Equal(t, to.Hex(), single.To)
+	assert.Equal(t, "5", single.TokenID)
+	assert.Equal(t, "3", single.Amount)
+
+	for i, transfer := range transfers[3:] {
+		assert.Equal(t, entity.TokenStandardERC1155, transfer.Standard)
+		assert.Equal(t, uint(13), transfer.LogIndex)
+		assert.Equal(t, uint(i), transfer.BatchIndex)
+	}
+	assert.Equal(t, []string{"7", "70"}, []string{transfers[3].TokenID, transfers[3].Amount})
+	assert.Equal(t, []string{"8", huge.String()}, []string{transfers[4].TokenID, transfers[4].Amount})
+}
+
+func TestDecodeTokenTransfers_MalformedBatch(t *testing.T) {
+	topics := []common.Hash{transferBatchTopic, {}, {}, {}}
+	mismatched, err := transferBatchArguments.Pack([]*big.Int{big.NewInt(1)}, []*big.Int{})
+	require.NoError(t, err)
+
+	assert.Empty(t, DecodeTokenTransfers(&types.Receipt{Logs: []*types.Log{
+		{Topics: topics, Data: []byte{0x01}},
+		{Topics: topics, Data: mismatched},
+	}}))
+}
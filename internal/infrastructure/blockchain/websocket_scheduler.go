@@ -2,46 +2,111 @@ package blockchain
 
 import (
 	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/domain/entity"
 	"ethereum-raw-data-crawler/internal/domain/service"
 	"ethereum-raw-data-crawler/internal/infrastructure/config"
 	"ethereum-raw-data-crawler/internal/infrastructure/logger"
 	"fmt"
 	"math/big"
+	"net/url"
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
-// WebSocketScheduler implements BlockSchedulerService using WebSocket
+const (
+	// headQueueSize bounds the heads waiting for the callback. A full queue
+	// blocks the reader instead of dropping heads.
+	headQueueSize = 256
+
+	// recentHeadsWindow is how many heights below the latest head are
+	// remembered for deduplication and reorg detection
+	recentHeadsWindow = 128
+)
+
+// wsHeadSource is one WebSocket endpoint subscribed to newHeads. Connection
+// fields are guarded by WebSocketScheduler.mu, statistics by headMu.
+type wsHeadSource struct {
+	name            string
+	url             string
+	logger          *logger.Logger
+	conn            *websocket.Conn
+	subID           string
+	reconnectCh     chan struct{}
+	lastMessageTime time.Time
+
+	// Statistics
+	headsReceived int64
+	firstSeen     int64         // Heads this source announced before any other source
+	totalLatency  time.Duration // Block timestamp to arrival, summed over all heads
+	lagged        int64         // Heads another source announced first
+	totalLag      time.Duration // Delay behind the first source, summed over lagged heads
+	lastHeadTime  time.Time
+}
+
+// seenHead records the first announcement of a block hash
+type seenHead struct {
+	number uint64
+	at     time.Time
+	source string
+}
+
+// WebSocketScheduler implements BlockSchedulerService using one or more
+// WebSocket endpoints. Heads from all endpoints are merged and deduplicated by
+// block hash, so the fastest endpoint drives the crawler.
 type WebSocketScheduler struct {
 	config          *config.EthereumConfig
 	logger          *logger.Logger
-	conn            *websocket.Conn
+	sources         []*wsHeadSource
 	isRunning       bool
-	callback        func(*big.Int)
+	callback        func(*service.NewHeadEvent)
 	stopChan        chan struct{}
 	mu              sync.RWMutex
-	subID           string
-	reconnectCh     chan struct{}
-	lastMessageTime time.Time
 	schedulerCtx    context.Context    // Context riêng cho scheduler
 	schedulerCancel context.CancelFunc // Cancel function cho scheduler context
+
+	// Heads are queued by the readers and delivered in order by dispatchHeads
+	heads chan *service.NewHeadEvent
+
+	headMu       sync.Mutex
+	lastHead     *entity.BlockHeader
+	lastHeadTime time.Time
+	recentHashes map[uint64]string    // Height -> latest announced hash
+	seenHeads    map[string]*seenHead // Hash -> first announcement
 }
 
 // NewWebSocketScheduler creates a new WebSocket scheduler
 func NewWebSocketScheduler(cfg *config.EthereumConfig, logger *logger.Logger) service.BlockSchedulerService {
-	return &WebSocketScheduler{
-		config:          cfg,
-		logger:          logger.WithComponent("websocket-scheduler"),
-		stopChan:        make(chan struct{}),
-		reconnectCh:     make(chan struct{}, 1),
-		lastMessageTime: time.Now(),
+	w := &WebSocketScheduler{
+		config:       cfg,
+		logger:       logger.WithComponent("websocket-scheduler"),
+		stopChan:     make(chan struct{}),
+		heads:        make(chan *service.NewHeadEvent, headQueueSize),
+		lastHeadTime: time.Now(),
+		recentHashes: make(map[uint64]string),
+		seenHeads:    make(map[string]*seenHead),
+	}
+
+	for _, endpoint := range headSourceURLs(cfg) {
+		name := sourceName(endpoint, len(w.sources))
+		w.sources = append(w.sources, &wsHeadSource{
+			name:            name,
+			url:             endpoint,
+			logger:          w.logger.With(zap.String("source", name)),
+			reconnectCh:     make(chan struct{}, 1),
+			lastMessageTime: time.Now(),
+		})
 	}
+
+	return w
 }
 
-// Start starts the WebSocket scheduler
+// Start starts the WebSocket scheduler. It succeeds when at least one
+// endpoint connects, the others keep retrying in the background.
 func (w *WebSocketScheduler) Start(ctx context.Context) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -50,20 +115,47 @@ func (w *WebSocketScheduler) Start(ctx context.Context) error {
 		return fmt.Errorf("scheduler is already running")
 	}
 
-	w.logger.Info("Starting WebSocket scheduler", zap.String("ws_url", w.config.WSURL))
+	if len(w.sources) == 0 {
+		return fmt.Errorf("WebSocket URL is not configured")
+	}
+
+	w.logger.Info("Starting WebSocket scheduler", zap.Int("sources", len(w.sources)))
+
+	// Stop closes stopChan, tạo lại để scheduler có thể start lại
+	w.stopChan = make(chan struct{})
 
 	// Tạo context riêng cho scheduler, không phụ thuộc vào context từ bên ngoài
 	w.schedulerCtx, w.schedulerCancel = context.WithCancel(context.Background())
 
-	if err := w.connect(ctx); err != nil {
+	var errs []error
+	for _, src := range w.sources {
+		if err := w.connect(ctx, src); err != nil {
+			src.logger.Warn("Failed to connect head source", zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", src.name, err))
+		}
+	}
+	if len(errs) == len(w.sources) {
 		w.schedulerCancel() // Cancel context nếu connect thất bại
-		return fmt.Errorf("failed to connect to WebSocket: %w", err)
+		return fmt.Errorf("failed to connect to WebSocket: %w", errors.Join(errs...))
 	}
 
 	w.isRunning = true
 
-	// Start connection monitor với scheduler context
-	go w.connectionMonitor(w.schedulerCtx)
+	// Heads seen before a restart are stale, another leader may have crawled
+	// the blocks in between
+	w.resetHeads()
+
+	// Start connection monitors với scheduler context. Sources that failed to
+	// connect are retried by their monitor.
+	for _, src := range w.sources {
+		// Reconnect requests of a previous run are stale
+		select {
+		case <-src.reconnectCh:
+		default:
+		}
+		go w.connectionMonitor(w.schedulerCtx, src)
+	}
+	go w.dispatchHeads(w.schedulerCtx)
 
 	return nil
 }
@@ -87,13 +179,15 @@ func (w *WebSocketScheduler) Stop() error {
 	close(w.stopChan)
 	w.isRunning = false
 
-	if w.conn != nil {
-		// Send unsubscribe message if we have a subscription
-		if w.subID != "" {
-			w.unsubscribeFromBlocks()
+	for _, src := range w.sources {
+		if src.conn != nil {
+			// Send unsubscribe message if we have a subscription
+			if src.subID != "" {
+				w.unsubscribeFromBlocks(src)
+			}
+			src.conn.Close()
+			src.conn = nil
 		}
-		w.conn.Close()
-		w.conn = nil
 	}
 
 	return nil
@@ -106,8 +200,8 @@ func (w *WebSocketScheduler) IsRunning() bool {
 	return w.isRunning
 }
 
-// SubscribeNewBlocks subscribes to new block events
-func (w *WebSocketScheduler) SubscribeNewBlocks(ctx context.Context, callback func(*big.Int)) error {
+// SubscribeNewBlocks subscribes to new block events on every connected source
+func (w *WebSocketScheduler) SubscribeNewBlocks(ctx context.Context, callback func(*service.NewHeadEvent)) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -118,14 +212,33 @@ func (w *WebSocketScheduler) SubscribeNewBlocks(ctx context.Context, callback fu
 	w.callback = callback
 
 	// Subscribe to new heads
-	if err := w.subscribeToBlocks(); err != nil {
-		return fmt.Errorf("failed to subscribe to blocks: %w", err)
+	var errs []error
+	subscribed := 0
+	for _, src := range w.sources {
+		if src.conn == nil {
+			// Subscribed by handleReconnection once connected
+			continue
+		}
+		if err := w.subscribeToBlocks(src); err != nil {
+			src.logger.Warn("Failed to subscribe head source", zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", src.name, err))
+			w.triggerReconnect(src, "subscribe failure")
+			continue
+		}
+		subscribed++
+	}
+	if subscribed == 0 {
+		return fmt.Errorf("failed to subscribe to blocks: %w", errors.Join(errs...))
 	}
 
-	// Start message listener với scheduler context thay vì context từ tham số
-	go w.messageListener(w.schedulerCtx)
+	// Start message listeners với scheduler context thay vì context từ tham số
+	for _, src := range w.sources {
+		go w.messageListener(w.schedulerCtx, src)
+	}
 
-	w.logger.Info("Successfully subscribed to new block events")
+	w.logger.Info("Successfully subscribed to new block events",
+		zap.Int("subscribed_sources", subscribed),
+		zap.Int("sources", len(w.sources)))
 	return nil
 }
 
@@ -134,34 +247,91 @@ func (w *WebSocketScheduler) Unsubscribe() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	if w.subID != "" {
-		return w.unsubscribeFromBlocks()
+	var errs []error
+	for _, src := range w.sources {
+		if src.subID != "" {
+			if err := w.unsubscribeFromBlocks(src); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// LastHeadTime returns when any source last announced a head, including
+// heads another source announced first
+func (w *WebSocketScheduler) LastHeadTime() time.Time {
+	w.headMu.Lock()
+	defer w.headMu.Unlock()
+	return w.lastHeadTime
+}
+
+// GetSourceStats returns statistics for each WebSocket endpoint
+func (w *WebSocketScheduler) GetSourceStats() []service.HeadSourceStats {
+	w.mu.RLock()
+	connected := make([]bool, len(w.sources))
+	for i, src := range w.sources {
+		connected[i] = src.conn != nil && src.subID != ""
+	}
+	w.mu.RUnlock()
+
+	w.headMu.Lock()
+	defer w.headMu.Unlock()
+
+	stats := make([]service.HeadSourceStats, 0, len(w.sources))
+	for i, src := range w.sources {
+		sourceStats := service.HeadSourceStats{
+			Name:          src.name,
+			Connected:     connected[i],
+			HeadsReceived: src.headsReceived,
+			FirstSeen:     src.firstSeen,
+			LastHeadTime:  src.lastHeadTime,
+		}
+		if src.headsReceived > 0 {
+			sourceStats.AverageLatency = src.totalLatency / time.Duration(src.headsReceived)
+		}
+		if src.lagged > 0 {
+			sourceStats.AverageLag = src.totalLag / time.Duration(src.lagged)
+		}
+		stats = append(stats, sourceStats)
+	}
+
+	return stats
+}
+
+// connect establishes the WebSocket connection of a source. Must be called with mu held.
+func (w *WebSocketScheduler) connect(ctx context.Context, src *wsHeadSource) error {
+	conn, err := dial(ctx, src.url)
+	if err != nil {
+		return err
 	}
 
+	src.conn = conn
+	src.lastMessageTime = time.Now()
+	src.logger.Info("Successfully connected to WebSocket")
 	return nil
 }
 
-// connect establishes WebSocket connection
-func (w *WebSocketScheduler) connect(ctx context.Context) error {
-	if w.config.WSURL == "" {
-		return fmt.Errorf("WebSocket URL is not configured")
+// dial opens a WebSocket connection
+func dial(ctx context.Context, endpoint string) (*websocket.Conn, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("WebSocket URL is not configured")
 	}
 
-	dialer := websocket.DefaultDialer
+	dialer := *websocket.DefaultDialer
 	dialer.HandshakeTimeout = 30 * time.Second
 
-	conn, _, err := dialer.DialContext(ctx, w.config.WSURL, nil)
+	conn, _, err := dialer.DialContext(ctx, endpoint, nil)
 	if err != nil {
-		return fmt.Errorf("failed to dial WebSocket: %w", err)
+		return nil, fmt.Errorf("failed to dial WebSocket: %w", err)
 	}
 
-	w.conn = conn
-	w.logger.Info("Successfully connected to WebSocket")
-	return nil
+	return conn, nil
 }
 
-// subscribeToBlocks sends subscription request for new blocks
-func (w *WebSocketScheduler) subscribeToBlocks() error {
+// subscribeToBlocks sends subscription request for new blocks. Must be called with mu held.
+func (w *WebSocketScheduler) subscribeToBlocks(src *wsHeadSource) error {
 	subscribeMsg := map[string]interface{}{
 		"jsonrpc": "2.0",
 		"id":      1,
@@ -169,17 +339,17 @@ func (w *WebSocketScheduler) subscribeToBlocks() error {
 		"params":  []interface{}{"newHeads"},
 	}
 
-	if err := w.conn.WriteJSON(subscribeMsg); err != nil {
+	if err := src.conn.WriteJSON(subscribeMsg); err != nil {
 		return fmt.Errorf("failed to send subscribe message: %w", err)
 	}
 
-	w.logger.Debug("Sent subscription request for new blocks")
+	src.logger.Debug("Sent subscription request for new blocks")
 	return nil
 }
 
-// unsubscribeFromBlocks sends unsubscribe request
-func (w *WebSocketScheduler) unsubscribeFromBlocks() error {
-	if w.conn == nil || w.subID == "" {
+// unsubscribeFromBlocks sends unsubscribe request. Must be called with mu held.
+func (w *WebSocketScheduler) unsubscribeFromBlocks(src *wsHeadSource) error {
+	if src.conn == nil || src.subID == "" {
 		return nil
 	}
 
@@ -187,26 +357,36 @@ func (w *WebSocketScheduler) unsubscribeFromBlocks() error {
 		"jsonrpc": "2.0",
 		"id":      2,
 		"method":  "eth_unsubscribe",
-		"params":  []interface{}{w.subID},
+		"params":  []interface{}{src.subID},
 	}
 
-	if err := w.conn.WriteJSON(unsubscribeMsg); err != nil {
-		w.logger.Error("Failed to send unsubscribe message", zap.Error(err))
+	if err := src.conn.WriteJSON(unsubscribeMsg); err != nil {
+		src.logger.Error("Failed to send unsubscribe message", zap.Error(err))
 		return err
 	}
 
-	w.subID = ""
-	w.logger.Debug("Sent unsubscribe request")
+	src.subID = ""
+	src.logger.Debug("Sent unsubscribe request")
 	return nil
 }
 
-// messageListener listens for WebSocket messages
-func (w *WebSocketScheduler) messageListener(ctx context.Context) {
+// triggerReconnect asks the connection monitor of a source to reconnect
+func (w *WebSocketScheduler) triggerReconnect(src *wsHeadSource, reason string) {
+	select {
+	case src.reconnectCh <- struct{}{}:
+		src.logger.Info("Triggered WebSocket reconnection", zap.String("reason", reason))
+	default:
+		src.logger.Debug("Reconnection already in progress")
+	}
+}
+
+// messageListener listens for WebSocket messages of a source
+func (w *WebSocketScheduler) messageListener(ctx context.Context, src *wsHeadSource) {
 	defer func() {
 		if r := recover(); r != nil {
-			w.logger.Error("Message listener panic recovered", zap.Any("panic", r))
+			src.logger.Error("Message listener panic recovered", zap.Any("panic", r))
 		}
-		w.logger.Info("Message listener stopped")
+		src.logger.Info("Message listener stopped")
 
 		// Auto-restart message listener if scheduler is still running
 		w.mu.RLock()
@@ -214,35 +394,36 @@ func (w *WebSocketScheduler) messageListener(ctx context.Context) {
 		schedulerCtx := w.schedulerCtx
 		w.mu.RUnlock()
 
-		if running && schedulerCtx != nil {
-			w.logger.Info("Scheduler still running, attempting to restart message listener")
+		// A listener of a previous run must not restart after Stop and Start
+		if running && schedulerCtx != nil && schedulerCtx == ctx {
+			src.logger.Info("Scheduler still running, attempting to restart message listener")
 			// Wait a bit before restarting to avoid tight restart loops
 			time.Sleep(2 * time.Second)
 
 			select {
 			case <-w.stopChan:
-				w.logger.Info("Stop signal received during restart attempt")
+				src.logger.Info("Stop signal received during restart attempt")
 				return
 			case <-schedulerCtx.Done():
-				w.logger.Info("Scheduler context cancelled during restart attempt")
+				src.logger.Info("Scheduler context cancelled during restart attempt")
 				return
 			default:
-				w.logger.Info("Restarting message listener")
+				src.logger.Info("Restarting message listener")
 				// Sử dụng scheduler context thay vì context bị cancel
-				go w.messageListener(schedulerCtx)
+				go w.messageListener(schedulerCtx, src)
 			}
 		}
 	}()
 
-	w.logger.Info("Message listener started")
+	src.logger.Info("Message listener started")
 
 	for {
 		select {
 		case <-w.stopChan:
-			w.logger.Info("Message listener received stop signal")
+			src.logger.Info("Message listener received stop signal")
 			return
 		case <-ctx.Done():
-			w.logger.Info("Message listener context cancelled")
+			src.logger.Info("Message listener context cancelled")
 			// Kiểm tra xem có phải scheduler context bị cancel không
 			w.mu.RLock()
 			schedulerCtx := w.schedulerCtx
@@ -250,26 +431,26 @@ func (w *WebSocketScheduler) messageListener(ctx context.Context) {
 
 			if schedulerCtx != nil && schedulerCtx == ctx {
 				// Nếu là scheduler context bị cancel thì dừng hẳn
-				w.logger.Info("Scheduler context cancelled, stopping message listener permanently")
+				src.logger.Info("Scheduler context cancelled, stopping message listener permanently")
 				return
 			} else {
 				// Nếu chỉ là context từ bên ngoài bị cancel, tiếp tục với scheduler context
-				w.logger.Info("External context cancelled, but scheduler still running - continuing with scheduler context")
+				src.logger.Info("External context cancelled, but scheduler still running - continuing with scheduler context")
 				return // Defer function sẽ restart với scheduler context
 			}
 		default:
 			w.mu.RLock()
-			conn := w.conn
+			conn := src.conn
 			running := w.isRunning
 			w.mu.RUnlock()
 
 			if !running {
-				w.logger.Debug("Scheduler not running, stopping message listener")
+				src.logger.Debug("Scheduler not running, stopping message listener")
 				return
 			}
 
 			if conn == nil {
-				w.logger.Debug("No WebSocket connection, waiting...")
+				src.logger.Debug("No WebSocket connection, waiting...")
 				time.Sleep(1 * time.Second)
 				continue
 			}
@@ -281,80 +462,232 @@ func (w *WebSocketScheduler) messageListener(ctx context.Context) {
 			if err := conn.ReadJSON(&message); err != nil {
 				// Check if it's a normal close or timeout
 				if websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNormalClosure) {
-					w.logger.Warn("WebSocket connection closed", zap.Error(err))
+					src.logger.Warn("WebSocket connection closed", zap.Error(err))
 				} else if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() {
-					w.logger.Debug("WebSocket read timeout, continuing...")
+					src.logger.Debug("WebSocket read timeout, continuing...")
 					continue
 				} else {
-					w.logger.Error("Failed to read WebSocket message", zap.Error(err))
+					src.logger.Error("Failed to read WebSocket message", zap.Error(err))
 				}
 
-				// Trigger reconnection for any error
-				select {
-				case w.reconnectCh <- struct{}{}:
-					w.logger.Info("Triggered WebSocket reconnection")
-				default:
-					w.logger.Debug("Reconnection already in progress")
+				// Trigger reconnection for any error, unless the connection was
+				// closed by Stop
+				if ctx.Err() == nil {
+					w.triggerReconnect(src, "read error")
 				}
 				return
 			}
 
-			w.logger.Debug("Received WebSocket message", zap.Any("message_type", message["method"]))
-			w.handleMessage(message)
+			src.logger.Debug("Received WebSocket message", zap.Any("message_type", message["method"]))
+			w.handleMessage(src, message)
 		}
 	}
 }
 
 // handleMessage processes incoming WebSocket messages
-func (w *WebSocketScheduler) handleMessage(message map[string]interface{}) {
+func (w *WebSocketScheduler) handleMessage(src *wsHeadSource, message map[string]interface{}) {
 	// Update last message time
 	w.mu.Lock()
-	w.lastMessageTime = time.Now()
-	w.mu.Unlock()
+	src.lastMessageTime = time.Now()
 
 	// Handle subscription confirmation
-	if result, ok := message["result"].(string); ok && w.subID == "" {
-		w.subID = result
-		w.logger.Info("Received subscription ID", zap.String("sub_id", w.subID))
+	if result, ok := message["result"].(string); ok && src.subID == "" {
+		src.subID = result
+		w.mu.Unlock()
+		src.logger.Info("Received subscription ID", zap.String("sub_id", result))
 		return
 	}
+	w.mu.Unlock()
 
 	// Handle new block notifications
 	if method, ok := message["method"].(string); ok && method == "eth_subscription" {
 		params, ok := message["params"].(map[string]interface{})
 		if !ok {
-			w.logger.Debug("Invalid params in eth_subscription message")
+			src.logger.Debug("Invalid params in eth_subscription message")
 			return
 		}
 
 		result, ok := params["result"].(map[string]interface{})
 		if !ok {
-			w.logger.Debug("Invalid result in eth_subscription params")
+			src.logger.Debug("Invalid result in eth_subscription params")
 			return
 		}
 
-		// Extract block number
-		if numberHex, ok := result["number"].(string); ok {
-			blockNumber := new(big.Int)
-			if _, success := blockNumber.SetString(numberHex[2:], 16); success {
-				w.logger.Info("Received new block notification",
-					zap.String("block_number", blockNumber.String()))
+		header, err := parseHeader(result)
+		if err != nil {
+			src.logger.Error("Failed to parse new head", zap.Error(err))
+			return
+		}
 
-				// Call the callback function
-				if w.callback != nil {
-					go w.callback(blockNumber)
-				}
-			} else {
-				w.logger.Error("Failed to parse block number", zap.String("number_hex", numberHex))
+		event := w.trackHead(src, header)
+		if event == nil {
+			src.logger.Debug("Ignoring head already announced",
+				zap.String("block_number", header.Number.String()),
+				zap.String("block_hash", header.Hash))
+			return
+		}
+
+		src.logger.Info("Received new block notification",
+			zap.String("block_number", header.Number.String()),
+			zap.String("block_hash", header.Hash))
+
+		// Queue in arrival order, the dispatcher calls the callback one head at a time
+		select {
+		case w.heads <- event:
+		case <-w.stopChan:
+		}
+	}
+}
+
+// trackHead compares a head with the previously announced ones and records
+// source statistics. It returns nil for a hash that was already announced by
+// any source.
+func (w *WebSocketScheduler) trackHead(src *wsHeadSource, header *entity.BlockHeader) *service.NewHeadEvent {
+	w.headMu.Lock()
+	defer w.headMu.Unlock()
+
+	now := time.Now()
+	number := header.Number.Uint64()
+
+	src.headsReceived++
+	src.lastHeadTime = now
+	if !header.Timestamp.IsZero() {
+		src.totalLatency += now.Sub(header.Timestamp)
+	}
+	w.lastHeadTime = now
+
+	if seen, ok := w.seenHeads[header.Hash]; ok {
+		src.lagged++
+		src.totalLag += now.Sub(seen.at)
+		return nil
+	}
+	src.firstSeen++
+	w.seenHeads[header.Hash] = &seenHead{number: number, at: now, source: src.name}
+
+	event := &service.NewHeadEvent{Header: header}
+
+	if hash, ok := w.recentHashes[number]; ok {
+		event.ReplacedHash = hash
+		src.logger.Warn("Different block announced at the same height, possible reorg",
+			zap.Uint64("block_number", number),
+			zap.String("old_hash", hash),
+			zap.String("new_hash", header.Hash))
+	} else if w.lastHead != nil {
+		last := w.lastHead.Number.Uint64()
+		if number > last+1 {
+			for skipped := last + 1; skipped < number; skipped++ {
+				event.SkippedBlocks = append(event.SkippedBlocks, new(big.Int).SetUint64(skipped))
+			}
+			src.logger.Warn("Skipped heads detected",
+				zap.Uint64("previous_head", last),
+				zap.Uint64("block_number", number),
+				zap.Int("skipped", len(event.SkippedBlocks)))
+		}
+	}
+
+	w.lastHead = header
+	w.recentHashes[number] = header.Hash
+	if len(w.recentHashes) > recentHeadsWindow {
+		for height := range w.recentHashes {
+			if height+recentHeadsWindow < number {
+				delete(w.recentHashes, height)
+			}
+		}
+		for hash, seen := range w.seenHeads {
+			if seen.number+recentHeadsWindow < number {
+				delete(w.seenHeads, hash)
 			}
-		} else {
-			w.logger.Debug("No block number in subscription result")
+		}
+	}
+
+	return event
+}
+
+// resetHeads forgets previously announced heads
+func (w *WebSocketScheduler) resetHeads() {
+	w.headMu.Lock()
+	w.lastHead = nil
+	w.lastHeadTime = time.Now()
+	w.recentHashes = make(map[uint64]string)
+	w.seenHeads = make(map[string]*seenHead)
+	w.headMu.Unlock()
+
+	for {
+		select {
+		case <-w.heads:
+		default:
+			return
 		}
 	}
 }
 
-// connectionMonitor monitors WebSocket connection and handles reconnection
-func (w *WebSocketScheduler) connectionMonitor(ctx context.Context) {
+// dispatchHeads delivers queued heads to the callback one at a time
+func (w *WebSocketScheduler) dispatchHeads(ctx context.Context) {
+	w.logger.Info("Head dispatcher started")
+	defer w.logger.Info("Head dispatcher stopped")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-w.heads:
+			w.deliverHead(event)
+		}
+	}
+}
+
+// deliverHead calls the callback for a single head
+func (w *WebSocketScheduler) deliverHead(event *service.NewHeadEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Error("Head callback panic recovered",
+				zap.String("block_number", event.Header.Number.String()),
+				zap.Any("panic", r))
+		}
+	}()
+
+	w.mu.RLock()
+	callback := w.callback
+	w.mu.RUnlock()
+
+	if callback != nil {
+		callback(event)
+	}
+}
+
+// parseHeader reads the fields of a newHeads result
+func parseHeader(result map[string]interface{}) (*entity.BlockHeader, error) {
+	numberHex, _ := result["number"].(string)
+	number, err := hexutil.DecodeBig(numberHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block number %q: %w", numberHex, err)
+	}
+
+	hash, _ := result["hash"].(string)
+	if hash == "" {
+		return nil, fmt.Errorf("missing hash for block %s", number)
+	}
+	parentHash, _ := result["parentHash"].(string)
+
+	header := &entity.BlockHeader{
+		Number:     number,
+		Hash:       hash,
+		ParentHash: parentHash,
+	}
+
+	if timestampHex, ok := result["timestamp"].(string); ok {
+		timestamp, err := hexutil.DecodeUint64(timestampHex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q: %w", timestampHex, err)
+		}
+		header.Timestamp = time.Unix(int64(timestamp), 0)
+	}
+
+	return header, nil
+}
+
+// connectionMonitor monitors the WebSocket connection of a source and handles reconnection
+func (w *WebSocketScheduler) connectionMonitor(ctx context.Context, src *wsHeadSource) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
@@ -362,146 +695,164 @@ func (w *WebSocketScheduler) connectionMonitor(ctx context.Context) {
 	retryTicker := time.NewTicker(60 * time.Second)
 	defer retryTicker.Stop()
 
-	w.logger.Info("Connection monitor started")
+	src.logger.Info("Connection monitor started")
 
 	for {
 		select {
 		case <-w.stopChan:
-			w.logger.Info("Connection monitor received stop signal")
+			src.logger.Info("Connection monitor received stop signal")
 			return
 		case <-ctx.Done():
-			w.logger.Info("Connection monitor context cancelled")
+			src.logger.Info("Connection monitor context cancelled")
 			return
-		case <-w.reconnectCh:
-			w.logger.Info("Connection monitor handling reconnection request")
-			// Sử dụng scheduler context cho reconnection
-			w.mu.RLock()
-			schedulerCtx := w.schedulerCtx
-			w.mu.RUnlock()
-			if schedulerCtx != nil {
-				w.handleReconnection(schedulerCtx)
-			}
+		case <-src.reconnectCh:
+			src.logger.Info("Connection monitor handling reconnection request")
+			w.handleReconnection(ctx, src)
 		case <-retryTicker.C:
 			// Check if we need to retry connection
 			w.mu.RLock()
 			running := w.isRunning
-			conn := w.conn
-			schedulerCtx := w.schedulerCtx
+			conn := src.conn
 			w.mu.RUnlock()
 
-			if running && conn == nil && schedulerCtx != nil {
-				w.logger.Info("No active connection, attempting reconnection")
-				w.handleReconnection(schedulerCtx)
+			if running && conn == nil {
+				src.logger.Info("No active connection, attempting reconnection")
+				w.handleReconnection(ctx, src)
 			}
 		case <-ticker.C:
 			// Check connection health
 			w.mu.RLock()
-			conn := w.conn
+			conn := src.conn
 			running := w.isRunning
-			lastMessageTime := w.lastMessageTime
+			lastMessageTime := src.lastMessageTime
 			w.mu.RUnlock()
 
 			if running && conn != nil {
 				// Check if we haven't received messages for too long
 				timeSinceLastMessage := time.Since(lastMessageTime)
 				if timeSinceLastMessage > 2*time.Minute {
-					w.logger.Warn("No messages received for too long, triggering reconnection",
+					src.logger.Warn("No messages received for too long, triggering reconnection",
 						zap.Duration("time_since_last_message", timeSinceLastMessage))
-					select {
-					case w.reconnectCh <- struct{}{}:
-						w.logger.Info("Triggered reconnection due to message timeout")
-					default:
-						w.logger.Debug("Reconnection already in progress")
-					}
+					w.triggerReconnect(src, "message timeout")
 					continue
 				}
 
 				// Send ping to check connection health
-				w.logger.Debug("Sending WebSocket ping")
+				src.logger.Debug("Sending WebSocket ping")
 				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-					w.logger.Warn("WebSocket ping failed, triggering reconnection", zap.Error(err))
-					select {
-					case w.reconnectCh <- struct{}{}:
-						w.logger.Info("Triggered reconnection due to ping failure")
-					default:
-						w.logger.Debug("Reconnection already in progress")
-					}
+					src.logger.Warn("WebSocket ping failed, triggering reconnection", zap.Error(err))
+					w.triggerReconnect(src, "ping failure")
 				} else {
-					w.logger.Debug("WebSocket ping successful")
+					src.logger.Debug("WebSocket ping successful")
 				}
 			}
 		}
 	}
 }
 
-// handleReconnection handles WebSocket reconnection
-func (w *WebSocketScheduler) handleReconnection(ctx context.Context) {
+// handleReconnection reconnects a source. The lock is only held while the
+// connection is swapped, so other sources keep delivering heads meanwhile.
+func (w *WebSocketScheduler) handleReconnection(ctx context.Context, src *wsHeadSource) {
 	w.mu.Lock()
-	defer w.mu.Unlock()
-
 	if !w.isRunning {
-		w.logger.Debug("Scheduler not running, skipping reconnection")
+		w.mu.Unlock()
+		src.logger.Debug("Scheduler not running, skipping reconnection")
 		return
 	}
 
-	w.logger.Warn("Attempting to reconnect WebSocket")
+	src.logger.Warn("Attempting to reconnect WebSocket")
 
 	// Close existing connection
-	if w.conn != nil {
-		w.conn.Close()
-		w.conn = nil
+	if src.conn != nil {
+		src.conn.Close()
+		src.conn = nil
 	}
 
 	// Reset subscription ID
-	w.subID = ""
+	src.subID = ""
+	w.mu.Unlock()
 
-	// Retry connection with exponential backoff
+	// Retry connection with linear backoff
 	maxRetries := 10 // Increased retries
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if !w.isRunning {
-			w.logger.Info("Scheduler stopped during reconnection")
-			return
-		}
-
 		backoff := time.Duration(attempt) * 2 * time.Second // Linear backoff
 		if backoff > 30*time.Second {
 			backoff = 30 * time.Second // Cap at 30 seconds
 		}
 
-		w.logger.Info("Reconnection attempt",
+		src.logger.Info("Reconnection attempt",
 			zap.Int("attempt", attempt),
 			zap.Int("max_retries", maxRetries),
 			zap.Duration("backoff", backoff))
 
-		time.Sleep(backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			src.logger.Info("Scheduler stopped during reconnection")
+			return
+		}
 
-		if err := w.connect(ctx); err != nil {
-			w.logger.Error("Reconnection attempt failed",
+		conn, err := dial(ctx, src.url)
+		if err != nil {
+			src.logger.Error("Reconnection attempt failed",
 				zap.Int("attempt", attempt),
 				zap.Error(err))
 			continue
 		}
 
+		w.mu.Lock()
+		if !w.isRunning {
+			w.mu.Unlock()
+			conn.Close()
+			src.logger.Info("Scheduler stopped during reconnection")
+			return
+		}
+		src.conn = conn
+		src.lastMessageTime = time.Now()
+
 		// Re-subscribe to blocks
 		if w.callback != nil {
-			if err := w.subscribeToBlocks(); err != nil {
-				w.logger.Error("Failed to re-subscribe after reconnection", zap.Error(err))
+			if err := w.subscribeToBlocks(src); err != nil {
+				src.logger.Error("Failed to re-subscribe after reconnection", zap.Error(err))
 				// Close the connection and try again
-				if w.conn != nil {
-					w.conn.Close()
-					w.conn = nil
-				}
+				src.conn.Close()
+				src.conn = nil
+				w.mu.Unlock()
 				continue
 			}
 
 			// Note: Message listener will be automatically restarted by its own defer logic
-			w.logger.Info("WebSocket reconnection completed, message listener will restart automatically")
+			src.logger.Info("WebSocket reconnection completed, message listener will restart automatically")
 		}
+		w.mu.Unlock()
 
-		w.logger.Info("Successfully reconnected WebSocket", zap.Int("attempt", attempt))
+		src.logger.Info("Successfully reconnected WebSocket", zap.Int("attempt", attempt))
 		return
 	}
 
-	w.logger.Error("Failed to reconnect after maximum retries, scheduler will continue trying...")
+	src.logger.Error("Failed to reconnect after maximum retries, scheduler will continue trying...")
 	// Don't set isRunning to false, keep trying in background
 }
+
+// headSourceURLs returns the configured WebSocket endpoints without duplicates
+func headSourceURLs(cfg *config.EthereumConfig) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, endpoint := range append([]string{cfg.WSURL}, cfg.WSURLs...) {
+		if endpoint == "" || seen[endpoint] {
+			continue
+		}
+		seen[endpoint] = true
+		urls = append(urls, endpoint)
+	}
+	return urls
+}
+
+// sourceName names a source after its host. URLs often embed API keys, so
+// they are not logged.
+func sourceName(endpoint string, index int) string {
+	if parsed, err := url.Parse(endpoint); err == nil && parsed.Host != "" {
+		return fmt.Sprintf("%d-%s", index, parsed.Host)
+	}
+	return fmt.Sprintf("%d", index)
+}
@@ -2,21 +2,28 @@ package blockchain
 
 import (
 	"context"
+	"ethereum-raw-data-crawler/internal/domain/service"
 	"ethereum-raw-data-crawler/internal/infrastructure/config"
 	"ethereum-raw-data-crawler/internal/infrastructure/logger"
-	"math/big"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewWebSocketScheduler(t *testing.T) {
 	cfg := &config.EthereumConfig{
 		WSURL: "wss://mainnet.infura.io/ws/v3/test",
 	}
-	
-	logger := logger.NewLogger(&config.Config{
+
+	logger, _ := logger.NewLogger(&config.Config{
 		App: config.AppConfig{LogLevel: "info"},
 	})
 
@@ -30,8 +37,8 @@ func TestWebSocketScheduler_StartStop(t *testing.T) {
 	cfg := &config.EthereumConfig{
 		WSURL: "", // Empty URL to test error case
 	}
-	
-	logger := logger.NewLogger(&config.Config{
+
+	logger, _ := logger.NewLogger(&config.Config{
 		App: config.AppConfig{LogLevel: "info"},
 	})
 
@@ -53,13 +60,13 @@ func TestWebSocketScheduler_DoubleStart(t *testing.T) {
 	cfg := &config.EthereumConfig{
 		WSURL: "wss://mainnet.infura.io/ws/v3/test",
 	}
-	
-	logger := logger.NewLogger(&config.Config{
+
+	logger, _ := logger.NewLogger(&config.Config{
 		App: config.AppConfig{LogLevel: "info"},
 	})
 
 	scheduler := NewWebSocketScheduler(cfg, logger).(*WebSocketScheduler)
-	
+
 	// Manually set running to test double start
 	scheduler.isRunning = true
 
@@ -73,8 +80,8 @@ func TestWebSocketScheduler_SubscribeWithoutStart(t *testing.T) {
 	cfg := &config.EthereumConfig{
 		WSURL: "wss://mainnet.infura.io/ws/v3/test",
 	}
-	
-	logger := logger.NewLogger(&config.Config{
+
+	logger, _ := logger.NewLogger(&config.Config{
 		App: config.AppConfig{LogLevel: "info"},
 	})
 
@@ -82,7 +89,7 @@ func TestWebSocketScheduler_SubscribeWithoutStart(t *testing.T) {
 	ctx := context.Background()
 
 	// Test subscribe without starting
-	callback := func(blockNumber *big.Int) {
+	callback := func(event *service.NewHeadEvent) {
 		// Mock callback
 	}
 
@@ -95,8 +102,8 @@ func TestWebSocketScheduler_HandleMessage(t *testing.T) {
 	cfg := &config.EthereumConfig{
 		WSURL: "wss://mainnet.infura.io/ws/v3/test",
 	}
-	
-	logger := logger.NewLogger(&config.Config{
+
+	logger, _ := logger.NewLogger(&config.Config{
 		App: config.AppConfig{LogLevel: "info"},
 	})
 
@@ -107,39 +114,112 @@ func TestWebSocketScheduler_HandleMessage(t *testing.T) {
 		"result": "0x123456",
 	}
 
-	scheduler.handleMessage(subscriptionMessage)
-	assert.Equal(t, "0x123456", scheduler.subID)
+	scheduler.handleMessage(scheduler.sources[0], subscriptionMessage)
+	assert.Equal(t, "0x123456", scheduler.sources[0].subID)
 
 	// Test new block notification
-	var receivedBlockNumber *big.Int
-	scheduler.callback = func(blockNumber *big.Int) {
-		receivedBlockNumber = blockNumber
-	}
-
 	blockMessage := map[string]interface{}{
 		"method": "eth_subscription",
 		"params": map[string]interface{}{
 			"result": map[string]interface{}{
-				"number": "0x1234", // Block number 4660 in hex
+				"number":     "0x1234", // Block number 4660 in hex
+				"hash":       "0xaaa",
+				"parentHash": "0x999",
+				"timestamp":  "0x6853a1c0",
 			},
 		},
 	}
 
-	scheduler.handleMessage(blockMessage)
-	
-	// Give some time for the goroutine to execute
-	time.Sleep(10 * time.Millisecond)
-	
-	assert.NotNil(t, receivedBlockNumber)
-	assert.Equal(t, int64(4660), receivedBlockNumber.Int64())
+	scheduler.handleMessage(scheduler.sources[0], blockMessage)
+
+	event := receiveHead(t, scheduler)
+	assert.Equal(t, int64(4660), event.Header.Number.Int64())
+	assert.Equal(t, "0xaaa", event.Header.Hash)
+	assert.Equal(t, "0x999", event.Header.ParentHash)
+	assert.Equal(t, int64(0x6853a1c0), event.Header.Timestamp.Unix())
+	assert.Empty(t, event.SkippedBlocks)
+	assert.False(t, event.IsReorg())
+}
+
+func TestWebSocketScheduler_SkippedHeads(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	scheduler.handleMessage(scheduler.sources[0], newHeadMessage(100, "0xa100"))
+	scheduler.handleMessage(scheduler.sources[0], newHeadMessage(101, "0xa101"))
+	scheduler.handleMessage(scheduler.sources[0], newHeadMessage(104, "0xa104"))
+
+	assert.Equal(t, uint64(100), receiveHead(t, scheduler).Header.Number.Uint64())
+	assert.Empty(t, receiveHead(t, scheduler).SkippedBlocks)
+
+	event := receiveHead(t, scheduler)
+	assert.Equal(t, uint64(104), event.Header.Number.Uint64())
+	require.Len(t, event.SkippedBlocks, 2)
+	assert.Equal(t, uint64(102), event.SkippedBlocks[0].Uint64())
+	assert.Equal(t, uint64(103), event.SkippedBlocks[1].Uint64())
+}
+
+func TestWebSocketScheduler_SameHeightReorg(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	scheduler.handleMessage(scheduler.sources[0], newHeadMessage(100, "0xa100"))
+	scheduler.handleMessage(scheduler.sources[0], newHeadMessage(101, "0xa101"))
+	scheduler.handleMessage(scheduler.sources[0], newHeadMessage(101, "0xa101")) // Repeated, ignored
+	scheduler.handleMessage(scheduler.sources[0], newHeadMessage(101, "0xb101"))
+
+	receiveHead(t, scheduler)
+	receiveHead(t, scheduler)
+
+	event := receiveHead(t, scheduler)
+	assert.True(t, event.IsReorg())
+	assert.Equal(t, "0xa101", event.ReplacedHash)
+	assert.Equal(t, "0xb101", event.Header.Hash)
+	assert.Empty(t, event.SkippedBlocks)
+	assert.Empty(t, scheduler.heads)
+
+	// The new fork continues without a gap
+	scheduler.handleMessage(scheduler.sources[0], newHeadMessage(102, "0xb102"))
+	event = receiveHead(t, scheduler)
+	assert.False(t, event.IsReorg())
+	assert.Empty(t, event.SkippedBlocks)
+}
+
+func TestWebSocketScheduler_DispatchesInOrder(t *testing.T) {
+	scheduler := newTestScheduler(t)
+
+	var mu sync.Mutex
+	var received []uint64
+	scheduler.callback = func(event *service.NewHeadEvent) {
+		// Slow callback, later heads must wait for it
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		received = append(received, event.Header.Number.Uint64())
+		mu.Unlock()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.dispatchHeads(ctx)
+
+	for number := uint64(1); number <= 20; number++ {
+		scheduler.handleMessage(scheduler.sources[0], newHeadMessage(number, fmt.Sprintf("0x%x", number)))
+	}
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 20
+	}, time.Second, 5*time.Millisecond)
+	for i, number := range received {
+		assert.Equal(t, uint64(i+1), number)
+	}
 }
 
 func TestWebSocketScheduler_HandleInvalidMessage(t *testing.T) {
 	cfg := &config.EthereumConfig{
 		WSURL: "wss://mainnet.infura.io/ws/v3/test",
 	}
-	
-	logger := logger.NewLogger(&config.Config{
+
+	logger, _ := logger.NewLogger(&config.Config{
 		App: config.AppConfig{LogLevel: "info"},
 	})
 
@@ -152,7 +232,7 @@ func TestWebSocketScheduler_HandleInvalidMessage(t *testing.T) {
 	}
 
 	// This should not panic or cause errors
-	scheduler.handleMessage(invalidMessage)
+	scheduler.handleMessage(scheduler.sources[0], invalidMessage)
 
 	// Test message with invalid block number
 	invalidBlockMessage := map[string]interface{}{
@@ -164,15 +244,15 @@ func TestWebSocketScheduler_HandleInvalidMessage(t *testing.T) {
 		},
 	}
 
-	scheduler.handleMessage(invalidBlockMessage)
+	scheduler.handleMessage(scheduler.sources[0], invalidBlockMessage)
 }
 
 func TestWebSocketScheduler_Unsubscribe(t *testing.T) {
 	cfg := &config.EthereumConfig{
 		WSURL: "wss://mainnet.infura.io/ws/v3/test",
 	}
-	
-	logger := logger.NewLogger(&config.Config{
+
+	logger, _ := logger.NewLogger(&config.Config{
 		App: config.AppConfig{LogLevel: "info"},
 	})
 
@@ -183,11 +263,187 @@ func TestWebSocketScheduler_Unsubscribe(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test unsubscribe with subscription ID but no connection
-	scheduler.subID = "test_sub_id"
+	scheduler.sources[0].subID = "test_sub_id"
 	err = scheduler.Unsubscribe()
 	assert.NoError(t, err)
 }
 
+func TestWebSocketScheduler_SourceURLs(t *testing.T) {
+	scheduler := newTestScheduler(t, "wss://a.example/ws/key1", "wss://b.example/ws/key2", "wss://a.example/ws/key1")
+
+	require.Len(t, scheduler.sources, 2)
+	assert.Equal(t, "0-a.example", scheduler.sources[0].name)
+	assert.Equal(t, "1-b.example", scheduler.sources[1].name)
+}
+
+func TestWebSocketScheduler_DedupesAcrossSources(t *testing.T) {
+	scheduler := newTestScheduler(t, "wss://a.example/ws", "wss://b.example/ws")
+	fast, slow := scheduler.sources[0], scheduler.sources[1]
+
+	scheduler.handleMessage(fast, newHeadMessage(100, "0xa100"))
+	time.Sleep(5 * time.Millisecond)
+	scheduler.handleMessage(slow, newHeadMessage(100, "0xa100"))
+	scheduler.handleMessage(slow, newHeadMessage(101, "0xa101"))
+	scheduler.handleMessage(fast, newHeadMessage(101, "0xa101"))
+
+	assert.Equal(t, "0xa100", receiveHead(t, scheduler).Header.Hash)
+	event := receiveHead(t, scheduler)
+	assert.Equal(t, "0xa101", event.Header.Hash)
+	assert.Empty(t, event.SkippedBlocks)
+	assert.False(t, event.IsReorg())
+	assert.Empty(t, scheduler.heads, "each hash is delivered once")
+
+	stats := scheduler.GetSourceStats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, int64(2), stats[0].HeadsReceived)
+	assert.Equal(t, int64(1), stats[0].FirstSeen)
+	assert.Equal(t, int64(2), stats[1].HeadsReceived)
+	assert.Equal(t, int64(1), stats[1].FirstSeen)
+	assert.GreaterOrEqual(t, stats[1].AverageLag, 5*time.Millisecond)
+	assert.False(t, stats[0].Connected)
+}
+
+func TestWebSocketScheduler_MultipleEndpoints(t *testing.T) {
+	nodeA := newFakeNode(t)
+	nodeB := newFakeNode(t)
+	scheduler := newTestScheduler(t, nodeA.url, "ws://127.0.0.1:1/unreachable", nodeB.url)
+
+	ctx := context.Background()
+	require.NoError(t, scheduler.Start(ctx), "one unreachable endpoint doesn't stop the scheduler")
+	defer scheduler.Stop()
+
+	events := make(chan *service.NewHeadEvent, 10)
+	require.NoError(t, scheduler.SubscribeNewBlocks(ctx, func(event *service.NewHeadEvent) {
+		events <- event
+	}))
+	nodeA.waitSubscribed(t)
+	nodeB.waitSubscribed(t)
+
+	nodeA.sendHead(t, 200, "0xc200")
+	nodeB.sendHead(t, 200, "0xc200")
+	nodeB.sendHead(t, 201, "0xc201")
+
+	for _, want := range []uint64{200, 201} {
+		select {
+		case event := <-events:
+			assert.Equal(t, want, event.Header.Number.Uint64())
+		case <-time.After(2 * time.Second):
+			t.Fatalf("head %d not delivered", want)
+		}
+	}
+
+	// Let the duplicate arrive, it must not be delivered again
+	require.Eventually(t, func() bool {
+		stats := scheduler.GetSourceStats()
+		return stats[0].HeadsReceived+stats[2].HeadsReceived == 3
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.Empty(t, events)
+
+	stats := scheduler.GetSourceStats()
+	assert.True(t, stats[0].Connected)
+	assert.False(t, stats[1].Connected)
+	assert.True(t, stats[2].Connected)
+	assert.Equal(t, int64(2), stats[0].FirstSeen+stats[2].FirstSeen)
+	assert.WithinDuration(t, time.Now(), scheduler.LastHeadTime(), time.Second)
+}
+
+func newTestScheduler(t *testing.T, urls ...string) *WebSocketScheduler {
+	logger, err := logger.NewLogger(&config.Config{
+		App: config.AppConfig{LogLevel: "error"},
+	})
+	require.NoError(t, err)
+
+	if len(urls) == 0 {
+		urls = []string{"wss://mainnet.infura.io/ws/v3/test"}
+	}
+	cfg := &config.EthereumConfig{WSURL: urls[0], WSURLs: urls[1:]}
+	return NewWebSocketScheduler(cfg, logger).(*WebSocketScheduler)
+}
+
+// fakeNode is a WebSocket endpoint that accepts a newHeads subscription and
+// pushes heads on demand
+type fakeNode struct {
+	url        string
+	conns      chan *websocket.Conn
+	conn       *websocket.Conn
+	subscribed chan struct{}
+}
+
+func newFakeNode(t *testing.T) *fakeNode {
+	node := &fakeNode{
+		conns:      make(chan *websocket.Conn, 1),
+		subscribed: make(chan struct{}, 1),
+	}
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(rw, r, nil)
+		if err != nil {
+			return
+		}
+		node.conns <- conn
+
+		for {
+			var request map[string]interface{}
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			if request["method"] == "eth_subscribe" {
+				conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": request["id"], "result": "0xsub"})
+				node.subscribed <- struct{}{}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	node.url = "ws" + strings.TrimPrefix(server.URL, "http")
+	return node
+}
+
+func (n *fakeNode) waitSubscribed(t *testing.T) {
+	t.Helper()
+	select {
+	case n.conn = <-n.conns:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no connection")
+	}
+	select {
+	case <-n.subscribed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no subscription")
+	}
+}
+
+func (n *fakeNode) sendHead(t *testing.T, number uint64, hash string) {
+	t.Helper()
+	require.NoError(t, n.conn.WriteJSON(newHeadMessage(number, hash)))
+}
+
+func newHeadMessage(number uint64, hash string) map[string]interface{} {
+	return map[string]interface{}{
+		"method": "eth_subscription",
+		"params": map[string]interface{}{
+			"result": map[string]interface{}{
+				"number":     fmt.Sprintf("0x%x", number),
+				"hash":       hash,
+				"parentHash": "0x0",
+				"timestamp":  "0x0",
+			},
+		},
+	}
+}
+
+func receiveHead(t *testing.T, scheduler *WebSocketScheduler) *service.NewHeadEvent {
+	t.Helper()
+	select {
+	case event := <-scheduler.heads:
+		return event
+	default:
+		t.Fatal("no head queued")
+		return nil
+	}
+}
+
 // Integration test that would require a real WebSocket server
 // This is commented out as it requires external dependencies
 /*
@@ -195,12 +451,12 @@ func TestWebSocketScheduler_Integration(t *testing.T) {
 	// This test would require a mock WebSocket server
 	// or connection to a real Ethereum node
 	t.Skip("Integration test requires WebSocket server")
-	
+
 	cfg := &config.EthereumConfig{
 		WSURL: "wss://mainnet.infura.io/ws/v3/YOUR_PROJECT_ID",
 	}
-	
-	logger := logger.NewLogger(&config.Config{
+
+	logger, _ := logger.NewLogger(&config.Config{
 		App: config.AppConfig{LogLevel: "info"},
 	})
 
@@ -215,8 +471,8 @@ func TestWebSocketScheduler_Integration(t *testing.T) {
 
 	// Subscribe to new blocks
 	var receivedBlocks []*big.Int
-	callback := func(blockNumber *big.Int) {
-		receivedBlocks = append(receivedBlocks, blockNumber)
+	callback := func(event *service.NewHeadEvent) {
+		receivedBlocks = append(receivedBlocks, event.Header.Number)
 	}
 
 	err = scheduler.SubscribeNewBlocks(ctx, callback)
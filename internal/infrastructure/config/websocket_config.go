@@ -12,9 +12,9 @@ type WebSocketConfig struct {
 	WriteTimeout      time.Duration `mapstructure:"write_timeout"`      // Write timeout
 
 	// Data processing settings
-	BufferSize    int           `mapstructure:"buffer_size"`    // Message buffer size
-	BatchSize     int           `mapstructure:"batch_size"`     // Batch size for database writes
-	FlushInterval time.Duration `mapstructure:"flush_interval"` // Interval to flush buffered data
+	BufferSize    int           `mapstructure:"buffer_size"`    // Blocks buffered for database writes before crawls wait
+	BatchSize     int           `mapstructure:"batch_size"`     // Blocks per bulk database write
+	FlushInterval time.Duration `mapstructure:"flush_interval"` // Interval to flush buffered blocks
 	MaxRetries    int           `mapstructure:"max_retries"`    // Max retries for failed operations
 	RetryDelay    time.Duration `mapstructure:"retry_delay"`    // Delay between retries
 
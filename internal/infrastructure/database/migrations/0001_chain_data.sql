@@ -0,0 +1,108 @@
+-- Blocks, transactions and metrics of the PostgreSQL storage backend.
+-- Wei amounts are NUMERIC(78, 0), which holds any uint256, gas is BIGINT.
+
+CREATE TABLE IF NOT EXISTS blocks (
+    number             BIGINT PRIMARY KEY,
+    hash               TEXT NOT NULL UNIQUE,
+    parent_hash        TEXT NOT NULL,
+    nonce              NUMERIC(20, 0) NOT NULL,
+    sha3_uncles        TEXT NOT NULL,
+    logs_bloom         TEXT NOT NULL,
+    transactions_root  TEXT NOT NULL,
+    state_root         TEXT NOT NULL,
+    receipts_root      TEXT NOT NULL,
+    miner              TEXT NOT NULL,
+    difficulty         NUMERIC(78, 0),
+    total_difficulty   NUMERIC(78, 0),
+    extra_data         TEXT NOT NULL,
+    size               BIGINT NOT NULL,
+    gas_limit          BIGINT NOT NULL,
+    gas_used           BIGINT NOT NULL,
+    timestamp          TIMESTAMPTZ NOT NULL,
+    transaction_hashes TEXT[] NOT NULL DEFAULT '{}',
+    uncles             TEXT[] NOT NULL DEFAULT '{}',
+    crawled_at         TIMESTAMPTZ NOT NULL,
+    network            TEXT NOT NULL,
+    processed_at       TIMESTAMPTZ,
+    status             TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS blocks_network_number_idx ON blocks (network, number);
+CREATE INDEX IF NOT EXISTS blocks_timestamp_idx ON blocks (timestamp);
+CREATE INDEX IF NOT EXISTS blocks_status_idx ON blocks (status);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    hash                     TEXT PRIMARY KEY,
+    block_hash               TEXT NOT NULL,
+    block_number             BIGINT NOT NULL,
+    transaction_index        INTEGER NOT NULL,
+    from_address             TEXT NOT NULL,
+    to_address               TEXT,
+    value                    NUMERIC(78, 0) NOT NULL,
+    gas                      BIGINT NOT NULL,
+    gas_price                NUMERIC(78, 0),
+    gas_used                 BIGINT NOT NULL,
+    cumulative_gas_used      BIGINT NOT NULL,
+    data                     TEXT NOT NULL,
+    nonce                    BIGINT NOT NULL,
+    status                   SMALLINT NOT NULL,
+    max_fee_per_gas          NUMERIC(78, 0),
+    max_priority_fee_per_gas NUMERIC(78, 0),
+    contract_address         TEXT,
+    receipt_missing          BOOLEAN NOT NULL DEFAULT false,
+    crawled_at               TIMESTAMPTZ NOT NULL,
+    network                  TEXT NOT NULL,
+    processed_at             TIMESTAMPTZ,
+    tx_status                TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS transactions_block_hash_idx ON transactions (block_hash);
+CREATE INDEX IF NOT EXISTS transactions_block_number_idx ON transactions (block_number);
+CREATE INDEX IF NOT EXISTS transactions_from_address_idx ON transactions (from_address);
+CREATE INDEX IF NOT EXISTS transactions_to_address_idx ON transactions (to_address);
+CREATE INDEX IF NOT EXISTS transactions_network_block_number_idx ON transactions (network, block_number);
+CREATE INDEX IF NOT EXISTS transactions_tx_status_idx ON transactions (tx_status);
+CREATE INDEX IF NOT EXISTS transactions_value_idx ON transactions (value DESC);
+
+CREATE TABLE IF NOT EXISTS crawler_metrics (
+    id                      BIGSERIAL PRIMARY KEY,
+    timestamp               TIMESTAMPTZ NOT NULL,
+    last_processed_block    BIGINT NOT NULL,
+    current_block           BIGINT NOT NULL,
+    blocks_processed        BIGINT NOT NULL,
+    blocks_per_second       DOUBLE PRECISION NOT NULL,
+    incomplete_blocks       BIGINT NOT NULL,
+    transactions_processed  BIGINT NOT NULL,
+    transactions_per_second DOUBLE PRECISION NOT NULL,
+    error_count             BIGINT NOT NULL,
+    last_error_message      TEXT NOT NULL,
+    last_error_time         TIMESTAMPTZ,
+    average_processing_time BIGINT NOT NULL, -- nanoseconds
+    memory_usage            BIGINT NOT NULL,
+    goroutine_count         INTEGER NOT NULL,
+    network_latency         BIGINT NOT NULL, -- nanoseconds
+    rpc_calls_count         BIGINT NOT NULL,
+    rpc_error_rate          DOUBLE PRECISION NOT NULL,
+    rpc_rate_limited_count  BIGINT NOT NULL,
+    concurrent_workers      INTEGER NOT NULL,
+    request_delay           BIGINT NOT NULL, -- nanoseconds
+    db_connection_count     INTEGER NOT NULL,
+    db_writes_per_second    DOUBLE PRECISION NOT NULL,
+    network                 TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS crawler_metrics_timestamp_idx ON crawler_metrics (timestamp);
+CREATE INDEX IF NOT EXISTS crawler_metrics_network_timestamp_idx ON crawler_metrics (network, timestamp);
+
+CREATE TABLE IF NOT EXISTS system_health (
+    id                BIGSERIAL PRIMARY KEY,
+    timestamp         TIMESTAMPTZ NOT NULL,
+    status            TEXT NOT NULL,
+    components_health JSONB NOT NULL DEFAULT '{}',
+    message           TEXT NOT NULL,
+    network           TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS system_health_timestamp_idx ON system_health (timestamp);
+CREATE INDEX IF NOT EXISTS system_health_network_timestamp_idx ON system_health (network, timestamp);
+CREATE INDEX IF NOT EXISTS system_health_status_idx ON system_health (status);
@@ -0,0 +1,13 @@
+-- Block timestamp of transactions, for time-range queries without a join.
+-- Transactions of unknown blocks keep the zero time, like in MongoDB.
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS block_timestamp TIMESTAMPTZ NOT NULL DEFAULT '0001-01-01 00:00:00+00';
+
+UPDATE transactions t
+SET block_timestamp = b.timestamp
+FROM blocks b
+WHERE b.hash = t.block_hash;
+
+CREATE INDEX IF NOT EXISTS transactions_block_timestamp_idx ON transactions (block_timestamp);
+CREATE INDEX IF NOT EXISTS transactions_network_block_timestamp_idx ON transactions (network, block_timestamp);
+CREATE INDEX IF NOT EXISTS blocks_network_timestamp_idx ON blocks (network, timestamp);
@@ -0,0 +1,68 @@
+-- Address history paginated by (block_number, transaction_index) and
+-- per-address transaction counters kept up to date by triggers.
+
+CREATE INDEX IF NOT EXISTS transactions_from_address_position_idx
+    ON transactions (from_address, block_number DESC, transaction_index DESC);
+CREATE INDEX IF NOT EXISTS transactions_to_address_position_idx
+    ON transactions (to_address, block_number DESC, transaction_index DESC);
+-- Covered by the position indexes
+DROP INDEX IF EXISTS transactions_from_address_idx;
+DROP INDEX IF EXISTS transactions_to_address_idx;
+
+CREATE TABLE IF NOT EXISTS address_transaction_counts (
+    address    TEXT PRIMARY KEY,
+    total      BIGINT NOT NULL DEFAULT 0, -- Sent or received, a transaction to the address itself counted once
+    sent       BIGINT NOT NULL DEFAULT 0,
+    received   BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Applies the inserted (INSERT) or deleted (DELETE) rows of a statement to the
+-- counters. Rows updated by ON CONFLICT DO UPDATE are not in the transition
+-- table of INSERT, so upserting a stored transaction doesn't count it again.
+-- Counters are updated in address order, so concurrent statements touching
+-- the same addresses wait for each other instead of deadlocking.
+CREATE OR REPLACE FUNCTION count_address_transactions() RETURNS trigger AS $$
+DECLARE
+    sign BIGINT := CASE WHEN TG_OP = 'INSERT' THEN 1 ELSE -1 END;
+BEGIN
+    INSERT INTO address_transaction_counts AS c (address, total, sent, received, updated_at)
+    SELECT address, sign * count(*), sign * sum(sent), sign * sum(received), now()
+    FROM (
+        SELECT from_address AS address, 1 AS sent, CASE WHEN to_address = from_address THEN 1 ELSE 0 END AS received
+        FROM changed
+        UNION ALL
+        SELECT to_address, 0, 1 FROM changed WHERE to_address <> from_address
+    ) roles
+    GROUP BY address
+    ORDER BY address
+    ON CONFLICT (address) DO UPDATE
+        SET total = c.total + EXCLUDED.total,
+            sent = c.sent + EXCLUDED.sent,
+            received = c.received + EXCLUDED.received,
+            updated_at = EXCLUDED.updated_at;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS transactions_count_inserted ON transactions;
+CREATE TRIGGER transactions_count_inserted AFTER INSERT ON transactions
+    REFERENCING NEW TABLE AS changed
+    FOR EACH STATEMENT EXECUTE FUNCTION count_address_transactions();
+
+DROP TRIGGER IF EXISTS transactions_count_deleted ON transactions;
+CREATE TRIGGER transactions_count_deleted AFTER DELETE ON transactions
+    REFERENCING OLD TABLE AS changed
+    FOR EACH STATEMENT EXECUTE FUNCTION count_address_transactions();
+
+-- Transactions stored before the counters existed
+INSERT INTO address_transaction_counts (address, total, sent, received)
+SELECT address, count(*), sum(sent), sum(received)
+FROM (
+    SELECT from_address AS address, 1 AS sent, CASE WHEN to_address = from_address THEN 1 ELSE 0 END AS received
+    FROM transactions
+    UNION ALL
+    SELECT to_address, 0, 1 FROM transactions WHERE to_address <> from_address
+) roles
+GROUP BY address
+ON CONFLICT (address) DO NOTHING;
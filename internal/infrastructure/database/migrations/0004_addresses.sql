@@ -0,0 +1,63 @@
+-- Addresses with their first-seen transaction, last-active block and whether
+-- they are contracts, next to the transaction counters they absorb.
+
+ALTER TABLE IF EXISTS address_transaction_counts RENAME TO addresses;
+ALTER INDEX IF EXISTS address_transaction_counts_pkey RENAME TO addresses_pkey;
+
+ALTER TABLE addresses
+    ADD COLUMN IF NOT EXISTS first_seen_block    BIGINT,
+    ADD COLUMN IF NOT EXISTS first_seen_tx_index INTEGER,
+    ADD COLUMN IF NOT EXISTS first_seen_tx       TEXT,
+    ADD COLUMN IF NOT EXISTS last_active_block   BIGINT,
+    ADD COLUMN IF NOT EXISTS is_contract         BOOLEAN; -- NULL until classified
+
+-- Index for classifying addresses by their code
+CREATE INDEX IF NOT EXISTS addresses_unclassified_idx ON addresses (address) WHERE is_contract IS NULL;
+
+-- Same as in 0003, on the renamed table
+CREATE OR REPLACE FUNCTION count_address_transactions() RETURNS trigger AS $$
+DECLARE
+    sign BIGINT := CASE WHEN TG_OP = 'INSERT' THEN 1 ELSE -1 END;
+BEGIN
+    INSERT INTO addresses AS c (address, total, sent, received, updated_at)
+    SELECT address, sign * count(*), sign * sum(sent), sign * sum(received), now()
+    FROM (
+        SELECT from_address AS address, 1 AS sent, CASE WHEN to_address = from_address THEN 1 ELSE 0 END AS received
+        FROM changed
+        UNION ALL
+        SELECT to_address, 0, 1 FROM changed WHERE to_address <> from_address
+    ) roles
+    GROUP BY address
+    ORDER BY address
+    ON CONFLICT (address) DO UPDATE
+        SET total = c.total + EXCLUDED.total,
+            sent = c.sent + EXCLUDED.sent,
+            received = c.received + EXCLUDED.received,
+            updated_at = EXCLUDED.updated_at;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Activity of the transactions stored before addresses existed. Senders are
+-- no contracts, created contracts are, recipients are classified by their code.
+INSERT INTO addresses AS a (address, first_seen_block, first_seen_tx_index, first_seen_tx, last_active_block, is_contract)
+SELECT address, first_block, first_index, first_hash, last_block, is_contract
+FROM (
+    SELECT DISTINCT ON (address) address, block_number AS first_block, transaction_index AS first_index, hash AS first_hash,
+        max(block_number) OVER (PARTITION BY address) AS last_block,
+        bool_or(contract) OVER (PARTITION BY address) AS is_contract
+    FROM (
+        SELECT from_address AS address, false AS contract, block_number, transaction_index, hash FROM transactions
+        UNION ALL
+        SELECT to_address, NULL, block_number, transaction_index, hash FROM transactions WHERE to_address <> from_address
+        UNION ALL
+        SELECT contract_address, true, block_number, transaction_index, hash FROM transactions WHERE contract_address IS NOT NULL
+    ) roles
+    ORDER BY address, block_number, transaction_index
+) activity
+ON CONFLICT (address) DO UPDATE
+    SET first_seen_block = EXCLUDED.first_seen_block,
+        first_seen_tx_index = EXCLUDED.first_seen_tx_index,
+        first_seen_tx = EXCLUDED.first_seen_tx,
+        last_active_block = EXCLUDED.last_active_block,
+        is_contract = EXCLUDED.is_contract;
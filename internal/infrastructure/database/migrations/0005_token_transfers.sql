@@ -0,0 +1,31 @@
+-- Token transfers decoded from Transfer, TransferSingle and TransferBatch logs.
+-- A TransferBatch log gives one row per token, told apart by batch_index.
+
+CREATE TABLE IF NOT EXISTS token_transfers (
+    transaction_hash  TEXT NOT NULL,
+    log_index         INTEGER NOT NULL,
+    batch_index       INTEGER NOT NULL,
+    token             TEXT NOT NULL,
+    standard          TEXT NOT NULL,
+    from_address      TEXT NOT NULL,
+    to_address        TEXT NOT NULL,
+    operator          TEXT,                  -- ERC-1155 only
+    amount            NUMERIC(78, 0) NOT NULL,
+    token_id          NUMERIC(78, 0),        -- NULL for ERC-20
+    transaction_index INTEGER NOT NULL,
+    block_number      BIGINT NOT NULL,
+    block_hash        TEXT NOT NULL,
+    block_timestamp   TIMESTAMPTZ NOT NULL,
+    network           TEXT NOT NULL,
+    crawled_at        TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (transaction_hash, log_index, batch_index)
+);
+
+-- Newest first by token and by holder, sent or received
+CREATE INDEX IF NOT EXISTS token_transfers_token_position_idx
+    ON token_transfers (token, block_number DESC, log_index DESC, batch_index DESC);
+CREATE INDEX IF NOT EXISTS token_transfers_from_address_position_idx
+    ON token_transfers (from_address, block_number DESC, log_index DESC, batch_index DESC);
+CREATE INDEX IF NOT EXISTS token_transfers_to_address_position_idx
+    ON token_transfers (to_address, block_number DESC, log_index DESC, batch_index DESC);
+CREATE INDEX IF NOT EXISTS token_transfers_block_hash_idx ON token_transfers (block_hash);
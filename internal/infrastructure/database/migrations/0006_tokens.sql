@@ -0,0 +1,22 @@
+-- Token metadata read with eth_call, cached per token contract. A token is
+-- added when its first transfer is seen and resolved by the refresh worker.
+
+CREATE TABLE IF NOT EXISTS tokens (
+    address          TEXT PRIMARY KEY,
+    standard         TEXT NOT NULL,
+    first_seen_block BIGINT NOT NULL,
+    name             TEXT NOT NULL DEFAULT '',
+    symbol           TEXT NOT NULL DEFAULT '',
+    decimals         SMALLINT,              -- NULL when not implemented
+    total_supply     NUMERIC(78, 0),        -- NULL when not implemented
+    interfaces       TEXT[] NOT NULL DEFAULT '{}',
+    resolved_at      TIMESTAMPTZ,           -- NULL until resolved
+    failures         INTEGER NOT NULL DEFAULT 0,
+    last_error       TEXT NOT NULL DEFAULT '',
+    refresh_at       TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL,
+    updated_at       TIMESTAMPTZ NOT NULL
+);
+
+-- Index for the tokens due for a lookup
+CREATE INDEX IF NOT EXISTS tokens_refresh_at_idx ON tokens (refresh_at);
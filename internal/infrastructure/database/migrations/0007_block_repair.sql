@@ -0,0 +1,8 @@
+-- Receipt repair schedule of incomplete blocks. A block failing repair waits
+-- longer after every attempt, so it doesn't hold back the others.
+
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS repair_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS next_repair_at TIMESTAMPTZ; -- NULL until a repair failed
+
+-- Index for the incomplete blocks due for a repair
+CREATE INDEX IF NOT EXISTS blocks_next_repair_at_idx ON blocks (network, next_repair_at) WHERE status = 'incomplete';
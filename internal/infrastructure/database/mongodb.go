@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,6 +16,10 @@ type MongoDB struct {
 	Client   *mongo.Client
 	Database *mongo.Database
 	config   *config.MongoDBConfig
+
+	// Cached result of SupportsTransactions
+	transactionsMu       sync.Mutex
+	supportsTransactions *bool
 }
 
 // NewMongoDB creates new MongoDB connection with enhanced configuration
@@ -71,6 +76,29 @@ func (m *MongoDB) Close(ctx context.Context) error {
 	return m.Client.Disconnect(ctx)
 }
 
+// SupportsTransactions reports whether the deployment can run multi-document
+// transactions, which needs a replica set or a sharded cluster
+func (m *MongoDB) SupportsTransactions(ctx context.Context) (bool, error) {
+	m.transactionsMu.Lock()
+	defer m.transactionsMu.Unlock()
+
+	if m.supportsTransactions != nil {
+		return *m.supportsTransactions, nil
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := m.Database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, err
+	}
+
+	supported := hello.SetName != "" || hello.Msg == "isdbgrid"
+	m.supportsTransactions = &supported
+	return supported, nil
+}
+
 // GetCollection returns a collection
 func (m *MongoDB) GetCollection(name string) *mongo.Collection {
 	return m.Database.Collection(name)
@@ -96,9 +124,15 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: bson.D{{Key: "timestamp", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "network", Value: 1}, {Key: "timestamp", Value: 1}},
+		},
 		{
 			Keys: bson.D{{Key: "status", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "network", Value: 1}, {Key: "status", Value: 1}, {Key: "next_repair_at", Value: 1}},
+		},
 	}
 
 	if _, err := blocksCollection.Indexes().CreateMany(ctx, blocksIndexes); err != nil {
@@ -131,12 +165,97 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: bson.D{{Key: "tx_status", Value: 1}},
 		},
+		{
+			// Wei amounts are Decimal128, so this index serves top-by-value queries
+			Keys: bson.D{{Key: "value", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "block_timestamp", Value: 1}, {Key: "transaction_index", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "network", Value: 1}, {Key: "block_timestamp", Value: 1}},
+		},
+		{
+			// block_height is the numeric block number, these serve address history in position order
+			Keys: bson.D{{Key: "from", Value: 1}, {Key: "block_height", Value: -1}, {Key: "transaction_index", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "to", Value: 1}, {Key: "block_height", Value: -1}, {Key: "transaction_index", Value: -1}},
+		},
+		{
+			// Only the transactions not counted yet, found by the count sweep
+			Keys:    bson.D{{Key: "network", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"counted": false}),
+		},
 	}
 
 	if _, err := transactionsCollection.Indexes().CreateMany(ctx, transactionsIndexes); err != nil {
 		return err
 	}
 
+	// Addresses collection indexes
+	addressesCollection := m.GetCollection("addresses")
+
+	addressesIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "address", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	if _, err := addressesCollection.Indexes().CreateMany(ctx, addressesIndexes); err != nil {
+		return err
+	}
+
+	// Token transfers collection indexes
+	tokenTransfersCollection := m.GetCollection("token_transfers")
+
+	// Newest first by token and by holder, sent or received
+	newest := bson.D{{Key: "block_number", Value: -1}, {Key: "log_index", Value: -1}, {Key: "batch_index", Value: -1}}
+	tokenTransfersIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "transaction_hash", Value: 1},
+				{Key: "log_index", Value: 1},
+				{Key: "batch_index", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: append(bson.D{{Key: "token", Value: 1}}, newest...),
+		},
+		{
+			Keys: append(bson.D{{Key: "from", Value: 1}}, newest...),
+		},
+		{
+			Keys: append(bson.D{{Key: "to", Value: 1}}, newest...),
+		},
+		{
+			Keys: bson.D{{Key: "block_hash", Value: 1}},
+		},
+	}
+
+	if _, err := tokenTransfersCollection.Indexes().CreateMany(ctx, tokenTransfersIndexes); err != nil {
+		return err
+	}
+
+	// Tokens collection indexes
+	tokensCollection := m.GetCollection("tokens")
+
+	tokensIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "address", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "refresh_at", Value: 1}},
+		},
+	}
+
+	if _, err := tokensCollection.Indexes().CreateMany(ctx, tokensIndexes); err != nil {
+		return err
+	}
+
 	// Crawler metrics collection indexes
 	metricsCollection := m.GetCollection("crawler_metrics")
 
@@ -172,6 +291,59 @@ func (m *MongoDB) CreateIndexes(ctx context.Context) error {
 		return err
 	}
 
+	// Block retries collection indexes
+	retriesCollection := m.GetCollection("block_retries")
+
+	retriesIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "network", Value: 1}, {Key: "block_number", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "network", Value: 1}, {Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+		},
+	}
+
+	if _, err := retriesCollection.Indexes().CreateMany(ctx, retriesIndexes); err != nil {
+		return err
+	}
+
+	// Work ranges collection indexes
+	workRangesCollection := m.GetCollection("work_ranges")
+
+	workRangesIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "network", Value: 1}, {Key: "start_block", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "network", Value: 1}, {Key: "status", Value: 1}, {Key: "start_block", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "network", Value: 1}, {Key: "end_block", Value: -1}},
+		},
+	}
+
+	if _, err := workRangesCollection.Indexes().CreateMany(ctx, workRangesIndexes); err != nil {
+		return err
+	}
+
+	// Raw block archive (GridFS bucket raw_blocks) indexes
+	rawBlocksCollection := m.GetCollection("raw_blocks.files")
+
+	rawBlocksIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "metadata.network", Value: 1}, {Key: "metadata.number", Value: 1}, {Key: "uploadDate", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "metadata.network", Value: 1}, {Key: "metadata.hash", Value: 1}},
+		},
+	}
+
+	if _, err := rawBlocksCollection.Indexes().CreateMany(ctx, rawBlocksIndexes); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,155 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// manifestDir holds the export manifests, one per network
+const manifestDir = "_manifest"
+
+// FileSink implements ExportSink on the local filesystem. Files are written at
+// <root>/<blocks|transactions>/network=<network>/date=<YYYY-MM-DD>/<kind>-<from>-<to>.<format>,
+// the layout Hive-partitioned readers (Spark, Athena, DuckDB) expect.
+type FileSink struct {
+	root   string
+	format string
+}
+
+// NewFileSink creates a new filesystem export sink
+func NewFileSink(root, format string) (*FileSink, error) {
+	if root == "" {
+		return nil, fmt.Errorf("export directory is required")
+	}
+	if !ValidFormat(format) {
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	return &FileSink{root: root, format: format}, nil
+}
+
+// Format returns the file format
+func (s *FileSink) Format() string {
+	return s.format
+}
+
+// WritePartition writes the blocks file and, when there are any, the
+// transactions file of consecutive blocks of one partition
+func (s *FileSink) WritePartition(ctx context.Context, partition service.ExportPartition, blocks []*service.ExportBlock) ([]string, error) {
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+
+	blockRows := make([]BlockRow, 0, len(blocks))
+	var transactionRows []TransactionRow
+	for _, exported := range blocks {
+		blockRow, err := newBlockRow(exported.Block)
+		if err != nil {
+			return nil, err
+		}
+		blockRows = append(blockRows, blockRow)
+
+		for _, tx := range exported.Transactions {
+			transactionRows = append(transactionRows, newTransactionRow(tx, blockRow))
+		}
+	}
+
+	fromBlock, toBlock := blockRows[0].Number, blockRows[len(blockRows)-1].Number
+
+	blocksFile := s.partitionPath("blocks", partition, fromBlock, toBlock)
+	err := s.writeFile(blocksFile, func(w io.Writer) error {
+		return writeRows(w, s.format, blockRows, blockColumns, BlockRow.record)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", blocksFile, err)
+	}
+	files := []string{blocksFile}
+
+	if len(transactionRows) > 0 {
+		transactionsFile := s.partitionPath("transactions", partition, fromBlock, toBlock)
+		err := s.writeFile(transactionsFile, func(w io.Writer) error {
+			return writeRows(w, s.format, transactionRows, transactionColumns, TransactionRow.record)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", transactionsFile, err)
+		}
+		files = append(files, transactionsFile)
+	}
+
+	return files, nil
+}
+
+// LoadManifest reads the manifest of a network, nil when there is none
+func (s *FileSink) LoadManifest(ctx context.Context, network string) (*entity.ExportManifest, error) {
+	data, err := os.ReadFile(s.manifestPath(network))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read export manifest: %w", err)
+	}
+
+	var manifest entity.ExportManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode export manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// SaveManifest replaces the manifest of a network
+func (s *FileSink) SaveManifest(ctx context.Context, manifest *entity.ExportManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode export manifest: %w", err)
+	}
+
+	return s.writeFile(filepath.Join(manifestDir, manifest.Network+".json"), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// partitionPath returns the path of a partition file, relative to the root
+func (s *FileSink) partitionPath(kind string, partition service.ExportPartition, fromBlock, toBlock uint64) string {
+	return filepath.Join(kind,
+		"network="+partition.Network,
+		"date="+partition.Date.UTC().Format("2006-01-02"),
+		fmt.Sprintf("%s-%d-%d.%s", kind, fromBlock, toBlock, s.format))
+}
+
+func (s *FileSink) manifestPath(network string) string {
+	return filepath.Join(s.root, manifestDir, network+".json")
+}
+
+// writeFile writes a file relative to the root through a temporary file, so
+// readers never see it half written
+func (s *FileSink) writeFile(name string, write func(w io.Writer) error) error {
+	path := filepath.Join(s.root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
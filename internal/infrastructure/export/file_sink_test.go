@@ -0,0 +1,170 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/service"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testExportBlocks() []*service.ExportBlock {
+	to := "0xto"
+	contract := "0xcontract"
+	timestamp := time.Date(2024, 3, 9, 12, 30, 0, 0, time.UTC)
+
+	return []*service.ExportBlock{
+		{
+			Block: &entity.Block{
+				Number: "19400000", Hash: "0xb0", ParentHash: "0xparent", Timestamp: timestamp,
+				Miner: "0xminer", Difficulty: "0", TotalDifficulty: "58750003716598352816469",
+				GasLimit: 30_000_000, GasUsed: 42_000, TransactionHashes: []string{"0xt0", "0xt1"},
+				Network: "ethereum", Status: entity.BlockStatusProcessed,
+			},
+			Transactions: []*entity.Transaction{
+				{Hash: "0xt0", BlockHash: "0xb0", From: "0xfrom", To: &to, Value: "1000000000000000000000",
+					GasPrice: "30000000000", MaxFeePerGas: "40000000000", Gas: 21_000, GasUsed: 21_000,
+					Status: 1, Network: "ethereum"},
+				{Hash: "0xt1", BlockHash: "0xb0", TransactionIndex: 1, From: "0xfrom", Value: "0",
+					GasPrice: "30000000000", ContractAddress: &contract, ReceiptMissing: true, Network: "ethereum"},
+			},
+		},
+		{
+			Block: &entity.Block{
+				Number: "19400001", Hash: "0xb1", ParentHash: "0xb0", Timestamp: timestamp.Add(12 * time.Second),
+				Network: "ethereum", Status: entity.BlockStatusIncomplete,
+			},
+		},
+	}
+}
+
+func writeTestPartition(t *testing.T, format string) (string, []string) {
+	t.Helper()
+	root := t.TempDir()
+
+	sink, err := NewFileSink(root, format)
+	require.NoError(t, err)
+
+	files, err := sink.WritePartition(context.Background(), service.ExportPartition{
+		Network: "ethereum",
+		Date:    time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+	}, testExportBlocks())
+	require.NoError(t, err)
+	return root, files
+}
+
+func TestFileSink_Parquet(t *testing.T) {
+	root, files := writeTestPartition(t, FormatParquet)
+
+	assert.Equal(t, []string{
+		filepath.Join("blocks", "network=ethereum", "date=2024-03-09", "blocks-19400000-19400001.parquet"),
+		filepath.Join("transactions", "network=ethereum", "date=2024-03-09", "transactions-19400000-19400001.parquet"),
+	}, files)
+
+	blocks := readParquet[BlockRow](t, filepath.Join(root, files[0]))
+	require.Len(t, blocks, 2)
+	assert.Equal(t, uint64(19400000), blocks[0].Number)
+	assert.Equal(t, "58750003716598352816469", blocks[0].TotalDifficulty)
+	assert.Equal(t, int32(2), blocks[0].TransactionCount)
+	assert.True(t, blocks[0].Timestamp.Equal(time.Date(2024, 3, 9, 12, 30, 0, 0, time.UTC)))
+	assert.Equal(t, "incomplete", blocks[1].Status)
+
+	transactions := readParquet[TransactionRow](t, filepath.Join(root, files[1]))
+	require.Len(t, transactions, 2)
+	assert.Equal(t, uint64(19400000), transactions[0].BlockNumber)
+	assert.True(t, transactions[0].BlockTimestamp.Equal(blocks[0].Timestamp))
+	assert.Equal(t, "1000000000000000000000", transactions[0].Value)
+	require.NotNil(t, transactions[0].To)
+	assert.Equal(t, "0xto", *transactions[0].To)
+	require.NotNil(t, transactions[0].MaxFeePerGas)
+	assert.Nil(t, transactions[0].MaxPriorityFeePerGas)
+	assert.Nil(t, transactions[1].To)
+	require.NotNil(t, transactions[1].ContractAddress)
+	assert.True(t, transactions[1].ReceiptMissing)
+}
+
+func TestFileSink_CSV(t *testing.T) {
+	root, files := writeTestPartition(t, FormatCSV)
+	require.Len(t, files, 2)
+
+	f, err := os.Open(filepath.Join(root, files[1]))
+	require.NoError(t, err)
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, transactionColumns, records[0])
+	assert.Equal(t, "0xt0", records[1][1])
+	assert.Equal(t, "2024-03-09T12:30:00Z", records[1][4])
+	assert.Equal(t, "", records[2][7], "null to is empty")
+	assert.Equal(t, "true", records[2][19])
+}
+
+func TestFileSink_JSONL(t *testing.T) {
+	root, files := writeTestPartition(t, FormatJSONL)
+	require.Len(t, files, 2)
+
+	data, err := os.ReadFile(filepath.Join(root, files[0]))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var row map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &row))
+	assert.Equal(t, float64(19400001), row["number"])
+	assert.Equal(t, "0xb1", row["hash"])
+}
+
+func TestFileSink_Manifest(t *testing.T) {
+	sink, err := NewFileSink(t.TempDir(), FormatParquet)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	manifest, err := sink.LoadManifest(ctx, "ethereum")
+	require.NoError(t, err)
+	assert.Nil(t, manifest)
+
+	saved := &entity.ExportManifest{
+		Version: entity.ExportManifestVersion,
+		Network: "ethereum",
+		Format:  FormatParquet,
+		Ranges:  []entity.ExportRange{{FromBlock: 1, ToBlock: 1000, Blocks: 1000, Files: []string{"blocks/a.parquet"}}},
+	}
+	require.NoError(t, sink.SaveManifest(ctx, saved))
+
+	manifest, err = sink.LoadManifest(ctx, "ethereum")
+	require.NoError(t, err)
+	require.NotNil(t, manifest)
+	assert.Equal(t, saved.Ranges, manifest.Ranges)
+	last, ok := manifest.LastExportedBlock()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1000), last)
+}
+
+func TestNewFileSink_UnknownFormat(t *testing.T) {
+	_, err := NewFileSink(t.TempDir(), "xlsx")
+	assert.Error(t, err)
+}
+
+func readParquet[T any](t *testing.T, path string) []T {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	info, err := f.Stat()
+	require.NoError(t, err)
+	rows, err := parquet.Read[T](f, info.Size())
+	require.NoError(t, err)
+	return rows
+}
@@ -0,0 +1,175 @@
+package export
+
+import (
+	"ethereum-raw-data-crawler/internal/domain/entity"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// The export schema is stable: columns are only appended, and any change
+// bumps entity.ExportManifestVersion. Wei amounts are decimal strings since
+// they don't fit any Parquet integer or decimal type.
+
+// BlockRow is an exported block
+type BlockRow struct {
+	Network          string    `parquet:"network,dict" json:"network"`
+	Number           uint64    `parquet:"number" json:"number"`
+	Hash             string    `parquet:"hash" json:"hash"`
+	ParentHash       string    `parquet:"parent_hash" json:"parent_hash"`
+	Timestamp        time.Time `parquet:"timestamp,timestamp(millisecond)" json:"timestamp"`
+	Miner            string    `parquet:"miner,dict" json:"miner"`
+	Nonce            uint64    `parquet:"nonce" json:"nonce"`
+	Difficulty       string    `parquet:"difficulty" json:"difficulty"`
+	TotalDifficulty  string    `parquet:"total_difficulty" json:"total_difficulty"`
+	Size             uint64    `parquet:"size" json:"size"`
+	GasLimit         uint64    `parquet:"gas_limit" json:"gas_limit"`
+	GasUsed          uint64    `parquet:"gas_used" json:"gas_used"`
+	TransactionCount int32     `parquet:"transaction_count" json:"transaction_count"`
+	Sha3Uncles       string    `parquet:"sha3_uncles" json:"sha3_uncles"`
+	LogsBloom        string    `parquet:"logs_bloom" json:"logs_bloom"`
+	TransactionsRoot string    `parquet:"transactions_root" json:"transactions_root"`
+	StateRoot        string    `parquet:"state_root" json:"state_root"`
+	ReceiptsRoot     string    `parquet:"receipts_root" json:"receipts_root"`
+	ExtraData        string    `parquet:"extra_data" json:"extra_data"`
+	Status           string    `parquet:"status,dict" json:"status"`
+}
+
+// blockColumns are the CSV columns in the order of BlockRow.record
+var blockColumns = []string{
+	"network", "number", "hash", "parent_hash", "timestamp", "miner", "nonce", "difficulty",
+	"total_difficulty", "size", "gas_limit", "gas_used", "transaction_count", "sha3_uncles",
+	"logs_bloom", "transactions_root", "state_root", "receipts_root", "extra_data", "status",
+}
+
+// newBlockRow converts a stored block to its exported row
+func newBlockRow(block *entity.Block) (BlockRow, error) {
+	number, err := strconv.ParseUint(block.Number, 10, 64)
+	if err != nil {
+		return BlockRow{}, fmt.Errorf("invalid block number %q: %w", block.Number, err)
+	}
+
+	return BlockRow{
+		Network:          block.Network,
+		Number:           number,
+		Hash:             block.Hash,
+		ParentHash:       block.ParentHash,
+		Timestamp:        block.Timestamp.UTC(),
+		Miner:            block.Miner,
+		Nonce:            block.Nonce,
+		Difficulty:       block.Difficulty,
+		TotalDifficulty:  block.TotalDifficulty,
+		Size:             block.Size,
+		GasLimit:         block.GasLimit,
+		GasUsed:          block.GasUsed,
+		TransactionCount: int32(len(block.TransactionHashes)),
+		Sha3Uncles:       block.Sha3Uncles,
+		LogsBloom:        block.LogsBloom,
+		TransactionsRoot: block.TransactionsRoot,
+		StateRoot:        block.StateRoot,
+		ReceiptsRoot:     block.ReceiptsRoot,
+		ExtraData:        block.ExtraData,
+		Status:           string(block.Status),
+	}, nil
+}
+
+// record returns the CSV values of the row
+func (r BlockRow) record() []string {
+	return []string{
+		r.Network, formatUint(r.Number), r.Hash, r.ParentHash, formatTime(r.Timestamp), r.Miner,
+		formatUint(r.Nonce), r.Difficulty, r.TotalDifficulty, formatUint(r.Size), formatUint(r.GasLimit),
+		formatUint(r.GasUsed), strconv.Itoa(int(r.TransactionCount)), r.Sha3Uncles, r.LogsBloom,
+		r.TransactionsRoot, r.StateRoot, r.ReceiptsRoot, r.ExtraData, r.Status,
+	}
+}
+
+// TransactionRow is an exported transaction, with the timestamp of its block
+type TransactionRow struct {
+	Network              string    `parquet:"network,dict" json:"network"`
+	Hash                 string    `parquet:"hash" json:"hash"`
+	BlockNumber          uint64    `parquet:"block_number" json:"block_number"`
+	BlockHash            string    `parquet:"block_hash" json:"block_hash"`
+	BlockTimestamp       time.Time `parquet:"block_timestamp,timestamp(millisecond)" json:"block_timestamp"`
+	TransactionIndex     uint32    `parquet:"transaction_index" json:"transaction_index"`
+	From                 string    `parquet:"from" json:"from"`
+	To                   *string   `parquet:"to" json:"to"`
+	Value                string    `parquet:"value" json:"value"`
+	Gas                  uint64    `parquet:"gas" json:"gas"`
+	GasPrice             string    `parquet:"gas_price" json:"gas_price"`
+	MaxFeePerGas         *string   `parquet:"max_fee_per_gas" json:"max_fee_per_gas"`
+	MaxPriorityFeePerGas *string   `parquet:"max_priority_fee_per_gas" json:"max_priority_fee_per_gas"`
+	GasUsed              uint64    `parquet:"gas_used" json:"gas_used"`
+	CumulativeGasUsed    uint64    `parquet:"cumulative_gas_used" json:"cumulative_gas_used"`
+	Nonce                uint64    `parquet:"nonce" json:"nonce"`
+	Input                string    `parquet:"input" json:"input"`
+	Status               uint32    `parquet:"status" json:"status"`
+	ContractAddress      *string   `parquet:"contract_address" json:"contract_address"`
+	ReceiptMissing       bool      `parquet:"receipt_missing" json:"receipt_missing"`
+}
+
+// transactionColumns are the CSV columns in the order of TransactionRow.record
+var transactionColumns = []string{
+	"network", "hash", "block_number", "block_hash", "block_timestamp", "transaction_index", "from",
+	"to", "value", "gas", "gas_price", "max_fee_per_gas", "max_priority_fee_per_gas", "gas_used",
+	"cumulative_gas_used", "nonce", "input", "status", "contract_address", "receipt_missing",
+}
+
+// newTransactionRow converts a stored transaction to its exported row
+func newTransactionRow(tx *entity.Transaction, block BlockRow) TransactionRow {
+	return TransactionRow{
+		Network:              tx.Network,
+		Hash:                 tx.Hash,
+		BlockNumber:          block.Number,
+		BlockHash:            block.Hash,
+		BlockTimestamp:       block.Timestamp,
+		TransactionIndex:     uint32(tx.TransactionIndex),
+		From:                 tx.From,
+		To:                   tx.To,
+		Value:                string(tx.Value),
+		Gas:                  tx.Gas,
+		GasPrice:             string(tx.GasPrice),
+		MaxFeePerGas:         optionalString(string(tx.MaxFeePerGas)),
+		MaxPriorityFeePerGas: optionalString(string(tx.MaxPriorityFeePerGas)),
+		GasUsed:              tx.GasUsed,
+		CumulativeGasUsed:    tx.CumulativeGasUsed,
+		Nonce:                tx.Nonce,
+		Input:                tx.Data,
+		Status:               uint32(tx.Status),
+		ContractAddress:      tx.ContractAddress,
+		ReceiptMissing:       tx.ReceiptMissing,
+	}
+}
+
+// record returns the CSV values of the row, nulls are empty
+func (r TransactionRow) record() []string {
+	return []string{
+		r.Network, r.Hash, formatUint(r.BlockNumber), r.BlockHash, formatTime(r.BlockTimestamp),
+		formatUint(uint64(r.TransactionIndex)), r.From, nullableString(r.To), r.Value, formatUint(r.Gas),
+		r.GasPrice, nullableString(r.MaxFeePerGas), nullableString(r.MaxPriorityFeePerGas),
+		formatUint(r.GasUsed), formatUint(r.CumulativeGasUsed), formatUint(r.Nonce), r.Input,
+		formatUint(uint64(r.Status)), nullableString(r.ContractAddress), strconv.FormatBool(r.ReceiptMissing),
+	}
+}
+
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// optionalString maps an empty string to null
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func nullableString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Export formats
+const (
+	FormatParquet = "parquet"
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+)
+
+// ValidFormat reports whether a format can be exported
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatParquet, FormatCSV, FormatJSONL:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeRows encodes rows in a format. CSV files get a header of the columns,
+// with the values of each row given by record.
+func writeRows[T any](w io.Writer, format string, rows []T, columns []string, record func(T) []string) error {
+	switch format {
+	case FormatParquet:
+		writer := parquet.NewGenericWriter[T](w, parquet.Compression(&parquet.Snappy))
+		if _, err := writer.Write(rows); err != nil {
+			return err
+		}
+		return writer.Close()
+
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := writer.Write(record(row)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case FormatJSONL:
+		buffered := bufio.NewWriter(w)
+		encoder := json.NewEncoder(buffered)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+		}
+		return buffered.Flush()
+
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
@@ -2,38 +2,30 @@ package messaging
 
 import (
 	"context"
-	"encoding/json"
 	"ethereum-raw-data-crawler/internal/domain/entity"
+	"ethereum-raw-data-crawler/internal/domain/service"
 	"ethereum-raw-data-crawler/internal/infrastructure/config"
 	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"ethereum-raw-data-crawler/pkg/events"
+	eventsv1 "ethereum-raw-data-crawler/pkg/events/v1"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// TransactionEvent represents a transaction event to be published to NATS
-type TransactionEvent struct {
-	Hash        string    `json:"hash"`
-	From        string    `json:"from"`
-	To          string    `json:"to"`
-	Value       string    `json:"value"`
-	Data        string    `json:"data"`
-	BlockNumber string    `json:"block_number"`
-	BlockHash   string    `json:"block_hash"`
-	Timestamp   time.Time `json:"timestamp"`
-	GasUsed     string    `json:"gas_used"`
-	GasPrice    string    `json:"gas_price"`
-	Network     string    `json:"network"`
-}
-
 // NATSClient handles NATS JetStream operations and implements MessagingService interface
 type NATSClient struct {
 	conn      *nats.Conn
 	js        nats.JetStreamContext
 	config    *config.NATSConfig
 	logger    *logger.Logger
+	encoding  events.Encoding
 	isRunning bool
 }
 
@@ -57,7 +49,15 @@ func (n *NATSClient) Connect(ctx context.Context) error {
 		return nil
 	}
 
-	n.logger.Info("Connecting to NATS server", zap.String("url", n.config.URL))
+	encoding, err := events.ParseEncoding(n.config.Encoding)
+	if err != nil {
+		return err
+	}
+	n.encoding = encoding
+
+	n.logger.Info("Connecting to NATS server",
+		zap.String("url", n.config.URL),
+		zap.String("encoding", string(n.encoding)))
 
 	// Connect to NATS
 	opts := []nats.Option{
@@ -124,7 +124,13 @@ func (n *NATSClient) IsConnected() bool {
 // setupStream creates or updates the JetStream stream
 func (n *NATSClient) setupStream(ctx context.Context) error {
 	streamName := n.config.StreamName
-	subject := fmt.Sprintf("%s.events", n.config.SubjectPrefix)
+	subject := n.eventsSubject()
+	subjects := []string{subject, n.tokenTransfersSubject()}
+
+	retention, err := parseRetentionPolicy(n.config.StreamRetention)
+	if err != nil {
+		return err
+	}
 
 	// Check if stream exists
 	stream, err := n.js.StreamInfo(streamName)
@@ -136,9 +142,9 @@ func (n *NATSClient) setupStream(ctx context.Context) error {
 
 		streamConfig := &nats.StreamConfig{
 			Name:       streamName,
-			Subjects:   []string{subject},
+			Subjects:   subjects,
 			Storage:    nats.FileStorage,
-			Retention:  nats.WorkQueuePolicy,
+			Retention:  retention,
 			MaxMsgs:    1000000,            // 1M messages
 			MaxBytes:   1024 * 1024 * 1024, // 1GB
 			MaxAge:     24 * time.Hour,     // 24 hours
@@ -156,11 +162,52 @@ func (n *NATSClient) setupStream(ctx context.Context) error {
 		n.logger.Info("JetStream stream already exists",
 			zap.String("stream", streamName),
 			zap.Uint64("messages", stream.State.Msgs))
+		return n.addStreamSubjects(stream.Config, subjects)
 	}
 
 	return nil
 }
 
+// addStreamSubjects adds the subjects a stream created by an older version
+// doesn't capture yet
+func (n *NATSClient) addStreamSubjects(streamConfig nats.StreamConfig, subjects []string) error {
+	missing := false
+	for _, subject := range subjects {
+		if !slices.Contains(streamConfig.Subjects, subject) {
+			streamConfig.Subjects = append(streamConfig.Subjects, subject)
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	n.logger.Info("Adding subjects to JetStream stream",
+		zap.String("stream", streamConfig.Name),
+		zap.Strings("subjects", streamConfig.Subjects))
+	if _, err := n.js.UpdateStream(&streamConfig); err != nil {
+		n.logger.Error("Failed to update stream", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// parseRetentionPolicy parses the configured stream retention policy.
+// Limits retention keeps events after they are consumed, so several consumers
+// can read the stream independently and replay it from any block.
+func parseRetentionPolicy(name string) (nats.RetentionPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "workqueue":
+		return nats.WorkQueuePolicy, nil
+	case "limits":
+		return nats.LimitsPolicy, nil
+	case "interest":
+		return nats.InterestPolicy, nil
+	default:
+		return 0, fmt.Errorf("unsupported stream retention policy: %s", name)
+	}
+}
+
 // PublishTransaction publishes a transaction event to NATS JetStream
 func (n *NATSClient) PublishTransaction(ctx context.Context, tx *entity.Transaction) error {
 	if !n.IsConnected() {
@@ -171,38 +218,14 @@ func (n *NATSClient) PublishTransaction(ctx context.Context, tx *entity.Transact
 		return fmt.Errorf("NATS client is not connected")
 	}
 
-	// Convert transaction to event
-	var toAddress string
-	if tx.To != nil {
-		toAddress = *tx.To
-	}
-
-	event := &TransactionEvent{
-		Hash:        tx.Hash,
-		From:        tx.From,
-		To:          toAddress,
-		Value:       tx.Value,
-		Data:        tx.Data,
-		BlockNumber: tx.BlockNumber,
-		BlockHash:   tx.BlockHash,
-		Timestamp:   time.Now(),
-		GasUsed:     fmt.Sprintf("%d", tx.GasUsed),
-		GasPrice:    tx.GasPrice,
-		Network:     tx.Network,
-	}
-
-	// Serialize to JSON
-	data, err := json.Marshal(event)
+	msg, err := n.newTransactionMessage(tx)
 	if err != nil {
 		n.logger.Error("Failed to marshal transaction event", zap.Error(err))
 		return fmt.Errorf("failed to marshal transaction event: %w", err)
 	}
 
-	// Publish to JetStream
-	subject := fmt.Sprintf("%s.events", n.config.SubjectPrefix)
-
 	// Use transaction hash as message ID for deduplication
-	_, err = n.js.Publish(subject, data, nats.MsgId(tx.Hash))
+	_, err = n.js.PublishMsg(msg, nats.MsgId(tx.Hash))
 	if err != nil {
 		n.logger.Error("Failed to publish transaction event",
 			zap.String("hash", tx.Hash),
@@ -212,11 +235,86 @@ func (n *NATSClient) PublishTransaction(ctx context.Context, tx *entity.Transact
 
 	n.logger.Debug("Published transaction event",
 		zap.String("hash", tx.Hash),
-		zap.String("subject", subject))
+		zap.String("subject", msg.Subject))
 
 	return nil
 }
 
+// newTransactionMessage builds the NATS message for a transaction event,
+// including the schema headers consumers use to decode the payload
+func (n *NATSClient) newTransactionMessage(tx *entity.Transaction) (*nats.Msg, error) {
+	event := newTransactionEvent(tx)
+
+	encoding := n.encoding
+	if encoding == "" {
+		encoding = events.EncodingJSON
+	}
+
+	data, err := events.Marshal(encoding, event)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(n.eventsSubject())
+	msg.Data = data
+	msg.Header.Set(events.HeaderSchemaVersion, events.SchemaVersion)
+	msg.Header.Set(events.HeaderContentType, encoding.ContentType())
+	msg.Header.Set(events.HeaderEventType, events.EventTypeTransaction)
+	msg.Header.Set(events.HeaderNetwork, tx.Network)
+	msg.Header.Set(events.HeaderBlockNumber, strconv.FormatUint(event.BlockNumber, 10))
+
+	return msg, nil
+}
+
+// eventsSubject returns the subject transaction events are published on
+func (n *NATSClient) eventsSubject() string {
+	return fmt.Sprintf("%s.events", n.config.SubjectPrefix)
+}
+
+// tokenTransfersSubject returns the subject token transfer events are
+// published on. They have their own subject so transaction consumers don't
+// receive them.
+func (n *NATSClient) tokenTransfersSubject() string {
+	return fmt.Sprintf("%s.token_transfers", n.config.SubjectPrefix)
+}
+
+// newTransactionEvent converts a transaction entity to its published event
+func newTransactionEvent(tx *entity.Transaction) *eventsv1.TransactionEvent {
+	var toAddress string
+	if tx.To != nil {
+		toAddress = *tx.To
+	}
+
+	var contractAddress string
+	if tx.ContractAddress != nil {
+		contractAddress = *tx.ContractAddress
+	}
+
+	// Block numbers are stored as decimal strings; an unparsable value is published as 0
+	blockNumber, _ := strconv.ParseUint(tx.BlockNumber, 10, 64)
+
+	return &eventsv1.TransactionEvent{
+		Hash:                 tx.Hash,
+		From:                 tx.From,
+		To:                   toAddress,
+		Value:                string(tx.Value),
+		Data:                 tx.Data,
+		BlockNumber:          blockNumber,
+		BlockHash:            tx.BlockHash,
+		Timestamp:            timestamppb.New(time.Now()),
+		GasUsed:              tx.GasUsed,
+		GasPrice:             string(tx.GasPrice),
+		Network:              tx.Network,
+		TransactionIndex:     uint32(tx.TransactionIndex),
+		Nonce:                tx.Nonce,
+		Gas:                  tx.Gas,
+		Status:               tx.Status,
+		ContractAddress:      contractAddress,
+		MaxFeePerGas:         string(tx.MaxFeePerGas),
+		MaxPriorityFeePerGas: string(tx.MaxPriorityFeePerGas),
+	}
+}
+
 // PublishTransactions publishes multiple transaction events to NATS JetStream
 func (n *NATSClient) PublishTransactions(ctx context.Context, transactions []*entity.Transaction) error {
 	if !n.IsConnected() {
@@ -262,6 +360,96 @@ func (n *NATSClient) PublishTransactions(ctx context.Context, transactions []*en
 	return nil
 }
 
+// PublishTokenTransfers publishes token transfer events to NATS JetStream
+func (n *NATSClient) PublishTokenTransfers(ctx context.Context, transfers []*entity.TokenTransfer) error {
+	if !n.IsConnected() {
+		if !n.config.Enabled {
+			return nil
+		}
+		return fmt.Errorf("NATS client is not connected")
+	}
+
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	failed := 0
+	for _, transfer := range transfers {
+		if err := n.publishTokenTransfer(transfer); err != nil {
+			failed++
+			n.logger.Error("Failed to publish token transfer",
+				zap.String("hash", transfer.TransactionHash),
+				zap.Uint("log_index", transfer.LogIndex),
+				zap.Error(err))
+		}
+	}
+
+	n.logger.Debug("Finished publishing token transfer events",
+		zap.Int("total", len(transfers)),
+		zap.Int("errors", failed))
+
+	if failed > 0 {
+		return fmt.Errorf("failed to publish %d out of %d token transfers", failed, len(transfers))
+	}
+	return nil
+}
+
+// publishTokenTransfer publishes a token transfer event
+func (n *NATSClient) publishTokenTransfer(transfer *entity.TokenTransfer) error {
+	msg, err := n.newTokenTransferMessage(transfer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token transfer event: %w", err)
+	}
+
+	// Transaction hash, log index and batch index identify a transfer for deduplication
+	msgID := fmt.Sprintf("%s:%d:%d", transfer.TransactionHash, transfer.LogIndex, transfer.BatchIndex)
+	if _, err := n.js.PublishMsg(msg, nats.MsgId(msgID)); err != nil {
+		return fmt.Errorf("failed to publish token transfer event: %w", err)
+	}
+	return nil
+}
+
+// newTokenTransferMessage builds the NATS message for a token transfer event
+func (n *NATSClient) newTokenTransferMessage(transfer *entity.TokenTransfer) (*nats.Msg, error) {
+	event := &eventsv1.TokenTransferEvent{
+		Token:            transfer.Token,
+		Standard:         string(transfer.Standard),
+		From:             transfer.From,
+		To:               transfer.To,
+		Operator:         transfer.Operator,
+		Amount:           transfer.Amount,
+		TokenId:          transfer.TokenID,
+		TransactionHash:  transfer.TransactionHash,
+		TransactionIndex: uint32(transfer.TransactionIndex),
+		LogIndex:         uint32(transfer.LogIndex),
+		BatchIndex:       uint32(transfer.BatchIndex),
+		BlockNumber:      transfer.BlockNumber,
+		BlockHash:        transfer.BlockHash,
+		Network:          transfer.Network,
+		Timestamp:        timestamppb.New(time.Now()),
+	}
+
+	encoding := n.encoding
+	if encoding == "" {
+		encoding = events.EncodingJSON
+	}
+
+	data, err := events.Marshal(encoding, event)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(n.tokenTransfersSubject())
+	msg.Data = data
+	msg.Header.Set(events.HeaderSchemaVersion, events.SchemaVersion)
+	msg.Header.Set(events.HeaderContentType, encoding.ContentType())
+	msg.Header.Set(events.HeaderEventType, events.EventTypeTokenTransfer)
+	msg.Header.Set(events.HeaderNetwork, transfer.Network)
+	msg.Header.Set(events.HeaderBlockNumber, strconv.FormatUint(transfer.BlockNumber, 10))
+
+	return msg, nil
+}
+
 // GetStreamInfo returns information about the JetStream stream
 func (n *NATSClient) GetStreamInfo() (interface{}, error) {
 	if !n.IsConnected() {
@@ -270,3 +458,47 @@ func (n *NATSClient) GetStreamInfo() (interface{}, error) {
 
 	return n.js.StreamInfo(n.config.StreamName)
 }
+
+// requestQueueGroup spreads requests on a subject over the connected instances
+const requestQueueGroup = "ethereum-crawler"
+
+// HandleRequests replies to core NATS requests on subject. Each request is
+// handled in its own goroutine, a slow request doesn't hold up the others.
+func (n *NATSClient) HandleRequests(subject string, handler service.RequestHandler) (func() error, error) {
+	if !n.IsConnected() {
+		return nil, fmt.Errorf("NATS client is not connected")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := n.conn.QueueSubscribe(subject, requestQueueGroup, func(msg *nats.Msg) {
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					n.logger.Error("Panic recovered in request handler",
+						zap.String("subject", subject),
+						zap.Any("panic", r),
+						zap.Stack("stack"))
+				}
+			}()
+
+			reply := handler(ctx, msg.Data)
+			if msg.Reply == "" {
+				return
+			}
+			if err := msg.Respond(reply); err != nil {
+				n.logger.Warn("Failed to reply to request", zap.String("subject", subject), zap.Error(err))
+			}
+		}()
+	})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+
+	n.logger.Info("Handling requests", zap.String("subject", subject))
+
+	return func() error {
+		cancel()
+		return sub.Unsubscribe()
+	}, nil
+}
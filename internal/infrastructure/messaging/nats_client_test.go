@@ -5,11 +5,16 @@ import (
 	"ethereum-raw-data-crawler/internal/domain/entity"
 	"ethereum-raw-data-crawler/internal/infrastructure/config"
 	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"ethereum-raw-data-crawler/pkg/events"
+	eventsv1 "ethereum-raw-data-crawler/pkg/events/v1"
 	"testing"
 	"time"
 
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -47,6 +52,11 @@ func (m *MockNATSClient) PublishTransactions(ctx context.Context, transactions [
 	return args.Error(0)
 }
 
+func (m *MockNATSClient) PublishTokenTransfers(ctx context.Context, transfers []*entity.TokenTransfer) error {
+	args := m.Called(ctx, transfers)
+	return args.Error(0)
+}
+
 func (m *MockNATSClient) GetStreamInfo() (interface{}, error) {
 	args := m.Called()
 	return args.Get(0), args.Error(1)
@@ -163,6 +173,79 @@ func TestNATSClient_NotConnected(t *testing.T) {
 	_, err = client.GetStreamInfo()
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not connected")
+
+	// HandleRequests should fail when not connected
+	_, err = client.HandleRequests("transactions.crawl.requests", func(ctx context.Context, data []byte) []byte { return data })
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not connected")
+}
+
+func TestNATSClient_HandleRequests(t *testing.T) {
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+	t.Cleanup(srv.Shutdown)
+
+	logger, _ := logger.NewLogger(&config.Config{
+		App: config.AppConfig{LogLevel: "error"},
+	})
+	client := NewNATSClient(&config.NATSConfig{
+		URL:             srv.ClientURL(),
+		StreamName:      "TRANSACTIONS",
+		SubjectPrefix:   "transactions",
+		ConnectTimeout:  5 * time.Second,
+		Enabled:         true,
+		Encoding:        "json",
+		StreamRetention: "limits",
+	}, logger)
+	require.NoError(t, client.Connect(context.Background()))
+	t.Cleanup(func() { client.Disconnect() })
+
+	release := make(chan struct{})
+	stop, err := client.HandleRequests("transactions.crawl.requests", func(ctx context.Context, data []byte) []byte {
+		if string(data) == "slow" {
+			<-release
+		}
+		return append([]byte("done:"), data...)
+	})
+	require.NoError(t, err)
+
+	requester, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(requester.Close)
+
+	// A slow request doesn't hold up the next one
+	slow := make(chan *nats.Msg, 1)
+	go func() {
+		msg, err := requester.Request("transactions.crawl.requests", []byte("slow"), 5*time.Second)
+		if err == nil {
+			slow <- msg
+		}
+	}()
+
+	msg, err := requester.Request("transactions.crawl.requests", []byte("fast"), 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "done:fast", string(msg.Data))
+
+	close(release)
+	select {
+	case msg := <-slow:
+		assert.Equal(t, "done:slow", string(msg.Data))
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow request got no reply")
+	}
+
+	require.NoError(t, stop())
+	_, err = requester.Request("transactions.crawl.requests", []byte("late"), 200*time.Millisecond)
+	assert.ErrorIs(t, err, nats.ErrNoResponders)
 }
 
 func TestMockNATSClient(t *testing.T) {
@@ -221,3 +304,112 @@ func createTestTransaction() *entity.Transaction {
 		TxStatus:          entity.TransactionStatusProcessed,
 	}
 }
+
+func TestNATSClient_NewTransactionMessage(t *testing.T) {
+	cfg := &config.NATSConfig{
+		SubjectPrefix: "test",
+		Enabled:       true,
+	}
+
+	logger, _ := logger.NewLogger(&config.Config{
+		App: config.AppConfig{LogLevel: "info"},
+	})
+
+	client := NewNATSClient(cfg, logger)
+	client.encoding = events.EncodingProtobuf
+	tx := createTestTransaction()
+
+	msg, err := client.newTransactionMessage(tx)
+	assert.NoError(t, err)
+	assert.Equal(t, "test.events", msg.Subject)
+	assert.Equal(t, events.SchemaVersion, msg.Header.Get(events.HeaderSchemaVersion))
+	assert.Equal(t, events.ContentTypeProtobuf, msg.Header.Get(events.HeaderContentType))
+	assert.Equal(t, events.EventTypeTransaction, msg.Header.Get(events.HeaderEventType))
+	assert.Equal(t, "mainnet", msg.Header.Get(events.HeaderNetwork))
+	assert.Equal(t, "12345", msg.Header.Get(events.HeaderBlockNumber))
+
+	var event eventsv1.TransactionEvent
+	assert.NoError(t, events.Unmarshal(msg.Header.Get(events.HeaderContentType), msg.Data, &event))
+	assert.Equal(t, tx.Hash, event.Hash)
+	assert.Equal(t, *tx.To, event.To)
+	assert.Equal(t, uint64(12345), event.BlockNumber)
+	assert.Equal(t, tx.GasUsed, event.GasUsed)
+}
+
+func TestNATSClient_PublishTokenTransfers(t *testing.T) {
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+	t.Cleanup(srv.Shutdown)
+
+	// A stream created before token transfers were published
+	nc, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "TRANSACTIONS", Subjects: []string{"transactions.events"}})
+	require.NoError(t, err)
+
+	logger, _ := logger.NewLogger(&config.Config{
+		App: config.AppConfig{LogLevel: "error"},
+	})
+	client := NewNATSClient(&config.NATSConfig{
+		URL:            srv.ClientURL(),
+		StreamName:     "TRANSACTIONS",
+		SubjectPrefix:  "transactions",
+		ConnectTimeout: 5 * time.Second,
+		Enabled:        true,
+		Encoding:       "protobuf",
+	}, logger)
+	require.NoError(t, client.Connect(context.Background()))
+	t.Cleanup(func() { client.Disconnect() })
+
+	info, err := js.StreamInfo("TRANSACTIONS")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"transactions.events", "transactions.token_transfers"}, info.Config.Subjects)
+
+	transfer := &entity.TokenTransfer{
+		Token:           "0xtoken",
+		Standard:        entity.TokenStandardERC1155,
+		From:            "0xfrom",
+		To:              "0xto",
+		Operator:        "0xoperator",
+		Amount:          "3",
+		TokenID:         "7",
+		TransactionHash: "0xtx",
+		LogIndex:        4,
+		BatchIndex:      1,
+		BlockNumber:     12345,
+		Network:         "mainnet",
+	}
+	// Published twice, deduplicated by transaction, log and batch index
+	require.NoError(t, client.PublishTokenTransfers(context.Background(), []*entity.TokenTransfer{transfer}))
+	require.NoError(t, client.PublishTokenTransfers(context.Background(), []*entity.TokenTransfer{transfer}))
+
+	sub, err := js.SubscribeSync("transactions.token_transfers", nats.DeliverAll())
+	require.NoError(t, err)
+	msg, err := sub.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, events.EventTypeTokenTransfer, msg.Header.Get(events.HeaderEventType))
+	assert.Equal(t, "12345", msg.Header.Get(events.HeaderBlockNumber))
+
+	var event eventsv1.TokenTransferEvent
+	require.NoError(t, events.Unmarshal(msg.Header.Get(events.HeaderContentType), msg.Data, &event))
+	assert.Equal(t, "0xtoken", event.Token)
+	assert.Equal(t, "erc1155", event.Standard)
+	assert.Equal(t, "0xoperator", event.Operator)
+	assert.Equal(t, "7", event.TokenId)
+	assert.Equal(t, uint32(1), event.BatchIndex)
+
+	_, err = sub.NextMsg(200 * time.Millisecond)
+	assert.ErrorIs(t, err, nats.ErrTimeout)
+}
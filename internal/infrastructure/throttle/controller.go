@@ -0,0 +1,395 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"go.uber.org/zap"
+)
+
+// Controller tunes crawler concurrency and RPC request pacing from observed
+// RPC latency, error rate and rate limit responses (AIMD). Every adjust
+// interval it adds one worker and shortens the request delay by one step while
+// the node keeps up, and halves the workers and doubles the delay as soon as
+// the node pushes back.
+type Controller struct {
+	logger  *logger.Logger
+	enabled bool
+
+	// Bounds
+	minWorkers     int
+	maxWorkers     int
+	minDelay       time.Duration
+	maxDelay       time.Duration
+	delayStep      time.Duration
+	targetLatency  time.Duration
+	maxErrorRate   float64
+	adjustInterval time.Duration
+
+	mu          sync.Mutex
+	workers     int
+	delay       time.Duration
+	inFlight    int
+	priority    int           // Priority callers waiting for a slot
+	changed     chan struct{} // Closed when a worker slot may have become free
+	nextRequest time.Time
+
+	// Current window
+	windowStart       time.Time
+	windowCalls       int
+	windowErrors      int
+	windowRateLimited int
+	windowLatency     time.Duration
+	lastAdjustment    time.Time
+
+	// Totals
+	totalCalls       uint64
+	totalErrors      uint64
+	totalRateLimited uint64
+	increases        uint64
+	decreases        uint64
+	lastLatency      time.Duration
+	lastErrorRate    float64
+}
+
+// Stats is a snapshot of the controller state
+type Stats struct {
+	Enabled          bool          `json:"enabled"`
+	Workers          int           `json:"workers"`
+	InFlight         int           `json:"in_flight"`
+	PriorityWaiting  int           `json:"priority_waiting"`
+	RequestDelay     time.Duration `json:"request_delay"`
+	AverageLatency   time.Duration `json:"average_latency"` // Of the last adjusted window
+	ErrorRate        float64       `json:"error_rate"`      // Of the last adjusted window
+	RPCCalls         uint64        `json:"rpc_calls"`
+	RPCErrors        uint64        `json:"rpc_errors"`
+	RateLimitedCalls uint64        `json:"rate_limited_calls"`
+	Increases        uint64        `json:"increases"`
+	Decreases        uint64        `json:"decreases"`
+}
+
+// NewController creates a controller bounded by the crawler configuration.
+// When adaptive concurrency is disabled it keeps ConcurrentWorkers and the
+// Ethereum rate limit fixed.
+func NewController(cfg *config.Config, logger *logger.Logger) *Controller {
+	crawler := cfg.Crawler
+
+	c := &Controller{
+		logger:         logger.WithComponent("throttle"),
+		enabled:        crawler.AdaptiveConcurrency,
+		minWorkers:     crawler.MinWorkers,
+		maxWorkers:     crawler.ConcurrentWorkers,
+		minDelay:       crawler.MinRequestDelay,
+		maxDelay:       crawler.MaxRequestDelay,
+		delayStep:      crawler.RequestDelayStep,
+		targetLatency:  crawler.TargetLatency,
+		maxErrorRate:   crawler.MaxErrorRate,
+		adjustInterval: crawler.AdjustInterval,
+		workers:        crawler.ConcurrentWorkers,
+		delay:          cfg.Ethereum.RateLimit,
+		changed:        make(chan struct{}),
+		windowStart:    time.Now(),
+	}
+
+	if c.maxWorkers <= 0 {
+		c.maxWorkers = 1
+	}
+	if c.minWorkers <= 0 || c.minWorkers > c.maxWorkers {
+		c.minWorkers = 1
+	}
+	if c.maxDelay <= 0 {
+		c.maxDelay = 5 * time.Second
+	}
+	if c.minDelay > c.maxDelay {
+		c.minDelay = c.maxDelay
+	}
+	if c.delayStep <= 0 {
+		c.delayStep = 50 * time.Millisecond
+	}
+	if c.targetLatency <= 0 {
+		c.targetLatency = 2 * time.Second
+	}
+	if c.maxErrorRate <= 0 {
+		c.maxErrorRate = 0.1
+	}
+	if c.adjustInterval <= 0 {
+		c.adjustInterval = 10 * time.Second
+	}
+	if c.enabled {
+		c.workers = clampInt(c.workers, c.minWorkers, c.maxWorkers)
+		c.delay = clampDuration(c.delay, c.minDelay, c.maxDelay)
+	}
+
+	return c
+}
+
+// Acquire waits for a free worker slot under the current limit. Slots go to
+// priority callers first.
+func (c *Controller) Acquire(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		if c.inFlight < c.workers && c.priority == 0 {
+			c.inFlight++
+			c.mu.Unlock()
+			return nil
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// AcquirePriority waits for a free worker slot ahead of routine callers. While
+// a priority caller waits, Acquire hands out no slots.
+func (c *Controller) AcquirePriority(ctx context.Context) error {
+	c.mu.Lock()
+	c.priority++
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.priority--
+		if c.priority == 0 {
+			c.notify() // Routine callers may take slots again
+		}
+		c.mu.Unlock()
+	}()
+
+	for {
+		c.mu.Lock()
+		if c.inFlight < c.workers {
+			c.inFlight++
+			c.mu.Unlock()
+			return nil
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release frees a worker slot taken by Acquire or AcquirePriority
+func (c *Controller) Release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	c.notify()
+}
+
+// Wait paces RPC requests so consecutive requests are at least the current
+// request delay apart
+func (c *Controller) Wait(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	at := c.nextRequest
+	if at.Before(now) {
+		at = now
+	}
+	c.nextRequest = at.Add(c.delay)
+	c.mu.Unlock()
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Observe records the outcome of an RPC request
+func (c *Controller) Observe(latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.totalCalls++
+	c.windowCalls++
+	c.windowLatency += latency
+
+	rateLimited := false
+	if isFailure(err) {
+		c.totalErrors++
+		c.windowErrors++
+		if IsRateLimitError(err) {
+			rateLimited = true
+			c.totalRateLimited++
+			c.windowRateLimited++
+		}
+	}
+
+	if !c.enabled {
+		return
+	}
+
+	// A rate limit response ends the window early, the node asked us to slow down
+	now := time.Now()
+	if now.Sub(c.windowStart) >= c.adjustInterval ||
+		(rateLimited && now.Sub(c.lastAdjustment) >= c.adjustInterval/4) {
+		c.adjust(now)
+	}
+}
+
+// GetStats returns a snapshot of the controller state
+func (c *Controller) GetStats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Enabled:          c.enabled,
+		Workers:          c.workers,
+		InFlight:         c.inFlight,
+		PriorityWaiting:  c.priority,
+		RequestDelay:     c.delay,
+		AverageLatency:   c.lastLatency,
+		ErrorRate:        c.lastErrorRate,
+		RPCCalls:         c.totalCalls,
+		RPCErrors:        c.totalErrors,
+		RateLimitedCalls: c.totalRateLimited,
+		Increases:        c.increases,
+		Decreases:        c.decreases,
+	}
+}
+
+// adjust applies an AIMD step from the current window. Must be called with mu held.
+func (c *Controller) adjust(now time.Time) {
+	calls := c.windowCalls
+	if calls == 0 {
+		c.windowStart = now
+		return
+	}
+
+	avgLatency := c.windowLatency / time.Duration(calls)
+	errorRate := float64(c.windowErrors) / float64(calls)
+	rateLimited := c.windowRateLimited
+
+	c.lastLatency = avgLatency
+	c.lastErrorRate = errorRate
+	c.windowStart = now
+	c.windowCalls = 0
+	c.windowErrors = 0
+	c.windowRateLimited = 0
+	c.windowLatency = 0
+	c.lastAdjustment = now
+
+	var reason string
+	switch {
+	case rateLimited > 0:
+		reason = "rate_limited"
+	case errorRate > c.maxErrorRate:
+		reason = "error_rate"
+	case avgLatency > c.targetLatency:
+		reason = "latency"
+	}
+
+	oldWorkers, oldDelay := c.workers, c.delay
+	if reason != "" {
+		// Multiplicative decrease
+		c.workers = clampInt(c.workers/2, c.minWorkers, c.maxWorkers)
+		c.delay = clampDuration(maxDuration(c.delay*2, c.delayStep), c.minDelay, c.maxDelay)
+		if c.workers != oldWorkers || c.delay != oldDelay {
+			c.decreases++
+		}
+	} else {
+		// Additive increase
+		reason = "healthy"
+		c.workers = clampInt(c.workers+1, c.minWorkers, c.maxWorkers)
+		c.delay = clampDuration(c.delay-c.delayStep, c.minDelay, c.maxDelay)
+		if c.workers != oldWorkers || c.delay != oldDelay {
+			c.increases++
+			c.notify()
+		}
+	}
+
+	if c.workers == oldWorkers && c.delay == oldDelay {
+		c.logger.Debug("Concurrency unchanged",
+			zap.String("reason", reason),
+			zap.Int("workers", c.workers),
+			zap.Duration("request_delay", c.delay))
+		return
+	}
+
+	c.logger.Info("Adjusted concurrency",
+		zap.String("reason", reason),
+		zap.Int("workers_from", oldWorkers),
+		zap.Int("workers_to", c.workers),
+		zap.Duration("request_delay_from", oldDelay),
+		zap.Duration("request_delay_to", c.delay),
+		zap.Duration("average_latency", avgLatency),
+		zap.Float64("error_rate", errorRate),
+		zap.Int("rate_limited", rateLimited),
+		zap.Int("calls", calls))
+}
+
+// notify wakes goroutines waiting for a worker slot. Must be called with mu held.
+func (c *Controller) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// IsRateLimitError reports whether the node rejected a request for rate limiting
+func IsRateLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errStr := err.Error()
+	return strings.Contains(errStr, "429") ||
+		strings.Contains(errStr, "Too Many Requests") ||
+		strings.Contains(strings.ToLower(errStr), "rate limit")
+}
+
+// isFailure reports whether err counts against the node. Missing data and
+// cancelled requests are not the node's fault.
+func isFailure(err error) bool {
+	return err != nil &&
+		!errors.Is(err, ethereum.NotFound) &&
+		!errors.Is(err, context.Canceled)
+}
+
+func clampInt(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+func clampDuration(value, min, max time.Duration) time.Duration {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
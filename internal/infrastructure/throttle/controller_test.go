@@ -0,0 +1,216 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"ethereum-raw-data-crawler/internal/infrastructure/config"
+	"ethereum-raw-data-crawler/internal/infrastructure/logger"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestController(t *testing.T, adaptive bool) *Controller {
+	cfg := &config.Config{
+		App:      config.AppConfig{LogLevel: "error"},
+		Ethereum: config.EthereumConfig{RateLimit: 200 * time.Millisecond},
+		Crawler: config.CrawlerConfig{
+			ConcurrentWorkers:   8,
+			AdaptiveConcurrency: adaptive,
+			MinWorkers:          2,
+			MinRequestDelay:     0,
+			MaxRequestDelay:     time.Second,
+			RequestDelayStep:    100 * time.Millisecond,
+			TargetLatency:       time.Second,
+			MaxErrorRate:        0.2,
+			AdjustInterval:      time.Hour, // Windows are closed by the tests
+		},
+	}
+	log, err := logger.NewLogger(cfg)
+	require.NoError(t, err)
+	c := NewController(cfg, log)
+	c.lastAdjustment = time.Now() // Rate limits don't end the window early
+	return c
+}
+
+func TestController_AdditiveIncrease(t *testing.T) {
+	c := newTestController(t, true)
+	require.Equal(t, 8, c.GetStats().Workers)
+
+	c.Observe(100*time.Millisecond, nil)
+	c.Observe(300*time.Millisecond, ethereum.NotFound) // Missing data is not the node's fault
+	c.adjust(time.Now())
+
+	stats := c.GetStats()
+	assert.Equal(t, 8, stats.Workers, "workers are capped at ConcurrentWorkers")
+	assert.Equal(t, 100*time.Millisecond, stats.RequestDelay)
+	assert.Equal(t, 200*time.Millisecond, stats.AverageLatency)
+	assert.Equal(t, float64(0), stats.ErrorRate)
+	assert.Equal(t, uint64(1), stats.Increases)
+
+	c.Observe(100*time.Millisecond, nil)
+	c.adjust(time.Now())
+	c.Observe(100*time.Millisecond, nil)
+	c.adjust(time.Now())
+	assert.Equal(t, time.Duration(0), c.GetStats().RequestDelay, "delay is bounded by MinRequestDelay")
+}
+
+func TestController_MultiplicativeDecrease(t *testing.T) {
+	tests := []struct {
+		name    string
+		latency time.Duration
+		errs    []error
+	}{
+		{name: "rate limited", latency: 100 * time.Millisecond, errs: []error{errors.New("429 Too Many Requests"), nil, nil, nil, nil}},
+		{name: "error rate", latency: 100 * time.Millisecond, errs: []error{errors.New("connection reset"), nil}},
+		{name: "latency", latency: 3 * time.Second, errs: []error{nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(t, true)
+			for _, err := range tt.errs {
+				c.Observe(tt.latency, err)
+			}
+			c.adjust(time.Now())
+
+			stats := c.GetStats()
+			assert.Equal(t, 4, stats.Workers)
+			assert.Equal(t, 400*time.Millisecond, stats.RequestDelay)
+			assert.Equal(t, uint64(1), stats.Decreases)
+
+			// Repeated pressure stops at the configured bounds
+			for i := 0; i < 5; i++ {
+				for _, err := range tt.errs {
+					c.Observe(tt.latency, err)
+				}
+				c.adjust(time.Now())
+			}
+			stats = c.GetStats()
+			assert.Equal(t, 2, stats.Workers)
+			assert.Equal(t, time.Second, stats.RequestDelay)
+		})
+	}
+}
+
+func TestController_RateLimitEndsWindowEarly(t *testing.T) {
+	c := newTestController(t, true)
+	c.lastAdjustment = time.Now().Add(-time.Hour)
+
+	c.Observe(50*time.Millisecond, fmt.Errorf("rpc: %w", errors.New("429 Too Many Requests")))
+
+	stats := c.GetStats()
+	assert.Equal(t, 4, stats.Workers)
+	assert.Equal(t, uint64(1), stats.RateLimitedCalls)
+}
+
+func TestController_Disabled(t *testing.T) {
+	c := newTestController(t, false)
+	c.lastAdjustment = time.Now().Add(-time.Hour)
+
+	c.Observe(5*time.Second, errors.New("429 Too Many Requests"))
+
+	stats := c.GetStats()
+	assert.False(t, stats.Enabled)
+	assert.Equal(t, 8, stats.Workers)
+	assert.Equal(t, 200*time.Millisecond, stats.RequestDelay)
+	assert.Equal(t, uint64(1), stats.RPCErrors)
+}
+
+func TestController_AcquireRespectsLimit(t *testing.T) {
+	c := newTestController(t, true)
+	c.Observe(3*time.Second, nil)
+	c.adjust(time.Now())
+	require.Equal(t, 4, c.GetStats().Workers)
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, c.Acquire(ctx))
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.Acquire(timeoutCtx), context.DeadlineExceeded)
+
+	// A released slot unblocks a waiter
+	acquired := make(chan error, 1)
+	go func() { acquired <- c.Acquire(ctx) }()
+	c.Release()
+	select {
+	case err := <-acquired:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("waiter was not woken by Release")
+	}
+	assert.Equal(t, 4, c.GetStats().InFlight)
+}
+
+func TestController_WaitPacesRequests(t *testing.T) {
+	c := newTestController(t, false)
+	c.delay = 20 * time.Millisecond
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, c.Wait(ctx))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+}
+
+func TestController_AcquirePriorityGoesFirst(t *testing.T) {
+	c := newTestController(t, false)
+	c.workers = 1
+	ctx := context.Background()
+	require.NoError(t, c.Acquire(ctx))
+
+	// A routine caller queues up before the priority caller
+	routine := make(chan error, 1)
+	go func() { routine <- c.Acquire(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+
+	priority := make(chan error, 1)
+	go func() { priority <- c.AcquirePriority(ctx) }()
+	require.Eventually(t, func() bool { return c.GetStats().PriorityWaiting == 1 }, time.Second, 5*time.Millisecond)
+
+	c.Release()
+	select {
+	case err := <-priority:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("priority caller did not get the released slot")
+	}
+	select {
+	case <-routine:
+		t.Fatal("routine caller took the slot ahead of the priority caller")
+	default:
+	}
+
+	// Routine callers continue once no priority caller is waiting
+	c.Release()
+	select {
+	case err := <-routine:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("routine caller was not woken")
+	}
+	assert.Equal(t, 0, c.GetStats().PriorityWaiting)
+}
+
+func TestController_AcquirePriorityCancelled(t *testing.T) {
+	c := newTestController(t, false)
+	c.workers = 1
+	ctx := context.Background()
+	require.NoError(t, c.Acquire(ctx))
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.AcquirePriority(timeoutCtx), context.DeadlineExceeded)
+	assert.Equal(t, 0, c.GetStats().PriorityWaiting)
+
+	c.Release()
+	require.NoError(t, c.Acquire(ctx), "a cancelled priority caller does not block routine callers")
+}
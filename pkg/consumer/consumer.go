@@ -0,0 +1,403 @@
+// Package consumer is a client library for consuming crawler events from NATS JetStream.
+//
+// A Consumer pulls events from the crawler stream, decodes them into the typed
+// messages of the versioned event schema and hands them to a Handler. Handlers
+// returning nil are acknowledged, errors are redelivered with backoff and errors
+// wrapping ErrTerminate stop redelivery:
+//
+//	c, err := consumer.Connect(consumer.Config{Durable: "my-service"})
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Drain()
+//
+//	return c.Run(ctx, func(ctx context.Context, event *consumer.Event) error {
+//		return store(ctx, event.Transaction)
+//	})
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+var (
+	// ErrTerminate can be wrapped by handler errors to stop redelivery of an event
+	ErrTerminate = errors.New("terminate event")
+	// ErrUnsupportedSchema is returned when an event uses a schema version this package doesn't know
+	ErrUnsupportedSchema = errors.New("unsupported event schema version")
+	// ErrUnknownEventType is returned when an event has an unknown type header
+	ErrUnknownEventType = errors.New("unknown event type")
+	// ErrDraining is returned by Run when called on a consumer that is draining
+	ErrDraining = errors.New("consumer is draining")
+)
+
+// Handler processes a single event
+type Handler func(ctx context.Context, event *Event) error
+
+// Config represents consumer configuration
+type Config struct {
+	URL     string
+	Stream  string
+	Subject string
+
+	// Durable is the durable consumer name. An ephemeral consumer is created when empty.
+	Durable string
+
+	// StartBlock starts delivery at the first event for this block or later. It is
+	// only applied when the consumer is created; existing durables keep their position.
+	StartBlock uint64
+	// DeliverNew starts delivery with events published after the consumer is created.
+	// By default all events retained by the stream are delivered.
+	DeliverNew bool
+
+	AckWait      time.Duration
+	MaxDeliver   int
+	BatchSize    int
+	FetchTimeout time.Duration
+	// Backoff is the redelivery delay used by Event.Nak, indexed by delivery attempt
+	Backoff []time.Duration
+	// InactiveThreshold controls how long the server keeps an idle ephemeral consumer
+	InactiveThreshold time.Duration
+
+	// ErrorHandler is called for events that fail to decode or acknowledge
+	ErrorHandler func(msg *nats.Msg, err error)
+}
+
+// DefaultConfig returns configuration matching the crawler's default stream setup
+func DefaultConfig() Config {
+	return Config{
+		URL:               nats.DefaultURL,
+		Stream:            "TRANSACTIONS",
+		Subject:           "transactions.events",
+		AckWait:           30 * time.Second,
+		MaxDeliver:        5,
+		BatchSize:         10,
+		FetchTimeout:      time.Second,
+		Backoff:           []time.Duration{time.Second, 5 * time.Second, 30 * time.Second, time.Minute},
+		InactiveThreshold: 5 * time.Minute,
+	}
+}
+
+// withDefaults fills unset fields from DefaultConfig
+func (c Config) withDefaults() Config {
+	defaults := DefaultConfig()
+	if c.URL == "" {
+		c.URL = defaults.URL
+	}
+	if c.Stream == "" {
+		c.Stream = defaults.Stream
+	}
+	if c.Subject == "" {
+		c.Subject = defaults.Subject
+	}
+	if c.AckWait <= 0 {
+		c.AckWait = defaults.AckWait
+	}
+	if c.MaxDeliver == 0 {
+		c.MaxDeliver = defaults.MaxDeliver
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaults.BatchSize
+	}
+	if c.FetchTimeout <= 0 {
+		c.FetchTimeout = defaults.FetchTimeout
+	}
+	if c.Backoff == nil {
+		c.Backoff = defaults.Backoff
+	}
+	if c.InactiveThreshold <= 0 {
+		c.InactiveThreshold = defaults.InactiveThreshold
+	}
+	return c
+}
+
+// Consumer pulls crawler events from a JetStream stream
+type Consumer struct {
+	nc       *nats.Conn
+	js       nats.JetStreamContext
+	sub      *nats.Subscription
+	config   Config
+	ownsConn bool
+
+	mu       sync.Mutex
+	draining bool
+	stopChan chan struct{}
+	running  sync.WaitGroup
+}
+
+// Connect connects to NATS and creates a consumer. The connection is closed by Drain.
+func Connect(cfg Config, opts ...nats.Option) (*Consumer, error) {
+	cfg = cfg.withDefaults()
+
+	nc, err := nats.Connect(cfg.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	c, err := New(nc, cfg)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	c.ownsConn = true
+
+	return c, nil
+}
+
+// New creates a consumer on an existing connection
+func New(nc *nats.Conn, cfg Config) (*Consumer, error) {
+	cfg = cfg.withDefaults()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	c := &Consumer{
+		nc:       nc,
+		js:       js,
+		config:   cfg,
+		stopChan: make(chan struct{}),
+	}
+
+	if cfg.Durable != "" {
+		c.sub, err = c.subscribeDurable()
+	} else {
+		c.sub, err = c.subscribeEphemeral()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// subscribeDurable creates the durable consumer if needed and binds to it.
+// The consumer is created explicitly so that draining the subscription keeps it.
+func (c *Consumer) subscribeDurable() (*nats.Subscription, error) {
+	if _, err := c.js.ConsumerInfo(c.config.Stream, c.config.Durable); err != nil {
+		if !errors.Is(err, nats.ErrConsumerNotFound) {
+			return nil, fmt.Errorf("failed to look up consumer %s: %w", c.config.Durable, err)
+		}
+
+		consumerConfig := &nats.ConsumerConfig{
+			Durable:       c.config.Durable,
+			AckPolicy:     nats.AckExplicitPolicy,
+			AckWait:       c.config.AckWait,
+			MaxDeliver:    c.config.MaxDeliver,
+			FilterSubject: c.config.Subject,
+			ReplayPolicy:  nats.ReplayInstantPolicy,
+		}
+		if err := c.applyDeliverPolicy(consumerConfig); err != nil {
+			return nil, err
+		}
+
+		if _, err := c.js.AddConsumer(c.config.Stream, consumerConfig); err != nil {
+			return nil, fmt.Errorf("failed to create consumer %s: %w", c.config.Durable, err)
+		}
+	}
+
+	sub, err := c.js.PullSubscribe(c.config.Subject, c.config.Durable,
+		nats.Bind(c.config.Stream, c.config.Durable),
+		nats.ManualAck())
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind to consumer %s: %w", c.config.Durable, err)
+	}
+
+	return sub, nil
+}
+
+// subscribeEphemeral creates an ephemeral consumer that is deleted on Drain
+func (c *Consumer) subscribeEphemeral() (*nats.Subscription, error) {
+	consumerConfig := &nats.ConsumerConfig{}
+	if err := c.applyDeliverPolicy(consumerConfig); err != nil {
+		return nil, err
+	}
+
+	opts := []nats.SubOpt{
+		nats.BindStream(c.config.Stream),
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		nats.AckWait(c.config.AckWait),
+		nats.MaxDeliver(c.config.MaxDeliver),
+		nats.InactiveThreshold(c.config.InactiveThreshold),
+	}
+
+	switch consumerConfig.DeliverPolicy {
+	case nats.DeliverByStartSequencePolicy:
+		opts = append(opts, nats.StartSequence(consumerConfig.OptStartSeq))
+	case nats.DeliverNewPolicy:
+		opts = append(opts, nats.DeliverNew())
+	default:
+		opts = append(opts, nats.DeliverAll())
+	}
+
+	sub, err := c.js.PullSubscribe(c.config.Subject, "", opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ephemeral consumer: %w", err)
+	}
+
+	return sub, nil
+}
+
+// applyDeliverPolicy sets the deliver policy from the start block or DeliverNew settings
+func (c *Consumer) applyDeliverPolicy(consumerConfig *nats.ConsumerConfig) error {
+	if c.config.StartBlock > 0 {
+		seq, err := FindStartSequence(c.js, c.config.Stream, c.config.StartBlock)
+		if err != nil {
+			return fmt.Errorf("failed to find start sequence for block %d: %w", c.config.StartBlock, err)
+		}
+		if seq == 0 {
+			// Nothing at or after the start block yet, only new events can match
+			consumerConfig.DeliverPolicy = nats.DeliverNewPolicy
+			return nil
+		}
+		consumerConfig.DeliverPolicy = nats.DeliverByStartSequencePolicy
+		consumerConfig.OptStartSeq = seq
+		return nil
+	}
+
+	if c.config.DeliverNew {
+		consumerConfig.DeliverPolicy = nats.DeliverNewPolicy
+	} else {
+		consumerConfig.DeliverPolicy = nats.DeliverAllPolicy
+	}
+	return nil
+}
+
+// Run fetches events and passes them to the handler until the context is
+// cancelled or the consumer is drained
+func (c *Consumer) Run(ctx context.Context, handler Handler) error {
+	c.mu.Lock()
+	if c.draining {
+		c.mu.Unlock()
+		return ErrDraining
+	}
+	c.running.Add(1)
+	c.mu.Unlock()
+	defer c.running.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.stopChan:
+			return nil
+		default:
+		}
+
+		msgs, err := c.sub.Fetch(c.config.BatchSize, nats.MaxWait(c.config.FetchTimeout))
+		if err != nil {
+			if errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+				continue
+			}
+			if errors.Is(err, nats.ErrBadSubscription) || errors.Is(err, nats.ErrConnectionClosed) {
+				if c.isDraining() {
+					return nil
+				}
+				return err
+			}
+			c.reportError(nil, fmt.Errorf("failed to fetch events: %w", err))
+			time.Sleep(c.config.FetchTimeout)
+			continue
+		}
+
+		for _, msg := range msgs {
+			c.handle(ctx, msg, handler)
+		}
+	}
+}
+
+// handle decodes a message, runs the handler and settles the message
+func (c *Consumer) handle(ctx context.Context, msg *nats.Msg, handler Handler) {
+	event, err := Decode(msg)
+	if err != nil {
+		// Undecodable events will never succeed, stop redelivering them
+		c.reportError(msg, err)
+		c.settle(msg, msg.Term())
+		return
+	}
+	event.backoff = c.config.Backoff
+
+	// Events published for blocks before the requested start block are skipped.
+	// Blocks are published slightly out of order, so a few may follow the start sequence.
+	if c.config.StartBlock > 0 && event.BlockNumber < c.config.StartBlock {
+		c.settle(msg, event.Ack())
+		return
+	}
+
+	if err := handler(ctx, event); err != nil {
+		if errors.Is(err, ErrTerminate) {
+			c.settle(msg, event.Term())
+			return
+		}
+		c.settle(msg, event.Nak())
+		return
+	}
+
+	c.settle(msg, event.Ack())
+}
+
+// settle reports acknowledgement errors, ignoring messages the handler already settled
+func (c *Consumer) settle(msg *nats.Msg, err error) {
+	if err != nil && !errors.Is(err, nats.ErrMsgAlreadyAckd) {
+		c.reportError(msg, fmt.Errorf("failed to acknowledge event: %w", err))
+	}
+}
+
+// reportError forwards errors to the configured error handler
+func (c *Consumer) reportError(msg *nats.Msg, err error) {
+	if c.config.ErrorHandler != nil {
+		c.config.ErrorHandler(msg, err)
+	}
+}
+
+// isDraining reports whether Drain has been called
+func (c *Consumer) isDraining() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.draining
+}
+
+// Drain stops fetching, waits for in-flight events to be handled and then drains
+// the subscription. Ephemeral consumers are deleted; durable consumers are kept.
+func (c *Consumer) Drain() error {
+	c.mu.Lock()
+	if c.draining {
+		c.mu.Unlock()
+		return nil
+	}
+	c.draining = true
+	close(c.stopChan)
+	c.mu.Unlock()
+
+	// Run returns after finishing the batch it is handling
+	c.running.Wait()
+
+	var drainErr error
+	if c.config.Durable != "" {
+		// Unsubscribing a bound subscription leaves the durable consumer in place
+		drainErr = c.sub.Unsubscribe()
+	} else {
+		drainErr = c.sub.Drain()
+	}
+
+	if c.ownsConn {
+		if err := c.nc.Drain(); err != nil && drainErr == nil {
+			drainErr = err
+		}
+	}
+
+	return drainErr
+}
+
+// Info returns the server-side consumer information
+func (c *Consumer) Info() (*nats.ConsumerInfo, error) {
+	return c.sub.ConsumerInfo()
+}
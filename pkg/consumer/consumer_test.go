@@ -0,0 +1,269 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"ethereum-raw-data-crawler/pkg/events"
+	eventsv1 "ethereum-raw-data-crawler/pkg/events/v1"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testStream  = "TRANSACTIONS"
+	testSubject = "transactions.events"
+)
+
+func startJetStream(t *testing.T) *nats.Conn {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+	t.Cleanup(srv.Shutdown)
+
+	nc, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:      testStream,
+		Subjects:  []string{testSubject},
+		Retention: nats.LimitsPolicy,
+		Storage:   nats.MemoryStorage,
+	})
+	require.NoError(t, err)
+
+	return nc
+}
+
+func publishTransaction(t *testing.T, nc *nats.Conn, hash string, blockNumber uint64) {
+	t.Helper()
+
+	data, err := events.Marshal(events.EncodingProtobuf, &eventsv1.TransactionEvent{
+		Hash:        hash,
+		BlockNumber: blockNumber,
+		Network:     "ethereum",
+	})
+	require.NoError(t, err)
+
+	msg := nats.NewMsg(testSubject)
+	msg.Data = data
+	msg.Header.Set(events.HeaderSchemaVersion, events.SchemaVersion)
+	msg.Header.Set(events.HeaderContentType, events.ContentTypeProtobuf)
+	msg.Header.Set(events.HeaderEventType, events.EventTypeTransaction)
+	msg.Header.Set(events.HeaderBlockNumber, strconv.FormatUint(blockNumber, 10))
+
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.PublishMsg(msg)
+	require.NoError(t, err)
+}
+
+// collect runs the consumer until want events were handled
+func collect(t *testing.T, c *Consumer, want int, handler Handler) []*Event {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var received []*Event
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run(ctx, func(ctx context.Context, event *Event) error {
+			if handler != nil {
+				if err := handler(ctx, event); err != nil {
+					return err
+				}
+			}
+			mu.Lock()
+			received = append(received, event)
+			if len(received) == want {
+				cancel()
+			}
+			mu.Unlock()
+			return nil
+		})
+	}()
+
+	require.NoError(t, <-done)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, want)
+	return received
+}
+
+func testConfig() Config {
+	return Config{
+		Stream:       testStream,
+		Subject:      testSubject,
+		FetchTimeout: 100 * time.Millisecond,
+		Backoff:      []time.Duration{10 * time.Millisecond},
+	}
+}
+
+func TestConsumer_Run(t *testing.T) {
+	nc := startJetStream(t)
+	for i := uint64(1); i <= 3; i++ {
+		publishTransaction(t, nc, "0x"+strconv.FormatUint(i, 10), 100+i)
+	}
+
+	c, err := New(nc, testConfig())
+	require.NoError(t, err)
+
+	received := collect(t, c, 3, nil)
+	assert.Equal(t, "0x1", received[0].Transaction.Hash)
+	assert.Equal(t, uint64(101), received[0].BlockNumber)
+	assert.Equal(t, "ethereum", received[0].Network)
+	assert.Equal(t, events.SchemaVersion, received[0].SchemaVersion)
+	assert.Equal(t, uint64(3), received[2].Sequence)
+
+	require.NoError(t, c.Drain())
+}
+
+func TestConsumer_RedeliversFailedEvents(t *testing.T) {
+	nc := startJetStream(t)
+	publishTransaction(t, nc, "0x1", 1)
+
+	c, err := New(nc, testConfig())
+	require.NoError(t, err)
+
+	attempts := 0
+	received := collect(t, c, 1, func(ctx context.Context, event *Event) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, uint64(2), received[0].NumDelivered)
+
+	require.NoError(t, c.Drain())
+}
+
+func TestConsumer_StartBlock(t *testing.T) {
+	nc := startJetStream(t)
+	for i := uint64(1); i <= 10; i++ {
+		publishTransaction(t, nc, "0x"+strconv.FormatUint(i, 10), i)
+	}
+
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+
+	seq, err := FindStartSequence(js, testStream, 7)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), seq)
+
+	seq, err = FindStartSequence(js, testStream, 11)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), seq)
+
+	cfg := testConfig()
+	cfg.StartBlock = 7
+	c, err := New(nc, cfg)
+	require.NoError(t, err)
+
+	received := collect(t, c, 4, nil)
+	assert.Equal(t, uint64(7), received[0].BlockNumber)
+	assert.Equal(t, uint64(10), received[3].BlockNumber)
+
+	require.NoError(t, c.Drain())
+}
+
+func TestConsumer_DurableResumesAfterDrain(t *testing.T) {
+	nc := startJetStream(t)
+	publishTransaction(t, nc, "0x1", 1)
+	publishTransaction(t, nc, "0x2", 2)
+
+	cfg := testConfig()
+	cfg.Durable = "indexer"
+
+	c, err := New(nc, cfg)
+	require.NoError(t, err)
+	collect(t, c, 2, nil)
+	require.NoError(t, c.Drain())
+
+	publishTransaction(t, nc, "0x3", 3)
+
+	c, err = New(nc, cfg)
+	require.NoError(t, err)
+	received := collect(t, c, 1, nil)
+	assert.Equal(t, "0x3", received[0].Transaction.Hash)
+
+	require.NoError(t, c.Drain())
+}
+
+func TestDecode_LegacyJSON(t *testing.T) {
+	msg := nats.NewMsg(testSubject)
+	msg.Data = []byte(`{"hash":"0xabc","block_number":42,"network":"ethereum"}`)
+
+	event, err := Decode(msg)
+	require.NoError(t, err)
+	assert.Equal(t, events.EventTypeTransaction, event.Type)
+	assert.Equal(t, "0xabc", event.Transaction.Hash)
+	assert.Equal(t, uint64(42), event.BlockNumber)
+}
+
+func TestDecode_TokenTransfer(t *testing.T) {
+	data, err := events.Marshal(events.EncodingProtobuf, &eventsv1.TokenTransferEvent{
+		Token:       "0xtoken",
+		Standard:    "erc20",
+		Amount:      "1000",
+		BlockNumber: 42,
+		Network:     "ethereum",
+	})
+	require.NoError(t, err)
+
+	msg := nats.NewMsg("transactions.token_transfers")
+	msg.Data = data
+	msg.Header.Set(events.HeaderSchemaVersion, events.SchemaVersion)
+	msg.Header.Set(events.HeaderContentType, events.ContentTypeProtobuf)
+	msg.Header.Set(events.HeaderEventType, events.EventTypeTokenTransfer)
+
+	event, err := Decode(msg)
+	require.NoError(t, err)
+	assert.Equal(t, events.EventTypeTokenTransfer, event.Type)
+	assert.Nil(t, event.Transaction)
+	assert.Equal(t, "0xtoken", event.TokenTransfer.Token)
+	assert.Equal(t, "1000", event.TokenTransfer.Amount)
+	assert.Equal(t, uint64(42), event.BlockNumber)
+	assert.Equal(t, "ethereum", event.Network)
+}
+
+func TestDecode_UnsupportedSchema(t *testing.T) {
+	msg := nats.NewMsg(testSubject)
+	msg.Header.Set(events.HeaderSchemaVersion, "99")
+
+	_, err := Decode(msg)
+	assert.ErrorIs(t, err, ErrUnsupportedSchema)
+}
+
+func TestBackoffFor(t *testing.T) {
+	backoff := []time.Duration{time.Second, 5 * time.Second}
+
+	assert.Equal(t, time.Second, backoffFor(backoff, 1))
+	assert.Equal(t, 5*time.Second, backoffFor(backoff, 2))
+	assert.Equal(t, 5*time.Second, backoffFor(backoff, 10))
+	assert.Equal(t, time.Duration(0), backoffFor(nil, 1))
+}
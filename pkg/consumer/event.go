@@ -0,0 +1,145 @@
+package consumer
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"ethereum-raw-data-crawler/pkg/events"
+	eventsv1 "ethereum-raw-data-crawler/pkg/events/v1"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Event is a decoded crawler event together with the NATS message it came from
+type Event struct {
+	// Type is the event type header, e.g. events.EventTypeTransaction
+	Type          string
+	SchemaVersion string
+	Network       string
+	BlockNumber   uint64
+
+	// Transaction is set when Type is events.EventTypeTransaction
+	Transaction *eventsv1.TransactionEvent
+	// TokenTransfer is set when Type is events.EventTypeTokenTransfer
+	TokenTransfer *eventsv1.TokenTransferEvent
+
+	// Sequence is the stream sequence of the message
+	Sequence uint64
+	// NumDelivered is how many times the message has been delivered, starting at 1
+	NumDelivered uint64
+
+	Msg     *nats.Msg
+	backoff []time.Duration
+}
+
+// Decode decodes a crawler event from a NATS message. Messages published before
+// the schema was versioned carry no headers and are decoded as JSON transactions.
+func Decode(msg *nats.Msg) (*Event, error) {
+	event := &Event{
+		Type:          msg.Header.Get(events.HeaderEventType),
+		SchemaVersion: msg.Header.Get(events.HeaderSchemaVersion),
+		Network:       msg.Header.Get(events.HeaderNetwork),
+		Msg:           msg,
+	}
+
+	if event.SchemaVersion != "" && event.SchemaVersion != events.SchemaVersion {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSchema, event.SchemaVersion)
+	}
+
+	if event.Type == "" {
+		event.Type = events.EventTypeTransaction
+	}
+
+	contentType := msg.Header.Get(events.HeaderContentType)
+
+	switch event.Type {
+	case events.EventTypeTransaction:
+		var tx eventsv1.TransactionEvent
+		if err := events.Unmarshal(contentType, msg.Data, &tx); err != nil {
+			return nil, fmt.Errorf("failed to decode transaction event: %w", err)
+		}
+		event.Transaction = &tx
+		event.BlockNumber = tx.BlockNumber
+		if event.Network == "" {
+			event.Network = tx.Network
+		}
+	case events.EventTypeTokenTransfer:
+		var transfer eventsv1.TokenTransferEvent
+		if err := events.Unmarshal(contentType, msg.Data, &transfer); err != nil {
+			return nil, fmt.Errorf("failed to decode token transfer event: %w", err)
+		}
+		event.TokenTransfer = &transfer
+		event.BlockNumber = transfer.BlockNumber
+		if event.Network == "" {
+			event.Network = transfer.Network
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, event.Type)
+	}
+
+	if blockNumber, ok := blockNumberHeader(msg.Header); ok {
+		event.BlockNumber = blockNumber
+	}
+
+	if meta, err := msg.Metadata(); err == nil {
+		event.Sequence = meta.Sequence.Stream
+		event.NumDelivered = meta.NumDelivered
+	}
+
+	return event, nil
+}
+
+// Ack acknowledges the event
+func (e *Event) Ack() error {
+	return e.Msg.Ack()
+}
+
+// Nak asks for redelivery after a delay chosen from the consumer's backoff
+// schedule, based on how many times the event has already been delivered
+func (e *Event) Nak() error {
+	return e.Msg.NakWithDelay(e.NextBackoff())
+}
+
+// Term tells the server to stop redelivering the event
+func (e *Event) Term() error {
+	return e.Msg.Term()
+}
+
+// InProgress resets the ack wait timer for events that take long to handle
+func (e *Event) InProgress() error {
+	return e.Msg.InProgress()
+}
+
+// NextBackoff returns the redelivery delay for the event
+func (e *Event) NextBackoff() time.Duration {
+	return backoffFor(e.backoff, e.NumDelivered)
+}
+
+// backoffFor picks the delay for the given delivery count, repeating the last step
+func backoffFor(backoff []time.Duration, numDelivered uint64) time.Duration {
+	if len(backoff) == 0 {
+		return 0
+	}
+	index := 0
+	if numDelivered > 1 {
+		index = int(numDelivered - 1)
+	}
+	if index >= len(backoff) {
+		index = len(backoff) - 1
+	}
+	return backoff[index]
+}
+
+// blockNumberHeader parses the block number header
+func blockNumberHeader(header nats.Header) (uint64, bool) {
+	value := header.Get(events.HeaderBlockNumber)
+	if value == "" {
+		return 0, false
+	}
+	blockNumber, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return blockNumber, true
+}
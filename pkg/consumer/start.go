@@ -0,0 +1,74 @@
+package consumer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// FindStartSequence returns the stream sequence of the first event for the given
+// block or later, using a binary search over the block number header. Blocks are
+// published in ascending order, so the stream is sorted by block number apart
+// from small overlaps when the crawler retries blocks. Returns 0 when no retained
+// event is at or after the block.
+func FindStartSequence(js nats.JetStreamContext, stream string, blockNumber uint64) (uint64, error) {
+	info, err := js.StreamInfo(stream)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stream info: %w", err)
+	}
+
+	low, high := info.State.FirstSeq, info.State.LastSeq
+	if info.State.Msgs == 0 || low > high {
+		return 0, nil
+	}
+
+	result := uint64(0)
+	for low <= high {
+		mid := low + (high-low)/2
+
+		seq, number, err := messageBlockAt(js, stream, mid, high)
+		if err != nil {
+			return 0, err
+		}
+		if seq == 0 {
+			// Only deleted messages between mid and high
+			if mid == 0 {
+				break
+			}
+			high = mid - 1
+			continue
+		}
+
+		if number >= blockNumber {
+			result = seq
+			if mid == 0 {
+				break
+			}
+			high = mid - 1
+		} else {
+			low = seq + 1
+		}
+	}
+
+	return result, nil
+}
+
+// messageBlockAt returns the first retained message at or after seq (up to limit)
+// and its block number. Messages without a block number header are skipped.
+func messageBlockAt(js nats.JetStreamContext, stream string, seq, limit uint64) (uint64, uint64, error) {
+	for ; seq <= limit; seq++ {
+		msg, err := js.GetMsg(stream, seq)
+		if err != nil {
+			if errors.Is(err, nats.ErrMsgNotFound) {
+				continue
+			}
+			return 0, 0, fmt.Errorf("failed to get message %d: %w", seq, err)
+		}
+
+		if number, ok := blockNumberHeader(msg.Header); ok {
+			return seq, number, nil
+		}
+	}
+	return 0, 0, nil
+}
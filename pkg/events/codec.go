@@ -0,0 +1,98 @@
+// Package events contains the wire contract for events published by the crawler:
+// the NATS header names, the schema version and the supported payload encodings.
+// Message types live in the versioned sub-packages (see eventsv1).
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// SchemaVersion is the version of the event schema currently published
+const SchemaVersion = "1"
+
+// NATS header names attached to every published event
+const (
+	HeaderSchemaVersion = "Crawler-Schema-Version"
+	HeaderContentType   = "Content-Type"
+	HeaderEventType     = "Crawler-Event-Type"
+	HeaderNetwork       = "Crawler-Network"
+	HeaderBlockNumber   = "Crawler-Block-Number"
+)
+
+// Content types of event payloads
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Event types carried in the HeaderEventType header
+const (
+	EventTypeTransaction   = "transaction"
+	EventTypeTokenTransfer = "token_transfer"
+)
+
+// Encoding defines how event payloads are serialized
+type Encoding string
+
+const (
+	// EncodingJSON serializes events with the protobuf JSON mapping using snake_case
+	// field names, always emitting every field like the original JSON payload did
+	EncodingJSON Encoding = "json"
+	// EncodingProtobuf serializes events with the protobuf binary format
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+var (
+	jsonMarshaler   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	jsonUnmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// ParseEncoding parses an encoding name, defaulting to JSON when empty
+func ParseEncoding(name string) (Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "json":
+		return EncodingJSON, nil
+	case "protobuf", "proto":
+		return EncodingProtobuf, nil
+	default:
+		return "", fmt.Errorf("unsupported event encoding: %s", name)
+	}
+}
+
+// ContentType returns the content type header value for the encoding
+func (e Encoding) ContentType() string {
+	if e == EncodingProtobuf {
+		return ContentTypeProtobuf
+	}
+	return ContentTypeJSON
+}
+
+// Marshal serializes an event with the given encoding
+func Marshal(encoding Encoding, event proto.Message) ([]byte, error) {
+	switch encoding {
+	case EncodingJSON:
+		return jsonMarshaler.Marshal(event)
+	case EncodingProtobuf:
+		return proto.Marshal(event)
+	default:
+		return nil, fmt.Errorf("unsupported event encoding: %s", encoding)
+	}
+}
+
+// Unmarshal deserializes an event payload based on its content type.
+// Payloads without a content type are treated as JSON, which is what
+// publishers emitted before the schema was versioned.
+func Unmarshal(contentType string, data []byte, event proto.Message) error {
+	switch contentType {
+	case "", ContentTypeJSON:
+		return jsonUnmarshaler.Unmarshal(data, event)
+	case ContentTypeProtobuf:
+		return proto.Unmarshal(data, event)
+	default:
+		return fmt.Errorf("unsupported content type: %s", contentType)
+	}
+}
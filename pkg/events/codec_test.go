@@ -0,0 +1,78 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	eventsv1 "ethereum-raw-data-crawler/pkg/events/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestParseEncoding(t *testing.T) {
+	encoding, err := ParseEncoding("")
+	require.NoError(t, err)
+	assert.Equal(t, EncodingJSON, encoding)
+
+	encoding, err = ParseEncoding("PROTOBUF")
+	require.NoError(t, err)
+	assert.Equal(t, EncodingProtobuf, encoding)
+	assert.Equal(t, ContentTypeProtobuf, encoding.ContentType())
+
+	_, err = ParseEncoding("avro")
+	assert.Error(t, err)
+}
+
+func TestMarshalUnmarshal_RoundTrip(t *testing.T) {
+	event := &eventsv1.TransactionEvent{
+		Hash:        "0x1234567890abcdef",
+		From:        "0x1234567890123456789012345678901234567890",
+		Value:       "1000000000000000000",
+		BlockNumber: 12345,
+		GasUsed:     21000,
+		Network:     "mainnet",
+		Timestamp:   timestamppb.New(time.Unix(1700000000, 0)),
+	}
+
+	for _, encoding := range []Encoding{EncodingJSON, EncodingProtobuf} {
+		data, err := Marshal(encoding, event)
+		require.NoError(t, err)
+
+		var decoded eventsv1.TransactionEvent
+		require.NoError(t, Unmarshal(encoding.ContentType(), data, &decoded))
+		assert.True(t, proto.Equal(event, &decoded), "encoding %s", encoding)
+	}
+}
+
+func TestUnmarshal_LegacyJSON(t *testing.T) {
+	// Payload format published before the schema was versioned, without headers
+	legacy := []byte(`{
+		"hash": "0xabc",
+		"from": "0x1",
+		"to": "0x2",
+		"value": "10",
+		"data": "0x",
+		"block_number": "12345",
+		"block_hash": "0xdef",
+		"timestamp": "2024-01-15T10:30:00Z",
+		"gas_used": "21000",
+		"gas_price": "20000000000",
+		"network": "ethereum"
+	}`)
+
+	var event eventsv1.TransactionEvent
+	require.NoError(t, Unmarshal("", legacy, &event))
+	assert.Equal(t, "0xabc", event.Hash)
+	assert.Equal(t, uint64(12345), event.BlockNumber)
+	assert.Equal(t, uint64(21000), event.GasUsed)
+	assert.Equal(t, int64(1705314600), event.Timestamp.AsTime().Unix())
+}
+
+func TestUnmarshal_UnknownContentType(t *testing.T) {
+	var event eventsv1.TransactionEvent
+	err := Unmarshal("text/plain", []byte("hello"), &event)
+	assert.Error(t, err)
+}
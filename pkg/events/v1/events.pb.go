@@ -0,0 +1,482 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: pkg/events/v1/events.proto
+
+// Package crawler.events.v1 defines the events published by the crawler.
+//
+// Fields may be added to messages in this package, but existing field
+// numbers and types must never change. Breaking changes require a new
+// package version (crawler.events.v2) and a new schema version header.
+
+package eventsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// TransactionEvent is published after a transaction has been saved to the database.
+type TransactionEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Hash  string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	From  string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	// Empty for contract creation transactions.
+	To string `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	// Value in wei as a decimal string.
+	Value string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	// Input data as a 0x-prefixed hex string.
+	Data        string `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+	BlockNumber uint64 `protobuf:"varint,6,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	BlockHash   string `protobuf:"bytes,7,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	// Time the event was published.
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	GasUsed   uint64                 `protobuf:"varint,9,opt,name=gas_used,json=gasUsed,proto3" json:"gas_used,omitempty"`
+	// Gas price in wei as a decimal string.
+	GasPrice         string `protobuf:"bytes,10,opt,name=gas_price,json=gasPrice,proto3" json:"gas_price,omitempty"`
+	Network          string `protobuf:"bytes,11,opt,name=network,proto3" json:"network,omitempty"`
+	TransactionIndex uint32 `protobuf:"varint,12,opt,name=transaction_index,json=transactionIndex,proto3" json:"transaction_index,omitempty"`
+	Nonce            uint64 `protobuf:"varint,13,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Gas              uint64 `protobuf:"varint,14,opt,name=gas,proto3" json:"gas,omitempty"`
+	// 1 for success, 0 for failure.
+	Status uint64 `protobuf:"varint,15,opt,name=status,proto3" json:"status,omitempty"`
+	// Set when the transaction created a contract.
+	ContractAddress      string `protobuf:"bytes,16,opt,name=contract_address,json=contractAddress,proto3" json:"contract_address,omitempty"`
+	MaxFeePerGas         string `protobuf:"bytes,17,opt,name=max_fee_per_gas,json=maxFeePerGas,proto3" json:"max_fee_per_gas,omitempty"`
+	MaxPriorityFeePerGas string `protobuf:"bytes,18,opt,name=max_priority_fee_per_gas,json=maxPriorityFeePerGas,proto3" json:"max_priority_fee_per_gas,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *TransactionEvent) Reset() {
+	*x = TransactionEvent{}
+	mi := &file_pkg_events_v1_events_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionEvent) ProtoMessage() {}
+
+func (x *TransactionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_events_v1_events_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionEvent.ProtoReflect.Descriptor instead.
+func (*TransactionEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_events_v1_events_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TransactionEvent) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetBlockNumber() uint64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *TransactionEvent) GetBlockHash() string {
+	if x != nil {
+		return x.BlockHash
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *TransactionEvent) GetGasUsed() uint64 {
+	if x != nil {
+		return x.GasUsed
+	}
+	return 0
+}
+
+func (x *TransactionEvent) GetGasPrice() string {
+	if x != nil {
+		return x.GasPrice
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetTransactionIndex() uint32 {
+	if x != nil {
+		return x.TransactionIndex
+	}
+	return 0
+}
+
+func (x *TransactionEvent) GetNonce() uint64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *TransactionEvent) GetGas() uint64 {
+	if x != nil {
+		return x.Gas
+	}
+	return 0
+}
+
+func (x *TransactionEvent) GetStatus() uint64 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *TransactionEvent) GetContractAddress() string {
+	if x != nil {
+		return x.ContractAddress
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetMaxFeePerGas() string {
+	if x != nil {
+		return x.MaxFeePerGas
+	}
+	return ""
+}
+
+func (x *TransactionEvent) GetMaxPriorityFeePerGas() string {
+	if x != nil {
+		return x.MaxPriorityFeePerGas
+	}
+	return ""
+}
+
+// TokenTransferEvent is published after a token transfer has been saved to the
+// database. A TransferBatch log is published as one event per token.
+type TokenTransferEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Address of the token contract.
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// erc20, erc721 or erc1155.
+	Standard string `protobuf:"bytes,2,opt,name=standard,proto3" json:"standard,omitempty"`
+	// Zero address for mints.
+	From string `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	// Zero address for burns.
+	To string `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	// Address that moved ERC-1155 tokens on behalf of from, empty otherwise.
+	Operator string `protobuf:"bytes,5,opt,name=operator,proto3" json:"operator,omitempty"`
+	// Amount as a decimal string, 1 for ERC-721 tokens.
+	Amount string `protobuf:"bytes,6,opt,name=amount,proto3" json:"amount,omitempty"`
+	// Token ID as a decimal string, empty for ERC-20 tokens.
+	TokenId          string `protobuf:"bytes,7,opt,name=token_id,json=tokenId,proto3" json:"token_id,omitempty"`
+	TransactionHash  string `protobuf:"bytes,8,opt,name=transaction_hash,json=transactionHash,proto3" json:"transaction_hash,omitempty"`
+	TransactionIndex uint32 `protobuf:"varint,9,opt,name=transaction_index,json=transactionIndex,proto3" json:"transaction_index,omitempty"`
+	LogIndex         uint32 `protobuf:"varint,10,opt,name=log_index,json=logIndex,proto3" json:"log_index,omitempty"`
+	// Position of the token in a TransferBatch log, 0 otherwise.
+	BatchIndex  uint32 `protobuf:"varint,11,opt,name=batch_index,json=batchIndex,proto3" json:"batch_index,omitempty"`
+	BlockNumber uint64 `protobuf:"varint,12,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	BlockHash   string `protobuf:"bytes,13,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	Network     string `protobuf:"bytes,14,opt,name=network,proto3" json:"network,omitempty"`
+	// Time the event was published.
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TokenTransferEvent) Reset() {
+	*x = TokenTransferEvent{}
+	mi := &file_pkg_events_v1_events_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenTransferEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenTransferEvent) ProtoMessage() {}
+
+func (x *TokenTransferEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_events_v1_events_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenTransferEvent.ProtoReflect.Descriptor instead.
+func (*TokenTransferEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_events_v1_events_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TokenTransferEvent) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *TokenTransferEvent) GetStandard() string {
+	if x != nil {
+		return x.Standard
+	}
+	return ""
+}
+
+func (x *TokenTransferEvent) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *TokenTransferEvent) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *TokenTransferEvent) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *TokenTransferEvent) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *TokenTransferEvent) GetTokenId() string {
+	if x != nil {
+		return x.TokenId
+	}
+	return ""
+}
+
+func (x *TokenTransferEvent) GetTransactionHash() string {
+	if x != nil {
+		return x.TransactionHash
+	}
+	return ""
+}
+
+func (x *TokenTransferEvent) GetTransactionIndex() uint32 {
+	if x != nil {
+		return x.TransactionIndex
+	}
+	return 0
+}
+
+func (x *TokenTransferEvent) GetLogIndex() uint32 {
+	if x != nil {
+		return x.LogIndex
+	}
+	return 0
+}
+
+func (x *TokenTransferEvent) GetBatchIndex() uint32 {
+	if x != nil {
+		return x.BatchIndex
+	}
+	return 0
+}
+
+func (x *TokenTransferEvent) GetBlockNumber() uint64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *TokenTransferEvent) GetBlockHash() string {
+	if x != nil {
+		return x.BlockHash
+	}
+	return ""
+}
+
+func (x *TokenTransferEvent) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *TokenTransferEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+var File_pkg_events_v1_events_proto protoreflect.FileDescriptor
+
+const file_pkg_events_v1_events_proto_rawDesc = "" +
+	"\n" +
+	"\x1apkg/events/v1/events.proto\x12\x11crawler.events.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb9\x04\n" +
+	"\x10TransactionEvent\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\x12\x12\n" +
+	"\x04data\x18\x05 \x01(\tR\x04data\x12!\n" +
+	"\fblock_number\x18\x06 \x01(\x04R\vblockNumber\x12\x1d\n" +
+	"\n" +
+	"block_hash\x18\a \x01(\tR\tblockHash\x128\n" +
+	"\ttimestamp\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x19\n" +
+	"\bgas_used\x18\t \x01(\x04R\agasUsed\x12\x1b\n" +
+	"\tgas_price\x18\n" +
+	" \x01(\tR\bgasPrice\x12\x18\n" +
+	"\anetwork\x18\v \x01(\tR\anetwork\x12+\n" +
+	"\x11transaction_index\x18\f \x01(\rR\x10transactionIndex\x12\x14\n" +
+	"\x05nonce\x18\r \x01(\x04R\x05nonce\x12\x10\n" +
+	"\x03gas\x18\x0e \x01(\x04R\x03gas\x12\x16\n" +
+	"\x06status\x18\x0f \x01(\x04R\x06status\x12)\n" +
+	"\x10contract_address\x18\x10 \x01(\tR\x0fcontractAddress\x12%\n" +
+	"\x0fmax_fee_per_gas\x18\x11 \x01(\tR\fmaxFeePerGas\x126\n" +
+	"\x18max_priority_fee_per_gas\x18\x12 \x01(\tR\x14maxPriorityFeePerGas\"\xe5\x03\n" +
+	"\x12TokenTransferEvent\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1a\n" +
+	"\bstandard\x18\x02 \x01(\tR\bstandard\x12\x12\n" +
+	"\x04from\x18\x03 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x04 \x01(\tR\x02to\x12\x1a\n" +
+	"\boperator\x18\x05 \x01(\tR\boperator\x12\x16\n" +
+	"\x06amount\x18\x06 \x01(\tR\x06amount\x12\x19\n" +
+	"\btoken_id\x18\a \x01(\tR\atokenId\x12)\n" +
+	"\x10transaction_hash\x18\b \x01(\tR\x0ftransactionHash\x12+\n" +
+	"\x11transaction_index\x18\t \x01(\rR\x10transactionIndex\x12\x1b\n" +
+	"\tlog_index\x18\n" +
+	" \x01(\rR\blogIndex\x12\x1f\n" +
+	"\vbatch_index\x18\v \x01(\rR\n" +
+	"batchIndex\x12!\n" +
+	"\fblock_number\x18\f \x01(\x04R\vblockNumber\x12\x1d\n" +
+	"\n" +
+	"block_hash\x18\r \x01(\tR\tblockHash\x12\x18\n" +
+	"\anetwork\x18\x0e \x01(\tR\anetwork\x128\n" +
+	"\ttimestamp\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\ttimestampB2Z0ethereum-raw-data-crawler/pkg/events/v1;eventsv1b\x06proto3"
+
+var (
+	file_pkg_events_v1_events_proto_rawDescOnce sync.Once
+	file_pkg_events_v1_events_proto_rawDescData []byte
+)
+
+func file_pkg_events_v1_events_proto_rawDescGZIP() []byte {
+	file_pkg_events_v1_events_proto_rawDescOnce.Do(func() {
+		file_pkg_events_v1_events_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_events_v1_events_proto_rawDesc), len(file_pkg_events_v1_events_proto_rawDesc)))
+	})
+	return file_pkg_events_v1_events_proto_rawDescData
+}
+
+var file_pkg_events_v1_events_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_pkg_events_v1_events_proto_goTypes = []any{
+	(*TransactionEvent)(nil),      // 0: crawler.events.v1.TransactionEvent
+	(*TokenTransferEvent)(nil),    // 1: crawler.events.v1.TokenTransferEvent
+	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+}
+var file_pkg_events_v1_events_proto_depIdxs = []int32{
+	2, // 0: crawler.events.v1.TransactionEvent.timestamp:type_name -> google.protobuf.Timestamp
+	2, // 1: crawler.events.v1.TokenTransferEvent.timestamp:type_name -> google.protobuf.Timestamp
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_pkg_events_v1_events_proto_init() }
+func file_pkg_events_v1_events_proto_init() {
+	if File_pkg_events_v1_events_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_events_v1_events_proto_rawDesc), len(file_pkg_events_v1_events_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pkg_events_v1_events_proto_goTypes,
+		DependencyIndexes: file_pkg_events_v1_events_proto_depIdxs,
+		MessageInfos:      file_pkg_events_v1_events_proto_msgTypes,
+	}.Build()
+	File_pkg_events_v1_events_proto = out.File
+	file_pkg_events_v1_events_proto_goTypes = nil
+	file_pkg_events_v1_events_proto_depIdxs = nil
+}